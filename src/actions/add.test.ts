@@ -3,6 +3,7 @@ import { chance } from 'jest-chance';
 import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
 import { generateRandomPlatform } from '../../test/generateRandomPlatform.js';
 import { generateRemoteModDetails } from '../../test/generateRemoteDetails.js';
+import { generateSearchHit } from '../../test/generateSearchHit.js';
 import { generateModConfig } from '../../test/modConfigGenerator.js';
 import { generateModsJson } from '../../test/modlistGenerator.js';
 import { expectCommandStartTelemetry, expectCommandStartTelemetryNth } from '../../test/telemetryHelper.js';
@@ -11,13 +12,16 @@ import { CouldNotFindModException } from '../errors/CouldNotFindModException.js'
 import { DownloadFailedException } from '../errors/DownloadFailedException.js';
 import { NoRemoteFileFound } from '../errors/NoRemoteFileFound.js';
 import { UnknownPlatformException } from '../errors/UnknownPlatformException.js';
+import { chooseSearchHit } from '../interactions/chooseSearchHit.js';
 import { modNotFound } from '../interactions/modNotFound.js';
 import { noRemoteFileFound } from '../interactions/noRemoteFileFound.js';
 import { Logger } from '../lib/Logger.js';
 import { ensureConfiguration, getModsFolder, readLockFile, writeConfigFile, writeLockFile } from '../lib/config.js';
 import { downloadFile } from '../lib/downloader.js';
 import { ModInstall, ModsJson, Platform, RemoteModDetails } from '../lib/modlist.types.js';
+import { ensureModsFolder } from '../lib/modsFolder.js';
 import { fetchModDetails } from '../repositories/index.js';
+import { resolveByName } from '../repositories/resolveByName.js';
 import { add } from './add.js';
 
 vi.mock('../lib/Logger.js');
@@ -25,10 +29,13 @@ vi.mock('../mmm.js');
 vi.mock('../lib/config.js');
 vi.mock('../repositories/index.js');
 vi.mock('../lib/downloader.js');
+vi.mock('../lib/modsFolder.js');
 vi.mock('@inquirer/prompts');
 vi.mock('../interactions/shouldCreateConfig.js');
 vi.mock('../interactions/modNotFound.ts');
 vi.mock('../interactions/noRemoteFileFound.js');
+vi.mock('../interactions/chooseSearchHit.js');
+vi.mock('../repositories/resolveByName.js');
 
 interface LocalTestContext {
   randomConfiguration: GeneratorResult<ModsJson>;
@@ -73,6 +80,7 @@ describe('The add module', async () => {
     // the mod details returned from the repository
     context.randomModDetails = generateRemoteModDetails();
     vi.mocked(fetchModDetails).mockResolvedValueOnce(context.randomModDetails.generated);
+    vi.mocked(resolveByName).mockResolvedValue({ candidates: [] });
     vi.mocked(logger.error).mockImplementation(() => {
       throw new Error('process.exit');
     });
@@ -83,6 +91,18 @@ describe('The add module', async () => {
     vi.clearAllMocks();
   });
 
+  it<LocalTestContext>('checks the mods folder before downloading anything', async ({ randomConfiguration }) => {
+    vi.mocked(ensureModsFolder).mockRejectedValueOnce(new Error('The mods folder is a file'));
+
+    await expect(add(Platform.MODRINTH, chance.word(), { config: 'config.json' }, logger)).rejects.toThrow(
+      'The mods folder is a file'
+    );
+
+    expect(vi.mocked(ensureModsFolder)).toHaveBeenCalledWith(randomConfiguration.generated.modsFolder, logger);
+    expect(vi.mocked(fetchModDetails)).not.toHaveBeenCalled();
+    expect(vi.mocked(downloadFile)).not.toHaveBeenCalled();
+  });
+
   it<LocalTestContext>('should add a mod to the configuration', async ({ randomConfiguration, randomModDetails }) => {
     const randomPlatform = chance.pickone(['fabric', 'forge']);
     const randomModId = chance.word();
@@ -350,7 +370,11 @@ describe('The add module', async () => {
 
         expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
           context.randomModDetails.generated.downloadUrl,
-          expect.any(String)
+          expect.any(String),
+          {
+            expectedHash: context.randomModDetails.generated.hash,
+            expectedLength: context.randomModDetails.generated.fileLength
+          }
         );
 
         // make sure we save with the correct platform
@@ -419,6 +443,96 @@ describe('The add module', async () => {
       expect(vi.mocked(writeConfigFile)).toHaveBeenCalledOnce();
       expect(vi.mocked(writeLockFile)).toHaveBeenCalledOnce();
     });
+
+    it<LocalTestContext>('adds the mod the name clearly refers to', async ({ randomConfiguration }) => {
+      const { randomPlatform, randomMod } = assumeModNotFound();
+      const match = generateSearchHit({ platform: randomPlatform }).generated;
+      vi.mocked(resolveByName).mockResolvedValueOnce({ match: match, candidates: [] });
+      vi.mocked(fetchModDetails).mockResolvedValueOnce(generateRemoteModDetails().generated);
+      assumeDownloadIsSuccessful();
+
+      await add(randomPlatform, randomMod, { config: 'config.json' }, logger);
+
+      expect(vi.mocked(resolveByName)).toHaveBeenCalledWith(
+        randomPlatform,
+        randomMod,
+        randomConfiguration.generated.gameVersion,
+        randomConfiguration.generated.loader
+      );
+      expect(logger.log).toHaveBeenCalledWith(
+        `"${randomMod}" isn't a project id on ${randomPlatform}, adding ${match.name} (${match.id})`
+      );
+      expect(vi.mocked(fetchModDetails).mock.calls[1][1]).toEqual(match.id);
+      expect(vi.mocked(modNotFound)).not.toHaveBeenCalled();
+    });
+
+    it('lets the user choose when the name fits more than one mod', async () => {
+      const { randomPlatform, randomMod } = assumeModNotFound();
+      const candidates = [
+        generateSearchHit({ platform: randomPlatform }).generated,
+        generateSearchHit({ platform: randomPlatform }).generated
+      ];
+      vi.mocked(resolveByName).mockResolvedValueOnce({ candidates: candidates });
+      vi.mocked(chooseSearchHit).mockResolvedValueOnce(candidates[1]);
+      vi.mocked(fetchModDetails).mockResolvedValueOnce(generateRemoteModDetails().generated);
+      assumeDownloadIsSuccessful();
+
+      await add(randomPlatform, randomMod, { config: 'config.json' }, logger);
+
+      expect(vi.mocked(chooseSearchHit)).toHaveBeenCalledWith(randomMod, candidates);
+      expect(vi.mocked(fetchModDetails).mock.calls[1][1]).toEqual(candidates[1].id);
+      expect(vi.mocked(modNotFound)).not.toHaveBeenCalled();
+    });
+
+    it('asks for the project id when none of the candidates was meant', async () => {
+      const { randomPlatform, randomMod } = assumeModNotFound();
+      vi.mocked(resolveByName).mockResolvedValueOnce({
+        candidates: [generateSearchHit({ platform: randomPlatform }).generated]
+      });
+      vi.mocked(chooseSearchHit).mockResolvedValueOnce(undefined);
+      vi.mocked(modNotFound).mockRejectedValueOnce(new Error('process.exit'));
+
+      await expect(add(randomPlatform, randomMod, { config: 'config.json' }, logger)).rejects.toThrow('process.exit');
+
+      expect(vi.mocked(modNotFound)).toHaveBeenCalledOnce();
+    });
+
+    it('does not choose for the user in quiet mode', async () => {
+      const { randomPlatform, randomMod } = assumeModNotFound();
+      vi.mocked(resolveByName).mockResolvedValueOnce({
+        candidates: [generateSearchHit({ platform: randomPlatform }).generated]
+      });
+      vi.mocked(modNotFound).mockRejectedValueOnce(new Error('process.exit'));
+
+      await expect(add(randomPlatform, randomMod, { config: 'config.json', quiet: true }, logger)).rejects.toThrow(
+        'process.exit'
+      );
+
+      expect(vi.mocked(chooseSearchHit)).not.toHaveBeenCalled();
+      expect(vi.mocked(modNotFound)).toHaveBeenCalledOnce();
+    });
+
+    it('asks for the project id when the search fails', async () => {
+      const { randomPlatform, randomMod } = assumeModNotFound();
+      vi.mocked(resolveByName).mockRejectedValueOnce(new Error('Search is down'));
+      vi.mocked(modNotFound).mockRejectedValueOnce(new Error('process.exit'));
+
+      await expect(add(randomPlatform, randomMod, { config: 'config.json' }, logger)).rejects.toThrow('process.exit');
+
+      expect(logger.debug).toHaveBeenCalledWith(`Could not search ${randomPlatform} for ${randomMod}: Search is down`);
+      expect(vi.mocked(modNotFound)).toHaveBeenCalledOnce();
+    });
+
+    it('asks for the project id when the search finds the project itself', async () => {
+      const { randomPlatform, randomMod } = assumeModNotFound();
+      const match = generateSearchHit({ platform: randomPlatform, id: randomMod }).generated;
+      vi.mocked(resolveByName).mockResolvedValueOnce({ match: match, candidates: [] });
+      vi.mocked(modNotFound).mockRejectedValueOnce(new Error('process.exit'));
+
+      await expect(add(randomPlatform, randomMod, { config: 'config.json' }, logger)).rejects.toThrow('process.exit');
+
+      expect(vi.mocked(modNotFound)).toHaveBeenCalledOnce();
+    });
   });
 
   describe('When the download fails', () => {
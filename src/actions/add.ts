@@ -5,14 +5,19 @@ import { CouldNotFindModException } from '../errors/CouldNotFindModException.js'
 import { DownloadFailedException } from '../errors/DownloadFailedException.js';
 import { NoRemoteFileFound } from '../errors/NoRemoteFileFound.js';
 import { UnknownPlatformException } from '../errors/UnknownPlatformException.js';
+import { findCause } from '../errors/findCause.js';
+import { chooseSearchHit } from '../interactions/chooseSearchHit.js';
 import { modNotFound } from '../interactions/modNotFound.js';
 import { noRemoteFileFound } from '../interactions/noRemoteFileFound.js';
 import { Logger } from '../lib/Logger.js';
 import { ensureConfiguration, getModsFolder, readLockFile, writeConfigFile, writeLockFile } from '../lib/config.js';
 import { downloadFile } from '../lib/downloader.js';
-import { Mod, Platform } from '../lib/modlist.types.js';
+import { Mod, ModsJson, Platform } from '../lib/modlist.types.js';
+import { ensureModsFolder } from '../lib/modsFolder.js';
+import { allowedReleaseTypesOf } from '../lib/releaseChannel.js';
 import { DefaultOptions, telemetry } from '../mmm.js';
-import { fetchModDetails } from '../repositories/index.js';
+import { SearchHit, fetchModDetails } from '../repositories/index.js';
+import { resolveByName } from '../repositories/resolveByName.js';
 
 export interface AddOptions extends DefaultOptions {
   allowVersionFallback?: boolean;
@@ -50,6 +55,37 @@ const handleUnknownPlatformException = async (
   await add(selectedPlatform as Platform, id, options, logger);
 };
 
+/**
+ * The mod the id names when it isn't a project id, like sodium for the Sodium project on Curseforge.
+ * When the name fits more than one mod the user chooses, in quiet mode nothing is chosen.
+ */
+const findModByName = async (
+  platform: Platform,
+  name: string,
+  configuration: ModsJson,
+  options: AddOptions,
+  logger: Logger
+): Promise<SearchHit | undefined> => {
+  try {
+    const { match, candidates } = await resolveByName(platform, name, configuration.gameVersion, configuration.loader);
+    if (match?.id === name) {
+      // Searching for the id found the project itself, it's the one that can't be added
+      return undefined;
+    }
+    if (match) {
+      logger.log(`"${name}" isn't a project id on ${platform}, adding ${match.name} (${match.id})`);
+      return match;
+    }
+    if (candidates.length === 0 || options.quiet) {
+      return undefined;
+    }
+    return await chooseSearchHit(name, candidates);
+  } catch (error) {
+    logger.debug(`Could not search ${platform} for ${name}: ${(error as Error).message}`);
+    return undefined;
+  }
+};
+
 export const add = async (platform: Platform, id: string, options: AddOptions, logger: Logger) => {
   performance.mark('add-start');
   const configuration = await ensureConfiguration(options.config, logger, options.quiet);
@@ -74,21 +110,24 @@ export const add = async (platform: Platform, id: string, options: AddOptions, l
     return;
   }
 
+  const modsFolder = getModsFolder(options.config, configuration);
+  await ensureModsFolder(modsFolder, logger);
+
   try {
     const modData = await fetchModDetails(
       platform,
       id,
-      configuration.defaultAllowedReleaseTypes,
+      allowedReleaseTypesOf({ type: platform }, configuration),
       configuration.gameVersion,
       configuration.loader,
       !!options.allowVersionFallback,
       options.version
     );
 
-    await downloadFile(
-      modData.downloadUrl,
-      path.resolve(getModsFolder(options.config, configuration), modData.fileName)
-    );
+    await downloadFile(modData.downloadUrl, path.resolve(modsFolder, modData.fileName), {
+      expectedHash: modData.hash,
+      expectedLength: modData.fileLength
+    });
 
     const installations = await readLockFile(options, logger);
 
@@ -146,7 +185,13 @@ export const add = async (platform: Platform, id: string, options: AddOptions, l
       return;
     }
 
-    if (error instanceof CouldNotFindModException) {
+    if (findCause(error, CouldNotFindModException)) {
+      const meant = await findModByName(platform, id, configuration, options, logger);
+      if (meant) {
+        await add(platform, meant.id, options, logger);
+        return;
+      }
+
       const { id: newId, platform: newPlatform } = await modNotFound(id, platform, logger, options);
       await add(newPlatform, newId, options, logger);
       return;
@@ -0,0 +1,50 @@
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { expectCommandStartTelemetry } from '../../test/telemetryHelper.js';
+import { Logger } from '../lib/Logger.js';
+import { clearFileCache, getFileCacheDirectory } from '../lib/fileCache.js';
+import { DefaultOptions } from '../mmm.js';
+import { clearCache } from './clearCache.js';
+
+vi.mock('../lib/Logger.js');
+vi.mock('../lib/fileCache.js');
+vi.mock('../mmm.js');
+
+interface LocalTestContext {
+  options: DefaultOptions;
+  logger: Logger;
+}
+
+describe('The clear cache action', () => {
+  beforeEach<LocalTestContext>((context) => {
+    vi.resetAllMocks();
+    context.logger = new Logger({} as never);
+    context.options = {
+      config: 'config.json',
+      quiet: false,
+      debug: false
+    };
+    vi.mocked(getFileCacheDirectory).mockReturnValue('/home/steve/.cache/mmm');
+  });
+
+  it<LocalTestContext>('tells how much room it made', async ({ options, logger }) => {
+    vi.mocked(clearFileCache).mockResolvedValueOnce({ files: 3, bytes: 3 * 1024 * 1024 });
+
+    await clearCache(options, logger);
+
+    expect(logger.log).toHaveBeenCalledWith('Removed 3 file(s), 3.0 MB, from the cache in /home/steve/.cache/mmm');
+  });
+
+  it<LocalTestContext>('calls the correct telemetry', async ({ options, logger }) => {
+    vi.mocked(clearFileCache).mockResolvedValueOnce({ files: 0, bytes: 0 });
+
+    await clearCache(options, logger);
+
+    expectCommandStartTelemetry({
+      command: 'clear-cache',
+      success: true,
+      arguments: {
+        options: options
+      }
+    });
+  });
+});
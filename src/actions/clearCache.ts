@@ -0,0 +1,26 @@
+import { Logger } from '../lib/Logger.js';
+import { clearFileCache, getFileCacheDirectory } from '../lib/fileCache.js';
+import { DefaultOptions, telemetry } from '../mmm.js';
+
+export type ClearCacheOptions = DefaultOptions;
+
+/**
+ * Removes the downloaded mod files kept for later, the mods folder and the lock file stay as they are
+ */
+export const clearCache = async (options: ClearCacheOptions, logger: Logger) => {
+  performance.mark('clear-cache-start');
+
+  const { files, bytes } = await clearFileCache();
+  const megabytes = (bytes / (1024 * 1024)).toFixed(1);
+  logger.log(`Removed ${files} file(s), ${megabytes} MB, from the cache in ${getFileCacheDirectory()}`);
+
+  performance.mark('clear-cache-succeed');
+  await telemetry.captureCommand({
+    command: 'clear-cache',
+    success: true,
+    arguments: {
+      options: options
+    },
+    duration: performance.measure('clear-cache-duration', 'clear-cache-start', 'clear-cache-succeed').duration
+  });
+};
@@ -0,0 +1,119 @@
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { expectCommandStartTelemetry } from '../../test/telemetryHelper.js';
+import { LockFileCorruptedException } from '../errors/LockFileCorruptedException.js';
+import { Logger } from '../lib/Logger.js';
+import { fileExists, readLockFileAt } from '../lib/config.js';
+import { ModInstall, Platform } from '../lib/modlist.types.js';
+import { DefaultOptions } from '../mmm.js';
+import { diffLocks } from './diff.js';
+
+vi.mock('../lib/Logger.js');
+vi.mock('../lib/config.js');
+vi.mock('../mmm.js');
+
+interface LocalTestContext {
+  options: DefaultOptions;
+  logger: Logger;
+}
+
+const assumeLocks = (before: ModInstall[], after: ModInstall[]) => {
+  vi.mocked(readLockFileAt).mockImplementation(async (lockFile) => (lockFile === 'before.json' ? before : after));
+};
+
+describe('The diff action', () => {
+  beforeEach<LocalTestContext>((context) => {
+    vi.resetAllMocks();
+    context.logger = new Logger({} as never);
+    context.options = {
+      config: 'config.json',
+      quiet: false,
+      debug: false
+    };
+
+    vi.mocked(context.logger.error).mockImplementation(() => {
+      throw new Error('process.exit');
+    });
+    vi.mocked(fileExists).mockResolvedValue(true);
+  });
+
+  it<LocalTestContext>('prints the added, removed and changed mods', async ({ options, logger }) => {
+    const kept = generateModInstall({
+      type: Platform.MODRINTH,
+      id: 'sodium',
+      name: 'Sodium',
+      fileName: 'sodium-0.5.7.jar',
+      hash: 'old',
+      releasedOn: '2024-01-01T00:00:00Z'
+    }).generated;
+    const upgraded = { ...kept, fileName: 'sodium-0.5.8.jar', hash: 'new', releasedOn: '2024-02-01T00:00:00Z' };
+    const removed = generateModInstall({
+      type: Platform.CURSEFORGE,
+      id: '1',
+      name: 'Jade',
+      fileName: 'jade.jar'
+    }).generated;
+    const added = generateModInstall({
+      type: Platform.MODRINTH,
+      id: 'lithium',
+      name: 'Lithium',
+      fileName: 'lithium.jar'
+    }).generated;
+    assumeLocks([kept, removed], [upgraded, added]);
+
+    await diffLocks('before.json', 'after.json', options, logger);
+
+    expect(vi.mocked(logger.log).mock.calls).toEqual([
+      ['+ Lithium (lithium) for modrinth: lithium.jar'],
+      ['- Jade (1) for curseforge: jade.jar'],
+      ['~ Sodium (sodium) for modrinth: sodium-0.5.7.jar -> sodium-0.5.8.jar (upgrade)']
+    ]);
+  });
+
+  it<LocalTestContext>('tells when the lock files are the same', async ({ options, logger }) => {
+    const installation = generateModInstall().generated;
+    assumeLocks([installation], [installation]);
+
+    await diffLocks('before.json', 'after.json', options, logger);
+
+    expect(logger.log).toHaveBeenCalledOnce();
+    expect(logger.log).toHaveBeenCalledWith('The lock files install the same files');
+  });
+
+  it<LocalTestContext>('stops when a lock file does not exist', async ({ options, logger }) => {
+    vi.mocked(fileExists).mockResolvedValueOnce(false);
+
+    await expect(diffLocks('before.json', 'after.json', options, logger)).rejects.toThrow('process.exit');
+
+    expect(logger.error).toHaveBeenCalledWith('The lock file at "before.json" does not exist', 1);
+    expect(readLockFileAt).not.toHaveBeenCalled();
+  });
+
+  it<LocalTestContext>('stops when a lock file is corrupted', async ({ options, logger }) => {
+    const error = new LockFileCorruptedException('after.json');
+    vi.mocked(readLockFileAt).mockResolvedValueOnce([]).mockRejectedValueOnce(error);
+
+    await expect(diffLocks('before.json', 'after.json', options, logger)).rejects.toThrow('process.exit');
+
+    expect(logger.error).toHaveBeenCalledWith(error.message, 1);
+  });
+
+  it<LocalTestContext>('calls the correct telemetry', async ({ options, logger }) => {
+    assumeLocks([], [generateModInstall().generated]);
+
+    await diffLocks('before.json', 'after.json', options, logger);
+
+    expectCommandStartTelemetry({
+      command: 'diff',
+      success: true,
+      arguments: {
+        options: options
+      },
+      extra: {
+        added: 1,
+        removed: 0,
+        changed: 0
+      }
+    });
+  });
+});
@@ -0,0 +1,64 @@
+import chalk from 'chalk';
+import { Logger } from '../lib/Logger.js';
+import { fileExists, readLockFileAt } from '../lib/config.js';
+import { diffLockFiles } from '../lib/lockDiff.js';
+import { ModInstall } from '../lib/modlist.types.js';
+import { DefaultOptions, telemetry } from '../mmm.js';
+
+export type DiffOptions = DefaultOptions;
+
+const describeInstallation = (installation: ModInstall) =>
+  `${installation.name} (${installation.id}) for ${installation.type}`;
+
+const readLock = async (lockFile: string, logger: Logger): Promise<ModInstall[]> => {
+  if (!(await fileExists(lockFile))) {
+    logger.error(`The lock file at "${lockFile}" does not exist`, 1);
+  }
+
+  try {
+    return await readLockFileAt(lockFile);
+  } catch (error) {
+    logger.error((error as Error).message, 1);
+  }
+};
+
+/**
+ * Prints what changed between two lock files, like the one of the main branch and the one of an update.
+ * The same two files always print the same lines, so the output itself can be kept in git.
+ */
+export const diffLocks = async (before: string, after: string, options: DiffOptions, logger: Logger) => {
+  performance.mark('diff-start');
+
+  const diff = diffLockFiles(await readLock(before, logger), await readLock(after, logger));
+
+  if (diff.added.length + diff.removed.length + diff.changed.length === 0) {
+    logger.log('The lock files install the same files');
+  }
+
+  diff.added.forEach((installation) => {
+    logger.log(`${chalk.green('+')} ${describeInstallation(installation)}: ${installation.fileName}`);
+  });
+
+  diff.removed.forEach((installation) => {
+    logger.log(`${chalk.red('-')} ${describeInstallation(installation)}: ${installation.fileName}`);
+  });
+
+  diff.changed.forEach(({ from, to, type }) => {
+    logger.log(`${chalk.cyan('~')} ${describeInstallation(to)}: ${from.fileName} -> ${to.fileName} (${type})`);
+  });
+
+  performance.mark('diff-succeed');
+  await telemetry.captureCommand({
+    command: 'diff',
+    success: true,
+    arguments: {
+      options: options
+    },
+    extra: {
+      added: diff.added.length,
+      removed: diff.removed.length,
+      changed: diff.changed.length
+    },
+    duration: performance.measure('diff-duration', 'diff-start', 'diff-succeed').duration
+  });
+};
@@ -0,0 +1,143 @@
+import fs from 'node:fs/promises';
+import { chance } from 'jest-chance';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { expectCommandStartTelemetry } from '../../test/telemetryHelper.js';
+import { MrpackLoaderUnsupportedException } from '../errors/MrpackLoaderUnsupportedException.js';
+import { Logger } from '../lib/Logger.js';
+import { ensureConfiguration, getModsFolder, readLockFile } from '../lib/config.js';
+import { Loader, ModInstall, ModsJson } from '../lib/modlist.types.js';
+import { MrpackExport, buildMrpack, createMrpack } from '../lib/mrpack.js';
+import { ExportOptions, exportMrpack } from './exportMrpack.js';
+
+vi.mock('node:fs/promises');
+vi.mock('../mmm.js');
+vi.mock('../lib/Logger.js');
+vi.mock('../lib/config.js');
+vi.mock('../lib/mrpack.js');
+
+const options: ExportOptions = {
+  config: 'modlist.json',
+  debug: false,
+  quiet: false,
+  packVersion: '1.0.0',
+  loaderVersion: '0.15.11'
+};
+
+const emptyPack = (): MrpackExport => ({
+  index: {
+    formatVersion: 1,
+    game: 'minecraft',
+    versionId: '1.0.0',
+    name: 'pack',
+    files: [],
+    dependencies: { minecraft: '1.20.1' }
+  },
+  embedded: [],
+  skipped: []
+});
+
+describe('The mrpack export action', () => {
+  let logger: Logger;
+  let configuration: ModsJson;
+  let installations: ModInstall[];
+  let archive: Buffer;
+
+  beforeEach(() => {
+    vi.resetAllMocks();
+    logger = new Logger({} as never);
+    configuration = generateModsJson({ loader: Loader.FABRIC }).generated;
+    installations = [generateModInstall().generated];
+    archive = Buffer.from(chance.word());
+
+    vi.mocked(ensureConfiguration).mockResolvedValue(configuration);
+    vi.mocked(readLockFile).mockResolvedValue(installations);
+    vi.mocked(getModsFolder).mockReturnValue('/mods');
+    vi.mocked(buildMrpack).mockResolvedValue(emptyPack());
+    vi.mocked(createMrpack).mockReturnValue(archive);
+    vi.mocked(logger.error).mockImplementation(() => {
+      throw new Error('process.exit');
+    });
+  });
+
+  it('writes the pack of the installed mods', async () => {
+    await exportMrpack('My Pack.mrpack', options, logger);
+
+    expect(ensureConfiguration).toHaveBeenCalledWith(options.config, logger);
+    expect(readLockFile).toHaveBeenCalledWith(options, logger);
+    expect(buildMrpack).toHaveBeenCalledWith(configuration, installations, '/mods', {
+      name: 'My Pack',
+      versionId: '1.0.0',
+      loaderVersion: '0.15.11',
+      embed: false
+    });
+    expect(fs.writeFile).toHaveBeenCalledWith('My Pack.mrpack', archive);
+    expect(logger.log).toHaveBeenCalledWith(expect.stringContaining('Exported 0 files to My Pack.mrpack'));
+  });
+
+  it('uses the name it was given', async () => {
+    await exportMrpack('pack.mrpack', { ...options, name: 'Fixture Pack' }, logger);
+
+    expect(buildMrpack).toHaveBeenCalledWith(
+      configuration,
+      installations,
+      '/mods',
+      expect.objectContaining({ name: 'Fixture Pack' })
+    );
+  });
+
+  it('embeds the files the launchers can not download when asked to', async () => {
+    await exportMrpack('pack.mrpack', { ...options, embed: true }, logger);
+
+    expect(buildMrpack).toHaveBeenCalledWith(
+      configuration,
+      installations,
+      '/mods',
+      expect.objectContaining({ embed: true })
+    );
+  });
+
+  it('warns about the files it embeds and lists the ones it skips', async () => {
+    vi.mocked(buildMrpack).mockResolvedValueOnce({
+      ...emptyPack(),
+      embedded: [{ name: 'overrides/mods/jei.jar', data: Buffer.from('jei') }],
+      skipped: [{ fileName: 'sodium.jar', reason: "it isn't installed, run mmm install first" }]
+    });
+
+    await exportMrpack('pack.mrpack', options, logger);
+
+    expect(logger.log).toHaveBeenCalledWith(
+      expect.stringContaining("jei.jar can't be downloaded by the launchers, it is put in the pack.")
+    );
+    expect(logger.log).toHaveBeenCalledWith(expect.stringContaining('Exported 1 files to pack.mrpack'));
+    expect(logger.log).toHaveBeenCalledWith('\nThe following files are not in the pack:\n', true);
+    expect(logger.log).toHaveBeenCalledWith(
+      expect.stringContaining("sodium.jar: it isn't installed, run mmm install first"),
+      true
+    );
+  });
+
+  it('stops when the pack can not be made', async () => {
+    const error = new MrpackLoaderUnsupportedException(Loader.PAPER, ['fabric']);
+    vi.mocked(buildMrpack).mockRejectedValueOnce(error);
+
+    await expect(exportMrpack('pack.mrpack', options, logger)).rejects.toThrow('process.exit');
+
+    expect(logger.error).toHaveBeenCalledWith(error.message, 2);
+    expect(fs.writeFile).not.toHaveBeenCalled();
+  });
+
+  it('sends the telemetry', async () => {
+    await exportMrpack('pack.mrpack', options, logger);
+
+    expectCommandStartTelemetry({
+      command: 'export',
+      success: true,
+      arguments: {
+        options: options,
+        file: 'pack.mrpack'
+      }
+    });
+  });
+});
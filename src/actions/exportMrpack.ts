@@ -0,0 +1,63 @@
+import fs from 'node:fs/promises';
+import path from 'node:path';
+import chalk from 'chalk';
+import { Logger } from '../lib/Logger.js';
+import { ensureConfiguration, getModsFolder, readLockFile } from '../lib/config.js';
+import { MrpackExport, buildMrpack, createMrpack } from '../lib/mrpack.js';
+import { DefaultOptions, telemetry } from '../mmm.js';
+
+export interface ExportOptions extends DefaultOptions {
+  name?: string;
+  packVersion: string;
+  loaderVersion: string;
+  embed?: boolean;
+}
+
+export const exportMrpack = async (file: string, options: ExportOptions, logger: Logger) => {
+  performance.mark('export-start');
+  const configuration = await ensureConfiguration(options.config, logger);
+  const installations = await readLockFile(options, logger);
+  const modsFolder = getModsFolder(options.config, configuration);
+  let pack: MrpackExport;
+
+  try {
+    pack = await buildMrpack(configuration, installations, modsFolder, {
+      name: options.name ?? path.basename(file, path.extname(file)),
+      versionId: options.packVersion,
+      loaderVersion: options.loaderVersion,
+      embed: !!options.embed
+    });
+    await fs.writeFile(file, createMrpack(pack));
+  } catch (error) {
+    logger.error((error as Error).message, 2);
+  }
+
+  pack.embedded.forEach((entry) => {
+    logger.log(
+      chalk.yellow(
+        `${path.posix.basename(entry.name)} can't be downloaded by the launchers, it is put in the pack. ` +
+          'Make sure its license lets you share it.'
+      )
+    );
+  });
+
+  logger.log(`${chalk.green('\u2705')} Exported ${pack.index.files.length + pack.embedded.length} files to ${file}`);
+
+  if (pack.skipped.length > 0) {
+    logger.log('\nThe following files are not in the pack:\n', true);
+    pack.skipped.forEach(({ fileName, reason }) => {
+      logger.log(`  ${chalk.red('\u274c')} ${fileName}: ${reason}`, true);
+    });
+  }
+
+  performance.mark('export-succeed');
+  await telemetry.captureCommand({
+    command: 'export',
+    success: true,
+    arguments: {
+      options: options,
+      file: file
+    },
+    duration: performance.measure('export-duration', 'export-start', 'export-succeed').duration
+  });
+};
@@ -0,0 +1,193 @@
+import path from 'node:path';
+import { chance } from 'jest-chance';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { expectCommandStartTelemetry } from '../../test/telemetryHelper.js';
+import { PackwizPackInvalidException } from '../errors/PackwizPackInvalidException.js';
+import { Logger } from '../lib/Logger.js';
+import { ensureConfiguration, fileExists, writeConfigFile } from '../lib/config.js';
+import { Loader, ModsJson, Platform, ReleaseType } from '../lib/modlist.types.js';
+import { PackwizMod, PackwizPack, mapPackwizMods, readPackwizPack } from '../lib/packwiz.js';
+import { importPackwiz } from './importPackwiz.js';
+
+vi.mock('../mmm.js');
+vi.mock('../lib/Logger.js');
+vi.mock('../lib/config.js');
+vi.mock('../lib/packwiz.js');
+
+const options = {
+  config: 'modlist.json',
+  debug: false,
+  quiet: false
+};
+
+const packwizMod = (name: string, file = `mods/${name}.pw.toml`): PackwizMod => ({
+  file: file,
+  name: name,
+  pinned: false,
+  download: {}
+});
+
+describe('The packwiz import action', () => {
+  let logger: Logger;
+  let pack: PackwizPack;
+
+  beforeEach(() => {
+    vi.resetAllMocks();
+    logger = new Logger({} as never);
+    pack = {
+      file: path.resolve('pack.toml'),
+      name: 'Fixture Pack',
+      gameVersion: '1.20.1',
+      loader: Loader.FABRIC,
+      mods: [packwizMod('sodium')]
+    };
+
+    vi.mocked(readPackwizPack).mockResolvedValue(pack);
+    vi.mocked(mapPackwizMods).mockResolvedValue({ mods: [], unmapped: [] });
+    vi.mocked(logger.error).mockImplementation(() => {
+      throw new Error('process.exit');
+    });
+  });
+
+  it('maps the mods of the pack', async () => {
+    const packPath = chance.word();
+
+    await importPackwiz(packPath, options, logger);
+
+    expect(readPackwizPack).toHaveBeenCalledWith(packPath);
+    expect(mapPackwizMods).toHaveBeenCalledWith(pack.mods);
+  });
+
+  describe('when there is no modlist yet', () => {
+    beforeEach(() => {
+      vi.mocked(fileExists).mockResolvedValue(false);
+    });
+
+    it('makes one from the versions of the pack', async () => {
+      const sodium = generateModConfig({ type: Platform.MODRINTH, id: 'AANobbMI', name: 'Sodium' }).generated;
+      vi.mocked(mapPackwizMods).mockResolvedValueOnce({ mods: [sodium], unmapped: [] });
+
+      await importPackwiz('pack.toml', options, logger);
+
+      expect(fileExists).toHaveBeenCalledWith(path.resolve(options.config));
+      expect(ensureConfiguration).not.toHaveBeenCalled();
+      expect(writeConfigFile).toHaveBeenCalledWith(
+        {
+          loader: Loader.FABRIC,
+          gameVersion: '1.20.1',
+          defaultAllowedReleaseTypes: [ReleaseType.RELEASE, ReleaseType.BETA],
+          modsFolder: 'mods',
+          mods: [sodium]
+        },
+        options,
+        logger
+      );
+      expect(logger.log).toHaveBeenCalledWith(expect.stringContaining('Imported Sodium (AANobbMI) for modrinth'));
+    });
+
+    it.each<[string, Partial<PackwizPack>]>([
+      ['the Minecraft version', { gameVersion: undefined }],
+      ['a supported loader', { loader: undefined }]
+    ])('stops when the pack does not tell %s', async (_missing, overrides) => {
+      vi.mocked(readPackwizPack).mockResolvedValueOnce({ ...pack, ...overrides });
+      const reason = 'it does not tell the Minecraft version and a supported loader';
+
+      await expect(importPackwiz('pack.toml', options, logger)).rejects.toThrow('process.exit');
+
+      expect(logger.error).toHaveBeenCalledWith(new PackwizPackInvalidException(pack.file, reason).message, 2);
+      expect(writeConfigFile).not.toHaveBeenCalled();
+    });
+  });
+
+  describe('when there is a modlist already', () => {
+    let configuration: ModsJson;
+
+    beforeEach(() => {
+      configuration = generateModsJson({
+        mods: [generateModConfig({ type: Platform.MODRINTH, id: 'AANobbMI', name: 'Sodium' }).generated]
+      }).generated;
+      vi.mocked(fileExists).mockResolvedValue(true);
+      vi.mocked(ensureConfiguration).mockResolvedValue(configuration);
+    });
+
+    it('adds the mods it does not have yet', async () => {
+      const sodium = generateModConfig({ type: Platform.MODRINTH, id: 'AANobbMI', name: 'Sodium' }).generated;
+      const jei = generateModConfig({ type: Platform.CURSEFORGE, id: '238222', name: 'Just Enough Items' }).generated;
+      vi.mocked(mapPackwizMods).mockResolvedValueOnce({ mods: [sodium, jei], unmapped: [] });
+      const expectedMods = [configuration.mods[0], jei];
+
+      await importPackwiz('pack.toml', options, logger);
+
+      expect(ensureConfiguration).toHaveBeenCalledWith(options.config, logger);
+      expect(writeConfigFile).toHaveBeenCalledWith(
+        expect.objectContaining({ gameVersion: configuration.gameVersion, mods: expectedMods }),
+        options,
+        logger
+      );
+      expect(logger.log).toHaveBeenCalledWith('Sodium is already in the modlist');
+      expect(logger.log).toHaveBeenCalledWith(
+        expect.stringContaining('Imported Just Enough Items (238222) for curseforge')
+      );
+    });
+  });
+
+  it('lists the mods it could not import', async () => {
+    vi.mocked(fileExists).mockResolvedValue(false);
+    vi.mocked(mapPackwizMods).mockResolvedValueOnce({
+      mods: [],
+      unmapped: [
+        {
+          mod: packwizMod('faithful', 'resourcepacks/faithful.pw.toml'),
+          reason: "it isn't in the mods folder of the pack"
+        },
+        { mod: packwizMod('custom'), reason: "it doesn't tell which Modrinth or Curseforge project it is" }
+      ]
+    });
+
+    await importPackwiz('pack.toml', options, logger);
+
+    expect(logger.log).toHaveBeenCalledTimes(3);
+    expect(logger.log).toHaveBeenCalledWith(
+      '\nThe following files of Fixture Pack could not be imported, please add them by hand:\n',
+      true
+    );
+    expect(logger.log).toHaveBeenCalledWith(
+      expect.stringContaining("faithful (resourcepacks/faithful.pw.toml): it isn't in the mods folder of the pack"),
+      true
+    );
+    expect(logger.log).toHaveBeenCalledWith(
+      expect.stringContaining(
+        "custom (mods/custom.pw.toml): it doesn't tell which Modrinth or Curseforge project it is"
+      ),
+      true
+    );
+  });
+
+  it('stops when the pack can not be read', async () => {
+    const error = new PackwizPackInvalidException(pack.file, 'it does not exist');
+    vi.mocked(readPackwizPack).mockRejectedValueOnce(error);
+
+    await expect(importPackwiz('pack.toml', options, logger)).rejects.toThrow('process.exit');
+
+    expect(logger.error).toHaveBeenCalledWith(error.message, 2);
+    expect(writeConfigFile).not.toHaveBeenCalled();
+  });
+
+  it('sends the telemetry', async () => {
+    vi.mocked(fileExists).mockResolvedValue(false);
+    const packPath = chance.word();
+
+    await importPackwiz(packPath, options, logger);
+
+    expectCommandStartTelemetry({
+      command: 'import',
+      success: true,
+      arguments: {
+        options: options,
+        pack: packPath
+      }
+    });
+  });
+});
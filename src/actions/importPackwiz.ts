@@ -0,0 +1,73 @@
+import path from 'path';
+import chalk from 'chalk';
+import { PackwizPackInvalidException } from '../errors/PackwizPackInvalidException.js';
+import { Logger } from '../lib/Logger.js';
+import { ensureConfiguration, fileExists, writeConfigFile } from '../lib/config.js';
+import { ModsJson, ReleaseType } from '../lib/modlist.types.js';
+import { PackwizImport, PackwizPack, mapPackwizMods, readPackwizPack } from '../lib/packwiz.js';
+import { DefaultOptions, telemetry } from '../mmm.js';
+
+/**
+ * The mods are added to the existing modlist, without one the modlist is made from the versions of the pack
+ */
+const modlistFor = async (pack: PackwizPack, options: DefaultOptions, logger: Logger): Promise<ModsJson> => {
+  if (await fileExists(path.resolve(options.config))) {
+    return await ensureConfiguration(options.config, logger);
+  }
+
+  if (!pack.gameVersion || !pack.loader) {
+    throw new PackwizPackInvalidException(pack.file, 'it does not tell the Minecraft version and a supported loader');
+  }
+
+  return {
+    loader: pack.loader,
+    gameVersion: pack.gameVersion,
+    defaultAllowedReleaseTypes: [ReleaseType.RELEASE, ReleaseType.BETA],
+    modsFolder: 'mods',
+    mods: []
+  };
+};
+
+export const importPackwiz = async (packPath: string, options: DefaultOptions, logger: Logger) => {
+  performance.mark('import-start');
+  let pack: PackwizPack;
+  let imported: PackwizImport;
+  let configuration: ModsJson;
+
+  try {
+    pack = await readPackwizPack(packPath);
+    imported = await mapPackwizMods(pack.mods);
+    configuration = await modlistFor(pack, options, logger);
+  } catch (error) {
+    logger.error((error as Error).message, 2);
+  }
+
+  imported.mods.forEach((mod) => {
+    if (configuration.mods.some((configured) => configured.type === mod.type && configured.id === mod.id)) {
+      logger.log(`${mod.name} is already in the modlist`);
+      return;
+    }
+    configuration.mods.push(mod);
+    logger.log(`${chalk.green('\u2705')} Imported ${mod.name} (${mod.id}) for ${mod.type}`);
+  });
+
+  await writeConfigFile(configuration, options, logger);
+
+  if (imported.unmapped.length > 0) {
+    logger.log(`\nThe following files of ${pack.name} could not be imported, please add them by hand:\n`, true);
+    imported.unmapped.forEach(({ mod, reason }) => {
+      logger.log(`  ${chalk.red('\u274c')} ${mod.name} (${mod.file}): ${reason}`, true);
+    });
+  }
+
+  performance.mark('import-succeed');
+  await telemetry.captureCommand({
+    command: 'import',
+    success: true,
+    arguments: {
+      options: options,
+      pack: packPath
+    },
+    duration: performance.measure('import-duration', 'import-start', 'import-succeed').duration
+  });
+};
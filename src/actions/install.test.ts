@@ -1,7 +1,8 @@
 import { chance } from 'jest-chance';
-import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
 import { generateRemoteModDetails } from '../../test/generateRemoteDetails.js';
 import { generateScanResult } from '../../test/generateScanResult.js';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
 import { generateModsJson } from '../../test/modlistGenerator.js';
 import {
   assumeModFileExists,
@@ -22,12 +23,20 @@ import { handleFetchErrors } from '../errors/handleFetchErrors.js';
 import { Logger } from '../lib/Logger.js';
 import { ensureConfiguration, getModsFolder, readLockFile, writeConfigFile, writeLockFile } from '../lib/config.js';
 import { fileIsManaged, getInstallation, hasInstallation } from '../lib/configurationHelper.js';
+import { findConflicts } from '../lib/conflicts.js';
 import { downloadFile } from '../lib/downloader.js';
+import { DuplicateReason, findDuplicateMods } from '../lib/duplicateMods.js';
 import { getModFiles } from '../lib/fileHelper.js';
 import { getHash } from '../lib/hash.js';
-import { ModInstall, Platform } from '../lib/modlist.types.js';
+import { detectLockDrift } from '../lib/lockDrift.js';
+import { Loader, ModInstall, Platform, ReleaseType, RemoteModDetails } from '../lib/modlist.types.js';
+import { ensureModsFolder } from '../lib/modsFolder.js';
+import { isOfflineMode } from '../lib/offline.js';
+import { findModsUnavailableOffline } from '../lib/offlineResolution.js';
+import { ProgressEvent, setProgress } from '../lib/progress.js';
 import { scanFiles } from '../lib/scan.js';
-import { updateMod } from '../lib/updater.js';
+import { UnsatisfiedReason, findUnsatisfiedDependencies } from '../lib/unsatisfiedDependencies.js';
+import { restoreAdditionalFiles, updateMod } from '../lib/updater.js';
 import { DefaultOptions } from '../mmm.js';
 import { fetchModDetails } from '../repositories/index.js';
 import { install } from './install.js';
@@ -46,6 +55,13 @@ vi.mock('../lib/configurationHelper.js');
 vi.mock('../lib/scan.js');
 vi.mock('./scan.js');
 vi.mock('../mmm.js');
+vi.mock('../lib/offline.js');
+vi.mock('../lib/offlineResolution.js');
+vi.mock('../lib/duplicateMods.js');
+vi.mock('../lib/modsFolder.js');
+vi.mock('../lib/unsatisfiedDependencies.js');
+vi.mock('../lib/lockDrift.js');
+vi.mock('../lib/conflicts.js');
 
 interface LocalTestContext {
   options: DefaultOptions;
@@ -66,6 +82,13 @@ describe('The install module', () => {
     });
     vi.mocked(handleFetchErrors).mockReturnValue();
     vi.mocked(getModFiles).mockResolvedValue([]);
+    vi.mocked(findDuplicateMods).mockReturnValue([]);
+    vi.mocked(findUnsatisfiedDependencies).mockReturnValue([]);
+    vi.mocked(findConflicts).mockReturnValue([]);
+  });
+
+  afterEach(() => {
+    setProgress();
   });
 
   it<LocalTestContext>('installs a new mod with no release type override', async ({ options, logger }) => {
@@ -111,6 +134,11 @@ describe('The install module', () => {
     );
 
     expect(vi.mocked(downloadFile)).toHaveBeenCalledOnce();
+    expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+      remoteDetails.downloadUrl,
+      expect.stringContaining(remoteDetails.fileName),
+      { expectedHash: remoteDetails.hash, expectedLength: remoteDetails.fileLength }
+    );
     expect(vi.mocked(fetchModDetails)).toHaveBeenCalledOnce();
 
     verifyBasics();
@@ -139,8 +167,283 @@ describe('The install module', () => {
     expectModDetailsHaveBeenFetchedCorrectlyForMod(randomUninstalledMod, randomConfiguration);
   });
 
+  it<LocalTestContext>('installs the additional files of a new mod', async ({ options, logger }) => {
+    const { randomConfiguration } = setupOneUninstalledMod();
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([]);
+
+    const additionalFile = {
+      fileName: 'textures.zip',
+      hash: chance.hash(),
+      downloadUrl: 'https://example.com/textures.zip'
+    };
+    const remoteDetails = generateRemoteModDetails({ additionalFiles: [additionalFile] }).generated;
+    vi.mocked(fetchModDetails).mockResolvedValueOnce(remoteDetails);
+    assumeSuccessfulDownload();
+
+    await install(options, logger);
+
+    expect(vi.mocked(downloadFile)).toHaveBeenCalledTimes(2);
+    expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+      additionalFile.downloadUrl,
+      expect.stringContaining(additionalFile.fileName),
+      { expectedHash: additionalFile.hash }
+    );
+    expect(vi.mocked(writeLockFile).mock.calls[0][0][0].additionalFiles).toEqual([additionalFile]);
+  });
+
+  it<LocalTestContext>('warns about the mods that were added from both platforms', async ({ options, logger }) => {
+    const { randomConfiguration } = setupOneUninstalledMod();
+    const curseforge = generateModConfig({ type: Platform.CURSEFORGE, name: 'Sodium' }).generated;
+    const modrinth = generateModConfig({ type: Platform.MODRINTH, name: 'Sodium' }).generated;
+    randomConfiguration.mods = [];
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(readLockFile).mockResolvedValueOnce([]);
+    vi.mocked(findDuplicateMods).mockReturnValueOnce([{ mods: [curseforge, modrinth], reason: DuplicateReason.NAME }]);
+
+    await install(options, logger);
+
+    expect(vi.mocked(findDuplicateMods)).toHaveBeenCalledWith(randomConfiguration, []);
+    expect(logger.log).toHaveBeenCalledWith(
+      '\u26a0 Sodium (curseforge) and Sodium (modrinth) look like the same mod, keep only one of them'
+    );
+  });
+
+  describe('when the dependencies of the mods are checked', () => {
+    it<LocalTestContext>('keeps the dependencies of a new mod in the lock file', async ({ options, logger }) => {
+      const { randomConfiguration } = setupOneUninstalledMod();
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+      const dependencies = [{ type: Platform.MODRINTH, id: 'P7dR8mSH' }];
+      vi.mocked(fetchModDetails).mockResolvedValueOnce(
+        generateRemoteModDetails({ dependencies: dependencies }).generated
+      );
+      assumeSuccessfulDownload();
+
+      await install(options, logger);
+
+      const installations = vi.mocked(writeLockFile).mock.calls[0][0];
+      expect(installations[0].dependencies).toEqual(dependencies);
+      expect(vi.mocked(findUnsatisfiedDependencies)).toHaveBeenCalledWith(randomConfiguration, installations);
+    });
+
+    it<LocalTestContext>('warns about a dependency that is not in the modlist', async ({ options, logger }) => {
+      const { randomConfiguration } = setupOneUninstalledMod();
+      const mod = generateModConfig({ name: 'Sodium Extra' }).generated;
+      randomConfiguration.mods = [];
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+      vi.mocked(findUnsatisfiedDependencies).mockReturnValueOnce([
+        { mod: mod, dependency: { type: Platform.MODRINTH, id: 'AANobbMI' }, reason: UnsatisfiedReason.MISSING }
+      ]);
+
+      await install(options, logger);
+
+      expect(logger.log).toHaveBeenCalledWith(
+        "\u26a0 Sodium Extra needs AANobbMI from modrinth, which isn't in the modlist. " +
+          'Add it, or give the mod that should provide it a fallback on that platform'
+      );
+    });
+
+    it<LocalTestContext>('warns about a dependency that no longer supports the game version', async ({
+      options,
+      logger
+    }) => {
+      const { randomConfiguration } = setupOneUninstalledMod();
+      const mod = generateModConfig({ name: 'Sodium Extra' }).generated;
+      const provider = generateModConfig({ name: 'Sodium' }).generated;
+      randomConfiguration.mods = [];
+      randomConfiguration.gameVersion = '1.21';
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+      vi.mocked(findUnsatisfiedDependencies).mockReturnValueOnce([
+        {
+          mod: mod,
+          dependency: { type: provider.type, id: provider.id },
+          reason: UnsatisfiedReason.UNAVAILABLE,
+          provider: provider
+        }
+      ]);
+
+      await install(options, logger);
+
+      expect(logger.log).toHaveBeenCalledWith(
+        "\u26a0 Sodium Extra needs Sodium, which couldn't be installed for Minecraft 1.21"
+      );
+    });
+
+    it<LocalTestContext>('leaves the check to the caller when asked to', async ({ options, logger }) => {
+      const { randomConfiguration } = setupOneUninstalledMod();
+      randomConfiguration.mods = [];
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+
+      await install(options, logger, false);
+
+      expect(vi.mocked(findUnsatisfiedDependencies)).not.toHaveBeenCalled();
+    });
+  });
+
+  describe('when the conflicts between the mods are checked', () => {
+    it<LocalTestContext>('keeps the incompatibilities of a new mod in the lock file', async ({ options, logger }) => {
+      const { randomConfiguration } = setupOneUninstalledMod();
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+      const incompatibilities = [{ type: Platform.MODRINTH, id: 'YL57xq9U' }];
+      vi.mocked(fetchModDetails).mockResolvedValueOnce(
+        generateRemoteModDetails({ incompatibilities: incompatibilities }).generated
+      );
+      assumeSuccessfulDownload();
+
+      await install(options, logger);
+
+      const installations = vi.mocked(writeLockFile).mock.calls[0][0];
+      expect(installations[0].incompatibilities).toEqual(incompatibilities);
+      expect(vi.mocked(findConflicts)).toHaveBeenCalledWith(randomConfiguration, installations);
+    });
+
+    it<LocalTestContext>('warns about the mods that are incompatible', async ({ options, logger }) => {
+      const { randomConfiguration } = setupOneUninstalledMod();
+      const mod = generateModConfig({ name: 'Sodium' }).generated;
+      const other = generateModConfig({ name: 'Iris' }).generated;
+      randomConfiguration.mods = [];
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+      vi.mocked(findConflicts).mockReturnValueOnce([{ mod: mod, conflictsWith: other }]);
+
+      await install(options, logger);
+
+      expect(logger.log).toHaveBeenCalledWith('\u26a0 Sodium is incompatible with Iris, keep only one of them');
+    });
+
+    it<LocalTestContext>('leaves the check to the caller when asked to', async ({ options, logger }) => {
+      const { randomConfiguration } = setupOneUninstalledMod();
+      randomConfiguration.mods = [];
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+
+      await install(options, logger, false);
+
+      expect(vi.mocked(findConflicts)).not.toHaveBeenCalled();
+    });
+  });
+
+  it<LocalTestContext>('names the new file after the configured template', async ({ options, logger }) => {
+    const { randomConfiguration, randomUninstalledMod } = setupOneUninstalledMod();
+    randomConfiguration.fileNameTemplate = '{slug}-{gameVersion}.jar';
+    const remoteDetails = generateRemoteModDetails({ name: 'Sodium Extra' }).generated;
+    const expectedFileName = `sodium-extra-${randomConfiguration.gameVersion}.jar`;
+
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([]);
+    vi.mocked(fetchModDetails).mockResolvedValueOnce(remoteDetails);
+    assumeSuccessfulDownload();
+
+    await install(options, logger);
+
+    expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+      remoteDetails.downloadUrl,
+      expect.stringMatching(new RegExp(`${expectedFileName}$`)),
+      { expectedHash: remoteDetails.hash, expectedLength: remoteDetails.fileLength }
+    );
+    expect(vi.mocked(writeLockFile)).toHaveBeenCalledWith(
+      [expect.objectContaining({ id: randomUninstalledMod.id, fileName: expectedFileName })],
+      options,
+      logger
+    );
+  });
+
+  it<LocalTestContext>('asks the fallback with the release types of its platform', async ({ options, logger }) => {
+    const { randomConfiguration, randomUninstalledMod } = setupOneUninstalledMod();
+    delete randomUninstalledMod.allowedReleaseTypes;
+    randomUninstalledMod.type = Platform.CURSEFORGE;
+    randomUninstalledMod.fallback = { type: Platform.MODRINTH, id: 'AANobbMI' };
+    randomConfiguration.defaultAllowedReleaseTypes = [ReleaseType.RELEASE];
+    randomConfiguration.platformAllowedReleaseTypes = { [Platform.MODRINTH]: [ReleaseType.RELEASE, ReleaseType.BETA] };
+
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([]);
+    vi.mocked(fetchModDetails).mockResolvedValueOnce(generateRemoteModDetails().generated);
+    assumeSuccessfulDownload();
+
+    await install(options, logger);
+
+    expect(vi.mocked(fetchModDetails).mock.calls[0][2]).toEqual([ReleaseType.RELEASE]);
+    expect(vi.mocked(fetchModDetails).mock.calls[0][7]).toEqual({
+      type: Platform.MODRINTH,
+      id: 'AANobbMI',
+      allowedReleaseTypes: [ReleaseType.RELEASE, ReleaseType.BETA]
+    });
+  });
+
+  it<LocalTestContext>('tells which of the accepted loaders the new mod uses', async ({ options, logger }) => {
+    const { randomConfiguration, randomUninstalledMod } = setupOneUninstalledMod();
+    randomUninstalledMod.loaders = [Loader.FORGE, Loader.FABRIC];
+    const name = randomUninstalledMod.name;
+    const remoteDetails = generateRemoteModDetails({ name: name, loader: Loader.FABRIC }).generated;
+
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([]);
+    vi.mocked(fetchModDetails).mockResolvedValueOnce(remoteDetails);
+    assumeSuccessfulDownload();
+
+    await install(options, logger);
+
+    expect(vi.mocked(fetchModDetails).mock.calls[0][4]).toEqual([Loader.FORGE, Loader.FABRIC]);
+    expect(logger.log).toHaveBeenCalledWith(`${name} uses the fabric file`);
+  });
+
+  it<LocalTestContext>('checks the mods folder before downloading anything', async ({ options, logger }) => {
+    const { randomConfiguration } = setupOneUninstalledMod();
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([]);
+    vi.mocked(ensureModsFolder).mockRejectedValueOnce(new Error('The mods folder is missing'));
+
+    await expect(install(options, logger)).rejects.toThrow('The mods folder is missing');
+
+    expect(vi.mocked(ensureModsFolder)).toHaveBeenCalledWith(randomConfiguration.modsFolder, logger);
+    expect(vi.mocked(fetchModDetails)).not.toHaveBeenCalled();
+    expect(vi.mocked(downloadFile)).not.toHaveBeenCalled();
+  });
+
+  it<LocalTestContext>('reports every mod as it is resolved', async ({ options, logger }) => {
+    const randomConfiguration = generateModsJson().generated;
+    randomConfiguration.mods = [generateModConfig().generated, generateModConfig().generated];
+    const slowMod = generateRemoteModDetails().generated;
+    const fastMod = generateRemoteModDetails().generated;
+    const events: ProgressEvent[] = [];
+    setProgress({ report: (event) => events.push(event) });
+
+    let resolveSlowMod: (details: RemoteModDetails) => void = () => {};
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([]);
+    vi.mocked(fetchModDetails)
+      .mockReturnValueOnce(new Promise((resolve) => (resolveSlowMod = resolve)))
+      .mockResolvedValueOnce(fastMod);
+    vi.mocked(downloadFile).mockImplementation(async () => {
+      // The first mod only finishes after the second one
+      resolveSlowMod(slowMod);
+    });
+
+    await install(options, logger);
+
+    expect(events).toEqual([
+      { type: 'resolving', name: fastMod.name, current: 1, total: 2 },
+      { type: 'resolving', name: slowMod.name, current: 2, total: 2 }
+    ]);
+  });
+
   it<LocalTestContext>('downloads a missing mod', async ({ options, logger }) => {
     const { randomConfiguration, randomInstalledMod, randomInstallation } = setupOneInstalledMod();
+    randomInstallation.additionalFiles = [{ fileName: chance.word(), hash: chance.hash(), downloadUrl: chance.url() }];
 
     // Prepare the configuration file state
     vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
@@ -168,6 +471,15 @@ describe('The install module', () => {
     expect(vi.mocked(writeLockFile)).toHaveBeenCalledWith([randomInstallation], options, logger);
 
     expect(vi.mocked(downloadFile)).toHaveBeenCalledOnce();
+    expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+      randomInstallation.downloadUrl,
+      expect.any(String),
+      { expectedHash: randomInstallation.hash }
+    );
+    expect(vi.mocked(restoreAdditionalFiles)).toHaveBeenCalledWith(
+      randomInstallation.additionalFiles,
+      randomConfiguration.modsFolder
+    );
     expect(vi.mocked(fetchModDetails)).not.toHaveBeenCalled();
 
     verifyBasics();
@@ -201,6 +513,10 @@ describe('The install module', () => {
     expect(vi.mocked(writeLockFile)).toHaveBeenCalledWith([randomInstallation], options, logger);
 
     expect(vi.mocked(updateMod)).toHaveBeenCalledOnce();
+    expect(vi.mocked(restoreAdditionalFiles)).toHaveBeenCalledWith(
+      randomInstallation.additionalFiles,
+      randomConfiguration.modsFolder
+    );
     expect(vi.mocked(downloadFile)).not.toHaveBeenCalled();
     expect(vi.mocked(fetchModDetails)).not.toHaveBeenCalled();
 
@@ -310,6 +626,60 @@ describe('The install module', () => {
     verifyBasics();
   });
 
+  describe('when forced', () => {
+    const assumeUpToDateInstallation = () => {
+      const { randomInstalledMod, randomInstallation, randomConfiguration } = setupOneInstalledMod();
+
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+      vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+      vi.mocked(hasInstallation).mockReturnValueOnce(true);
+      vi.mocked(getInstallation).mockReturnValueOnce(0);
+      assumeModFileExists(randomInstallation.fileName);
+      vi.mocked(getHash).mockResolvedValueOnce(randomInstallation.hash);
+
+      return { randomInstalledMod: randomInstalledMod, randomInstallation: randomInstallation };
+    };
+
+    it<LocalTestContext>('downloads an up to date mod again', async ({ options, logger }) => {
+      const { randomInstalledMod, randomInstallation } = assumeUpToDateInstallation();
+      assumeSuccessfulDownload();
+
+      await install({ ...options, force: true }, logger);
+
+      expect(logger.log).toHaveBeenCalledWith(
+        `${randomInstalledMod.name} is up to date, downloading it again from ${randomInstallation.type}`
+      );
+      expect(vi.mocked(downloadFile)).toHaveBeenCalledOnce();
+      expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+        randomInstallation.downloadUrl,
+        expect.stringContaining(randomInstallation.fileName),
+        { expectedHash: randomInstallation.hash, skipCache: true }
+      );
+      expect(vi.mocked(restoreAdditionalFiles)).toHaveBeenCalledWith(
+        randomInstallation.additionalFiles,
+        expect.any(String),
+        true
+      );
+      expect(vi.mocked(fetchModDetails)).not.toHaveBeenCalled();
+      expect(vi.mocked(writeLockFile)).toHaveBeenCalledWith([randomInstallation], expect.anything(), logger);
+    });
+
+    it<LocalTestContext>('leaves an up to date mod alone without force', async ({ options, logger }) => {
+      const { randomInstallation } = assumeUpToDateInstallation();
+
+      await install({ ...options, force: false }, logger);
+
+      expect(vi.mocked(downloadFile)).not.toHaveBeenCalled();
+      expect(vi.mocked(updateMod)).not.toHaveBeenCalled();
+      expect(vi.mocked(restoreAdditionalFiles)).toHaveBeenCalledWith(
+        randomInstallation.additionalFiles,
+        expect.any(String),
+        false
+      );
+    });
+  });
+
   describe('when there are unknown files', () => {
     it<LocalTestContext>('checks if all files are managed', async ({ options, logger }) => {
       // Prepare the configuration file state
@@ -353,7 +723,7 @@ describe('The install module', () => {
 
       const scanResults = [generateScanResult().generated];
 
-      vi.mocked(scanFiles).mockResolvedValueOnce(scanResults); // not processing anything
+      vi.mocked(scanFiles).mockResolvedValueOnce({ results: scanResults, unmatched: [] }); // not processing anything
       vi.mocked(processScanResults).mockReturnValue({ unsure: [] } as never);
       // Run the install
       await install(options, logger);
@@ -376,7 +746,7 @@ describe('The install module', () => {
       vi.mocked(getModFiles).mockResolvedValueOnce([chance.word()]);
       vi.mocked(fileIsManaged).mockReturnValue(false); //don't care about the files
 
-      vi.mocked(scanFiles).mockResolvedValueOnce([]); // not processing anything
+      vi.mocked(scanFiles).mockResolvedValueOnce({ results: [], unmatched: [] }); // not processing anything
 
       const processResult = {
         unsure: [
@@ -398,6 +768,136 @@ describe('The install module', () => {
     });
   });
 
+  describe('when the lock is checked for drift', () => {
+    const assumeLockedMod = () => {
+      const { randomInstalledMod, randomInstallation, randomConfiguration } = setupOneInstalledMod();
+
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+      vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+      vi.mocked(hasInstallation).mockReturnValueOnce(true);
+      vi.mocked(getInstallation).mockReturnValueOnce(0);
+      assumeModFileExists(randomInstallation.fileName);
+      vi.mocked(getHash).mockResolvedValueOnce(randomInstallation.hash);
+
+      return {
+        randomInstalledMod: randomInstalledMod,
+        randomInstallation: randomInstallation,
+        randomConfiguration: randomConfiguration
+      };
+    };
+
+    it<LocalTestContext>('warns about a drifted entry and keeps the locked file', async ({ options, logger }) => {
+      const { randomInstalledMod, randomInstallation, randomConfiguration } = assumeLockedMod();
+      const remote = generateRemoteModDetails({ fileName: 'reuploaded.jar' }).generated;
+      vi.mocked(detectLockDrift).mockResolvedValueOnce({
+        drifted: [{ mod: randomInstalledMod, locked: randomInstallation, remote: remote }],
+        errors: []
+      });
+
+      await install({ ...options, checkDrift: true }, logger);
+
+      expect(vi.mocked(detectLockDrift)).toHaveBeenCalledWith(randomConfiguration, [randomInstallation]);
+      expect(logger.log).toHaveBeenCalledWith(
+        `\u26a0 ${randomInstalledMod.name} is locked to ${randomInstallation.fileName}, ` +
+          `but ${randomInstalledMod.type} serves reuploaded.jar with a different hash or download url now. ` +
+          'The locked file is installed, run mmm update to switch'
+      );
+      expect(vi.mocked(fetchModDetails)).not.toHaveBeenCalled();
+      expect(vi.mocked(writeLockFile)).toHaveBeenCalledWith([randomInstallation], expect.anything(), logger);
+    });
+
+    it<LocalTestContext>('tells about the mods that could not be checked', async ({ options, logger }) => {
+      const { randomInstalledMod } = assumeLockedMod();
+      vi.mocked(detectLockDrift).mockResolvedValueOnce({
+        drifted: [],
+        errors: [{ mod: randomInstalledMod, error: new Error('the platform is down') }]
+      });
+
+      await install({ ...options, checkDrift: true }, logger);
+
+      expect(logger.debug).toHaveBeenCalledWith(
+        `Could not check the lock of ${randomInstalledMod.name} against ${randomInstalledMod.type}: ` +
+          'the platform is down'
+      );
+    });
+
+    it<LocalTestContext>('does not check without being asked to', async ({ options, logger }) => {
+      assumeLockedMod();
+
+      await install(options, logger);
+
+      expect(vi.mocked(detectLockDrift)).not.toHaveBeenCalled();
+    });
+  });
+
+  describe('when running in offline mode', () => {
+    beforeEach(() => {
+      vi.mocked(isOfflineMode).mockReturnValue(true);
+    });
+
+    it<LocalTestContext>('installs the locked mods', async ({ options, logger }) => {
+      const { randomConfiguration, randomInstallation } = setupOneInstalledMod();
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+      vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+      vi.mocked(findModsUnavailableOffline).mockResolvedValueOnce([]);
+
+      vi.mocked(hasInstallation).mockReturnValueOnce(true);
+      vi.mocked(getInstallation).mockReturnValueOnce(0);
+      assumeModFileIsMissing(randomInstallation);
+
+      await install(options, logger);
+
+      expect(vi.mocked(findModsUnavailableOffline)).toHaveBeenCalledWith(
+        randomConfiguration,
+        [randomInstallation],
+        randomConfiguration.modsFolder
+      );
+      expect(vi.mocked(getModFiles)).not.toHaveBeenCalled();
+      expect(vi.mocked(scanFiles)).not.toHaveBeenCalled();
+      expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+        randomInstallation.downloadUrl,
+        expect.any(String),
+        { expectedHash: randomInstallation.hash }
+      );
+      expect(vi.mocked(fetchModDetails)).not.toHaveBeenCalled();
+    });
+
+    it<LocalTestContext>('does not check the lock for drift', async ({ options, logger }) => {
+      const { randomConfiguration, randomInstallation } = setupOneInstalledMod();
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+      vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+      vi.mocked(findModsUnavailableOffline).mockResolvedValueOnce([]);
+
+      await install({ ...options, checkDrift: true }, logger);
+
+      expect(vi.mocked(detectLockDrift)).not.toHaveBeenCalled();
+    });
+
+    it<LocalTestContext>('lists the mods that cannot be installed offline', async ({ options, logger }) => {
+      const first = generateModConfig().generated;
+      const second = generateModConfig().generated;
+      const randomConfiguration = generateModsJson({ mods: [first, second] }).generated;
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+      vi.mocked(findModsUnavailableOffline).mockResolvedValueOnce([first, second]);
+
+      await expect(install(options, logger)).rejects.toThrow('process.exit');
+
+      const [message, code] = vi.mocked(logger.error).mock.calls[0];
+      expect(message).toContain("The following mods can't be installed in offline mode:");
+      expect(message).toContain(`  - ${first.name}`);
+      expect(message).toContain(`  - ${second.name}`);
+      expect(message).toContain('Run mmm install without --offline to download them.');
+      expect(code).toEqual(1);
+      expect(vi.mocked(downloadFile)).not.toHaveBeenCalled();
+      expect(vi.mocked(writeLockFile)).not.toHaveBeenCalled();
+    });
+  });
+
   describe('when fetching a missing mod file fails', () => {
     it<LocalTestContext>('passes the correct error', async ({ options, logger }) => {
       const url = chance.url({ protocol: 'https' });
@@ -11,23 +11,55 @@ import {
   writeLockFile
 } from '../lib/config.js';
 import { fileIsManaged, getInstallation, hasInstallation } from '../lib/configurationHelper.js';
+import { findConflicts } from '../lib/conflicts.js';
 import { downloadFile } from '../lib/downloader.js';
+import { findDuplicateMods } from '../lib/duplicateMods.js';
 import { getModFiles } from '../lib/fileHelper.js';
+import { downloadFileName } from '../lib/fileNameTemplate.js';
+import { fileOverridesOf } from '../lib/fileOverrides.js';
 import { getHash } from '../lib/hash.js';
+import { acceptedLoaders } from '../lib/loaderCompatibility.js';
+import { detectLockDrift } from '../lib/lockDrift.js';
 import { Mod, ModInstall, ModsJson, Platform, RemoteModDetails } from '../lib/modlist.types.js';
+import { ensureModsFolder } from '../lib/modsFolder.js';
+import { isOfflineMode } from '../lib/offline.js';
+import { findModsUnavailableOffline } from '../lib/offlineResolution.js';
+import { getProgress } from '../lib/progress.js';
+import { allowedReleaseTypesOf, fallbackOf } from '../lib/releaseChannel.js';
 import { scanFiles } from '../lib/scan.js';
-import { updateMod } from '../lib/updater.js';
+import { UnsatisfiedReason, findUnsatisfiedDependencies } from '../lib/unsatisfiedDependencies.js';
+import { restoreAdditionalFiles, updateMod } from '../lib/updater.js';
 import { DefaultOptions, telemetry } from '../mmm.js';
 import { fetchModDetails } from '../repositories/index.js';
 import { processScanResults } from './scan.js';
 
+export interface InstallOptions extends DefaultOptions {
+  /**
+   * Downloads the locked files again, even the ones that are up to date
+   */
+  force?: boolean;
+  /**
+   * Tells about the locked files the platforms would serve differently now, the locked ones are still installed
+   */
+  checkDrift?: boolean;
+}
+
 const getMod = async (moddata: RemoteModDetails, modsFolder: string) => {
-  await downloadFile(moddata.downloadUrl, path.resolve(modsFolder, moddata.fileName));
+  await downloadFile(moddata.downloadUrl, path.resolve(modsFolder, moddata.fileName), {
+    expectedHash: moddata.hash,
+    expectedLength: moddata.fileLength
+  });
+  for (const additionalFile of moddata.additionalFiles || []) {
+    await downloadFile(additionalFile.downloadUrl, path.resolve(modsFolder, additionalFile.fileName), {
+      expectedHash: additionalFile.hash
+    });
+  }
   return {
     fileName: moddata.fileName,
     releasedOn: moddata.releaseDate,
     hash: moddata.hash,
-    downloadUrl: moddata.downloadUrl
+    downloadUrl: moddata.downloadUrl,
+    additionalFiles: moddata.additionalFiles
   };
 };
 
@@ -47,22 +79,119 @@ const handleUnknownFiles = async (
     return;
   }
 
-  const scanResults = await scanFiles(nonManagedFiles, installations, Platform.MODRINTH, configuration);
-  const { unsure } = processScanResults(scanResults, configuration, installations, logger);
+  const { results } = await scanFiles(nonManagedFiles, installations, Platform.MODRINTH, configuration);
+  const { unsure } = processScanResults(results, configuration, installations, logger);
 
   if (unsure.length > 0) {
     logger.error('\nPlease fix the unresolved issues above manually or by running mmm scan, then try again.', 1);
   }
 };
 
-export const install = async (options: DefaultOptions, logger: Logger) => {
+const ensureOfflineInstallIsPossible = async (
+  configuration: ModsJson,
+  installations: ModInstall[],
+  modsFolder: string,
+  logger: Logger
+) => {
+  const unavailable = await findModsUnavailableOffline(configuration, installations, modsFolder);
+
+  if (unavailable.length === 0) {
+    return;
+  }
+
+  const list = unavailable.map((mod) => `  - ${mod.name}${chalk.gray('(' + mod.id + ')')} from ${mod.type}`).join('\n');
+  logger.error(
+    `The following mods can't be installed in offline mode:\n${list}\n\nRun mmm install without --offline to download them.`,
+    1
+  );
+};
+
+const warnAboutDuplicateMods = (configuration: ModsJson, installations: ModInstall[], logger: Logger) => {
+  findDuplicateMods(configuration, installations).forEach(({ mods: [mod, other] }) => {
+    logger.log(
+      `${chalk.yellow('\u26a0')} ${mod.name} (${mod.type}) and ${other.name} (${other.type}) look like the same mod, keep only one of them`
+    );
+  });
+};
+
+const warnAboutLockDrift = async (configuration: ModsJson, installations: ModInstall[], logger: Logger) => {
+  const { drifted, errors } = await detectLockDrift(configuration, installations);
+
+  drifted.forEach(({ mod, locked, remote }) => {
+    logger.log(
+      `${chalk.yellow('\u26a0')} ${mod.name} is locked to ${locked.fileName}, but ${mod.type} serves ` +
+        `${remote.fileName} with a different hash or download url now. The locked file is installed, run mmm update ` +
+        'to switch'
+    );
+  });
+  errors.forEach(({ mod, error }) => {
+    logger.debug(`Could not check the lock of ${mod.name} against ${mod.type}: ${(error as Error).message}`);
+  });
+};
+
+/**
+ * Tells about the installed mods that won't load, because a mod they require isn't in the modlist or couldn't be
+ * installed
+ */
+export const warnAboutUnsatisfiedDependencies = (
+  configuration: ModsJson,
+  installations: ModInstall[],
+  logger: Logger
+) => {
+  findUnsatisfiedDependencies(configuration, installations).forEach(({ mod, dependency, reason, provider }) => {
+    if (reason === UnsatisfiedReason.MISSING) {
+      logger.log(
+        `${chalk.yellow('\u26a0')} ${mod.name} needs ${dependency.id} from ${dependency.type}, ` +
+          "which isn't in the modlist. Add it, or give the mod that should provide it a fallback on that platform"
+      );
+      return;
+    }
+    logger.log(
+      `${chalk.yellow('\u26a0')} ${mod.name} needs ${provider?.name}, which couldn't be installed for Minecraft ` +
+        configuration.gameVersion
+    );
+  });
+};
+
+/**
+ * Tells about the installed mods that won't boot together, because the file of one of them says so
+ */
+export const warnAboutConflicts = (configuration: ModsJson, installations: ModInstall[], logger: Logger) => {
+  findConflicts(configuration, installations).forEach(({ mod, conflictsWith }) => {
+    logger.log(
+      `${chalk.yellow('\u26a0')} ${mod.name} is incompatible with ${conflictsWith.name}, keep only one of them`
+    );
+  });
+};
+
+/**
+ * @param checkDependencies Whether to warn about the unsatisfied dependencies and the conflicts, update checks them
+ * itself once the mods are updated
+ */
+export const install = async (options: InstallOptions, logger: Logger, checkDependencies = true) => {
   performance.mark('install-start');
   const configuration = await ensureConfiguration(options.config, logger);
   const installations = await readLockFile(options, logger);
-  await handleUnknownFiles(options, configuration, installations, logger);
+  const modsFolder = getModsFolder(options.config, configuration);
+  await ensureModsFolder(modsFolder, logger);
+
+  warnAboutDuplicateMods(configuration, installations, logger);
+
+  if (isOfflineMode()) {
+    // Identifying the unknown files needs the platforms, so they are left alone
+    await ensureOfflineInstallIsPossible(configuration, installations, modsFolder, logger);
+  } else {
+    await handleUnknownFiles(options, configuration, installations, logger);
+    if (options.checkDrift) {
+      await warnAboutLockDrift(configuration, installations, logger);
+    }
+  }
+
   const installedMods = installations;
   const mods = configuration.mods;
-  const modsFolder = getModsFolder(options.config, configuration);
+  const takenFileNames = new Set(installations.map((installation) => installation.fileName));
+
+  let resolvedMods = 0;
 
   const processMod = async (mod: Mod, index: number) => {
     const canonVersion = mod.version || 'latest';
@@ -79,7 +208,10 @@ export const install = async (options: DefaultOptions, logger: Logger) => {
 
         if (!(await fileExists(modPath))) {
           logger.log(`${mod.name} doesn't exist, downloading from ${installedMods[installedModIndex].type}`);
-          await downloadFile(installedMods[installedModIndex].downloadUrl, modPath);
+          await downloadFile(installedMods[installedModIndex].downloadUrl, modPath, {
+            expectedHash: installedMods[installedModIndex].hash
+          });
+          await restoreAdditionalFiles(installedMods[installedModIndex].additionalFiles, modsFolder);
           return;
         }
 
@@ -87,26 +219,43 @@ export const install = async (options: DefaultOptions, logger: Logger) => {
         if (installedMods[installedModIndex].hash !== installedHash) {
           logger.log(`${mod.name} has hash mismatch, downloading from source`);
           await updateMod(installedMods[installedModIndex], modPath, modsFolder);
+          await restoreAdditionalFiles(installedMods[installedModIndex].additionalFiles, modsFolder);
           return;
         }
+
+        if (options.force) {
+          // The locked file is downloaded again so the version stays the same
+          logger.log(`${mod.name} is up to date, downloading it again from ${installedMods[installedModIndex].type}`);
+          await downloadFile(installedMods[installedModIndex].downloadUrl, modPath, {
+            expectedHash: installedMods[installedModIndex].hash,
+            skipCache: true
+          });
+        }
+        await restoreAdditionalFiles(installedMods[installedModIndex].additionalFiles, modsFolder, options.force);
         return;
       }
 
       const modData = await fetchModDetails(
         mod.type,
         mod.id,
-        mod.allowedReleaseTypes || configuration.defaultAllowedReleaseTypes,
+        allowedReleaseTypesOf(mod, configuration),
         configuration.gameVersion,
-        configuration.loader,
+        acceptedLoaders(mod, configuration),
         !!mod.allowVersionFallback,
-        mod.version
+        mod.version,
+        fallbackOf(mod, configuration),
+        fileOverridesOf(mod)
       );
 
       mods[index].name = modData.name;
 
       // no installation exists
       logger.log(`${mod.name} doesn't exist, downloading from ${mod.type}`);
-      const dlData = await getMod(modData, modsFolder);
+      if (modData.loader) {
+        logger.log(`${mod.name} uses the ${modData.loader} file`);
+      }
+      const fileName = downloadFileName(modData, { mod: mod, configuration: configuration, taken: takenFileNames });
+      const dlData = await getMod({ ...modData, fileName: fileName }, modsFolder);
 
       installedMods.push({
         name: modData.name,
@@ -115,11 +264,18 @@ export const install = async (options: DefaultOptions, logger: Logger) => {
         fileName: dlData.fileName,
         releasedOn: dlData.releasedOn,
         hash: dlData.hash,
-        downloadUrl: dlData.downloadUrl
+        downloadUrl: dlData.downloadUrl,
+        additionalFiles: dlData.additionalFiles,
+        dependencies: modData.dependencies,
+        incompatibilities: modData.incompatibilities
       });
       return;
     } catch (error) {
       handleFetchErrors(error as Error, mod, logger);
+    } finally {
+      // The mods resolve at the same time, so the count is of the ones that are done
+      resolvedMods++;
+      getProgress().report({ type: 'resolving', name: mod.name, current: resolvedMods, total: mods.length });
     }
   };
 
@@ -127,6 +283,11 @@ export const install = async (options: DefaultOptions, logger: Logger) => {
 
   await Promise.all(promises);
 
+  if (checkDependencies) {
+    warnAboutUnsatisfiedDependencies(configuration, installedMods, logger);
+    warnAboutConflicts(configuration, installedMods, logger);
+  }
+
   await writeLockFile(installedMods, options, logger);
   await writeConfigFile(configuration, options, logger);
   logger.log(`${chalk.green('\u2705')} all mods are installed!`);
@@ -3,13 +3,17 @@ import { generateModConfig } from '../../test/modConfigGenerator.js';
 import { generateModInstall } from '../../test/modInstallGenerator.js';
 import { generateModsJson } from '../../test/modlistGenerator.js';
 import { expectCommandStartTelemetry } from '../../test/telemetryHelper.js';
+import { CouldNotFindModException } from '../errors/CouldNotFindModException.js';
 import { Logger } from '../lib/Logger.js';
 import { ensureConfiguration, readLockFile } from '../lib/config.js';
+import { Platform } from '../lib/modlist.types.js';
+import { PlannedChange, PlannedChangeType, planUpdate } from '../lib/updatePlan.js';
 import { DefaultOptions } from '../mmm.js';
 import { list } from './list.js';
 
 vi.mock('../lib/Logger.js');
 vi.mock('../lib/config.js');
+vi.mock('../lib/updatePlan.js');
 vi.mock('../mmm.js');
 
 interface LocalTestContext {
@@ -89,6 +93,87 @@ describe('The list action', async () => {
     });
   });
 
+  describe('when asked for JSON', () => {
+    const change = (overrides: Partial<PlannedChange>): PlannedChange => ({
+      id: 'sodium',
+      name: 'Sodium',
+      platform: Platform.MODRINTH,
+      type: PlannedChangeType.NONE,
+      currentFileName: 'sodium-0.5.8.jar',
+      currentReleaseDate: '2024-02-01T00:00:00Z',
+      newFileName: 'sodium-0.5.8.jar',
+      newReleaseDate: '2024-02-01T00:00:00Z',
+      ...overrides
+    });
+
+    beforeEach(() => {
+      vi.mocked(ensureConfiguration).mockResolvedValue(generateModsJson().generated);
+      vi.mocked(readLockFile).mockResolvedValue([]);
+    });
+
+    it<LocalTestContext>('prints a line for every mod sorted by name', async ({ options, logger }) => {
+      vi.mocked(planUpdate).mockResolvedValueOnce({
+        changes: [
+          change({}),
+          change({
+            id: '238222',
+            name: 'Jade',
+            platform: Platform.CURSEFORGE,
+            type: PlannedChangeType.UPGRADE,
+            currentFileName: 'jade-1.0.jar',
+            newFileName: 'jade-1.1.jar'
+          })
+        ],
+        errors: []
+      });
+
+      await list({ ...options, json: true }, logger);
+
+      expect(logger.log).toHaveBeenCalledOnce();
+      expect(logger.log).toHaveBeenCalledWith(
+        '{"project":"238222","name":"Jade","platform":"curseforge","installedVersion":"jade-1.0.jar",' +
+          '"latestVersion":"jade-1.1.jar","updateAvailable":true}\n' +
+          '{"project":"sodium","name":"Sodium","platform":"modrinth","installedVersion":"sodium-0.5.8.jar",' +
+          '"latestVersion":"sodium-0.5.8.jar","updateAvailable":false}',
+        true
+      );
+    });
+
+    it<LocalTestContext>('prints nothing when there are no mods', async ({ options, logger }) => {
+      vi.mocked(planUpdate).mockResolvedValueOnce({ changes: [], errors: [] });
+
+      await list({ ...options, json: true }, logger);
+
+      expect(logger.log).not.toHaveBeenCalled();
+    });
+
+    it<LocalTestContext>('reports the mods that could not be resolved at the end', async ({ options, logger }) => {
+      const mod = generateModConfig({ name: 'Gone', id: 'gone', type: Platform.MODRINTH }).generated;
+      vi.mocked(planUpdate).mockResolvedValueOnce({
+        changes: [change({})],
+        errors: [{ mod: mod, error: new CouldNotFindModException(mod.id, mod.type) }]
+      });
+
+      await expect(list({ ...options, json: true }, logger)).rejects.toThrow('process.exit');
+
+      expect(logger.log).toHaveBeenCalledOnce();
+      expect(logger.error).toHaveBeenCalledWith(
+        'Gone(gone) cannot be found on modrinth anymore. Was the mod revoked?',
+        1
+      );
+    });
+
+    it<LocalTestContext>('throws the unexpected errors on', async ({ options, logger }) => {
+      const error = new Error('something went wrong');
+      vi.mocked(planUpdate).mockResolvedValueOnce({
+        changes: [],
+        errors: [{ mod: generateModConfig().generated, error: error }]
+      });
+
+      await expect(list({ ...options, json: true }, logger)).rejects.toThrow(error);
+    });
+  });
+
   it<LocalTestContext>('calls the correct telemetry', async ({ options, logger }) => {
     const randomConfig = generateModsJson().generated;
     vi.mocked(ensureConfiguration).mockResolvedValue(randomConfig);
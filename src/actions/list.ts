@@ -1,16 +1,44 @@
 import chalk from 'chalk';
+import { describeFetchError } from '../errors/handleFetchErrors.js';
 import { Logger } from '../lib/Logger.js';
 import { ensureConfiguration, readLockFile } from '../lib/config.js';
-import { Mod } from '../lib/modlist.types.js';
+import { toJsonLines } from '../lib/jsonLines.js';
+import { Mod, ModInstall, ModsJson } from '../lib/modlist.types.js';
+import { planUpdate } from '../lib/updatePlan.js';
 import { DefaultOptions, telemetry } from '../mmm.js';
 
-export type ListOptions = DefaultOptions;
+export interface ListOptions extends DefaultOptions {
+  /**
+   * Prints a line of JSON for every mod, with the version the platform offers, instead of the human readable list
+   */
+  json?: boolean;
+}
 
-export const list = async (options: ListOptions, logger: Logger) => {
-  performance.mark('list-start');
-  const config = await ensureConfiguration(options.config, logger);
-  const installed = await readLockFile(options, logger);
+/**
+ * Only the JSON goes to the standard output so it can be piped, the mods that couldn't be resolved are reported on the
+ * standard error at the end
+ */
+const listAsJsonLines = async (config: ModsJson, installed: ModInstall[], logger: Logger) => {
+  const plan = await planUpdate(config, installed);
+  const lines = toJsonLines(plan.changes.sort((a, b) => a.name.localeCompare(b.name)));
+
+  if (lines) {
+    logger.log(lines.trimEnd(), true);
+  }
+
+  if (plan.errors.length > 0) {
+    const messages = plan.errors.map(({ mod, error }) => {
+      const description = describeFetchError(error as Error, mod);
+      if (!description) {
+        throw error;
+      }
+      return description;
+    });
+    logger.error(messages.join('\n'), 1);
+  }
+};
 
+const listMods = (config: ModsJson, installed: ModInstall[], logger: Logger) => {
   logger.log(chalk.green('Configured mods'), true);
 
   const sortByName = (a: Mod, b: Mod) => {
@@ -30,6 +58,18 @@ export const list = async (options: ListOptions, logger: Logger) => {
       );
     }
   });
+};
+
+export const list = async (options: ListOptions, logger: Logger) => {
+  performance.mark('list-start');
+  const config = await ensureConfiguration(options.config, logger);
+  const installed = await readLockFile(options, logger);
+
+  if (options.json) {
+    await listAsJsonLines(config, installed, logger);
+  } else {
+    listMods(config, installed, logger);
+  }
 
   performance.mark('list-succeed');
 
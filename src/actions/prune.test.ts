@@ -10,6 +10,7 @@ import { ensureConfiguration, getModsFolder, readLockFile } from '../lib/config.
 import { fileIsManaged } from '../lib/configurationHelper.js';
 import { getModFiles } from '../lib/fileHelper.js';
 import { ModInstall, ModsJson } from '../lib/modlist.types.js';
+import { findOrphanedFiles } from '../lib/orphanedFiles.js';
 import { PruneOptions, prune } from './prune.js';
 
 interface LocalTestContext {
@@ -24,6 +25,7 @@ vi.mock('../lib/Logger.js');
 vi.mock('../lib/configurationHelper.js');
 vi.mock('../interactions/shouldPruneFiles.js');
 vi.mock('../lib/fileHelper.js');
+vi.mock('../lib/orphanedFiles.js');
 vi.mock('fs/promises');
 vi.mock('../mmm.js');
 
@@ -43,6 +45,7 @@ describe('The prune action', () => {
     vi.mocked(ensureConfiguration).mockResolvedValueOnce(context.configuration);
     vi.mocked(readLockFile).mockResolvedValueOnce(context.installations);
     vi.mocked(getModsFolder).mockReturnValue(context.configuration.modsFolder);
+    vi.mocked(findOrphanedFiles).mockImplementation(async (files: string[]) => ({ orphaned: files, unknown: [] }));
   });
 
   it<LocalTestContext>('notifies about no files in the mods folder', async ({ options, logger }) => {
@@ -155,4 +158,72 @@ describe('The prune action', () => {
       }
     });
   });
+
+  describe('when the folder has current, old and unknown files', () => {
+    const currentFile = 'current.jar';
+    const oldFile = 'old.jar';
+    const unknownFile = 'manual.jar';
+
+    beforeEach(() => {
+      vi.mocked(getModFiles).mockResolvedValueOnce([currentFile, oldFile, unknownFile]);
+      vi.mocked(fileIsManaged).mockImplementation((file: string) => file === currentFile);
+      vi.mocked(findOrphanedFiles).mockReset();
+      vi.mocked(findOrphanedFiles).mockResolvedValueOnce({ orphaned: [oldFile], unknown: [unknownFile] });
+      vi.mocked(shouldPruneFiles).mockResolvedValueOnce(true);
+    });
+
+    it<LocalTestContext>('only deletes the old files of the managed mods', async ({ options, logger, configuration }) => {
+      await prune(options, logger);
+
+      expect(vi.mocked(findOrphanedFiles)).toHaveBeenCalledWith([oldFile, unknownFile], configuration);
+      expect(fs.rm).toHaveBeenCalledOnce();
+      expect(fs.rm).toHaveBeenCalledWith(path.resolve(configuration.modsFolder, oldFile), { force: true });
+      expect(vi.mocked(logger.log).mock.calls[0][0]).toContain('will be kept, use --all to prune them too');
+      expect(vi.mocked(logger.log).mock.calls[1][0]).toContain(unknownFile);
+    });
+
+    it<LocalTestContext>('deletes every unmanaged file when asked to', async ({ options, logger, configuration }) => {
+      options.all = true;
+
+      await prune(options, logger);
+
+      expect(vi.mocked(findOrphanedFiles)).not.toHaveBeenCalled();
+      expect(fs.rm).toHaveBeenCalledTimes(2);
+      expect(fs.rm).toHaveBeenNthCalledWith(1, path.resolve(configuration.modsFolder, oldFile), { force: true });
+      expect(fs.rm).toHaveBeenNthCalledWith(2, path.resolve(configuration.modsFolder, unknownFile), { force: true });
+    });
+
+    it<LocalTestContext>('only lists the files in dry-run mode', async ({ options, logger }) => {
+      options.dryRun = true;
+
+      await prune(options, logger);
+
+      expect(fs.rm).not.toHaveBeenCalled();
+      expect(vi.mocked(shouldPruneFiles)).not.toHaveBeenCalled();
+      expect(logger.log).toHaveBeenCalledWith(expect.stringContaining(oldFile));
+      expect(logger.log).toHaveBeenCalledWith(expect.stringContaining('Nothing has been deleted.'));
+      expectCommandStartTelemetry({
+        command: 'prune',
+        success: true,
+        duration: expect.any(Number),
+        arguments: {
+          options: options
+        }
+      });
+    });
+  });
+
+  it<LocalTestContext>('keeps the unknown files when there are no old ones', async ({ options, logger }) => {
+    const unknownFile = chance.word();
+    vi.mocked(getModFiles).mockResolvedValueOnce([unknownFile]);
+    vi.mocked(fileIsManaged).mockReturnValue(false);
+    vi.mocked(findOrphanedFiles).mockReset();
+    vi.mocked(findOrphanedFiles).mockResolvedValueOnce({ orphaned: [], unknown: [unknownFile] });
+
+    await prune(options, logger);
+
+    expect(fs.rm).not.toHaveBeenCalled();
+    expect(vi.mocked(shouldPruneFiles)).not.toHaveBeenCalled();
+    expect(logger.log).toHaveBeenLastCalledWith('You have no leftover files of your mods in your mods folder.');
+  });
 });
@@ -6,12 +6,27 @@ import { Logger } from '../lib/Logger.js';
 import { ensureConfiguration, getModsFolder, readLockFile } from '../lib/config.js';
 import { fileIsManaged } from '../lib/configurationHelper.js';
 import { getModFiles } from '../lib/fileHelper.js';
+import { findOrphanedFiles } from '../lib/orphanedFiles.js';
 import { DefaultOptions, telemetry } from '../mmm.js';
 
 export interface PruneOptions extends DefaultOptions {
   force: boolean;
+  all?: boolean;
+  dryRun?: boolean;
 }
 
+const capturePrune = async (options: PruneOptions, endMark: string) => {
+  performance.mark(endMark);
+  await telemetry.captureCommand({
+    command: 'prune',
+    success: true,
+    arguments: {
+      options: options
+    },
+    duration: performance.measure('prune-duration', 'prune-start', endMark).duration
+  });
+};
+
 export const prune = async (options: PruneOptions, logger: Logger) => {
   performance.mark('prune-start');
   const configuration = await ensureConfiguration(options.config, logger);
@@ -34,38 +49,46 @@ export const prune = async (options: PruneOptions, logger: Logger) => {
     return;
   }
 
+  let toPrune = unmanaged;
+
+  if (!options.all) {
+    const { orphaned, unknown } = await findOrphanedFiles(unmanaged, configuration);
+    toPrune = orphaned;
+
+    if (unknown.length > 0) {
+      logger.log('The following files are not managed by mmm and will be kept, use --all to prune them too:');
+      for (const file of unknown) {
+        logger.log(`${chalk.yellow('\u2714')} ${file}`);
+      }
+    }
+
+    if (toPrune.length === 0) {
+      logger.log('You have no leftover files of your mods in your mods folder.');
+      return;
+    }
+  }
+
   logger.log('The following files are unmanaged:');
-  for (const file of unmanaged) {
+  for (const file of toPrune) {
     logger.log(`${chalk.red('\u274c')} ${file}`);
   }
 
+  if (options.dryRun) {
+    logger.log(chalk.yellow('Running in dry-run mode. Nothing has been deleted.'));
+    await capturePrune(options, 'prune-dry-run');
+    return;
+  }
+
   if (!(await shouldPruneFiles(options, logger))) {
-    performance.mark('prune-cancelled');
-    await telemetry.captureCommand({
-      command: 'prune',
-      success: true,
-      arguments: {
-        options: options
-      },
-      duration: performance.measure('prune-duration', 'prune-start', 'prune-cancelled').duration
-    });
+    await capturePrune(options, 'prune-cancelled');
     return;
   }
 
-  for (const file of unmanaged) {
+  for (const file of toPrune) {
     const filePath = path.resolve(modsFolder, file);
     await fs.rm(filePath, { force: true });
     logger.log(`Deleted: ${filePath}`);
   }
 
-  performance.mark('prune-succeed');
-
-  await telemetry.captureCommand({
-    command: 'prune',
-    success: true,
-    arguments: {
-      options: options
-    },
-    duration: performance.measure('prune-duration', 'prune-start', 'prune-succeed').duration
-  });
+  await capturePrune(options, 'prune-succeed');
 };
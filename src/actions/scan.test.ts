@@ -1,11 +1,14 @@
 import path from 'path';
 import { chance } from 'jest-chance';
 import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateCurseforgeModFile } from '../../test/generateCurseforgeModFile.js';
+import { generatePlatformLookupResult } from '../../test/generatePlatformLookupResult.js';
 import { ScanResultGeneratorOverrides, generateScanResult } from '../../test/generateScanResult.js';
 import { generateModConfig } from '../../test/modConfigGenerator.js';
 import { generateModInstall } from '../../test/modInstallGenerator.js';
 import { generateModsJson } from '../../test/modlistGenerator.js';
 import { expectCommandStartTelemetry } from '../../test/telemetryHelper.js';
+import { LockFileCorruptedException } from '../errors/LockFileCorruptedException.js';
 import { shouldAddScanResults } from '../interactions/shouldAddScanResults.js';
 import { Logger } from '../lib/Logger.js';
 import { ensureConfiguration, getModsFolder, readLockFile, writeConfigFile, writeLockFile } from '../lib/config.js';
@@ -69,8 +72,41 @@ describe('The Scan action', () => {
     });
   });
 
+  describe('when the lock file is corrupted', () => {
+    beforeEach(() => {
+      vi.mocked(readLockFile).mockReset();
+    });
+
+    it<LocalTestContext>('rebuilds it from the mods folder', async ({ options, logger }) => {
+      const randomResult = generateScanResult().generated;
+      vi.mocked(readLockFile).mockRejectedValueOnce(new LockFileCorruptedException('config-lock.json'));
+      vi.mocked(scanLib).mockResolvedValueOnce({ results: [randomResult], unmatched: [] });
+      vi.mocked(shouldAddScanResults).mockResolvedValueOnce(true);
+
+      await scan(options, logger);
+
+      expect(vi.mocked(readLockFile)).toHaveBeenCalledWith(options, logger, true);
+      expect(vi.mocked(logger.log)).toHaveBeenCalledWith(
+        '\u274c The lock file is corrupted, scanning the mods folder to rebuild it'
+      );
+      expect(vi.mocked(writeLockFile).mock.calls[0][0]).toEqual([
+        expect.objectContaining({
+          id: randomResult.matches[0].modId,
+          fileName: randomResult.matches[0].local.fileName
+        })
+      ]);
+    });
+
+    it<LocalTestContext>('still fails on the other errors', async ({ options, logger }) => {
+      vi.mocked(readLockFile).mockRejectedValueOnce(new Error('permission denied'));
+
+      await expect(scan(options, logger)).rejects.toThrow('permission denied');
+      expect(vi.mocked(scanLib)).not.toHaveBeenCalled();
+    });
+  });
+
   it<LocalTestContext>('correctly reports when there are no unmanaged mods', async ({ options, logger }) => {
-    vi.mocked(scanLib).mockResolvedValueOnce([]);
+    vi.mocked(scanLib).mockResolvedValueOnce({ results: [], unmatched: [] });
 
     options.quiet = false;
     await scan(options, logger);
@@ -85,7 +121,7 @@ describe('The Scan action', () => {
     const name = chance.word();
     const randomResult = generateScanResult({ name: name }).generated;
 
-    vi.mocked(scanLib).mockResolvedValueOnce([randomResult]);
+    vi.mocked(scanLib).mockResolvedValueOnce({ results: [randomResult], unmatched: [] });
     vi.mocked(shouldAddScanResults).mockResolvedValueOnce(false);
 
     await scan(options, logger);
@@ -105,7 +141,7 @@ describe('The Scan action', () => {
       results.push(generateScanResult().generated);
     }
 
-    vi.mocked(scanLib).mockResolvedValueOnce(results);
+    vi.mocked(scanLib).mockResolvedValueOnce({ results: results, unmatched: [] });
     vi.mocked(shouldAddScanResults).mockResolvedValueOnce(false);
 
     await scan(options, logger);
@@ -142,7 +178,7 @@ describe('The Scan action', () => {
     const details3 = randomModDetails();
     const randomResult3 = generateScanResult(details3).generated;
 
-    vi.mocked(scanLib).mockResolvedValueOnce([randomResult1, randomResult2, randomResult3]);
+    vi.mocked(scanLib).mockResolvedValueOnce({ results: [randomResult1, randomResult2, randomResult3], unmatched: [] });
     vi.mocked(shouldAddScanResults).mockResolvedValueOnce(true);
 
     await scan(context.options, context.logger);
@@ -200,13 +236,57 @@ describe('The Scan action', () => {
     });
   });
 
+  it<LocalTestContext>('adds the mods of a mixed scan from the platform of their match', async (context) => {
+    const curseforgeResult = generateScanResult({ platform: Platform.CURSEFORGE }).generated;
+    const modrinthResult = generateScanResult({ platform: Platform.MODRINTH }).generated;
+    // Modrinth knows the first file too, but its details couldn't be fetched
+    curseforgeResult.localDetails.unshift(generatePlatformLookupResult({ platform: Platform.MODRINTH }).generated);
+
+    vi.mocked(scanLib).mockResolvedValueOnce({ results: [curseforgeResult, modrinthResult], unmatched: [] });
+    vi.mocked(shouldAddScanResults).mockResolvedValueOnce(true);
+
+    await scan(context.options, context.logger);
+
+    const writtenConfig = vi.mocked(writeConfigFile).mock.calls[0][0];
+    expect(writtenConfig.mods).toContainEqual({
+      name: curseforgeResult.matches[0].remote.name,
+      id: curseforgeResult.matches[0].modId,
+      type: Platform.CURSEFORGE
+    });
+    expect(writtenConfig.mods).toContainEqual({
+      name: modrinthResult.matches[0].remote.name,
+      id: modrinthResult.matches[0].modId,
+      type: Platform.MODRINTH
+    });
+    expect(vi.mocked(writeLockFile).mock.calls[0][0]).toContainEqual(
+      expect.objectContaining({ type: Platform.CURSEFORGE, hash: curseforgeResult.matches[0].local.hash })
+    );
+  });
+
+  it<LocalTestContext>('does not add a file that no platform could resolve', async (context) => {
+    const unresolved = generateScanResult().generated;
+    unresolved.matches = [];
+    const modCount = context.randomConfiguration.mods.length;
+
+    vi.mocked(scanLib).mockResolvedValueOnce({ results: [unresolved], unmatched: [] });
+    vi.mocked(shouldAddScanResults).mockResolvedValueOnce(true);
+
+    await scan(context.options, context.logger);
+
+    expect(vi.mocked(writeConfigFile).mock.calls[0][0].mods.length).toEqual(modCount);
+    expect(vi.mocked(context.logger.log)).not.toHaveBeenCalledWith(
+      expect.stringContaining('Found unmanaged mod: '),
+      true
+    );
+  });
+
   describe('when there are unrecognizable files in the mods folder', () => {
     beforeEach(() => {
       vi.mocked(getModFiles).mockReset();
     });
     it<LocalTestContext>('logs things correctly no scan results but foreign files', async ({ options, logger }) => {
       const randomModName = chance.word();
-      vi.mocked(scanLib).mockResolvedValueOnce([]);
+      vi.mocked(scanLib).mockResolvedValueOnce({ results: [], unmatched: [] });
       vi.mocked(getModFiles).mockResolvedValueOnce(['first-bad-mod-x', 'second-bad-mod-y', randomModName]);
 
       await scan(options, logger);
@@ -225,7 +305,10 @@ describe('The Scan action', () => {
 
     it<LocalTestContext>('logs things correctly with scan results and foreign files', async ({ options, logger }) => {
       const randomModName = chance.word();
-      vi.mocked(scanLib).mockResolvedValueOnce([generateScanResult({ name: 'hi there' }).generated]);
+      vi.mocked(scanLib).mockResolvedValueOnce({
+        results: [generateScanResult({ name: 'hi there' }).generated],
+        unmatched: []
+      });
       vi.mocked(shouldAddScanResults).mockResolvedValueOnce(false);
       vi.mocked(getModFiles).mockResolvedValueOnce(['first-bad-mod', 'second-bad-mod', randomModName]);
 
@@ -242,6 +325,47 @@ describe('The Scan action', () => {
       expect(logCalls[3][0]).toMatchInlineSnapshot('"  ❌ second-bad-mod"');
       expect(logCalls[4][0]).toContain(randomModName);
     });
+
+    it<LocalTestContext>('shows the fingerprints of the files no platform knows', async ({ options, logger }) => {
+      vi.mocked(scanLib).mockResolvedValueOnce({
+        results: [],
+        unmatched: [{ fingerprint: '1234', files: ['unknown-mod', 'copy-of-unknown-mod'] }]
+      });
+      vi.mocked(getModFiles).mockResolvedValueOnce(['unknown-mod', 'copy-of-unknown-mod', 'unreadable-mod']);
+
+      await scan(options, logger);
+      const logCalls = vi.mocked(logger.log).mock.calls;
+
+      expect(logCalls[2][0]).toMatchInlineSnapshot('"  ❌ unknown-mod (fingerprint 1234)"');
+      expect(logCalls[3][0]).toMatchInlineSnapshot('"  ❌ copy-of-unknown-mod (fingerprint 1234)"');
+      expect(logCalls[4][0]).toMatchInlineSnapshot('"  ❌ unreadable-mod"');
+    });
+
+    it<LocalTestContext>('shows the Curseforge file a partially matched file probably is', async ({
+      options,
+      logger
+    }) => {
+      const guessedFile = generateCurseforgeModFile({ fileName: 'sodium-fabric-0.5.3.jar' }).generated;
+      vi.mocked(scanLib).mockResolvedValueOnce({
+        results: [],
+        unmatched: [
+          {
+            fingerprint: '1234',
+            files: ['sodium-repacked'],
+            guess: { fingerprint: 1234, modId: '394468', file: guessedFile, confidence: 0.873 }
+          }
+        ]
+      });
+      vi.mocked(getModFiles).mockResolvedValueOnce(['sodium-repacked']);
+
+      await scan(options, logger);
+      const logCalls = vi.mocked(logger.log).mock.calls;
+
+      expect(logCalls[2][0]).toMatchInlineSnapshot('"  ❌ sodium-repacked (fingerprint 1234)"');
+      expect(logCalls[3][0]).toMatchInlineSnapshot(
+        '"     probably sodium-fabric-0.5.3.jar of the Curseforge project 394468, 87% sure"'
+      );
+    });
   });
 
   describe('when there are half-state files in the mods folder', () => {
@@ -289,7 +413,10 @@ describe('The Scan action', () => {
       vi.mocked(readLockFile).mockResolvedValue([mod1Install, mod2Install, mod3Install]);
       scanResults4.localDetails[0].mod.fileName = 'mod4-local-filename';
 
-      vi.mocked(scanLib).mockResolvedValueOnce([scanResults1, scanResults2, scanResults3, scanResults4]);
+      vi.mocked(scanLib).mockResolvedValueOnce({
+        results: [scanResults1, scanResults2, scanResults3, scanResults4],
+        unmatched: []
+      });
       vi.mocked(shouldAddScanResults).mockResolvedValueOnce(false);
       vi.mocked(getModFiles).mockResolvedValueOnce([
         path.resolve(modsDir, mod1Install.fileName),
@@ -343,7 +470,7 @@ describe('The Scan action', () => {
        */
       vi.mocked(readLockFile).mockResolvedValue([mod2Install]);
 
-      vi.mocked(scanLib).mockResolvedValueOnce([scanResults1, scanResults2]);
+      vi.mocked(scanLib).mockResolvedValueOnce({ results: [scanResults1, scanResults2], unmatched: [] });
       vi.mocked(shouldAddScanResults).mockResolvedValueOnce(true);
       vi.mocked(getModFiles).mockResolvedValueOnce([
         path.resolve(modsDir, mod1Install.fileName),
@@ -373,7 +500,7 @@ describe('The Scan action', () => {
   });
 
   it<LocalTestContext>('calls the correct telemetry', async ({ options, logger }) => {
-    vi.mocked(scanLib).mockResolvedValueOnce([]);
+    vi.mocked(scanLib).mockResolvedValueOnce({ results: [], unmatched: [] });
 
     options.quiet = false;
     await scan(options, logger);
@@ -1,9 +1,10 @@
 import chalk from 'chalk';
+import { LockFileCorruptedException } from '../errors/LockFileCorruptedException.js';
 import { shouldAddScanResults } from '../interactions/shouldAddScanResults.js';
 import { Logger } from '../lib/Logger.js';
 import { ensureConfiguration, getModsFolder, readLockFile, writeConfigFile, writeLockFile } from '../lib/config.js';
 import { Mod, ModInstall, ModsJson, Platform, RemoteModDetails } from '../lib/modlist.types.js';
-import { scan as scanLib } from '../lib/scan.js';
+import { UnmatchedFile, scan as scanLib } from '../lib/scan.js';
 import { DefaultOptions, telemetry } from '../mmm.js';
 import { PlatformLookupResult } from '../repositories/index.js';
 
@@ -16,10 +17,30 @@ export interface ScanOptions extends DefaultOptions {
   add: boolean;
 }
 
+/**
+ * A platform that knows the scanned file, Curseforge by its fingerprint or Modrinth by its hash
+ */
+export interface ScanMatch {
+  platform: Platform;
+  modId: string;
+  /**
+   * The file in the mods folder as the platform knows it
+   */
+  local: RemoteModDetails;
+  /**
+   * The file the platform would install for the configuration
+   */
+  remote: RemoteModDetails;
+}
+
 export interface ScanResults {
   preferredDetails: RemoteModDetails;
   allRemoteDetails: RemoteModDetails[];
   localDetails: PlatformLookupResult[];
+  /**
+   * The platforms the file could be resolved on, the preferred one first
+   */
+  matches: ScanMatch[];
 }
 
 export interface FoundEntries {
@@ -46,6 +67,7 @@ const processForeignFiles = async (
   installations: ModInstall[],
   dealtWith: string[],
   hasResults: boolean,
+  unmatched: UnmatchedFile[],
   logger: Logger
 ) => {
   const modsFolder = getModsFolder(options.config, configuration);
@@ -75,11 +97,25 @@ const processForeignFiles = async (
   if (hasForeignFiles) {
     logger.log('\nThe following files cannot be matched to any mod on any of the platforms:\n');
     nonMatchedFiles.forEach((file) => {
-      logger.log(`  ${chalk.red('\u274c')} ${file}`);
+      // The fingerprint lets the file be looked up on Curseforge by hand
+      const unmatchedFile = unmatched.find((candidate) => candidate.files.includes(file));
+      const fingerprint = unmatchedFile?.fingerprint;
+      logger.log(`  ${chalk.red('\u274c')} ${file}${fingerprint ? chalk.gray(` (fingerprint ${fingerprint})`) : ''}`);
+      if (unmatchedFile?.guess) {
+        const { modId, file: guessedFile, confidence } = unmatchedFile.guess;
+        const certainty = Math.round(confidence * 100);
+        logger.log(
+          chalk.gray(`     probably ${guessedFile.fileName} of the Curseforge project ${modId}, ${certainty}% sure`)
+        );
+      }
     });
   }
 };
 
+const remoteDetailsOn = (hit: ScanResults, platform: Platform) => {
+  return hit.matches.find((match) => match.platform === platform)?.remote;
+};
+
 export const processScanResults = (
   scanResults: ScanResults[],
   configuration: ModsJson,
@@ -94,13 +130,13 @@ export const processScanResults = (
     // We know that `hit` has no direct matches in the lockfile, so we need to look for a configuration match
 
     const halfMatching = hit.localDetails
-      .map((local, index) => {
+      .map((local) => {
         const modIndex = findInConfiguration(local.platform, local.modId, configuration);
         if (modIndex < 0) {
           return {
             found: false,
             configured: null,
-            remote: hit.allRemoteDetails[index],
+            remote: remoteDetailsOn(hit, local.platform),
             local: local
           };
         }
@@ -108,7 +144,7 @@ export const processScanResults = (
         return {
           found: true,
           configured: configuration.mods[modIndex],
-          remote: hit.allRemoteDetails[index],
+          remote: remoteDetailsOn(hit, local.platform),
           local: local
         };
       })
@@ -160,25 +196,27 @@ export const processScanResults = (
 
     // ================================================================================================================
 
-    if (halfMatching.length === 0) {
+    // The entry comes from the platform that resolved the file, which isn't the first hit when that one failed
+    const match = hit.matches[0];
+    if (halfMatching.length === 0 && match) {
       const message =
-        chalk.green('\u2705') + `Found unmanaged mod: ${chalk.bold(chalk.whiteBright(hit.preferredDetails.name))}`;
+        chalk.green('\u2705') + `Found unmanaged mod: ${chalk.bold(chalk.whiteBright(match.remote.name))}`;
       logger.log(message, true);
 
       unmanaged.push({
         mod: {
-          type: hit.localDetails[0].platform,
-          id: hit.localDetails[0].modId,
-          name: hit.preferredDetails.name
+          type: match.platform,
+          id: match.modId,
+          name: match.remote.name
         },
         install: {
-          name: hit.preferredDetails.name,
-          type: hit.localDetails[0].platform,
-          id: hit.localDetails[0].modId,
-          fileName: hit.localDetails[0].mod.fileName,
-          hash: hit.localDetails[0].mod.hash,
-          downloadUrl: hit.localDetails[0].mod.downloadUrl,
-          releasedOn: hit.localDetails[0].mod.releaseDate
+          name: match.remote.name,
+          type: match.platform,
+          id: match.modId,
+          fileName: match.local.fileName,
+          hash: match.local.hash,
+          downloadUrl: match.local.downloadUrl,
+          releasedOn: match.local.releaseDate
         }
       });
     }
@@ -189,14 +227,30 @@ export const processScanResults = (
   };
 };
 
+/**
+ * A corrupted lock file is rebuilt from the mods folder, every file the configuration has is found again by the scan
+ */
+const readLockFileToRebuild = async (options: ScanOptions, logger: Logger): Promise<ModInstall[]> => {
+  try {
+    return await readLockFile(options, logger, true);
+  } catch (error) {
+    if (error instanceof LockFileCorruptedException) {
+      logger.log(`${chalk.red('\u274c')} The lock file is corrupted, scanning the mods folder to rebuild it`);
+      return [];
+    }
+    throw error;
+  }
+};
+
 export const scan = async (options: ScanOptions, logger: Logger) => {
   performance.mark('scan-start');
   const configuration = await ensureConfiguration(options.config, logger);
-  const installations = await readLockFile(options, logger);
+  const installations = await readLockFileToRebuild(options, logger);
   let scanResults: ScanResults[] = [];
+  let unmatched: UnmatchedFile[] = [];
 
   try {
-    scanResults = await scanLib(options.config, options.prefer, configuration, installations);
+    ({ results: scanResults, unmatched } = await scanLib(options.config, options.prefer, configuration, installations));
   } catch (error) {
     logger.error((error as Error).message, 2);
   }
@@ -238,7 +292,7 @@ export const scan = async (options: ScanOptions, logger: Logger) => {
     dealtWith.push(managed.install.fileName);
   });
 
-  await processForeignFiles(options, configuration, installations, dealtWith, hasResults, logger);
+  await processForeignFiles(options, configuration, installations, dealtWith, hasResults, unmatched, logger);
 
   performance.mark('scan-succeed');
 
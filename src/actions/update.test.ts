@@ -1,8 +1,11 @@
 import * as path from 'path';
 import { chance } from 'jest-chance';
-import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateRandomPlatform } from '../../test/generateRandomPlatform.js';
 import { generateRemoteModDetails } from '../../test/generateRemoteDetails.js';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
 import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
 import {
   assumeModFileExists,
   assumeModFileIsMissing,
@@ -11,22 +14,38 @@ import {
   verifyBasics
 } from '../../test/setupHelpers.js';
 import { expectCommandStartTelemetry } from '../../test/telemetryHelper.js';
+import { MultiError } from '../errors/MultiError.js';
 import { handleFetchErrors } from '../errors/handleFetchErrors.js';
 import { Logger } from '../lib/Logger.js';
-import { ensureConfiguration, getModsFolder, readLockFile, writeConfigFile, writeLockFile } from '../lib/config.js';
+import {
+  ensureConfiguration,
+  fileExists,
+  getModsFolder,
+  readLockFile,
+  writeConfigFile,
+  writeLockFile
+} from '../lib/config.js';
 import { downloadFile } from '../lib/downloader.js';
 import { getHash } from '../lib/hash.js';
-import { updateMod } from '../lib/updater.js';
+import { Loader, Platform } from '../lib/modlist.types.js';
+import { ensureModsFolder } from '../lib/modsFolder.js';
+import { ProgressEvent, setProgress } from '../lib/progress.js';
+import { PlannedChange, PlannedChangeType, planUpdate } from '../lib/updatePlan.js';
+import { updateAdditionalFiles, updateMod } from '../lib/updater.js';
 import { DefaultOptions } from '../mmm.js';
+import { changelogToText, getFileChangelog } from '../repositories/curseforge/changelog.js';
 import { fetchModDetails } from '../repositories/index.js';
-import { install } from './install.js';
-import { update } from './update.js';
+import { install, warnAboutConflicts, warnAboutUnsatisfiedDependencies } from './install.js';
+import { UpdateOptions, update } from './update.js';
 
 vi.mock('../repositories/index.js');
+vi.mock('../repositories/curseforge/changelog.js');
 vi.mock('../lib/downloader.js');
 vi.mock('../lib/config.js');
 vi.mock('../lib/updater.js');
+vi.mock('../lib/updatePlan.js');
 vi.mock('../lib/hash.js');
+vi.mock('../lib/modsFolder.js');
 vi.mock('./install.js');
 vi.mock('../lib/Logger.js');
 vi.mock('../errors/handleFetchErrors.js');
@@ -53,6 +72,10 @@ describe('The update action', () => {
     vi.mocked(handleFetchErrors).mockReturnValue();
   });
 
+  afterEach(() => {
+    setProgress();
+  });
+
   it<LocalTestContext>('does nothing when there are no updates', async ({ options, logger }) => {
     const { randomConfiguration, randomInstallation, randomInstalledMod } = setupOneInstalledMod();
     delete randomInstalledMod.allowedReleaseTypes;
@@ -86,6 +109,138 @@ describe('The update action', () => {
     verifyBasics();
   });
 
+  it<LocalTestContext>('checks the dependencies once the mods are updated', async ({ options, logger }) => {
+    const { randomConfiguration, randomInstallation } = setupOneInstalledMod();
+    const dependencies = [{ type: randomInstallation.type, id: chance.word() }];
+
+    vi.mocked(fetchModDetails).mockResolvedValueOnce(
+      generateRemoteModDetails({
+        hash: randomInstallation.hash,
+        releaseDate: randomInstallation.releasedOn,
+        dependencies: dependencies
+      }).generated
+    );
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+    assumeModFileExists(randomInstallation.fileName);
+    vi.mocked(getHash).mockResolvedValueOnce(randomInstallation.hash);
+
+    await update(options, logger);
+
+    // The install before the update leaves the check to it, so it is done once
+    expect(vi.mocked(install)).toHaveBeenCalledWith(options, logger, false);
+    expect(vi.mocked(warnAboutUnsatisfiedDependencies)).toHaveBeenCalledWith(
+      randomConfiguration,
+      [{ ...randomInstallation, dependencies: dependencies }],
+      logger
+    );
+  });
+
+  it<LocalTestContext>('checks the conflicts once the mods are updated', async ({ options, logger }) => {
+    const { randomConfiguration, randomInstallation } = setupOneInstalledMod();
+    const incompatibilities = [{ type: randomInstallation.type, id: chance.word() }];
+
+    vi.mocked(fetchModDetails).mockResolvedValueOnce(
+      generateRemoteModDetails({
+        hash: randomInstallation.hash,
+        releaseDate: randomInstallation.releasedOn,
+        incompatibilities: incompatibilities
+      }).generated
+    );
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+    assumeModFileExists(randomInstallation.fileName);
+    vi.mocked(getHash).mockResolvedValueOnce(randomInstallation.hash);
+
+    await update(options, logger);
+
+    expect(vi.mocked(warnAboutConflicts)).toHaveBeenCalledWith(
+      randomConfiguration,
+      [{ ...randomInstallation, incompatibilities: incompatibilities }],
+      logger
+    );
+  });
+
+  it<LocalTestContext>('checks the mods folder before updating anything', async ({ options, logger }) => {
+    const { randomConfiguration, randomInstallation } = setupOneInstalledMod();
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+    vi.mocked(ensureModsFolder).mockRejectedValueOnce(new Error('The mods folder is missing'));
+
+    await expect(update(options, logger)).rejects.toThrow('The mods folder is missing');
+
+    expect(vi.mocked(ensureModsFolder)).toHaveBeenCalledWith(randomConfiguration.modsFolder, logger);
+    expect(vi.mocked(fetchModDetails)).not.toHaveBeenCalled();
+    expect(vi.mocked(writeLockFile)).not.toHaveBeenCalled();
+  });
+
+  it<LocalTestContext>('reports the mod it resolves', async ({ options, logger }) => {
+    const { randomConfiguration, randomInstallation } = setupOneInstalledMod();
+    const name = chance.word();
+    const events: ProgressEvent[] = [];
+    setProgress({ report: (event) => events.push(event) });
+
+    const remoteDetails = generateRemoteModDetails({
+      name: name,
+      hash: randomInstallation.hash,
+      releaseDate: randomInstallation.releasedOn
+    });
+
+    vi.mocked(fetchModDetails).mockResolvedValueOnce(remoteDetails.generated);
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+    assumeModFileExists(randomInstallation.fileName);
+    vi.mocked(getHash).mockResolvedValueOnce(randomInstallation.hash);
+
+    await update(options, logger);
+
+    expect(events).toEqual([{ type: 'resolving', name: name, current: 1, total: 1 }]);
+  });
+
+  it<LocalTestContext>('tells which of the accepted loaders the update uses', async ({ options, logger }) => {
+    const { randomConfiguration, randomInstallation, randomInstalledMod } = setupOneInstalledMod();
+    randomInstalledMod.loaders = [Loader.FORGE, Loader.FABRIC];
+    const name = randomInstalledMod.name;
+
+    vi.mocked(fetchModDetails).mockResolvedValueOnce(
+      generateRemoteModDetails({ name: name, loader: Loader.FABRIC, releaseDate: '2099-01-01T00:00:00.000Z' }).generated
+    );
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+    assumeModFileExists(randomInstallation.fileName);
+    vi.mocked(getHash).mockResolvedValueOnce(randomInstallation.hash);
+
+    await update(options, logger);
+
+    expect(vi.mocked(fetchModDetails).mock.calls[0][4]).toEqual([Loader.FORGE, Loader.FABRIC]);
+    expect(logger.log).toHaveBeenCalledWith(`${name} uses the fabric file`);
+  });
+
+  it<LocalTestContext>('does not upgrade a pinned mod even when a newer file exists', async ({ options, logger }) => {
+    const { randomConfiguration, randomInstallation, randomInstalledMod } = setupOneInstalledMod();
+    randomInstalledMod.pinned = true;
+
+    vi.mocked(fetchModDetails).mockResolvedValueOnce(
+      generateRemoteModDetails({ releaseDate: '2099-01-01T00:00:00.000Z' }).generated
+    );
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+
+    await update(options, logger);
+
+    expect(logger.log).toHaveBeenCalledWith(`${randomInstalledMod.name} is pinned, skipping`);
+    expect(vi.mocked(install)).toHaveBeenCalledOnce();
+    expect(vi.mocked(fetchModDetails)).not.toHaveBeenCalled();
+    expect(vi.mocked(updateMod)).not.toHaveBeenCalled();
+    expect(vi.mocked(writeLockFile)).toHaveBeenCalledWith([randomInstallation], options, logger);
+  });
+
   it<LocalTestContext>('calls the correct telemetry', async ({ options, logger }) => {
     const { randomConfiguration, randomInstallation, randomInstalledMod } = setupOneInstalledMod();
     delete randomInstalledMod.allowedReleaseTypes;
@@ -190,6 +345,31 @@ describe('The update action', () => {
     verifyBasics();
   });
 
+  it<LocalTestContext>('swaps the additional files for the ones of the new version', async ({ options, logger }) => {
+    const { randomConfiguration, randomInstallation } = setupOneInstalledMod();
+    const installedFiles = [{ fileName: chance.word(), hash: chance.hash(), downloadUrl: chance.url() }];
+    const newFiles = [{ fileName: chance.word(), hash: chance.hash(), downloadUrl: chance.url() }];
+    randomInstallation.additionalFiles = installedFiles;
+
+    const remoteDetails = generateRemoteModDetails({ hash: chance.hash(), additionalFiles: newFiles });
+
+    vi.mocked(fetchModDetails).mockResolvedValueOnce(remoteDetails.generated);
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    assumeModFileExists(randomInstallation.fileName);
+    vi.mocked(getHash).mockResolvedValueOnce(randomInstallation.hash);
+
+    await update(options, logger);
+
+    expect(vi.mocked(updateAdditionalFiles)).toHaveBeenCalledWith(
+      installedFiles,
+      newFiles,
+      randomConfiguration.modsFolder
+    );
+    expect(vi.mocked(writeLockFile).mock.calls[0][0][0].additionalFiles).toEqual(newFiles);
+  });
+
   it<LocalTestContext>('can update based on release date only', async ({ options, logger }) => {
     const { randomConfiguration, randomInstallation, randomInstalledMod } = setupOneInstalledMod();
     const oldFilename = randomInstallation.fileName;
@@ -245,6 +425,37 @@ describe('The update action', () => {
     verifyBasics();
   });
 
+  it<LocalTestContext>('names the new file after the configured template', async ({ options, logger }) => {
+    const { randomConfiguration, randomInstallation } = setupOneInstalledMod();
+    randomConfiguration.fileNameTemplate = '{slug}.jar';
+    const oldModPath = path.resolve(randomConfiguration.modsFolder, randomInstallation.fileName);
+    const remoteDetails = generateRemoteModDetails({
+      name: 'Sodium',
+      releaseDate: '2099-01-01T00:00:00.000Z'
+    }).generated;
+
+    vi.mocked(fetchModDetails).mockResolvedValueOnce(remoteDetails);
+    vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+    vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+    vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+    vi.mocked(updateMod).mockResolvedValueOnce(remoteDetails);
+    assumeModFileExists(randomInstallation.fileName);
+    vi.mocked(getHash).mockResolvedValueOnce(randomInstallation.hash);
+
+    await update(options, logger);
+
+    expect(vi.mocked(updateMod)).toHaveBeenCalledWith(
+      { ...remoteDetails, fileName: 'sodium.jar' },
+      oldModPath,
+      randomConfiguration.modsFolder
+    );
+    expect(vi.mocked(writeLockFile)).toHaveBeenCalledWith(
+      [expect.objectContaining({ fileName: 'sodium.jar' })],
+      options,
+      logger
+    );
+  });
+
   it<LocalTestContext>('logs the update checks for debug mode', async ({ options, logger }) => {
     const { randomConfiguration, randomInstallation, randomInstalledMod } = setupOneInstalledMod();
 
@@ -328,17 +539,317 @@ describe('The update action', () => {
     );
   });
 
-  it<LocalTestContext>('handles unexpected errors', async ({ options, logger }) => {
+  it<LocalTestContext>('reports an unexpected error instead of crashing', async ({ options, logger }) => {
     const randomErrorMessage = chance.sentence();
     const { randomConfiguration } = setupOneInstalledMod();
 
-    vi.mocked(handleFetchErrors).mockReset();
-    vi.mocked(handleFetchErrors).mockImplementation(() => {
-      throw new Error(randomErrorMessage);
-    });
-
+    vi.mocked(fetchModDetails).mockRejectedValueOnce(new Error(randomErrorMessage));
     vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
     vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
-    await expect(update(options, logger)).rejects.toThrow(randomErrorMessage);
+    vi.mocked(readLockFile).mockResolvedValueOnce([]);
+
+    const actual = await update({ ...options, failFast: true }, logger);
+
+    expect(actual?.message).toContain(randomErrorMessage);
+    expect(vi.mocked(writeLockFile)).toHaveBeenCalledOnce();
+  });
+
+  describe('when some of the mods fail', () => {
+    const setupTwoInstalledMods = () => {
+      const failing = setupOneInstalledMod();
+      const succeeding = setupOneInstalledMod();
+      const randomConfiguration = failing.randomConfiguration;
+      randomConfiguration.mods = [failing.randomInstalledMod, succeeding.randomInstalledMod];
+
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(getModsFolder).mockReturnValue(randomConfiguration.modsFolder);
+      vi.mocked(readLockFile).mockResolvedValueOnce([failing.randomInstallation, succeeding.randomInstallation]);
+      vi.mocked(fileExists).mockResolvedValue(true);
+      vi.mocked(getHash).mockResolvedValue(succeeding.randomInstallation.hash);
+
+      return { failing: failing, succeeding: succeeding };
+    };
+
+    it<LocalTestContext>('updates the rest and reports the failures at the end', async ({ options, logger }) => {
+      const { failing, succeeding } = setupTwoInstalledMods();
+      const error = new Error(chance.sentence());
+      const failingMod = { ...failing.randomInstalledMod };
+      const newFile = generateRemoteModDetails({ releaseDate: '2099-01-01T00:00:00.000Z' }).generated;
+      vi.mocked(fetchModDetails).mockRejectedValueOnce(error).mockResolvedValueOnce(newFile);
+      vi.mocked(updateMod).mockResolvedValueOnce(newFile);
+
+      const actual = await update(options, logger);
+
+      expect(vi.mocked(updateMod)).toHaveBeenCalledOnce();
+      expect(vi.mocked(writeLockFile)).toHaveBeenCalledWith(
+        [
+          failing.randomInstallation,
+          expect.objectContaining({ id: succeeding.randomInstallation.id, hash: newFile.hash })
+        ],
+        options,
+        logger
+      );
+      expect(actual).toBeInstanceOf(MultiError);
+      expect(actual?.failures).toEqual([{ mod: failingMod, error: error }]);
+      expect(logger.log).toHaveBeenCalledWith(`❌ ${actual?.message}`, true);
+      expect(vi.mocked(handleFetchErrors)).not.toHaveBeenCalled();
+    });
+
+    it<LocalTestContext>('collects every failure', async ({ options, logger }) => {
+      setupTwoInstalledMods();
+      vi.mocked(fetchModDetails).mockRejectedValue(new Error(chance.sentence()));
+
+      const actual = await update(options, logger);
+
+      expect(actual?.failures).toHaveLength(2);
+      expect(vi.mocked(writeLockFile)).toHaveBeenCalledOnce();
+    });
+
+    it<LocalTestContext>('reports no failures when every mod made it', async ({ options, logger }) => {
+      const { succeeding } = setupTwoInstalledMods();
+      vi.mocked(fetchModDetails).mockResolvedValue(
+        generateRemoteModDetails({
+          hash: succeeding.randomInstallation.hash,
+          releaseDate: '1970-01-01T00:00:00.000Z'
+        }).generated
+      );
+
+      expect(await update(options, logger)).toBeUndefined();
+    });
+
+    it<LocalTestContext>('stops at the first failure when failing fast', async ({ options, logger }) => {
+      const { failing } = setupTwoInstalledMods();
+      const error = new Error(chance.sentence());
+      vi.mocked(fetchModDetails).mockRejectedValueOnce(error);
+
+      const actual = await update({ ...options, failFast: true }, logger);
+
+      expect(actual?.failures).toEqual([{ mod: failing.randomInstalledMod, error: error }]);
+      expect(logger.log).toHaveBeenCalledWith(`❌ ${actual?.message}`, true);
+      expect(vi.mocked(fetchModDetails)).toHaveBeenCalledOnce();
+      expect(vi.mocked(updateMod)).not.toHaveBeenCalled();
+    });
+
+    it<LocalTestContext>('keeps the mods updated before the failure when failing fast', async ({
+      options,
+      logger
+    }) => {
+      // The first of the two mods is the one that gets updated this time
+      const { failing: updated, succeeding: failing } = setupTwoInstalledMods();
+      const newFile = generateRemoteModDetails({ releaseDate: '2099-01-01T00:00:00.000Z' }).generated;
+      const error = new Error(chance.sentence());
+      vi.mocked(fetchModDetails).mockResolvedValueOnce(newFile).mockRejectedValueOnce(error);
+
+      const failFastOptions = { ...options, failFast: true };
+
+      const actual = await update(failFastOptions, logger);
+
+      expect(vi.mocked(updateMod)).toHaveBeenCalledOnce();
+      expect(vi.mocked(writeLockFile)).toHaveBeenCalledWith(
+        [
+          expect.objectContaining({ id: updated.randomInstallation.id, hash: newFile.hash }),
+          failing.randomInstallation
+        ],
+        failFastOptions,
+        logger
+      );
+      expect(vi.mocked(writeConfigFile)).toHaveBeenCalledOnce();
+      expect(actual?.failures).toEqual([{ mod: failing.randomInstalledMod, error: error }]);
+    });
+  });
+
+  describe('when running in dry-run mode', () => {
+    const plannedChange = (type: PlannedChangeType): PlannedChange => ({
+      id: chance.word(),
+      name: chance.word(),
+      platform: generateRandomPlatform(),
+      type: type,
+      currentFileName: chance.word(),
+      currentReleaseDate: chance.date({ string: true }) as string,
+      newFileName: chance.word(),
+      newReleaseDate: chance.date({ string: true }) as string
+    });
+
+    beforeEach<LocalTestContext>(({ options }) => {
+      (options as UpdateOptions).dryRun = true;
+    });
+
+    it<LocalTestContext>('reports the planned changes without touching anything', async ({ options, logger }) => {
+      const { randomConfiguration, randomInstallation } = setupOneInstalledMod();
+      const upgrade = plannedChange(PlannedChangeType.UPGRADE);
+      const downgrade = plannedChange(PlannedChangeType.DOWNGRADE);
+      const noop = plannedChange(PlannedChangeType.NONE);
+      const newInstall = { ...plannedChange(PlannedChangeType.INSTALL), currentFileName: undefined };
+
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(readLockFile).mockResolvedValueOnce([randomInstallation]);
+      vi.mocked(planUpdate).mockResolvedValueOnce({ changes: [upgrade, downgrade, noop, newInstall], errors: [] });
+
+      await update(options, logger);
+
+      expect(vi.mocked(planUpdate)).toHaveBeenCalledWith(randomConfiguration, [randomInstallation]);
+      expect(logger.log).toHaveBeenCalledWith(
+        `${upgrade.name}: ${upgrade.currentFileName} → ${upgrade.newFileName} (upgrade)`
+      );
+      expect(logger.log).toHaveBeenCalledWith(
+        `${downgrade.name}: ${downgrade.currentFileName} → ${downgrade.newFileName} (downgrade)`
+      );
+      expect(logger.log).toHaveBeenCalledWith(`${newInstall.name}: not installed → ${newInstall.newFileName} (install)`);
+      expect(logger.log).not.toHaveBeenCalledWith(expect.stringContaining(noop.name));
+
+      expect(vi.mocked(install)).not.toHaveBeenCalled();
+      expect(vi.mocked(fetchModDetails)).not.toHaveBeenCalled();
+      expect(vi.mocked(downloadFile)).not.toHaveBeenCalled();
+      expect(vi.mocked(updateMod)).not.toHaveBeenCalled();
+      expect(vi.mocked(writeLockFile)).not.toHaveBeenCalled();
+      expect(vi.mocked(writeConfigFile)).not.toHaveBeenCalled();
+    });
+
+    it<LocalTestContext>('reports the pinned mods as skipped', async ({ options, logger }) => {
+      const { randomConfiguration } = setupOneInstalledMod();
+      const pinned = plannedChange(PlannedChangeType.PINNED);
+
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+      vi.mocked(planUpdate).mockResolvedValueOnce({ changes: [pinned], errors: [] });
+
+      await update(options, logger);
+
+      expect(logger.log).toHaveBeenCalledWith(`${pinned.name}: pinned to ${pinned.currentFileName}, skipped`);
+      expect(logger.log).toHaveBeenCalledWith('Every mod is up to date.');
+    });
+
+    it<LocalTestContext>('tells the user when everything is up to date', async ({ options, logger }) => {
+      const { randomConfiguration } = setupOneInstalledMod();
+
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+      vi.mocked(planUpdate).mockResolvedValueOnce({ changes: [plannedChange(PlannedChangeType.NONE)], errors: [] });
+
+      await update(options, logger);
+
+      expect(logger.log).toHaveBeenCalledWith('Every mod is up to date.');
+    });
+
+    it<LocalTestContext>('reports the mods that could not be checked', async ({ options, logger }) => {
+      const { randomConfiguration, randomInstalledMod } = setupOneInstalledMod();
+      const error = new Error(chance.sentence());
+
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+      vi.mocked(planUpdate).mockResolvedValueOnce({ changes: [], errors: [{ mod: randomInstalledMod, error: error }] });
+
+      await update(options, logger);
+
+      expect(vi.mocked(handleFetchErrors)).toHaveBeenCalledWith(error, randomInstalledMod, logger);
+      expect(logger.log).not.toHaveBeenCalledWith('Every mod is up to date.');
+    });
+
+    describe('and the changelogs are asked for', () => {
+      beforeEach<LocalTestContext>(({ options }) => {
+        (options as UpdateOptions).changelog = true;
+      });
+
+      it<LocalTestContext>('prints the changelog of a new Curseforge file', async ({ options, logger }) => {
+        const { randomConfiguration } = setupOneInstalledMod();
+        const upgrade = {
+          ...plannedChange(PlannedChangeType.UPGRADE),
+          platform: Platform.CURSEFORGE,
+          curseforgeFileId: 4567890
+        };
+
+        vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+        vi.mocked(readLockFile).mockResolvedValueOnce([]);
+        vi.mocked(planUpdate).mockResolvedValueOnce({ changes: [upgrade], errors: [] });
+        vi.mocked(getFileChangelog).mockResolvedValueOnce('<ul><li>Fixed a crash</li><li>Faster</li></ul>');
+        vi.mocked(changelogToText).mockReturnValueOnce('- Fixed a crash\n- Faster');
+
+        await update(options, logger);
+
+        expect(vi.mocked(getFileChangelog)).toHaveBeenCalledWith(upgrade.id, 4567890);
+        expect(vi.mocked(changelogToText)).toHaveBeenCalledWith('<ul><li>Fixed a crash</li><li>Faster</li></ul>');
+        expect(logger.log).toHaveBeenCalledWith('    - Fixed a crash\n    - Faster');
+      });
+
+      it<LocalTestContext>('asks the Curseforge fallback for its changelog', async ({ options, logger }) => {
+        const mod = generateModConfig({
+          type: Platform.MODRINTH,
+          fallback: { type: Platform.CURSEFORGE, id: '238222' }
+        }).generated;
+        const upgrade = {
+          ...plannedChange(PlannedChangeType.UPGRADE),
+          id: mod.id,
+          platform: Platform.MODRINTH,
+          curseforgeFileId: 4567890
+        };
+
+        vi.mocked(ensureConfiguration).mockResolvedValueOnce(generateModsJson({ mods: [mod] }).generated);
+        vi.mocked(readLockFile).mockResolvedValueOnce([]);
+        vi.mocked(planUpdate).mockResolvedValueOnce({ changes: [upgrade], errors: [] });
+        vi.mocked(getFileChangelog).mockResolvedValueOnce('');
+        vi.mocked(changelogToText).mockReturnValueOnce('');
+
+        await update(options, logger);
+
+        expect(vi.mocked(getFileChangelog)).toHaveBeenCalledWith('238222', 4567890);
+      });
+
+      it<LocalTestContext>('does not ask for the changelog of the other files', async ({ options, logger }) => {
+        const { randomConfiguration } = setupOneInstalledMod();
+        const upgrade = { ...plannedChange(PlannedChangeType.UPGRADE), platform: Platform.MODRINTH };
+
+        vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+        vi.mocked(readLockFile).mockResolvedValueOnce([]);
+        vi.mocked(planUpdate).mockResolvedValueOnce({ changes: [upgrade], errors: [] });
+
+        await update(options, logger);
+
+        expect(vi.mocked(getFileChangelog)).not.toHaveBeenCalled();
+      });
+
+      it<LocalTestContext>('goes on when a changelog cannot be fetched', async ({ options, logger }) => {
+        const { randomConfiguration } = setupOneInstalledMod();
+        const curseforgeChange = (fileId: number) => ({
+          ...plannedChange(PlannedChangeType.UPGRADE),
+          platform: Platform.CURSEFORGE,
+          curseforgeFileId: fileId
+        });
+        const first = curseforgeChange(1);
+        const second = curseforgeChange(2);
+
+        vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+        vi.mocked(readLockFile).mockResolvedValueOnce([]);
+        vi.mocked(planUpdate).mockResolvedValueOnce({ changes: [first, second], errors: [] });
+        vi.mocked(getFileChangelog).mockRejectedValueOnce(new Error('Internal Server Error'));
+        vi.mocked(getFileChangelog).mockResolvedValueOnce('Fixed a crash');
+        vi.mocked(changelogToText).mockReturnValueOnce('Fixed a crash');
+
+        await update(options, logger);
+
+        expect(logger.debug).toHaveBeenCalledWith(
+          `Could not fetch the changelog of ${first.name}: Internal Server Error`
+        );
+        expect(logger.log).toHaveBeenCalledWith('    Fixed a crash');
+      });
+    });
+
+    it<LocalTestContext>('calls the correct telemetry', async ({ options, logger }) => {
+      const { randomConfiguration } = setupOneInstalledMod();
+
+      vi.mocked(ensureConfiguration).mockResolvedValueOnce(randomConfiguration);
+      vi.mocked(readLockFile).mockResolvedValueOnce([]);
+      vi.mocked(planUpdate).mockResolvedValueOnce({ changes: [], errors: [] });
+
+      await update(options, logger);
+
+      expectCommandStartTelemetry({
+        command: 'update',
+        success: true,
+        duration: expect.any(Number),
+        arguments: {
+          options: options
+        }
+      });
+    });
   });
 });
@@ -1,4 +1,5 @@
 import path from 'path';
+import chalk from 'chalk';
 import { Logger } from '../lib/Logger.js';
 import {
   ensureConfiguration,
@@ -8,19 +9,129 @@ import {
   writeConfigFile,
   writeLockFile
 } from '../lib/config.js';
+import { downloadFileName } from '../lib/fileNameTemplate.js';
+import { fileOverridesOf } from '../lib/fileOverrides.js';
 import { getHash } from '../lib/hash.js';
-import { Mod } from '../lib/modlist.types.js';
-import { updateMod } from '../lib/updater.js';
-import { DefaultOptions, telemetry } from '../mmm.js';
+import { acceptedLoaders } from '../lib/loaderCompatibility.js';
+import { Mod, ModsJson, Platform } from '../lib/modlist.types.js';
+import { ensureModsFolder } from '../lib/modsFolder.js';
+import { getProgress } from '../lib/progress.js';
+import { allowedReleaseTypesOf, fallbackOf } from '../lib/releaseChannel.js';
+import { updateAdditionalFiles, updateMod } from '../lib/updater.js';
+import { telemetry } from '../mmm.js';
+import { changelogToText, getFileChangelog } from '../repositories/curseforge/changelog.js';
 import { fetchModDetails } from '../repositories/index.js';
-import { install } from './install.js';
+import { InstallOptions, install, warnAboutConflicts, warnAboutUnsatisfiedDependencies } from './install.js';
 
+import { ModFailure, MultiError } from '../errors/MultiError.js';
 import { handleFetchErrors } from '../errors/handleFetchErrors.js';
 import { getInstallation, hasInstallation } from '../lib/configurationHelper.js';
+import { PlannedChange, PlannedChangeType, planUpdate } from '../lib/updatePlan.js';
 
-export const update = async (options: DefaultOptions, logger: Logger) => {
+export interface UpdateOptions extends InstallOptions {
+  dryRun?: boolean;
+  /**
+   * Stops at the first mod that fails instead of updating the rest and reporting the failures at the end
+   */
+  failFast?: boolean;
+  /**
+   * Prints the changelog of the new files in the dry-run, only the ones from Curseforge have one
+   */
+  changelog?: boolean;
+}
+
+/**
+ * The new file of a mod can come from the Curseforge fallback, its changelog is under the project of the fallback
+ */
+const curseforgeProjectOf = (change: PlannedChange, configuration: ModsJson) => {
+  if (change.platform === Platform.CURSEFORGE) {
+    return change.id;
+  }
+  const mod = configuration.mods.find(
+    (configured) => configured.type === change.platform && configured.id === change.id
+  );
+  return mod?.fallback?.type === Platform.CURSEFORGE ? mod.fallback.id : undefined;
+};
+
+/**
+ * A changelog that can't be fetched doesn't stop the dry-run, it's only there to help decide
+ */
+const printChangelog = async (change: PlannedChange, configuration: ModsJson, logger: Logger) => {
+  const projectId = curseforgeProjectOf(change, configuration);
+  if (!change.curseforgeFileId || !projectId) {
+    return;
+  }
+
+  try {
+    const changelog = changelogToText(await getFileChangelog(projectId, change.curseforgeFileId));
+    if (changelog) {
+      // Indented under the line of the mod
+      logger.log(changelog.replace(/^/gm, '    '));
+    }
+  } catch (error) {
+    logger.debug(`Could not fetch the changelog of ${change.name}: ${(error as Error).message}`);
+  }
+};
+
+const dryRun = async (options: UpdateOptions, logger: Logger) => {
+  logger.log(chalk.yellow('Running in dry-run mode. Nothing will actually be updated.'));
+
+  const configuration = await ensureConfiguration(options.config, logger);
+  const installations = await readLockFile(options, logger);
+  const plan = await planUpdate(configuration, installations);
+
+  const changes = plan.changes.filter(
+    (change) => change.type !== PlannedChangeType.NONE && change.type !== PlannedChangeType.PINNED
+  );
+
+  for (const change of changes) {
+    const current = change.currentFileName ?? 'not installed';
+    logger.log(`${change.name}: ${current} → ${change.newFileName} (${change.type})`);
+    if (options.changelog) {
+      await printChangelog(change, configuration, logger);
+    }
+  }
+
+  plan.changes
+    .filter((change) => change.type === PlannedChangeType.PINNED)
+    .forEach((change) => {
+      logger.log(`${change.name}: pinned to ${change.currentFileName}, skipped`);
+    });
+
+  if (changes.length === 0 && plan.errors.length === 0) {
+    logger.log('Every mod is up to date.');
+  }
+
+  plan.errors.forEach(({ mod, error }) => {
+    handleFetchErrors(error as Error, mod, logger);
+  });
+};
+
+/**
+ * Updates every mod that has a newer file.
+ * A mod that fails doesn't stop the others, unless failing fast, so the lock file still gets the successful updates.
+ *
+ * @returns The failed mods, undefined when every mod made it
+ */
+export const update = async (options: UpdateOptions, logger: Logger): Promise<MultiError | undefined> => {
   performance.mark('update-start');
-  await install(options, logger);
+
+  if (options.dryRun) {
+    await dryRun(options, logger);
+
+    performance.mark('update-succeed');
+    await telemetry.captureCommand({
+      command: 'update',
+      success: true,
+      arguments: {
+        options: options
+      },
+      duration: performance.measure('update-duration', 'update-start', 'update-succeed').duration
+    });
+    return;
+  }
+
+  await install(options, logger, false);
   performance.mark('update-install-success');
 
   const configuration = await ensureConfiguration(options.config, logger);
@@ -29,19 +140,33 @@ export const update = async (options: DefaultOptions, logger: Logger) => {
   const installedMods = installations;
   const mods = configuration.mods;
   const modsFolder = getModsFolder(options.config, configuration);
+  await ensureModsFolder(modsFolder, logger);
+
+  const failures: ModFailure[] = [];
+  const takenFileNames = new Set(installations.map((installation) => installation.fileName));
+
+  let resolvedMods = 0;
 
   const processMod = async (mod: Mod, index: number) => {
     try {
       logger.debug(`[update] Checking ${mod.name} for ${mod.type}`);
 
+      // The install before the update has already downloaded the pinned file when it was missing
+      if (mod.pinned && hasInstallation(mod, installations)) {
+        logger.log(`${mod.name} is pinned, skipping`);
+        return;
+      }
+
       const modData = await fetchModDetails(
         mod.type,
         mod.id,
-        mod.allowedReleaseTypes || configuration.defaultAllowedReleaseTypes,
+        allowedReleaseTypesOf(mod, configuration),
         configuration.gameVersion,
-        configuration.loader,
+        acceptedLoaders(mod, configuration),
         !!mod.allowVersionFallback,
-        mod.version
+        mod.version,
+        fallbackOf(mod, configuration),
+        fileOverridesOf(mod)
       );
       mods[index].name = modData.name;
 
@@ -65,24 +190,56 @@ export const update = async (options: DefaultOptions, logger: Logger) => {
       const installedHash = await getHash(oldModPath);
       if (modData.hash !== installedHash || modData.releaseDate > installedMods[installedModIndex].releasedOn) {
         logger.log(`${mod.name} has an update, downloading...`);
-        await updateMod(modData, oldModPath, modsFolder);
+        if (modData.loader) {
+          logger.log(`${mod.name} uses the ${modData.loader} file`);
+        }
+        const fileName = downloadFileName(modData, {
+          mod: mod,
+          configuration: configuration,
+          taken: takenFileNames,
+          currentFileName: installedMods[installedModIndex].fileName
+        });
+        await updateMod({ ...modData, fileName: fileName }, oldModPath, modsFolder);
+        await updateAdditionalFiles(
+          installedMods[installedModIndex].additionalFiles,
+          modData.additionalFiles,
+          modsFolder
+        );
 
         installedMods[installedModIndex].hash = modData.hash;
         installedMods[installedModIndex].downloadUrl = modData.downloadUrl;
         installedMods[installedModIndex].releasedOn = modData.releaseDate;
-        installedMods[installedModIndex].fileName = modData.fileName;
-
-        return;
+        installedMods[installedModIndex].fileName = fileName;
+        installedMods[installedModIndex].additionalFiles = modData.additionalFiles;
       }
+      // The installed file is the one that was fetched, an older lock file doesn't have its dependencies yet
+      installedMods[installedModIndex].dependencies = modData.dependencies;
+      installedMods[installedModIndex].incompatibilities = modData.incompatibilities;
       return;
     } catch (error) {
-      handleFetchErrors(error as Error, mod, logger);
+      failures.push({ mod: mod, error: error as Error });
+    } finally {
+      // The mods can resolve at the same time, so the count is of the ones that are done
+      resolvedMods++;
+      getProgress().report({ type: 'resolving', name: mod.name, current: resolvedMods, total: mods.length });
     }
   };
 
-  const promises = mods.map(processMod);
+  if (options.failFast) {
+    // One at a time, so nothing else gets checked or downloaded after the first failure.
+    // The mods before it are already swapped on disk, so the lock file is still written for them.
+    for (const [index, mod] of mods.entries()) {
+      await processMod(mod, index);
+      if (failures.length > 0) {
+        break;
+      }
+    }
+  } else {
+    await Promise.all(mods.map(processMod));
+  }
 
-  await Promise.all(promises);
+  warnAboutUnsatisfiedDependencies(configuration, installedMods, logger);
+  warnAboutConflicts(configuration, installedMods, logger);
 
   await writeLockFile(installedMods, options, logger);
   await writeConfigFile(configuration, options, logger);
@@ -90,10 +247,18 @@ export const update = async (options: DefaultOptions, logger: Logger) => {
   performance.mark('update-succeed');
   await telemetry.captureCommand({
     command: 'update',
-    success: true,
+    success: failures.length === 0,
     arguments: {
       options: options
     },
     duration: performance.measure('update-duration', 'update-start', 'update-succeed').duration
   });
+
+  if (failures.length === 0) {
+    return;
+  }
+
+  const error = new MultiError(failures);
+  logger.log(`${chalk.red('\u274c')} ${error.message}`, true);
+  return error;
 };
@@ -0,0 +1,98 @@
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { expectCommandStartTelemetry } from '../../test/telemetryHelper.js';
+import { Logger } from '../lib/Logger.js';
+import { ensureConfiguration, getModsFolder, readLockFile } from '../lib/config.js';
+import { ModInstall } from '../lib/modlist.types.js';
+import { InstallationVerification, verifyInstallation } from '../lib/verifyInstallation.js';
+import { DefaultOptions } from '../mmm.js';
+import { verify } from './verify.js';
+
+vi.mock('../lib/Logger.js');
+vi.mock('../lib/config.js');
+vi.mock('../lib/verifyInstallation.js');
+vi.mock('../mmm.js');
+
+interface LocalTestContext {
+  options: DefaultOptions;
+  logger: Logger;
+}
+
+const installation = (name: string): ModInstall =>
+  generateModInstall({ name: name, fileName: `${name}.jar` }).generated;
+
+const assumeVerification = (verification: Partial<InstallationVerification>) => {
+  const result = { intact: [], modified: [], corrupted: [], missing: [], ...verification };
+  vi.mocked(readLockFile).mockResolvedValueOnce(Object.values(result).flat());
+  vi.mocked(verifyInstallation).mockResolvedValueOnce(result);
+};
+
+describe('The verify action', () => {
+  beforeEach<LocalTestContext>((context) => {
+    vi.resetAllMocks();
+    context.logger = new Logger({} as never);
+    context.options = {
+      config: 'config.json',
+      quiet: false,
+      debug: false
+    };
+
+    vi.mocked(context.logger.error).mockImplementation(() => {
+      throw new Error('process.exit');
+    });
+    vi.mocked(ensureConfiguration).mockResolvedValue(generateModsJson().generated);
+    vi.mocked(getModsFolder).mockReturnValue('/minecraft/mods');
+  });
+
+  it<LocalTestContext>('tells when every mod matches the lock file', async ({ options, logger }) => {
+    assumeVerification({ intact: [installation('sodium'), installation('lithium')] });
+
+    await verify(options, logger);
+
+    expect(vi.mocked(verifyInstallation)).toHaveBeenCalledWith(expect.any(Array), '/minecraft/mods');
+    expect(logger.log).toHaveBeenCalledWith('\u2705 All 2 mod(s) match the lock file');
+    expect(logger.error).not.toHaveBeenCalled();
+  });
+
+  it<LocalTestContext>('lists the files that do not match and fails', async ({ options, logger }) => {
+    assumeVerification({
+      intact: [installation('sodium')],
+      modified: [installation('lithium')],
+      corrupted: [installation('jade')],
+      missing: [installation('iris')]
+    });
+
+    await expect(verify(options, logger)).rejects.toThrow('process.exit');
+
+    expect(vi.mocked(logger.log).mock.calls).toEqual([
+      ['\u274c iris is missing: iris.jar'],
+      ['\u274c jade is corrupted: jade.jar'],
+      ['\u26a0 lithium has been changed: lithium.jar']
+    ]);
+    expect(logger.error).toHaveBeenCalledWith(
+      "3 of the 4 mod(s) don't match the lock file, run mmm install to fix them",
+      1
+    );
+  });
+
+  it<LocalTestContext>('calls the correct telemetry', async ({ options, logger }) => {
+    assumeVerification({ intact: [installation('sodium')], missing: [installation('iris')] });
+
+    await expect(verify(options, logger)).rejects.toThrow('process.exit');
+
+    expectCommandStartTelemetry({
+      command: 'verify',
+      success: true,
+      arguments: {
+        options: options
+      },
+      extra: {
+        intact: 1,
+        modified: 0,
+        corrupted: 0,
+        missing: 1
+      }
+    });
+  });
+});
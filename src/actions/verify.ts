@@ -0,0 +1,56 @@
+import chalk from 'chalk';
+import { Logger } from '../lib/Logger.js';
+import { ensureConfiguration, getModsFolder, readLockFile } from '../lib/config.js';
+import { verifyInstallation } from '../lib/verifyInstallation.js';
+import { DefaultOptions, telemetry } from '../mmm.js';
+
+export type VerifyOptions = DefaultOptions;
+
+/**
+ * Checks the installed files against the lock file, exits with an error when any of them don't match it
+ */
+export const verify = async (options: VerifyOptions, logger: Logger) => {
+  performance.mark('verify-start');
+  const configuration = await ensureConfiguration(options.config, logger);
+  const installations = await readLockFile(options, logger);
+  const modsFolder = getModsFolder(options.config, configuration);
+
+  const { intact, modified, corrupted, missing } = await verifyInstallation(installations, modsFolder);
+
+  missing.forEach((installation) => {
+    logger.log(`${chalk.red('\u274c')} ${installation.name} is missing: ${installation.fileName}`);
+  });
+  corrupted.forEach((installation) => {
+    logger.log(`${chalk.red('\u274c')} ${installation.name} is corrupted: ${installation.fileName}`);
+  });
+  modified.forEach((installation) => {
+    logger.log(`${chalk.yellow('\u26a0')} ${installation.name} has been changed: ${installation.fileName}`);
+  });
+
+  const problems = missing.length + corrupted.length + modified.length;
+
+  performance.mark('verify-succeed');
+  await telemetry.captureCommand({
+    command: 'verify',
+    success: true,
+    arguments: {
+      options: options
+    },
+    extra: {
+      intact: intact.length,
+      modified: modified.length,
+      corrupted: corrupted.length,
+      missing: missing.length
+    },
+    duration: performance.measure('verify-duration', 'verify-start', 'verify-succeed').duration
+  });
+
+  if (problems > 0) {
+    logger.error(
+      `${problems} of the ${installations.length} mod(s) don't match the lock file, run mmm install to fix them`,
+      1
+    );
+  }
+
+  logger.log(`${chalk.green('\u2705')} All ${intact.length} mod(s) match the lock file`);
+};
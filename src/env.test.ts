@@ -1,3 +1,5 @@
+import os from 'node:os';
+import path from 'node:path';
 import * as process from 'process';
 import { afterEach, describe, expect, it, vi } from 'vitest';
 
@@ -24,4 +26,47 @@ describe('The environment variables', () => {
     expect(curseForgeApiKey).toBe('cf-key');
     expect(modrinthApiKey).toBe('mr-key');
   });
+
+  it('uses the public APIs by default', async () => {
+    // @ts-ignore
+    delete process.env.CURSEFORGE_API_URL;
+    // @ts-ignore
+    delete process.env.MODRINTH_API_URL;
+
+    const { curseforgeApiUrl, modrinthApiUrl } = await import('./env.js');
+    expect(curseforgeApiUrl).toBe('https://api.curseforge.com/v1');
+    expect(modrinthApiUrl).toBe('https://api.modrinth.com/v2');
+  });
+
+  it('can point to other APIs', async () => {
+    process.env.CURSEFORGE_API_URL = 'https://cf-proxy.example.com/v1';
+    process.env.MODRINTH_API_URL = 'https://mr-proxy.example.com/v2';
+
+    const { curseforgeApiUrl, modrinthApiUrl } = await import('./env.js');
+    expect(curseforgeApiUrl).toBe('https://cf-proxy.example.com/v1');
+    expect(modrinthApiUrl).toBe('https://mr-proxy.example.com/v2');
+
+    // @ts-ignore
+    delete process.env.CURSEFORGE_API_URL;
+    // @ts-ignore
+    delete process.env.MODRINTH_API_URL;
+  });
+
+  it('keeps the file cache in the home folder by default', async () => {
+    // @ts-ignore
+    delete process.env.MMM_CACHE_DIR;
+
+    const { fileCacheDirectory } = await import('./env.js');
+    expect(fileCacheDirectory).toBe(path.join(os.homedir(), '.cache', 'mmm'));
+  });
+
+  it('can keep the file cache elsewhere', async () => {
+    process.env.MMM_CACHE_DIR = '/tmp/mmm-cache';
+
+    const { fileCacheDirectory } = await import('./env.js');
+    expect(fileCacheDirectory).toBe('/tmp/mmm-cache');
+
+    // @ts-ignore
+    delete process.env.MMM_CACHE_DIR;
+  });
 });
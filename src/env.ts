@@ -1,4 +1,10 @@
+import os from 'node:os';
+import path from 'node:path';
+
 export const curseForgeApiKey = process.env.CURSEFORGE_API_KEY || 'REPL_CURSEFORGE_API_KEY';
 export const modrinthApiKey = process.env.MODRINTH_API_KEY || 'REPL_MODRINTH_API_KEY';
 export const posthogApiKey = process.env.POSTHOG_API_KEY || 'REPL_POSTHOG_API_KEY';
 export const helpUrl = process.env.HELP_URL || 'REPL_HELP_URL';
+export const curseforgeApiUrl = process.env.CURSEFORGE_API_URL || 'https://api.curseforge.com/v1';
+export const modrinthApiUrl = process.env.MODRINTH_API_URL || 'https://api.modrinth.com/v2';
+export const fileCacheDirectory = process.env.MMM_CACHE_DIR || path.join(os.homedir(), '.cache', 'mmm');
@@ -0,0 +1,5 @@
+export class CategoriesCouldNotBeFetchedException extends Error {
+  constructor() {
+    super('Categories could not be fetched');
+  }
+}
@@ -0,0 +1,26 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { generateRandomPlatform } from '../../test/generateRandomPlatform.js';
+import { CouldNotFindModException } from './CouldNotFindModException.js';
+
+describe('The Could Not Find Mod Exception', () => {
+  it('records the mod and the platform', () => {
+    const modId = chance.word();
+    const platform = generateRandomPlatform();
+
+    const error = new CouldNotFindModException(modId, platform);
+
+    expect(error.modId).toEqual(modId);
+    expect(error.platform).toEqual(platform);
+    expect(error.message).toEqual(`Could not find the given mod: ${platform}: ${modId}`);
+    expect(error).not.toHaveProperty('cause');
+  });
+
+  it('keeps the cause', () => {
+    const cause = new Error(chance.sentence());
+
+    const error = new CouldNotFindModException(chance.word(), generateRandomPlatform(), cause);
+
+    expect(error.cause).toBe(cause);
+  });
+});
@@ -4,8 +4,8 @@ export class CouldNotFindModException extends Error {
   public readonly modId: string;
   public readonly platform: Platform;
 
-  constructor(modId: string, platform: Platform) {
-    super(`Could not find the given mod: ${platform}: ${modId}`);
+  constructor(modId: string, platform: Platform, cause?: unknown) {
+    super(`Could not find the given mod: ${platform}: ${modId}`, cause === undefined ? undefined : { cause: cause });
     this.modId = modId;
     this.platform = platform;
   }
@@ -1,5 +1,12 @@
 export class CurseforgeDownloadUrlError extends Error {
-  constructor(modName: string) {
-    super(`Curseforge doesn't provide a download url for ${modName}. Try adding the mod from Modrinth instead`);
+  public readonly modName: string;
+  public readonly projectId?: string;
+  public readonly fileId?: number;
+
+  constructor(modName: string, projectId?: string, fileId?: number) {
+    super(`Curseforge doesn't provide a download url for ${modName}. Try adding the mod from Modrinth instead or download it manually`);
+    this.modName = modName;
+    this.projectId = projectId;
+    this.fileId = fileId;
   }
 }
@@ -0,0 +1,20 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { generateCurseforgeModFile } from '../../test/generateCurseforgeModFile.js';
+import { CurseforgePaginationError } from './CurseforgePaginationError.js';
+
+describe('The Curseforge pagination error', () => {
+  it('keeps the files collected so far', () => {
+    const projectId = chance.word();
+    const files = [generateCurseforgeModFile().generated, generateCurseforgeModFile().generated];
+
+    const error = new CurseforgePaginationError(projectId, files, 10);
+
+    expect(error.projectId).toEqual(projectId);
+    expect(error.files).toBe(files);
+    expect(error.totalCount).toEqual(10);
+    expect(error.message).toEqual(
+      `Curseforge stopped returning files for ${projectId} after 2 of 10. The file list is incomplete`
+    );
+  });
+});
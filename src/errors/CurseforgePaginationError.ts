@@ -0,0 +1,16 @@
+import { CurseforgeModFile } from '../repositories/curseforge/fetch.js';
+
+export class CurseforgePaginationError extends Error {
+  public readonly projectId: string;
+  public readonly files: CurseforgeModFile[];
+  public readonly totalCount: number;
+
+  constructor(projectId: string, files: CurseforgeModFile[], totalCount: number) {
+    super(
+      `Curseforge stopped returning files for ${projectId} after ${files.length} of ${totalCount}. The file list is incomplete`
+    );
+    this.projectId = projectId;
+    this.files = files;
+    this.totalCount = totalCount;
+  }
+}
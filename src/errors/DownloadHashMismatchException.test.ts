@@ -0,0 +1,20 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { DownloadHashMismatchException } from './DownloadHashMismatchException.js';
+
+describe('The download hash mismatch exception', () => {
+  it('has the details of the download', () => {
+    const url = chance.url();
+    const expectedHash = chance.hash();
+    const actualHash = chance.hash();
+
+    const exception = new DownloadHashMismatchException(url, expectedHash, actualHash);
+
+    expect(exception.url).toEqual(url);
+    expect(exception.expectedHash).toEqual(expectedHash);
+    expect(exception.actualHash).toEqual(actualHash);
+    expect(exception.message).toEqual(
+      `The file downloaded from "${url}" is corrupt (expected hash ${expectedHash}, got ${actualHash}). Please try again`
+    );
+  });
+});
@@ -0,0 +1,14 @@
+export class DownloadHashMismatchException extends Error {
+  public readonly url: string;
+  public readonly expectedHash: string;
+  public readonly actualHash: string;
+
+  constructor(url: string, expectedHash: string, actualHash: string) {
+    super(
+      `The file downloaded from "${url}" is corrupt (expected hash ${expectedHash}, got ${actualHash}). Please try again`
+    );
+    this.url = url;
+    this.expectedHash = expectedHash;
+    this.actualHash = actualHash;
+  }
+}
@@ -0,0 +1,18 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { DownloadRedirectedException } from './DownloadRedirectedException.js';
+
+describe('The download redirected exception', () => {
+  it('has the details of the redirect', () => {
+    const url = chance.url({ protocol: 'https' });
+    const location = chance.url({ protocol: 'http' });
+
+    const exception = new DownloadRedirectedException(url, location);
+
+    expect(exception.url).toEqual(url);
+    expect(exception.location).toEqual(location);
+    expect(exception.message).toEqual(
+      `The file at "${url}" redirects to "${location}", which is not a secure address. The file was not downloaded`
+    );
+  });
+});
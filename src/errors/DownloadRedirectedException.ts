@@ -0,0 +1,12 @@
+export class DownloadRedirectedException extends Error {
+  public readonly url: string;
+  public readonly location: string;
+
+  constructor(url: string, location: string) {
+    super(
+      `The file at "${url}" redirects to "${location}", which is not a secure address. The file was not downloaded`
+    );
+    this.url = url;
+    this.location = location;
+  }
+}
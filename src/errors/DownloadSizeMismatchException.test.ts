@@ -0,0 +1,20 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { DownloadSizeMismatchException } from './DownloadSizeMismatchException.js';
+
+describe('The download size mismatch exception', () => {
+  it('has the details of the download', () => {
+    const url = chance.url();
+    const expectedLength = chance.integer({ min: 1, max: 100000 });
+    const actualLength = chance.integer({ min: 1, max: 100000 });
+
+    const exception = new DownloadSizeMismatchException(url, expectedLength, actualLength);
+
+    expect(exception.url).toEqual(url);
+    expect(exception.expectedLength).toEqual(expectedLength);
+    expect(exception.actualLength).toEqual(actualLength);
+    expect(exception.message).toEqual(
+      `The file at "${url}" has ${actualLength} bytes instead of ${expectedLength}. Please try again later`
+    );
+  });
+});
@@ -0,0 +1,12 @@
+export class DownloadSizeMismatchException extends Error {
+  public readonly url: string;
+  public readonly expectedLength: number;
+  public readonly actualLength: number;
+
+  constructor(url: string, expectedLength: number, actualLength: number) {
+    super(`The file at "${url}" has ${actualLength} bytes instead of ${expectedLength}. Please try again later`);
+    this.url = url;
+    this.expectedLength = expectedLength;
+    this.actualLength = actualLength;
+  }
+}
@@ -0,0 +1,16 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { DownloadStalledException } from './DownloadStalledException.js';
+
+describe('The download stalled exception', () => {
+  it('tells which download stalled and for how long', () => {
+    const url = chance.url();
+    const idleTimeout = chance.integer({ min: 1 });
+
+    const error = new DownloadStalledException(url, idleTimeout);
+
+    expect(error.url).toEqual(url);
+    expect(error.idleTimeout).toEqual(idleTimeout);
+    expect(error.message).toEqual(`The download of "${url}" got no data for ${idleTimeout}ms`);
+  });
+});
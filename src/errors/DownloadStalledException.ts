@@ -0,0 +1,10 @@
+export class DownloadStalledException extends Error {
+  public readonly url: string;
+  public readonly idleTimeout: number;
+
+  constructor(url: string, idleTimeout: number) {
+    super(`The download of "${url}" got no data for ${idleTimeout}ms`);
+    this.url = url;
+    this.idleTimeout = idleTimeout;
+  }
+}
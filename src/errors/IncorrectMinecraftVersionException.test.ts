@@ -0,0 +1,27 @@
+import { describe, expect, it } from 'vitest';
+import { IncorrectMinecraftVersionException, didYouMean } from './IncorrectMinecraftVersionException.js';
+
+describe('The incorrect Minecraft version exception', () => {
+  it.each([
+    [[], ''],
+    [['1.20.1'], ' Did you mean 1.20.1?'],
+    [['1.20.1', '1.20'], ' Did you mean 1.20.1 or 1.20?'],
+    [['1.20.1', '1.20', '1.2.1'], ' Did you mean 1.20.1, 1.20 or 1.2.1?']
+  ])('offers %j as %j', (suggestions, expected) => {
+    expect(didYouMean(suggestions)).toEqual(expected);
+  });
+
+  it('has no suggestions by default', () => {
+    const error = new IncorrectMinecraftVersionException('1.20.l');
+
+    expect(error.message).toEqual('The specified Minecraft version (1.20.l) is not valid.');
+    expect(error.suggestions).toEqual([]);
+  });
+
+  it('offers the suggestions', () => {
+    const error = new IncorrectMinecraftVersionException('1.20.l', ['1.20.1']);
+
+    expect(error.message).toEqual('The specified Minecraft version (1.20.l) is not valid. Did you mean 1.20.1?');
+    expect(error.suggestions).toEqual(['1.20.1']);
+  });
+});
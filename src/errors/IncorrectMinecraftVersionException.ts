@@ -1,5 +1,20 @@
+/**
+ * Offers the versions the user might have meant, like: Did you mean 1.20.1 or 1.20?
+ */
+export const didYouMean = (suggestions: string[] = []): string => {
+  if (suggestions.length === 0) {
+    return '';
+  }
+  const last = suggestions[suggestions.length - 1];
+  const rest = suggestions.slice(0, -1);
+  return ` Did you mean ${rest.length > 0 ? `${rest.join(', ')} or ${last}` : last}?`;
+};
+
 export class IncorrectMinecraftVersionException extends Error {
-  constructor(version: string) {
-    super(`The specified Minecraft version (${version}) is not valid.`);
+  public readonly suggestions: string[];
+
+  constructor(version: string, suggestions: string[] = []) {
+    super(`The specified Minecraft version (${version}) is not valid.${didYouMean(suggestions)}`);
+    this.suggestions = suggestions;
   }
 }
@@ -0,0 +1,17 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { generateRandomPlatform } from '../../test/generateRandomPlatform.js';
+import { InvalidBaseUrlException } from './InvalidBaseUrlException.js';
+
+describe('The Invalid Base Url Exception', () => {
+  it('records the platform and the url', () => {
+    const platform = generateRandomPlatform();
+    const url = chance.word();
+
+    const error = new InvalidBaseUrlException(platform, url);
+
+    expect(error.platform).toBe(platform);
+    expect(error.url).toBe(url);
+    expect(error.message).toBe(`The API url for ${platform} is not a valid http(s) url: ${url}`);
+  });
+});
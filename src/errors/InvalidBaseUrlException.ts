@@ -0,0 +1,10 @@
+export class InvalidBaseUrlException extends Error {
+  public readonly platform: string;
+  public readonly url: string;
+
+  constructor(platform: string, url: string) {
+    super(`The API url for ${platform} is not a valid http(s) url: ${url}`);
+    this.platform = platform;
+    this.url = url;
+  }
+}
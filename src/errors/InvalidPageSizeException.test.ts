@@ -0,0 +1,14 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { InvalidPageSizeException } from './InvalidPageSizeException.js';
+
+describe('The Invalid Page Size Exception', () => {
+  it('records the page size', () => {
+    const pageSize = chance.integer({ max: 0 });
+
+    const error = new InvalidPageSizeException(pageSize);
+
+    expect(error.pageSize).toBe(pageSize);
+    expect(error.message).toBe(`The page size has to be a whole number above 0, got ${pageSize}`);
+  });
+});
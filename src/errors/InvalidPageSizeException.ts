@@ -0,0 +1,8 @@
+export class InvalidPageSizeException extends Error {
+  public readonly pageSize: number;
+
+  constructor(pageSize: number) {
+    super(`The page size has to be a whole number above 0, got ${pageSize}`);
+    this.pageSize = pageSize;
+  }
+}
@@ -0,0 +1,16 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { LockFileCorruptedException } from './LockFileCorruptedException.js';
+
+describe('The lock file corrupted exception', () => {
+  it('points to the way to rebuild the lock file', () => {
+    const lockFile = `${chance.word()}-lock.json`;
+
+    const exception = new LockFileCorruptedException(lockFile);
+
+    expect(exception.lockFile).toEqual(lockFile);
+    expect(exception.message).toEqual(
+      `The lock file at "${lockFile}" is corrupted. Run mmm scan --add to rebuild it from the mods folder`
+    );
+  });
+});
@@ -0,0 +1,8 @@
+export class LockFileCorruptedException extends Error {
+  public readonly lockFile: string;
+
+  constructor(lockFile: string) {
+    super(`The lock file at "${lockFile}" is corrupted. Run mmm scan --add to rebuild it from the mods folder`);
+    this.lockFile = lockFile;
+  }
+}
@@ -0,0 +1,22 @@
+import { describe, expect, it } from 'vitest';
+import { ModsFolderInvalidException, ModsFolderProblem } from './ModsFolderInvalidException.js';
+
+describe('The mods folder invalid exception', () => {
+  it.each([
+    [
+      ModsFolderProblem.MISSING,
+      "The mods folder /mods doesn't exist, please create it or fix the modsFolder in the configuration"
+    ],
+    [
+      ModsFolderProblem.NOT_A_DIRECTORY,
+      'The mods folder /mods is a file, please point the modsFolder of the configuration to a folder'
+    ],
+    [ModsFolderProblem.NOT_WRITABLE, "The mods folder /mods can't be written, please check its permissions"]
+  ])('explains the %s folder', (problem, expected) => {
+    const exception = new ModsFolderInvalidException('/mods', problem);
+
+    expect(exception.modsFolder).toEqual('/mods');
+    expect(exception.problem).toEqual(problem);
+    expect(exception.message).toEqual(expected);
+  });
+});
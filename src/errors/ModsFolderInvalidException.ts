@@ -0,0 +1,23 @@
+/* eslint-disable no-unused-vars */
+export enum ModsFolderProblem {
+  MISSING = 'missing',
+  NOT_A_DIRECTORY = 'not-a-directory',
+  NOT_WRITABLE = 'not-writable'
+}
+
+const explanations: Record<ModsFolderProblem, string> = {
+  [ModsFolderProblem.MISSING]: "doesn't exist, please create it or fix the modsFolder in the configuration",
+  [ModsFolderProblem.NOT_A_DIRECTORY]: 'is a file, please point the modsFolder of the configuration to a folder',
+  [ModsFolderProblem.NOT_WRITABLE]: "can't be written, please check its permissions"
+};
+
+export class ModsFolderInvalidException extends Error {
+  public readonly modsFolder: string;
+  public readonly problem: ModsFolderProblem;
+
+  constructor(modsFolder: string, problem: ModsFolderProblem) {
+    super(`The mods folder ${modsFolder} ${explanations[problem]}`);
+    this.modsFolder = modsFolder;
+    this.problem = problem;
+  }
+}
@@ -0,0 +1,14 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { MrpackLoaderUnsupportedException } from './MrpackLoaderUnsupportedException.js';
+
+describe('The mrpack loader unsupported exception', () => {
+  it('tells the loader and the ones that can be used', () => {
+    const loader = chance.word();
+
+    const error = new MrpackLoaderUnsupportedException(loader, ['fabric', 'quilt']);
+
+    expect(error.loader).toEqual(loader);
+    expect(error.message).toEqual(`A Modrinth modpack can't use the ${loader} loader, only fabric, quilt`);
+  });
+});
@@ -0,0 +1,8 @@
+export class MrpackLoaderUnsupportedException extends Error {
+  public readonly loader: string;
+
+  constructor(loader: string, supported: string[]) {
+    super(`A Modrinth modpack can't use the ${loader} loader, only ${supported.join(', ')}`);
+    this.loader = loader;
+  }
+}
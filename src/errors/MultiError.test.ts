@@ -0,0 +1,33 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { CouldNotFindModException } from './CouldNotFindModException.js';
+import { MultiError } from './MultiError.js';
+import { describeFetchError } from './handleFetchErrors.js';
+
+describe('The multi error', () => {
+  it('lists every failed mod', () => {
+    const first = { mod: generateModConfig().generated, error: new Error(chance.sentence()) };
+    const second = { mod: generateModConfig().generated, error: new Error(chance.sentence()) };
+
+    const error = new MultiError([first, second]);
+
+    expect(error.failures).toEqual([first, second]);
+    expect(error.message).toEqual(
+      [
+        '2 mod(s) failed:',
+        `  - ${first.mod.name}(${first.mod.id}) from ${first.mod.type}: ${first.error.message}`,
+        `  - ${second.mod.name}(${second.mod.id}) from ${second.mod.type}: ${second.error.message}`
+      ].join('\n')
+    );
+  });
+
+  it('uses the friendly message of the expected failures', () => {
+    const mod = generateModConfig().generated;
+    const failure = { mod: mod, error: new CouldNotFindModException(mod.id, mod.type) };
+
+    const error = new MultiError([failure]);
+
+    expect(error.message).toEqual(['1 mod(s) failed:', `  - ${describeFetchError(failure.error, mod)}`].join('\n'));
+  });
+});
@@ -0,0 +1,23 @@
+import { Mod } from '../lib/modlist.types.js';
+import { describeFetchError } from './handleFetchErrors.js';
+
+export interface ModFailure {
+  mod: Mod;
+  error: Error;
+}
+
+/**
+ * The mods that failed in a run that carried on with the rest of them
+ */
+export class MultiError extends Error {
+  public readonly failures: ModFailure[];
+
+  constructor(failures: ModFailure[]) {
+    const details = failures.map(
+      ({ mod, error }) =>
+        `  - ${describeFetchError(error, mod) ?? `${mod.name}(${mod.id}) from ${mod.type}: ${error.message}`}`
+    );
+    super([`${failures.length} mod(s) failed:`, ...details].join('\n'));
+    this.failures = failures;
+  }
+}
@@ -0,0 +1,43 @@
+import { describe, expect, it } from 'vitest';
+import { Platform } from '../lib/modlist.types.js';
+import { NoRemoteFileFound, describeSupportedTargets } from './NoRemoteFileFound.js';
+
+describe('The no remote file found exception', () => {
+  it('has the details of the mod', () => {
+    const exception = new NoRemoteFileFound('Sodium', Platform.MODRINTH);
+
+    expect(exception.modName).toEqual('Sodium');
+    expect(exception.platform).toEqual(Platform.MODRINTH);
+    expect(exception.supported).toBeUndefined();
+    expect(exception.message).toEqual('No compatible files were found for the given mod: modrinth: Sodium');
+  });
+
+  it('tells what the files of the mod are made for', () => {
+    const supported = { gameVersions: ['1.20.4', '1.20.1'], loaders: ['fabric', 'quilt'] };
+
+    const exception = new NoRemoteFileFound('Sodium', Platform.CURSEFORGE, supported);
+
+    expect(exception.supported).toBe(supported);
+    expect(exception.message).toEqual(
+      'No compatible files were found for the given mod: curseforge: Sodium. It has files for the game versions ' +
+        '1.20.4, 1.20.1 and the loaders fabric, quilt.'
+    );
+  });
+
+  it.each([
+    [undefined, ''],
+    [{ gameVersions: [], loaders: [] }, ''],
+    [{ gameVersions: ['1.20.1'], loaders: [] }, 'It has files for the game versions 1.20.1.'],
+    [{ gameVersions: [], loaders: ['forge'] }, 'It has files for the loaders forge.']
+  ])('describes %j as %j', (supported, expected) => {
+    expect(describeSupportedTargets(supported)).toEqual(expected);
+  });
+
+  it('only lists the newest ten game versions', () => {
+    const gameVersions = Array.from({ length: 12 }, (_, index) => `1.${20 - index}`);
+
+    expect(describeSupportedTargets({ gameVersions: gameVersions, loaders: [] })).toEqual(
+      'It has files for the game versions 1.20, 1.19, 1.18, 1.17, 1.16, 1.15, 1.14, 1.13, 1.12, 1.11 and 2 older.'
+    );
+  });
+});
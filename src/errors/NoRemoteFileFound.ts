@@ -1,12 +1,50 @@
 import { Platform } from '../lib/modlist.types.js';
 
+/**
+ * What the files of a mod are made for, so the user can see why none of them fit
+ */
+export interface SupportedTargets {
+  /**
+   * Newest first
+   */
+  gameVersions: string[];
+  loaders: string[];
+}
+
+const shownGameVersions = 10;
+
+const describeGameVersions = (gameVersions: string[]) => {
+  const shown = gameVersions.slice(0, shownGameVersions).join(', ');
+  const hidden = gameVersions.length - shownGameVersions;
+  return hidden > 0 ? `${shown} and ${hidden} older` : shown;
+};
+
+/**
+ * Like: It has files for the game versions 1.20.4, 1.20.1 and the loaders fabric, quilt.
+ * Empty when nothing is known about the files.
+ */
+export const describeSupportedTargets = (supported?: SupportedTargets): string => {
+  const parts = [];
+  if (supported && supported.gameVersions.length > 0) {
+    parts.push(`the game versions ${describeGameVersions(supported.gameVersions)}`);
+  }
+  if (supported && supported.loaders.length > 0) {
+    parts.push(`the loaders ${supported.loaders.join(', ')}`);
+  }
+  return parts.length > 0 ? `It has files for ${parts.join(' and ')}.` : '';
+};
+
 export class NoRemoteFileFound extends Error {
   public readonly modName: string;
   public readonly platform: Platform;
+  public readonly supported?: SupportedTargets;
 
-  constructor(modName: string, platform: Platform) {
-    super(`No compatible files were found for the given mod: ${platform}: ${modName}`);
+  constructor(modName: string, platform: Platform, supported?: SupportedTargets) {
+    const message = `No compatible files were found for the given mod: ${platform}: ${modName}`;
+    const description = describeSupportedTargets(supported);
+    super(description ? `${message}. ${description}` : message);
     this.modName = modName;
     this.platform = platform;
+    this.supported = supported;
   }
 }
@@ -0,0 +1,14 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { OfflineException } from './OfflineException.js';
+
+describe('The Offline Exception', () => {
+  it('records the url', () => {
+    const url = chance.url();
+
+    const error = new OfflineException(url);
+
+    expect(error.url).toBe(url);
+    expect(error.message).toBe(`Cannot reach ${url} in offline mode`);
+  });
+});
@@ -0,0 +1,8 @@
+export class OfflineException extends Error {
+  public readonly url: string;
+
+  constructor(url: string) {
+    super(`Cannot reach ${url} in offline mode`);
+    this.url = url;
+  }
+}
@@ -0,0 +1,16 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { PackwizPackInvalidException } from './PackwizPackInvalidException.js';
+
+describe('The packwiz pack invalid exception', () => {
+  it('tells which file is wrong and why', () => {
+    const file = chance.word();
+    const reason = chance.sentence();
+
+    const error = new PackwizPackInvalidException(file, reason);
+
+    expect(error.file).toEqual(file);
+    expect(error.reason).toEqual(reason);
+    expect(error.message).toEqual(`The packwiz file ${file} can not be imported: ${reason}`);
+  });
+});
@@ -0,0 +1,10 @@
+export class PackwizPackInvalidException extends Error {
+  public readonly file: string;
+  public readonly reason: string;
+
+  constructor(file: string, reason: string) {
+    super(`The packwiz file ${file} can not be imported: ${reason}`);
+    this.file = file;
+    this.reason = reason;
+  }
+}
@@ -0,0 +1,31 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { RunInProgressException } from './RunInProgressException.js';
+
+describe('The Run In Progress Exception', () => {
+  it('tells which process holds the run lock', () => {
+    const runLock = chance.word();
+    const pid = chance.natural();
+
+    const error = new RunInProgressException(runLock, pid);
+
+    expect(error.runLock).toBe(runLock);
+    expect(error.pid).toBe(pid);
+    expect(error.message).toBe(
+      `Another run of mmm (process ${pid}) is changing the modlist. ` +
+        `Wait for it to finish, or delete ${runLock} if it isn't running anymore`
+    );
+  });
+
+  it('leaves the process out when it is not known', () => {
+    const runLock = chance.word();
+
+    const error = new RunInProgressException(runLock);
+
+    expect(error.pid).toBeUndefined();
+    expect(error.message).toBe(
+      'Another run of mmm is changing the modlist. ' +
+        `Wait for it to finish, or delete ${runLock} if it isn't running anymore`
+    );
+  });
+});
@@ -0,0 +1,11 @@
+export class RunInProgressException extends Error {
+  public readonly runLock: string;
+  public readonly pid?: number;
+
+  constructor(runLock: string, pid?: number) {
+    const holder = pid === undefined ? 'Another run of mmm' : `Another run of mmm (process ${pid})`;
+    super(`${holder} is changing the modlist. Wait for it to finish, or delete ${runLock} if it isn't running anymore`);
+    this.runLock = runLock;
+    this.pid = pid;
+  }
+}
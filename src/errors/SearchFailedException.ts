@@ -0,0 +1,12 @@
+import { Platform } from '../lib/modlist.types.js';
+
+export class SearchFailedException extends Error {
+  public readonly query: string;
+  public readonly platform: Platform;
+
+  constructor(query: string, platform: Platform) {
+    super(`Could not search for mods on ${platform}: ${query}`);
+    this.query = query;
+    this.platform = platform;
+  }
+}
@@ -0,0 +1,16 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { TomlSyntaxError } from './TomlSyntaxError.js';
+
+describe('The TOML syntax error', () => {
+  it('tells which line is wrong and why', () => {
+    const line = chance.integer({ min: 1 });
+    const reason = chance.sentence();
+
+    const error = new TomlSyntaxError(line, reason);
+
+    expect(error.line).toEqual(line);
+    expect(error.reason).toEqual(reason);
+    expect(error.message).toEqual(`Line ${line} of the TOML file is invalid: ${reason}`);
+  });
+});
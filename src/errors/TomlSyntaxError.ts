@@ -0,0 +1,10 @@
+export class TomlSyntaxError extends Error {
+  public readonly line: number;
+  public readonly reason: string;
+
+  constructor(line: number, reason: string) {
+    super(`Line ${line} of the TOML file is invalid: ${reason}`);
+    this.line = line;
+    this.reason = reason;
+  }
+}
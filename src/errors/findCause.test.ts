@@ -0,0 +1,48 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { generateRandomPlatform } from '../../test/generateRandomPlatform.js';
+import { CouldNotFindModException } from './CouldNotFindModException.js';
+import { NoRemoteFileFound } from './NoRemoteFileFound.js';
+import { findCause } from './findCause.js';
+
+describe('The cause finder', () => {
+  it('finds the error itself', () => {
+    const error = new CouldNotFindModException(chance.word(), generateRandomPlatform());
+
+    expect(findCause(error, CouldNotFindModException)).toBe(error);
+  });
+
+  it('finds a wrapped error with its fields', () => {
+    const modId = chance.word();
+    const platform = generateRandomPlatform();
+    const notFound = new CouldNotFindModException(modId, platform);
+    const wrapped = new Error(chance.sentence(), { cause: new Error(chance.sentence(), { cause: notFound }) });
+
+    const actual = findCause(wrapped, CouldNotFindModException);
+
+    expect(actual).toBe(notFound);
+    expect(actual?.modId).toEqual(modId);
+    expect(actual?.platform).toEqual(platform);
+  });
+
+  it('does not confuse other errors', () => {
+    const error = new Error(chance.sentence(), {
+      cause: new NoRemoteFileFound(chance.word(), generateRandomPlatform())
+    });
+
+    expect(findCause(error, CouldNotFindModException)).toBeUndefined();
+  });
+
+  it('handles values that are not errors', () => {
+    expect(findCause(undefined, CouldNotFindModException)).toBeUndefined();
+    expect(findCause(null, CouldNotFindModException)).toBeUndefined();
+    expect(findCause(chance.word(), CouldNotFindModException)).toBeUndefined();
+  });
+
+  it('stops following a circular cause chain', () => {
+    const error = new Error(chance.sentence());
+    Object.assign(error, { cause: error });
+
+    expect(findCause(error, CouldNotFindModException)).toBeUndefined();
+  });
+});
@@ -0,0 +1,18 @@
+type ErrorClass<T extends Error> = new (...args: never[]) => T;
+
+const MAX_DEPTH = 10;
+
+/**
+ * Returns the first error in the cause chain that is an instance of the given class.
+ * This lets callers branch on an error even after something else wrapped it.
+ */
+export const findCause = <T extends Error>(error: unknown, errorClass: ErrorClass<T>): T | undefined => {
+  let current = error;
+  for (let depth = 0; depth < MAX_DEPTH && current !== undefined && current !== null; depth++) {
+    if (current instanceof errorClass) {
+      return current;
+    }
+    current = (current as { cause?: unknown }).cause;
+  }
+  return undefined;
+};
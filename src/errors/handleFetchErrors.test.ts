@@ -2,11 +2,16 @@ import { chance } from 'jest-chance';
 import { beforeEach, describe, expect, it, vi } from 'vitest';
 import { generateModConfig } from '../../test/modConfigGenerator.js';
 import { Logger } from '../lib/Logger.js';
-import { Mod } from '../lib/modlist.types.js';
+import { Mod, Platform } from '../lib/modlist.types.js';
+import { CircuitOpen } from '../lib/rateLimiter/CircuitOpen.js';
+import { CurseforgeUnauthorized } from '../lib/rateLimiter/CurseforgeUnauthorized.js';
+import { RateLimited } from '../lib/rateLimiter/RateLimited.js';
+import { RequestTimedOut } from '../lib/rateLimiter/RequestTimedOut.js';
 import { CouldNotFindModException } from './CouldNotFindModException.js';
 import { DownloadFailedException } from './DownloadFailedException.js';
 import { NoRemoteFileFound } from './NoRemoteFileFound.js';
-import { handleFetchErrors } from './handleFetchErrors.js';
+import { OfflineException } from './OfflineException.js';
+import { describeFetchError, handleFetchErrors } from './handleFetchErrors.js';
 
 interface LocalTestContext {
   logger: Logger;
@@ -41,6 +46,13 @@ describe('The mod fetch error handler', () => {
     expect(logCall[1]).toBeTruthy();
   });
 
+  it<LocalTestContext>('handles when the mod cannot be found behind another error', ({ logger, randomMod }) => {
+    const error = new Error(chance.sentence(), { cause: new CouldNotFindModException(randomMod.id, randomMod.type) });
+    handleFetchErrors(error, randomMod, logger);
+
+    expect(vi.mocked(logger.log).mock.calls[0][0]).toContain('cannot be found on');
+  });
+
   it<LocalTestContext>('handles when no remote files are found', ({ logger, randomMod }) => {
     const error = new NoRemoteFileFound(randomMod.name, randomMod.type);
     handleFetchErrors(error, randomMod, logger);
@@ -56,6 +68,87 @@ describe('The mod fetch error handler', () => {
     expect(logCall[1]).toBeTruthy();
   });
 
+  it<LocalTestContext>('tells what the files of the mod are made for', ({ logger, randomMod }) => {
+    const supported = { gameVersions: ['1.20.4'], loaders: ['fabric'] };
+    const error = new NoRemoteFileFound(randomMod.name, randomMod.type, supported);
+    handleFetchErrors(error, randomMod, logger);
+
+    expect(vi.mocked(logger.log).mock.calls[0][0]).toContain(
+      'Please update it. It has files for the game versions 1.20.4 and the loaders fabric.'
+    );
+  });
+
+  it<LocalTestContext>('handles when the platform is rate limiting us', ({ logger, randomMod }) => {
+    const error = new RateLimited({ statusText: 'Too Many Requests' } as Response, 30500, Platform.MODRINTH);
+    handleFetchErrors(error, randomMod, logger);
+
+    const logCall = vi.mocked(logger.log).mock.calls[0];
+    const logMessage = logCall[0];
+    expect(logMessage).toContain(randomMod.name);
+    expect(logMessage).toContain(randomMod.id);
+    expect(logMessage).toContain('modrinth is rate limiting us');
+    expect(logMessage).toContain('Please try again in 31 seconds.');
+
+    expect(logCall[1]).toBeTruthy();
+  });
+
+  it<LocalTestContext>('handles when the rate limit has no end in sight', ({ logger, randomMod }) => {
+    const error = new RateLimited({ statusText: 'Too Many Requests' } as Response, null);
+    handleFetchErrors(error, randomMod, logger);
+
+    const logMessage = vi.mocked(logger.log).mock.calls[0][0];
+    expect(logMessage).toContain(`${randomMod.type} is rate limiting us`);
+    expect(logMessage).toContain('Please try again later.');
+  });
+
+  it<LocalTestContext>('handles when the platform does not answer in time', ({ logger, randomMod }) => {
+    const error = new RequestTimedOut(chance.url(), chance.integer({ min: 1 }));
+    handleFetchErrors(error, randomMod, logger);
+
+    const logCall = vi.mocked(logger.log).mock.calls[0];
+    const logMessage = logCall[0];
+    expect(logMessage).toContain(`${randomMod.type} did not answer in time`);
+    expect(logMessage).toContain(randomMod.name);
+    expect(logMessage).toContain('Please try again later.');
+    expect(logCall[1]).toBeTruthy();
+  });
+
+  it<LocalTestContext>('handles when the platform keeps failing', ({ logger, randomMod }) => {
+    const error = new CircuitOpen(chance.domain(), 12001);
+    handleFetchErrors(error, randomMod, logger);
+
+    const logCall = vi.mocked(logger.log).mock.calls[0];
+    const logMessage = logCall[0];
+    expect(logMessage).toContain(`${randomMod.type} keeps failing`);
+    expect(logMessage).toContain(randomMod.name);
+    expect(logMessage).toContain('Please try again in 13 seconds.');
+    expect(logCall[1]).toBeTruthy();
+  });
+
+  it<LocalTestContext>('handles when curseforge does not accept the api key', ({ logger, randomMod }) => {
+    const error = new CurseforgeUnauthorized(chance.url(), 403);
+    handleFetchErrors(error, randomMod, logger);
+
+    const logCall = vi.mocked(logger.log).mock.calls[0];
+    const logMessage = logCall[0];
+    expect(logMessage).toContain('Curseforge did not accept the API key');
+    expect(logMessage).toContain(randomMod.name);
+    expect(logMessage).toContain('CURSEFORGE_API_KEY');
+    expect(logCall[1]).toBeTruthy();
+  });
+
+  it<LocalTestContext>('handles when mmm is offline', ({ logger, randomMod }) => {
+    const error = new OfflineException(chance.url());
+    handleFetchErrors(error, randomMod, logger);
+
+    const logCall = vi.mocked(logger.log).mock.calls[0];
+    const logMessage = logCall[0];
+    expect(logMessage).toContain(randomMod.name);
+    expect(logMessage).toContain(randomMod.id);
+    expect(logMessage).toContain(`needs ${randomMod.type} but mmm is running in offline mode.`);
+    expect(logCall[1]).toBeTruthy();
+  });
+
   it<LocalTestContext>('handles when the download fails', ({ logger, randomMod }) => {
     const url = chance.url({ protocol: 'http' });
     const error = new DownloadFailedException(url);
@@ -77,3 +170,15 @@ describe('The mod fetch error handler', () => {
     }).toThrow(error);
   });
 });
+
+describe('The mod fetch error description', () => {
+  it('describes the expected errors', () => {
+    const randomMod = generateModConfig().generated;
+
+    expect(describeFetchError(new OfflineException(chance.url()), randomMod)).toContain('offline mode');
+  });
+
+  it('does not describe the unexpected errors', () => {
+    expect(describeFetchError(new Error(chance.sentence()), generateModConfig().generated)).toBeUndefined();
+  });
+});
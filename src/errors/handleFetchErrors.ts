@@ -1,24 +1,60 @@
 import chalk from 'chalk';
 import { Logger } from '../lib/Logger.js';
 import { Mod } from '../lib/modlist.types.js';
+import { CircuitOpen } from '../lib/rateLimiter/CircuitOpen.js';
+import { CurseforgeUnauthorized } from '../lib/rateLimiter/CurseforgeUnauthorized.js';
+import { RateLimited } from '../lib/rateLimiter/RateLimited.js';
+import { RequestTimedOut } from '../lib/rateLimiter/RequestTimedOut.js';
 import { CouldNotFindModException } from './CouldNotFindModException.js';
 import { DownloadFailedException } from './DownloadFailedException.js';
-import { NoRemoteFileFound } from './NoRemoteFileFound.js';
+import { NoRemoteFileFound, describeSupportedTargets } from './NoRemoteFileFound.js';
+import { OfflineException } from './OfflineException.js';
+import { findCause } from './findCause.js';
 
-export const handleFetchErrors = (error: Error, mod: Mod, logger: Logger) => {
-  if (error instanceof CouldNotFindModException) {
-    logger.log(
-      `${chalk.red('\u274c')} ${mod.name}${chalk.gray('(' + mod.id + ')')} cannot be found on ${mod.type} anymore. Was the mod revoked?`,
-      true
-    );
-    return;
+/**
+ * The message for the errors that are expected when fetching a mod
+ *
+ * @returns undefined when the error is not one of them
+ */
+export const describeFetchError = (error: Error, mod: Mod): string | undefined => {
+  if (findCause(error, CouldNotFindModException)) {
+    return `${mod.name}${chalk.gray('(' + mod.id + ')')} cannot be found on ${mod.type} anymore. Was the mod revoked?`;
   }
 
   if (error instanceof NoRemoteFileFound) {
-    logger.log(
-      `${chalk.red('\u274c')} ${mod.type} doesn't serve the required file for ${mod.name}${chalk.gray('(' + mod.id + ')')} anymore. Please update it.`,
-      true
-    );
+    const supported = describeSupportedTargets(error.supported);
+    return `${mod.type} doesn't serve the required file for ${mod.name}${chalk.gray('(' + mod.id + ')')} anymore. Please update it.${supported ? ` ${supported}` : ''}`;
+  }
+
+  if (error instanceof RateLimited) {
+    const retryAfter = error.retryAfter();
+    const when = retryAfter === null ? 'later' : `in ${Math.ceil(retryAfter / 1000)} seconds`;
+    return `${error.platform() ?? mod.type} is rate limiting us, ${mod.name}${chalk.gray('(' + mod.id + ')')} could not be checked. Please try again ${when}.`;
+  }
+
+  if (error instanceof CurseforgeUnauthorized) {
+    return `Curseforge did not accept the API key, ${mod.name}${chalk.gray('(' + mod.id + ')')} could not be checked. Set the CURSEFORGE_API_KEY environment variable to a valid key.`;
+  }
+
+  if (error instanceof RequestTimedOut) {
+    return `${mod.type} did not answer in time, ${mod.name}${chalk.gray('(' + mod.id + ')')} could not be checked. Please try again later.`;
+  }
+
+  if (error instanceof CircuitOpen) {
+    return `${mod.type} keeps failing, ${mod.name}${chalk.gray('(' + mod.id + ')')} could not be checked. Please try again in ${Math.ceil(error.retryIn() / 1000)} seconds.`;
+  }
+
+  if (error instanceof OfflineException) {
+    return `${mod.name}${chalk.gray('(' + mod.id + ')')} needs ${mod.type} but mmm is running in offline mode.`;
+  }
+
+  return undefined;
+};
+
+export const handleFetchErrors = (error: Error, mod: Mod, logger: Logger) => {
+  const description = describeFetchError(error, mod);
+  if (description) {
+    logger.log(`${chalk.red('\u274c')} ${description}`, true);
     return;
   }
 
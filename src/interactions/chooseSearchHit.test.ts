@@ -0,0 +1,62 @@
+import { select } from '@inquirer/prompts';
+import { afterEach, describe, expect, it, vi } from 'vitest';
+import { generateSearchHit } from '../../test/generateSearchHit.js';
+import { SearchHit } from '../repositories/index.js';
+import { chooseSearchHit } from './chooseSearchHit.js';
+
+vi.mock('@inquirer/prompts');
+
+const hit = (id: string, overrides?: Partial<SearchHit>): SearchHit =>
+  generateSearchHit({
+    id: id,
+    name: `Mod ${id}`,
+    author: 'someone',
+    downloads: 1500,
+    latestGameVersion: undefined,
+    ...overrides
+  }).generated;
+
+describe('The search hit chooser', () => {
+  afterEach(() => {
+    vi.resetAllMocks();
+  });
+
+  it('numbers the candidates in the order they were found', async () => {
+    vi.mocked(select).mockResolvedValueOnce('2');
+
+    await chooseSearchHit('sodium', [hit('1', { latestGameVersion: '1.21' }), hit('2')]);
+
+    expect(vi.mocked(select).mock.calls[0][0]).toMatchInlineSnapshot(`
+      {
+        "choices": [
+          {
+            "name": "1. Mod 1 by someone (1500 downloads, up to Minecraft 1.21)",
+            "value": "1",
+          },
+          {
+            "name": "2. Mod 2 by someone (1500 downloads)",
+            "value": "2",
+          },
+          {
+            "name": "None of these",
+            "value": "",
+          },
+        ],
+        "message": "More than one mod is called "sodium", which one did you mean?",
+      }
+    `);
+  });
+
+  it('returns the chosen candidate', async () => {
+    const candidates = [hit('1'), hit('2')];
+    vi.mocked(select).mockResolvedValueOnce('2');
+
+    expect(await chooseSearchHit('sodium', candidates)).toEqual(candidates[1]);
+  });
+
+  it('returns nothing when none of them was meant', async () => {
+    vi.mocked(select).mockResolvedValueOnce('');
+
+    expect(await chooseSearchHit('sodium', [hit('1'), hit('2')])).toBeUndefined();
+  });
+});
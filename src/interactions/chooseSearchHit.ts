@@ -0,0 +1,23 @@
+import { select } from '@inquirer/prompts';
+import chalk from 'chalk';
+import { SearchHit } from '../repositories/index.js';
+
+const describeHit = (hit: SearchHit) => {
+  const latest = hit.latestGameVersion ? `, up to Minecraft ${hit.latestGameVersion}` : '';
+  return `${hit.name} by ${hit.author} (${hit.downloads} downloads${latest})`;
+};
+
+/**
+ * Asks which of the mods found for the name was meant, undefined when it's none of them
+ */
+export const chooseSearchHit = async (name: string, candidates: SearchHit[]): Promise<SearchHit | undefined> => {
+  const chosen: string = await select({
+    message: `More than one mod is called "${chalk.whiteBright(name)}", which one did you mean?`,
+    choices: [
+      ...candidates.map((hit, index) => ({ name: `${index + 1}. ${describeHit(hit)}`, value: hit.id })),
+      { name: 'None of these', value: '' }
+    ]
+  });
+
+  return candidates.find((hit) => hit.id === chosen);
+};
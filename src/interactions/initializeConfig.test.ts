@@ -6,6 +6,7 @@ import { generateInitializeOptions } from '../../test/initializeOptionsGenerator
 import { IncorrectMinecraftVersionException } from '../errors/IncorrectMinecraftVersionException.js';
 import { Logger } from '../lib/Logger.js';
 import { fileExists, writeConfigFile } from '../lib/config.js';
+import { suggestGameVersions } from '../lib/gameVersionSuggestions.js';
 import { verifyMinecraftVersion } from '../lib/minecraftVersionVerifier.js';
 import { Loader, ReleaseType } from '../lib/modlist.types.js';
 import { configFile } from './configFileOverwrite.js';
@@ -13,6 +14,7 @@ import { getLatestMinecraftVersion } from './getLatestMinecraftVersion.js';
 import { InitializeOptions, initializeConfig } from './initializeConfig.js';
 
 vi.mock('../lib/minecraftVersionVerifier.js');
+vi.mock('../lib/gameVersionSuggestions.js');
 vi.mock('./configFileOverwrite.js');
 vi.mock('../lib/config.js', () => ({
   fileExists: vi.fn().mockResolvedValue(false),
@@ -111,6 +113,33 @@ describe('The Initialization Interaction', () => {
 
         expect(actual).toMatchInlineSnapshot('"The game version is invalid. Please enter a valid game version"');
       });
+
+      it('it should suggest the versions that were meant', async () => {
+        vi.mocked(verifyMinecraftVersion).mockReset();
+        vi.mocked(verifyMinecraftVersion).mockResolvedValueOnce(false);
+        vi.mocked(suggestGameVersions).mockResolvedValueOnce(['1.20.1', '1.2.1']);
+        const inputOptions = generateInitializeOptions({ gameVersion: '1.20.l' });
+
+        await expect(initializeConfig(inputOptions.generated, chance.word(), logger)).rejects.toThrow(
+          'The specified Minecraft version (1.20.l) is not valid. Did you mean 1.20.1 or 1.2.1?'
+        );
+        expect(suggestGameVersions).toHaveBeenCalledWith('1.20.l');
+      });
+
+      it('it should suggest the versions that were meant on the interactive ui', async () => {
+        vi.mocked(verifyMinecraftVersion).mockReset();
+        vi.mocked(verifyMinecraftVersion).mockResolvedValue(false);
+        vi.mocked(suggestGameVersions).mockResolvedValue(['1.20.1']);
+        const userInput = generateInitializeOptions().generated;
+        delete userInput.gameVersion;
+
+        await initializeConfig(userInput, chance.word(), logger);
+
+        const verifierFunction = vi.mocked(input).mock.calls[0][0].validate;
+        const actual = await verifierFunction!('1.20.l');
+
+        expect(actual).toEqual('The game version is invalid. Did you mean 1.20.1? Please enter a valid game version');
+      });
     });
   });
 
@@ -1,8 +1,9 @@
 import path from 'node:path';
 import { checkbox, input, select } from '@inquirer/prompts';
-import { IncorrectMinecraftVersionException } from '../errors/IncorrectMinecraftVersionException.js';
+import { IncorrectMinecraftVersionException, didYouMean } from '../errors/IncorrectMinecraftVersionException.js';
 import { Logger } from '../lib/Logger.js';
 import { fileExists, writeConfigFile } from '../lib/config.js';
+import { suggestGameVersions } from '../lib/gameVersionSuggestions.js';
 import { verifyMinecraftVersion } from '../lib/minecraftVersionVerifier.js';
 import { Loader, ModsJson, ReleaseType } from '../lib/modlist.types.js';
 import { DefaultOptions } from '../mmm.js';
@@ -30,7 +31,8 @@ const validateGameVersion = async (input: string): Promise<boolean | string> =>
   if (await verifyMinecraftVersion(input)) {
     return true;
   }
-  return 'The game version is invalid. Please enter a valid game version';
+  const suggestions = await suggestGameVersions(input);
+  return `The game version is invalid.${didYouMean(suggestions)} Please enter a valid game version`;
 };
 
 const mergeOptions = (options: InitializeOptions, iq: AnswersInternal) => {
@@ -65,7 +67,8 @@ const validateInput = async (options: InitializeOptions, cwd: string) => {
    */
   if (options.gameVersion) {
     if (!(await verifyMinecraftVersion(options.gameVersion))) {
-      throw new IncorrectMinecraftVersionException(options.gameVersion);
+      const suggestions = await suggestGameVersions(options.gameVersion);
+      throw new IncorrectMinecraftVersionException(options.gameVersion, suggestions);
     }
   }
   if (options.modsFolder) {
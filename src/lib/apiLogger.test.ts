@@ -0,0 +1,62 @@
+import { chance } from 'jest-chance';
+import { afterEach, describe, expect, it, vi } from 'vitest';
+import { recordingApiLogger } from '../../test/recordingApiLogger.js';
+import { formatLogLine, getApiLogger, lineApiLogger, noopApiLogger, setApiLogger } from './apiLogger.js';
+
+describe('The api logger', () => {
+  afterEach(() => {
+    setApiLogger();
+  });
+
+  it('does not log anything by default', () => {
+    const log = vi.spyOn(console, 'log');
+    const debug = vi.spyOn(console, 'debug');
+
+    expect(getApiLogger()).toBe(noopApiLogger);
+    getApiLogger().debug(chance.word());
+    getApiLogger().info(chance.word());
+    getApiLogger().warn(chance.word());
+    getApiLogger().error(chance.word());
+
+    expect(log).not.toHaveBeenCalled();
+    expect(debug).not.toHaveBeenCalled();
+  });
+
+  it('logs to the logger it was given', () => {
+    const logger = recordingApiLogger();
+    const message = chance.sentence();
+    setApiLogger(logger);
+
+    getApiLogger().warn(message, { status: 500 });
+
+    expect(logger.entries).toEqual([{ level: 'warn', message: message, fields: { status: 500 } }]);
+  });
+
+  it('goes back to the default without a logger', () => {
+    setApiLogger(recordingApiLogger());
+    setApiLogger();
+
+    expect(getApiLogger()).toBe(noopApiLogger);
+  });
+
+  describe('when writing lines', () => {
+    it('writes the level, the message and the fields', () => {
+      expect(formatLogLine('debug', 'request', { url: 'https://api.modrinth.com/v2/search', attempt: 1 })).toEqual(
+        '[debug] request url="https://api.modrinth.com/v2/search" attempt=1'
+      );
+    });
+
+    it('writes the message alone without fields', () => {
+      expect(formatLogLine('error', 'failed')).toEqual('[error] failed');
+    });
+
+    it.each(['debug', 'info', 'warn', 'error'] as const)('hands the %s entries to the writer', (level) => {
+      const write = vi.fn();
+      const message = chance.word();
+
+      lineApiLogger(write)[level](message, { id: '123' });
+
+      expect(write).toHaveBeenCalledWith(`[${level}] ${message} id="123"`);
+    });
+  });
+});
@@ -0,0 +1,54 @@
+export type ApiLogLevel = 'debug' | 'info' | 'warn' | 'error';
+
+export type ApiLogFields = Record<string, unknown>;
+
+/**
+ * What the platform and the http layers tell about their work, the fields carry the details like the url.
+ */
+export interface ApiLogger {
+  debug(message: string, fields?: ApiLogFields): void;
+  info(message: string, fields?: ApiLogFields): void;
+  warn(message: string, fields?: ApiLogFields): void;
+  error(message: string, fields?: ApiLogFields): void;
+}
+
+const ignore = () => {
+  //
+};
+
+export const noopApiLogger: ApiLogger = {
+  debug: ignore,
+  info: ignore,
+  warn: ignore,
+  error: ignore
+};
+
+let apiLogger: ApiLogger = noopApiLogger;
+
+/**
+ * Sets the logger the api layers log to.
+ * Calling it without a logger goes back to the default, which doesn't log anything.
+ */
+export const setApiLogger = (logger?: ApiLogger) => {
+  apiLogger = logger ?? noopApiLogger;
+};
+
+export const getApiLogger = () => apiLogger;
+
+/**
+ * Writes an entry on one line, like: [debug] request url="https://api.modrinth.com/v2/search" attempt=1
+ */
+export const formatLogLine = (level: ApiLogLevel, message: string, fields: ApiLogFields = {}): string => {
+  const details = Object.entries(fields).map(([key, value]) => `${key}=${JSON.stringify(value)}`);
+  return [`[${level}]`, message, ...details].join(' ');
+};
+
+/**
+ * A logger that hands every entry as a single line to the given function
+ */
+export const lineApiLogger = (write: (line: string) => void): ApiLogger => ({
+  debug: (message, fields) => write(formatLogLine('debug', message, fields)),
+  info: (message, fields) => write(formatLogLine('info', message, fields)),
+  warn: (message, fields) => write(formatLogLine('warn', message, fields)),
+  error: (message, fields) => write(formatLogLine('error', message, fields))
+});
@@ -0,0 +1,79 @@
+import { afterEach, describe, expect, it, vi } from 'vitest';
+import { InvalidBaseUrlException } from '../errors/InvalidBaseUrlException.js';
+import { apiUrl, getBaseUrl, normalizeBaseUrl, setBaseUrl, verifyEnvironmentBaseUrls } from './baseUrl.js';
+import { Platform } from './modlist.types.js';
+
+describe('The API base urls', () => {
+  afterEach(() => {
+    setBaseUrl(Platform.CURSEFORGE);
+    setBaseUrl(Platform.MODRINTH);
+  });
+
+  it('uses the public APIs by default', () => {
+    expect(getBaseUrl(Platform.CURSEFORGE)).toEqual('https://api.curseforge.com/v1');
+    expect(getBaseUrl(Platform.MODRINTH)).toEqual('https://api.modrinth.com/v2');
+  });
+
+  it.each(['https://proxy.example.com/v1', 'https://proxy.example.com/v1/', 'https://proxy.example.com/v1//'])(
+    'removes the trailing slashes from %s',
+    (url) => {
+      expect(normalizeBaseUrl(Platform.CURSEFORGE, url)).toEqual('https://proxy.example.com/v1');
+    }
+  );
+
+  it('accepts a http url without a path', () => {
+    expect(normalizeBaseUrl(Platform.MODRINTH, 'http://localhost:8080')).toEqual('http://localhost:8080');
+  });
+
+  it.each(['not a url', 'ftp://proxy.example.com/v1', ''])('refuses %j', (url) => {
+    expect(() => normalizeBaseUrl(Platform.MODRINTH, url)).toThrow(
+      new InvalidBaseUrlException(Platform.MODRINTH, url)
+    );
+  });
+
+  it('builds the urls of the endpoints from the base url', () => {
+    setBaseUrl(Platform.CURSEFORGE, 'https://proxy.example.com/v1/');
+
+    expect(apiUrl(Platform.CURSEFORGE, 'mods/123/files')).toEqual('https://proxy.example.com/v1/mods/123/files');
+    expect(apiUrl(Platform.CURSEFORGE, '/fingerprints')).toEqual('https://proxy.example.com/v1/fingerprints');
+    expect(apiUrl(Platform.MODRINTH, 'version_files')).toEqual('https://api.modrinth.com/v2/version_files');
+  });
+
+  it('refuses to set an invalid url', () => {
+    expect(() => setBaseUrl(Platform.MODRINTH, 'nope')).toThrow(InvalidBaseUrlException);
+    expect(getBaseUrl(Platform.MODRINTH)).toEqual('https://api.modrinth.com/v2');
+  });
+
+  it('can go back to the default', () => {
+    setBaseUrl(Platform.MODRINTH, 'https://proxy.example.com');
+    setBaseUrl(Platform.MODRINTH);
+
+    expect(getBaseUrl(Platform.MODRINTH)).toEqual('https://api.modrinth.com/v2');
+  });
+
+  it('accepts the default environment', () => {
+    expect(() => verifyEnvironmentBaseUrls()).not.toThrow();
+  });
+
+  describe('when an environment variable is not a url', () => {
+    afterEach(() => {
+      vi.doUnmock('../env.js');
+      vi.resetModules();
+    });
+
+    it('only refuses it once it is checked', async () => {
+      vi.resetModules();
+      vi.doMock('../env.js', () => ({
+        curseforgeApiUrl: 'https://api.curseforge.com/v1',
+        modrinthApiUrl: 'not a url'
+      }));
+
+      const baseUrl = await import('./baseUrl.js');
+
+      expect(() => baseUrl.verifyEnvironmentBaseUrls()).toThrow(
+        new InvalidBaseUrlException(Platform.MODRINTH, 'not a url')
+      );
+      expect(baseUrl.getBaseUrl(Platform.CURSEFORGE)).toEqual('https://api.curseforge.com/v1');
+    });
+  });
+});
@@ -0,0 +1,69 @@
+import { curseforgeApiUrl, modrinthApiUrl } from '../env.js';
+import { InvalidBaseUrlException } from '../errors/InvalidBaseUrlException.js';
+import { Platform } from './modlist.types.js';
+
+/**
+ * Makes sure the url can be used as the base of the API requests.
+ * The trailing slashes are removed so both https://proxy/v1/ and https://proxy/v1 work.
+ *
+ * @throws {InvalidBaseUrlException} When the url isn't a http(s) url
+ */
+export const normalizeBaseUrl = (platform: Platform, url: string): string => {
+  let parsed: URL;
+  try {
+    parsed = new URL(url);
+  } catch {
+    throw new InvalidBaseUrlException(platform, url);
+  }
+
+  if (!['http:', 'https:'].includes(parsed.protocol)) {
+    throw new InvalidBaseUrlException(platform, url);
+  }
+
+  return parsed.toString().replace(/\/+$/, '');
+};
+
+const environmentBaseUrls: Record<Platform, string> = {
+  [Platform.CURSEFORGE]: curseforgeApiUrl,
+  [Platform.MODRINTH]: modrinthApiUrl
+};
+
+const baseUrls: Partial<Record<Platform, string>> = {};
+
+/**
+ * Checks the CURSEFORGE_API_URL and MODRINTH_API_URL environment variables.
+ * It's called when a command starts so a typo doesn't surface as a failed lookup later on.
+ *
+ * @throws {InvalidBaseUrlException} When one of them isn't a http(s) url
+ */
+export const verifyEnvironmentBaseUrls = () => {
+  Object.values(Platform).forEach((platform) => {
+    normalizeBaseUrl(platform, environmentBaseUrls[platform]);
+  });
+};
+
+/**
+ * Sets the url every request to the platform's API is sent to, like a self-hosted proxy or a mock server.
+ * Calling it without a url goes back to the CURSEFORGE_API_URL or MODRINTH_API_URL environment variable
+ * or the public API when those aren't set either.
+ *
+ * @throws {InvalidBaseUrlException} When the url isn't a http(s) url
+ */
+export const setBaseUrl = (platform: Platform, url?: string) => {
+  baseUrls[platform] = url ? normalizeBaseUrl(platform, url) : undefined;
+};
+
+/**
+ * @throws {InvalidBaseUrlException} When the environment variable of the platform isn't a http(s) url
+ */
+export const getBaseUrl = (platform: Platform) =>
+  baseUrls[platform] ?? normalizeBaseUrl(platform, environmentBaseUrls[platform]);
+
+/**
+ * The url of an endpoint of the platform's API
+ *
+ * @param platform
+ * @param endpoint The path of the endpoint, like mods/search
+ */
+export const apiUrl = (platform: Platform, endpoint: string) =>
+  `${getBaseUrl(platform)}/${endpoint.replace(/^\/+/, '')}`;
@@ -0,0 +1,16 @@
+import { describe, expect, it } from 'vitest';
+import { chunk } from './chunk.js';
+
+describe('The chunking', () => {
+  it('splits the items into batches of the size', () => {
+    expect(chunk([1, 2, 3, 4, 5], 2)).toEqual([[1, 2], [3, 4], [5]]);
+  });
+
+  it('keeps the items together when they fit', () => {
+    expect(chunk(['a', 'b'], 5)).toEqual([['a', 'b']]);
+  });
+
+  it('has no batches without items', () => {
+    expect(chunk([], 3)).toEqual([]);
+  });
+});
@@ -0,0 +1,10 @@
+/**
+ * Splits the items into batches of the given size, the last one holds what's left
+ */
+export const chunk = <T>(items: T[], size: number): T[][] => {
+  const chunks: T[][] = [];
+  for (let i = 0; i < items.length; i += size) {
+    chunks.push(items.slice(i, i + size));
+  }
+  return chunks;
+};
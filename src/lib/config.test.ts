@@ -1,11 +1,13 @@
 import fs from 'node:fs/promises';
+import os from 'node:os';
 import path from 'node:path';
 import { chance } from 'jest-chance';
 import * as process from 'process';
-import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
 import { generateModInstall } from '../../test/modInstallGenerator.js';
 import { generateModsJson } from '../../test/modlistGenerator.js';
 import { ConfigFileNotFoundException } from '../errors/ConfigFileNotFoundException.js';
+import { LockFileCorruptedException } from '../errors/LockFileCorruptedException.js';
 import { fileToWrite } from '../interactions/fileToWrite.js';
 import { initializeConfig } from '../interactions/initializeConfig.js';
 import { shouldCreateConfig } from '../interactions/shouldCreateConfig.js';
@@ -19,10 +21,12 @@ import {
   initializeConfigFile,
   readConfigFile,
   readLockFile,
+  readLockFileAt,
   writeConfigFile,
   writeLockFile
 } from './config.js';
-import { Loader, ModInstall, ModsJson, Platform, ReleaseType } from './modlist.types.js';
+import { allowsSnapshotGameVersions, setSnapshotGameVersions } from './gameVersionMatcher.js';
+import { Loader, ModInstall, ModsJson, Platform, ReleaseChannel, ReleaseType } from './modlist.types.js';
 
 vi.mock('../interactions/shouldCreateConfig.js');
 vi.mock('../interactions/initializeConfig.js');
@@ -78,6 +82,29 @@ describe('The config library', () => {
     );
   });
 
+  it<LocalTestContext>('writes the config file as TOML when it ends in .toml', async ({ options }) => {
+    options.config = 'modlist.toml';
+    const config = {
+      something: 'value'
+    } as unknown as ModsJson;
+
+    await writeConfigFile(config, options, logger);
+
+    expect(vi.mocked(fs.writeFile)).toHaveBeenCalledWith(
+      path.resolve('modlist.toml'),
+      expect.stringContaining('something = "value"')
+    );
+  });
+
+  it<LocalTestContext>('writes the lock file of a TOML config as JSON', async ({ options }) => {
+    options.config = 'modlist.toml';
+    const expectedLockFilePath = path.resolve('modlist-lock.json');
+
+    await writeLockFile([], options, logger);
+
+    expect(vi.mocked(fs.writeFile)).toHaveBeenCalledWith(`${expectedLockFilePath}.tmp`, '[]');
+  });
+
   it<LocalTestContext>('can write the lock file', async ({ options }) => {
     const config = [
       {
@@ -91,9 +118,31 @@ describe('The config library', () => {
     expect(vi.mocked(fileToWrite)).toHaveBeenCalledWith(expectedLockFilePath, options, logger);
 
     expect(vi.mocked(fs.writeFile)).toHaveBeenCalledWith(
-      expectedLockFilePath,
+      `${expectedLockFilePath}.tmp`,
       '[\n' + '  {\n' + '    "something": "value"\n' + '  }\n' + ']'
     );
+    expect(vi.mocked(fs.rename)).toHaveBeenCalledWith(`${expectedLockFilePath}.tmp`, expectedLockFilePath);
+  });
+
+  it<LocalTestContext>('leaves the old lock file alone when writing the new one fails', async ({ options }) => {
+    const expectedLockFilePath = path.resolve('config-lock.json');
+    vi.mocked(fs.writeFile).mockRejectedValueOnce(new Error('disk full'));
+
+    await expect(writeLockFile([generateModInstall().generated], options, logger)).rejects.toThrow('disk full');
+
+    expect(vi.mocked(fs.rename)).not.toHaveBeenCalled();
+    expect(vi.mocked(fs.rm)).toHaveBeenCalledWith(`${expectedLockFilePath}.tmp`, { force: true });
+  });
+
+  it<LocalTestContext>('leaves the old lock file alone when the new one cannot be moved into place', async ({
+    options
+  }) => {
+    const expectedLockFilePath = path.resolve('config-lock.json');
+    vi.mocked(fs.rename).mockRejectedValueOnce(new Error('permission denied'));
+
+    await expect(writeLockFile([], options, logger)).rejects.toThrow('permission denied');
+
+    expect(vi.mocked(fs.rm)).toHaveBeenCalledWith(`${expectedLockFilePath}.tmp`, { force: true });
   });
 
   it<LocalTestContext>('can read the lock file when it exists', async ({ options }) => {
@@ -115,6 +164,18 @@ describe('The config library', () => {
     expect(vi.mocked(fs.readFile)).toHaveBeenCalledWith(path.resolve(lockfileName), { encoding: 'utf8' });
   });
 
+  it<LocalTestContext>('reads back exactly what it wrote to the lock file', async ({ options }) => {
+    const installations = [generateModInstall().generated, generateModInstall().generated];
+
+    await writeLockFile(installations, options, logger);
+    const written = vi.mocked(fs.writeFile).mock.calls[0][1] as string;
+
+    vi.mocked(fs.access).mockResolvedValueOnce();
+    vi.mocked(fs.readFile).mockResolvedValueOnce(written);
+
+    expect(await readLockFile(options, logger)).toEqual(installations);
+  });
+
   it<LocalTestContext>('can returns an empty array and creates the file when the lock file does not exist', async ({
     options
   }) => {
@@ -128,7 +189,60 @@ describe('The config library', () => {
     expect(actualOutput).toEqual([]);
 
     expect(vi.mocked(fs.readFile)).not.toHaveBeenCalled();
-    expect(vi.mocked(fs.writeFile)).toHaveBeenCalledWith(path.resolve('config-lock.json'), '[]');
+    expect(vi.mocked(fs.writeFile)).toHaveBeenCalledWith(`${path.resolve('config-lock.json')}.tmp`, '[]');
+    expect(vi.mocked(fs.rename)).toHaveBeenCalledWith(
+      `${path.resolve('config-lock.json')}.tmp`,
+      path.resolve('config-lock.json')
+    );
+  });
+
+  it<LocalTestContext>('throws a clear error when the lock file is truncated', async ({ options }) => {
+    const lockfileContents = JSON.stringify([generateModInstall().generated], null, 2);
+    vi.mocked(fs.access).mockResolvedValueOnce();
+    vi.mocked(fs.readFile).mockResolvedValueOnce(lockfileContents.slice(0, lockfileContents.length / 2));
+
+    await expect(readLockFile(options, logger)).rejects.toThrow(
+      new LockFileCorruptedException(path.resolve('config-lock.json'))
+    );
+    expect(vi.mocked(logger.error)).toHaveBeenCalledWith(
+      new LockFileCorruptedException(path.resolve('config-lock.json')).message,
+      1
+    );
+  });
+
+  it<LocalTestContext>('throws a clear error when the lock file is not a list', async ({ options }) => {
+    vi.mocked(fs.access).mockResolvedValueOnce();
+    vi.mocked(fs.readFile).mockResolvedValueOnce('{}');
+
+    await expect(readLockFile(options, logger)).rejects.toThrow(
+      new LockFileCorruptedException(path.resolve('config-lock.json'))
+    );
+    expect(vi.mocked(logger.error)).toHaveBeenCalledOnce();
+  });
+
+  it<LocalTestContext>('leaves the corrupted lock file to the caller that rebuilds it', async ({ options }) => {
+    vi.mocked(fs.access).mockResolvedValueOnce();
+    vi.mocked(fs.readFile).mockResolvedValueOnce('{}');
+
+    await expect(readLockFile(options, logger, true)).rejects.toBeInstanceOf(LockFileCorruptedException);
+    expect(vi.mocked(logger.error)).not.toHaveBeenCalled();
+  });
+
+  it('can read a lock file by its location', async () => {
+    const installations = [generateModInstall().generated];
+    vi.mocked(fs.readFile).mockResolvedValueOnce(JSON.stringify(installations, null, 2));
+
+    expect(await readLockFileAt('other/modlist-lock.json')).toEqual(installations);
+    expect(vi.mocked(fs.readFile)).toHaveBeenCalledWith(path.resolve('other/modlist-lock.json'), { encoding: 'utf8' });
+    expect(vi.mocked(fs.writeFile)).not.toHaveBeenCalled();
+  });
+
+  it('throws a clear error when the lock file at the location is corrupted', async () => {
+    vi.mocked(fs.readFile).mockResolvedValueOnce('{}');
+
+    await expect(readLockFileAt('other/modlist-lock.json')).rejects.toThrow(
+      new LockFileCorruptedException(path.resolve('other/modlist-lock.json'))
+    );
   });
 
   it<LocalTestContext>('can read from the config file when it exists', async ({ options }) => {
@@ -148,6 +262,25 @@ describe('The config library', () => {
     expect(actualOutput).toEqual(randomModsJson.expected);
   });
 
+  it('can read from a TOML config file', async () => {
+    vi.mocked(fs.access).mockResolvedValueOnce();
+
+    const randomModsJson = generateModsJson();
+    const fileContents = [
+      `loader = "${randomModsJson.generated.loader}"`,
+      `gameVersion = "${randomModsJson.generated.gameVersion}"`,
+      `defaultAllowedReleaseTypes = ${JSON.stringify(randomModsJson.generated.defaultAllowedReleaseTypes)}`,
+      `modsFolder = "${randomModsJson.generated.modsFolder}"`,
+      'mods = []'
+    ].join('\n');
+
+    vi.mocked(fs.readFile).mockResolvedValueOnce(fileContents);
+
+    const actualOutput = await readConfigFile('modlist.toml');
+
+    expect(actualOutput).toEqual(randomModsJson.expected);
+  });
+
   it('throws an error when the config file does not exist', async () => {
     const configName = path.resolve('config.json');
 
@@ -182,6 +315,15 @@ describe('The config library', () => {
     await expect(ensureConfiguration(configName, logger)).rejects.toThrowErrorMatchingSnapshot();
   });
 
+  it('tells what is wrong with a TOML config file', async () => {
+    vi.mocked(fs.access).mockResolvedValueOnce();
+    vi.mocked(fs.readFile).mockResolvedValueOnce('loader = "fabric');
+
+    await expect(ensureConfiguration('modlist.toml', logger)).rejects.toThrow(
+      'There is a problem with the configuration file, please check! Line 1 of the TOML file is invalid'
+    );
+  });
+
   it('can initialize a new config file', async () => {
     const configName = 'config.json';
 
@@ -195,6 +337,10 @@ describe('The config library', () => {
   });
 
   describe('when ensuring that a config exists', () => {
+    afterEach(() => {
+      setSnapshotGameVersions();
+    });
+
     describe('and in interactive mode', () => {
       it('creates a new one if there is no existing one', async () => {
         const configName = 'config.json';
@@ -252,6 +398,26 @@ describe('The config library', () => {
 
       expect(actualOutput).toEqual(randomModsJson.expected);
     });
+
+    it('considers the snapshots when the configuration allows them', async () => {
+      vi.mocked(fs.access).mockResolvedValueOnce();
+      vi.mocked(fs.readFile).mockResolvedValueOnce(
+        JSON.stringify({ ...generateModsJson().generated, allowSnapshots: true })
+      );
+
+      await ensureConfiguration('config.json', logger);
+
+      expect(allowsSnapshotGameVersions()).toBeTruthy();
+    });
+
+    it('leaves the snapshots alone when the configuration does not mention them', async () => {
+      vi.mocked(fs.access).mockResolvedValueOnce();
+      vi.mocked(fs.readFile).mockResolvedValueOnce(JSON.stringify(generateModsJson().generated));
+
+      await ensureConfiguration('config.json', logger);
+
+      expect(allowsSnapshotGameVersions()).toBeFalsy();
+    });
   });
 
   it('can resolve a relative mod folder', () => {
@@ -275,6 +441,16 @@ describe('The config library', () => {
     expect(actual).toEqual(expected);
   });
 
+  it.each([
+    ['~', os.homedir()],
+    ['~/minecraft/mods', path.join(os.homedir(), 'minecraft', 'mods')],
+    ['~mods', path.resolve('/some-path', '~mods')]
+  ])('resolves the mod folder %j as %j', (modsFolder, expected) => {
+    const randomModsJson = generateModsJson({ modsFolder: modsFolder }).generated;
+
+    expect(getModsFolder('/some-path/config.json', randomModsJson)).toEqual(expected);
+  });
+
   it('should validate a correct ModsJson object', () => {
     const validModsJson: ModsJson = {
       loader: Loader.FORGE,
@@ -317,4 +493,101 @@ describe('The config library', () => {
     const result = ModsJsonSchema.safeParse(invalidModsJson);
     expect(result.success).toBe(false);
   });
+
+  it('should validate the release channel of a mod', () => {
+    const modsJson = (releaseChannel: unknown) => ({
+      loader: Loader.FABRIC,
+      gameVersion: '1.20.1',
+      defaultAllowedReleaseTypes: [ReleaseType.RELEASE],
+      modsFolder: 'mods',
+      mods: [{ id: '394468', type: Platform.CURSEFORGE, name: 'Sodium', releaseChannel: releaseChannel }]
+    });
+
+    expect(ModsJsonSchema.safeParse(modsJson(ReleaseChannel.BETA)).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson('nightly')).success).toBe(false);
+  });
+
+  it('should validate the fallback of a mod', () => {
+    const modsJson = (fallback: unknown) => ({
+      loader: Loader.FABRIC,
+      gameVersion: '1.20.1',
+      defaultAllowedReleaseTypes: [ReleaseType.RELEASE],
+      modsFolder: 'mods',
+      mods: [{ id: '394468', type: Platform.CURSEFORGE, name: 'Sodium', fallback: fallback }]
+    });
+
+    expect(ModsJsonSchema.safeParse(modsJson({ type: Platform.MODRINTH, id: 'sodium' })).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson({ type: 'invalid_platform', id: 'sodium' })).success).toBe(false);
+    expect(ModsJsonSchema.safeParse(modsJson({ type: Platform.MODRINTH })).success).toBe(false);
+  });
+
+  it('should validate the file overrides of a mod', () => {
+    const modsJson = (overrides: Record<string, unknown>) => ({
+      loader: Loader.FABRIC,
+      gameVersion: '1.20.1',
+      defaultAllowedReleaseTypes: [ReleaseType.RELEASE],
+      modsFolder: 'mods',
+      mods: [{ id: '394468', type: Platform.CURSEFORGE, name: 'Sodium', ...overrides }]
+    });
+
+    expect(ModsJsonSchema.safeParse(modsJson({ excludeFileNamePattern: '-forge\\.jar$' })).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson({ forceFileId: '4567890' })).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson({ excludeFileNamePattern: '(' })).success).toBe(false);
+    expect(ModsJsonSchema.safeParse(modsJson({ forceFileId: 4567890 })).success).toBe(false);
+    expect(ModsJsonSchema.safeParse(modsJson({ installAdditionalFiles: true })).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson({ installAdditionalFiles: 'yes' })).success).toBe(false);
+    expect(ModsJsonSchema.safeParse(modsJson({ pinned: true, forceFileId: '4567890' })).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson({ pinned: 'yes' })).success).toBe(false);
+    expect(ModsJsonSchema.safeParse(modsJson({ loaders: [Loader.FORGE, Loader.FABRIC] })).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson({ loaders: ['minecraft'] })).success).toBe(false);
+  });
+
+  it('should validate the file name template', () => {
+    const modsJson = (fileNameTemplate: unknown) => ({
+      loader: Loader.FABRIC,
+      gameVersion: '1.20.1',
+      defaultAllowedReleaseTypes: [ReleaseType.RELEASE],
+      modsFolder: 'mods',
+      fileNameTemplate: fileNameTemplate,
+      mods: []
+    });
+
+    expect(ModsJsonSchema.safeParse(modsJson('{slug}-{gameVersion}.jar')).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson(undefined)).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson(42)).success).toBe(false);
+    expect(ModsJsonSchema.safeParse(modsJson(' ... ')).success).toBe(false);
+  });
+
+  it('should validate the snapshot setting', () => {
+    const modsJson = (allowSnapshots: unknown) => ({
+      loader: Loader.FABRIC,
+      gameVersion: '24w09a',
+      defaultAllowedReleaseTypes: [ReleaseType.RELEASE],
+      modsFolder: 'mods',
+      allowSnapshots: allowSnapshots,
+      mods: []
+    });
+
+    expect(ModsJsonSchema.safeParse(modsJson(true)).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson(undefined)).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson('yes')).success).toBe(false);
+  });
+
+  it('should validate the release types of the platforms', () => {
+    const modsJson = (platformAllowedReleaseTypes: unknown) => ({
+      loader: Loader.FABRIC,
+      gameVersion: '1.20.1',
+      defaultAllowedReleaseTypes: [ReleaseType.RELEASE],
+      platformAllowedReleaseTypes: platformAllowedReleaseTypes,
+      modsFolder: 'mods',
+      mods: []
+    });
+
+    const stableCurseforge = { curseforge: [ReleaseType.RELEASE], modrinth: [ReleaseType.RELEASE, ReleaseType.BETA] };
+    expect(ModsJsonSchema.safeParse(modsJson(stableCurseforge)).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson({ modrinth: [ReleaseType.BETA] })).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson(undefined)).success).toBe(true);
+    expect(ModsJsonSchema.safeParse(modsJson({ curseforge: ['nightly'] })).success).toBe(false);
+    expect(ModsJsonSchema.safeParse(modsJson({ curseforge: ReleaseType.RELEASE })).success).toBe(false);
+  });
 });
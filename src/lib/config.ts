@@ -1,14 +1,21 @@
 import fs from 'node:fs/promises';
+import os from 'node:os';
 import path from 'path';
 import { z } from 'zod';
 import { ConfigFileInvalidError } from '../errors/ConfigFileInvalidError.js';
 import { ConfigFileNotFoundException } from '../errors/ConfigFileNotFoundException.js';
+import { LockFileCorruptedException } from '../errors/LockFileCorruptedException.js';
+import { TomlSyntaxError } from '../errors/TomlSyntaxError.js';
 import { fileToWrite } from '../interactions/fileToWrite.js';
 import { initializeConfig } from '../interactions/initializeConfig.js';
 import { shouldCreateConfig } from '../interactions/shouldCreateConfig.js';
 import { DefaultOptions } from '../mmm.js';
 import { Logger } from './Logger.js';
-import { Loader, ModInstall, ModsJson, Platform, ReleaseType } from './modlist.types.js';
+import { isValidFileNameTemplate } from './fileNameTemplate.js';
+import { isValidFileNamePattern } from './fileOverrides.js';
+import { setSnapshotGameVersions } from './gameVersionMatcher.js';
+import { Loader, ModInstall, ModsJson, Platform, ReleaseChannel, ReleaseType } from './modlist.types.js';
+import { parseModlist, serializeModlist } from './modlistFormat.js';
 
 // Define the structure of a single mod installation
 export const ModInstallSchema = z.object({
@@ -17,7 +24,22 @@ export const ModInstallSchema = z.object({
   type: z.nativeEnum(Platform),
   version: z.string().optional(),
   allowVersionFallback: z.boolean().optional(),
-  allowedReleaseTypes: z.array(z.nativeEnum(ReleaseType)).optional()
+  allowedReleaseTypes: z.array(z.nativeEnum(ReleaseType)).optional(),
+  releaseChannel: z.nativeEnum(ReleaseChannel).optional(),
+  excludeFileNamePattern: z
+    .string()
+    .refine(isValidFileNamePattern, { message: 'excludeFileNamePattern has to be a valid regular expression' })
+    .optional(),
+  forceFileId: z.string().optional(),
+  installAdditionalFiles: z.boolean().optional(),
+  pinned: z.boolean().optional(),
+  loaders: z.array(z.nativeEnum(Loader)).optional(),
+  fallback: z
+    .object({
+      type: z.nativeEnum(Platform),
+      id: z.string()
+    })
+    .optional()
 });
 
 // Define the structure of the ModsJson object
@@ -25,7 +47,18 @@ export const ModsJsonSchema = z.object({
   loader: z.nativeEnum(Loader), //loader values
   gameVersion: z.string(),
   defaultAllowedReleaseTypes: z.array(z.nativeEnum(ReleaseType)),
+  platformAllowedReleaseTypes: z
+    .object({
+      [Platform.CURSEFORGE]: z.array(z.nativeEnum(ReleaseType)).optional(),
+      [Platform.MODRINTH]: z.array(z.nativeEnum(ReleaseType)).optional()
+    })
+    .optional(),
   modsFolder: z.string(),
+  fileNameTemplate: z
+    .string()
+    .refine(isValidFileNameTemplate, { message: 'fileNameTemplate has to leave something of the file name' })
+    .optional(),
+  allowSnapshots: z.boolean().optional(),
   mods: z.array(ModInstallSchema)
 });
 
@@ -43,16 +76,51 @@ const getLockfileName = (configPath: string) => {
 export const writeConfigFile = async (config: ModsJson, options: DefaultOptions, logger: Logger) => {
   const configLocation = path.resolve(options.config);
   const fileToUse = await fileToWrite(configLocation, options, logger);
-  await fs.writeFile(fileToUse, JSON.stringify(config, null, 2));
+  await fs.writeFile(fileToUse, serializeModlist(config, fileToUse));
 };
 
+/**
+ * The lock file is written next to itself first and then moved into place,
+ * so a crash in the middle of writing it leaves the old lock file intact.
+ */
 export const writeLockFile = async (config: ModInstall[], options: DefaultOptions, logger: Logger) => {
   const configLocation = getLockfileName(path.resolve(options.config));
   const fileToUse = await fileToWrite(configLocation, options, logger);
-  await fs.writeFile(fileToUse, JSON.stringify(config, null, 2));
+  const temporaryFile = `${fileToUse}.tmp`;
+  try {
+    await fs.writeFile(temporaryFile, JSON.stringify(config, null, 2));
+    await fs.rename(temporaryFile, fileToUse);
+  } catch (error) {
+    await fs.rm(temporaryFile, { force: true });
+    throw error;
+  }
+};
+
+const parseLockFile = (lockFileLocation: string, contents: string): ModInstall[] => {
+  let lock: unknown;
+  try {
+    lock = JSON.parse(contents);
+  } catch (_) {
+    throw new LockFileCorruptedException(lockFileLocation);
+  }
+
+  if (!Array.isArray(lock)) {
+    throw new LockFileCorruptedException(lockFileLocation);
+  }
+
+  return lock;
 };
 
-export const readLockFile = async (options: DefaultOptions, logger: Logger): Promise<ModInstall[]> => {
+/**
+ * A corrupted lock file stops the command with the error, unless the caller rebuilds it and asks for the exception.
+ *
+ * @throws {LockFileCorruptedException} When the lock file isn't a list of installations, like after a crash
+ */
+export const readLockFile = async (
+  options: DefaultOptions,
+  logger: Logger,
+  rethrowCorrupted = false
+): Promise<ModInstall[]> => {
   const lockFileLocation = getLockfileName(path.resolve(options.config));
   const lockFileExists = await fileExists(lockFileLocation);
 
@@ -60,7 +128,14 @@ export const readLockFile = async (options: DefaultOptions, logger: Logger): Pro
     const configContents = await fs.readFile(lockFileLocation, {
       encoding: 'utf8'
     });
-    return JSON.parse(configContents);
+    try {
+      return parseLockFile(lockFileLocation, configContents);
+    } catch (error) {
+      if (error instanceof LockFileCorruptedException && !rethrowCorrupted) {
+        logger.error(error.message, 1);
+      }
+      throw error;
+    }
   }
 
   const emptyModLock: ModInstall[] = [];
@@ -70,6 +145,19 @@ export const readLockFile = async (options: DefaultOptions, logger: Logger): Pro
   return emptyModLock;
 };
 
+/**
+ * Reads the lock file at the location, like the one of another branch or server, without creating it
+ *
+ * @throws {LockFileCorruptedException} When the file isn't a list of installations
+ */
+export const readLockFileAt = async (lockFileLocation: string): Promise<ModInstall[]> => {
+  const location = path.resolve(lockFileLocation);
+  const contents = await fs.readFile(location, {
+    encoding: 'utf8'
+  });
+  return parseLockFile(location, contents);
+};
+
 export const readConfigFile = async (configPath: string): Promise<ModsJson> => {
   const configLocation = path.resolve(configPath);
 
@@ -80,7 +168,7 @@ export const readConfigFile = async (configPath: string): Promise<ModsJson> => {
   const configContents = await fs.readFile(configLocation, {
     encoding: 'utf8'
   });
-  return JSON.parse(configContents);
+  return parseModlist(configContents, configLocation);
 };
 
 export const initializeConfigFile = async (configPath: string, logger: Logger): Promise<ModsJson> => {
@@ -104,6 +192,10 @@ export const ensureConfiguration = async (configPath: string, logger: Logger, qu
     if (!validationResult.success) {
       throw new ConfigFileInvalidError();
     }
+    // The setting only turns the snapshots on, so it doesn't undo the --snapshots option
+    if (config.allowSnapshots) {
+      setSnapshotGameVersions(true);
+    }
     performance.mark('ensure-configuration-succeed');
     return config;
   } catch (error) {
@@ -117,15 +209,26 @@ export const ensureConfiguration = async (configPath: string, logger: Logger, qu
     if (error instanceof ConfigFileInvalidError) {
       logger.error('There is a problem with the configuration file, please check!', 1);
     }
+
+    if (error instanceof TomlSyntaxError) {
+      logger.error(`There is a problem with the configuration file, please check! ${error.message}`, 1);
+    }
     throw error;
   }
 };
 
+/**
+ * The mods folder of the configuration, ~ is the home folder and a relative path starts from the configuration file
+ */
 export const getModsFolder = (configLocation: string, config: ModsJson): string => {
   const realConfigLocation = path.resolve(configLocation);
   const configFolder = path.dirname(realConfigLocation);
   const configuredModsFolder = config.modsFolder;
 
+  if (/^~(?=$|[\\/])/.test(configuredModsFolder)) {
+    return path.join(os.homedir(), configuredModsFolder.slice(1));
+  }
+
   if (path.isAbsolute(configuredModsFolder)) {
     return configuredModsFolder;
   }
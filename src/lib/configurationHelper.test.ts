@@ -40,6 +40,14 @@ describe('The configuration helper', () => {
     expect(fileIsManaged('does-not-exist.jar', installations)).toBeFalsy();
   });
 
+  it<LocalTestContext>('can tell if an additional file is managed by the config', ({ installations }) => {
+    installations[1].additionalFiles = [
+      { fileName: 'textures.zip', hash: chance.hash(), downloadUrl: 'https://example.com/textures.zip' }
+    ];
+
+    expect(fileIsManaged('textures.zip', installations)).toBeTruthy();
+  });
+
   describe('when looking up mods', () => {
     it('can find a mod by ID', () => {
       const modId = chance.word();
@@ -29,7 +29,10 @@ export const findLocalMods = (lookup: string[], configuration: ModsJson) => {
 export const fileIsManaged = (file: string, installations: ModInstall[]) => {
   const filename = path.basename(file);
   const result = installations.find((install) => {
-    return install.fileName === filename;
+    return (
+      install.fileName === filename ||
+      (install.additionalFiles || []).some((additionalFile) => additionalFile.fileName === filename)
+    );
   });
 
   return result !== undefined;
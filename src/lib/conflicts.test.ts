@@ -0,0 +1,82 @@
+import { describe, expect, it } from 'vitest';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { findConflicts } from './conflicts.js';
+import { Mod, ModInstall, Platform, RequiredDependency } from './modlist.types.js';
+
+const configurationWith = (mods: Mod[]) => generateModsJson({ mods: mods }).generated;
+
+const installationOf = (mod: Mod, incompatibilities?: RequiredDependency[]): ModInstall =>
+  generateModInstall({ type: mod.type, id: mod.id, incompatibilities: incompatibilities }).generated;
+
+const curseforge = (id: string): RequiredDependency => ({ type: Platform.CURSEFORGE, id: id });
+
+describe('The conflict detection', () => {
+  it('flags a mod that marks another one incompatible', () => {
+    const first = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+    const second = generateModConfig({ type: Platform.CURSEFORGE, id: '2' }).generated;
+    const third = generateModConfig({ type: Platform.CURSEFORGE, id: '3' }).generated;
+
+    const actual = findConflicts(configurationWith([first, second, third]), [
+      installationOf(first, [curseforge('2')]),
+      installationOf(second),
+      installationOf(third)
+    ]);
+
+    expect(actual).toEqual([{ mod: first, conflictsWith: second }]);
+  });
+
+  it('reports a pair that marks each other incompatible once', () => {
+    const first = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+    const second = generateModConfig({ type: Platform.CURSEFORGE, id: '2' }).generated;
+
+    const actual = findConflicts(configurationWith([first, second]), [
+      installationOf(first, [curseforge('2')]),
+      installationOf(second, [curseforge('1')])
+    ]);
+
+    expect(actual).toEqual([{ mod: first, conflictsWith: second }]);
+  });
+
+  it('ignores the incompatible mods that are not in the modlist', () => {
+    const mod = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+
+    const actual = findConflicts(configurationWith([mod]), [installationOf(mod, [curseforge('5')])]);
+
+    expect(actual).toEqual([]);
+  });
+
+  it('ignores the incompatible mods that are not installed', () => {
+    const mod = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+    const other = generateModConfig({ type: Platform.CURSEFORGE, id: '2' }).generated;
+
+    const actual = findConflicts(configurationWith([mod, other]), [installationOf(mod, [curseforge('2')])]);
+
+    expect(actual).toEqual([]);
+  });
+
+  it('flags a mod installed from its fallback', () => {
+    const mod = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+    const other = generateModConfig({
+      type: Platform.MODRINTH,
+      id: 'P7dR8mSH',
+      fallback: { type: Platform.CURSEFORGE, id: '2' }
+    }).generated;
+
+    const actual = findConflicts(configurationWith([mod, other]), [
+      installationOf(mod, [curseforge('2')]),
+      installationOf(other)
+    ]);
+
+    expect(actual).toEqual([{ mod: mod, conflictsWith: other }]);
+  });
+
+  it('does not let a mod conflict with itself', () => {
+    const mod = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+
+    const actual = findConflicts(configurationWith([mod]), [installationOf(mod, [curseforge('1')])]);
+
+    expect(actual).toEqual([]);
+  });
+});
@@ -0,0 +1,42 @@
+import { getInstallation } from './configurationHelper.js';
+import { Mod, ModInstall, ModsJson } from './modlist.types.js';
+import { provides } from './unsatisfiedDependencies.js';
+
+export interface ModConflict {
+  /**
+   * The mod whose installed file declares the other one incompatible
+   */
+  mod: Mod;
+  conflictsWith: Mod;
+}
+
+/**
+ * The pairs of installed mods that can't be installed together because the file of one of them says so.
+ * This should be checked once the mods are resolved, a pack with a conflict won't boot.
+ *
+ * A pair that marks each other incompatible is only reported once.
+ *
+ * @param configuration
+ * @param installations The mods that ended up installed, with the incompatibilities of their files
+ */
+export const findConflicts = (configuration: ModsJson, installations: ModInstall[]): ModConflict[] => {
+  const installed = configuration.mods.filter((mod) => getInstallation(mod, installations) !== -1);
+  const conflicts: ModConflict[] = [];
+
+  installed.forEach((mod) => {
+    const installation = installations[getInstallation(mod, installations)];
+
+    (installation.incompatibilities || []).forEach((incompatibility) => {
+      const conflictsWith = installed.find((other) => other !== mod && provides(other, incompatibility));
+      if (!conflictsWith) {
+        return;
+      }
+      const reported = conflicts.some((conflict) => conflict.mod === conflictsWith && conflict.conflictsWith === mod);
+      if (!reported) {
+        conflicts.push({ mod: mod, conflictsWith: conflictsWith });
+      }
+    });
+  });
+
+  return conflicts;
+};
@@ -0,0 +1,163 @@
+import { afterEach, beforeEach, describe, expect, it } from 'vitest';
+import {
+  DEFAULT_CONCURRENT_DOWNLOADS,
+  setDownloadBandwidth,
+  setMaxConcurrentDownloads,
+  throttleBandwidth,
+  withDownloadSlot
+} from './downloadThrottle.js';
+import { setNow, setSleep } from './rateLimiter/clock.js';
+
+const deferred = () => {
+  let resolve: () => void = () => {};
+  const promise = new Promise<void>((done) => {
+    resolve = done;
+  });
+  return { promise: promise, resolve: resolve };
+};
+
+const flush = () => new Promise((resolve) => setTimeout(resolve, 0));
+
+describe('The download throttle', () => {
+  afterEach(() => {
+    setMaxConcurrentDownloads();
+    setDownloadBandwidth();
+    setNow();
+    setSleep();
+  });
+
+  describe('when downloading many files', () => {
+    const startDownloads = (count: number) => {
+      let running = 0;
+      let mostRunning = 0;
+      const downloads = Array.from({ length: count }, deferred);
+      const started: number[] = [];
+      const finished = downloads.map((download, index) =>
+        withDownloadSlot(async () => {
+          started.push(index);
+          running++;
+          mostRunning = Math.max(mostRunning, running);
+          await download.promise;
+          running--;
+          return index;
+        })
+      );
+      return { downloads: downloads, started: started, finished: finished, mostRunning: () => mostRunning };
+    };
+
+    it('runs no more than the default number of downloads at once', async () => {
+      const { downloads, finished, mostRunning } = startDownloads(DEFAULT_CONCURRENT_DOWNLOADS + 3);
+      await flush();
+
+      expect(mostRunning()).toEqual(DEFAULT_CONCURRENT_DOWNLOADS);
+
+      for (const download of downloads) {
+        download.resolve();
+      }
+      expect(await Promise.all(finished)).toEqual(downloads.map((_, index) => index));
+      expect(mostRunning()).toEqual(DEFAULT_CONCURRENT_DOWNLOADS);
+    });
+
+    it('starts the waiting downloads in order as the slots free up', async () => {
+      setMaxConcurrentDownloads(2);
+      const { downloads, started, finished } = startDownloads(4);
+      await flush();
+
+      expect(started).toEqual([0, 1]);
+
+      downloads[1].resolve();
+      await flush();
+      expect(started).toEqual([0, 1, 2]);
+
+      for (const download of downloads) {
+        download.resolve();
+      }
+      await Promise.all(finished);
+      expect(started).toEqual([0, 1, 2, 3]);
+    });
+
+    it('frees the slot of a failed download', async () => {
+      setMaxConcurrentDownloads(1);
+
+      await expect(withDownloadSlot(() => Promise.reject(new Error('failed')))).rejects.toThrow('failed');
+
+      expect(await withDownloadSlot(() => Promise.resolve('next'))).toEqual('next');
+    });
+
+    it('runs at least one download at a time', async () => {
+      setMaxConcurrentDownloads(0);
+      const { downloads, finished, mostRunning } = startDownloads(2);
+      await flush();
+
+      expect(mostRunning()).toEqual(1);
+
+      for (const download of downloads) {
+        download.resolve();
+      }
+      await Promise.all(finished);
+    });
+  });
+
+  describe('when the bandwidth is capped', () => {
+    let time: number;
+    let waits: number[];
+
+    beforeEach(() => {
+      time = 0;
+      waits = [];
+      setNow(() => time);
+      setSleep(async (milliseconds) => {
+        waits.push(milliseconds);
+        time += milliseconds;
+      });
+    });
+
+    it('does not wait without a cap', async () => {
+      await throttleBandwidth(1000000);
+
+      expect(waits).toEqual([]);
+    });
+
+    it('slows the chunks down to the capped bandwidth', async () => {
+      setDownloadBandwidth(1000);
+
+      await throttleBandwidth(500);
+      await throttleBandwidth(500);
+      await throttleBandwidth(2000);
+
+      expect(waits).toEqual([500, 500, 2000]);
+      expect(time).toEqual(3000);
+    });
+
+    it('does not let the downloads catch up after a pause', async () => {
+      setDownloadBandwidth(1000);
+
+      await throttleBandwidth(500);
+      time += 300;
+      await throttleBandwidth(500);
+      time += 800;
+      await throttleBandwidth(500);
+
+      expect(waits).toEqual([500, 500, 500]);
+    });
+
+    it('shares the bandwidth between the downloads', async () => {
+      setDownloadBandwidth(1000);
+      setSleep(async (milliseconds) => {
+        waits.push(milliseconds);
+      });
+
+      await Promise.all([throttleBandwidth(1000), throttleBandwidth(1000)]);
+
+      expect(waits).toEqual([1000, 2000]);
+    });
+
+    it('ignores a cap below one byte a second', async () => {
+      setDownloadBandwidth(0);
+
+      await throttleBandwidth(1000);
+
+      expect(waits).toEqual([]);
+    });
+  });
+});
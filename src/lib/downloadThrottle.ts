@@ -0,0 +1,76 @@
+import { now, sleep } from './rateLimiter/clock.js';
+
+/**
+ * Enough to keep a home connection busy without the downloads timing each other out
+ */
+export const DEFAULT_CONCURRENT_DOWNLOADS = 4;
+
+let maxConcurrentDownloads = DEFAULT_CONCURRENT_DOWNLOADS;
+let runningDownloads = 0;
+const waitingDownloads: (() => void)[] = [];
+
+let bytesPerSecond: number | undefined;
+let bandwidthFreeAt = 0;
+
+/**
+ * Sets how many files can be downloaded at the same time. It has nothing to do with the rate limit of the apis,
+ * the downloads come from the CDNs. Calling it without a value goes back to the default.
+ */
+export const setMaxConcurrentDownloads = (max?: number) => {
+  maxConcurrentDownloads = max === undefined ? DEFAULT_CONCURRENT_DOWNLOADS : Math.max(1, Math.floor(max));
+};
+
+/**
+ * Caps how many bytes the downloads can receive in a second, all of them together.
+ * Calling it without a value, or with anything below 1, lets the downloads go as fast as they can.
+ */
+export const setDownloadBandwidth = (limit?: number) => {
+  bytesPerSecond = limit !== undefined && limit >= 1 ? limit : undefined;
+  bandwidthFreeAt = 0;
+};
+
+const acquireSlot = async () => {
+  if (runningDownloads < maxConcurrentDownloads) {
+    runningDownloads++;
+    return;
+  }
+  await new Promise<void>((resolve) => waitingDownloads.push(resolve));
+};
+
+const releaseSlot = () => {
+  const next = waitingDownloads.shift();
+  if (next) {
+    // The slot goes straight to the next download so nothing can jump the queue
+    next();
+    return;
+  }
+  runningDownloads--;
+};
+
+/**
+ * Runs the download once fewer than the maximum number of downloads are running, in the order they were started.
+ */
+export const withDownloadSlot = async <T>(download: () => Promise<T>): Promise<T> => {
+  await acquireSlot();
+  try {
+    return await download();
+  } finally {
+    releaseSlot();
+  }
+};
+
+/**
+ * Waits after a chunk of a download arrived for as long as it would have taken at the capped bandwidth.
+ * The chunks of every download share the same bandwidth, so they queue up behind each other.
+ */
+export const throttleBandwidth = async (bytes: number) => {
+  if (bytesPerSecond === undefined) {
+    return;
+  }
+
+  bandwidthFreeAt = Math.max(now(), bandwidthFreeAt) + (bytes / bytesPerSecond) * 1000;
+  const wait = bandwidthFreeAt - now();
+  if (wait > 0) {
+    await sleep(wait);
+  }
+};
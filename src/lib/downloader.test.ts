@@ -1,53 +1,628 @@
+import * as crypto from 'crypto';
+import fs from 'node:fs/promises';
+import os from 'node:os';
 import path from 'node:path';
 import { chance } from 'jest-chance';
-import { default as Downloader } from 'nodejs-file-downloader';
-import { afterEach, describe, expect, it, vi } from 'vitest';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
 import { DownloadFailedException } from '../errors/DownloadFailedException.js';
-import { downloadFile } from './downloader.js';
+import { DownloadHashMismatchException } from '../errors/DownloadHashMismatchException.js';
+import { DownloadRedirectedException } from '../errors/DownloadRedirectedException.js';
+import { DownloadSizeMismatchException } from '../errors/DownloadSizeMismatchException.js';
+import { OfflineException } from '../errors/OfflineException.js';
+import { setDownloadBandwidth } from './downloadThrottle.js';
+import { DEFAULT_DOWNLOAD_IDLE_TIMEOUT, downloadFile, setDownloadIdleTimeout } from './downloader.js';
+import { getFileCacheDirectory, isCached, setFileCacheDirectory, storeInCache } from './fileCache.js';
+import { getMetrics, resetMetrics } from './metrics.js';
+import { setOfflineMode } from './offline.js';
+import { ProgressEvent, setProgress } from './progress.js';
+import { setNow, setSleep } from './rateLimiter/clock.js';
 
-vi.mock('nodejs-file-downloader');
+interface LocalTestContext {
+  directory: string;
+  destination: string;
+  url: string;
+  contents: string;
+  hash: string;
+}
 
-describe('The downloader facade', () => {
-  afterEach(() => {
+const sha1 = (contents: string) => crypto.createHash('sha1').update(contents).digest('hex');
+
+const respondWith = (body: string, status = 200) => {
+  vi.mocked(fetch).mockResolvedValueOnce(new Response(body, { status: status }));
+};
+
+const respondToHead = (status: number, contentLength?: number) => {
+  const headers = contentLength === undefined ? {} : { 'content-length': String(contentLength) };
+  vi.mocked(fetch).mockResolvedValueOnce(new Response(null, { status: status, headers: headers }));
+};
+
+const respondRedirectedTo = (location: string, body: string | null, headers: Record<string, string> = {}) => {
+  const response = new Response(body, { status: 200, headers: headers });
+  Object.defineProperty(response, 'redirected', { value: true });
+  Object.defineProperty(response, 'url', { value: location });
+  vi.mocked(fetch).mockResolvedValueOnce(response);
+};
+
+const respondToHeadRedirectedTo = (location: string, contentLength: number) => {
+  respondRedirectedTo(location, null, { 'content-length': String(contentLength) });
+};
+
+const respondWithBrokenStream = (body: string) => {
+  let sent = false;
+  const stream = new ReadableStream({
+    pull(controller) {
+      if (sent) {
+        controller.error(new Error('connection reset'));
+        return;
+      }
+      sent = true;
+      controller.enqueue(new TextEncoder().encode(body));
+    }
+  });
+  vi.mocked(fetch).mockResolvedValueOnce(new Response(stream));
+};
+
+// Sends the first part of the file and then keeps the connection open without sending anything else
+const respondWithStalledStream = (body: string, status = 200) => {
+  let sent = false;
+  const stream = new ReadableStream({
+    pull(controller) {
+      if (sent) {
+        return new Promise(() => {});
+      }
+      sent = true;
+      controller.enqueue(new TextEncoder().encode(body));
+    }
+  });
+  vi.mocked(fetch).mockResolvedValueOnce(new Response(stream, { status: status }));
+};
+
+const sentHeaders = (call: number) => vi.mocked(fetch).mock.calls[call][1]?.headers as Headers;
+
+describe('The downloader', () => {
+  beforeEach<LocalTestContext>(async (context) => {
+    vi.stubGlobal('fetch', vi.fn());
+    context.directory = await fs.mkdtemp(path.join(os.tmpdir(), 'mmm-download-'));
+    context.destination = path.resolve(context.directory, `${chance.word()}.jar`);
+    context.url = chance.url({ protocol: 'https' });
+    context.contents = chance.paragraph();
+    context.hash = sha1(context.contents);
+    setFileCacheDirectory(path.resolve(context.directory, 'cache'));
+  });
+
+  afterEach<LocalTestContext>(async (context) => {
     vi.resetAllMocks();
+    setFileCacheDirectory();
+    setOfflineMode();
+    setDownloadBandwidth();
+    setNow();
+    setSleep();
+    setProgress();
+    resetMetrics();
+    setDownloadIdleTimeout();
+    await fs.rm(context.directory, { recursive: true, force: true });
   });
 
-  it('should invoke the downloader lib as expected', async () => {
-    const url = chance.url();
-    const destination = path.resolve(chance.word());
+  it<LocalTestContext>('downloads the file to its destination', async (context) => {
+    respondWith(context.contents);
+
+    await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+    expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+    await expect(fs.access(context.destination + '.part')).rejects.toThrow();
+    expect(vi.mocked(fetch).mock.calls[0][0]).toEqual(context.url);
+    expect(sentHeaders(0).has('Range')).toBeFalsy();
+  });
+
+  it<LocalTestContext>('does not need a hash', async (context) => {
+    respondWith(context.contents);
+
+    await downloadFile(context.url, context.destination);
+
+    expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+  });
+
+  it<LocalTestContext>('accepts the hash in any case', async (context) => {
+    respondWith(context.contents);
+
+    await downloadFile(context.url, context.destination, { expectedHash: context.hash.toUpperCase() });
+
+    expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+  });
 
-    // @ts-ignore
-    vi.mocked(Downloader).mockImplementationOnce(() => ({
-      download: vi.fn().mockResolvedValueOnce({ downloadStatus: 'COMPLETE', filePath: destination }),
-      cancel: vi.fn()
-    }));
+  it<LocalTestContext>('keeps the downloaded file in the cache', async (context) => {
+    respondWith(context.contents);
 
-    expect(async () => {
-      await downloadFile(url, destination);
-    }).not.toThrow();
+    await downloadFile(context.url, context.destination, { expectedHash: context.hash });
 
-    expect(vi.mocked(Downloader)).toHaveBeenCalledOnce();
-    expect(vi.mocked(Downloader)).toHaveBeenCalledWith({
-      url: url,
-      directory: path.dirname(destination),
-      filename: path.basename(destination),
-      cloneFiles: false,
-      maxAttempts: 3
+    expect(await isCached(context.hash)).toBeTruthy();
+  });
+
+  it<LocalTestContext>('does not cache a file without a hash', async (context) => {
+    respondWith(context.contents);
+
+    await downloadFile(context.url, context.destination);
+
+    await expect(fs.readdir(getFileCacheDirectory())).rejects.toThrow();
+  });
+
+  it<LocalTestContext>('takes the file from the cache when it has it', async (context) => {
+    const cachedFile = path.resolve(context.directory, 'cached.jar');
+    await fs.writeFile(cachedFile, context.contents);
+    await storeInCache(cachedFile, context.hash);
+
+    await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+    expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+    expect(vi.mocked(fetch)).not.toHaveBeenCalled();
+  });
+
+  it<LocalTestContext>('downloads the file again when the cache is skipped', async (context) => {
+    const cachedFile = path.resolve(context.directory, 'cached.jar');
+    await fs.writeFile(cachedFile, context.contents);
+    await storeInCache(cachedFile, context.hash);
+    respondWith(context.contents);
+
+    await downloadFile(context.url, context.destination, { expectedHash: context.hash, skipCache: true });
+
+    expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+    expect(vi.mocked(fetch)).toHaveBeenCalledOnce();
+  });
+
+  describe('in offline mode', () => {
+    beforeEach(() => {
+      setOfflineMode(true);
+    });
+
+    it<LocalTestContext>('takes the file from the cache', async (context) => {
+      const cachedFile = path.resolve(context.directory, 'cached.jar');
+      await fs.writeFile(cachedFile, context.contents);
+      await storeInCache(cachedFile, context.hash);
+
+      await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+      expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+    });
+
+    it<LocalTestContext>('refuses to download a file that is not in the cache', async (context) => {
+      await expect(downloadFile(context.url, context.destination, { expectedHash: context.hash })).rejects.toThrow(
+        new OfflineException(context.url)
+      );
+
+      expect(vi.mocked(fetch)).not.toHaveBeenCalled();
+      await expect(fs.access(context.destination)).rejects.toThrow();
     });
   });
 
-  it('should throw an error if the download fails', async () => {
-    const url = chance.url();
-    const destination = path.resolve(chance.word());
+  it<LocalTestContext>('does not download a file of unknown size redirected away from https', async (context) => {
+    const location = chance.url({ protocol: 'http' });
+    respondRedirectedTo(location, context.contents);
+
+    await expect(downloadFile(context.url, context.destination, { expectedHash: context.hash })).rejects.toThrow(
+      new DownloadRedirectedException(context.url, location)
+    );
 
-    // @ts-ignore
-    vi.mocked(Downloader).mockImplementationOnce(() => ({
-      download: vi.fn().mockRejectedValueOnce(new Error('Download failed')),
-      cancel: vi.fn()
-    }));
+    // A redirect isn't retried, the next attempt would end up in the same place
+    expect(fetch).toHaveBeenCalledTimes(1);
+    await expect(fs.access(context.destination)).rejects.toThrow();
+  });
+
+  describe('when the size of the file is known', () => {
+    it<LocalTestContext>('checks the file on the server before downloading it', async (context) => {
+      respondToHead(200, context.contents.length);
+      respondWith(context.contents);
+
+      await downloadFile(context.url, context.destination, {
+        expectedHash: context.hash,
+        expectedLength: context.contents.length
+      });
+
+      expect(vi.mocked(fetch).mock.calls[0][0]).toEqual(context.url);
+      expect(vi.mocked(fetch).mock.calls[0][1]?.method).toEqual('HEAD');
+      expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+    });
+
+    it<LocalTestContext>('does not download a file of a different size', async (context) => {
+      const expectedLength = context.contents.length + 1;
+      respondToHead(200, context.contents.length);
+
+      await expect(
+        downloadFile(context.url, context.destination, {
+          expectedHash: context.hash,
+          expectedLength: expectedLength
+        })
+      ).rejects.toThrow(new DownloadSizeMismatchException(context.url, expectedLength, context.contents.length));
+
+      expect(fetch).toHaveBeenCalledTimes(1);
+      await expect(fs.access(context.destination)).rejects.toThrow();
+    });
+
+    it<LocalTestContext>('downloads the file when the server does not allow HEAD requests', async (context) => {
+      respondToHead(405);
+      respondWith(context.contents);
+
+      await downloadFile(context.url, context.destination, {
+        expectedHash: context.hash,
+        expectedLength: context.contents.length
+      });
+
+      expect(fetch).toHaveBeenCalledTimes(2);
+      expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+    });
+
+    it<LocalTestContext>('downloads the file when the server does not send the length', async (context) => {
+      respondToHead(200);
+      respondWith(context.contents);
+
+      await downloadFile(context.url, context.destination, {
+        expectedHash: context.hash,
+        expectedLength: context.contents.length + 1
+      });
+
+      expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+    });
+
+    it<LocalTestContext>('downloads the file when the HEAD request cannot be made', async (context) => {
+      vi.mocked(fetch).mockRejectedValueOnce(new Error('ECONNRESET'));
+      respondWith(context.contents);
+
+      await downloadFile(context.url, context.destination, {
+        expectedHash: context.hash,
+        expectedLength: context.contents.length
+      });
+
+      expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+    });
+
+    it<LocalTestContext>('fails early when the server does not have the file', async (context) => {
+      respondToHead(404);
+
+      await expect(
+        downloadFile(context.url, context.destination, {
+          expectedHash: context.hash,
+          expectedLength: context.contents.length
+        })
+      ).rejects.toThrow(new DownloadFailedException(context.url));
+
+      expect(fetch).toHaveBeenCalledTimes(1);
+    });
+
+    it<LocalTestContext>('follows a redirect that stays on https', async (context) => {
+      respondToHeadRedirectedTo(chance.url({ protocol: 'https' }), context.contents.length);
+      respondWith(context.contents);
+
+      await downloadFile(context.url, context.destination, {
+        expectedHash: context.hash,
+        expectedLength: context.contents.length
+      });
+
+      expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+    });
+
+    it<LocalTestContext>('does not download a file that is redirected away from https', async (context) => {
+      const location = chance.url({ protocol: 'http' });
+      respondToHeadRedirectedTo(location, context.contents.length);
+
+      await expect(
+        downloadFile(context.url, context.destination, {
+          expectedHash: context.hash,
+          expectedLength: context.contents.length
+        })
+      ).rejects.toThrow(new DownloadRedirectedException(context.url, location));
+
+      expect(fetch).toHaveBeenCalledTimes(1);
+      await expect(fs.access(context.destination)).rejects.toThrow();
+    });
+
+    it<LocalTestContext>('refuses an insecure redirect when the HEAD request is not allowed', async (context) => {
+      const location = chance.url({ protocol: 'http' });
+      respondToHead(405);
+      respondRedirectedTo(location, context.contents);
+
+      await expect(
+        downloadFile(context.url, context.destination, {
+          expectedHash: context.hash,
+          expectedLength: context.contents.length
+        })
+      ).rejects.toThrow(new DownloadRedirectedException(context.url, location));
+
+      expect(fetch).toHaveBeenCalledTimes(2);
+      await expect(fs.access(context.destination)).rejects.toThrow();
+    });
+
+    it<LocalTestContext>('does not ask the server about a cached file', async (context) => {
+      const cached = path.resolve(context.directory, 'cached.jar');
+      await fs.writeFile(cached, context.contents);
+      await storeInCache(cached, context.hash);
+
+      await downloadFile(context.url, context.destination, {
+        expectedHash: context.hash,
+        expectedLength: context.contents.length
+      });
+
+      expect(fetch).not.toHaveBeenCalled();
+    });
+  });
+
+  it<LocalTestContext>('slows the download down to the capped bandwidth', async (context) => {
+    let time = 0;
+    setNow(() => time);
+    setSleep(async (milliseconds) => {
+      time += milliseconds;
+    });
+    setDownloadBandwidth(10);
+    respondWith(context.contents);
 
-    await expect(async () => {
-      await downloadFile(url, destination);
-    }).rejects.toThrow(new DownloadFailedException(url));
+    await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+    expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+    expect(time).toEqual(Buffer.byteLength(context.contents) * 100);
+  });
+
+  describe('when reporting the progress', () => {
+    const respondInChunks = (chunks: string[], headers: Record<string, string> = {}) => {
+      const stream = new ReadableStream({
+        start(controller) {
+          chunks.forEach((chunk) => controller.enqueue(new TextEncoder().encode(chunk)));
+          controller.close();
+        }
+      });
+      vi.mocked(fetch).mockResolvedValueOnce(new Response(stream, { headers: headers }));
+    };
+
+    const collectEvents = () => {
+      const events: ProgressEvent[] = [];
+      setProgress({ report: (event) => events.push(event) });
+      return events;
+    };
+
+    it<LocalTestContext>('reports every chunk and then the verification', async (context) => {
+      const events = collectEvents();
+      const fileName = path.basename(context.destination);
+      respondToHead(200, 10);
+      respondInChunks(['0123', '4567', '89']);
+
+      await downloadFile(context.url, context.destination, { expectedHash: sha1('0123456789'), expectedLength: 10 });
+
+      expect(events).toEqual([
+        { type: 'downloading', fileName: fileName, downloadedBytes: 4, totalBytes: 10 },
+        { type: 'downloading', fileName: fileName, downloadedBytes: 8, totalBytes: 10 },
+        { type: 'downloading', fileName: fileName, downloadedBytes: 10, totalBytes: 10 },
+        { type: 'verifying', fileName: fileName }
+      ]);
+    });
+
+    it<LocalTestContext>('takes the size from the server when it is not known', async (context) => {
+      const events = collectEvents();
+      respondInChunks(['0123', '4567'], { 'content-length': '8' });
+
+      await downloadFile(context.url, context.destination);
+
+      expect(events.map((event) => event.type)).toEqual(['downloading', 'downloading']);
+      expect(events.at(-1)).toHaveProperty('totalBytes', 8);
+    });
+
+    it<LocalTestContext>('counts what was already there when resuming', async (context) => {
+      const events = collectEvents();
+      await fs.writeFile(`${context.destination}.part`, '0123');
+      vi.mocked(fetch).mockResolvedValueOnce(new Response('4567', { status: 206, headers: { 'content-length': '4' } }));
+
+      await downloadFile(context.url, context.destination);
+
+      expect(events).toEqual([
+        { type: 'downloading', fileName: path.basename(context.destination), downloadedBytes: 8, totalBytes: 8 }
+      ]);
+    });
+
+    it<LocalTestContext>('does not know the size when nobody tells it', async (context) => {
+      const events = collectEvents();
+      respondInChunks(['0123']);
+
+      await downloadFile(context.url, context.destination);
+
+      expect(events).toEqual([
+        { type: 'downloading', fileName: path.basename(context.destination), downloadedBytes: 4, totalBytes: undefined }
+      ]);
+    });
+
+    it<LocalTestContext>('has nothing to report for a cached file', async (context) => {
+      const cachedFile = path.resolve(context.directory, 'cached.jar');
+      await fs.writeFile(cachedFile, context.contents);
+      await storeInCache(cachedFile, context.hash);
+      const events = collectEvents();
+
+      await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+      expect(events).toEqual([]);
+    });
+  });
+
+  it<LocalTestContext>('removes the download when the hash does not match', async (context) => {
+    const expectedHash = chance.hash();
+    respondWith(context.contents);
+
+    await expect(downloadFile(context.url, context.destination, { expectedHash: expectedHash })).rejects.toThrow(
+      new DownloadHashMismatchException(context.url, expectedHash, context.hash)
+    );
+
+    await expect(fs.access(context.destination)).rejects.toThrow();
+    await expect(fs.access(context.destination + '.part')).rejects.toThrow();
+  });
+
+  it<LocalTestContext>('resumes a truncated stream with a range request', async (context) => {
+    const splitAt = Math.floor(context.contents.length / 2);
+    respondWithBrokenStream(context.contents.slice(0, splitAt));
+    respondWith(context.contents.slice(splitAt), 206);
+
+    await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+    expect(sentHeaders(1).get('Range')).toEqual(`bytes=${splitAt}-`);
+    expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+  });
+
+  it<LocalTestContext>('resumes a partial file left by an earlier download', async (context) => {
+    const splitAt = Math.floor(context.contents.length / 3);
+    await fs.writeFile(context.destination + '.part', context.contents.slice(0, splitAt));
+    respondWith(context.contents.slice(splitAt), 206);
+
+    await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+    expect(sentHeaders(0).get('Range')).toEqual(`bytes=${splitAt}-`);
+    expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+  });
+
+  it<LocalTestContext>('starts over when the server ignores the range', async (context) => {
+    await fs.writeFile(context.destination + '.part', context.contents.slice(0, 10));
+    respondWith(context.contents, 200);
+
+    await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+    expect(sentHeaders(0).get('Range')).toEqual('bytes=10-');
+    expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+  });
+
+  it<LocalTestContext>('starts over when the range cannot be satisfied', async (context) => {
+    await fs.writeFile(context.destination + '.part', context.contents + context.contents);
+    respondWith('', 416);
+    respondWith(context.contents);
+
+    await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+    expect(sentHeaders(1).has('Range')).toBeFalsy();
+    expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+  });
+
+  it<LocalTestContext>('fails after the maximum attempts and keeps the partial file', async (context) => {
+    respondWithBrokenStream(context.contents.slice(0, 5));
+    respondWith('', 500);
+    respondWith('', 500);
+
+    await expect(downloadFile(context.url, context.destination, { expectedHash: context.hash })).rejects.toThrow(
+      new DownloadFailedException(context.url)
+    );
+
+    expect(fetch).toHaveBeenCalledTimes(3);
+    await expect(fs.access(context.destination)).rejects.toThrow();
+    expect(await fs.readFile(context.destination + '.part', 'utf-8')).toEqual(context.contents.slice(0, 5));
+  });
+
+  it<LocalTestContext>('fails when the response has no body', async (context) => {
+    vi.mocked(fetch).mockImplementation(async () => new Response(null));
+
+    await expect(downloadFile(context.url, context.destination)).rejects.toThrow(
+      new DownloadFailedException(context.url)
+    );
+  });
+
+  it<LocalTestContext>('fails when the request cannot be made', async (context) => {
+    vi.mocked(fetch).mockRejectedValue(new Error('ENOTFOUND'));
+
+    await expect(downloadFile(context.url, context.destination)).rejects.toThrow(
+      new DownloadFailedException(context.url)
+    );
+  });
+
+  describe('when counting the metrics', () => {
+    it<LocalTestContext>('counts the requests and the downloaded bytes', async (context) => {
+      const size = Buffer.byteLength(context.contents);
+      respondToHead(200, size);
+      respondWithBrokenStream(context.contents.slice(0, 5));
+      respondWith(context.contents);
+
+      await downloadFile(context.url, context.destination, { expectedHash: context.hash, expectedLength: size });
+
+      expect(getMetrics()).toMatchObject({
+        requests: 3,
+        retries: 1,
+        clientErrors: 0,
+        serverErrors: 0,
+        downloadedBytes: 5 + size
+      });
+    });
+
+    it<LocalTestContext>('counts the requests that failed', async (context) => {
+      vi.mocked(fetch).mockRejectedValueOnce(new Error('ECONNRESET'));
+      respondWith('', 404);
+      respondWith('', 503);
+
+      await expect(downloadFile(context.url, context.destination)).rejects.toThrow(DownloadFailedException);
+
+      expect(getMetrics()).toMatchObject({ requests: 3, retries: 2, clientErrors: 1, serverErrors: 1 });
+    });
+
+    it<LocalTestContext>('counts the check before the download that failed', async (context) => {
+      vi.mocked(fetch).mockRejectedValueOnce(new Error('ECONNRESET'));
+      respondWith(context.contents);
+
+      await downloadFile(context.url, context.destination, { expectedLength: Buffer.byteLength(context.contents) });
+
+      expect(getMetrics()).toMatchObject({ requests: 2, retries: 0 });
+    });
+
+    it<LocalTestContext>('counts a file from the cache as a cache hit', async (context) => {
+      const cachedFile = path.resolve(context.directory, 'cached.jar');
+      await fs.writeFile(cachedFile, context.contents);
+      await storeInCache(cachedFile, context.hash);
+
+      await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+      expect(getMetrics()).toMatchObject({ requests: 0, cacheHits: 1 });
+    });
+  });
+
+  describe('when the download stops getting data', () => {
+    beforeEach(() => {
+      setDownloadIdleTimeout(20);
+    });
+
+    it('has an idle timeout of 30 seconds by default', () => {
+      expect(DEFAULT_DOWNLOAD_IDLE_TIMEOUT).toEqual(30000);
+    });
+
+    it<LocalTestContext>('resumes the download after the idle timeout', async (context) => {
+      respondWithStalledStream(context.contents.slice(0, 5));
+      vi.mocked(fetch).mockResolvedValueOnce(new Response(context.contents.slice(5), { status: 206 }));
+
+      await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+      expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+      expect(fetch).toHaveBeenCalledTimes(2);
+      expect(sentHeaders(1).get('Range')).toEqual('bytes=5-');
+    });
+
+    it<LocalTestContext>('fails when every attempt stalls and keeps the partial file', async (context) => {
+      respondWithStalledStream(context.contents.slice(0, 5));
+      respondWithStalledStream(context.contents.slice(5, 10), 206);
+      respondWithStalledStream(context.contents.slice(10, 15), 206);
+
+      await expect(downloadFile(context.url, context.destination, { expectedHash: context.hash })).rejects.toThrow(
+        new DownloadFailedException(context.url)
+      );
+
+      expect(fetch).toHaveBeenCalledTimes(3);
+      expect(await fs.readFile(context.destination + '.part', 'utf-8')).toEqual(context.contents.slice(0, 15));
+    });
+
+    it<LocalTestContext>('lets a slow download finish as long as the data keeps coming', async (context) => {
+      const chunks = [context.contents.slice(0, 5), context.contents.slice(5)];
+      const stream = new ReadableStream({
+        async pull(controller) {
+          await new Promise((resolve) => setTimeout(resolve, 10));
+          const chunk = chunks.shift();
+          if (chunk === undefined) {
+            controller.close();
+            return;
+          }
+          controller.enqueue(new TextEncoder().encode(chunk));
+        }
+      });
+      vi.mocked(fetch).mockResolvedValueOnce(new Response(stream));
+      setDownloadIdleTimeout(200);
+
+      await downloadFile(context.url, context.destination, { expectedHash: context.hash });
+
+      expect(await fs.readFile(context.destination, 'utf-8')).toEqual(context.contents);
+      expect(fetch).toHaveBeenCalledOnce();
+    });
   });
 });
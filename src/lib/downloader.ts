@@ -1,20 +1,272 @@
-import path from 'path';
-import Downloader from 'nodejs-file-downloader';
+import fs from 'node:fs/promises';
+import path from 'node:path';
 import { DownloadFailedException } from '../errors/DownloadFailedException.js';
+import { DownloadHashMismatchException } from '../errors/DownloadHashMismatchException.js';
+import { DownloadRedirectedException } from '../errors/DownloadRedirectedException.js';
+import { DownloadSizeMismatchException } from '../errors/DownloadSizeMismatchException.js';
+import { DownloadStalledException } from '../errors/DownloadStalledException.js';
+import { throttleBandwidth, withDownloadSlot } from './downloadThrottle.js';
+import { restoreFromCache, storeInCache } from './fileCache.js';
+import { getHash } from './hash.js';
+import { countCacheHit, countDownloadedBytes, countRequest } from './metrics.js';
+import { assertOnline } from './offline.js';
+import { getProgress } from './progress.js';
+import { transportFetch } from './rateLimiter/transport.js';
+import { getUserAgent } from './rateLimiter/userAgent.js';
 
-export const downloadFile = async (url: string, destination: string) => {
-  // eslint-disable-next-line @typescript-eslint/ban-ts-comment
-  // @ts-ignore
-  const downloader = new Downloader({
-    url: url,
-    directory: path.dirname(destination),
-    filename: path.basename(destination),
-    cloneFiles: false,
-    maxAttempts: 3
+const MAX_ATTEMPTS = 3;
+const PARTIAL_CONTENT = 206;
+const RANGE_NOT_SATISFIABLE = 416;
+const METHOD_NOT_ALLOWED = 405;
+const NOT_IMPLEMENTED = 501;
+
+export const DEFAULT_DOWNLOAD_IDLE_TIMEOUT = 30000;
+
+let idleTimeout = DEFAULT_DOWNLOAD_IDLE_TIMEOUT;
+
+/**
+ * Sets how long a download can go without receiving any data before the attempt is given up and resumed.
+ * A stalled stream keeps the connection open, so the timeout of the request itself never notices it.
+ * Calling it without a value goes back to the default of 30 seconds.
+ */
+export const setDownloadIdleTimeout = (milliseconds?: number) => {
+  idleTimeout = milliseconds ?? DEFAULT_DOWNLOAD_IDLE_TIMEOUT;
+};
+
+/**
+ * Reads the next chunk, unless no data arrives within the idle timeout
+ *
+ * @throws {DownloadStalledException} When the chunk takes longer than the idle timeout
+ */
+const readChunk = async (reader: ReadableStreamDefaultReader<Uint8Array>, url: string) => {
+  let timer: NodeJS.Timeout | undefined;
+  const stalled = new Promise<never>((_resolve, reject) => {
+    timer = setTimeout(() => reject(new DownloadStalledException(url, idleTimeout)), idleTimeout);
   });
+
   try {
-    await downloader.download();
+    return await Promise.race([reader.read(), stalled]);
+  } catch (error) {
+    // Let go of the connection, the next attempt resumes from what was written so far
+    reader.cancel().catch(() => {});
+    throw error;
+  } finally {
+    clearTimeout(timer);
+  }
+};
+
+const partialFileFor = (destination: string) => `${destination}.part`;
+
+const downloadedSize = async (partialFile: string) => {
+  return await fs.stat(partialFile).then(
+    (stats) => stats.size,
+    () => 0
+  );
+};
+
+/**
+ * A resumed download only sends the rest of the file, so the length from the server doesn't cover what we already have
+ */
+const totalLength = (response: Response, alreadyDownloaded: number, expectedLength?: number) => {
+  if (expectedLength !== undefined) {
+    return expectedLength;
+  }
+  const contentLength = Number.parseInt(response.headers.get('content-length') ?? '', 10);
+  return Number.isNaN(contentLength) ? undefined : contentLength + alreadyDownloaded;
+};
+
+/**
+ * The CDNs redirect to wherever the file is stored, but a file asked for over https has to stay on https
+ */
+const isUnexpectedRedirect = (url: string, response: Response) => {
+  if (!response.redirected || !response.url) {
+    return false;
+  }
+  return new URL(url).protocol === 'https:' && new URL(response.url).protocol !== 'https:';
+};
+
+const downloadAttempt = async (
+  url: string,
+  partialFile: string,
+  fileName: string,
+  attempt: number,
+  expectedLength?: number
+) => {
+  const size = await downloadedSize(partialFile);
+  const headers = new Headers({ 'user-agent': getUserAgent() });
+  if (size > 0) {
+    headers.set('Range', `bytes=${size}-`);
+  }
+
+  let response: Response;
+  try {
+    response = await transportFetch(url, { headers: headers });
+  } catch (error) {
+    countRequest(attempt, null);
+    throw error;
+  }
+  countRequest(attempt, response.status);
+
+  if (response.status === RANGE_NOT_SATISFIABLE) {
+    // Whatever we have isn't a prefix of the file anymore, start over
+    await fs.rm(partialFile, { force: true });
+    throw new Error(`Could not resume ${url}`);
+  }
+
+  if (!response.ok || !response.body) {
+    throw new Error(`Could not download ${url}: ${response.status}`);
+  }
+
+  // The HEAD request isn't always made or answered, so the response with the file is checked too
+  if (isUnexpectedRedirect(url, response)) {
+    throw new DownloadRedirectedException(url, response.url);
+  }
+
+  // Servers that ignore the range send the whole file again
+  const resumed = response.status === PARTIAL_CONTENT;
+  const file = await fs.open(partialFile, resumed ? 'a' : 'w');
+  const reader = response.body.getReader();
+  let downloadedBytes = resumed ? size : 0;
+  const totalBytes = totalLength(response, downloadedBytes, expectedLength);
+  try {
+    // Every chunk is written as it arrives so a dropped connection leaves a file we can resume
+    for (;;) {
+      const { done, value } = await readChunk(reader, url);
+      if (done) {
+        break;
+      }
+      await file.write(value);
+      downloadedBytes += value.byteLength;
+      countDownloadedBytes(value.byteLength);
+      getProgress().report({
+        type: 'downloading',
+        fileName: fileName,
+        downloadedBytes: downloadedBytes,
+        totalBytes: totalBytes
+      });
+      await throttleBandwidth(value.byteLength);
+    }
+  } finally {
+    await file.close();
+  }
+};
+
+/**
+ * Asks the server about the file before downloading it, so a missing file or a different one fails straight away.
+ * Servers that don't answer HEAD requests, or don't send the length, are trusted and the download goes ahead.
+ *
+ * @throws {DownloadFailedException} When the server doesn't have the file
+ * @throws {DownloadRedirectedException} When the server redirects the download to an address that isn't secure
+ * @throws {DownloadSizeMismatchException} When the server has a file of a different size
+ */
+const preflightCheck = async (url: string, expectedLength: number) => {
+  let response: Response;
+  try {
+    response = await transportFetch(url, { method: 'HEAD', headers: { 'user-agent': getUserAgent() } });
   } catch (_) {
+    countRequest(1, null);
+    // The download itself retries, there's no point in failing here
+    return;
+  }
+
+  countRequest(1, response.status);
+
+  if (response.status === METHOD_NOT_ALLOWED || response.status === NOT_IMPLEMENTED) {
+    return;
+  }
+
+  if (!response.ok) {
     throw new DownloadFailedException(url);
   }
+
+  if (isUnexpectedRedirect(url, response)) {
+    throw new DownloadRedirectedException(url, response.url);
+  }
+
+  const contentLength = Number.parseInt(response.headers.get('content-length') ?? '', 10);
+  if (!Number.isNaN(contentLength) && contentLength !== expectedLength) {
+    throw new DownloadSizeMismatchException(url, expectedLength, contentLength);
+  }
+};
+
+export interface DownloadOptions {
+  /**
+   * The sha1 hash the downloaded file has to match
+   */
+  expectedHash?: string;
+  /**
+   * Downloads the file even when the file cache has it
+   */
+  skipCache?: boolean;
+  /**
+   * The size of the file in bytes, the server is asked about it before the download
+   */
+  expectedLength?: number;
+}
+
+/**
+ * Downloads the file next to its destination first and only moves it into place once it's complete.
+ * An interrupted download is resumed from where it stopped, when the server supports ranges.
+ * A download that gets no data for the idle timeout counts as interrupted too.
+ * When the expected sha1 hash is known, the downloaded file has to match it.
+ * Files with a known hash are kept in the file cache and are taken from there the next time they're needed,
+ * unless the cache is skipped to get a fresh copy.
+ * When the size of the file is known, the server is asked whether it has the same file before it's downloaded.
+ * A download redirected from https to somewhere that isn't secure is refused, whether or not the server was asked.
+ * Only a few files are downloaded at the same time and their bandwidth can be capped, see the download throttle.
+ * The progress hears about every chunk that arrives and about the file being verified.
+ *
+ * @throws {OfflineException} When the file isn't in the cache and mmm is in offline mode
+ * @throws {DownloadFailedException} When the file can't be downloaded
+ * @throws {DownloadRedirectedException} When the server redirects the download to an address that isn't secure
+ * @throws {DownloadSizeMismatchException} When the server has a file of a different size
+ * @throws {DownloadHashMismatchException} When the downloaded file doesn't match the expected hash
+ */
+export const downloadFile = async (url: string, destination: string, options: DownloadOptions = {}) => {
+  const { expectedHash, skipCache = false, expectedLength } = options;
+  if (expectedHash && !skipCache && (await restoreFromCache(expectedHash, destination))) {
+    countCacheHit();
+    return;
+  }
+
+  assertOnline(url);
+
+  const partialFile = partialFileFor(destination);
+  const fileName = path.basename(destination);
+
+  await withDownloadSlot(async () => {
+    if (expectedLength !== undefined) {
+      await preflightCheck(url, expectedLength);
+    }
+
+    for (let attempt = 1; ; attempt++) {
+      try {
+        await downloadAttempt(url, partialFile, fileName, attempt, expectedLength);
+        return;
+      } catch (error) {
+        if (error instanceof DownloadRedirectedException) {
+          throw error;
+        }
+        if (attempt === MAX_ATTEMPTS) {
+          // The partial file stays so the next download can pick up where this one stopped
+          throw new DownloadFailedException(url);
+        }
+      }
+    }
+  });
+
+  if (expectedHash) {
+    getProgress().report({ type: 'verifying', fileName: fileName });
+    const actualHash = await getHash(partialFile);
+    if (actualHash.toLowerCase() !== expectedHash.toLowerCase()) {
+      await fs.rm(partialFile, { force: true });
+      throw new DownloadHashMismatchException(url, expectedHash, actualHash);
+    }
+  }
+
+  await fs.rename(partialFile, destination);
+
+  if (expectedHash) {
+    await storeInCache(destination, expectedHash);
+  }
 };
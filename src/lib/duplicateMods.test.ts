@@ -0,0 +1,73 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { DuplicateReason, findDuplicateMods } from './duplicateMods.js';
+import { Mod, Platform } from './modlist.types.js';
+
+const configurationWith = (mods: Mod[]) => generateModsJson({ mods: mods }).generated;
+
+describe('The duplicate mod detection', () => {
+  it('finds a mod that was added from both platforms', () => {
+    const curseforge = generateModConfig({ type: Platform.CURSEFORGE, id: '394468', name: 'Sodium' }).generated;
+    const modrinth = generateModConfig({ type: Platform.MODRINTH, id: 'AANobbMI', name: 'sodium' }).generated;
+
+    const actual = findDuplicateMods(configurationWith([curseforge, modrinth]));
+
+    expect(actual).toEqual([{ mods: [curseforge, modrinth], reason: DuplicateReason.NAME }]);
+  });
+
+  it('ignores the punctuation in the names', () => {
+    const curseforge = generateModConfig({ type: Platform.CURSEFORGE, name: 'Fabric API' }).generated;
+    const modrinth = generateModConfig({ type: Platform.MODRINTH, name: 'fabric-api' }).generated;
+
+    expect(findDuplicateMods(configurationWith([curseforge, modrinth]))).toHaveLength(1);
+  });
+
+  it('does not report genuinely different mods', () => {
+    const sodium = generateModConfig({ type: Platform.CURSEFORGE, name: 'Sodium' }).generated;
+    const sodiumExtra = generateModConfig({ type: Platform.MODRINTH, name: 'Sodium Extra' }).generated;
+
+    expect(findDuplicateMods(configurationWith([sodium, sodiumExtra]))).toEqual([]);
+  });
+
+  it('does not trust the short names', () => {
+    const curseforge = generateModConfig({ type: Platform.CURSEFORGE, name: 'Lib' }).generated;
+    const modrinth = generateModConfig({ type: Platform.MODRINTH, name: 'LIB' }).generated;
+
+    expect(findDuplicateMods(configurationWith([curseforge, modrinth]))).toEqual([]);
+  });
+
+  it('only compares the mods of different platforms', () => {
+    const first = generateModConfig({ type: Platform.MODRINTH, name: 'Sodium' }).generated;
+    const second = generateModConfig({ type: Platform.MODRINTH, name: 'Sodium' }).generated;
+
+    expect(findDuplicateMods(configurationWith([first, second]))).toEqual([]);
+  });
+
+  it('finds the mods that have the same file installed', () => {
+    const curseforge = generateModConfig({ type: Platform.CURSEFORGE, name: 'Iris Shaders' }).generated;
+    const modrinth = generateModConfig({ type: Platform.MODRINTH, name: 'Iris' }).generated;
+    const hash = chance.hash();
+    const installations = [
+      generateModInstall({ type: curseforge.type, id: curseforge.id, hash: hash }).generated,
+      generateModInstall({ type: modrinth.type, id: modrinth.id, hash: hash }).generated
+    ];
+
+    const actual = findDuplicateMods(configurationWith([curseforge, modrinth]), installations);
+
+    expect(actual).toEqual([{ mods: [curseforge, modrinth], reason: DuplicateReason.FILE }]);
+  });
+
+  it('does not report the mods with different files', () => {
+    const curseforge = generateModConfig({ type: Platform.CURSEFORGE, name: 'Iris Shaders' }).generated;
+    const modrinth = generateModConfig({ type: Platform.MODRINTH, name: 'Iris' }).generated;
+    const installations = [
+      generateModInstall({ type: curseforge.type, id: curseforge.id }).generated,
+      generateModInstall({ type: modrinth.type, id: modrinth.id }).generated
+    ];
+
+    expect(findDuplicateMods(configurationWith([curseforge, modrinth]), installations)).toEqual([]);
+  });
+});
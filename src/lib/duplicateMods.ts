@@ -0,0 +1,61 @@
+import { getInstallation } from './configurationHelper.js';
+import { Mod, ModInstall, ModsJson } from './modlist.types.js';
+
+export enum DuplicateReason {
+  NAME = 'name',
+  FILE = 'file'
+}
+
+export interface DuplicateMod {
+  mods: [Mod, Mod];
+  reason: DuplicateReason;
+}
+
+/**
+ * Short names like "Lib" or "API" are shared by too many different mods to mean anything
+ */
+const MIN_NAME_LENGTH = 4;
+
+const normalizedName = (mod: Mod) => mod.name.toLowerCase().replace(/[^a-z0-9]/g, '');
+
+const haveTheSameName = (mod: Mod, other: Mod) => {
+  const name = normalizedName(mod);
+  return name.length >= MIN_NAME_LENGTH && name === normalizedName(other);
+};
+
+const haveTheSameFile = (mod: Mod, other: Mod, installations: ModInstall[]) => {
+  const installation = installations[getInstallation(mod, installations)];
+  const otherInstallation = installations[getInstallation(other, installations)];
+  return !!installation && !!otherInstallation && installation.hash === otherInstallation.hash;
+};
+
+/**
+ * Finds the mods that were added from more than one platform, which would put two copies of the same mod in the
+ * mods folder. Only mods on different platforms are compared. They have to have the same name apart from the case and
+ * the punctuation, or the same file in the lock file, so genuinely different mods don't get reported.
+ *
+ * @param configuration
+ * @param installations The contents of the lock file, the names are all there is to go on without them
+ */
+export const findDuplicateMods = (configuration: ModsJson, installations: ModInstall[] = []): DuplicateMod[] => {
+  const duplicates: DuplicateMod[] = [];
+
+  configuration.mods.forEach((mod, index) => {
+    configuration.mods.slice(index + 1).forEach((other) => {
+      if (mod.type === other.type) {
+        return;
+      }
+
+      if (haveTheSameFile(mod, other, installations)) {
+        duplicates.push({ mods: [mod, other], reason: DuplicateReason.FILE });
+        return;
+      }
+
+      if (haveTheSameName(mod, other)) {
+        duplicates.push({ mods: [mod, other], reason: DuplicateReason.NAME });
+      }
+    });
+  });
+
+  return duplicates;
+};
@@ -0,0 +1,154 @@
+import * as crypto from 'crypto';
+import fs from 'node:fs/promises';
+import os from 'node:os';
+import path from 'node:path';
+import { chance } from 'jest-chance';
+import { afterEach, beforeEach, describe, expect, it } from 'vitest';
+import { fileCacheDirectory } from '../env.js';
+import {
+  clearFileCache,
+  getFileCacheDirectory,
+  isCached,
+  restoreFromCache,
+  setFileCacheDirectory,
+  setFileCacheSize,
+  storeInCache
+} from './fileCache.js';
+
+interface LocalTestContext {
+  directory: string;
+  file: string;
+  contents: string;
+  hash: string;
+}
+
+const sha1 = (contents: string) => crypto.createHash('sha1').update(contents).digest('hex');
+
+describe('The file cache', () => {
+  beforeEach<LocalTestContext>(async (context) => {
+    context.directory = await fs.mkdtemp(path.join(os.tmpdir(), 'mmm-cache-'));
+    context.file = path.resolve(context.directory, `${chance.word()}.jar`);
+    context.contents = chance.paragraph();
+    context.hash = sha1(context.contents);
+    await fs.writeFile(context.file, context.contents);
+    setFileCacheDirectory(path.resolve(context.directory, 'cache'));
+  });
+
+  afterEach<LocalTestContext>(async (context) => {
+    setFileCacheDirectory();
+    setFileCacheSize();
+    await fs.rm(context.directory, { recursive: true, force: true });
+  });
+
+  it('uses the configured directory by default', () => {
+    setFileCacheDirectory();
+
+    expect(getFileCacheDirectory()).toEqual(fileCacheDirectory);
+  });
+
+  it<LocalTestContext>('does not have files that were never stored', async (context) => {
+    expect(await isCached(context.hash)).toBeFalsy();
+    expect(await restoreFromCache(context.hash, path.resolve(context.directory, 'restored.jar'))).toBeFalsy();
+  });
+
+  it<LocalTestContext>('gives back the stored files', async (context) => {
+    const destination = path.resolve(context.directory, 'restored.jar');
+
+    await storeInCache(context.file, context.hash);
+
+    expect(await isCached(context.hash.toUpperCase())).toBeTruthy();
+    expect(await restoreFromCache(context.hash, destination)).toBeTruthy();
+    expect(await fs.readFile(destination, 'utf-8')).toEqual(context.contents);
+  });
+
+  it<LocalTestContext>('does not trust a damaged copy', async (context) => {
+    await storeInCache(context.file, context.hash);
+    await fs.writeFile(path.resolve(getFileCacheDirectory(), `${context.hash}.jar`), chance.paragraph());
+
+    expect(await isCached(context.hash)).toBeFalsy();
+  });
+
+  it<LocalTestContext>('ignores a cache that cannot be written', async (context) => {
+    setFileCacheDirectory(context.file);
+
+    await expect(storeInCache(context.file, context.hash)).resolves.toBeUndefined();
+    expect(await isCached(context.hash)).toBeFalsy();
+  });
+
+  describe('when the cache is full', () => {
+    const fileOf = async (context: LocalTestContext, contents: string) => {
+      const file = path.resolve(context.directory, `${sha1(contents)}.jar`);
+      await fs.writeFile(file, contents);
+      return { file: file, hash: sha1(contents) };
+    };
+
+    const makeOlder = async (hash: string, seconds: number) => {
+      const usedAt = new Date(Date.now() - seconds * 1000);
+      await fs.utimes(path.resolve(getFileCacheDirectory(), `${hash}.jar`), usedAt, usedAt);
+    };
+
+    it<LocalTestContext>('removes the files used the longest time ago', async (context) => {
+      const first = await fileOf(context, 'a'.repeat(40));
+      const second = await fileOf(context, 'b'.repeat(40));
+      const third = await fileOf(context, 'c'.repeat(40));
+      setFileCacheSize(100);
+
+      await storeInCache(first.file, first.hash);
+      await makeOlder(first.hash, 20);
+      await storeInCache(second.file, second.hash);
+      await makeOlder(second.hash, 10);
+      await storeInCache(third.file, third.hash);
+
+      expect(await isCached(first.hash)).toBeFalsy();
+      expect(await isCached(second.hash)).toBeTruthy();
+      expect(await isCached(third.hash)).toBeTruthy();
+    });
+
+    it<LocalTestContext>('keeps the files that were restored recently', async (context) => {
+      const first = await fileOf(context, 'a'.repeat(40));
+      const second = await fileOf(context, 'b'.repeat(40));
+      const third = await fileOf(context, 'c'.repeat(40));
+      setFileCacheSize(100);
+
+      await storeInCache(first.file, first.hash);
+      await makeOlder(first.hash, 20);
+      await storeInCache(second.file, second.hash);
+      await makeOlder(second.hash, 10);
+      await restoreFromCache(first.hash, path.resolve(context.directory, 'restored.jar'));
+      await storeInCache(third.file, third.hash);
+
+      expect(await isCached(first.hash)).toBeTruthy();
+      expect(await isCached(second.hash)).toBeFalsy();
+    });
+
+    it<LocalTestContext>('does not keep a file bigger than the whole cache', async (context) => {
+      setFileCacheSize(context.contents.length - 1);
+
+      await storeInCache(context.file, context.hash);
+
+      expect(await isCached(context.hash)).toBeFalsy();
+    });
+  });
+
+  describe('when clearing the cache', () => {
+    it<LocalTestContext>('removes the mod files and tells how much room they took', async (context) => {
+      await storeInCache(context.file, context.hash);
+
+      expect(await clearFileCache()).toEqual({ files: 1, bytes: context.contents.length });
+      expect(await isCached(context.hash)).toBeFalsy();
+    });
+
+    it<LocalTestContext>('leaves the other caches in the directory alone', async () => {
+      const responses = path.resolve(getFileCacheDirectory(), 'http');
+      await fs.mkdir(responses, { recursive: true });
+
+      await clearFileCache();
+
+      await expect(fs.access(responses)).resolves.toBeUndefined();
+    });
+
+    it('has nothing to remove from a cache that was never written', async () => {
+      expect(await clearFileCache()).toEqual({ files: 0, bytes: 0 });
+    });
+  });
+});
@@ -0,0 +1,127 @@
+import fs from 'node:fs/promises';
+import path from 'node:path';
+import { fileCacheDirectory } from '../env.js';
+import { getHash } from './hash.js';
+
+/**
+ * A gigabyte holds the mods of a few packs, the files used the longest time ago make room for the new ones
+ */
+export const DEFAULT_FILE_CACHE_SIZE = 1024 * 1024 * 1024;
+
+let cacheDirectory = fileCacheDirectory;
+let cacheSize = DEFAULT_FILE_CACHE_SIZE;
+
+/**
+ * Sets where the downloaded mod files are kept for later, like the offline mode.
+ * Calling it without a value goes back to the MMM_CACHE_DIR environment variable or ~/.cache/mmm
+ */
+export const setFileCacheDirectory = (directory?: string) => {
+  cacheDirectory = directory || fileCacheDirectory;
+};
+
+export const getFileCacheDirectory = () => cacheDirectory;
+
+/**
+ * Sets how many bytes the cached files may take up together.
+ * Calling it without a value restores the default.
+ */
+export const setFileCacheSize = (bytes?: number) => {
+  cacheSize = bytes ?? DEFAULT_FILE_CACHE_SIZE;
+};
+
+// The files are stored by their sha1 hash, which is what the lock file knows about them
+const cachedFileFor = (hash: string) => path.resolve(cacheDirectory, `${hash.toLowerCase()}.jar`);
+
+/**
+ * Whether the cache has an intact copy of the file with the given sha1 hash
+ */
+export const isCached = async (hash: string): Promise<boolean> => {
+  try {
+    return (await getHash(cachedFileFor(hash))) === hash.toLowerCase();
+  } catch {
+    return false;
+  }
+};
+
+interface CachedFile {
+  file: string;
+  size: number;
+  usedAt: number;
+}
+
+/**
+ * Only the mod files, the cache directory can hold other caches too, like the API responses
+ */
+const cachedFiles = async (): Promise<CachedFile[]> => {
+  const entries = await fs.readdir(cacheDirectory).catch(() => [] as string[]);
+  const files = await Promise.all(
+    entries
+      .filter((entry) => entry.endsWith('.jar'))
+      .map(async (entry) => {
+        const file = path.resolve(cacheDirectory, entry);
+        const stats = await fs.stat(file).catch(() => undefined);
+        return stats ? { file: file, size: stats.size, usedAt: stats.mtimeMs } : undefined;
+      })
+  );
+  return files.filter((file): file is CachedFile => file !== undefined);
+};
+
+/**
+ * Removes the files used the longest time ago until the cache fits in its size again
+ */
+const evict = async () => {
+  const files = (await cachedFiles()).sort((a, b) => a.usedAt - b.usedAt);
+  let size = files.reduce((total, file) => total + file.size, 0);
+  for (const file of files) {
+    if (size <= cacheSize) {
+      return;
+    }
+    await fs.rm(file.file, { force: true });
+    size -= file.size;
+  }
+};
+
+/**
+ * Keeps a copy of a downloaded file in the cache.
+ * A file bigger than the whole cache isn't kept, the others push out the files used the longest time ago.
+ */
+export const storeInCache = async (file: string, hash: string) => {
+  try {
+    if ((await fs.stat(file)).size > cacheSize) {
+      return;
+    }
+    await fs.mkdir(cacheDirectory, { recursive: true });
+    await fs.copyFile(file, cachedFileFor(hash));
+    await evict();
+  } catch {
+    // A cache that can't be written is just a cache miss next time
+  }
+};
+
+/**
+ * Removes every mod file from the cache
+ *
+ * @returns How many files were removed and how many bytes they took up
+ */
+export const clearFileCache = async (): Promise<{ files: number; bytes: number }> => {
+  const files = await cachedFiles();
+  await Promise.all(files.map((file) => fs.rm(file.file, { force: true })));
+  return { files: files.length, bytes: files.reduce((total, file) => total + file.size, 0) };
+};
+
+/**
+ * Copies the file with the given sha1 hash from the cache to the destination.
+ *
+ * @returns Whether the cache had the file
+ */
+export const restoreFromCache = async (hash: string, destination: string): Promise<boolean> => {
+  if (!(await isCached(hash))) {
+    return false;
+  }
+
+  await fs.copyFile(cachedFileFor(hash), destination);
+  // The time of the last use decides which files make room first
+  const now = new Date();
+  await fs.utimes(cachedFileFor(hash), now, now).catch(() => undefined);
+  return true;
+};
@@ -0,0 +1,133 @@
+import { describe, expect, it } from 'vitest';
+import { generateRemoteModDetails } from '../../test/generateRemoteDetails.js';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import {
+  downloadFileName,
+  expandFileNameTemplate,
+  isValidFileNameTemplate,
+  sanitizeFileName,
+  slugify,
+  uniqueFileName
+} from './fileNameTemplate.js';
+import { Loader, Platform } from './modlist.types.js';
+
+const context = (fileNameTemplate?: string, taken: string[] = [], currentFileName?: string) => ({
+  mod: generateModConfig({ id: 'AANobbMI', type: Platform.MODRINTH }).generated,
+  configuration: generateModsJson({
+    gameVersion: '1.20.1',
+    loader: Loader.FABRIC,
+    fileNameTemplate: fileNameTemplate
+  }).generated,
+  taken: new Set(taken),
+  currentFileName: currentFileName
+});
+
+const details = generateRemoteModDetails({
+  name: 'Sodium Extra',
+  fileName: 'sodium-extra-0.5.1.jar',
+  version: '0.5.1'
+}).generated;
+
+describe('The file name template', () => {
+  it.each([
+    ['Sodium', 'sodium'],
+    ['Sodium Extra!', 'sodium-extra'],
+    ['  (Fabric) API  ', 'fabric-api'],
+    ["Xaero's Minimap", 'xaero-s-minimap']
+  ])('makes %j into the slug %j', (name, expected) => {
+    expect(slugify(name)).toEqual(expected);
+  });
+
+  it.each([
+    ['sodium/extra.jar', 'sodium-extra.jar'],
+    ['sodium\\extra.jar', 'sodium-extra.jar'],
+    ['sodium:1.20.1.jar', 'sodium-1.20.1.jar'],
+    ['what?*"<>|.jar', 'what------.jar'],
+    ['tab\there.jar', 'tab-here.jar'],
+    [' ..sodium.jar. ', 'sodium.jar']
+  ])('sanitizes %j into %j', (fileName, expected) => {
+    expect(sanitizeFileName(fileName)).toEqual(expected);
+  });
+
+  it('fills in the placeholders', () => {
+    expect(expandFileNameTemplate('{slug}-{gameVersion}.jar', { slug: 'sodium', gameVersion: '1.20.1' })).toEqual(
+      'sodium-1.20.1.jar'
+    );
+  });
+
+  it('keeps the placeholders it does not know', () => {
+    expect(expandFileNameTemplate('{slug}-{unknown}.jar', { slug: 'sodium' })).toEqual('sodium-{unknown}.jar');
+  });
+
+  it('sanitizes the values it fills in', () => {
+    expect(expandFileNameTemplate('{name}.jar', { name: 'Sodium: Extra/Plus' })).toEqual('Sodium- Extra-Plus.jar');
+  });
+
+  it.each([
+    ['{slug}.jar', true],
+    ['/', true],
+    ['', false],
+    [' .. ', false]
+  ])('finds %j valid: %j', (template, expected) => {
+    expect(isValidFileNameTemplate(template)).toBe(expected);
+  });
+
+  it.each([
+    ['sodium.jar', [], 'sodium.jar'],
+    ['sodium.jar', ['sodium.jar'], 'sodium-2.jar'],
+    ['sodium.jar', ['sodium.jar', 'sodium-2.jar'], 'sodium-3.jar'],
+    ['sodium', ['sodium'], 'sodium-2']
+  ])('makes %j unique among %j as %j', (fileName, taken, expected) => {
+    expect(uniqueFileName(fileName, new Set(taken))).toEqual(expected);
+  });
+
+  describe('when naming a download', () => {
+    it('keeps the name of the file without a template', () => {
+      expect(downloadFileName(details, context())).toEqual('sodium-extra-0.5.1.jar');
+    });
+
+    it('names it after the template', () => {
+      const actual = downloadFileName(
+        details,
+        context('{platform}-{id}-{slug}-{name}-{loader}-{gameVersion}-{version}-{fileName}')
+      );
+
+      expect(actual).toEqual(
+        'modrinth-AANobbMI-sodium-extra-Sodium Extra-fabric-1.20.1-0.5.1-sodium-extra-0.5.1.jar'
+      );
+    });
+
+    it('takes the version from the file name when the platform does not tell it', () => {
+      const withoutVersion = { ...details, version: undefined };
+
+      expect(downloadFileName(withoutVersion, context('{slug}-{version}.jar'))).toEqual(
+        'sodium-extra-sodium-extra-0.5.1.jar'
+      );
+    });
+
+    it('keeps the name of the file when the template comes out empty', () => {
+      const unnamed = { ...details, name: '\u94a0' };
+
+      expect(downloadFileName(unnamed, context('{slug}'))).toEqual('sodium-extra-0.5.1.jar');
+    });
+
+    it('does not take the name of another file', () => {
+      expect(downloadFileName(details, context('{slug}.jar', ['sodium-extra.jar']))).toEqual('sodium-extra-2.jar');
+    });
+
+    it('keeps the two files of the same run apart', () => {
+      const shared = context('{loader}.jar');
+
+      expect(downloadFileName(details, shared)).toEqual('fabric.jar');
+      expect(downloadFileName(details, shared)).toEqual('fabric-2.jar');
+      expect(shared.taken).toEqual(new Set(['fabric.jar', 'fabric-2.jar']));
+    });
+
+    it('can keep the name of the file it replaces', () => {
+      const replacing = context('{slug}.jar', ['sodium-extra.jar'], 'sodium-extra.jar');
+
+      expect(downloadFileName(details, replacing)).toEqual('sodium-extra.jar');
+    });
+  });
+});
@@ -0,0 +1,107 @@
+import path from 'node:path';
+import { Mod, ModsJson, RemoteModDetails } from './modlist.types.js';
+
+/**
+ * The characters Windows, macOS or Linux don't allow in a file name, apart from the control characters
+ */
+const unsafeCharacters = '\\/:*?"<>|';
+
+const placeholderPattern = /\{(\w+)\}/g;
+
+export interface FileNameContext {
+  mod: Mod;
+  configuration: ModsJson;
+  /**
+   * The file names already in use in the mods folder, the new name is added to them
+   */
+  taken: Set<string>;
+  /**
+   * The file the mod has now, it can keep its name as it gets replaced
+   */
+  currentFileName?: string;
+}
+
+/**
+ * Sodium Extra! becomes sodium-extra
+ */
+export const slugify = (name: string): string => {
+  return name
+    .toLowerCase()
+    .replace(/[^a-z0-9]+/g, '-')
+    .replace(/^-+|-+$/g, '');
+};
+
+/**
+ * Replaces the characters a file name can't have with dashes.
+ * The spaces and the dots at the ends are dropped too, Windows doesn't keep them.
+ */
+export const sanitizeFileName = (fileName: string): string => {
+  return [...fileName]
+    .map((character) => (character.charCodeAt(0) < 32 || unsafeCharacters.includes(character) ? '-' : character))
+    .join('')
+    .replace(/^[\s.]+|[\s.]+$/g, '');
+};
+
+/**
+ * A template has to leave something of the file name once the characters it can't have are gone
+ */
+export const isValidFileNameTemplate = (template: string): boolean => {
+  return sanitizeFileName(template) !== '';
+};
+
+/**
+ * Fills in the placeholders of the template, like {slug}-{gameVersion}.jar.
+ * A placeholder without a value is left as it is.
+ */
+export const expandFileNameTemplate = (template: string, values: Record<string, string>): string => {
+  return sanitizeFileName(
+    template.replace(placeholderPattern, (placeholder, key: string) => (key in values ? values[key] : placeholder))
+  );
+};
+
+/**
+ * Numbers the file when its name is taken already, sodium.jar becomes sodium-2.jar
+ */
+export const uniqueFileName = (fileName: string, taken: Set<string>): string => {
+  const extension = path.extname(fileName);
+  const base = fileName.slice(0, fileName.length - extension.length);
+  let candidate = fileName;
+  for (let number = 2; taken.has(candidate); number++) {
+    candidate = `${base}-${number}${extension}`;
+  }
+  return candidate;
+};
+
+/**
+ * The name the downloaded file gets in the mods folder.
+ * Without a fileNameTemplate in the configuration, or when the template comes out empty for the mod,
+ * it's the name the platform gives the file.
+ * The {slug} is made from the name of the mod, so it can differ from the slug of the project page.
+ */
+export const downloadFileName = (details: RemoteModDetails, context: FileNameContext): string => {
+  const template = context.configuration.fileNameTemplate;
+  if (!template) {
+    return details.fileName;
+  }
+
+  const expanded = expandFileNameTemplate(template, {
+    name: details.name,
+    slug: slugify(details.name),
+    version: details.version ?? path.basename(details.fileName, path.extname(details.fileName)),
+    id: context.mod.id,
+    platform: context.mod.type,
+    gameVersion: context.configuration.gameVersion,
+    loader: context.configuration.loader,
+    fileName: details.fileName
+  });
+  const fileName = expanded || details.fileName;
+
+  const taken = new Set(context.taken);
+  if (context.currentFileName) {
+    taken.delete(context.currentFileName);
+  }
+
+  const unique = uniqueFileName(fileName, taken);
+  context.taken.add(unique);
+  return unique;
+};
@@ -0,0 +1,56 @@
+import { describe, expect, it } from 'vitest';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { fileOverridesOf, isExcludedFileName, isValidFileNamePattern } from './fileOverrides.js';
+
+describe('The file overrides', () => {
+  describe('of a mod', () => {
+    it('are undefined when the mod has none', () => {
+      const mod = generateModConfig().generated;
+      delete mod.excludeFileNamePattern;
+      delete mod.forceFileId;
+      delete mod.installAdditionalFiles;
+
+      expect(fileOverridesOf(mod)).toBeUndefined();
+    });
+
+    it('are picked from the configuration of the mod', () => {
+      const mod = generateModConfig({ excludeFileNamePattern: '-forge', forceFileId: '4567890' }).generated;
+
+      expect(fileOverridesOf(mod)).toEqual({ excludeFileNamePattern: '-forge', forceFileId: '4567890' });
+    });
+
+    it('include the additional files when they are asked for', () => {
+      const mod = generateModConfig({ installAdditionalFiles: true }).generated;
+
+      expect(fileOverridesOf(mod)).toEqual({ installAdditionalFiles: true });
+    });
+  });
+
+  describe('when validating the file name pattern', () => {
+    it('accepts a regular expression', () => {
+      expect(isValidFileNamePattern('-(forge|neoforge)\\.jar$')).toBe(true);
+    });
+
+    it('rejects a broken regular expression', () => {
+      expect(isValidFileNamePattern('(')).toBe(false);
+    });
+  });
+
+  describe('when excluding a file', () => {
+    it('keeps every file without overrides', () => {
+      expect(isExcludedFileName('sodium-forge.jar')).toBe(false);
+    });
+
+    it('keeps every file without a pattern', () => {
+      expect(isExcludedFileName('sodium-forge.jar', { forceFileId: '4567890' })).toBe(false);
+    });
+
+    it('excludes the matching file ignoring the case', () => {
+      expect(isExcludedFileName('Sodium-FORGE-0.5.3.jar', { excludeFileNamePattern: '-forge' })).toBe(true);
+    });
+
+    it('keeps the file that does not match', () => {
+      expect(isExcludedFileName('sodium-fabric-0.5.3.jar', { excludeFileNamePattern: '-forge' })).toBe(false);
+    });
+  });
+});
@@ -0,0 +1,38 @@
+import { FileOverrides } from './modlist.types.js';
+
+/**
+ * The overrides of the mod without the rest of its configuration, undefined when it has none
+ */
+export const fileOverridesOf = (mod: FileOverrides): FileOverrides | undefined => {
+  if (
+    mod.excludeFileNamePattern === undefined &&
+    mod.forceFileId === undefined &&
+    mod.installAdditionalFiles === undefined
+  ) {
+    return undefined;
+  }
+  return {
+    excludeFileNamePattern: mod.excludeFileNamePattern,
+    forceFileId: mod.forceFileId,
+    installAdditionalFiles: mod.installAdditionalFiles
+  };
+};
+
+export const isValidFileNamePattern = (pattern: string) => {
+  try {
+    new RegExp(pattern, 'i');
+    return true;
+  } catch {
+    return false;
+  }
+};
+
+/**
+ * Whether the file is excluded by the overrides, the pattern is matched anywhere in the name ignoring the case
+ */
+export const isExcludedFileName = (fileName: string, overrides: FileOverrides = {}) => {
+  if (!overrides.excludeFileNamePattern) {
+    return false;
+  }
+  return new RegExp(overrides.excludeFileNamePattern, 'i').test(fileName);
+};
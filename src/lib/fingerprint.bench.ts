@@ -0,0 +1,26 @@
+import { randomBytes } from 'node:crypto';
+import { mkdtempSync, writeFileSync } from 'node:fs';
+import os from 'node:os';
+import path from 'node:path';
+import { bench, describe } from 'vitest';
+import { getFingerprints } from './fingerprint.js';
+
+const JAR_COUNT = 200;
+const JAR_SIZE = 512 * 1024;
+
+const folder = mkdtempSync(path.join(os.tmpdir(), 'mmm-fingerprint-bench-'));
+const jars = Array.from({ length: JAR_COUNT }, (_, index) => {
+  const jar = path.join(folder, `mod-${index}.jar`);
+  writeFileSync(jar, randomBytes(JAR_SIZE));
+  return jar;
+});
+
+describe(`Fingerprinting ${JAR_COUNT} jars`, () => {
+  bench('one at a time', async () => {
+    await getFingerprints(jars, 1);
+  });
+
+  bench('as many at a time as there are CPUs', async () => {
+    await getFingerprints(jars);
+  });
+});
@@ -0,0 +1,116 @@
+import fs from 'node:fs/promises';
+import os from 'node:os';
+import { chance } from 'jest-chance';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { fileExists } from './config.js';
+import { getBufferFingerprint, getFingerprint, getFingerprints } from './fingerprint.js';
+
+vi.mock('./config.js');
+vi.mock('node:fs/promises');
+
+describe('The fingerprint module', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+  });
+
+  it.each([
+    ['', 1540447798],
+    ['a', 626045324],
+    ['hello world', 2824650221],
+    ['The quick brown fox\njumps over\tthe lazy dog\r\n', 3751777527]
+  ])('calculates the fingerprint of "%s"', (contents, expected) => {
+    expect(getBufferFingerprint(Buffer.from(contents))).toEqual(expected);
+  });
+
+  it('calculates the fingerprint of all the byte values', () => {
+    const contents = Uint8Array.from({ length: 256 }, (_, index) => index);
+    expect(getBufferFingerprint(contents)).toEqual(2094645347);
+  });
+
+  it('ignores the whitespace characters', () => {
+    expect(getBufferFingerprint(Buffer.from(' hello\tworld\r\n'))).toEqual(
+      getBufferFingerprint(Buffer.from('helloworld'))
+    );
+  });
+
+  it('throws an error if the file does not exist', async () => {
+    vi.mocked(fileExists).mockResolvedValueOnce(false);
+    const randomFile = chance.word();
+    await expect(async () => {
+      await getFingerprint(randomFile);
+    }).rejects.toThrow(new Error(`File (${randomFile}) does not exist, can't determine the fingerprint`));
+  });
+
+  it('returns the fingerprint of the file', async () => {
+    const randomFile = chance.word();
+    vi.mocked(fileExists).mockResolvedValueOnce(true);
+    vi.mocked(fs.readFile).mockResolvedValueOnce(Buffer.from('hello world'));
+
+    await expect(getFingerprint(randomFile)).resolves.toEqual(2824650221);
+    expect(fs.readFile).toHaveBeenCalledWith(randomFile);
+  });
+
+  describe('when fingerprinting many files', () => {
+    const readFilesSlowly = (delays: Record<string, number>) => {
+      let running = 0;
+      let mostRunning = 0;
+      vi.mocked(fileExists).mockResolvedValue(true);
+      vi.mocked(fs.readFile).mockImplementation(async (file) => {
+        running++;
+        mostRunning = Math.max(mostRunning, running);
+        await new Promise((resolve) => setTimeout(resolve, delays[file as string] || 0));
+        running--;
+        return Buffer.from(file as string);
+      });
+      return () => mostRunning;
+    };
+
+    it('keeps the order of the files whichever finishes first', async () => {
+      readFilesSlowly({ 'a.jar': 30, 'b.jar': 10, 'c.jar': 0 });
+
+      const actual = await getFingerprints(['a.jar', 'b.jar', 'c.jar'], 3);
+
+      expect(actual).toEqual({
+        fingerprints: [
+          { file: 'a.jar', fingerprint: getBufferFingerprint(Buffer.from('a.jar')) },
+          { file: 'b.jar', fingerprint: getBufferFingerprint(Buffer.from('b.jar')) },
+          { file: 'c.jar', fingerprint: getBufferFingerprint(Buffer.from('c.jar')) }
+        ],
+        errors: []
+      });
+    });
+
+    it('reports the files that cannot be fingerprinted', async () => {
+      readFilesSlowly({});
+      vi.mocked(fileExists).mockImplementation(async (file) => file !== 'missing.jar');
+
+      const actual = await getFingerprints(['a.jar', 'missing.jar', 'c.jar'], 2);
+
+      expect(actual.fingerprints.map((fingerprint) => fingerprint.file)).toEqual(['a.jar', 'c.jar']);
+      expect(actual.errors).toEqual([
+        {
+          file: 'missing.jar',
+          error: new Error("File (missing.jar) does not exist, can't determine the fingerprint")
+        }
+      ]);
+    });
+
+    it('does not read more files at the same time than it was told to', async () => {
+      const mostRunning = readFilesSlowly({ 'a.jar': 5, 'b.jar': 5, 'c.jar': 5, 'd.jar': 5 });
+
+      const actual = await getFingerprints(['a.jar', 'b.jar', 'c.jar', 'd.jar'], 2);
+
+      expect(actual.fingerprints).toHaveLength(4);
+      expect(mostRunning()).toEqual(2);
+    });
+
+    it('reads as many files at the same time as there are CPUs by default', async () => {
+      vi.spyOn(os, 'cpus').mockReturnValueOnce(new Array(3).fill({}));
+      const mostRunning = readFilesSlowly({ 'a.jar': 5, 'b.jar': 5, 'c.jar': 5, 'd.jar': 5, 'e.jar': 5 });
+
+      await getFingerprints(['a.jar', 'b.jar', 'c.jar', 'd.jar', 'e.jar']);
+
+      expect(mostRunning()).toEqual(3);
+    });
+  });
+});
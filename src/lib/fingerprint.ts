@@ -0,0 +1,102 @@
+import fs from 'node:fs/promises';
+import os from 'node:os';
+import { fileExists } from './config.js';
+import { mapWithConcurrency } from './workerPool.js';
+
+const MURMUR_MULTIPLIER = 0x5bd1e995;
+const MURMUR_SEED = 1;
+const WHITESPACE = new Set([9, 10, 13, 32]);
+
+const murmur2 = (data: Uint8Array, seed: number): number => {
+  let hash = (seed ^ data.length) >>> 0;
+  let index = 0;
+
+  while (data.length - index >= 4) {
+    let k = data[index] | (data[index + 1] << 8) | (data[index + 2] << 16) | (data[index + 3] << 24);
+    k = Math.imul(k, MURMUR_MULTIPLIER);
+    k ^= k >>> 24;
+    k = Math.imul(k, MURMUR_MULTIPLIER);
+
+    hash = Math.imul(hash, MURMUR_MULTIPLIER) ^ k;
+    index += 4;
+  }
+
+  switch (data.length - index) {
+    case 3:
+      hash ^= data[index + 2] << 16;
+    // falls through
+    case 2:
+      hash ^= data[index + 1] << 8;
+    // falls through
+    case 1:
+      hash ^= data[index];
+      hash = Math.imul(hash, MURMUR_MULTIPLIER);
+  }
+
+  hash ^= hash >>> 13;
+  hash = Math.imul(hash, MURMUR_MULTIPLIER);
+  hash ^= hash >>> 15;
+
+  return hash >>> 0;
+};
+
+/**
+ * Calculates the Curseforge fingerprint of the given bytes.
+ * Curseforge ignores the whitespace bytes and uses murmur2 with a seed of 1 on the rest.
+ */
+export const getBufferFingerprint = (contents: Uint8Array): number => {
+  const normalized = contents.filter((byte) => !WHITESPACE.has(byte));
+  return murmur2(normalized, MURMUR_SEED);
+};
+
+export const getFingerprint = async (file: string): Promise<number> => {
+  if (!(await fileExists(file))) {
+    throw new Error(`File (${file}) does not exist, can't determine the fingerprint`);
+  }
+
+  const contents = await fs.readFile(file);
+  return getBufferFingerprint(contents);
+};
+
+export interface FileFingerprint {
+  file: string;
+  fingerprint: number;
+}
+
+export interface FingerprintError {
+  file: string;
+  error: unknown;
+}
+
+export interface FingerprintScan {
+  /**
+   * In the order of the files, whichever finished first
+   */
+  fingerprints: FileFingerprint[];
+  errors: FingerprintError[];
+}
+
+/**
+ * Fingerprints many files at once, as many at the same time as there are CPUs unless told otherwise.
+ * A file that can't be fingerprinted is reported in the errors and doesn't stop the others.
+ *
+ * @param files
+ * @param concurrency The maximum number of files read and fingerprinted at the same time
+ */
+export const getFingerprints = async (
+  files: string[],
+  concurrency: number = os.cpus().length
+): Promise<FingerprintScan> => {
+  const settled = await mapWithConcurrency(files, getFingerprint, concurrency);
+  const scan: FingerprintScan = { fingerprints: [], errors: [] };
+
+  settled.forEach((outcome, index) => {
+    if (outcome.status === 'rejected') {
+      scan.errors.push({ file: files[index], error: outcome.reason });
+      return;
+    }
+    scan.fingerprints.push({ file: files[index], fingerprint: outcome.value });
+  });
+
+  return scan;
+};
@@ -0,0 +1,221 @@
+import { afterEach, describe, expect, it } from 'vitest';
+import {
+  allowsSnapshotGameVersions,
+  compareGameVersions,
+  gameVersionMatches,
+  gameVersionsToRequest,
+  isSnapshot,
+  isStrictGameVersionMatching,
+  latestGameVersion,
+  normalizeGameVersion,
+  setSnapshotGameVersions,
+  setStrictGameVersionMatching
+} from './gameVersionMatcher.js';
+
+describe('The game version matcher', () => {
+  afterEach(() => {
+    setStrictGameVersionMatching();
+    setSnapshotGameVersions();
+  });
+
+  it('is not strict by default', () => {
+    expect(isStrictGameVersionMatching()).toBeFalsy();
+  });
+
+  it.each([
+    ['1.20.1', ['1.20.1', 'Fabric']],
+    ['1.20.1', ['Forge', 'Client', '1.20.1']],
+    ['1.20.1', ['1.20']],
+    ['1.20.4', ['1.20']],
+    ['1.20.1', ['1.20.x']],
+    ['1.20.1', ['1.20.*']],
+    ['1.20.1', ['>=1.20 <1.21']],
+    ['1.20.1', ['~1.20']],
+    ['1.20.1', ['1.20-1.20.4']],
+    ['1.20.1', ['=1.20.1']],
+    ['1.20', ['1.20']],
+    ['1.20.2-rc1', ['1.20.2-RC1']],
+    ['1.20.0', ['1.20']],
+    ['1.20', ['1.20.0']],
+    ['1.20-pre1', ['1.20 Pre-Release 1']]
+  ])('accepts %s for a file tagged %j', (requested, tags) => {
+    expect(gameVersionMatches(requested, tags)).toBeTruthy();
+  });
+
+  it.each([
+    ['1.20.1', ['Fabric']],
+    ['1.20.1', ['1.20.2', 'Fabric']],
+    ['1.20.1', ['1.19']],
+    ['1.20', ['1.20.1']],
+    ['1.20.1', ['1.21.x']],
+    ['1.20.1', ['>=1.20.2']],
+    ['1.20.1', ['<1.20.1']],
+    ['1.20.1', ['>1.20.1']],
+    ['1.20.1', ['<=1.20']],
+    ['1.20.1', ['~1.19']],
+    ['1.20.1', ['1.20.2-1.20.4']],
+    ['1.20.1', ['1.20-pre1']],
+    ['1.20.1', ['23w31a']],
+    ['1.20.1', ['']],
+    ['1.20.1', []],
+    ['23w31a', ['1.20']],
+    ['1.20-pre1', ['1.20']]
+  ])('rejects %s for a file tagged %j', (requested, tags) => {
+    expect(gameVersionMatches(requested, tags)).toBeFalsy();
+  });
+
+  it('ignores the case of the tags', () => {
+    expect(gameVersionMatches('1.20.1', ['1.20.X'])).toBeTruthy();
+  });
+
+  it('only accepts the exact version when strict', () => {
+    setStrictGameVersionMatching(true);
+
+    expect(isStrictGameVersionMatching()).toBeTruthy();
+    expect(gameVersionMatches('1.20.1', ['1.20.1', 'Fabric'])).toBeTruthy();
+    expect(gameVersionMatches('1.20.1', ['1.20'])).toBeFalsy();
+    expect(gameVersionMatches('1.20.1', ['1.20.x'])).toBeFalsy();
+    expect(gameVersionMatches('1.20.0', ['1.20'])).toBeTruthy();
+  });
+
+  describe('when it comes to snapshots', () => {
+    const tags = ['1.20.4', '24w09a', 'Fabric'];
+
+    it('keeps them out of the packs on a release by default', () => {
+      expect(allowsSnapshotGameVersions()).toBeFalsy();
+      expect(gameVersionMatches('1.20.4', tags)).toBeTruthy();
+      expect(gameVersionMatches('1.20.5', ['24w09a'])).toBeFalsy();
+    });
+
+    it('still matches the snapshot a pack is on when they are not allowed', () => {
+      expect(gameVersionMatches('24w09a', tags)).toBeTruthy();
+      expect(gameVersionMatches('23w31a', ['23w31a'])).toBeTruthy();
+      expect(gameVersionMatches('24w10a', tags)).toBeFalsy();
+    });
+
+    it('only matches a snapshot with itself when they are allowed', () => {
+      setSnapshotGameVersions(true);
+
+      expect(allowsSnapshotGameVersions()).toBeTruthy();
+      expect(gameVersionMatches('24w09a', tags)).toBeTruthy();
+      expect(gameVersionMatches('24W09A', tags)).toBeTruthy();
+      expect(gameVersionMatches('24w10a', tags)).toBeFalsy();
+      expect(gameVersionMatches('1.20.4', tags)).toBeTruthy();
+      expect(gameVersionMatches('1.20.5', ['24w09a'])).toBeFalsy();
+    });
+
+    it.each([
+      ['24w09a', true],
+      [' 24W09A ', true],
+      ['1.20.4', false],
+      ['1.20.5-pre1', false],
+      ['24w09', false]
+    ])('knows whether %j is a snapshot', (version, expected) => {
+      expect(isSnapshot(version)).toEqual(expected);
+    });
+  });
+
+  describe('when ordering the game versions', () => {
+    it('puts them from the oldest to the newest', () => {
+      const versions = ['24w09a', '1.20.4', 'Fabric', '1.20.5-rc1', '1.9', '23w51b', '1.20.5-pre2', '1.20.5', '24w09b'];
+
+      expect(versions.sort(compareGameVersions)).toEqual([
+        'Fabric',
+        '1.9',
+        '1.20.4',
+        '1.20.5-pre2',
+        '1.20.5-rc1',
+        '1.20.5',
+        '23w51b',
+        '24w09a',
+        '24w09b'
+      ]);
+    });
+
+    it('treats the spellings of the same version as equal', () => {
+      expect(compareGameVersions('1.20', '1.20.0')).toEqual(0);
+      expect(compareGameVersions('1.20 Pre-Release 1', '1.20-pre1')).toEqual(0);
+    });
+
+    it('finds the newest release while the snapshots are ignored', () => {
+      expect(latestGameVersion(['1.20.4', '24w09a', '1.20.1', 'Fabric', '1.20.x'])).toEqual('1.20.4');
+    });
+
+    it('finds the newest snapshot when they are allowed', () => {
+      setSnapshotGameVersions(true);
+
+      expect(latestGameVersion(['1.20.4', '24w09a', '1.20.1', 'Fabric', '1.20.x'])).toEqual('24w09a');
+    });
+
+    it('has no newest version when there are no Minecraft versions', () => {
+      expect(latestGameVersion(['Fabric', '1.20.x'])).toBeUndefined();
+      expect(latestGameVersion(['24w09a'])).toBeUndefined();
+    });
+  });
+
+  describe('when normalizing a game version', () => {
+    it.each([
+      ['1.20', '1.20'],
+      ['1.20.0', '1.20'],
+      ['1.20.1', '1.20.1'],
+      [' 1.20.1 ', '1.20.1'],
+      ['1.7.10', '1.7.10'],
+      ['1.020.01', '1.20.1'],
+      ['1.20.x', '1.20.x'],
+      ['1.20.X', '1.20.x'],
+      ['1.20.*', '1.20.x'],
+      ['23w31a', '23w31a'],
+      ['23W31A', '23w31a'],
+      ['1.20-pre1', '1.20-pre1'],
+      ['1.20.0-pre1', '1.20-pre1'],
+      ['1.20.2-Pre02', '1.20.2-pre2'],
+      ['1.20 Pre-Release 1', '1.20-pre1'],
+      ['1.20.2-rc1', '1.20.2-rc1'],
+      ['1.20.2-RC1', '1.20.2-rc1'],
+      ['1.20.2 Release Candidate 1', '1.20.2-rc1']
+    ])('turns %j into %j', (version, expected) => {
+      expect(normalizeGameVersion(version)).toEqual(expected);
+    });
+
+    it.each([
+      '',
+      ' ',
+      'Fabric',
+      'Client',
+      '1',
+      '1.',
+      '1.20.',
+      '1.20.1.2',
+      '1.20.y',
+      'v1.20',
+      '23w31',
+      '23w310a',
+      '1.20-pre',
+      '1.20-beta1',
+      '>=1.20',
+      '1.20-1.20.4'
+    ])('refuses %j', (version) => {
+      expect(normalizeGameVersion(version)).toBeNull();
+    });
+  });
+
+  describe('when working out the versions to ask for', () => {
+    it('asks for the minor version as well', () => {
+      expect(gameVersionsToRequest('1.20.1')).toEqual(['1.20.1', '1.20']);
+    });
+
+    it('only asks for the version when it has no patch version', () => {
+      expect(gameVersionsToRequest('1.20')).toEqual(['1.20']);
+    });
+
+    it('only asks for snapshots as they are', () => {
+      expect(gameVersionsToRequest('23w31a')).toEqual(['23w31a']);
+    });
+
+    it('only asks for the version when strict', () => {
+      setStrictGameVersionMatching(true);
+
+      expect(gameVersionsToRequest('1.20.1')).toEqual(['1.20.1']);
+    });
+  });
+});
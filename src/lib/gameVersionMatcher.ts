@@ -0,0 +1,247 @@
+type ReleaseVersion = [number, number, number];
+
+/**
+ * The year, the week and the letter of a snapshot, 24w09a is [24, 9, 'a']
+ */
+type SnapshotVersion = [number, number, string];
+
+const releasePattern = /^(\d+)\.(\d+)(?:\.(\d+))?$/;
+const wildcardPattern = /^(\d+)\.(\d+)\.[x*]$/;
+const comparatorPattern = /^(>=|<=|>|<|=|~)?(\d+\.\d+(?:\.\d+)?)$/;
+const hyphenRangePattern = /^(\d+\.\d+(?:\.\d+)?)\s*-\s*(\d+\.\d+(?:\.\d+)?)$/;
+const snapshotPattern = /^(\d{2})w(\d{2})([a-z])$/;
+const preReleasePattern = /^(\d+\.\d+(?:\.\d+)?)(?:-pre|\s+pre-release\s+)(\d+)$/;
+const releaseCandidatePattern = /^(\d+\.\d+(?:\.\d+)?)(?:-rc|\s+release candidate\s+)(\d+)$/;
+const stagedReleasePattern = /^(.+)-(pre|rc)(\d+)$/;
+
+let strictGameVersionMatching = false;
+
+/**
+ * When strict, only the files tagged with the exact game version are accepted.
+ * Calling it without a value restores the default, which accepts the files made for the whole minor version too.
+ */
+export const setStrictGameVersionMatching = (strict?: boolean) => {
+  strictGameVersionMatching = !!strict;
+};
+
+export const isStrictGameVersionMatching = () => strictGameVersionMatching;
+
+let snapshotGameVersions = false;
+
+/**
+ * Snapshots, like 24w09a, are left out of the matching unless they are allowed.
+ * Calling it without a value restores the default, which ignores them.
+ */
+export const setSnapshotGameVersions = (allow?: boolean) => {
+  snapshotGameVersions = !!allow;
+};
+
+export const allowsSnapshotGameVersions = () => snapshotGameVersions;
+
+export const isSnapshot = (version: string): boolean => snapshotPattern.test(version.trim().toLowerCase());
+
+/**
+ * Snapshots, pre-releases and anything that isn't a Minecraft release gives null
+ */
+const parseRelease = (version: string): ReleaseVersion | null => {
+  const match = version.match(releasePattern);
+  if (!match) {
+    return null;
+  }
+  return [parseInt(match[1], 10), parseInt(match[2], 10), parseInt(match[3] || '0', 10)];
+};
+
+const parseSnapshot = (version: string): SnapshotVersion | null => {
+  const match = version.match(snapshotPattern);
+  if (!match) {
+    return null;
+  }
+  return [parseInt(match[1], 10), parseInt(match[2], 10), match[3]];
+};
+
+const canonicalRelease = (version: string): string => {
+  const [major, minor, patch] = parseRelease(version) as ReleaseVersion;
+  return patch === 0 ? `${major}.${minor}` : `${major}.${minor}.${patch}`;
+};
+
+/**
+ * Brings the different spellings of a game version to the one used for the matching.
+ * Releases lose a 0 patch version (1.20.0 is 1.20), wildcards end in x (1.20.* is 1.20.x),
+ * pre-releases and release candidates are written like 1.20-pre1 and 1.20-rc1 and snapshots are lowercased.
+ *
+ * @returns null when the version isn't a Minecraft version
+ */
+export const normalizeGameVersion = (version: string): string | null => {
+  const trimmed = version.trim().toLowerCase();
+
+  if (snapshotPattern.test(trimmed)) {
+    return trimmed;
+  }
+
+  if (releasePattern.test(trimmed)) {
+    return canonicalRelease(trimmed);
+  }
+
+  const wildcard = trimmed.match(wildcardPattern);
+  if (wildcard) {
+    return `${canonicalRelease(`${wildcard[1]}.${wildcard[2]}`)}.x`;
+  }
+
+  const preRelease = trimmed.match(preReleasePattern);
+  if (preRelease) {
+    return `${canonicalRelease(preRelease[1])}-pre${parseInt(preRelease[2], 10)}`;
+  }
+
+  const releaseCandidate = trimmed.match(releaseCandidatePattern);
+  if (releaseCandidate) {
+    return `${canonicalRelease(releaseCandidate[1])}-rc${parseInt(releaseCandidate[2], 10)}`;
+  }
+
+  return null;
+};
+
+/**
+ * The tags that aren't game versions, like the loader names, are only trimmed and lowercased
+ */
+const canonicalTag = (tag: string) => normalizeGameVersion(tag) ?? tag.trim().toLowerCase();
+
+const compare = (a: ReleaseVersion, b: ReleaseVersion) => a[0] - b[0] || a[1] - b[1] || a[2] - b[2];
+
+const sameMinor = (a: ReleaseVersion, b: ReleaseVersion) => a[0] === b[0] && a[1] === b[1];
+
+/**
+ * Snapshots rank above the releases and anything else below them.
+ * Pre-releases come before the release candidates, which come before the release itself.
+ * The keys are the same length so they can be compared part by part.
+ */
+const sortKey = (version: string): number[] => {
+  const normalized = normalizeGameVersion(version) || '';
+
+  const snapshot = parseSnapshot(normalized);
+  if (snapshot) {
+    return [2, snapshot[0], snapshot[1], snapshot[2].charCodeAt(0), 0, 0];
+  }
+
+  const staged = normalized.match(stagedReleasePattern);
+  if (staged) {
+    return [1, ...(parseRelease(staged[1]) as ReleaseVersion), staged[2] === 'pre' ? 0 : 1, parseInt(staged[3], 10)];
+  }
+
+  const release = parseRelease(normalized);
+  return release ? [1, ...release, 2, 0] : [0, 0, 0, 0, 0, 0];
+};
+
+/**
+ * Orders the game versions from the oldest to the newest, it can be given to sort as it is.
+ *
+ * Snapshots are newer than every release as there is no telling which release they lead up to.
+ * Anything that isn't a single Minecraft version, like a wildcard or a loader name, is older than everything else.
+ */
+export const compareGameVersions = (a: string, b: string): number => {
+  const first = sortKey(a);
+  const second = sortKey(b);
+  const firstDifferent = first.findIndex((part, index) => part !== second[index]);
+  return firstDifferent === -1 ? 0 : first[firstDifferent] - second[firstDifferent];
+};
+
+/**
+ * The newest of the game versions, the snapshots are only considered when they are allowed
+ *
+ * @returns undefined when none of them is a Minecraft version that can be used
+ */
+export const latestGameVersion = (versions: string[]): string | undefined => {
+  return versions
+    .filter((version) => sortKey(version)[0] !== 0)
+    .filter((version) => snapshotGameVersions || !isSnapshot(version))
+    .sort(compareGameVersions)
+    .at(-1);
+};
+
+const satisfiesComparator = (requested: ReleaseVersion, comparator: string): boolean => {
+  const match = comparator.match(comparatorPattern);
+  if (!match) {
+    return false;
+  }
+
+  const version = parseRelease(match[2]) as ReleaseVersion;
+  const difference = compare(requested, version);
+
+  switch (match[1]) {
+    case '>=':
+      return difference >= 0;
+    case '<=':
+      return difference <= 0;
+    case '>':
+      return difference > 0;
+    case '<':
+      return difference < 0;
+    case '~':
+      return sameMinor(requested, version) && difference >= 0;
+    default:
+      return difference === 0;
+  }
+};
+
+const tagCoversVersion = (requested: ReleaseVersion, tag: string): boolean => {
+  const wildcard = tag.match(wildcardPattern);
+  if (wildcard) {
+    return requested[0] === parseInt(wildcard[1], 10) && requested[1] === parseInt(wildcard[2], 10);
+  }
+
+  const hyphenRange = tag.match(hyphenRangePattern);
+  if (hyphenRange) {
+    const from = parseRelease(hyphenRange[1]) as ReleaseVersion;
+    const to = parseRelease(hyphenRange[2]) as ReleaseVersion;
+    return compare(requested, from) >= 0 && compare(requested, to) <= 0;
+  }
+
+  const release = tag.match(releasePattern);
+  if (release) {
+    // A tag without a patch version, like 1.20, stands for the whole minor version
+    return release[3] === undefined && sameMinor(requested, parseRelease(tag) as ReleaseVersion);
+  }
+
+  const comparators = tag.split(/\s+/);
+  return comparators.every((comparator) => satisfiesComparator(requested, comparator));
+};
+
+/**
+ * Decides whether a file tagged with the given game versions can be used with the requested game version.
+ *
+ * The tags can contain anything the platforms put next to the game versions, like the loader names Curseforge uses.
+ * Those never match. Snapshots only ever match themselves, so a pack on a release never gets a snapshot file and a
+ * pack on a snapshot still gets the files of that snapshot whether or not the snapshots are allowed.
+ * The spellings of the same version are the same, so 1.20.0 matches a file tagged 1.20 even when strict.
+ *
+ * @param requestedVersion The game version of the pack
+ * @param tags The game versions the file is tagged with
+ */
+export const gameVersionMatches = (requestedVersion: string, tags: string[]): boolean => {
+  const requested = requestedVersion.trim().toLowerCase();
+  const normalizedTags = tags.map((tag) => tag.trim().toLowerCase());
+
+  const canonicalRequested = canonicalTag(requested);
+  if (normalizedTags.some((tag) => canonicalTag(tag) === canonicalRequested)) {
+    return true;
+  }
+
+  const requestedRelease = parseRelease(requested);
+  if (strictGameVersionMatching || !requestedRelease) {
+    return false;
+  }
+
+  return normalizedTags.some((tag) => tag !== '' && tagCoversVersion(requestedRelease, tag));
+};
+
+/**
+ * The game versions to ask the platforms for when looking for files for the requested game version.
+ * Apart from the version itself, this is the minor version the files are sometimes tagged with instead.
+ */
+export const gameVersionsToRequest = (requestedVersion: string): string[] => {
+  const match = requestedVersion.match(releasePattern);
+  if (strictGameVersionMatching || !match || match[3] === undefined) {
+    return [requestedVersion];
+  }
+
+  return [requestedVersion, `${match[1]}.${match[2]}`];
+};
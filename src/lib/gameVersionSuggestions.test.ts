@@ -0,0 +1,41 @@
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { getCurseforgeMinecraftVersions } from '../repositories/curseforge/discovery.js';
+import { closestGameVersions, suggestGameVersions } from './gameVersionSuggestions.js';
+
+vi.mock('../repositories/curseforge/discovery.js');
+
+const known = ['1.21', '1.20.6', '1.20.4', '1.20.2', '1.20.1', '1.20', '1.19.2', '1.2.5', '1.2.1'];
+
+describe('The game version suggestions', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+  });
+
+  it.each([
+    ['1.20.l', ['1.20.1', '1.20.6', '1.20.4']],
+    ['1.2O.4', ['1.20.4', '1.20.6', '1.20.2']],
+    ['1.19.w', ['1.19.2']],
+    ['1,21', ['1.21', '1.20', '1.2.1']],
+    [' 1.19 ', ['1.21', '1.20', '1.19.2']],
+    ['fabric', []],
+    ['24w09a', []]
+  ])('suggests %j for %j', (input, expected) => {
+    expect(closestGameVersions(input, known)).toEqual(expected);
+  });
+
+  it('can suggest fewer versions', () => {
+    expect(closestGameVersions('1.20.l', known, 1)).toEqual(['1.20.1']);
+  });
+
+  it('suggests the versions curseforge knows about', async () => {
+    vi.mocked(getCurseforgeMinecraftVersions).mockResolvedValueOnce(known);
+
+    expect(await suggestGameVersions('1.20.l')).toEqual(['1.20.1', '1.20.6', '1.20.4']);
+  });
+
+  it('has no suggestions when curseforge cannot list the versions', async () => {
+    vi.mocked(getCurseforgeMinecraftVersions).mockRejectedValueOnce(new Error('offline'));
+
+    expect(await suggestGameVersions('1.20.l')).toEqual([]);
+  });
+});
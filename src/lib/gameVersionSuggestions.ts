@@ -0,0 +1,57 @@
+import { getCurseforgeMinecraftVersions } from '../repositories/curseforge/discovery.js';
+import { compareGameVersions } from './gameVersionMatcher.js';
+
+/**
+ * How many characters can be off for a version to count as a typo of another one, 1.20.l is one off from 1.20.1
+ */
+const MAX_DISTANCE = 2;
+
+const SUGGESTION_LIMIT = 3;
+
+/**
+ * The number of characters to add, remove or change to get from one to the other
+ */
+const editDistance = (from: string, to: string): number => {
+  let previous = Array.from({ length: to.length + 1 }, (_, index) => index);
+  for (let i = 1; i <= from.length; i++) {
+    const current = [i];
+    for (let j = 1; j <= to.length; j++) {
+      const substitution = previous[j - 1] + (from[i - 1] === to[j - 1] ? 0 : 1);
+      current.push(Math.min(previous[j] + 1, current[j - 1] + 1, substitution));
+    }
+    previous = current;
+  }
+  return previous[to.length];
+};
+
+/**
+ * The letters that get typed for the digits they look like, and the comma for the dot
+ */
+const lookalike = (input: string): string => {
+  return input.replace(/[il]/g, '1').replace(/o/g, '0').replace(/,/g, '.');
+};
+
+/**
+ * The known versions that are closest to the mistyped one, the newest first when they're just as close
+ */
+export const closestGameVersions = (input: string, known: string[], limit = SUGGESTION_LIMIT): string[] => {
+  const normalizedInput = lookalike(input.trim().toLowerCase());
+  return known
+    .map((version) => ({ version: version, distance: editDistance(normalizedInput, version.toLowerCase()) }))
+    .filter(({ distance }) => distance <= MAX_DISTANCE)
+    .sort((a, b) => a.distance - b.distance || compareGameVersions(b.version, a.version))
+    .slice(0, limit)
+    .map(({ version }) => version);
+};
+
+/**
+ * The game versions Curseforge knows about that the user might have meant.
+ * There are no suggestions when Curseforge can't list its versions.
+ */
+export const suggestGameVersions = async (input: string): Promise<string[]> => {
+  try {
+    return closestGameVersions(input, await getCurseforgeMinecraftVersions());
+  } catch {
+    return [];
+  }
+};
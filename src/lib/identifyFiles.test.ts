@@ -0,0 +1,103 @@
+import { chance } from 'jest-chance';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generatePlatformLookupResult } from '../../test/generatePlatformLookupResult.js';
+import { lookup } from '../repositories/index.js';
+import { getFingerprint } from './fingerprint.js';
+import { getHash } from './hash.js';
+import { identifyFiles } from './identifyFiles.js';
+import { Platform } from './modlist.types.js';
+
+vi.mock('./fingerprint.js');
+vi.mock('./hash.js');
+vi.mock('../repositories/index.js');
+
+describe('The file identification', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+  });
+
+  it('does not look anything up without files', async () => {
+    const actual = await identifyFiles([]);
+
+    expect(actual).toEqual({ identified: [], unidentified: [] });
+    expect(vi.mocked(lookup)).not.toHaveBeenCalled();
+  });
+
+  it('matches the files of a folder to their projects', async () => {
+    const curseforgeFile = '/mods/curseforge-mod.jar';
+    const modrinthFile = '/mods/modrinth-mod.jar';
+    const unknownFile = '/mods/unknown.jar';
+    const hashes: Record<string, string> = {
+      [curseforgeFile]: chance.hash(),
+      [modrinthFile]: chance.hash(),
+      [unknownFile]: chance.hash()
+    };
+    const curseforgeHit = generatePlatformLookupResult({ platform: Platform.CURSEFORGE }).generated;
+    const modrinthHit = generatePlatformLookupResult({ platform: Platform.MODRINTH }).generated;
+
+    vi.mocked(getHash).mockImplementation(async (file: string) => hashes[file]);
+    vi.mocked(getFingerprint).mockResolvedValue(chance.integer({ min: 1 }));
+    vi.mocked(lookup).mockResolvedValueOnce([
+      { sha1Hash: hashes[modrinthFile], hits: [modrinthHit] },
+      { sha1Hash: hashes[curseforgeFile], hits: [curseforgeHit] }
+    ]);
+
+    const actual = await identifyFiles([curseforgeFile, modrinthFile, unknownFile]);
+
+    expect(actual).toEqual({
+      identified: [
+        { file: curseforgeFile, hits: [curseforgeHit] },
+        { file: modrinthFile, hits: [modrinthHit] }
+      ],
+      unidentified: [unknownFile]
+    });
+  });
+
+  it('keeps every platform a file was found on', async () => {
+    const file = '/mods/everywhere.jar';
+    const hash = chance.hash();
+    const hits = [
+      generatePlatformLookupResult({ platform: Platform.CURSEFORGE }).generated,
+      generatePlatformLookupResult({ platform: Platform.MODRINTH }).generated
+    ];
+
+    vi.mocked(getHash).mockResolvedValueOnce(hash);
+    vi.mocked(getFingerprint).mockResolvedValueOnce(chance.integer({ min: 1 }));
+    vi.mocked(lookup).mockResolvedValueOnce([{ sha1Hash: hash, hits: hits }]);
+
+    const actual = await identifyFiles([file]);
+
+    expect(actual.identified).toEqual([{ file: file, hits: hits }]);
+  });
+
+  it('treats a result without hits as unidentified', async () => {
+    const file = '/mods/nothing.jar';
+    const hash = chance.hash();
+
+    vi.mocked(getHash).mockResolvedValueOnce(hash);
+    vi.mocked(getFingerprint).mockResolvedValueOnce(chance.integer({ min: 1 }));
+    vi.mocked(lookup).mockResolvedValueOnce([{ sha1Hash: hash, hits: [] }]);
+
+    const actual = await identifyFiles([file]);
+
+    expect(actual).toEqual({ identified: [], unidentified: [file] });
+  });
+
+  it('looks the files up on both platforms', async () => {
+    const files = ['/mods/a.jar', '/mods/b.jar'];
+    const hashes = [chance.hash(), chance.hash()];
+    const fingerprint = chance.integer({ min: 1 });
+
+    vi.mocked(getHash).mockResolvedValueOnce(hashes[0]).mockResolvedValueOnce(hashes[1]);
+    vi.mocked(getFingerprint).mockResolvedValueOnce(fingerprint).mockRejectedValueOnce(new Error());
+    vi.mocked(lookup).mockResolvedValueOnce([]);
+
+    await identifyFiles(files);
+
+    expect(vi.mocked(getHash)).toHaveBeenCalledWith(files[0], 'sha1');
+    expect(vi.mocked(lookup)).toHaveBeenCalledWith([
+      { platform: Platform.CURSEFORGE, hash: [String(fingerprint)] },
+      { platform: Platform.MODRINTH, hash: hashes }
+    ]);
+  });
+});
@@ -0,0 +1,57 @@
+import { PlatformLookupResult, lookup } from '../repositories/index.js';
+import { Modrinth } from '../repositories/modrinth/index.js';
+import { getFingerprint } from './fingerprint.js';
+import { getHash } from './hash.js';
+import { Platform } from './modlist.types.js';
+
+export interface IdentifiedFile {
+  file: string;
+  hits: PlatformLookupResult[];
+}
+
+export interface FileIdentification {
+  identified: IdentifiedFile[];
+  unidentified: string[];
+}
+
+/**
+ * Matches the files to their projects on the platforms.
+ * Curseforge is asked with the murmur2 fingerprints of the files and Modrinth with their SHA-1 hashes.
+ * A file can be found on more than one platform, the files that can't be found anywhere are returned separately.
+ *
+ * @param files The absolute paths of the files
+ */
+export const identifyFiles = async (files: string[]): Promise<FileIdentification> => {
+  if (files.length === 0) {
+    return { identified: [], unidentified: [] };
+  }
+
+  const hashes = await Promise.all(files.map((file) => getHash(file, Modrinth.PREFERRED_HASH)));
+  const fingerprints = await Promise.all(files.map((file) => getFingerprint(file).catch(() => undefined)));
+
+  const lookupResults = await lookup([
+    {
+      platform: Platform.CURSEFORGE,
+      hash: fingerprints.filter((fingerprint) => fingerprint !== undefined).map(String)
+    },
+    {
+      platform: Platform.MODRINTH,
+      hash: hashes
+    }
+  ]);
+
+  const identification: FileIdentification = { identified: [], unidentified: [] };
+
+  files.forEach((file, index) => {
+    const result = lookupResults.find((lookupResult) => lookupResult.sha1Hash === hashes[index]);
+
+    if (!result || result.hits.length === 0) {
+      identification.unidentified.push(file);
+      return;
+    }
+
+    identification.identified.push({ file: file, hits: result.hits });
+  });
+
+  return identification;
+};
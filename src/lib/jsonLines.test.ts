@@ -0,0 +1,74 @@
+import { describe, expect, it } from 'vitest';
+import { toJsonLines, toModListEntry } from './jsonLines.js';
+import { Platform } from './modlist.types.js';
+import { PlannedChange, PlannedChangeType } from './updatePlan.js';
+
+const upToDate: PlannedChange = {
+  id: 'AANobbMI',
+  name: 'Sodium',
+  platform: Platform.MODRINTH,
+  type: PlannedChangeType.NONE,
+  currentFileName: 'sodium-fabric-0.5.3.jar',
+  currentReleaseDate: '2023-09-01T00:00:00.000Z',
+  newFileName: 'sodium-fabric-0.5.3.jar',
+  newReleaseDate: '2023-09-01T00:00:00.000Z'
+};
+
+const withUpdate: PlannedChange = {
+  id: '306612',
+  name: 'Fabric API',
+  platform: Platform.CURSEFORGE,
+  type: PlannedChangeType.UPGRADE,
+  currentFileName: 'fabric-api-0.86.0.jar',
+  currentReleaseDate: '2023-07-01T00:00:00.000Z',
+  newFileName: 'fabric-api-0.87.0.jar',
+  newReleaseDate: '2023-08-01T00:00:00.000Z'
+};
+
+const notInstalled: PlannedChange = {
+  id: 'gvQqBUqZ',
+  name: 'Lithium',
+  platform: Platform.MODRINTH,
+  type: PlannedChangeType.INSTALL,
+  newFileName: 'lithium-fabric-0.11.2.jar',
+  newReleaseDate: '2023-06-01T00:00:00.000Z'
+};
+
+describe('The JSON Lines mod list', () => {
+  it('puts every mod on its own line', () => {
+    expect(toJsonLines([upToDate, withUpdate])).toMatchInlineSnapshot(`
+      "{"project":"AANobbMI","name":"Sodium","platform":"modrinth","installedVersion":"sodium-fabric-0.5.3.jar","latestVersion":"sodium-fabric-0.5.3.jar","updateAvailable":false}
+      {"project":"306612","name":"Fabric API","platform":"curseforge","installedVersion":"fabric-api-0.86.0.jar","latestVersion":"fabric-api-0.87.0.jar","updateAvailable":true}
+      "
+    `);
+  });
+
+  it('can be read back line by line', () => {
+    const lines = toJsonLines([upToDate, withUpdate, notInstalled]).trimEnd().split('\n');
+
+    expect(lines.map((line) => JSON.parse(line))).toEqual([
+      toModListEntry(upToDate),
+      toModListEntry(withUpdate),
+      toModListEntry(notInstalled)
+    ]);
+  });
+
+  it('marks the mods that are not installed', () => {
+    expect(toModListEntry(notInstalled)).toEqual({
+      project: 'gvQqBUqZ',
+      name: 'Lithium',
+      platform: Platform.MODRINTH,
+      installedVersion: null,
+      latestVersion: 'lithium-fabric-0.11.2.jar',
+      updateAvailable: false
+    });
+  });
+
+  it('treats a different older file as an available update', () => {
+    expect(toModListEntry({ ...withUpdate, type: PlannedChangeType.DOWNGRADE }).updateAvailable).toBeTruthy();
+  });
+
+  it('returns nothing for no mods', () => {
+    expect(toJsonLines([])).toEqual('');
+  });
+});
@@ -0,0 +1,49 @@
+import { Platform } from './modlist.types.js';
+import { PlannedChange, PlannedChangeType } from './updatePlan.js';
+
+/**
+ * One line of the machine readable mod list.
+ * Scripts depend on these names, so a field can be added but never renamed or removed.
+ */
+export interface ModListEntry {
+  /**
+   * The id of the project on its platform
+   */
+  project: string;
+  name: string;
+  platform: Platform;
+  /**
+   * The file name from the lock file, null when the mod isn't installed
+   */
+  installedVersion: string | null;
+  /**
+   * The file name the platform offers for the configuration
+   */
+  latestVersion: string;
+  /**
+   * True when the installed file is not the one the platform offers
+   */
+  updateAvailable: boolean;
+}
+
+export const toModListEntry = (change: PlannedChange): ModListEntry => {
+  // Built field by field so the keys always come out in the same order
+  return {
+    project: change.id,
+    name: change.name,
+    platform: change.platform,
+    installedVersion: change.currentFileName ?? null,
+    latestVersion: change.newFileName,
+    updateAvailable: change.type === PlannedChangeType.UPGRADE || change.type === PlannedChangeType.DOWNGRADE
+  };
+};
+
+/**
+ * Newline delimited JSON with a mod on every line, made to be piped into jq and the like.
+ * Every line ends with a newline, no mods is an empty string.
+ *
+ * @param changes The planned changes of the mods, like the ones in an update plan
+ */
+export const toJsonLines = (changes: PlannedChange[]): string => {
+  return changes.map((change) => `${JSON.stringify(toModListEntry(change))}\n`).join('');
+};
@@ -0,0 +1,62 @@
+import { afterEach, describe, expect, it } from 'vitest';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import {
+  acceptedLoaders,
+  compatibleLoaders,
+  isStrictLoaderMatching,
+  setStrictLoaderMatching
+} from './loaderCompatibility.js';
+import { Loader } from './modlist.types.js';
+
+describe('The loader compatibility', () => {
+  afterEach(() => {
+    setStrictLoaderMatching();
+  });
+
+  it('is not strict by default', () => {
+    expect(isStrictLoaderMatching()).toBeFalsy();
+  });
+
+  it('lets Quilt use Fabric files', () => {
+    expect(compatibleLoaders(Loader.QUILT)).toEqual([Loader.QUILT, Loader.FABRIC]);
+  });
+
+  it('does not let Fabric use Quilt files', () => {
+    expect(compatibleLoaders(Loader.FABRIC)).toEqual([Loader.FABRIC]);
+  });
+
+  it('only returns the loader itself when there is nothing compatible', () => {
+    expect(compatibleLoaders(Loader.FORGE)).toEqual([Loader.FORGE]);
+  });
+
+  it('only returns the loader itself when strict', () => {
+    setStrictLoaderMatching(true);
+
+    expect(isStrictLoaderMatching()).toBeTruthy();
+    expect(compatibleLoaders(Loader.QUILT)).toEqual([Loader.QUILT]);
+  });
+
+  it('can restore the default', () => {
+    setStrictLoaderMatching(true);
+    setStrictLoaderMatching();
+
+    expect(compatibleLoaders(Loader.QUILT)).toEqual([Loader.QUILT, Loader.FABRIC]);
+  });
+
+  describe('when a mod accepts more loaders', () => {
+    const configuration = generateModsJson({ loader: Loader.QUILT }).generated;
+
+    it('uses the loaders of the mod in their order', () => {
+      const mod = generateModConfig({ loaders: [Loader.FORGE, Loader.FABRIC] }).generated;
+
+      expect(acceptedLoaders(mod, configuration)).toEqual([Loader.FORGE, Loader.FABRIC]);
+    });
+
+    it.each([[undefined], [[]]])('uses the loader of the modlist when the mod has %j', (loaders) => {
+      const mod = generateModConfig({ loaders: loaders }).generated;
+
+      expect(acceptedLoaders(mod, configuration)).toEqual(Loader.QUILT);
+    });
+  });
+});
@@ -0,0 +1,40 @@
+import { Loader, Mod, ModsJson } from './modlist.types.js';
+
+/**
+ * The loaders that can also load the mods published for other loaders.
+ * Quilt can load most Fabric mods so a Fabric-only mod is still usable on a Quilt pack.
+ */
+export const loaderCompatibility: Partial<Record<Loader, Loader[]>> = {
+  [Loader.QUILT]: [Loader.FABRIC]
+};
+
+let strictLoaderMatching = false;
+
+/**
+ * When strict, only the files published for the configured loader are accepted.
+ * Calling it without a value restores the default, which accepts the compatible loaders too.
+ */
+export const setStrictLoaderMatching = (strict?: boolean) => {
+  strictLoaderMatching = !!strict;
+};
+
+export const isStrictLoaderMatching = () => strictLoaderMatching;
+
+/**
+ * The loaders whose files can be used with the given loader, in the order of preference.
+ * The loader itself always comes first.
+ */
+export const compatibleLoaders = (loader: Loader): Loader[] => {
+  if (strictLoaderMatching) {
+    return [loader];
+  }
+
+  return [loader, ...(loaderCompatibility[loader] || [])];
+};
+
+/**
+ * The loaders a mod can be installed for, the loader of the modlist when the mod doesn't name any
+ */
+export const acceptedLoaders = (mod: Mod, configuration: ModsJson): Loader | Loader[] => {
+  return mod.loaders && mod.loaders.length > 0 ? mod.loaders : configuration.loader;
+};
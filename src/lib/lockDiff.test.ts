@@ -0,0 +1,103 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { VersionChangeType, diffLockFiles } from './lockDiff.js';
+import { Platform } from './modlist.types.js';
+
+describe('The lock file diff', () => {
+  it('finds nothing between identical lists', () => {
+    const installations = [generateModInstall().generated, generateModInstall().generated];
+
+    expect(diffLockFiles(installations, [...installations])).toEqual({ added: [], removed: [], changed: [] });
+  });
+
+  it('finds nothing between two empty lists', () => {
+    expect(diffLockFiles([], [])).toEqual({ added: [], removed: [], changed: [] });
+  });
+
+  it('finds the added mods', () => {
+    const kept = generateModInstall().generated;
+    const added = generateModInstall().generated;
+
+    const actual = diffLockFiles([kept], [kept, added]);
+
+    expect(actual).toEqual({ added: [added], removed: [], changed: [] });
+  });
+
+  it('finds the removed mods', () => {
+    const kept = generateModInstall().generated;
+    const removed = generateModInstall().generated;
+
+    const actual = diffLockFiles([kept, removed], [kept]);
+
+    expect(actual).toEqual({ added: [], removed: [removed], changed: [] });
+  });
+
+  it('finds the upgraded mods', () => {
+    const from = generateModInstall({ releasedOn: '2023-01-01T00:00:00Z' }).generated;
+    const to = generateModInstall({
+      id: from.id,
+      type: from.type,
+      name: from.name,
+      releasedOn: '2024-01-01T00:00:00Z'
+    }).generated;
+
+    const actual = diffLockFiles([from], [to]);
+
+    expect(actual).toEqual({
+      added: [],
+      removed: [],
+      changed: [{ from: from, to: to, type: VersionChangeType.UPGRADE }]
+    });
+  });
+
+  it('finds the downgraded mods', () => {
+    const from = generateModInstall({ releasedOn: '2024-01-01T00:00:00Z' }).generated;
+    const to = generateModInstall({ id: from.id, type: from.type, releasedOn: '2023-01-01T00:00:00Z' }).generated;
+
+    const actual = diffLockFiles([from], [to]);
+
+    expect(actual.changed).toEqual([{ from: from, to: to, type: VersionChangeType.DOWNGRADE }]);
+  });
+
+  it('finds the replaced files', () => {
+    const from = generateModInstall().generated;
+    const to = { ...from, hash: chance.hash() };
+
+    const actual = diffLockFiles([from], [to]);
+
+    expect(actual.changed).toEqual([{ from: from, to: to, type: VersionChangeType.REPLACED }]);
+  });
+
+  it('does not mix up the same id on different platforms', () => {
+    const curseforge = generateModInstall({ id: '123', type: Platform.CURSEFORGE }).generated;
+    const modrinth = generateModInstall({ id: '123', type: Platform.MODRINTH }).generated;
+
+    const actual = diffLockFiles([curseforge], [modrinth]);
+
+    expect(actual).toEqual({ added: [modrinth], removed: [curseforge], changed: [] });
+  });
+
+  it('sorts the results by platform, name and id', () => {
+    const modrinthA = generateModInstall({ type: Platform.MODRINTH, name: 'Alpha', id: 'b' }).generated;
+    const modrinthB = generateModInstall({ type: Platform.MODRINTH, name: 'beta', id: 'a' }).generated;
+    const curseforgeZ = generateModInstall({ type: Platform.CURSEFORGE, name: 'Zeta', id: '2' }).generated;
+    const curseforgeZ2 = generateModInstall({ type: Platform.CURSEFORGE, name: 'zeta', id: '1' }).generated;
+
+    const actual = diffLockFiles([], [modrinthB, curseforgeZ, modrinthA, curseforgeZ2]);
+
+    expect(actual.added).toEqual([curseforgeZ2, curseforgeZ, modrinthA, modrinthB]);
+  });
+
+  it('does not change the lists it compares', () => {
+    const before = [generateModInstall().generated, generateModInstall().generated];
+    const after = [generateModInstall().generated, generateModInstall().generated];
+    const beforeCopy = [...before];
+    const afterCopy = [...after];
+
+    diffLockFiles(before, after);
+
+    expect(before).toEqual(beforeCopy);
+    expect(after).toEqual(afterCopy);
+  });
+});
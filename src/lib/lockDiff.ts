@@ -0,0 +1,68 @@
+import { ModInstall } from './modlist.types.js';
+
+export enum VersionChangeType {
+  UPGRADE = 'upgrade',
+  DOWNGRADE = 'downgrade',
+  // A different file released at the same time, like a re-upload
+  REPLACED = 'replaced'
+}
+
+export interface VersionChange {
+  from: ModInstall;
+  to: ModInstall;
+  type: VersionChangeType;
+}
+
+export interface LockDiff {
+  added: ModInstall[];
+  removed: ModInstall[];
+  changed: VersionChange[];
+}
+
+const keyOf = (installation: ModInstall) => `${installation.type}:${installation.id}`;
+
+// Sorted by platform, name and id so the same two states always give the same output
+const compareInstallations = (a: ModInstall, b: ModInstall) =>
+  a.type.localeCompare(b.type) ||
+  a.name.toLowerCase().localeCompare(b.name.toLowerCase()) ||
+  a.id.localeCompare(b.id);
+
+const changeType = (from: ModInstall, to: ModInstall) => {
+  if (to.releasedOn > from.releasedOn) {
+    return VersionChangeType.UPGRADE;
+  }
+
+  if (to.releasedOn < from.releasedOn) {
+    return VersionChangeType.DOWNGRADE;
+  }
+
+  return VersionChangeType.REPLACED;
+};
+
+/**
+ * Compares two installed states, like the lock files of two branches or two servers.
+ * Mods are matched by their platform and id, a mod has changed when it's installed from a different file.
+ *
+ * Unlike the update plan, nothing is resolved against the platforms, only the two states are compared.
+ *
+ * @param before The contents of the old lock file
+ * @param after The contents of the new lock file
+ */
+export const diffLockFiles = (before: ModInstall[], after: ModInstall[]): LockDiff => {
+  const beforeByKey = new Map(before.map((installation) => [keyOf(installation), installation]));
+  const afterByKey = new Map(after.map((installation) => [keyOf(installation), installation]));
+
+  const added = after.filter((installation) => !beforeByKey.has(keyOf(installation)));
+  const removed = before.filter((installation) => !afterByKey.has(keyOf(installation)));
+  const changed = after
+    .filter((installation) => beforeByKey.has(keyOf(installation)))
+    .map((to) => ({ from: beforeByKey.get(keyOf(to)) as ModInstall, to: to }))
+    .filter(({ from, to }) => from.hash !== to.hash || from.fileName !== to.fileName)
+    .map(({ from, to }) => ({ from: from, to: to, type: changeType(from, to) }));
+
+  return {
+    added: added.sort(compareInstallations),
+    removed: removed.sort(compareInstallations),
+    changed: changed.sort((a, b) => compareInstallations(a.to, b.to))
+  };
+};
@@ -0,0 +1,93 @@
+import { chance } from 'jest-chance';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateRemoteModDetails } from '../../test/generateRemoteDetails.js';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { ProjectToResolve, resolveProjects } from '../repositories/index.js';
+import { detectLockDrift } from './lockDrift.js';
+import { Mod, ModInstall, ModsJson, RemoteModDetails } from './modlist.types.js';
+
+vi.mock('../repositories/index.js');
+
+const assumeResolved = (details: RemoteModDetails[]) => {
+  vi.mocked(resolveProjects).mockImplementationOnce(async (projects: ProjectToResolve[]) => ({
+    resolved: projects.map((project, index) => ({ project: project, details: details[index] })),
+    errors: []
+  }));
+};
+
+const lockedDetails = (installation: ModInstall) =>
+  generateRemoteModDetails({ hash: installation.hash, downloadUrl: installation.downloadUrl }).generated;
+
+describe('The lock drift detection', () => {
+  let configuration: ModsJson;
+  let mod: Mod;
+  let installation: ModInstall;
+
+  beforeEach(() => {
+    vi.resetAllMocks();
+    mod = generateModConfig().generated;
+    installation = generateModInstall({ id: mod.id, type: mod.type }).generated;
+    configuration = generateModsJson({ mods: [mod] }).generated;
+  });
+
+  it('reports nothing when the lock matches the platform', async () => {
+    assumeResolved([lockedDetails(installation)]);
+
+    const actual = await detectLockDrift(configuration, [installation]);
+
+    expect(actual).toEqual({ drifted: [], errors: [] });
+  });
+
+  it('reports an entry whose file has a different hash', async () => {
+    const remote = generateRemoteModDetails({ downloadUrl: installation.downloadUrl }).generated;
+    assumeResolved([remote]);
+
+    const actual = await detectLockDrift(configuration, [installation]);
+
+    expect(actual.drifted).toEqual([{ mod: mod, locked: installation, remote: remote }]);
+  });
+
+  it('reports an entry whose file moved', async () => {
+    const remote = generateRemoteModDetails({ hash: installation.hash }).generated;
+    assumeResolved([remote]);
+
+    const actual = await detectLockDrift(configuration, [installation]);
+
+    expect(actual.drifted).toEqual([{ mod: mod, locked: installation, remote: remote }]);
+  });
+
+  it('only checks the mods that are locked', async () => {
+    const unlockedMod = generateModConfig().generated;
+    configuration.mods.push(unlockedMod);
+    assumeResolved([lockedDetails(installation)]);
+
+    await detectLockDrift(configuration, [installation]);
+
+    const projects = vi.mocked(resolveProjects).mock.calls[0][0];
+    expect(projects).toHaveLength(1);
+    expect(projects[0].id).toEqual(mod.id);
+  });
+
+  it('passes the concurrency on', async () => {
+    const concurrency = chance.integer({ min: 1, max: 10 });
+    assumeResolved([lockedDetails(installation)]);
+
+    await detectLockDrift(configuration, [installation], concurrency);
+
+    expect(vi.mocked(resolveProjects)).toHaveBeenCalledWith(expect.any(Array), concurrency);
+  });
+
+  it('reports the mods that could not be checked', async () => {
+    const error = new Error(chance.sentence());
+    vi.mocked(resolveProjects).mockImplementationOnce(async (projects: ProjectToResolve[]) => ({
+      resolved: [],
+      errors: [{ project: projects[0], error: error }]
+    }));
+
+    const actual = await detectLockDrift(configuration, [installation]);
+
+    expect(actual).toEqual({ drifted: [], errors: [{ mod: mod, error: error }] });
+  });
+});
@@ -0,0 +1,52 @@
+import { resolveProjects } from '../repositories/index.js';
+import { getInstallation, hasInstallation } from './configurationHelper.js';
+import { Mod, ModInstall, ModsJson, RemoteModDetails } from './modlist.types.js';
+import { PlanError, projectForMod } from './updatePlan.js';
+
+export interface LockDrift {
+  mod: Mod;
+  locked: ModInstall;
+  remote: RemoteModDetails;
+}
+
+export interface LockDriftResult {
+  drifted: LockDrift[];
+  errors: PlanError[];
+}
+
+/**
+ * Checks every locked mod against what its platform resolves to right now.
+ * An entry has drifted when the platform would now serve a different file (hash or download url) for the same
+ * configuration, for example when a pinned version has been re-uploaded.
+ *
+ * Mods that are not in the lock file are not checked, install takes care of those.
+ *
+ * @param configuration
+ * @param installations The contents of the lock file
+ * @param concurrency The maximum number of mods resolved at the same time
+ */
+export const detectLockDrift = async (
+  configuration: ModsJson,
+  installations: ModInstall[],
+  concurrency?: number
+): Promise<LockDriftResult> => {
+  const lockedMods = configuration.mods.filter((mod) => hasInstallation(mod, installations));
+  const projects = lockedMods.map((mod) => projectForMod(mod, configuration));
+
+  const result = await resolveProjects(projects, concurrency);
+  const drifted: LockDrift[] = [];
+
+  result.resolved.forEach(({ project, details }) => {
+    const mod = lockedMods[projects.indexOf(project)];
+    const locked = installations[getInstallation(mod, installations)];
+
+    if (locked.hash !== details.hash || locked.downloadUrl !== details.downloadUrl) {
+      drifted.push({ mod: mod, locked: locked, remote: details });
+    }
+  });
+
+  return {
+    drifted: drifted,
+    errors: result.errors.map(({ project, error }) => ({ mod: lockedMods[projects.indexOf(project)], error: error }))
+  };
+};
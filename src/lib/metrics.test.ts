@@ -0,0 +1,87 @@
+import { afterEach, describe, expect, it } from 'vitest';
+import {
+  countCacheHit,
+  countDownloadedBytes,
+  countRequest,
+  formatMetrics,
+  getMetrics,
+  resetMetrics
+} from './metrics.js';
+
+describe('The metrics', () => {
+  afterEach(() => {
+    resetMetrics();
+  });
+
+  it('starts with nothing counted', () => {
+    expect(getMetrics()).toEqual({
+      requests: 0,
+      retries: 0,
+      clientErrors: 0,
+      serverErrors: 0,
+      cacheHits: 0,
+      downloadedBytes: 0
+    });
+  });
+
+  it('tallies the requests', () => {
+    countRequest(1, 200);
+    countRequest(1, 503);
+    countRequest(2, 500);
+    countRequest(3, 200);
+    countRequest(1, 404);
+    countRequest(1, 429);
+    countRequest(1, null);
+    countRequest(2, null);
+    countRequest(1, 304);
+
+    expect(getMetrics()).toMatchObject({
+      requests: 9,
+      retries: 3,
+      clientErrors: 2,
+      serverErrors: 2
+    });
+  });
+
+  it('tallies the cache hits and the downloaded bytes', () => {
+    countCacheHit();
+    countCacheHit();
+    countDownloadedBytes(1024);
+    countDownloadedBytes(512);
+
+    expect(getMetrics()).toMatchObject({ cacheHits: 2, downloadedBytes: 1536 });
+  });
+
+  it('hands out a copy', () => {
+    const before = getMetrics();
+
+    countRequest(1, 200);
+
+    expect(before.requests).toEqual(0);
+    expect(getMetrics().requests).toEqual(1);
+  });
+
+  it('can be reset', () => {
+    countRequest(2, 500);
+    countCacheHit();
+
+    resetMetrics();
+
+    expect(getMetrics()).toEqual(expect.objectContaining({ requests: 0, retries: 0, cacheHits: 0 }));
+  });
+
+  it('can be shown', () => {
+    const counters = {
+      requests: 12,
+      retries: 2,
+      clientErrors: 1,
+      serverErrors: 3,
+      cacheHits: 4,
+      downloadedBytes: 2048
+    };
+
+    expect(formatMetrics(counters)).toEqual(
+      'requests: 12, retries: 2, 4xx: 1, 5xx: 3, cache hits: 4, downloaded: 2048 bytes'
+    );
+  });
+});
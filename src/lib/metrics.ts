@@ -0,0 +1,80 @@
+/**
+ * What the run did over the network, for the logs of CI and servers.
+ */
+export interface Metrics {
+  /**
+   * Every attempt counts, the retries, the downloads and the checks before the downloads too
+   */
+  requests: number;
+  retries: number;
+  clientErrors: number;
+  serverErrors: number;
+  /**
+   * The API responses and the files that were taken from the caches instead of the network
+   */
+  cacheHits: number;
+  downloadedBytes: number;
+}
+
+const CLIENT_ERROR = 400;
+const SERVER_ERROR = 500;
+
+const emptyMetrics = (): Metrics => ({
+  requests: 0,
+  retries: 0,
+  clientErrors: 0,
+  serverErrors: 0,
+  cacheHits: 0,
+  downloadedBytes: 0
+});
+
+let metrics: Metrics = emptyMetrics();
+
+/**
+ * A copy of the counters, the requests still running don't change it
+ */
+export const getMetrics = (): Metrics => ({ ...metrics });
+
+export const resetMetrics = () => {
+  metrics = emptyMetrics();
+};
+
+/**
+ * Counts an attempt of a request, the status is null when it failed without a response
+ */
+export const countRequest = (attempt: number, status: number | null) => {
+  metrics.requests++;
+  if (attempt > 1) {
+    metrics.retries++;
+  }
+  if (status === null) {
+    return;
+  }
+  if (status >= SERVER_ERROR) {
+    metrics.serverErrors++;
+  } else if (status >= CLIENT_ERROR) {
+    metrics.clientErrors++;
+  }
+};
+
+export const countCacheHit = () => {
+  metrics.cacheHits++;
+};
+
+export const countDownloadedBytes = (bytes: number) => {
+  metrics.downloadedBytes += bytes;
+};
+
+/**
+ * Writes the counters the way they're shown at the end of the run
+ */
+export const formatMetrics = (counters: Metrics): string => {
+  return [
+    `requests: ${counters.requests}`,
+    `retries: ${counters.retries}`,
+    `4xx: ${counters.clientErrors}`,
+    `5xx: ${counters.serverErrors}`,
+    `cache hits: ${counters.cacheHits}`,
+    `downloaded: ${counters.downloadedBytes} bytes`
+  ].join(', ');
+};
@@ -1,5 +1,6 @@
 import { chance } from 'jest-chance';
 import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { OfflineException } from '../errors/OfflineException.js';
 import { Logger } from './Logger.js';
 import { hasUpdate } from './mmmVersionCheck.js';
 import { rateLimitingFetch } from './rateLimiter/index.js';
@@ -28,6 +29,13 @@ describe('The MMM Version Check module', () => {
     expect(actual.latestVersionUrl).toEqual('<github cannot be reached>');
   });
 
+  it('should stay silent in offline mode', async () => {
+    vi.mocked(rateLimitingFetch).mockRejectedValueOnce(new OfflineException(chance.url()));
+    const actual = await hasUpdate('', logger);
+    expect(actual.hasUpdate).toBeFalsy();
+    expect(actual.latestVersionUrl).toEqual('<github cannot be reached>');
+  });
+
   it('should throw an error if the fetch errors', async () => {
     vi.mocked(rateLimitingFetch).mockRejectedValueOnce(new Error('something'));
     await expect(hasUpdate('', logger)).rejects.toThrow('something');
@@ -1,5 +1,6 @@
 import chalk from 'chalk';
 import { GithubReleasesNotFoundException } from '../errors/GithubReleasesNotFoundException.js';
+import { OfflineException } from '../errors/OfflineException.js';
 import { version } from '../version.js';
 import { Logger } from './Logger.js';
 import { rateLimitingFetch } from './rateLimiter/index.js';
@@ -69,7 +70,7 @@ export const hasUpdate = async (currentVersion: string, logger: Logger): Promise
     const releases = await githubReleases();
     latestVersion = releases[0];
   } catch (error) {
-    if (!(error instanceof GithubReleasesNotFoundException)) {
+    if (!(error instanceof GithubReleasesNotFoundException || error instanceof OfflineException)) {
       throw error;
     }
   }
@@ -1,10 +1,55 @@
 /* eslint-disable no-unused-vars */
+/**
+ * A file installed next to the main file of a mod, like a resource pack the author ships separately
+ */
+export interface AdditionalFile {
+  fileName: string;
+  hash: string;
+  downloadUrl: string;
+}
+
 export interface RemoteModDetails {
   name: string;
   fileName: string;
+  /**
+   * The version of the file as the platform shows it, the display name on Curseforge
+   */
+  version?: string;
   releaseDate: string;
   hash: string;
   downloadUrl: string;
+  /**
+   * The size of the file in bytes, when the platform tells it
+   */
+  fileLength?: number;
+  /**
+   * The files to install alongside, only there when the mod asks for them
+   */
+  additionalFiles?: AdditionalFile[];
+  /**
+   * The loader the file was picked for, only there when the mod accepts more than one
+   */
+  loader?: Loader;
+  /**
+   * The projects the file can't work without, only there when it has any
+   */
+  dependencies?: RequiredDependency[];
+  /**
+   * The projects the file can't be installed alongside, only there when it has any
+   */
+  incompatibilities?: RequiredDependency[];
+  /**
+   * The id of the file, only there for the files from Curseforge so their changelog can be fetched
+   */
+  curseforgeFileId?: number;
+}
+
+/**
+ * A project the file refers to, like one it can't do without, on the platform the file is from
+ */
+export interface RequiredDependency {
+  type: Platform;
+  id: string;
 }
 
 export enum ReleaseType {
@@ -13,6 +58,15 @@ export enum ReleaseType {
   RELEASE = 'release'
 }
 
+/**
+ * How adventurous a user is with their updates. Each channel includes the more stable ones.
+ */
+export enum ReleaseChannel {
+  RELEASE = 'release',
+  BETA = 'beta',
+  ALPHA = 'alpha'
+}
+
 export enum Platform {
   CURSEFORGE = 'curseforge',
   MODRINTH = 'modrinth'
@@ -47,21 +101,88 @@ export interface ModInstall {
   releasedOn: string;
   hash: string;
   downloadUrl: string;
+  additionalFiles?: AdditionalFile[];
+  /**
+   * The required dependencies of the installed file, kept so they can be checked without asking the platform again
+   */
+  dependencies?: RequiredDependency[];
+  /**
+   * The projects the installed file can't be installed alongside, kept for the same reason as the dependencies
+   */
+  incompatibilities?: RequiredDependency[];
+}
+
+/**
+ * The same mod on another platform, used when the configured platform can't give us a file
+ */
+export interface ModFallback {
+  type: Platform;
+  id: string;
+}
+
+/**
+ * The fallback as it is looked up, with the release types for its own platform
+ */
+export interface ResolvedModFallback extends ModFallback {
+  allowedReleaseTypes: ReleaseType[];
+}
+
+/**
+ * Ways out for when the automatic file selection picks the wrong file of a mod
+ */
+export interface FileOverrides {
+  /**
+   * A regular expression, the files with a matching name are never picked. Like "forge" for a combined jar.
+   */
+  excludeFileNamePattern?: string;
+  /**
+   * The file to use no matter what, the file id on Curseforge and the version id on Modrinth
+   */
+  forceFileId?: string;
+  /**
+   * Installs the additional files the author attached to the picked file too, only Curseforge has them
+   */
+  installAdditionalFiles?: boolean;
 }
 
-export interface Mod {
+export interface Mod extends FileOverrides {
   type: Platform;
   id: string;
   allowedReleaseTypes?: ReleaseType[];
+  /**
+   * A shorthand for the allowedReleaseTypes, the channel includes the more stable release types
+   */
+  releaseChannel?: ReleaseChannel;
   name: string;
   allowVersionFallback?: boolean;
   version?: string | undefined;
+  fallback?: ModFallback;
+  /**
+   * Keeps the installed file, update never looks for a newer one. forceFileId picks the file to install.
+   */
+  pinned?: boolean;
+  /**
+   * The loaders the mod can be installed for in the order of preference, instead of the loader of the modlist
+   */
+  loaders?: Loader[];
 }
 
 export interface ModsJson {
   loader: Loader;
   gameVersion: string;
   defaultAllowedReleaseTypes: ReleaseType[];
+  /**
+   * The release types of the mods of a platform, like only the releases from Curseforge but the betas from Modrinth
+   */
+  platformAllowedReleaseTypes?: Partial<Record<Platform, ReleaseType[]>>;
   modsFolder: string;
+  /**
+   * How the downloaded files are named, like {slug}-{gameVersion}.jar. Without it, the files keep their own names.
+   */
+  fileNameTemplate?: string;
+  /**
+   * Considers the snapshot game versions, like the --snapshots option does for a single run
+   */
+  allowSnapshots?: boolean;
   mods: Mod[];
 }
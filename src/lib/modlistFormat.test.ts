@@ -0,0 +1,122 @@
+import { describe, expect, it } from 'vitest';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { Loader, ModsJson, Platform, ReleaseType } from './modlist.types.js';
+import { isTomlModlist, parseModlist, serializeModlist } from './modlistFormat.js';
+
+const jsonModlist = `{
+  "loader": "fabric",
+  "gameVersion": "1.20.1",
+  "defaultAllowedReleaseTypes": ["release", "beta"],
+  "modsFolder": "mods",
+  "mods": [
+    {
+      "type": "modrinth",
+      "id": "AANobbMI",
+      "name": "Sodium",
+      "fallback": {
+        "type": "curseforge",
+        "id": "394468"
+      }
+    },
+    {
+      "type": "curseforge",
+      "id": "238222",
+      "name": "Just Enough Items",
+      "version": "15.2.0.27",
+      "allowedReleaseTypes": ["alpha"],
+      "pinned": true
+    }
+  ]
+}`;
+
+const tomlModlist = `# The mods of the server
+loader = "fabric"
+gameVersion = "1.20.1"
+defaultAllowedReleaseTypes = ["release", "beta"]
+modsFolder = "mods"
+
+[[mods]]
+type = "modrinth"
+id = "AANobbMI"
+name = "Sodium"
+fallback = { type = "curseforge", id = "394468" }
+
+[[mods]]
+type = "curseforge"
+id = "238222"
+name = "Just Enough Items"
+version = "15.2.0.27"
+allowedReleaseTypes = [
+  "alpha",
+]
+pinned = true
+`;
+
+const expectedModlist: ModsJson = {
+  loader: Loader.FABRIC,
+  gameVersion: '1.20.1',
+  defaultAllowedReleaseTypes: [ReleaseType.RELEASE, ReleaseType.BETA],
+  modsFolder: 'mods',
+  mods: [
+    {
+      type: Platform.MODRINTH,
+      id: 'AANobbMI',
+      name: 'Sodium',
+      fallback: { type: Platform.CURSEFORGE, id: '394468' }
+    },
+    {
+      type: Platform.CURSEFORGE,
+      id: '238222',
+      name: 'Just Enough Items',
+      version: '15.2.0.27',
+      allowedReleaseTypes: [ReleaseType.ALPHA],
+      pinned: true
+    }
+  ]
+};
+
+describe('The modlist format', () => {
+  it.each([
+    ['modlist.toml', true],
+    ['./servers/MODLIST.TOML', true],
+    ['modlist.json', false],
+    ['modlist', false],
+    ['toml', false]
+  ])('knows if %s is a TOML modlist', (configPath, expected) => {
+    expect(isTomlModlist(configPath)).toEqual(expected);
+  });
+
+  it('reads the same modlist from TOML and JSON', () => {
+    const fromToml = parseModlist(tomlModlist, 'modlist.toml');
+    const fromJson = parseModlist(jsonModlist, 'modlist.json');
+
+    expect(fromToml).toEqual(expectedModlist);
+    expect(fromToml).toStrictEqual(fromJson);
+  });
+
+  it.each(['modlist.json', 'modlist.toml'])('reads back what it writes to %s', (configPath) => {
+    const modlist = generateModsJson({
+      fileNameTemplate: '{slug}-{gameVersion}.jar',
+      mods: [
+        generateModConfig({ fallback: { type: Platform.MODRINTH, id: 'AANobbMI' } }).generated,
+        generateModConfig({ loaders: [Loader.FABRIC, Loader.QUILT], excludeFileNamePattern: '\\.zip$' }).generated,
+        generateModConfig({ name: 'Xaero\'s "Minimap"\t\\' }).generated
+      ]
+    }).generated;
+
+    expect(parseModlist(serializeModlist(modlist, configPath), configPath)).toEqual(modlist);
+  });
+
+  it('writes the JSON the way it always did', () => {
+    expect(serializeModlist(expectedModlist, 'modlist.json')).toEqual(JSON.stringify(expectedModlist, null, 2));
+  });
+
+  it('writes the mods of a TOML modlist as an array of tables', () => {
+    const actual = serializeModlist({ ...expectedModlist, mods: [expectedModlist.mods[0]] }, 'modlist.toml');
+
+    expect(actual).toContain('[[mods]]');
+    expect(actual).toContain('[mods.fallback]');
+    expect(actual.indexOf('modsFolder')).toBeLessThan(actual.indexOf('[[mods]]'));
+  });
+});
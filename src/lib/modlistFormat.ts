@@ -0,0 +1,27 @@
+import path from 'node:path';
+import { ModsJson } from './modlist.types.js';
+import { parseToml, stringifyToml } from './toml.js';
+
+/**
+ * The modlist is written in TOML when its file ends in .toml, in JSON otherwise
+ */
+export const isTomlModlist = (configPath: string) => path.extname(configPath).toLowerCase() === '.toml';
+
+/**
+ * Reads the contents of the modlist in the format of its file, the rest of the app only sees the ModsJson
+ *
+ * @throws {TomlSyntaxError} When a .toml modlist isn't valid TOML
+ */
+export const parseModlist = (contents: string, configPath: string): ModsJson => {
+  if (isTomlModlist(configPath)) {
+    return parseToml(contents) as unknown as ModsJson;
+  }
+  return JSON.parse(contents);
+};
+
+export const serializeModlist = (config: ModsJson, configPath: string): string => {
+  if (isTomlModlist(configPath)) {
+    return stringifyToml(config);
+  }
+  return JSON.stringify(config, null, 2);
+};
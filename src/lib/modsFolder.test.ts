@@ -0,0 +1,79 @@
+import { Stats, constants } from 'node:fs';
+import fs from 'node:fs/promises';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { ModsFolderInvalidException, ModsFolderProblem } from '../errors/ModsFolderInvalidException.js';
+import { Logger } from './Logger.js';
+import { ensureModsFolder, verifyModsFolder } from './modsFolder.js';
+
+vi.mock('node:fs/promises');
+vi.mock('./Logger.js');
+
+const modsFolder = '/minecraft/mods';
+
+const assumeStats = (isDirectory: boolean) => {
+  vi.mocked(fs.stat).mockResolvedValueOnce({ isDirectory: () => isDirectory } as Stats);
+};
+
+describe('The mods folder', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+  });
+
+  it('accepts a folder that can be written', async () => {
+    assumeStats(true);
+    vi.mocked(fs.access).mockResolvedValueOnce();
+
+    await expect(verifyModsFolder(modsFolder)).resolves.toBeUndefined();
+    expect(fs.access).toHaveBeenCalledWith(modsFolder, constants.W_OK);
+  });
+
+  it('rejects a missing folder', async () => {
+    vi.mocked(fs.stat).mockRejectedValueOnce(new Error('ENOENT'));
+
+    await expect(verifyModsFolder(modsFolder)).rejects.toThrow(
+      new ModsFolderInvalidException(modsFolder, ModsFolderProblem.MISSING)
+    );
+  });
+
+  it('rejects a file where the folder should be', async () => {
+    assumeStats(false);
+
+    await expect(verifyModsFolder(modsFolder)).rejects.toThrow(
+      new ModsFolderInvalidException(modsFolder, ModsFolderProblem.NOT_A_DIRECTORY)
+    );
+    expect(fs.access).not.toHaveBeenCalled();
+  });
+
+  it('rejects a read-only folder', async () => {
+    assumeStats(true);
+    vi.mocked(fs.access).mockRejectedValueOnce(new Error('EACCES'));
+
+    const error = await verifyModsFolder(modsFolder).catch((e) => e);
+
+    expect(error).toBeInstanceOf(ModsFolderInvalidException);
+    expect(error.problem).toEqual(ModsFolderProblem.NOT_WRITABLE);
+  });
+
+  describe('when making sure of it for a command', () => {
+    it('stops the command with the problem', async () => {
+      const logger = new Logger({} as never);
+      vi.mocked(fs.stat).mockRejectedValueOnce(new Error('ENOENT'));
+
+      await expect(ensureModsFolder(modsFolder, logger)).rejects.toBeInstanceOf(ModsFolderInvalidException);
+      expect(logger.error).toHaveBeenCalledWith(
+        "The mods folder /minecraft/mods doesn't exist, please create it or fix the modsFolder in the configuration",
+        1
+      );
+    });
+
+    it('lets the command go on with a good folder', async () => {
+      const logger = new Logger({} as never);
+      assumeStats(true);
+      vi.mocked(fs.access).mockResolvedValueOnce();
+
+      await ensureModsFolder(modsFolder, logger);
+
+      expect(logger.error).not.toHaveBeenCalled();
+    });
+  });
+});
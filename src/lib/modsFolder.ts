@@ -0,0 +1,38 @@
+import { constants } from 'node:fs';
+import fs from 'node:fs/promises';
+import { ModsFolderInvalidException, ModsFolderProblem } from '../errors/ModsFolderInvalidException.js';
+import { Logger } from './Logger.js';
+
+/**
+ * Makes sure the mods can be downloaded into the folder, before any of them is.
+ *
+ * @throws {ModsFolderInvalidException} When the folder is missing, is a file or can't be written
+ */
+export const verifyModsFolder = async (modsFolder: string) => {
+  const stats = await fs.stat(modsFolder).catch(() => null);
+  if (!stats) {
+    throw new ModsFolderInvalidException(modsFolder, ModsFolderProblem.MISSING);
+  }
+
+  if (!stats.isDirectory()) {
+    throw new ModsFolderInvalidException(modsFolder, ModsFolderProblem.NOT_A_DIRECTORY);
+  }
+
+  try {
+    await fs.access(modsFolder, constants.W_OK);
+  } catch {
+    throw new ModsFolderInvalidException(modsFolder, ModsFolderProblem.NOT_WRITABLE);
+  }
+};
+
+/**
+ * Stops the command with the problem of the mods folder
+ */
+export const ensureModsFolder = async (modsFolder: string, logger: Logger) => {
+  try {
+    await verifyModsFolder(modsFolder);
+  } catch (error) {
+    logger.error((error as Error).message, 1);
+    throw error;
+  }
+};
@@ -0,0 +1,293 @@
+import * as crypto from 'crypto';
+import fs from 'node:fs/promises';
+import os from 'node:os';
+import path from 'node:path';
+import { chance } from 'jest-chance';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { readZip } from '../../test/readZip.js';
+import { MrpackLoaderUnsupportedException } from '../errors/MrpackLoaderUnsupportedException.js';
+import { getProjectSides } from '../repositories/modrinth/fetch.js';
+import { Loader, ModInstall, ModsJson, Platform } from './modlist.types.js';
+import {
+  MRPACK_INDEX_FILE,
+  MrpackExport,
+  MrpackIndexSchema,
+  MrpackOptions,
+  buildMrpack,
+  createMrpack,
+  isAllowedDownload
+} from './mrpack.js';
+
+vi.mock('../repositories/modrinth/fetch.js');
+
+interface LocalTestContext {
+  modsFolder: string;
+  configuration: ModsJson;
+}
+
+const options: MrpackOptions = {
+  name: 'Fixture Pack',
+  versionId: '1.0.0',
+  loaderVersion: '0.15.11'
+};
+
+const digest = (contents: Buffer, algorithm: string) => crypto.createHash(algorithm).update(contents).digest('hex');
+
+const install = async (modsFolder: string, overrides: Partial<ModInstall>) => {
+  const contents = Buffer.from(chance.paragraph());
+  const installation = generateModInstall({
+    fileName: `${chance.guid()}.jar`,
+    hash: digest(contents, 'sha1'),
+    ...overrides
+  }).generated;
+  await fs.writeFile(path.resolve(modsFolder, installation.fileName), contents);
+  return { installation: installation, contents: contents };
+};
+
+const modrinthUrl = (fileName: string) => `https://cdn.modrinth.com/data/AANobbMI/versions/OihdIimA/${fileName}`;
+
+describe('The mrpack export', () => {
+  beforeEach<LocalTestContext>(async (context) => {
+    vi.resetAllMocks();
+    vi.mocked(getProjectSides).mockResolvedValue([]);
+    context.modsFolder = await fs.mkdtemp(path.join(os.tmpdir(), 'mmm-mrpack-'));
+    context.configuration = generateModsJson({ loader: Loader.FABRIC, gameVersion: '1.20.1' }).generated;
+  });
+
+  afterEach<LocalTestContext>(async (context) => {
+    await fs.rm(context.modsFolder, { recursive: true, force: true });
+  });
+
+  it<LocalTestContext>('lists the Modrinth files with their hashes, sizes and sides', async (context) => {
+    const fileName = 'sodium.jar';
+    const { installation, contents } = await install(context.modsFolder, {
+      type: Platform.MODRINTH,
+      id: 'AANobbMI',
+      fileName: fileName,
+      downloadUrl: modrinthUrl(fileName)
+    });
+    vi.mocked(getProjectSides).mockResolvedValueOnce([
+      { id: 'AANobbMI', client_side: 'required', server_side: 'unsupported' }
+    ]);
+
+    const actual = await buildMrpack(context.configuration, [installation], context.modsFolder, options);
+
+    expect(getProjectSides).toHaveBeenCalledWith(['AANobbMI']);
+    expect(actual).toEqual({
+      index: {
+        formatVersion: 1,
+        game: 'minecraft',
+        versionId: '1.0.0',
+        name: 'Fixture Pack',
+        files: [
+          {
+            path: 'mods/sodium.jar',
+            hashes: { sha1: digest(contents, 'sha1'), sha512: digest(contents, 'sha512') },
+            env: { client: 'required', server: 'unsupported' },
+            downloads: [modrinthUrl(fileName)],
+            fileSize: contents.length
+          }
+        ],
+        dependencies: { minecraft: '1.20.1', 'fabric-loader': '0.15.11' }
+      },
+      embedded: [],
+      skipped: []
+    });
+    expect(MrpackIndexSchema.safeParse(actual.index).success).toBeTruthy();
+  });
+
+  it.each([
+    [Loader.FABRIC, 'fabric-loader'],
+    [Loader.QUILT, 'quilt-loader'],
+    [Loader.FORGE, 'forge'],
+    [Loader.NEOFORGE, 'neoforge']
+  ])('names the %s loader %s in the dependencies', async (loader, dependency) => {
+    const configuration = generateModsJson({ loader: loader, gameVersion: '1.20.1' }).generated;
+
+    const actual = await buildMrpack(configuration, [], os.tmpdir(), options);
+
+    expect(actual.index.dependencies).toEqual({ minecraft: '1.20.1', [dependency]: '0.15.11' });
+  });
+
+  it<LocalTestContext>('refuses the loaders a Modrinth modpack can not have', async ({ configuration, modsFolder }) => {
+    configuration.loader = Loader.LITELOADER;
+
+    await expect(buildMrpack(configuration, [], modsFolder, options)).rejects.toThrow(
+      new MrpackLoaderUnsupportedException(Loader.LITELOADER, ['fabric', 'quilt', 'forge', 'neoforge'])
+    );
+  });
+
+  it<LocalTestContext>('needs the mods on both sides when their sides are not known', async (context) => {
+    const first = await install(context.modsFolder, {
+      type: Platform.MODRINTH,
+      id: 'first',
+      downloadUrl: modrinthUrl('a')
+    });
+    const second = await install(context.modsFolder, {
+      type: Platform.MODRINTH,
+      id: 'second',
+      downloadUrl: modrinthUrl('b')
+    });
+    const github = await install(context.modsFolder, {
+      type: Platform.CURSEFORGE,
+      downloadUrl: 'https://github.com/owner/repo/releases/download/1.0.0/mod.jar'
+    });
+    vi.mocked(getProjectSides).mockResolvedValueOnce([
+      { id: 'first', client_side: 'unknown', server_side: 'optional' }
+    ]);
+
+    const actual = await buildMrpack(
+      context.configuration,
+      [first.installation, second.installation, github.installation],
+      context.modsFolder,
+      options
+    );
+
+    expect(getProjectSides).toHaveBeenCalledWith(['first', 'second']);
+    expect(actual.index.files.map((file) => file.env)).toEqual([
+      { client: 'required', server: 'optional' },
+      { client: 'required', server: 'required' },
+      { client: 'required', server: 'required' }
+    ]);
+  });
+
+  it<LocalTestContext>('needs the mods on both sides when Modrinth can not tell their sides', async (context) => {
+    const { installation } = await install(context.modsFolder, {
+      type: Platform.MODRINTH,
+      downloadUrl: modrinthUrl('a')
+    });
+    vi.mocked(getProjectSides).mockRejectedValueOnce(new Error('Bad Gateway'));
+
+    const actual = await buildMrpack(context.configuration, [installation], context.modsFolder, options);
+
+    expect(actual.index.files[0].env).toEqual({ client: 'required', server: 'required' });
+  });
+
+  it<LocalTestContext>('skips the files the launchers can not download', async (context) => {
+    const { installation } = await install(context.modsFolder, {
+      type: Platform.CURSEFORGE,
+      fileName: 'jei.jar',
+      downloadUrl: 'https://edge.forgecdn.net/files/5101/366/jei.jar'
+    });
+
+    const actual = await buildMrpack(context.configuration, [installation], context.modsFolder, options);
+
+    expect(actual.index.files).toEqual([]);
+    expect(actual.embedded).toEqual([]);
+    expect(actual.skipped).toEqual([
+      {
+        fileName: 'jei.jar',
+        reason: "the launchers can't download it, --embed puts it in the pack if its license lets you share it"
+      }
+    ]);
+  });
+
+  it<LocalTestContext>('embeds the files the launchers can not download when asked to', async (context) => {
+    const { installation, contents } = await install(context.modsFolder, {
+      type: Platform.CURSEFORGE,
+      fileName: 'jei.jar',
+      downloadUrl: 'https://edge.forgecdn.net/files/5101/366/jei.jar'
+    });
+
+    const actual = await buildMrpack(context.configuration, [installation], context.modsFolder, {
+      ...options,
+      embed: true
+    });
+
+    expect(actual.index.files).toEqual([]);
+    expect(actual.embedded).toEqual([{ name: 'overrides/mods/jei.jar', data: contents }]);
+    expect(actual.skipped).toEqual([]);
+  });
+
+  it<LocalTestContext>('adds the additional files of a mod', async (context) => {
+    const additionalContents = Buffer.from(chance.paragraph());
+    await fs.writeFile(path.resolve(context.modsFolder, 'addon.jar'), additionalContents);
+    const { installation } = await install(context.modsFolder, {
+      type: Platform.MODRINTH,
+      downloadUrl: modrinthUrl('main.jar'),
+      additionalFiles: [
+        { fileName: 'addon.jar', hash: digest(additionalContents, 'sha1'), downloadUrl: modrinthUrl('addon.jar') }
+      ]
+    });
+
+    const actual = await buildMrpack(context.configuration, [installation], context.modsFolder, options);
+
+    expect(actual.index.files.map((file) => file.path)).toEqual([`mods/${installation.fileName}`, 'mods/addon.jar']);
+    expect(actual.index.files[1].downloads).toEqual([modrinthUrl('addon.jar')]);
+  });
+
+  it<LocalTestContext>('skips the files that are missing or changed', async (context) => {
+    const missing = generateModInstall({ fileName: 'missing.jar' }).generated;
+    const { installation: changed } = await install(context.modsFolder, {
+      fileName: 'changed.jar',
+      hash: chance.hash()
+    });
+
+    const actual = await buildMrpack(context.configuration, [missing, changed], context.modsFolder, options);
+
+    expect(actual.index.files).toEqual([]);
+    expect(actual.skipped).toEqual([
+      { fileName: 'missing.jar', reason: "it isn't installed, run mmm install first" },
+      { fileName: 'changed.jar', reason: 'it changed since it was installed' }
+    ]);
+  });
+
+  describe('when zipping the pack', () => {
+    let pack: MrpackExport;
+
+    beforeEach(() => {
+      pack = {
+        index: {
+          formatVersion: 1,
+          game: 'minecraft',
+          versionId: '1.0.0',
+          name: 'Fixture Pack',
+          files: [
+            {
+              path: 'mods/sodium.jar',
+              hashes: { sha1: chance.hash({ length: 40 }), sha512: chance.hash({ length: 128 }) },
+              env: { client: 'required', server: 'optional' },
+              downloads: [modrinthUrl('sodium.jar')],
+              fileSize: 1234
+            }
+          ],
+          dependencies: { minecraft: '1.20.1', 'fabric-loader': '0.15.11' }
+        },
+        embedded: [{ name: 'overrides/mods/jei.jar', data: Buffer.from('jei') }],
+        skipped: []
+      };
+    });
+
+    it('puts the index at the root and the embedded files in the overrides', () => {
+      const entries = readZip(createMrpack(pack));
+
+      expect(entries.map((entry) => entry.name)).toEqual([MRPACK_INDEX_FILE, 'overrides/mods/jei.jar']);
+      expect(MrpackIndexSchema.parse(JSON.parse(entries[0].data.toString('utf8')))).toEqual(pack.index);
+      expect(entries[1].data.toString('utf8')).toEqual('jei');
+    });
+
+    it.each([
+      ['a file outside of the instance', { path: '../sodium.jar' }],
+      ['an absolute path', { path: '/mods/sodium.jar' }],
+      ['a download from elsewhere', { downloads: ['https://example.com/sodium.jar'] }]
+    ])('refuses %s', (_description, overrides) => {
+      pack.index.files[0] = { ...pack.index.files[0], ...overrides };
+
+      expect(() => createMrpack(pack)).toThrow();
+    });
+  });
+
+  it.each([
+    ['https://cdn.modrinth.com/data/AANobbMI/versions/OihdIimA/sodium.jar', true],
+    ['https://github.com/owner/repo/releases/download/1.0.0/mod.jar', true],
+    ['https://raw.githubusercontent.com/owner/repo/main/mod.jar', true],
+    ['https://gitlab.com/owner/repo/-/raw/main/mod.jar', true],
+    ['http://cdn.modrinth.com/data/AANobbMI/versions/OihdIimA/sodium.jar', false],
+    ['https://edge.forgecdn.net/files/5101/366/jei.jar', false],
+    ['not a url', false]
+  ])('tells if the launchers download %s', (url, expected) => {
+    expect(isAllowedDownload(url)).toBe(expected);
+  });
+});
@@ -0,0 +1,225 @@
+import * as crypto from 'crypto';
+import fs from 'node:fs/promises';
+import path from 'node:path';
+import { z } from 'zod';
+import { MrpackLoaderUnsupportedException } from '../errors/MrpackLoaderUnsupportedException.js';
+import { ModrinthProjectSides, ModrinthSide, getProjectSides } from '../repositories/modrinth/fetch.js';
+import { fileExists } from './config.js';
+import { AdditionalFile, Loader, ModInstall, ModsJson, Platform } from './modlist.types.js';
+import { ZipEntry, createZip } from './zip.js';
+
+export const MRPACK_INDEX_FILE = 'modrinth.index.json';
+
+/**
+ * The ids Modrinth gives the loaders in the dependencies of a modpack, the other loaders can't be in one
+ */
+export const MRPACK_LOADERS: Partial<Record<Loader, string>> = {
+  [Loader.FABRIC]: 'fabric-loader',
+  [Loader.QUILT]: 'quilt-loader',
+  [Loader.FORGE]: 'forge',
+  [Loader.NEOFORGE]: 'neoforge'
+};
+
+/**
+ * The launchers only download the files of a modpack from these hosts
+ */
+export const MRPACK_DOWNLOAD_HOSTS = ['cdn.modrinth.com', 'github.com', 'raw.githubusercontent.com', 'gitlab.com'];
+
+export const isAllowedDownload = (url: string): boolean => {
+  try {
+    const parsed = new URL(url);
+    return parsed.protocol === 'https:' && MRPACK_DOWNLOAD_HOSTS.includes(parsed.hostname);
+  } catch (_) {
+    return false;
+  }
+};
+
+/**
+ * The launchers refuse the files that would end up outside of the instance folder
+ */
+const staysInInstance = (filePath: string) => {
+  return !filePath.startsWith('/') && !filePath.includes('\\') && !filePath.split('/').includes('..');
+};
+
+const EnvironmentSchema = z.enum(['required', 'optional', 'unsupported']);
+
+export const MrpackFileSchema = z.object({
+  path: z.string().refine(staysInInstance, { message: 'The path of a file has to stay in the instance folder' }),
+  hashes: z.object({
+    sha1: z.string(),
+    sha512: z.string()
+  }),
+  env: z
+    .object({
+      client: EnvironmentSchema,
+      server: EnvironmentSchema
+    })
+    .optional(),
+  downloads: z.array(
+    z.string().refine(isAllowedDownload, { message: `The files can only be downloaded from ${MRPACK_DOWNLOAD_HOSTS}` })
+  ),
+  fileSize: z.number()
+});
+
+// The modrinth.index.json of the mrpack format, version 1
+export const MrpackIndexSchema = z.object({
+  formatVersion: z.literal(1),
+  game: z.literal('minecraft'),
+  versionId: z.string(),
+  name: z.string(),
+  summary: z.string().optional(),
+  files: z.array(MrpackFileSchema),
+  dependencies: z.object({
+    minecraft: z.string(),
+    forge: z.string().optional(),
+    neoforge: z.string().optional(),
+    'fabric-loader': z.string().optional(),
+    'quilt-loader': z.string().optional()
+  })
+});
+
+export type MrpackFile = z.infer<typeof MrpackFileSchema>;
+export type MrpackIndex = z.infer<typeof MrpackIndexSchema>;
+type MrpackEnvironment = NonNullable<MrpackFile['env']>;
+
+export interface MrpackOptions {
+  name: string;
+  versionId: string;
+  /**
+   * The version of the loader the launchers install, like 0.15.11 for Fabric
+   */
+  loaderVersion: string;
+  /**
+   * Puts the files the launchers can't download in the pack itself, only when their licenses let them be shared
+   */
+  embed?: boolean;
+}
+
+export interface SkippedMrpackFile {
+  fileName: string;
+  reason: string;
+}
+
+export interface MrpackExport {
+  index: MrpackIndex;
+  /**
+   * The files the launchers can't download, like the ones from Curseforge, when they are put in the pack itself
+   */
+  embedded: ZipEntry[];
+  skipped: SkippedMrpackFile[];
+}
+
+/**
+ * A mod without a known side is needed on both
+ */
+const BOTH_SIDES: MrpackEnvironment = { client: 'required', server: 'required' };
+
+const environmentOf = (side: ModrinthSide) => (side === 'unknown' ? 'required' : side);
+
+/**
+ * Modrinth tells on which sides its projects run, the mods of the other platforms are needed on both
+ */
+const modrinthEnvironments = async (installations: ModInstall[]): Promise<Map<string, MrpackEnvironment>> => {
+  const projectIds = installations
+    .filter((installation) => installation.type === Platform.MODRINTH)
+    .map((installation) => installation.id);
+  const projects = await getProjectSides(projectIds).catch((): ModrinthProjectSides[] => []);
+
+  return new Map(
+    projects.map((project) => [
+      project.id,
+      { client: environmentOf(project.client_side), server: environmentOf(project.server_side) }
+    ])
+  );
+};
+
+const digest = (contents: Buffer, algorithm: string) => crypto.createHash(algorithm).update(contents).digest('hex');
+
+/**
+ * Puts the installed files of the lock file into a modpack.
+ * The files the launchers can download are listed in the index with their hashes and sizes, the ones they can't are
+ * embedded in the overrides when asked to and skipped otherwise. The files that aren't installed or changed since are
+ * skipped too.
+ *
+ * @throws {MrpackLoaderUnsupportedException} When the loader of the modlist can't be in a Modrinth modpack
+ */
+export const buildMrpack = async (
+  configuration: ModsJson,
+  installations: ModInstall[],
+  modsFolder: string,
+  options: MrpackOptions
+): Promise<MrpackExport> => {
+  const loader = MRPACK_LOADERS[configuration.loader];
+  if (!loader) {
+    throw new MrpackLoaderUnsupportedException(configuration.loader, Object.keys(MRPACK_LOADERS));
+  }
+
+  const environments = await modrinthEnvironments(installations);
+  const pack: MrpackExport = {
+    index: {
+      formatVersion: 1,
+      game: 'minecraft',
+      versionId: options.versionId,
+      name: options.name,
+      files: [],
+      dependencies: {
+        minecraft: configuration.gameVersion,
+        [loader]: options.loaderVersion
+      }
+    },
+    embedded: [],
+    skipped: []
+  };
+
+  for (const installation of installations) {
+    const files: AdditionalFile[] = [installation, ...(installation.additionalFiles ?? [])];
+
+    for (const file of files) {
+      const localPath = path.resolve(modsFolder, file.fileName);
+      if (!(await fileExists(localPath))) {
+        pack.skipped.push({ fileName: file.fileName, reason: "it isn't installed, run mmm install first" });
+        continue;
+      }
+
+      const contents = await fs.readFile(localPath);
+      const sha1 = digest(contents, 'sha1');
+      if (sha1 !== file.hash) {
+        pack.skipped.push({ fileName: file.fileName, reason: 'it changed since it was installed' });
+        continue;
+      }
+
+      const packPath = `mods/${file.fileName}`;
+      if (!isAllowedDownload(file.downloadUrl)) {
+        if (options.embed) {
+          pack.embedded.push({ name: `overrides/${packPath}`, data: contents });
+        } else {
+          pack.skipped.push({
+            fileName: file.fileName,
+            reason: "the launchers can't download it, --embed puts it in the pack if its license lets you share it"
+          });
+        }
+        continue;
+      }
+
+      pack.index.files.push({
+        path: packPath,
+        hashes: { sha1: sha1, sha512: digest(contents, 'sha512') },
+        env: environments.get(installation.id) ?? BOTH_SIDES,
+        downloads: [file.downloadUrl],
+        fileSize: contents.length
+      });
+    }
+  }
+
+  return pack;
+};
+
+/**
+ * Zips the modpack the way the mrpack format wants it, the index at the root and the embedded files next to it
+ *
+ * @throws {ZodError} When the index isn't a valid mrpack index, like with a file outside of the instance folder
+ */
+export const createMrpack = (pack: MrpackExport): Buffer => {
+  const index = MrpackIndexSchema.parse(pack.index);
+  return createZip([{ name: MRPACK_INDEX_FILE, data: Buffer.from(JSON.stringify(index, null, 2)) }, ...pack.embedded]);
+};
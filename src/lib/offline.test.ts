@@ -0,0 +1,30 @@
+import { chance } from 'jest-chance';
+import { afterEach, describe, expect, it } from 'vitest';
+import { OfflineException } from '../errors/OfflineException.js';
+import { assertOnline, isOfflineMode, setOfflineMode } from './offline.js';
+
+describe('The offline mode', () => {
+  afterEach(() => {
+    setOfflineMode();
+  });
+
+  it('is off by default', () => {
+    expect(isOfflineMode()).toBeFalsy();
+    expect(() => assertOnline(chance.url())).not.toThrow();
+  });
+
+  it('refuses to go to the network when on', () => {
+    const url = chance.url();
+    setOfflineMode(true);
+
+    expect(isOfflineMode()).toBeTruthy();
+    expect(() => assertOnline(url)).toThrow(new OfflineException(url));
+  });
+
+  it('can be turned off again', () => {
+    setOfflineMode(true);
+    setOfflineMode();
+
+    expect(isOfflineMode()).toBeFalsy();
+  });
+});
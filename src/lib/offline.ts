@@ -0,0 +1,24 @@
+import { OfflineException } from '../errors/OfflineException.js';
+
+let offlineMode = false;
+
+/**
+ * In offline mode no request leaves the machine, everything has to come from the lock file and the file cache.
+ * Calling it without a value goes back to the default, which is online.
+ */
+export const setOfflineMode = (offline?: boolean) => {
+  offlineMode = !!offline;
+};
+
+export const isOfflineMode = () => offlineMode;
+
+/**
+ * Has to be called before anything goes out to the network.
+ *
+ * @throws {OfflineException} When running in offline mode
+ */
+export const assertOnline = (url: string) => {
+  if (offlineMode) {
+    throw new OfflineException(url);
+  }
+};
@@ -0,0 +1,91 @@
+import * as crypto from 'crypto';
+import fs from 'node:fs/promises';
+import os from 'node:os';
+import path from 'node:path';
+import { chance } from 'jest-chance';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { downloadFile } from './downloader.js';
+import { setFileCacheDirectory, storeInCache } from './fileCache.js';
+import { Mod, ModInstall } from './modlist.types.js';
+import { setOfflineMode } from './offline.js';
+import { findModsUnavailableOffline } from './offlineResolution.js';
+
+interface LocalTestContext {
+  directory: string;
+  modsFolder: string;
+}
+
+const sha1 = (contents: string) => crypto.createHash('sha1').update(contents).digest('hex');
+
+const lockedMod = (contents: string): { mod: Mod; installation: ModInstall } => {
+  const mod = generateModConfig().generated;
+  const installation = generateModInstall({
+    id: mod.id,
+    type: mod.type,
+    fileName: `${chance.guid()}.jar`,
+    hash: sha1(contents)
+  }).generated;
+  return { mod: mod, installation: installation };
+};
+
+describe('The offline resolution', () => {
+  beforeEach<LocalTestContext>(async (context) => {
+    vi.stubGlobal('fetch', vi.fn());
+    context.directory = await fs.mkdtemp(path.join(os.tmpdir(), 'mmm-offline-'));
+    context.modsFolder = path.resolve(context.directory, 'mods');
+    await fs.mkdir(context.modsFolder);
+    setFileCacheDirectory(path.resolve(context.directory, 'cache'));
+    setOfflineMode(true);
+  });
+
+  afterEach<LocalTestContext>(async (context) => {
+    vi.resetAllMocks();
+    setFileCacheDirectory();
+    setOfflineMode();
+    await fs.rm(context.directory, { recursive: true, force: true });
+  });
+
+  it<LocalTestContext>('installs everything from the mods folder and a populated cache', async (context) => {
+    const installedContents = chance.paragraph();
+    const cachedContents = chance.paragraph();
+    const installed = lockedMod(installedContents);
+    const cached = lockedMod(cachedContents);
+    const configuration = generateModsJson({ mods: [installed.mod, cached.mod] }).generated;
+    const installations = [installed.installation, cached.installation];
+
+    await fs.writeFile(path.resolve(context.modsFolder, installed.installation.fileName), installedContents);
+    const cachedSource = path.resolve(context.directory, 'source.jar');
+    await fs.writeFile(cachedSource, cachedContents);
+    await storeInCache(cachedSource, cached.installation.hash);
+
+    const unavailable = await findModsUnavailableOffline(configuration, installations, context.modsFolder);
+    expect(unavailable).toEqual([]);
+
+    const destination = path.resolve(context.modsFolder, cached.installation.fileName);
+    await downloadFile(cached.installation.downloadUrl, destination, { expectedHash: cached.installation.hash });
+
+    expect(await fs.readFile(destination, 'utf-8')).toEqual(cachedContents);
+    expect(vi.mocked(fetch)).not.toHaveBeenCalled();
+  });
+
+  it<LocalTestContext>('lists every mod that cannot be installed offline', async (context) => {
+    const installedContents = chance.paragraph();
+    const installed = lockedMod(installedContents);
+    const missing = lockedMod(chance.paragraph());
+    const damaged = lockedMod(chance.paragraph());
+    const notLocked = generateModConfig().generated;
+    const configuration = generateModsJson({ mods: [installed.mod, missing.mod, notLocked, damaged.mod] }).generated;
+    const installations = [installed.installation, missing.installation, damaged.installation];
+
+    await fs.writeFile(path.resolve(context.modsFolder, installed.installation.fileName), installedContents);
+    await fs.writeFile(path.resolve(context.modsFolder, damaged.installation.fileName), chance.paragraph());
+
+    const unavailable = await findModsUnavailableOffline(configuration, installations, context.modsFolder);
+
+    expect(unavailable).toEqual([missing.mod, notLocked, damaged.mod]);
+    expect(vi.mocked(fetch)).not.toHaveBeenCalled();
+  });
+});
@@ -0,0 +1,44 @@
+import path from 'path';
+import { fileExists } from './config.js';
+import { getInstallation } from './configurationHelper.js';
+import { isCached } from './fileCache.js';
+import { getHash } from './hash.js';
+import { Mod, ModInstall, ModsJson } from './modlist.types.js';
+
+const isInstalled = async (installation: ModInstall, modsFolder: string) => {
+  const modPath = path.resolve(modsFolder, installation.fileName);
+  if (!(await fileExists(modPath))) {
+    return false;
+  }
+
+  return (await getHash(modPath)) === installation.hash;
+};
+
+/**
+ * Finds the mods that can't be installed without the network.
+ * A mod can only be installed offline when it's in the lock file and its locked file is either already in the mods
+ * folder or in the file cache. Everything else would need to be resolved or downloaded from its platform.
+ *
+ * @param configuration
+ * @param installations The contents of the lock file
+ * @param modsFolder
+ */
+export const findModsUnavailableOffline = async (
+  configuration: ModsJson,
+  installations: ModInstall[],
+  modsFolder: string
+): Promise<Mod[]> => {
+  const availability = await Promise.all(
+    configuration.mods.map(async (mod) => {
+      const index = getInstallation(mod, installations);
+      if (index === -1) {
+        return false;
+      }
+
+      const installation = installations[index];
+      return (await isInstalled(installation, modsFolder)) || (await isCached(installation.hash));
+    })
+  );
+
+  return configuration.mods.filter((_mod, index) => !availability[index]);
+};
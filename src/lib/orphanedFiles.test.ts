@@ -0,0 +1,70 @@
+import { chance } from 'jest-chance';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generatePlatformLookupResult } from '../../test/generatePlatformLookupResult.js';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { identifyFiles } from './identifyFiles.js';
+import { Mod, ModsJson, Platform } from './modlist.types.js';
+import { findOrphanedFiles } from './orphanedFiles.js';
+
+vi.mock('./identifyFiles.js');
+
+describe('The orphaned files finder', () => {
+  let configuration: ModsJson;
+  let managedMod: Mod;
+
+  beforeEach(() => {
+    vi.resetAllMocks();
+    managedMod = generateModConfig().generated;
+    configuration = generateModsJson({ mods: [managedMod] }).generated;
+  });
+
+  it('sorts the files of the configured mods from everything else', async () => {
+    const oldFile = '/mods/old-version.jar';
+    const removedModFile = '/mods/removed-mod.jar';
+    const manualFile = '/mods/manual.jar';
+
+    vi.mocked(identifyFiles).mockResolvedValueOnce({
+      identified: [
+        {
+          file: removedModFile,
+          hits: [generatePlatformLookupResult({ platform: managedMod.type, modId: chance.word() }).generated]
+        },
+        {
+          file: oldFile,
+          hits: [generatePlatformLookupResult({ platform: managedMod.type, modId: managedMod.id }).generated]
+        }
+      ],
+      unidentified: [manualFile]
+    });
+
+    const actual = await findOrphanedFiles([oldFile, removedModFile, manualFile], configuration);
+
+    expect(vi.mocked(identifyFiles)).toHaveBeenCalledWith([oldFile, removedModFile, manualFile]);
+    expect(actual).toEqual({ orphaned: [oldFile], unknown: [removedModFile, manualFile] });
+  });
+
+  it('does not mix up the platforms', async () => {
+    const file = '/mods/other-platform.jar';
+    const otherPlatform = managedMod.type === Platform.MODRINTH ? Platform.CURSEFORGE : Platform.MODRINTH;
+
+    vi.mocked(identifyFiles).mockResolvedValueOnce({
+      identified: [
+        { file: file, hits: [generatePlatformLookupResult({ platform: otherPlatform, modId: managedMod.id }).generated] }
+      ],
+      unidentified: []
+    });
+
+    const actual = await findOrphanedFiles([file], configuration);
+
+    expect(actual).toEqual({ orphaned: [], unknown: [file] });
+  });
+
+  it('returns nothing without files', async () => {
+    vi.mocked(identifyFiles).mockResolvedValueOnce({ identified: [], unidentified: [] });
+
+    const actual = await findOrphanedFiles([], configuration);
+
+    expect(actual).toEqual({ orphaned: [], unknown: [] });
+  });
+});
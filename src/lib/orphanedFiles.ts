@@ -0,0 +1,42 @@
+import { identifyFiles } from './identifyFiles.js';
+import { ModsJson } from './modlist.types.js';
+
+export interface OrphanedFiles {
+  orphaned: string[];
+  unknown: string[];
+}
+
+/**
+ * Sorts the files that aren't in the lock file into the leftover files of the configured mods, like the old versions
+ * left behind by an update, and everything else.
+ *
+ * A file of a mod that is no longer in the modlist can't be told apart from one that was added by hand,
+ * so those end up in the unknown files.
+ *
+ * @param files The files that are not in the lock file
+ * @param configuration
+ */
+export const findOrphanedFiles = async (files: string[], configuration: ModsJson): Promise<OrphanedFiles> => {
+  const { identified, unidentified } = await identifyFiles(files);
+
+  const orphanedFiles: OrphanedFiles = { orphaned: [], unknown: [...unidentified] };
+
+  identified.forEach(({ file, hits }) => {
+    const belongsToConfiguredMod = hits.some((hit) =>
+      configuration.mods.some((mod) => mod.type === hit.platform && mod.id === hit.modId)
+    );
+
+    if (belongsToConfiguredMod) {
+      orphanedFiles.orphaned.push(file);
+      return;
+    }
+    orphanedFiles.unknown.push(file);
+  });
+
+  // Keep the order of the folder so the output is predictable
+  const order = (file: string) => files.indexOf(file);
+  orphanedFiles.orphaned.sort((a, b) => order(a) - order(b));
+  orphanedFiles.unknown.sort((a, b) => order(a) - order(b));
+
+  return orphanedFiles;
+};
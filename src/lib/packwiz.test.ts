@@ -0,0 +1,237 @@
+import path from 'node:path';
+import { fileURLToPath } from 'node:url';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateModrinthVersion } from '../../test/generateModrinthVersion.js';
+import { CouldNotFindModException } from '../errors/CouldNotFindModException.js';
+import { PackwizPackInvalidException } from '../errors/PackwizPackInvalidException.js';
+import { getVersion } from '../repositories/modrinth/fetch.js';
+import { getVersionsByHashes } from '../repositories/modrinth/lookup.js';
+import { Loader, Platform } from './modlist.types.js';
+import { PackwizMod, mapPackwizMods, packwizModToMod, readPackwizPack } from './packwiz.js';
+
+vi.mock('../repositories/modrinth/fetch.js');
+vi.mock('../repositories/modrinth/lookup.js');
+
+const fixture = (name: string) => fileURLToPath(new URL(`../../test/fixtures/${name}`, import.meta.url));
+
+const lithiumHash = '0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d';
+
+const packwizMod = (overrides: Partial<PackwizMod> = {}): PackwizMod => ({
+  file: 'mods/sodium.pw.toml',
+  name: 'Sodium',
+  pinned: false,
+  download: {},
+  ...overrides
+});
+
+describe('The packwiz import', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+    vi.mocked(getVersionsByHashes).mockResolvedValue({});
+  });
+
+  describe('when reading a pack', () => {
+    it('reads the pack, its index and the metafiles in it', async () => {
+      const pack = await readPackwizPack(fixture('packwiz'));
+
+      expect(pack.file).toEqual(path.resolve(fixture('packwiz'), 'pack.toml'));
+      expect(pack.name).toEqual('Fixture Pack');
+      expect(pack.gameVersion).toEqual('1.20.1');
+      expect(pack.loader).toEqual(Loader.FABRIC);
+      expect(pack.mods.map((mod) => mod.file)).toEqual([
+        'mods/sodium.pw.toml',
+        'mods/jei.pw.toml',
+        'mods/fabric-api.pw.toml',
+        'mods/lithium.pw.toml',
+        'mods/iris.pw.toml',
+        'mods/custom.pw.toml',
+        'resourcepacks/faithful.pw.toml'
+      ]);
+    });
+
+    it('reads the references of a mod', async () => {
+      const pack = await readPackwizPack(path.resolve(fixture('packwiz'), 'pack.toml'));
+
+      expect(pack.mods[1]).toEqual({
+        file: 'mods/jei.pw.toml',
+        name: 'Just Enough Items',
+        pinned: true,
+        download: {
+          hashFormat: 'sha1',
+          hash: '2f1e0d9c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e'
+        },
+        curseforge: {
+          projectId: '238222',
+          fileId: '5101366'
+        }
+      });
+    });
+
+    it('fills in what a minimal pack leaves out', async () => {
+      const pack = await readPackwizPack(fixture('packwiz-minimal'));
+
+      expect(pack).toEqual({
+        file: path.resolve(fixture('packwiz-minimal'), 'pack.toml'),
+        name: 'packwiz-minimal',
+        gameVersion: '1.20.1',
+        loader: undefined,
+        mods: [{ file: 'mods/plain.pw.toml', name: 'plain', pinned: false, download: {} }]
+      });
+    });
+
+    it('reads a pack without files', async () => {
+      const pack = await readPackwizPack(fixture('packwiz-empty'));
+
+      expect(pack.name).toEqual('Empty Pack');
+      expect(pack.gameVersion).toBeUndefined();
+      expect(pack.mods).toEqual([]);
+    });
+
+    it('tells when a file of the pack is missing', async () => {
+      const packFile = path.resolve(fixture('packwiz-missing'), 'pack.toml');
+
+      await expect(readPackwizPack(packFile)).rejects.toThrow(PackwizPackInvalidException);
+      await expect(readPackwizPack(packFile)).rejects.toHaveProperty('file', packFile);
+    });
+
+    it('tells when a file of the pack is not valid TOML', async () => {
+      const packFile = path.resolve(fixture('packwiz-invalid'), 'pack.toml');
+
+      await expect(readPackwizPack(packFile)).rejects.toBeInstanceOf(PackwizPackInvalidException);
+      await expect(readPackwizPack(packFile)).rejects.toThrow('Line 1 of the TOML file is invalid');
+    });
+  });
+
+  describe('when mapping a mod', () => {
+    it.each<[string, Partial<PackwizMod>, object | undefined]>([
+      ['a Modrinth project', { modrinth: { modId: 'AANobbMI' } }, { type: Platform.MODRINTH, id: 'AANobbMI' }],
+      [
+        'a Curseforge project',
+        { curseforge: { projectId: '394468', fileId: '4593200' } },
+        { type: Platform.CURSEFORGE, id: '394468' }
+      ],
+      [
+        'a mod on both platforms',
+        { modrinth: { modId: 'AANobbMI' }, curseforge: { projectId: '394468' } },
+        { type: Platform.MODRINTH, id: 'AANobbMI', fallback: { type: Platform.CURSEFORGE, id: '394468' } }
+      ],
+      [
+        'a file from the Modrinth servers',
+        { download: { url: 'https://cdn.modrinth.com/data/AANobbMI/versions/OihdIimA/sodium.jar' } },
+        { type: Platform.MODRINTH, id: 'AANobbMI' }
+      ],
+      [
+        'a pinned Modrinth version',
+        { pinned: true, modrinth: { modId: 'AANobbMI', version: 'OihdIimA' } },
+        { type: Platform.MODRINTH, id: 'AANobbMI', pinned: true, forceFileId: 'OihdIimA' }
+      ],
+      [
+        'a pinned mod without its file',
+        { pinned: true, modrinth: { modId: 'AANobbMI' } },
+        { type: Platform.MODRINTH, id: 'AANobbMI' }
+      ],
+      ['a file from elsewhere', { download: { url: 'https://example.com/sodium.jar' } }, undefined]
+    ])('maps %s', (_description, overrides, expected) => {
+      const actual = packwizModToMod(packwizMod(overrides));
+
+      expect(actual).toEqual(expected && { name: 'Sodium', ...expected });
+    });
+
+    it('uses the Modrinth project it was given', () => {
+      expect(packwizModToMod(packwizMod(), 'AANobbMI')).toEqual({
+        type: Platform.MODRINTH,
+        id: 'AANobbMI',
+        name: 'Sodium'
+      });
+    });
+  });
+
+  describe('when mapping the fixture pack', () => {
+    beforeEach(() => {
+      vi.mocked(getVersionsByHashes).mockImplementation(async (hashes) => {
+        return hashes.includes(lithiumHash)
+          ? { [lithiumHash]: generateModrinthVersion({ project_id: 'gvQqBUqZ' }).generated }
+          : {};
+      });
+      vi.mocked(getVersion).mockResolvedValueOnce(generateModrinthVersion({ project_id: 'YL57xq9U' }).generated);
+    });
+
+    it('produces the modlist entries of the pack', async () => {
+      const pack = await readPackwizPack(fixture('packwiz'));
+
+      const actual = await mapPackwizMods(pack.mods);
+
+      expect(actual.mods).toEqual([
+        { type: Platform.MODRINTH, id: 'AANobbMI', name: 'Sodium' },
+        { type: Platform.CURSEFORGE, id: '238222', name: 'Just Enough Items', pinned: true, forceFileId: '5101366' },
+        {
+          type: Platform.MODRINTH,
+          id: 'P7dR8mSH',
+          name: 'Fabric API',
+          fallback: { type: Platform.CURSEFORGE, id: '306612' }
+        },
+        { type: Platform.MODRINTH, id: 'gvQqBUqZ', name: 'Lithium' },
+        { type: Platform.MODRINTH, id: 'YL57xq9U', name: 'Iris Shaders' }
+      ]);
+      expect(actual.unmapped.map(({ mod, reason }) => [mod.name, reason])).toEqual([
+        ['Faithful 32x', "it isn't in the mods folder of the pack"],
+        ['Custom Mod', "it doesn't tell which Modrinth or Curseforge project it is"]
+      ]);
+    });
+
+    it('flags the mods it could not look up apart from the unknown ones', async () => {
+      vi.mocked(getVersionsByHashes).mockReset().mockRejectedValue(new Error('Bad Gateway'));
+      vi.mocked(getVersion).mockReset().mockRejectedValue(new TypeError('fetch failed'));
+      const pack = await readPackwizPack(fixture('packwiz'));
+
+      const actual = await mapPackwizMods(pack.mods);
+
+      expect(actual.mods).toHaveLength(3);
+      expect(actual.unmapped.map(({ mod, reason }) => [mod.name, reason])).toEqual([
+        ['Faithful 32x', "it isn't in the mods folder of the pack"],
+        ['Lithium', 'it could not be looked up on Modrinth: Bad Gateway'],
+        ['Iris Shaders', 'it could not be looked up on Modrinth: fetch failed'],
+        ['Custom Mod', "it doesn't tell which Modrinth or Curseforge project it is"]
+      ]);
+    });
+
+    it('looks up the ids it is missing on Modrinth', async () => {
+      const pack = await readPackwizPack(fixture('packwiz'));
+
+      await mapPackwizMods(pack.mods);
+
+      expect(getVersionsByHashes).toHaveBeenCalledTimes(2);
+      expect(getVersionsByHashes).toHaveBeenCalledWith([lithiumHash], 'sha1');
+      expect(getVersionsByHashes).toHaveBeenCalledWith([], 'sha512');
+      expect(getVersion).toHaveBeenCalledOnce();
+      expect(getVersion).toHaveBeenCalledWith('kuOV4Ece', 'kuOV4Ece');
+    });
+
+    it('flags the mods Modrinth does not know', async () => {
+      vi.mocked(getVersionsByHashes).mockReset().mockResolvedValue({});
+      vi.mocked(getVersion).mockReset().mockRejectedValue(new CouldNotFindModException('kuOV4Ece', Platform.MODRINTH));
+      const pack = await readPackwizPack(fixture('packwiz'));
+
+      const actual = await mapPackwizMods(pack.mods);
+
+      expect(actual.mods).toHaveLength(3);
+      expect(actual.unmapped.map(({ mod, reason }) => [mod.name, reason])).toEqual([
+        ['Faithful 32x', "it isn't in the mods folder of the pack"],
+        ['Lithium', "Modrinth doesn't know the file"],
+        ['Iris Shaders', "Modrinth doesn't know the version kuOV4Ece"],
+        ['Custom Mod', "it doesn't tell which Modrinth or Curseforge project it is"]
+      ]);
+    });
+  });
+
+  it('flags a Curseforge file without its project', async () => {
+    const mod = packwizMod({ curseforge: { fileId: '4593200' } });
+
+    const actual = await mapPackwizMods([mod]);
+
+    expect(actual).toEqual({
+      mods: [],
+      unmapped: [{ mod: mod, reason: 'only the Curseforge file 4593200 is known, not its project' }]
+    });
+  });
+});
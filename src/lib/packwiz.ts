@@ -0,0 +1,300 @@
+import fs from 'node:fs/promises';
+import path from 'node:path';
+import { CouldNotFindModException } from '../errors/CouldNotFindModException.js';
+import { PackwizPackInvalidException } from '../errors/PackwizPackInvalidException.js';
+import { findCause } from '../errors/findCause.js';
+import { getVersion } from '../repositories/modrinth/fetch.js';
+import { getVersionsByHashes } from '../repositories/modrinth/lookup.js';
+import { Loader, Mod, Platform } from './modlist.types.js';
+import { TomlTable, TomlValue, parseToml } from './toml.js';
+
+/**
+ * The files Modrinth serves have the project id in their path, like https://cdn.modrinth.com/data/AANobbMI/versions/...
+ */
+const modrinthDownloadPattern = /^https:\/\/cdn\.modrinth\.com\/data\/([A-Za-z0-9]+)\//;
+
+/**
+ * The hash formats of packwiz that Modrinth can find a file by
+ */
+const modrinthHashFormats = ['sha1', 'sha512'];
+
+/**
+ * A mod of the pack as its .pw.toml describes it
+ */
+export interface PackwizMod {
+  /**
+   * The path of the .pw.toml in the index, like mods/sodium.pw.toml
+   */
+  file: string;
+  name: string;
+  /**
+   * packwiz doesn't update the pinned mods
+   */
+  pinned: boolean;
+  download: {
+    url?: string;
+    hashFormat?: string;
+    hash?: string;
+  };
+  modrinth?: {
+    modId?: string;
+    version?: string;
+  };
+  curseforge?: {
+    projectId?: string;
+    fileId?: string;
+  };
+}
+
+export interface PackwizPack {
+  /**
+   * The pack.toml the pack was read from
+   */
+  file: string;
+  name: string;
+  gameVersion?: string;
+  /**
+   * Only there when the pack uses a loader the modlist knows
+   */
+  loader?: Loader;
+  mods: PackwizMod[];
+}
+
+export interface UnmappedPackwizMod {
+  mod: PackwizMod;
+  reason: string;
+}
+
+export interface PackwizImport {
+  mods: Mod[];
+  /**
+   * The mods that can't be told which Modrinth or Curseforge project they are, they have to be added by hand
+   */
+  unmapped: UnmappedPackwizMod[];
+}
+
+const tableOf = (value: TomlValue | undefined): TomlTable => {
+  return typeof value === 'object' && !Array.isArray(value) ? value : {};
+};
+
+/**
+ * packwiz writes the Curseforge ids as numbers, the modlist keeps every id as text
+ */
+const textOf = (value: TomlValue | undefined): string | undefined => {
+  return typeof value === 'string' || typeof value === 'number' ? String(value) : undefined;
+};
+
+const readToml = async (file: string): Promise<TomlTable> => {
+  try {
+    return parseToml(await fs.readFile(file, { encoding: 'utf8' }));
+  } catch (error) {
+    throw new PackwizPackInvalidException(file, (error as Error).message);
+  }
+};
+
+const readMod = async (indexFolder: string, file: string): Promise<PackwizMod> => {
+  const metadata = await readToml(path.resolve(indexFolder, file));
+  const download = tableOf(metadata.download);
+  const update = tableOf(metadata.update);
+  const modrinth = tableOf(update.modrinth);
+  const curseforge = tableOf(update.curseforge);
+
+  return {
+    file: file,
+    name: textOf(metadata.name) ?? path.basename(file, '.pw.toml'),
+    pinned: metadata.pin === true,
+    download: {
+      url: textOf(download.url),
+      hashFormat: textOf(download['hash-format']),
+      hash: textOf(download.hash)
+    },
+    modrinth:
+      update.modrinth === undefined
+        ? undefined
+        : { modId: textOf(modrinth['mod-id']), version: textOf(modrinth.version) },
+    curseforge:
+      update.curseforge === undefined
+        ? undefined
+        : { projectId: textOf(curseforge['project-id']), fileId: textOf(curseforge['file-id']) }
+  };
+};
+
+/**
+ * Reads the pack.toml, its index and the .pw.toml of every mod in the index.
+ * The files of the index that aren't metafiles, like the configs, are left out.
+ *
+ * @param packPath The pack.toml or the folder it is in
+ * @throws {PackwizPackInvalidException} When one of the files can't be read or isn't valid TOML
+ */
+export const readPackwizPack = async (packPath: string): Promise<PackwizPack> => {
+  const packFile = path.extname(packPath) === '.toml' ? path.resolve(packPath) : path.resolve(packPath, 'pack.toml');
+  const pack = await readToml(packFile);
+
+  const indexFile = path.resolve(path.dirname(packFile), textOf(tableOf(pack.index).file) ?? 'index.toml');
+  const index = await readToml(indexFile);
+  const entries = Array.isArray(index.files) ? index.files.map(tableOf) : [];
+  const metafiles = entries.flatMap((entry) => {
+    const file = textOf(entry.file);
+    return file !== undefined && (entry.metafile === true || file.endsWith('.pw.toml')) ? [file] : [];
+  });
+
+  const versions = tableOf(pack.versions);
+
+  return {
+    file: packFile,
+    name: textOf(pack.name) ?? path.basename(path.dirname(packFile)),
+    gameVersion: textOf(versions.minecraft),
+    loader: Object.values(Loader).find((loader) => versions[loader] !== undefined),
+    mods: await Promise.all(metafiles.map((file) => readMod(path.dirname(indexFile), file)))
+  };
+};
+
+const isInModsFolder = (mod: PackwizMod) => {
+  const folder = path.posix.dirname(mod.file);
+  return folder === 'mods' || folder.startsWith('mods/');
+};
+
+const toMod = (mod: PackwizMod, platform: Platform, id: string, fileId?: string): Mod => {
+  const result: Mod = {
+    type: platform,
+    id: id,
+    name: mod.name
+  };
+  if (mod.pinned && fileId) {
+    result.pinned = true;
+    result.forceFileId = fileId;
+  }
+  return result;
+};
+
+/**
+ * Turns the project the .pw.toml points at into a mod of the modlist.
+ * Modrinth comes first and the same mod on Curseforge becomes its fallback.
+ *
+ * @param modrinthId The Modrinth project when it was looked up, otherwise it's taken from the .pw.toml
+ * @returns undefined when the .pw.toml doesn't tell the project
+ */
+export const packwizModToMod = (
+  mod: PackwizMod,
+  modrinthId = mod.modrinth?.modId ?? modrinthDownloadPattern.exec(mod.download.url ?? '')?.[1]
+): Mod | undefined => {
+  const curseforgeId = mod.curseforge?.projectId;
+
+  if (modrinthId) {
+    const result = toMod(mod, Platform.MODRINTH, modrinthId, mod.modrinth?.version);
+    if (curseforgeId) {
+      result.fallback = { type: Platform.CURSEFORGE, id: curseforgeId };
+    }
+    return result;
+  }
+
+  if (curseforgeId) {
+    return toMod(mod, Platform.CURSEFORGE, curseforgeId, mod.curseforge?.fileId);
+  }
+
+  return undefined;
+};
+
+interface ModrinthLookup {
+  projectId?: string;
+  /**
+   * Why Modrinth could not be asked, the project may well be there
+   */
+  failure?: string;
+}
+
+const lookupFailure = (error: unknown): ModrinthLookup => {
+  return { failure: `it could not be looked up on Modrinth: ${(error as Error).message}` };
+};
+
+/**
+ * Looks the files up on Modrinth by their hashes, one request for every hash format
+ */
+const modrinthProjectsByHash = async (mods: PackwizMod[]): Promise<Record<string, ModrinthLookup>> => {
+  const lookups: Record<string, ModrinthLookup> = {};
+
+  for (const algorithm of modrinthHashFormats) {
+    const hashes = mods
+      .filter((mod) => mod.download.hashFormat === algorithm && mod.download.hash)
+      .map((mod) => mod.download.hash as string);
+    try {
+      const versions = await getVersionsByHashes(hashes, algorithm);
+      Object.entries(versions).forEach(([hash, version]) => {
+        lookups[hash] = { projectId: version.project_id };
+      });
+    } catch (error) {
+      hashes.forEach((hash) => {
+        lookups[hash] = lookupFailure(error);
+      });
+    }
+  }
+
+  return lookups;
+};
+
+const lookUpModrinthId = async (
+  mod: PackwizMod,
+  lookupsByHash: Record<string, ModrinthLookup>
+): Promise<ModrinthLookup> => {
+  const versionId = mod.modrinth?.version;
+  if (versionId) {
+    try {
+      return { projectId: (await getVersion(versionId, versionId)).project_id };
+    } catch (error) {
+      // Only an answer from Modrinth means it doesn't know the version
+      return findCause(error, CouldNotFindModException) ? {} : lookupFailure(error);
+    }
+  }
+  return (mod.download.hash && lookupsByHash[mod.download.hash]) || {};
+};
+
+const unmappedReason = (mod: PackwizMod) => {
+  if (mod.modrinth?.version) {
+    return `Modrinth doesn't know the version ${mod.modrinth.version}`;
+  }
+  if (mod.curseforge?.fileId) {
+    return `only the Curseforge file ${mod.curseforge.fileId} is known, not its project`;
+  }
+  if (mod.download.hash && modrinthHashFormats.includes(mod.download.hashFormat ?? '')) {
+    return "Modrinth doesn't know the file";
+  }
+  return "it doesn't tell which Modrinth or Curseforge project it is";
+};
+
+/**
+ * Maps the mods of the pack to the mods of the modlist.
+ * The ones without a project id are looked up on Modrinth by their version or their hash,
+ * the ones still unknown and the ones that aren't mods, like the resource packs, are returned as unmapped.
+ */
+export const mapPackwizMods = async (mods: PackwizMod[]): Promise<PackwizImport> => {
+  const unmapped: UnmappedPackwizMod[] = mods
+    .filter((mod) => !isInModsFolder(mod))
+    .map((mod) => ({ mod: mod, reason: "it isn't in the mods folder of the pack" }));
+
+  const candidates = mods.filter(isInModsFolder);
+  const lookupsByHash = await modrinthProjectsByHash(
+    candidates.filter((mod) => packwizModToMod(mod) === undefined && !mod.modrinth?.version)
+  );
+
+  const mapped: Mod[] = [];
+  for (const mod of candidates) {
+    const known = packwizModToMod(mod);
+    if (known) {
+      mapped.push(known);
+      continue;
+    }
+
+    const lookup = await lookUpModrinthId(mod, lookupsByHash);
+    const result = packwizModToMod(mod, lookup.projectId);
+    if (result) {
+      mapped.push(result);
+    } else {
+      unmapped.push({ mod: mod, reason: lookup.failure ?? unmappedReason(mod) });
+    }
+  }
+
+  return {
+    mods: mapped,
+    unmapped: unmapped
+  };
+};
@@ -0,0 +1,111 @@
+import { afterEach, describe, expect, it, vi } from 'vitest';
+import {
+  ProgressEvent,
+  downloadedPercentage,
+  formatProgress,
+  getProgress,
+  lineProgress,
+  noopProgress,
+  setProgress
+} from './progress.js';
+
+const downloading = (downloadedBytes: number, totalBytes?: number, fileName = 'sodium.jar'): ProgressEvent => ({
+  type: 'downloading',
+  fileName: fileName,
+  downloadedBytes: downloadedBytes,
+  totalBytes: totalBytes
+});
+
+describe('The progress', () => {
+  afterEach(() => {
+    setProgress();
+  });
+
+  it('ignores the events by default', () => {
+    expect(getProgress()).toBe(noopProgress);
+    expect(() => getProgress().report(downloading(1, 2))).not.toThrow();
+  });
+
+  it('can be set and reset', () => {
+    const progress = { report: vi.fn() };
+
+    setProgress(progress);
+    expect(getProgress()).toBe(progress);
+
+    setProgress();
+    expect(getProgress()).toBe(noopProgress);
+  });
+
+  it.each([
+    [0, 200, 0],
+    [90, 200, 45],
+    [199, 200, 99],
+    [200, 200, 100],
+    [300, 200, 100],
+    [10, undefined, undefined],
+    [10, 0, undefined]
+  ])('makes %j of %j bytes %j percent', (downloadedBytes, totalBytes, expected) => {
+    expect(downloadedPercentage(downloadedBytes, totalBytes)).toEqual(expected);
+  });
+
+  it.each<[ProgressEvent, string]>([
+    [{ type: 'resolving', name: 'Sodium', current: 12, total: 200 }, 'resolving mod 12/200 (Sodium)'],
+    [downloading(90, 200), 'downloading sodium.jar (45%)'],
+    [downloading(1024), 'downloading sodium.jar (1024 bytes)'],
+    [{ type: 'verifying', fileName: 'sodium.jar' }, 'verifying sodium.jar']
+  ])('shows %j as %j', (event, expected) => {
+    expect(formatProgress(event)).toEqual(expected);
+  });
+
+  describe('when writing lines', () => {
+    it('writes every event that is not a download', () => {
+      const write = vi.fn();
+      const progress = lineProgress(write);
+
+      progress.report({ type: 'resolving', name: 'Sodium', current: 1, total: 2 });
+      progress.report({ type: 'resolving', name: 'Lithium', current: 2, total: 2 });
+      progress.report({ type: 'verifying', fileName: 'sodium.jar' });
+
+      expect(write.mock.calls).toEqual([
+        ['resolving mod 1/2 (Sodium)'],
+        ['resolving mod 2/2 (Lithium)'],
+        ['verifying sodium.jar']
+      ]);
+    });
+
+    it('only writes a download when it got another tenth of the way', () => {
+      const write = vi.fn();
+      const progress = lineProgress(write);
+
+      [1, 5, 9, 10, 15, 35, 99, 100].forEach((downloadedBytes) => progress.report(downloading(downloadedBytes, 100)));
+
+      expect(write.mock.calls).toEqual([
+        ['downloading sodium.jar (1%)'],
+        ['downloading sodium.jar (10%)'],
+        ['downloading sodium.jar (35%)'],
+        ['downloading sodium.jar (99%)'],
+        ['downloading sodium.jar (100%)']
+      ]);
+    });
+
+    it('follows the downloads separately', () => {
+      const write = vi.fn();
+      const progress = lineProgress(write);
+
+      progress.report(downloading(5, 100, 'sodium.jar'));
+      progress.report(downloading(5, 100, 'lithium.jar'));
+
+      expect(write).toHaveBeenCalledTimes(2);
+    });
+
+    it('writes a download of an unknown size once', () => {
+      const write = vi.fn();
+      const progress = lineProgress(write);
+
+      progress.report(downloading(10));
+      progress.report(downloading(20));
+
+      expect(write.mock.calls).toEqual([['downloading sodium.jar (10 bytes)']]);
+    });
+  });
+});
@@ -0,0 +1,104 @@
+/**
+ * What the long operations tell about how far they got
+ */
+export type ProgressEvent =
+  | {
+      type: 'resolving';
+      /**
+       * The mod that was just resolved
+       */
+      name: string;
+      /**
+       * How many of the mods are resolved so far, this one included
+       */
+      current: number;
+      total: number;
+    }
+  | {
+      type: 'downloading';
+      fileName: string;
+      downloadedBytes: number;
+      /**
+       * Only there when the size of the file is known
+       */
+      totalBytes?: number;
+    }
+  | {
+      type: 'verifying';
+      fileName: string;
+    };
+
+/**
+ * Receives the events of the resolve and the download layers, the ones that don't care can leave it unset.
+ */
+export interface Progress {
+  report(event: ProgressEvent): void;
+}
+
+export const noopProgress: Progress = {
+  report: () => {
+    //
+  }
+};
+
+let progress: Progress = noopProgress;
+
+/**
+ * Sets where the progress events go.
+ * Calling it without a progress goes back to the default, which ignores them.
+ */
+export const setProgress = (receiver?: Progress) => {
+  progress = receiver ?? noopProgress;
+};
+
+export const getProgress = () => progress;
+
+/**
+ * The whole percentage of the file that is downloaded, undefined when the size of the file isn't known
+ */
+export const downloadedPercentage = (downloadedBytes: number, totalBytes?: number): number | undefined => {
+  if (!totalBytes) {
+    return undefined;
+  }
+  return Math.min(100, Math.floor((downloadedBytes / totalBytes) * 100));
+};
+
+/**
+ * Writes an event the way it's shown to the user, like: downloading sodium.jar (45%)
+ */
+export const formatProgress = (event: ProgressEvent): string => {
+  switch (event.type) {
+    case 'resolving':
+      return `resolving mod ${event.current}/${event.total} (${event.name})`;
+    case 'downloading': {
+      const percentage = downloadedPercentage(event.downloadedBytes, event.totalBytes);
+      return percentage === undefined
+        ? `downloading ${event.fileName} (${event.downloadedBytes} bytes)`
+        : `downloading ${event.fileName} (${percentage}%)`;
+    }
+    case 'verifying':
+      return `verifying ${event.fileName}`;
+  }
+};
+
+/**
+ * A progress that hands the events as lines to the given function.
+ * A download is only written when it got at least another tenth of the way, so a big file doesn't flood the output.
+ */
+export const lineProgress = (write: (line: string) => void): Progress => {
+  const writtenSteps = new Map<string, number>();
+
+  return {
+    report: (event) => {
+      if (event.type === 'downloading') {
+        const percentage = downloadedPercentage(event.downloadedBytes, event.totalBytes);
+        const step = percentage === undefined ? 0 : Math.floor(percentage / 10);
+        if (writtenSteps.get(event.fileName) === step) {
+          return;
+        }
+        writtenSteps.set(event.fileName, step);
+      }
+      write(formatProgress(event));
+    }
+  };
+};
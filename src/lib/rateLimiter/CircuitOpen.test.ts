@@ -0,0 +1,15 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { CircuitOpen } from './CircuitOpen.js';
+
+describe('The circuit open exception', () => {
+  it('can return the host and when it can be tried again', () => {
+    const host = chance.domain();
+
+    const error = new CircuitOpen(host, 29001);
+
+    expect(error.host()).toEqual(host);
+    expect(error.retryIn()).toEqual(29001);
+    expect(error.message).toEqual(`${host} failed too many times in a row, the requests to it are paused for 30s`);
+  });
+});
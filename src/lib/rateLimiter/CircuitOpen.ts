@@ -0,0 +1,20 @@
+export class CircuitOpen extends Error {
+  private readonly circuitHost: string;
+  private readonly retryInMs: number;
+  constructor(host: string, retryIn: number) {
+    super(`${host} failed too many times in a row, the requests to it are paused for ${Math.ceil(retryIn / 1000)}s`);
+    this.circuitHost = host;
+    this.retryInMs = retryIn;
+  }
+
+  host() {
+    return this.circuitHost;
+  }
+
+  /**
+   * How long in milliseconds until a request can try the host again
+   */
+  retryIn() {
+    return this.retryInMs;
+  }
+}
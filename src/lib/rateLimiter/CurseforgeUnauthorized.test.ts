@@ -0,0 +1,18 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { CurseforgeUnauthorized } from './CurseforgeUnauthorized.js';
+
+describe('The Curseforge unauthorized exception', () => {
+  it('can return the url and the status', () => {
+    const url = chance.url();
+    const status = chance.pickone([401, 403]);
+
+    const error = new CurseforgeUnauthorized(url, status);
+
+    expect(error.url()).toEqual(url);
+    expect(error.status()).toEqual(status);
+    expect(error.message).toEqual(
+      `Curseforge refused the request to ${url} with ${status}, the CURSEFORGE_API_KEY is missing or invalid`
+    );
+  });
+});
@@ -0,0 +1,17 @@
+export class CurseforgeUnauthorized extends Error {
+  private readonly requestUrl: string;
+  private readonly responseStatus: number;
+  constructor(url: string, status: number) {
+    super(`Curseforge refused the request to ${url} with ${status}, the CURSEFORGE_API_KEY is missing or invalid`);
+    this.requestUrl = url;
+    this.responseStatus = status;
+  }
+
+  url() {
+    return this.requestUrl;
+  }
+
+  status() {
+    return this.responseStatus;
+  }
+}
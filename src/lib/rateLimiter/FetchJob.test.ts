@@ -1,15 +1,39 @@
 import { chance } from 'jest-chance';
-import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { RecordingApiLogger, recordingApiLogger } from '../../../test/recordingApiLogger.js';
+import { setApiLogger } from '../apiLogger.js';
+import { setBaseUrl } from '../baseUrl.js';
+import { Platform } from '../modlist.types.js';
+import { CircuitOpen } from './CircuitOpen.js';
+import { CurseforgeUnauthorized } from './CurseforgeUnauthorized.js';
 import { FetchJob } from './FetchJob.js';
 import { MaximumRetriesReached } from './MaximumRetriesReached.js';
+import { RateLimited } from './RateLimited.js';
+import { RequestTimedOut } from './RequestTimedOut.js';
 import { Retrying } from './Retrying.js';
+import { RetryingOnError } from './RetryingOnError.js';
+import { getCurseforgeApiKey, setCurseforgeApiKey } from './apiKeys.js';
+import { Backoff } from './backoff.js';
+import { setCircuitBreaker } from './circuitBreaker.js';
 import { RateLimit } from './index.js';
+import { remainingRetries, setRetryBudget } from './retryBudget.js';
+import { defaultUserAgent, setUserAgent } from './userAgent.js';
 
 interface LocalTestContext {
   testRateLimit: RateLimit;
   randomDomain: string;
 }
 
+// Behaves like a server that accepted the connection but never answers
+const assumeStalledConnection = () => {
+  vi.mocked(fetch).mockImplementationOnce(
+    (_input, init) =>
+      new Promise((_resolve, reject) => {
+        init?.signal?.addEventListener('abort', () => reject(init.signal?.reason), { once: true });
+      })
+  );
+};
+
 describe('The FetchJob class', () => {
   beforeEach<LocalTestContext>((context) => {
     vi.resetAllMocks();
@@ -35,6 +59,12 @@ describe('The FetchJob class', () => {
     expect(job.retryIn()).toEqual(testRateLimit.timeBetweenCalls);
   });
 
+  it<LocalTestContext>('is not throttled before it runs', ({ randomDomain, testRateLimit }) => {
+    const job = new FetchJob(randomDomain, {}, testRateLimit);
+
+    expect(job.isThrottled()).toBe(false);
+  });
+
   it<LocalTestContext>('returns on a successful fetch', async ({ randomDomain, testRateLimit }) => {
     const randomResponse = {
       ok: true,
@@ -52,6 +82,116 @@ describe('The FetchJob class', () => {
     expect(actual).toBe(randomResponse);
   });
 
+  describe('when sending the request', () => {
+    beforeEach(() => {
+      vi.mocked(fetch).mockResolvedValueOnce({
+        ok: true,
+        headers: {
+          has: vi.fn().mockReturnValue(false)
+        }
+      } as unknown as Response);
+    });
+
+    afterEach(() => {
+      setUserAgent();
+      setCurseforgeApiKey();
+      setBaseUrl(Platform.CURSEFORGE);
+    });
+
+    const sentHeaders = () => new Headers(vi.mocked(fetch).mock.calls[0][1]?.headers);
+
+    it<LocalTestContext>('sets the default user agent', async ({ randomDomain, testRateLimit }) => {
+      await new FetchJob(randomDomain, {}, testRateLimit).execute();
+
+      expect(sentHeaders().get('user-agent')).toEqual(defaultUserAgent);
+    });
+
+    it<LocalTestContext>('sets the configured user agent', async ({ randomDomain, testRateLimit }) => {
+      const randomUserAgent = chance.word();
+      setUserAgent(randomUserAgent);
+
+      await new FetchJob(randomDomain, {}, testRateLimit).execute();
+
+      expect(sentHeaders().get('user-agent')).toEqual(randomUserAgent);
+    });
+
+    it<LocalTestContext>('does not overwrite the user agent of the caller', async ({ randomDomain, testRateLimit }) => {
+      const randomUserAgent = chance.word();
+
+      await new FetchJob(randomDomain, { headers: { 'User-Agent': randomUserAgent } }, testRateLimit).execute();
+
+      expect(sentHeaders().get('user-agent')).toEqual(randomUserAgent);
+    });
+
+    it<LocalTestContext>('keeps the rest of the request intact', async ({ randomDomain, testRateLimit }) => {
+      const randomKey = chance.hash();
+
+      await new FetchJob(
+        randomDomain,
+        { method: 'POST', body: randomKey, headers: { 'x-api-key': randomKey } },
+        testRateLimit
+      ).execute();
+
+      const init = vi.mocked(fetch).mock.calls[0][1];
+      expect(init?.method).toEqual('POST');
+      expect(init?.body).toEqual(randomKey);
+      expect(sentHeaders().get('x-api-key')).toEqual(randomKey);
+    });
+
+    it<LocalTestContext>('sets the api key for Curseforge requests', async ({ testRateLimit }) => {
+      await new FetchJob('https://api.curseforge.com/v1/mods/' + chance.word(), {}, testRateLimit).execute();
+
+      expect(sentHeaders().get('x-api-key')).toEqual(getCurseforgeApiKey());
+    });
+
+    it<LocalTestContext>('sets the configured api key for Curseforge requests', async ({ testRateLimit }) => {
+      const randomKey = chance.hash();
+      setCurseforgeApiKey(randomKey);
+
+      await new FetchJob('https://api.curseforge.com/v1/fingerprints', {}, testRateLimit).execute();
+
+      expect(sentHeaders().get('x-api-key')).toEqual(randomKey);
+    });
+
+    it<LocalTestContext>('does not overwrite the api key of the caller', async ({ testRateLimit }) => {
+      const randomKey = chance.hash();
+      setCurseforgeApiKey(chance.hash());
+
+      await new FetchJob(
+        'https://api.curseforge.com/v1/fingerprints',
+        { headers: { 'x-api-key': randomKey } },
+        testRateLimit
+      ).execute();
+
+      expect(sentHeaders().get('x-api-key')).toEqual(randomKey);
+    });
+
+    it<LocalTestContext>('sets the api key for the base url Curseforge was moved to', async ({ testRateLimit }) => {
+      setBaseUrl(Platform.CURSEFORGE, 'https://cf-proxy.example.com:8443/v1');
+
+      await new FetchJob('https://cf-proxy.example.com:8443/v1/mods/1', {}, testRateLimit).execute();
+
+      expect(sentHeaders().get('x-api-key')).toEqual(getCurseforgeApiKey());
+    });
+
+    it<LocalTestContext>('does not send the Curseforge api key to other hosts', async ({ testRateLimit }) => {
+      setCurseforgeApiKey(chance.hash());
+
+      await new FetchJob('https://api.modrinth.com/v2/project/' + chance.word(), {}, testRateLimit).execute();
+
+      expect(sentHeaders().has('x-api-key')).toBeFalsy();
+    });
+
+    it<LocalTestContext>('respects the headers of a request object', async ({ randomDomain, testRateLimit }) => {
+      const randomUserAgent = chance.word();
+      const request = new Request(randomDomain, { headers: { 'user-agent': randomUserAgent } });
+
+      await new FetchJob(request, {}, testRateLimit).execute();
+
+      expect(sentHeaders().get('user-agent')).toEqual(randomUserAgent);
+    });
+  });
+
   it<LocalTestContext>('rejects properly', async ({ randomDomain, testRateLimit }) => {
     const randomReason = chance.word();
 
@@ -62,6 +202,85 @@ describe('The FetchJob class', () => {
     await expect(job.execute()).rejects.toThrow(randomReason);
   });
 
+  it<LocalTestContext>('retries transient network errors', async ({ randomDomain, testRateLimit }) => {
+    const networkError = new TypeError('fetch failed', { cause: Object.assign(new Error(), { code: 'ECONNRESET' }) });
+    const handler = vi.fn();
+
+    vi.mocked(fetch).mockRejectedValue(networkError);
+
+    const job = new FetchJob(randomDomain, {}, testRateLimit);
+    job.onError(handler);
+
+    await expect(job.execute()).rejects.toThrow(RetryingOnError); //attempt 1
+    await expect(job.execute()).rejects.toThrow(RetryingOnError); //attempt 2
+    expect(handler).not.toHaveBeenCalled();
+
+    await expect(job.execute()).rejects.toThrow(networkError); //attempt 3
+    expect(handler).toHaveBeenCalledWith(networkError);
+  });
+
+  describe('when the server stops answering', () => {
+    it<LocalTestContext>('gives every attempt a timeout', async ({ randomDomain, testRateLimit }) => {
+      vi.mocked(fetch).mockResolvedValueOnce({ ok: true, headers: new Headers() } as Response);
+
+      await new FetchJob(randomDomain, {}, testRateLimit).execute();
+
+      const signal = vi.mocked(fetch).mock.calls[0][1]?.signal;
+      expect(signal).toBeInstanceOf(AbortSignal);
+      expect(signal?.aborted).toBeFalsy();
+    });
+
+    it<LocalTestContext>('cuts off the request after the timeout', async ({ randomDomain }) => {
+      const handler = vi.fn();
+      assumeStalledConnection();
+
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 1, timeBetweenCalls: 0, timeout: 20 });
+      job.onError(handler);
+
+      const startedAt = performance.now();
+      await expect(job.execute()).rejects.toThrow(new RequestTimedOut(new Request(randomDomain).url, 20));
+      expect(performance.now() - startedAt).toBeLessThan(1000);
+      expect(handler).toHaveBeenCalledWith(expect.any(RequestTimedOut));
+    });
+
+    it<LocalTestContext>('retries the attempts that timed out', async ({ randomDomain }) => {
+      assumeStalledConnection();
+
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 3, timeBetweenCalls: 0, timeout: 20 });
+
+      await expect(job.execute()).rejects.toThrow(RetryingOnError);
+    });
+
+    it<LocalTestContext>('does not mistake an abort for a timeout', async ({ randomDomain }) => {
+      const controller = new AbortController();
+      const reason = new DOMException('This operation was aborted', 'AbortError');
+      assumeStalledConnection();
+
+      const job = new FetchJob(
+        randomDomain,
+        { signal: controller.signal },
+        { maxAttempts: 3, timeBetweenCalls: 0, timeout: 1000 }
+      );
+      const execution = job.execute();
+      controller.abort(reason);
+
+      await expect(execution).rejects.toThrow(reason);
+    });
+  });
+
+  it<LocalTestContext>('does not retry aborted requests', async ({ randomDomain, testRateLimit }) => {
+    const abortError = new DOMException('This operation was aborted', 'AbortError');
+    const handler = vi.fn();
+
+    vi.mocked(fetch).mockRejectedValueOnce(abortError);
+
+    const job = new FetchJob(randomDomain, {}, testRateLimit);
+    job.onError(handler);
+
+    await expect(job.execute()).rejects.toThrow(abortError);
+    expect(handler).toHaveBeenCalledWith(abortError);
+  });
+
   it<LocalTestContext>('calls the response handler on success', async ({ randomDomain, testRateLimit }) => {
     const randomResponse = {
       ok: true,
@@ -170,6 +389,76 @@ describe('The FetchJob class', () => {
     expect(handler).toHaveBeenCalledWith(new MaximumRetriesReached(randomResponse));
   });
 
+  describe('when a server error is retried', () => {
+    // A response whose body tells if it was let go
+    const trackedResponse = (status: number) => {
+      const body = { cancel: vi.fn().mockResolvedValue(undefined) };
+      const response = {
+        ok: status < 400,
+        status: status,
+        body: body,
+        bodyUsed: false,
+        headers: {
+          has: vi.fn().mockReturnValue(false),
+          get: vi.fn()
+        }
+      } as unknown as Response;
+      return { response: response, body: body };
+    };
+
+    it<LocalTestContext>('closes the bodies of the responses it retries', async ({ randomDomain }) => {
+      const first = trackedResponse(502);
+      const second = trackedResponse(503);
+      const last = trackedResponse(200);
+      vi.mocked(fetch)
+        .mockResolvedValueOnce(first.response)
+        .mockResolvedValueOnce(second.response)
+        .mockResolvedValueOnce(last.response);
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 3, timeBetweenCalls: 0 });
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+      await expect(job.execute()).rejects.toThrow(Retrying);
+      const actual = await job.execute();
+
+      expect(first.body.cancel).toHaveBeenCalledOnce();
+      expect(second.body.cancel).toHaveBeenCalledOnce();
+      expect(last.body.cancel).not.toHaveBeenCalled();
+      expect(actual).toBe(last.response);
+    });
+
+    it<LocalTestContext>('keeps the body of the response it gives up on', async ({ randomDomain }) => {
+      const first = trackedResponse(500);
+      const last = trackedResponse(500);
+      vi.mocked(fetch).mockResolvedValueOnce(first.response).mockResolvedValueOnce(last.response);
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 2, timeBetweenCalls: 0 });
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+      await expect(job.execute()).rejects.toThrow(MaximumRetriesReached);
+
+      expect(first.body.cancel).toHaveBeenCalledOnce();
+      expect(last.body.cancel).not.toHaveBeenCalled();
+    });
+
+    it<LocalTestContext>('retries when the body can not be closed', async ({ randomDomain }) => {
+      const failed = trackedResponse(500);
+      failed.body.cancel.mockRejectedValueOnce(new TypeError('The stream is locked'));
+      vi.mocked(fetch).mockResolvedValueOnce(failed.response);
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 2, timeBetweenCalls: 0 });
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+    });
+
+    it<LocalTestContext>('leaves a body that was read already', async ({ randomDomain }) => {
+      const failed = trackedResponse(500);
+      vi.mocked(fetch).mockResolvedValueOnce({ ...failed.response, bodyUsed: true } as Response);
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 2, timeBetweenCalls: 0 });
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+
+      expect(failed.body.cancel).not.toHaveBeenCalled();
+    });
+  });
+
   it<LocalTestContext>('sets the retry time to the rate limit time', async ({ randomDomain }) => {
     const randomResponse = {
       ok: false,
@@ -197,6 +486,7 @@ describe('The FetchJob class', () => {
     await expect(job.execute()).rejects.toThrow(Retrying);
 
     expect(job.retryIn()).toEqual(11000);
+    expect(job.isThrottled()).toBe(true);
 
     expect(vi.mocked(randomResponse.headers.has)).toHaveBeenCalledWith('X-Ratelimit-Remaining');
     expect(vi.mocked(randomResponse.headers.get)).toHaveBeenNthCalledWith(1, 'X-Ratelimit-Remaining');
@@ -264,4 +554,601 @@ describe('The FetchJob class', () => {
     expect(vi.mocked(randomResponse.headers.get)).toHaveBeenNthCalledWith(1, 'X-Ratelimit-Remaining');
     expect(vi.mocked(randomResponse.headers.get)).toHaveBeenNthCalledWith(2, 'X-Ratelimit-Reset');
   });
+
+  it<LocalTestContext>('backs off exponentially between retries', async ({ randomDomain }) => {
+    const randomResponse = {
+      ok: false,
+      headers: {
+        has: vi.fn().mockReturnValue(false),
+        get: vi.fn()
+      }
+    } as unknown as Response;
+
+    vi.mocked(fetch).mockResolvedValue(randomResponse);
+
+    const job = new FetchJob(
+      randomDomain,
+      {},
+      {
+        timeBetweenCalls: 1000,
+        maxAttempts: 4,
+        backoff: Backoff.EXPONENTIAL,
+        maxInterval: 3000
+      }
+    );
+
+    await expect(job.execute()).rejects.toThrow(Retrying);
+    expect(job.retryIn()).toEqual(1000);
+
+    await expect(job.execute()).rejects.toThrow(Retrying);
+    expect(job.retryIn()).toEqual(2000);
+
+    await expect(job.execute()).rejects.toThrow(Retrying);
+    expect(job.retryIn()).toEqual(3000);
+  });
+
+  it<LocalTestContext>('does not retry a not modified response', async ({ randomDomain, testRateLimit }) => {
+    const notModified = {
+      ok: false,
+      status: 304,
+      headers: {
+        has: vi.fn().mockReturnValue(false)
+      }
+    } as unknown as Response;
+    vi.mocked(fetch).mockResolvedValueOnce(notModified);
+
+    const actual = await new FetchJob(randomDomain, {}, testRateLimit).execute();
+
+    expect(actual).toBe(notModified);
+  });
+
+  describe('when curseforge does not accept the api key', () => {
+    afterEach(() => {
+      setBaseUrl(Platform.CURSEFORGE);
+    });
+
+    it.each([401, 403])('fails with %i without retrying', async (status) => {
+      const url = 'https://api.curseforge.com/v1/mods/1';
+      const handler = vi.fn();
+      vi.mocked(fetch).mockResolvedValue({
+        ok: false,
+        status: status,
+        headers: {
+          has: vi.fn().mockReturnValue(false),
+          get: vi.fn()
+        }
+      } as unknown as Response);
+
+      const job = new FetchJob(url, {}, { maxAttempts: 3, timeBetweenCalls: 0 });
+      job.onError(handler);
+
+      await expect(job.execute()).rejects.toThrow(new CurseforgeUnauthorized(url, status));
+      expect(handler).toHaveBeenCalledWith(expect.any(CurseforgeUnauthorized));
+      expect(vi.mocked(fetch)).toHaveBeenCalledTimes(1);
+    });
+
+    it('fails without retrying for the base url Curseforge was moved to', async () => {
+      const url = 'https://cf-proxy.example.com:8443/v1/mods/1';
+      setBaseUrl(Platform.CURSEFORGE, 'https://cf-proxy.example.com:8443/v1');
+      vi.mocked(fetch).mockResolvedValue({
+        ok: false,
+        status: 401,
+        headers: {
+          has: vi.fn().mockReturnValue(false),
+          get: vi.fn()
+        }
+      } as unknown as Response);
+
+      const job = new FetchJob(url, {}, { maxAttempts: 3, timeBetweenCalls: 0 });
+
+      await expect(job.execute()).rejects.toThrow(new CurseforgeUnauthorized(url, 401));
+      expect(vi.mocked(fetch)).toHaveBeenCalledTimes(1);
+    });
+
+    it<LocalTestContext>('retries the same status from other hosts', async ({ randomDomain }) => {
+      vi.mocked(fetch).mockResolvedValue({
+        ok: false,
+        status: 403,
+        headers: {
+          has: vi.fn().mockReturnValue(false),
+          get: vi.fn()
+        }
+      } as unknown as Response);
+
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 3, timeBetweenCalls: 0 });
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+    });
+  });
+
+  describe('when the run has a retry budget', () => {
+    const failedResponse = {
+      ok: false,
+      status: 500,
+      headers: {
+        has: vi.fn().mockReturnValue(false)
+      }
+    } as unknown as Response;
+
+    beforeEach(() => {
+      // All the failures go to the same host, the circuit breaker would stop them before the budget does
+      setCircuitBreaker({ failureThreshold: 0, cooldown: 0 });
+    });
+
+    afterEach(() => {
+      setRetryBudget();
+      setCircuitBreaker();
+    });
+
+    it<LocalTestContext>('shares the retries between the requests', async ({ randomDomain }) => {
+      setRetryBudget(3);
+      vi.mocked(fetch).mockResolvedValue(failedResponse);
+      const rateLimit = { maxAttempts: 3, timeBetweenCalls: 0 };
+      const first = new FetchJob(randomDomain, {}, rateLimit);
+      const second = new FetchJob(randomDomain, {}, rateLimit);
+      const third = new FetchJob(randomDomain, {}, rateLimit);
+
+      await expect(first.execute()).rejects.toThrow(Retrying);
+      await expect(first.execute()).rejects.toThrow(Retrying);
+      await expect(first.execute()).rejects.toThrow(MaximumRetriesReached);
+      await expect(second.execute()).rejects.toThrow(Retrying);
+      expect(remainingRetries()).toEqual(0);
+
+      await expect(second.execute()).rejects.toThrow(MaximumRetriesReached);
+      await expect(third.execute()).rejects.toThrow(MaximumRetriesReached);
+      expect(vi.mocked(fetch)).toHaveBeenCalledTimes(6);
+    });
+
+    it<LocalTestContext>('fails fast on a network error once the budget is used up', async ({ randomDomain }) => {
+      setRetryBudget(0);
+      const networkError = new TypeError('fetch failed', {
+        cause: Object.assign(new Error(), { code: 'ECONNRESET' })
+      });
+      const handler = vi.fn();
+      vi.mocked(fetch).mockRejectedValueOnce(networkError);
+
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 3, timeBetweenCalls: 0 });
+      job.onError(handler);
+
+      await expect(job.execute()).rejects.toThrow(networkError);
+      expect(handler).toHaveBeenCalledWith(networkError);
+    });
+
+    it<LocalTestContext>('fails fast on a timeout once the budget is used up', async ({ randomDomain }) => {
+      setRetryBudget(0);
+      assumeStalledConnection();
+
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 3, timeBetweenCalls: 0, timeout: 20 });
+
+      await expect(job.execute()).rejects.toThrow(new RequestTimedOut(new Request(randomDomain).url, 20));
+    });
+
+    it<LocalTestContext>('keeps the budget for the failures', async ({ randomDomain, testRateLimit }) => {
+      setRetryBudget(1);
+      vi.mocked(fetch).mockResolvedValueOnce({
+        ok: true,
+        headers: {
+          has: vi.fn().mockReturnValue(false)
+        }
+      } as unknown as Response);
+
+      await new FetchJob(randomDomain, {}, testRateLimit).execute();
+
+      expect(remainingRetries()).toEqual(1);
+    });
+  });
+
+  describe('when the host keeps failing', () => {
+    const response = (status: number) =>
+      ({
+        ok: status < 400,
+        status: status,
+        headers: {
+          has: vi.fn().mockReturnValue(false)
+        }
+      }) as unknown as Response;
+
+    const rateLimit = { maxAttempts: 1, timeBetweenCalls: 0 };
+
+    beforeEach(() => {
+      setCircuitBreaker({ failureThreshold: 2, cooldown: 10000 });
+    });
+
+    afterEach(() => {
+      setCircuitBreaker();
+    });
+
+    it<LocalTestContext>('stops sending the requests after the server errors', async ({ randomDomain }) => {
+      const handler = vi.fn();
+      vi.mocked(fetch).mockResolvedValue(response(503));
+
+      await expect(new FetchJob(randomDomain, {}, rateLimit).execute()).rejects.toThrow(MaximumRetriesReached);
+      await expect(new FetchJob(randomDomain, {}, rateLimit).execute()).rejects.toThrow(MaximumRetriesReached);
+
+      const job = new FetchJob(randomDomain, {}, rateLimit);
+      job.onError(handler);
+
+      await expect(job.execute()).rejects.toThrow(CircuitOpen);
+      expect(handler).toHaveBeenCalledWith(expect.any(CircuitOpen));
+      expect(fetch).toHaveBeenCalledTimes(2);
+    });
+
+    it<LocalTestContext>('stops the retries of a request too', async ({ randomDomain }) => {
+      vi.mocked(fetch).mockResolvedValue(response(500));
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 3, timeBetweenCalls: 0 });
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+      await expect(job.execute()).rejects.toThrow(Retrying);
+      await expect(job.execute()).rejects.toThrow(CircuitOpen);
+      expect(fetch).toHaveBeenCalledTimes(2);
+    });
+
+    it<LocalTestContext>('counts the network errors', async ({ randomDomain }) => {
+      const networkError = new TypeError('fetch failed', {
+        cause: Object.assign(new Error(), { code: 'ECONNREFUSED' })
+      });
+      vi.mocked(fetch).mockRejectedValue(networkError);
+
+      await expect(new FetchJob(randomDomain, {}, rateLimit).execute()).rejects.toThrow(networkError);
+      await expect(new FetchJob(randomDomain, {}, rateLimit).execute()).rejects.toThrow(networkError);
+
+      await expect(new FetchJob(randomDomain, {}, rateLimit).execute()).rejects.toThrow(CircuitOpen);
+    });
+
+    it<LocalTestContext>('counts the timeouts', async ({ randomDomain }) => {
+      assumeStalledConnection();
+      assumeStalledConnection();
+      const stalling = { ...rateLimit, timeout: 20 };
+
+      await expect(new FetchJob(randomDomain, {}, stalling).execute()).rejects.toThrow(RequestTimedOut);
+      await expect(new FetchJob(randomDomain, {}, stalling).execute()).rejects.toThrow(RequestTimedOut);
+
+      await expect(new FetchJob(randomDomain, {}, stalling).execute()).rejects.toThrow(CircuitOpen);
+    });
+
+    it<LocalTestContext>('does not count the errors of the request itself', async ({ randomDomain }) => {
+      vi.mocked(fetch).mockResolvedValueOnce(response(500));
+      vi.mocked(fetch).mockResolvedValueOnce(response(404));
+      vi.mocked(fetch).mockResolvedValueOnce(response(500));
+      vi.mocked(fetch).mockResolvedValueOnce(response(200));
+
+      await expect(new FetchJob(randomDomain, {}, rateLimit).execute()).rejects.toThrow(MaximumRetriesReached);
+      await expect(new FetchJob(randomDomain, {}, rateLimit).execute()).rejects.toThrow(MaximumRetriesReached);
+      await expect(new FetchJob(randomDomain, {}, rateLimit).execute()).rejects.toThrow(MaximumRetriesReached);
+
+      await expect(new FetchJob(randomDomain, {}, rateLimit).execute()).resolves.toBeDefined();
+    });
+  });
+
+  describe('when the request is cancelled', () => {
+    it<LocalTestContext>('does not send an already cancelled request', async ({ randomDomain, testRateLimit }) => {
+      const errorCallback = vi.fn();
+      const signal = AbortSignal.abort();
+      const job = new FetchJob(randomDomain, { signal: signal }, testRateLimit);
+      job.onError(errorCallback);
+
+      await expect(job.execute()).rejects.toBe(signal.reason);
+
+      expect(fetch).not.toHaveBeenCalled();
+      expect(errorCallback).toHaveBeenCalledWith(signal.reason);
+    });
+
+    it<LocalTestContext>('passes the signal to the request', async ({ randomDomain, testRateLimit }) => {
+      const controller = new AbortController();
+      vi.mocked(fetch).mockResolvedValueOnce({
+        ok: true,
+        headers: {
+          has: vi.fn().mockReturnValue(false)
+        }
+      } as unknown as Response);
+
+      await new FetchJob(randomDomain, { signal: controller.signal }, testRateLimit).execute();
+
+      // The signal is combined with the timeout of the attempt, so aborting the controller has to reach the request
+      const signal = vi.mocked(fetch).mock.calls[0][1]?.signal;
+      const reason = new Error(chance.sentence());
+      controller.abort(reason);
+      expect(signal?.aborted).toBeTruthy();
+      expect(signal?.reason).toBe(reason);
+    });
+
+    it<LocalTestContext>('does not retry a request aborted in flight', async ({ randomDomain, testRateLimit }) => {
+      const abortError = new DOMException('This operation was aborted', 'AbortError');
+      vi.mocked(fetch).mockRejectedValueOnce(abortError);
+
+      const job = new FetchJob(randomDomain, { signal: new AbortController().signal }, testRateLimit);
+
+      await expect(job.execute()).rejects.toBe(abortError);
+    });
+  });
+
+  describe('when the server responds with too many requests', () => {
+    const tooManyRequests = (retryAfter: string | null) =>
+      ({
+        ok: false,
+        status: 429,
+        headers: {
+          has: vi.fn().mockReturnValue(false),
+          get: vi.fn().mockImplementation((name: string) => (name === 'Retry-After' ? retryAfter : null))
+        }
+      }) as unknown as Response;
+
+    it('reports the rate limit once the retries run out', async () => {
+      vi.mocked(fetch).mockResolvedValueOnce(tooManyRequests('12'));
+
+      const job = new FetchJob('https://api.curseforge.com/v1/mods/1', {}, { maxAttempts: 1, timeBetweenCalls: 0 });
+
+      const error = await job.execute().catch((e) => e);
+
+      expect(error).toBeInstanceOf(RateLimited);
+      expect(error).toBeInstanceOf(MaximumRetriesReached);
+      expect((error as RateLimited).retryAfter()).toEqual(12000);
+      expect((error as RateLimited).platform()).toEqual(Platform.CURSEFORGE);
+    });
+
+    it<LocalTestContext>('reports the rate limit of an unknown host without a Retry-After header', async ({
+      randomDomain
+    }) => {
+      vi.mocked(fetch).mockResolvedValueOnce(tooManyRequests(null));
+
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 1, timeBetweenCalls: 0 });
+
+      const error = await job.execute().catch((e) => e);
+
+      expect(error).toBeInstanceOf(RateLimited);
+      expect((error as RateLimited).retryAfter()).toBeNull();
+      expect((error as RateLimited).platform()).toBeUndefined();
+    });
+
+    it<LocalTestContext>('waits for the number of seconds in the Retry-After header', async ({
+      randomDomain,
+      testRateLimit
+    }) => {
+      const response = tooManyRequests('7');
+      vi.mocked(fetch).mockResolvedValueOnce(response);
+
+      const job = new FetchJob(randomDomain, {}, testRateLimit);
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+
+      expect(job.retryIn()).toEqual(7000);
+      expect(job.isThrottled()).toBe(true);
+      expect(vi.mocked(response.headers.get)).toHaveBeenCalledWith('Retry-After');
+    });
+
+    it<LocalTestContext>('waits until the date in the Retry-After header', async ({ randomDomain, testRateLimit }) => {
+      vi.useFakeTimers({ now: new Date('2024-01-01T00:00:00Z') });
+      vi.mocked(fetch).mockResolvedValueOnce(tooManyRequests('Mon, 01 Jan 2024 00:00:30 GMT'));
+
+      const job = new FetchJob(randomDomain, {}, testRateLimit);
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+
+      expect(job.retryIn()).toEqual(30000);
+      vi.useRealTimers();
+    });
+
+    it<LocalTestContext>('does not wait for a Retry-After date in the past', async ({ randomDomain, testRateLimit }) => {
+      vi.useFakeTimers({ now: new Date('2024-01-01T00:00:00Z') });
+      vi.mocked(fetch).mockResolvedValueOnce(tooManyRequests('Sun, 31 Dec 2023 23:59:00 GMT'));
+
+      const job = new FetchJob(randomDomain, {}, testRateLimit);
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+
+      expect(job.retryIn()).toEqual(0);
+      vi.useRealTimers();
+    });
+
+    it<LocalTestContext>('falls back to the default retry rate without a Retry-After header', async ({
+      randomDomain,
+      testRateLimit
+    }) => {
+      vi.mocked(fetch).mockResolvedValueOnce(tooManyRequests(null));
+
+      const job = new FetchJob(randomDomain, {}, testRateLimit);
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+
+      expect(job.retryIn()).toEqual(testRateLimit.timeBetweenCalls);
+    });
+
+    it<LocalTestContext>('falls back to the default retry rate when the Retry-After header is unparseable', async ({
+      randomDomain,
+      testRateLimit
+    }) => {
+      vi.mocked(fetch).mockResolvedValueOnce(tooManyRequests(chance.word()));
+
+      const job = new FetchJob(randomDomain, {}, testRateLimit);
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+
+      expect(job.retryIn()).toEqual(testRateLimit.timeBetweenCalls);
+    });
+
+    it<LocalTestContext>('only honours the Retry-After header for the response that sent it', async ({
+      randomDomain,
+      testRateLimit
+    }) => {
+      const serverError = {
+        ok: false,
+        status: 500,
+        headers: {
+          has: vi.fn().mockReturnValue(false),
+          get: vi.fn()
+        }
+      } as unknown as Response;
+
+      vi.mocked(fetch).mockResolvedValueOnce(tooManyRequests('7'));
+      vi.mocked(fetch).mockResolvedValueOnce(serverError);
+
+      const job = new FetchJob(randomDomain, {}, testRateLimit);
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+      expect(job.retryIn()).toEqual(7000);
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+      expect(job.retryIn()).toEqual(testRateLimit.timeBetweenCalls);
+    });
+
+    it<LocalTestContext>('succeeds once the server lets the request through', async ({ randomDomain, testRateLimit }) => {
+      const okResponse = {
+        ok: true,
+        status: 200,
+        headers: {
+          has: vi.fn().mockReturnValue(false),
+          get: vi.fn()
+        }
+      } as unknown as Response;
+
+      vi.mocked(fetch).mockResolvedValueOnce(tooManyRequests('1'));
+      vi.mocked(fetch).mockResolvedValueOnce(okResponse);
+
+      const job = new FetchJob(randomDomain, {}, testRateLimit);
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+      await expect(job.execute()).resolves.toBe(okResponse);
+    });
+  });
+
+  describe('when the request has a body', () => {
+    const serverError = {
+      ok: false,
+      status: 500,
+      headers: {
+        has: vi.fn().mockReturnValue(false)
+      }
+    } as unknown as Response;
+
+    const okResponse = {
+      ok: true,
+      status: 200,
+      headers: {
+        has: vi.fn().mockReturnValue(false)
+      }
+    } as unknown as Response;
+
+    it<LocalTestContext>('sends the body of a request object again on a retry', async ({ testRateLimit }) => {
+      const body = JSON.stringify({ fingerprints: [chance.integer({ min: 1 })] });
+      const request = new Request('https://api.curseforge.com/v1/fingerprints', { method: 'POST', body: body });
+      vi.mocked(fetch).mockResolvedValueOnce(serverError).mockResolvedValueOnce(okResponse);
+
+      const job = new FetchJob(request, {}, testRateLimit);
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+      await expect(job.execute()).resolves.toBe(okResponse);
+
+      const sentBodies = await Promise.all(vi.mocked(fetch).mock.calls.map((call) => (call[0] as Request).text()));
+      expect(sentBodies).toEqual([body, body]);
+      expect(request.bodyUsed).toBeFalsy();
+    });
+
+    it<LocalTestContext>('sends the body of the options again on a retry', async ({ randomDomain, testRateLimit }) => {
+      const body = JSON.stringify({ fingerprints: [chance.integer({ min: 1 })] });
+      vi.mocked(fetch).mockResolvedValueOnce(serverError).mockResolvedValueOnce(okResponse);
+
+      const job = new FetchJob(randomDomain, { method: 'POST', body: body }, testRateLimit);
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+      await expect(job.execute()).resolves.toBe(okResponse);
+
+      expect(vi.mocked(fetch).mock.calls.map((call) => call[1]?.body)).toEqual([body, body]);
+    });
+  });
+
+  describe('when logging', () => {
+    let logger: RecordingApiLogger;
+
+    const response = (status: number) =>
+      ({
+        ok: status < 400,
+        status: status,
+        headers: {
+          has: vi.fn().mockReturnValue(false),
+          get: vi.fn()
+        }
+      }) as unknown as Response;
+
+    beforeEach(() => {
+      logger = recordingApiLogger();
+      setApiLogger(logger);
+    });
+
+    afterEach(() => {
+      setApiLogger();
+    });
+
+    it<LocalTestContext>('logs the request and the status', async ({ randomDomain, testRateLimit }) => {
+      vi.mocked(fetch).mockResolvedValueOnce(response(200));
+      const url = new Request(randomDomain).url;
+
+      await new FetchJob(randomDomain, {}, testRateLimit).execute();
+
+      expect(logger.entries).toEqual([
+        { level: 'debug', message: 'request', fields: { url: url, attempt: 1 } },
+        { level: 'debug', message: 'response', fields: { url: url, attempt: 1, status: 200 } }
+      ]);
+    });
+
+    it<LocalTestContext>('logs the retries and giving up', async ({ randomDomain }) => {
+      vi.mocked(fetch).mockResolvedValue(response(500));
+      const url = new Request(randomDomain).url;
+      const job = new FetchJob(randomDomain, {}, { maxAttempts: 2, timeBetweenCalls: 1000 });
+
+      await expect(job.execute()).rejects.toThrow(Retrying);
+      await expect(job.execute()).rejects.toThrow(MaximumRetriesReached);
+
+      expect(logger.entries).toContainEqual({
+        level: 'debug',
+        message: 'retrying',
+        fields: { url: url, attempt: 1, status: 500, retryIn: 1000 }
+      });
+      expect(logger.entries.at(-1)).toEqual({
+        level: 'warn',
+        message: 'giving up',
+        fields: { url: url, attempt: 2, status: 500 }
+      });
+    });
+
+    it<LocalTestContext>('logs the retries of the network errors', async ({ randomDomain, testRateLimit }) => {
+      const networkError = new TypeError('fetch failed', { cause: Object.assign(new Error(), { code: 'ECONNRESET' }) });
+      vi.mocked(fetch).mockRejectedValueOnce(networkError);
+
+      await expect(new FetchJob(randomDomain, {}, testRateLimit).execute()).rejects.toThrow(RetryingOnError);
+
+      expect(logger.entries.at(-1)).toEqual({
+        level: 'debug',
+        message: 'retrying',
+        fields: { url: new Request(randomDomain).url, attempt: 1, error: String(networkError), retryIn: 1000 }
+      });
+    });
+
+    it<LocalTestContext>('logs the failed requests', async ({ randomDomain, testRateLimit }) => {
+      const error = new Error(chance.sentence());
+      vi.mocked(fetch).mockRejectedValueOnce(error);
+
+      await expect(new FetchJob(randomDomain, {}, testRateLimit).execute()).rejects.toThrow(error);
+
+      expect(logger.entries.at(-1)).toEqual({
+        level: 'warn',
+        message: 'request failed',
+        fields: { url: new Request(randomDomain).url, attempt: 1, error: String(error) }
+      });
+    });
+
+    it('logs the refused api key as an error', async () => {
+      const url = 'https://api.curseforge.com/v1/mods/1';
+      vi.mocked(fetch).mockResolvedValueOnce(response(401));
+
+      await expect(new FetchJob(url, {}, { maxAttempts: 3, timeBetweenCalls: 0 }).execute()).rejects.toThrow(
+        CurseforgeUnauthorized
+      );
+
+      expect(logger.entries.at(-1)).toEqual({
+        level: 'error',
+        message: 'api key refused',
+        fields: { url: url, status: 401 }
+      });
+    });
+  });
 });
@@ -1,11 +1,70 @@
+import { getApiLogger } from '../apiLogger.js';
+import { CurseforgeUnauthorized } from './CurseforgeUnauthorized.js';
 import { MaximumRetriesReached } from './MaximumRetriesReached.js';
+import { RateLimited } from './RateLimited.js';
+import { RequestTimedOut } from './RequestTimedOut.js';
 import { Retrying } from './Retrying.js';
+import { RetryingOnError } from './RetryingOnError.js';
+import { anySignal } from './anySignal.js';
+import { getCurseforgeApiKey, isCurseforgeHost } from './apiKeys.js';
+import { notifyAttempt } from './attempts.js';
+import { backoffDelay } from './backoff.js';
+import { circuitOpenError, recordFailure, recordSuccess } from './circuitBreaker.js';
 import { RateLimit } from './index.js';
+import { platformForHost } from './platformLimits.js';
+import { requestUrl } from './requestUrl.js';
+import { takeRetry } from './retryBudget.js';
+import { isTransientNetworkError } from './transientError.js';
+import { transportFetch } from './transport.js';
+import { getUserAgent } from './userAgent.js';
+
+const NOT_MODIFIED = 304;
+const SERVER_ERROR = 500;
+const UNAUTHORIZED = 401;
+const FORBIDDEN = 403;
+const TOO_MANY_REQUESTS = 429;
+export const DEFAULT_REQUEST_TIMEOUT = 30000;
+
+/**
+ * The Retry-After header can either be a number of seconds or an HTTP date.
+ * Returns the delay in milliseconds or null when the header can't be used.
+ */
+const parseRetryAfter = (retryAfter: string | null): number | null => {
+  const value = retryAfter?.trim();
+  if (!value) {
+    return null;
+  }
+
+  const seconds = Number(value);
+  if (!Number.isNaN(seconds)) {
+    return Math.max(0, seconds * 1000);
+  }
+
+  const date = Date.parse(value);
+  if (Number.isNaN(date)) {
+    return null;
+  }
+
+  return Math.max(0, date - Date.now());
+};
+
+/**
+ * Nothing reads the body of a response that is retried, it's cancelled so the connection can be used again.
+ * Over a long run on a flaky platform the unread bodies would keep their connections busy.
+ */
+const discardBody = (response: Response) => {
+  if (response.body && !response.bodyUsed) {
+    response.body.cancel().catch(() => {
+      // The connection is gone already
+    });
+  }
+};
 
 export class FetchJob {
   private tries = 0;
   private isRateLimiting = false;
   private rateLimitRetryInSeconds = 60;
+  private retryAfter: number | null = null;
   private readonly input: RequestInfo | URL;
   private readonly init?: RequestInit | undefined;
   private readonly rateLimit: RateLimit;
@@ -25,14 +84,52 @@ export class FetchJob {
   }
 
   host() {
-    return new URL(new Request(this.input).url).host;
+    return new URL(requestUrl(this.input)).host;
   }
 
   retryIn() {
+    if (this.retryAfter !== null) {
+      return this.retryAfter;
+    }
     if (this.isRateLimiting) {
       return (this.rateLimitRetryInSeconds + 1) * 1000;
     }
-    return this.rateLimit.timeBetweenCalls;
+    return backoffDelay(this.rateLimit, this.tries - 1);
+  }
+
+  /**
+   * Whether the platform asked us to slow down with the last response
+   */
+  isThrottled() {
+    return this.retryAfter !== null || this.isRateLimiting;
+  }
+
+  /**
+   * The platforms are told apart by the hostname, the host of the job has the port too
+   */
+  private isCurseforgeRequest() {
+    return isCurseforgeHost(new URL(requestUrl(this.input)).hostname);
+  }
+
+  private requestInit(): RequestInit {
+    const headers = new Headers(this.init?.headers ?? (this.input instanceof Request ? this.input.headers : undefined));
+    if (!headers.has('user-agent')) {
+      headers.set('user-agent', getUserAgent());
+    }
+    if (this.isCurseforgeRequest() && !headers.has('x-api-key')) {
+      headers.set('x-api-key', getCurseforgeApiKey());
+    }
+    return {
+      ...this.init,
+      headers: headers
+    };
+  }
+
+  private exhaustedError(response: Response, url: string): MaximumRetriesReached {
+    if (response.status === TOO_MANY_REQUESTS) {
+      return new RateLimited(response, this.retryAfter, platformForHost(new URL(url).hostname));
+    }
+    return new MaximumRetriesReached(response);
   }
 
   onResponse(responseCallback: (result: Response) => void) {
@@ -46,8 +143,51 @@ export class FetchJob {
   execute(): Promise<Response> {
     this.tries++;
     return new Promise<Response>((resolve, reject) => {
-      fetch(this.input, this.init)
+      // A job cancelled while it was waiting in the queue shouldn't reach the network
+      const signal = this.init?.signal;
+      if (signal?.aborted) {
+        this.errorCallback(signal.reason);
+        reject(signal.reason);
+        return;
+      }
+
+      const url = requestUrl(this.input);
+      const hostname = new URL(url).hostname;
+      // A retry of a host that keeps failing doesn't go out either
+      const circuitOpen = circuitOpenError(hostname);
+      if (circuitOpen) {
+        getApiLogger().warn('circuit open', { url: url, attempt: this.tries, retryIn: circuitOpen.retryIn() });
+        this.errorCallback(circuitOpen);
+        reject(circuitOpen);
+        return;
+      }
+
+      const startedAt = performance.now();
+      const timeout = this.rateLimit.timeout ?? DEFAULT_REQUEST_TIMEOUT;
+      // A stalled connection never errors on its own, so every attempt gets cut off after the timeout
+      const timeoutSignal = AbortSignal.timeout(timeout);
+      const requestSignal = signal ? anySignal([signal, timeoutSignal]) : timeoutSignal;
+      getApiLogger().debug('request', { url: url, attempt: this.tries });
+
+      // The body of a request object can only be read once, every attempt sends a copy so a retry sends it too
+      const input = this.input instanceof Request ? this.input.clone() : this.input;
+
+      transportFetch(input, { ...this.requestInit(), signal: requestSignal })
         .then((response) => {
+          notifyAttempt({
+            url: url,
+            attempt: this.tries,
+            status: response.status,
+            duration: performance.now() - startedAt
+          });
+          getApiLogger().debug('response', { url: url, attempt: this.tries, status: response.status });
+
+          if (response.status >= SERVER_ERROR) {
+            recordFailure(hostname);
+          } else {
+            recordSuccess(hostname);
+          }
+
           // handle rate limit headers
           if (response.headers.has('X-Ratelimit-Remaining')) {
             const remaining = response.headers.get('X-Ratelimit-Remaining');
@@ -61,12 +201,37 @@ export class FetchJob {
             }
           }
 
-          if (!response.ok) {
-            if (this.tries === this.rateLimit.maxAttempts) {
-              this.errorCallback(new MaximumRetriesReached(response));
-              reject(new MaximumRetriesReached(response));
+          // A 429 tells us exactly how long to wait when it comes with a Retry-After header
+          this.retryAfter =
+            response.status === TOO_MANY_REQUESTS ? parseRetryAfter(response.headers.get('Retry-After')) : null;
+
+          // Curseforge answers a missing or invalid api key like this, asking again won't change its mind
+          if (this.isCurseforgeRequest() && [UNAUTHORIZED, FORBIDDEN].includes(response.status)) {
+            const error = new CurseforgeUnauthorized(url, response.status);
+            getApiLogger().error('api key refused', { url: url, status: response.status });
+            this.errorCallback(error);
+            reject(error);
+            return;
+          }
+
+          // A 304 is the answer to a conditional request, the caller holds the body already
+          if (!response.ok && response.status !== NOT_MODIFIED) {
+            // An exhausted retry budget makes this the last attempt no matter how many are left
+            if (this.tries === this.rateLimit.maxAttempts || !takeRetry()) {
+              const error = this.exhaustedError(response, url);
+              getApiLogger().warn('giving up', { url: url, attempt: this.tries, status: response.status });
+              this.errorCallback(error);
+              reject(error);
               return;
             }
+            getApiLogger().debug('retrying', {
+              url: url,
+              attempt: this.tries,
+              status: response.status,
+              retryIn: this.retryIn()
+            });
+            // The last response stays whole, the caller gets it with the error when the retries run out
+            discardBody(response);
             reject(new Retrying(response));
             return;
           }
@@ -76,8 +241,35 @@ export class FetchJob {
           resolve(response);
         })
         .catch((reason) => {
-          this.errorCallback(reason);
-          reject(reason);
+          notifyAttempt({
+            url: url,
+            attempt: this.tries,
+            status: null,
+            duration: performance.now() - startedAt,
+            error: reason
+          });
+
+          const timedOut = timeoutSignal.aborted && !signal?.aborted;
+          if (timedOut || isTransientNetworkError(reason)) {
+            recordFailure(hostname);
+          }
+
+          if ((timedOut || isTransientNetworkError(reason)) && this.tries < this.rateLimit.maxAttempts && takeRetry()) {
+            this.retryAfter = null;
+            getApiLogger().debug('retrying', {
+              url: url,
+              attempt: this.tries,
+              error: String(reason),
+              retryIn: this.retryIn()
+            });
+            reject(new RetryingOnError(reason));
+            return;
+          }
+
+          const error = timedOut ? new RequestTimedOut(url, timeout) : reason;
+          getApiLogger().warn('request failed', { url: url, attempt: this.tries, error: String(error) });
+          this.errorCallback(error);
+          reject(error);
           return;
         });
     });
@@ -0,0 +1,24 @@
+import { Platform } from '../modlist.types.js';
+import { MaximumRetriesReached } from './MaximumRetriesReached.js';
+
+export class RateLimited extends MaximumRetriesReached {
+  private readonly retryAfterMs: number | null;
+  private readonly limitingPlatform: Platform | undefined;
+  constructor(lastResponse: Response, retryAfter: number | null, platform?: Platform) {
+    super(lastResponse);
+    this.message = 'Rate limited after the maximum number of retries';
+    this.retryAfterMs = retryAfter;
+    this.limitingPlatform = platform;
+  }
+
+  /**
+   * How long the server asked us to wait in milliseconds, null when it didn't say
+   */
+  retryAfter() {
+    return this.retryAfterMs;
+  }
+
+  platform() {
+    return this.limitingPlatform;
+  }
+}
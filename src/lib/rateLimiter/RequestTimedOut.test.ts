@@ -0,0 +1,16 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { RequestTimedOut } from './RequestTimedOut.js';
+
+describe('The request timed out exception', () => {
+  it('can return the url and the timeout', () => {
+    const url = chance.url();
+    const timeout = chance.integer({ min: 1 });
+
+    const error = new RequestTimedOut(url, timeout);
+
+    expect(error.url()).toEqual(url);
+    expect(error.timeout()).toEqual(timeout);
+    expect(error.message).toEqual(`The request to ${url} did not finish in ${timeout}ms`);
+  });
+});
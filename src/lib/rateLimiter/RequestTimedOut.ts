@@ -0,0 +1,17 @@
+export class RequestTimedOut extends Error {
+  private readonly requestUrl: string;
+  private readonly timeoutInMs: number;
+  constructor(url: string, timeout: number) {
+    super(`The request to ${url} did not finish in ${timeout}ms`);
+    this.requestUrl = url;
+    this.timeoutInMs = timeout;
+  }
+
+  url() {
+    return this.requestUrl;
+  }
+
+  timeout() {
+    return this.timeoutInMs;
+  }
+}
@@ -0,0 +1,11 @@
+export class ResponseTooLarge extends Error {
+  private readonly limit: number;
+  constructor(url: string, maxBytes: number) {
+    super(`The response from ${url} is larger than the allowed ${maxBytes} bytes`);
+    this.limit = maxBytes;
+  }
+
+  maxBytes() {
+    return this.limit;
+  }
+}
@@ -0,0 +1,14 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { RetryingOnError } from './RetryingOnError.js';
+
+describe('The retry on error exception', () => {
+  it('can return the last error', () => {
+    const testError = new Error(chance.sentence());
+
+    const retry = new RetryingOnError(testError);
+
+    expect(retry.lastError()).toBe(testError);
+    expect(retry.message).toEqual('Retrying call after error: ' + testError.message);
+  });
+});
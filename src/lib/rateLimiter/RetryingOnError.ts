@@ -0,0 +1,11 @@
+export class RetryingOnError extends Error {
+  private readonly error: Error;
+  constructor(lastError: Error) {
+    super('Retrying call after error: ' + lastError.message);
+    this.error = lastError;
+  }
+
+  lastError() {
+    return this.error;
+  }
+}
@@ -0,0 +1,45 @@
+import { describe, expect, it } from 'vitest';
+import { anySignal } from './anySignal.js';
+
+describe('The combined signal', () => {
+  it('is not aborted while none of the signals are', () => {
+    const signal = anySignal([new AbortController().signal, new AbortController().signal]);
+
+    expect(signal.aborted).toBeFalsy();
+  });
+
+  it('aborts with the reason of the first signal that aborts', () => {
+    const first = new AbortController();
+    const second = new AbortController();
+    const signal = anySignal([first.signal, second.signal]);
+
+    second.abort('second');
+    first.abort('first');
+
+    expect(signal.aborted).toBeTruthy();
+    expect(signal.reason).toEqual('second');
+  });
+
+  it('is aborted right away when one of the signals already is', () => {
+    const reason = new Error('cancelled');
+
+    const signal = anySignal([new AbortController().signal, AbortSignal.abort(reason)]);
+
+    expect(signal.aborted).toBeTruthy();
+    expect(signal.reason).toBe(reason);
+  });
+
+  it('lets go of the other signals once it aborts', () => {
+    const first = new AbortController();
+    const second = new AbortController();
+    const signal = anySignal([first.signal, second.signal]);
+    let aborts = 0;
+    signal.addEventListener('abort', () => aborts++);
+
+    first.abort('first');
+    second.abort('second');
+
+    expect(aborts).toEqual(1);
+    expect(signal.reason).toEqual('first');
+  });
+});
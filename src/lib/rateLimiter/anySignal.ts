@@ -0,0 +1,21 @@
+/**
+ * A signal that aborts with the reason of the first of the signals that does.
+ * It does what AbortSignal.any does, which only came with Node 20.3.
+ */
+export const anySignal = (signals: AbortSignal[]): AbortSignal => {
+  const controller = new AbortController();
+
+  const aborted = signals.find((signal) => signal.aborted);
+  if (aborted) {
+    controller.abort(aborted.reason);
+    return controller.signal;
+  }
+
+  const abort = (event: Event) => {
+    signals.forEach((signal) => signal.removeEventListener('abort', abort));
+    controller.abort((event.target as AbortSignal).reason);
+  };
+  signals.forEach((signal) => signal.addEventListener('abort', abort, { once: true }));
+
+  return controller.signal;
+};
@@ -0,0 +1,43 @@
+import { chance } from 'jest-chance';
+import { afterEach, describe, expect, it } from 'vitest';
+import { curseForgeApiKey } from '../../env.js';
+import { setBaseUrl } from '../baseUrl.js';
+import { Platform } from '../modlist.types.js';
+import { getCurseforgeApiKey, isCurseforgeHost, setCurseforgeApiKey } from './apiKeys.js';
+
+describe('The api keys', () => {
+  afterEach(() => {
+    setCurseforgeApiKey();
+    setBaseUrl(Platform.CURSEFORGE);
+  });
+
+  it('uses the environment variable for Curseforge by default', () => {
+    expect(getCurseforgeApiKey()).toEqual(curseForgeApiKey);
+  });
+
+  it('can use an explicitly provided Curseforge api key', () => {
+    const randomKey = chance.hash();
+    setCurseforgeApiKey(randomKey);
+
+    expect(getCurseforgeApiKey()).toEqual(randomKey);
+  });
+
+  it('goes back to the environment variable when the key is cleared', () => {
+    setCurseforgeApiKey(chance.hash());
+    setCurseforgeApiKey('');
+
+    expect(getCurseforgeApiKey()).toEqual(curseForgeApiKey);
+  });
+
+  it('recognises the Curseforge api host', () => {
+    expect(isCurseforgeHost('api.curseforge.com')).toBeTruthy();
+    expect(isCurseforgeHost('api.modrinth.com')).toBeFalsy();
+    expect(isCurseforgeHost(chance.domain())).toBeFalsy();
+  });
+
+  it('recognises the base url Curseforge was moved to', () => {
+    setBaseUrl(Platform.CURSEFORGE, 'https://cf-proxy.example.com:8443/v1');
+
+    expect(isCurseforgeHost('cf-proxy.example.com')).toBeTruthy();
+  });
+});
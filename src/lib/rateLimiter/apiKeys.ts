@@ -0,0 +1,22 @@
+import { curseForgeApiKey } from '../../env.js';
+import { Platform } from '../modlist.types.js';
+import { platformForHost } from './platformLimits.js';
+
+let configuredCurseforgeApiKey: string | undefined;
+
+/**
+ * Overrides the CURSEFORGE_API_KEY environment variable for every request to the Curseforge API.
+ * Calling it without a value goes back to using the environment variable.
+ */
+export const setCurseforgeApiKey = (apiKey?: string) => {
+  configuredCurseforgeApiKey = apiKey || undefined;
+};
+
+export const getCurseforgeApiKey = () => configuredCurseforgeApiKey ?? curseForgeApiKey;
+
+/**
+ * The Curseforge API, or the base url it was moved to, like a proxy in front of it
+ *
+ * @param hostname The host of the url without the port
+ */
+export const isCurseforgeHost = (hostname: string) => platformForHost(hostname) === Platform.CURSEFORGE;
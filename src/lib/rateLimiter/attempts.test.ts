@@ -0,0 +1,56 @@
+import { chance } from 'jest-chance';
+import { afterEach, describe, expect, it, vi } from 'vitest';
+import { getMetrics, resetMetrics } from '../metrics.js';
+import { AttemptInfo, notifyAttempt, setAttemptListener } from './attempts.js';
+
+const randomAttempt = (): AttemptInfo => ({
+  url: chance.url(),
+  attempt: chance.integer({ min: 1, max: 5 }),
+  status: chance.pickone([200, 404, 500]),
+  duration: chance.floating({ min: 0, max: 1000 })
+});
+
+describe('The attempt notifications', () => {
+  afterEach(() => {
+    setAttemptListener();
+    resetMetrics();
+  });
+
+  it('tells the listener about the attempt', () => {
+    const listener = vi.fn();
+    const attempt = randomAttempt();
+    setAttemptListener(listener);
+
+    notifyAttempt(attempt);
+
+    expect(listener).toHaveBeenCalledWith(attempt);
+  });
+
+  it('does nothing without a listener', () => {
+    expect(() => notifyAttempt(randomAttempt())).not.toThrow();
+  });
+
+  it('stops telling the removed listener', () => {
+    const listener = vi.fn();
+    setAttemptListener(listener);
+    setAttemptListener();
+
+    notifyAttempt(randomAttempt());
+
+    expect(listener).not.toHaveBeenCalled();
+  });
+
+  it('counts the attempt in the metrics', () => {
+    notifyAttempt({ ...randomAttempt(), attempt: 2, status: 503 });
+
+    expect(getMetrics()).toMatchObject({ requests: 1, retries: 1, serverErrors: 1 });
+  });
+
+  it('swallows the errors of the listener', () => {
+    setAttemptListener(() => {
+      throw new Error(chance.sentence());
+    });
+
+    expect(() => notifyAttempt(randomAttempt())).not.toThrow();
+  });
+});
@@ -0,0 +1,39 @@
+import { countRequest } from '../metrics.js';
+
+export interface AttemptInfo {
+  url: string;
+  attempt: number;
+  /**
+   * The status code of the response, null when the request failed without one.
+   */
+  status: number | null;
+  /**
+   * How long the attempt took in milliseconds.
+   */
+  duration: number;
+  error?: unknown;
+}
+
+export type AttemptListener = (attempt: AttemptInfo) => void;
+
+let attemptListener: AttemptListener | undefined;
+
+/**
+ * Sets the function that gets told about every attempt of every rate limited request, retries included.
+ * Calling it without a listener stops the notifications.
+ */
+export const setAttemptListener = (listener?: AttemptListener) => {
+  attemptListener = listener;
+};
+
+/**
+ * Counts the attempt in the metrics and tells the listener about it
+ */
+export const notifyAttempt = (attempt: AttemptInfo) => {
+  countRequest(attempt.attempt, attempt.status);
+  try {
+    attemptListener?.(attempt);
+  } catch {
+    // A broken listener shouldn't break the request it listens to
+  }
+};
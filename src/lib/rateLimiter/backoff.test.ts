@@ -0,0 +1,80 @@
+import { chance } from 'jest-chance';
+import { afterEach, describe, expect, it, vi } from 'vitest';
+import { Backoff, backoffDelay } from './backoff.js';
+
+describe('The backoff calculation', () => {
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it('defaults to a constant delay', () => {
+    const timeBetweenCalls = chance.integer({ min: 1, max: 1000 });
+    const rateLimit = { maxAttempts: 3, timeBetweenCalls };
+
+    expect(backoffDelay(rateLimit, 0)).toEqual(timeBetweenCalls);
+    expect(backoffDelay(rateLimit, 1)).toEqual(timeBetweenCalls);
+    expect(backoffDelay(rateLimit, 5)).toEqual(timeBetweenCalls);
+  });
+
+  it('can use a constant delay explicitly', () => {
+    const rateLimit = { maxAttempts: 3, timeBetweenCalls: 1000, backoff: Backoff.CONSTANT };
+
+    expect(backoffDelay(rateLimit, 0)).toEqual(1000);
+    expect(backoffDelay(rateLimit, 3)).toEqual(1000);
+  });
+
+  it('doubles the delay with every attempt when exponential', () => {
+    const rateLimit = { maxAttempts: 5, timeBetweenCalls: 1000, backoff: Backoff.EXPONENTIAL };
+
+    expect(backoffDelay(rateLimit, 0)).toEqual(1000);
+    expect(backoffDelay(rateLimit, 1)).toEqual(2000);
+    expect(backoffDelay(rateLimit, 2)).toEqual(4000);
+    expect(backoffDelay(rateLimit, 3)).toEqual(8000);
+  });
+
+  it('treats negative attempts as the first one', () => {
+    const rateLimit = { maxAttempts: 5, timeBetweenCalls: 1000, backoff: Backoff.EXPONENTIAL };
+
+    expect(backoffDelay(rateLimit, -1)).toEqual(1000);
+  });
+
+  it('caps the delay at the max interval', () => {
+    const rateLimit = { maxAttempts: 10, timeBetweenCalls: 1000, backoff: Backoff.EXPONENTIAL, maxInterval: 5000 };
+
+    expect(backoffDelay(rateLimit, 2)).toEqual(4000);
+    expect(backoffDelay(rateLimit, 3)).toEqual(5000);
+    expect(backoffDelay(rateLimit, 8)).toEqual(5000);
+  });
+
+  it.each([
+    [0, 1000],
+    [1, 2000],
+    [2, 4000],
+    [3, 8000]
+  ])('keeps the jittered delay of attempt %i within bounds', (attempt, expected) => {
+    const jitter = 0.25;
+    const rateLimit = { maxAttempts: 5, timeBetweenCalls: 1000, backoff: Backoff.EXPONENTIAL, jitter };
+
+    for (let i = 0; i < 50; i++) {
+      const actual = backoffDelay(rateLimit, attempt);
+      expect(actual).toBeGreaterThanOrEqual(expected * (1 - jitter));
+      expect(actual).toBeLessThanOrEqual(expected * (1 + jitter));
+    }
+  });
+
+  it('applies the jitter in both directions', () => {
+    const rateLimit = { maxAttempts: 3, timeBetweenCalls: 1000, jitter: 0.5 };
+    vi.spyOn(Math, 'random').mockReturnValueOnce(0).mockReturnValueOnce(1).mockReturnValueOnce(0.5);
+
+    expect(backoffDelay(rateLimit, 0)).toEqual(500);
+    expect(backoffDelay(rateLimit, 0)).toEqual(1500);
+    expect(backoffDelay(rateLimit, 0)).toEqual(1000);
+  });
+
+  it('clamps the jitter fraction between 0 and 1', () => {
+    vi.spyOn(Math, 'random').mockReturnValue(0);
+
+    expect(backoffDelay({ maxAttempts: 3, timeBetweenCalls: 1000, jitter: 5 }, 0)).toEqual(0);
+    expect(backoffDelay({ maxAttempts: 3, timeBetweenCalls: 1000, jitter: -1 }, 0)).toEqual(1000);
+  });
+});
@@ -0,0 +1,35 @@
+import { RateLimit } from './index.js';
+
+// eslint-disable-next-line no-unused-vars
+export enum Backoff {
+  // eslint-disable-next-line no-unused-vars
+  CONSTANT = 'constant',
+  // eslint-disable-next-line no-unused-vars
+  EXPONENTIAL = 'exponential'
+}
+
+/**
+ * Calculates how long to wait before the given retry attempt (0 being the first retry).
+ *
+ * The constant strategy always waits for timeBetweenCalls, the exponential one doubles it
+ * with every attempt up to maxInterval. The optional jitter is a fraction of the delay that
+ * gets randomly added or subtracted so concurrent retries don't hit the API at the same time.
+ */
+export const backoffDelay = (rateLimit: RateLimit, attempt: number): number => {
+  let delay = rateLimit.timeBetweenCalls;
+
+  if (rateLimit.backoff === Backoff.EXPONENTIAL) {
+    delay = rateLimit.timeBetweenCalls * Math.pow(2, Math.max(0, attempt));
+  }
+
+  if (rateLimit.maxInterval !== undefined) {
+    delay = Math.min(delay, rateLimit.maxInterval);
+  }
+
+  if (rateLimit.jitter) {
+    const jitter = Math.min(Math.max(rateLimit.jitter, 0), 1);
+    delay = delay + delay * jitter * (Math.random() * 2 - 1);
+  }
+
+  return Math.max(0, Math.round(delay));
+};
@@ -0,0 +1,118 @@
+import { afterEach, beforeEach, describe, expect, it } from 'vitest';
+import { CircuitOpen } from './CircuitOpen.js';
+import {
+  circuitOpenError,
+  defaultCircuitBreaker,
+  getCircuitBreaker,
+  recordFailure,
+  recordSuccess,
+  setCircuitBreaker
+} from './circuitBreaker.js';
+import { setNow } from './clock.js';
+
+const host = 'api.modrinth.com';
+
+const fail = (times: number) => {
+  for (let i = 0; i < times; i++) {
+    recordFailure(host);
+  }
+};
+
+describe('The circuit breaker', () => {
+  let time: number;
+
+  beforeEach(() => {
+    time = 0;
+    setNow(() => time);
+    setCircuitBreaker({ failureThreshold: 3, cooldown: 10000 });
+  });
+
+  afterEach(() => {
+    setNow();
+    setCircuitBreaker();
+  });
+
+  it('has defaults', () => {
+    setCircuitBreaker();
+
+    expect(getCircuitBreaker()).toBe(defaultCircuitBreaker);
+  });
+
+  it('lets the requests out while the host works', () => {
+    expect(circuitOpenError(host)).toBeUndefined();
+
+    fail(2);
+
+    expect(circuitOpenError(host)).toBeUndefined();
+  });
+
+  it('opens after the failures in a row', () => {
+    fail(3);
+
+    const error = circuitOpenError(host);
+    expect(error).toBeInstanceOf(CircuitOpen);
+    expect(error?.host()).toEqual(host);
+    expect(error?.retryIn()).toEqual(10000);
+  });
+
+  it('only counts the failures in a row', () => {
+    fail(2);
+    recordSuccess(host);
+    fail(2);
+
+    expect(circuitOpenError(host)).toBeUndefined();
+  });
+
+  it('keeps the hosts apart', () => {
+    fail(3);
+
+    expect(circuitOpenError('api.curseforge.com')).toBeUndefined();
+  });
+
+  it('tells how much of the cooldown is left', () => {
+    fail(3);
+    time = 4000;
+
+    expect(circuitOpenError(host)?.retryIn()).toEqual(6000);
+  });
+
+  it('lets a probe out after the cooldown', () => {
+    fail(3);
+    time = 10000;
+
+    expect(circuitOpenError(host)).toBeUndefined();
+  });
+
+  it('closes when the probe works', () => {
+    fail(3);
+    time = 10000;
+    recordSuccess(host);
+    fail(2);
+
+    expect(circuitOpenError(host)).toBeUndefined();
+  });
+
+  it('opens again when the probe fails', () => {
+    fail(3);
+    time = 10000;
+    fail(1);
+
+    expect(circuitOpenError(host)?.retryIn()).toEqual(10000);
+  });
+
+  it('can be turned off', () => {
+    setCircuitBreaker({ failureThreshold: 0, cooldown: 10000 });
+
+    fail(100);
+
+    expect(circuitOpenError(host)).toBeUndefined();
+  });
+
+  it('forgets the failures when it is set', () => {
+    fail(3);
+
+    setCircuitBreaker({ failureThreshold: 3, cooldown: 10000 });
+
+    expect(circuitOpenError(host)).toBeUndefined();
+  });
+});
@@ -0,0 +1,94 @@
+import { CircuitOpen } from './CircuitOpen.js';
+import { now } from './clock.js';
+
+export interface CircuitBreaker {
+  /**
+   * How many failures in a row it takes to stop sending requests to a host. 0 turns the circuit breaker off.
+   */
+  failureThreshold: number;
+  /**
+   * How long in milliseconds the requests to the host are refused before one is let through to see if it's back.
+   */
+  cooldown: number;
+}
+
+interface CircuitRecord {
+  host: string;
+  failures: number;
+  /**
+   * When the circuit last opened, undefined while it's closed
+   */
+  openedAt?: number;
+}
+
+export const defaultCircuitBreaker: CircuitBreaker = {
+  failureThreshold: 5,
+  cooldown: 30000
+};
+
+let settings: CircuitBreaker = defaultCircuitBreaker;
+const circuits: CircuitRecord[] = [];
+
+/**
+ * Sets when the requests to a host that keeps failing stop going out, and forgets the failures seen so far.
+ * Calling it without settings goes back to the defaults.
+ */
+export const setCircuitBreaker = (circuitBreaker?: CircuitBreaker) => {
+  settings = circuitBreaker ?? defaultCircuitBreaker;
+  circuits.length = 0;
+};
+
+export const getCircuitBreaker = () => settings;
+
+const getCircuit = (forHost: string): CircuitRecord => {
+  const existing = circuits.find((c) => c.host === forHost);
+  if (existing) {
+    return existing;
+  }
+
+  const circuit: CircuitRecord = { host: forHost, failures: 0 };
+  circuits.push(circuit);
+  return circuit;
+};
+
+/**
+ * The error to fail a request to the host with right away, undefined when the request can go out.
+ *
+ * Once the cooldown is over the circuit is half open, the next request goes out to probe the host.
+ * Its outcome closes the circuit or opens it again for another cooldown.
+ */
+export const circuitOpenError = (forHost: string): CircuitOpen | undefined => {
+  const openedAt = circuits.find((c) => c.host === forHost)?.openedAt;
+  if (openedAt === undefined) {
+    return undefined;
+  }
+
+  const retryIn = openedAt + settings.cooldown - now();
+  return retryIn > 0 ? new CircuitOpen(forHost, retryIn) : undefined;
+};
+
+/**
+ * The host answered, so it's up, even if the answer is an error of the request itself
+ */
+export const recordSuccess = (forHost: string) => {
+  const circuit = getCircuit(forHost);
+  circuit.failures = 0;
+  circuit.openedAt = undefined;
+};
+
+/**
+ * The host couldn't answer, it timed out, the connection broke or it responded with a server error
+ */
+export const recordFailure = (forHost: string) => {
+  if (settings.failureThreshold <= 0) {
+    return;
+  }
+
+  const circuit = getCircuit(forHost);
+  circuit.failures++;
+
+  // A failed probe of a half open circuit opens it again straight away
+  if (circuit.openedAt !== undefined || circuit.failures >= settings.failureThreshold) {
+    circuit.openedAt = now();
+  }
+};
@@ -0,0 +1,65 @@
+import { afterEach, describe, expect, it, vi } from 'vitest';
+import { now, setNow, setSleep, sleep } from './clock.js';
+
+describe('The rate limiter clock', () => {
+  afterEach(() => {
+    setSleep();
+    setNow();
+    vi.useRealTimers();
+  });
+
+  it('waits with a timer by default', async () => {
+    vi.useFakeTimers();
+    const waited = vi.fn();
+
+    const waiting = sleep(1000).then(waited);
+    await vi.advanceTimersByTimeAsync(999);
+    expect(waited).not.toHaveBeenCalled();
+
+    await vi.advanceTimersByTimeAsync(1);
+    await waiting;
+    expect(waited).toHaveBeenCalledOnce();
+  });
+
+  it('can be replaced', async () => {
+    const replacement = vi.fn().mockResolvedValue(undefined);
+    setSleep(replacement);
+
+    await sleep(1234);
+
+    expect(replacement).toHaveBeenCalledWith(1234);
+  });
+
+  it('goes back to the timer when the replacement is removed', async () => {
+    const replacement = vi.fn().mockResolvedValue(undefined);
+    setSleep(replacement);
+    setSleep();
+    vi.useFakeTimers();
+
+    const waiting = sleep(10);
+    await vi.advanceTimersByTimeAsync(10);
+    await waiting;
+
+    expect(replacement).not.toHaveBeenCalled();
+  });
+
+  it('reads the system time by default', () => {
+    vi.useFakeTimers({ now: 1234 });
+
+    expect(now()).toEqual(1234);
+  });
+
+  it('can read the time from somewhere else', () => {
+    setNow(() => 42);
+
+    expect(now()).toEqual(42);
+  });
+
+  it('goes back to the system time when the replacement is removed', () => {
+    setNow(() => 42);
+    setNow();
+    vi.useFakeTimers({ now: 1234 });
+
+    expect(now()).toEqual(1234);
+  });
+});
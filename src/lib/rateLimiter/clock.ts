@@ -0,0 +1,32 @@
+export type Sleep = (milliseconds: number) => Promise<void>;
+
+const timeoutSleep: Sleep = (milliseconds) => new Promise((resolve) => setTimeout(resolve, milliseconds));
+
+let currentSleep: Sleep = timeoutSleep;
+
+/**
+ * Sets how the rate limiter waits between the requests and the retries, and how the downloads wait for the bandwidth.
+ * Tests can record the waits instead of sitting through them, calling it without a function goes back to setTimeout.
+ */
+export const setSleep = (sleep?: Sleep) => {
+  currentSleep = sleep || timeoutSleep;
+};
+
+export const sleep: Sleep = (milliseconds) => currentSleep(milliseconds);
+
+export type Now = () => number;
+
+const systemNow: Now = () => Date.now();
+
+let currentNow: Now = systemNow;
+
+/**
+ * Sets where the rate limiter reads the time from when it works out how many requests it can send at once, and where
+ * the paging of the files and the capped downloads read it from.
+ * Calling it without a function goes back to the system time.
+ */
+export const setNow = (now?: Now) => {
+  currentNow = now || systemNow;
+};
+
+export const now: Now = () => currentNow();
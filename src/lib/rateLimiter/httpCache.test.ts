@@ -0,0 +1,418 @@
+import fs from 'node:fs/promises';
+import path from 'path';
+import { chance } from 'jest-chance';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { getMetrics, resetMetrics } from '../metrics.js';
+import { setNow } from './clock.js';
+import {
+  cachingFetch,
+  defaultCacheTtl,
+  getCacheTtl,
+  getHttpCacheDirectory,
+  parseMaxAge,
+  setCacheTtl,
+  setHttpCacheDirectory
+} from './httpCache.js';
+import { rateLimitingFetch } from './index.js';
+
+vi.mock('node:fs/promises');
+vi.mock('./index.js');
+
+interface LocalTestContext {
+  cacheDirectory: string;
+  url: string;
+  etag: string;
+  body: string;
+}
+
+const lastModified = 'Wed, 21 Oct 2015 07:28:00 GMT';
+
+const storedAt = 1_700_000_000_000;
+
+const cachedEntry = (etag: string, body: string, extra: Record<string, unknown> = {}) => {
+  vi.mocked(fs.readFile).mockResolvedValueOnce(
+    JSON.stringify({ etag: etag, body: body, storedAt: storedAt, ...extra })
+  );
+};
+
+const noCachedEntry = () => {
+  vi.mocked(fs.readFile).mockRejectedValueOnce(new Error('ENOENT'));
+};
+
+describe('The HTTP cache', () => {
+  beforeEach<LocalTestContext>((context) => {
+    vi.resetAllMocks();
+    context.cacheDirectory = chance.word();
+    context.url = chance.url({ protocol: 'https' });
+    context.etag = `"${chance.hash()}"`;
+    context.body = JSON.stringify({ data: chance.sentence() });
+    setNow(() => storedAt);
+  });
+
+  afterEach(() => {
+    setNow();
+    setCacheTtl();
+    resetMetrics();
+  });
+
+  it<LocalTestContext>('returns the cached body when the server says it is not modified', async (context) => {
+    const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+    cachedEntry(context.etag, context.body);
+
+    const response = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+    expect(response.status).toEqual(200);
+    expect(await response.text()).toEqual(context.body);
+    expect(response.headers.get('ETag')).toEqual(context.etag);
+    expect(fs.writeFile).not.toHaveBeenCalled();
+  });
+
+  it<LocalTestContext>('counts the cached body as a cache hit', async (context) => {
+    const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+    cachedEntry(context.etag, context.body);
+
+    await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+    expect(getMetrics().cacheHits).toEqual(1);
+  });
+
+  it<LocalTestContext>('sends the cached etag with the request', async (context) => {
+    const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+    cachedEntry(context.etag, context.body);
+
+    await cachingFetch(context.cacheDirectory, fetcher)(context.url, { headers: { Accept: 'application/json' } });
+
+    const sentHeaders = fetcher.mock.calls[0][1].headers as Headers;
+    expect(sentHeaders.get('If-None-Match')).toEqual(context.etag);
+    expect(sentHeaders.get('Accept')).toEqual('application/json');
+  });
+
+  it<LocalTestContext>('stores a fresh response that has an etag', async (context) => {
+    const fetcher = vi.fn().mockResolvedValueOnce(new Response(context.body, { headers: { ETag: context.etag } }));
+    noCachedEntry();
+
+    const response = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+    expect(await response.text()).toEqual(context.body);
+    expect((fetcher.mock.calls[0][1].headers as Headers).has('If-None-Match')).toBeFalsy();
+    expect(getMetrics().cacheHits).toEqual(0);
+    expect(fs.mkdir).toHaveBeenCalledWith(context.cacheDirectory, { recursive: true });
+
+    const [cacheFile, contents] = vi.mocked(fs.writeFile).mock.calls[0];
+    expect(path.dirname(cacheFile as string)).toEqual(path.resolve(context.cacheDirectory));
+    expect(JSON.parse(contents as string)).toEqual({ etag: context.etag, body: context.body, storedAt: storedAt });
+  });
+
+  it<LocalTestContext>('replaces the cached body when the server sends a new one', async (context) => {
+    const newEtag = `"${chance.hash()}"`;
+    const newBody = JSON.stringify({ data: chance.sentence() });
+    const fetcher = vi.fn().mockResolvedValueOnce(new Response(newBody, { headers: { ETag: newEtag } }));
+    cachedEntry(context.etag, context.body);
+
+    const response = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+    expect(await response.json()).toEqual(JSON.parse(newBody));
+    expect(JSON.parse(vi.mocked(fs.writeFile).mock.calls[0][1] as string)).toEqual({
+      etag: newEtag,
+      body: newBody,
+      storedAt: storedAt
+    });
+  });
+
+  it<LocalTestContext>('uses the same cache file for the same url', async (context) => {
+    const fetcher = vi.fn().mockImplementation(() => new Response('{}', { headers: { ETag: context.etag } }));
+    noCachedEntry();
+    noCachedEntry();
+
+    const cachedFetch = cachingFetch(context.cacheDirectory, fetcher);
+    await cachedFetch(context.url);
+    await cachedFetch(context.url);
+
+    const cacheFiles = vi.mocked(fs.writeFile).mock.calls.map((call) => call[0]);
+    expect(cacheFiles[0]).toEqual(cacheFiles[1]);
+  });
+
+  it<LocalTestContext>('does not store a response without an etag or a date', async (context) => {
+    const response = new Response(context.body);
+    const fetcher = vi.fn().mockResolvedValueOnce(response);
+    noCachedEntry();
+
+    const actual = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+    expect(actual).toBe(response);
+    expect(fs.writeFile).not.toHaveBeenCalled();
+  });
+
+  it<LocalTestContext>('does not store a failed response', async (context) => {
+    const response = new Response(context.body, { status: 404, headers: { ETag: context.etag } });
+    const fetcher = vi.fn().mockResolvedValueOnce(response);
+    noCachedEntry();
+
+    const actual = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+    expect(actual).toBe(response);
+    expect(fs.writeFile).not.toHaveBeenCalled();
+  });
+
+  it<LocalTestContext>('ignores a broken cache entry', async (context) => {
+    const response = new Response(null, { status: 304 });
+    const fetcher = vi.fn().mockResolvedValueOnce(response);
+    vi.mocked(fs.readFile).mockResolvedValueOnce(JSON.stringify({ etag: context.etag }));
+
+    const actual = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+    expect((fetcher.mock.calls[0][1].headers as Headers).has('If-None-Match')).toBeFalsy();
+    expect(actual).toBe(response);
+  });
+
+  it<LocalTestContext>('ignores a cache entry without a validator', async (context) => {
+    const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+    vi.mocked(fs.readFile).mockResolvedValueOnce(JSON.stringify({ body: context.body }));
+
+    await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+    const sentHeaders = fetcher.mock.calls[0][1].headers as Headers;
+    expect(sentHeaders.has('If-None-Match')).toBeFalsy();
+    expect(sentHeaders.has('If-Modified-Since')).toBeFalsy();
+  });
+
+  it<LocalTestContext>('ignores a cache entry with a broken validator', async (context) => {
+    const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+    vi.mocked(fs.readFile).mockResolvedValueOnce(JSON.stringify({ etag: context.etag, lastModified: 1, body: '' }));
+
+    await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+    expect((fetcher.mock.calls[0][1].headers as Headers).has('If-None-Match')).toBeFalsy();
+  });
+
+  describe('when the server only sends a Last-Modified date', () => {
+    it<LocalTestContext>('stores the response with the date', async (context) => {
+      const headers = { 'Last-Modified': lastModified };
+      const fetcher = vi.fn().mockResolvedValueOnce(new Response(context.body, { headers: headers }));
+      noCachedEntry();
+
+      const response = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      expect(await response.text()).toEqual(context.body);
+      expect(JSON.parse(vi.mocked(fs.writeFile).mock.calls[0][1] as string)).toEqual({
+        lastModified: lastModified,
+        body: context.body,
+        storedAt: storedAt
+      });
+    });
+
+    it<LocalTestContext>('revalidates with the date and reuses the body', async (context) => {
+      const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+      vi.mocked(fs.readFile).mockResolvedValueOnce(
+        JSON.stringify({ lastModified: lastModified, body: context.body, storedAt: storedAt })
+      );
+
+      const response = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      const sentHeaders = fetcher.mock.calls[0][1].headers as Headers;
+      expect(sentHeaders.get('If-Modified-Since')).toEqual(lastModified);
+      expect(sentHeaders.has('If-None-Match')).toBeFalsy();
+      expect(response.status).toEqual(200);
+      expect(await response.text()).toEqual(context.body);
+      expect(response.headers.get('Last-Modified')).toEqual(lastModified);
+      expect(response.headers.has('ETag')).toBeFalsy();
+      expect(fs.writeFile).not.toHaveBeenCalled();
+    });
+  });
+
+  describe('when the server sends both an etag and a Last-Modified date', () => {
+    it<LocalTestContext>('stores both', async (context) => {
+      const headers = { ETag: context.etag, 'Last-Modified': lastModified };
+      const fetcher = vi.fn().mockResolvedValueOnce(new Response(context.body, { headers: headers }));
+      noCachedEntry();
+
+      await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      expect(JSON.parse(vi.mocked(fs.writeFile).mock.calls[0][1] as string)).toEqual({
+        etag: context.etag,
+        lastModified: lastModified,
+        body: context.body,
+        storedAt: storedAt
+      });
+    });
+
+    it<LocalTestContext>('revalidates with the etag', async (context) => {
+      const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+      vi.mocked(fs.readFile).mockResolvedValueOnce(
+        JSON.stringify({ etag: context.etag, lastModified: lastModified, body: context.body, storedAt: storedAt })
+      );
+
+      const response = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      const sentHeaders = fetcher.mock.calls[0][1].headers as Headers;
+      expect(sentHeaders.get('If-None-Match')).toEqual(context.etag);
+      expect(sentHeaders.has('If-Modified-Since')).toBeFalsy();
+      expect(response.headers.get('ETag')).toEqual(context.etag);
+      expect(response.headers.get('Last-Modified')).toEqual(lastModified);
+    });
+  });
+
+  describe('when an entry gets old', () => {
+    it.each([
+      ['max-age=300', 300],
+      ['public, max-age=60, must-revalidate', 60],
+      ['MAX-AGE="120"', 120],
+      ['s-maxage=300', undefined],
+      ['no-cache', undefined],
+      [null, undefined]
+    ])('reads the max-age of %j as %j', (cacheControl, expected) => {
+      expect(parseMaxAge(cacheControl)).toEqual(expected);
+    });
+
+    it('is off until a directory is set', () => {
+      expect(getHttpCacheDirectory()).toBeUndefined();
+
+      setHttpCacheDirectory('/tmp/mmm');
+      expect(getHttpCacheDirectory()).toEqual('/tmp/mmm');
+
+      setHttpCacheDirectory();
+      expect(getHttpCacheDirectory()).toBeUndefined();
+    });
+
+    it('has a default TTL that can be set and reset', () => {
+      expect(getCacheTtl()).toEqual(defaultCacheTtl);
+
+      setCacheTtl(30);
+      expect(getCacheTtl()).toEqual(30);
+
+      setCacheTtl();
+      expect(getCacheTtl()).toEqual(defaultCacheTtl);
+    });
+
+    it<LocalTestContext>('serves an entry within the TTL from the cache', async (context) => {
+      const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+      cachedEntry(context.etag, context.body);
+      setNow(() => storedAt + (defaultCacheTtl - 1) * 1000);
+
+      const response = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      expect((fetcher.mock.calls[0][1].headers as Headers).get('If-None-Match')).toEqual(context.etag);
+      expect(await response.text()).toEqual(context.body);
+    });
+
+    it<LocalTestContext>('downloads an entry past the TTL again without the etag', async (context) => {
+      const newBody = JSON.stringify({ data: chance.sentence() });
+      const fetcher = vi.fn().mockResolvedValueOnce(new Response(newBody, { headers: { ETag: context.etag } }));
+      cachedEntry(context.etag, context.body);
+      const later = storedAt + defaultCacheTtl * 1000;
+      setNow(() => later);
+
+      const response = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      const sentHeaders = fetcher.mock.calls[0][1].headers as Headers;
+      expect(sentHeaders.has('If-None-Match')).toBeFalsy();
+      expect(sentHeaders.has('If-Modified-Since')).toBeFalsy();
+      expect(await response.text()).toEqual(newBody);
+      expect(JSON.parse(vi.mocked(fs.writeFile).mock.calls[0][1] as string)).toEqual({
+        etag: context.etag,
+        body: newBody,
+        storedAt: later
+      });
+    });
+
+    it<LocalTestContext>('does not serve an expired entry when the server says 304 anyway', async (context) => {
+      const response = new Response(null, { status: 304 });
+      const fetcher = vi.fn().mockResolvedValueOnce(response);
+      cachedEntry(context.etag, context.body);
+      setNow(() => storedAt + defaultCacheTtl * 1000);
+
+      const actual = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      expect(actual).toBe(response);
+    });
+
+    it<LocalTestContext>('uses the TTL that was set', async (context) => {
+      const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+      cachedEntry(context.etag, context.body);
+      setCacheTtl(10);
+      setNow(() => storedAt + 10_000);
+
+      await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      expect((fetcher.mock.calls[0][1].headers as Headers).has('If-None-Match')).toBeFalsy();
+    });
+
+    it<LocalTestContext>('prefers the max-age of the response to the TTL', async (context) => {
+      const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+      cachedEntry(context.etag, context.body, { maxAge: 60 });
+      setNow(() => storedAt + 60_000);
+
+      await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      expect((fetcher.mock.calls[0][1].headers as Headers).has('If-None-Match')).toBeFalsy();
+    });
+
+    it<LocalTestContext>('stores the max-age of the response', async (context) => {
+      const headers = { ETag: context.etag, 'Cache-Control': 'public, max-age=300' };
+      const fetcher = vi.fn().mockResolvedValueOnce(new Response(context.body, { headers: headers }));
+      noCachedEntry();
+
+      await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      expect(JSON.parse(vi.mocked(fs.writeFile).mock.calls[0][1] as string)).toEqual({
+        etag: context.etag,
+        body: context.body,
+        storedAt: storedAt,
+        maxAge: 300
+      });
+    });
+
+    it<LocalTestContext>('treats an entry without the time it was stored as expired', async (context) => {
+      const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+      vi.mocked(fs.readFile).mockResolvedValueOnce(JSON.stringify({ etag: context.etag, body: context.body }));
+
+      await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      expect((fetcher.mock.calls[0][1].headers as Headers).has('If-None-Match')).toBeFalsy();
+    });
+
+    it<LocalTestContext>('ignores an entry with a broken time', async (context) => {
+      const fetcher = vi.fn().mockResolvedValueOnce(new Response(null, { status: 304 }));
+      cachedEntry(context.etag, context.body, { maxAge: 'forever' });
+
+      await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+      expect((fetcher.mock.calls[0][1].headers as Headers).has('If-None-Match')).toBeFalsy();
+    });
+  });
+
+  it<LocalTestContext>('still returns the response when the cache cannot be written', async (context) => {
+    const fetcher = vi.fn().mockResolvedValueOnce(new Response(context.body, { headers: { ETag: context.etag } }));
+    noCachedEntry();
+    vi.mocked(fs.writeFile).mockRejectedValueOnce(new Error('EACCES'));
+
+    const response = await cachingFetch(context.cacheDirectory, fetcher)(context.url);
+
+    expect(await response.text()).toEqual(context.body);
+  });
+
+  it<LocalTestContext>('uses the rate limited fetch by default', async (context) => {
+    vi.mocked(rateLimitingFetch).mockResolvedValueOnce(new Response(null, { status: 304 }));
+    cachedEntry(context.etag, context.body);
+
+    const response = await cachingFetch(context.cacheDirectory)(
+      new Request(context.url, { headers: { Accept: 'application/json' } })
+    );
+
+    const sentHeaders = vi.mocked(rateLimitingFetch).mock.calls[0][1]?.headers as Headers;
+    expect(sentHeaders.get('Accept')).toEqual('application/json');
+    expect(await response.text()).toEqual(context.body);
+  });
+
+  it<LocalTestContext>('does not cache anything but GET requests', async (context) => {
+    const response = new Response(context.body, { headers: { ETag: context.etag } });
+    const fetcher = vi.fn().mockResolvedValueOnce(response);
+    const init = { method: 'POST', body: '{}' };
+
+    const actual = await cachingFetch(context.cacheDirectory, fetcher)(context.url, init);
+
+    expect(actual).toBe(response);
+    expect(fetcher).toHaveBeenCalledWith(context.url, init);
+    expect(fs.readFile).not.toHaveBeenCalled();
+  });
+});
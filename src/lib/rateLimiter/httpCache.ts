@@ -0,0 +1,185 @@
+import * as crypto from 'crypto';
+import fs from 'node:fs/promises';
+import path from 'path';
+import { countCacheHit } from '../metrics.js';
+import { now } from './clock.js';
+import { rateLimitingFetch } from './index.js';
+import { Fetcher } from './transport.js';
+
+const NOT_MODIFIED = 304;
+
+export const defaultCacheTtl = 60 * 60;
+
+let cacheTtl = defaultCacheTtl;
+
+/**
+ * Sets how many seconds a cached response can be revalidated for when the server doesn't send a max-age.
+ * Calling it without a value restores the default.
+ */
+export const setCacheTtl = (seconds?: number) => {
+  cacheTtl = seconds ?? defaultCacheTtl;
+};
+
+export const getCacheTtl = () => cacheTtl;
+
+let httpCacheDirectory: string | undefined;
+
+/**
+ * Sends the requests of the rate limiter through the cache in the given directory.
+ * Calling it without a value turns the cache off, which is the default.
+ */
+export const setHttpCacheDirectory = (directory?: string) => {
+  httpCacheDirectory = directory;
+};
+
+export const getHttpCacheDirectory = () => httpCacheDirectory;
+
+/**
+ * Some proxies only send a Last-Modified, an entry has at least one of the validators
+ */
+interface CacheEntry {
+  etag?: string;
+  lastModified?: string;
+  body: string;
+  /**
+   * When the body was downloaded, in milliseconds. A 304 doesn't move it.
+   */
+  storedAt?: number;
+  /**
+   * The seconds from the Cache-Control of the response, the TTL is used without it
+   */
+  maxAge?: number;
+}
+
+const isValidator = (value: unknown) => value === undefined || typeof value === 'string';
+
+const isOptionalNumber = (value: unknown) => value === undefined || typeof value === 'number';
+
+/**
+ * Reads the max-age of a Cache-Control header like: public, max-age=300
+ */
+export const parseMaxAge = (cacheControl: string | null): number | undefined => {
+  const match = cacheControl?.match(/(?:^|,)\s*max-age\s*=\s*"?(\d+)"?\s*(?:,|$)/i);
+  return match ? Number(match[1]) : undefined;
+};
+
+/**
+ * An entry that was served longer than its max-age is downloaded again, even when the server still says 304.
+ * The entries of the versions that didn't store the time are treated as expired.
+ */
+const isExpired = (entry: CacheEntry) => {
+  if (entry.storedAt === undefined) {
+    return true;
+  }
+  return now() - entry.storedAt >= (entry.maxAge ?? cacheTtl) * 1000;
+};
+
+const cacheFileFor = (cacheDirectory: string, url: string) => {
+  const key = crypto.createHash('sha1').update(url).digest('hex');
+  return path.resolve(cacheDirectory, `${key}.json`);
+};
+
+const readEntry = async (cacheFile: string): Promise<CacheEntry | null> => {
+  try {
+    const entry = JSON.parse(await fs.readFile(cacheFile, 'utf-8'));
+    if (typeof entry?.body !== 'string' || !isValidator(entry.etag) || !isValidator(entry.lastModified)) {
+      return null;
+    }
+    if (!isOptionalNumber(entry.storedAt) || !isOptionalNumber(entry.maxAge)) {
+      return null;
+    }
+    if (!entry.etag && !entry.lastModified) {
+      return null;
+    }
+    return entry;
+  } catch {
+    return null;
+  }
+};
+
+const writeEntry = async (cacheDirectory: string, cacheFile: string, entry: CacheEntry) => {
+  try {
+    await fs.mkdir(cacheDirectory, { recursive: true });
+    await fs.writeFile(cacheFile, JSON.stringify(entry));
+  } catch {
+    // A cache that can't be written is just a cache miss next time
+  }
+};
+
+const cachedResponse = (entry: CacheEntry) => {
+  const headers = new Headers();
+  if (entry.etag) {
+    headers.set('ETag', entry.etag);
+  }
+  if (entry.lastModified) {
+    headers.set('Last-Modified', entry.lastModified);
+  }
+  return new Response(entry.body, {
+    status: 200,
+    headers: headers
+  });
+};
+
+/**
+ * The ETag is the stronger validator, the date is only sent when there is nothing better
+ */
+const setConditionalHeaders = (headers: Headers, entry: CacheEntry) => {
+  if (entry.etag) {
+    headers.set('If-None-Match', entry.etag);
+    return;
+  }
+  headers.set('If-Modified-Since', entry.lastModified as string);
+};
+
+/**
+ * Wraps a fetcher with an on-disk cache of the GET responses that come with an ETag or a Last-Modified date.
+ * Cached responses are revalidated with If-None-Match, or If-Modified-Since without an ETag, and reused when the
+ * server answers 304 Not Modified.
+ * An entry older than the max-age of its Cache-Control, or the TTL without one, is fetched again without the
+ * validators, so a proxy that keeps answering 304 can't serve the same body forever.
+ */
+export const cachingFetch = (cacheDirectory: string, fetcher: Fetcher = rateLimitingFetch): Fetcher => {
+  return async (input: RequestInfo | URL, init?: RequestInit): Promise<Response> => {
+    const request = new Request(input instanceof Request ? input.clone() : input, init);
+    if (request.method !== 'GET') {
+      return fetcher(input, init);
+    }
+
+    const cacheFile = cacheFileFor(cacheDirectory, request.url);
+    const storedEntry = await readEntry(cacheFile);
+    const entry = storedEntry && !isExpired(storedEntry) ? storedEntry : null;
+
+    const headers = new Headers(init?.headers ?? (input instanceof Request ? input.headers : undefined));
+    if (entry) {
+      setConditionalHeaders(headers, entry);
+    }
+
+    const response = await fetcher(input, { ...init, headers: headers });
+
+    if (entry && response.status === NOT_MODIFIED) {
+      countCacheHit();
+      return cachedResponse(entry);
+    }
+
+    const etag = response.headers.get('ETag') ?? undefined;
+    const lastModified = response.headers.get('Last-Modified') ?? undefined;
+    if (!response.ok || (!etag && !lastModified)) {
+      return response;
+    }
+
+    const freshEntry: CacheEntry = {
+      etag: etag,
+      lastModified: lastModified,
+      body: await response.text(),
+      storedAt: now(),
+      maxAge: parseMaxAge(response.headers.get('Cache-Control'))
+    };
+    await writeEntry(cacheDirectory, cacheFile, freshEntry);
+
+    return new Response(freshEntry.body, {
+      status: response.status,
+      statusText: response.statusText,
+      headers: response.headers
+    });
+  };
+};
@@ -1,7 +1,22 @@
+import fs from 'node:fs/promises';
+import os from 'node:os';
+import path from 'node:path';
 import { chance } from 'jest-chance';
-import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { OfflineException } from '../../errors/OfflineException.js';
+import { getMetrics, resetMetrics } from '../metrics.js';
+import { Platform } from '../modlist.types.js';
+import { setOfflineMode } from '../offline.js';
+import { CircuitOpen } from './CircuitOpen.js';
+import { CurseforgeUnauthorized } from './CurseforgeUnauthorized.js';
 import { MaximumRetriesReached } from './MaximumRetriesReached.js';
-import { RateLimit, rateLimitingFetch } from './index.js';
+import { setAttemptListener } from './attempts.js';
+import { Backoff } from './backoff.js';
+import { setCircuitBreaker } from './circuitBreaker.js';
+import { setNow, setSleep } from './clock.js';
+import { setHttpCacheDirectory } from './httpCache.js';
+import { RateLimit, burstRateLimit, rateLimitBudget, rateLimitingFetch } from './index.js';
+import { setPlatformRateLimit } from './platformLimits.js';
 import { Queue } from './queue.js';
 
 import { FetchJob } from './FetchJob.js';
@@ -44,6 +59,10 @@ describe('The Rate Limiting Library', () => {
       }) as unknown as Response;
   });
 
+  afterEach(() => {
+    setOfflineMode();
+  });
+
   it<LocalTestContext>('can resolve successfully', async ({ randomResponse, init, input, rateLimit }) => {
     const response = randomResponse();
     vi.mocked(fetch).mockResolvedValueOnce(response);
@@ -52,6 +71,15 @@ describe('The Rate Limiting Library', () => {
     expect(actual).toBe(response);
   });
 
+  it<LocalTestContext>('does not make requests in offline mode', async ({ init, input, rateLimit }) => {
+    setOfflineMode(true);
+
+    await expect(rateLimitingFetch(input, init, rateLimit)).rejects.toThrow(
+      new OfflineException(new Request(input).url)
+    );
+    expect(vi.mocked(fetch)).not.toHaveBeenCalled();
+  });
+
   it<LocalTestContext>('can reject successfully', async ({ randomResponse, init, input }) => {
     const response = randomResponse(false);
     vi.mocked(fetch).mockResolvedValue(response);
@@ -65,6 +93,16 @@ describe('The Rate Limiting Library', () => {
     expect(fetch).toHaveBeenCalledTimes(3); //maxAttempts amount of times
   });
 
+  it<LocalTestContext>('does not retry when curseforge refuses the api key', async ({ randomResponse, init }) => {
+    vi.mocked(fetch).mockResolvedValue({ ...randomResponse(false), status: 403 } as Response);
+
+    await expect(
+      rateLimitingFetch('https://api.curseforge.com/v1/mods/1', init, { timeBetweenCalls: 0, maxAttempts: 3 })
+    ).rejects.toThrow(CurseforgeUnauthorized);
+
+    expect(fetch).toHaveBeenCalledTimes(1);
+  });
+
   it<LocalTestContext>('can throw successfully', async ({ init, input }) => {
     const error = new Error('happens rarely');
     vi.mocked(fetch).mockRejectedValue(error);
@@ -78,6 +116,299 @@ describe('The Rate Limiting Library', () => {
     expect(fetch).toHaveBeenCalledTimes(1); //regardless of max retries, we reject after the first
   });
 
+  it<LocalTestContext>('retries transient network errors', async ({ randomResponse, init, input }) => {
+    const response = randomResponse();
+    const networkError = new TypeError('fetch failed', { cause: Object.assign(new Error(), { code: 'ETIMEDOUT' }) });
+    vi.mocked(fetch).mockRejectedValueOnce(networkError);
+    vi.mocked(fetch).mockRejectedValueOnce(networkError);
+    vi.mocked(fetch).mockResolvedValueOnce(response);
+
+    const actual = await rateLimitingFetch(input, init, {
+      maxAttempts: 3,
+      timeBetweenCalls: 0
+    });
+
+    expect(actual).toBe(response);
+    expect(fetch).toHaveBeenCalledTimes(3);
+  });
+
+  it<LocalTestContext>('gives up on transient network errors after the max attempts', async ({ init, input }) => {
+    const networkError = new TypeError('fetch failed', { cause: Object.assign(new Error(), { code: 'ECONNREFUSED' }) });
+    vi.mocked(fetch).mockRejectedValue(networkError);
+
+    await expect(
+      rateLimitingFetch(input, init, {
+        maxAttempts: 3,
+        timeBetweenCalls: 0
+      })
+    ).rejects.toThrow(networkError);
+
+    expect(fetch).toHaveBeenCalledTimes(3);
+  });
+
+  it<LocalTestContext>('rejects a cancelled request while it is waiting in the queue', async ({ init, input }) => {
+    const controller = new AbortController();
+    const request = rateLimitingFetch(input, { ...init, signal: controller.signal });
+
+    controller.abort();
+
+    await expect(request).rejects.toHaveProperty('name', 'AbortError');
+    // give the queue a chance to pick the job up
+    await new Promise((resolve) => setTimeout(resolve, 150));
+    expect(fetch).not.toHaveBeenCalled();
+  });
+
+  it<LocalTestContext>('uses the rate limit of the platform the request goes to', async ({ randomResponse, init }) => {
+    vi.mocked(fetch).mockImplementation(async () => randomResponse(false));
+    setPlatformRateLimit(Platform.CURSEFORGE, { timeBetweenCalls: 0, maxAttempts: 2 });
+    setPlatformRateLimit(Platform.MODRINTH, { timeBetweenCalls: 0, maxAttempts: 1 });
+
+    await Promise.allSettled([
+      rateLimitingFetch('https://api.curseforge.com/v1/mods/1', init),
+      rateLimitingFetch('https://api.modrinth.com/v2/project/a', init)
+    ]);
+
+    const calledHosts = vi.mocked(fetch).mock.calls.map((call) => new URL(call[0] as string).host);
+    expect(calledHosts.filter((host) => host === 'api.curseforge.com')).toHaveLength(2);
+    expect(calledHosts.filter((host) => host === 'api.modrinth.com')).toHaveLength(1);
+
+    setPlatformRateLimit(Platform.CURSEFORGE);
+    setPlatformRateLimit(Platform.MODRINTH);
+  });
+
+  it<LocalTestContext>('reports every attempt of a retried request', async ({ randomResponse, init, input }) => {
+    const listener = vi.fn();
+    setAttemptListener(listener);
+    vi.mocked(fetch).mockResolvedValueOnce({ ...randomResponse(false), status: 500 } as Response);
+    vi.mocked(fetch).mockResolvedValueOnce({ ...randomResponse(false), status: 502 } as Response);
+    vi.mocked(fetch).mockResolvedValueOnce({ ...randomResponse(), status: 200 } as Response);
+
+    await rateLimitingFetch(input, init, { timeBetweenCalls: 0, maxAttempts: 3 });
+
+    expect(listener).toHaveBeenCalledTimes(3);
+    expect(listener.mock.calls.map((call) => [call[0].attempt, call[0].status])).toEqual([
+      [1, 500],
+      [2, 502],
+      [3, 200]
+    ]);
+    expect(listener.mock.calls[0][0].url).toEqual(new URL(input as string).href);
+    expect(listener.mock.calls[0][0].duration).toBeGreaterThanOrEqual(0);
+
+    setAttemptListener();
+  });
+
+  it<LocalTestContext>('reports the error of a failed attempt', async ({ init, input }) => {
+    const listener = vi.fn();
+    const error = new Error(chance.sentence());
+    setAttemptListener(listener);
+    vi.mocked(fetch).mockRejectedValueOnce(error);
+
+    await expect(rateLimitingFetch(input, init, { timeBetweenCalls: 0, maxAttempts: 1 })).rejects.toBe(error);
+
+    expect(listener).toHaveBeenCalledOnce();
+    expect(listener.mock.calls[0][0]).toMatchObject({ attempt: 1, status: null, error: error });
+
+    setAttemptListener();
+  });
+
+  it<LocalTestContext>('tallies the requests in the metrics', async ({ randomResponse, init }) => {
+    const url = chance.url({ protocol: 'https' });
+    resetMetrics();
+    vi.mocked(fetch).mockResolvedValueOnce({ ...randomResponse(false), status: 503 } as Response);
+    vi.mocked(fetch).mockResolvedValueOnce({ ...randomResponse(), status: 200 } as Response);
+    vi.mocked(fetch).mockResolvedValueOnce({ ...randomResponse(false), status: 404 } as Response);
+    vi.mocked(fetch).mockRejectedValueOnce(new Error(chance.sentence()));
+
+    await rateLimitingFetch(url, init, { timeBetweenCalls: 0, maxAttempts: 3 });
+    await expect(rateLimitingFetch(url, init, { timeBetweenCalls: 0, maxAttempts: 1 })).rejects.toThrow(
+      MaximumRetriesReached
+    );
+    await expect(rateLimitingFetch(url, init, { timeBetweenCalls: 0, maxAttempts: 1 })).rejects.toThrow();
+
+    expect(getMetrics()).toEqual({
+      requests: 4,
+      retries: 1,
+      clientErrors: 1,
+      serverErrors: 1,
+      cacheHits: 0,
+      downloadedBytes: 0
+    });
+    resetMetrics();
+  });
+
+  it<LocalTestContext>('waits on the clock between the retries', async ({ randomResponse, init, input }) => {
+    const sleeps: number[] = [];
+    setSleep(async (milliseconds) => {
+      sleeps.push(milliseconds);
+    });
+    vi.mocked(fetch).mockResolvedValueOnce(randomResponse(false));
+    vi.mocked(fetch).mockResolvedValueOnce(randomResponse(false));
+    vi.mocked(fetch).mockResolvedValueOnce(randomResponse(false));
+    vi.mocked(fetch).mockResolvedValueOnce(randomResponse());
+
+    const startedAt = performance.now();
+    await rateLimitingFetch(input, init, { timeBetweenCalls: 10000, maxAttempts: 4, backoff: Backoff.EXPONENTIAL });
+
+    // 100 for the initial process delay, then the backoff of every retry
+    expect(sleeps).toEqual([100, 10000, 20000, 40000]);
+    expect(performance.now() - startedAt).toBeLessThan(1000);
+
+    setSleep();
+  });
+
+  describe('when the rate limit has a burst', () => {
+    let time: number;
+    let sleeps: number[];
+
+    beforeEach(() => {
+      time = 0;
+      sleeps = [];
+      setNow(() => time);
+      setSleep(async (milliseconds) => {
+        sleeps.push(milliseconds);
+        time += milliseconds;
+      });
+    });
+
+    afterEach(() => {
+      setNow();
+      setSleep();
+    });
+
+    const sendBatch = (url: string, size: number, rateLimit: RateLimit) =>
+      Promise.all(Array.from({ length: size }, () => rateLimitingFetch(url, {}, rateLimit)));
+
+    it('can be built from the requests a second', () => {
+      expect(burstRateLimit(4, 10)).toEqual({ timeBetweenCalls: 250, maxAttempts: 3, burst: 10 });
+      expect(burstRateLimit(4, 10, 5)).toEqual({ timeBetweenCalls: 250, maxAttempts: 5, burst: 10 });
+    });
+
+    it<LocalTestContext>('sends a batch of the burst size at once', async ({ randomResponse }) => {
+      vi.mocked(fetch).mockResolvedValue(randomResponse());
+
+      await sendBatch(chance.url(), 3, burstRateLimit(1, 3));
+
+      // 100 for the initial process delay
+      expect(sleeps).toEqual([100, 0, 0]);
+      expect(fetch).toHaveBeenCalledTimes(3);
+    });
+
+    it<LocalTestContext>('spaces out the requests over the burst', async ({ randomResponse }) => {
+      vi.mocked(fetch).mockResolvedValue(randomResponse());
+
+      await sendBatch(chance.url(), 5, burstRateLimit(1, 3));
+
+      expect(sleeps).toEqual([100, 0, 0, 900, 1000]);
+    });
+
+    it<LocalTestContext>('waits for the bucket to fill up before the next batch', async ({ randomResponse }) => {
+      const url = chance.url();
+      vi.mocked(fetch).mockResolvedValue(randomResponse());
+      await sendBatch(url, 3, burstRateLimit(1, 3));
+      // The queue goes quiet once the last request has settled
+      await new Promise((resolve) => setTimeout(resolve, 0));
+
+      time += 2000;
+      sleeps = [];
+      await sendBatch(url, 3, burstRateLimit(1, 3));
+
+      // A tenth of a token was left, the quiet two seconds and the initial process delay add 2.1 more
+      expect(sleeps).toEqual([100, 0, 800]);
+    });
+
+    it<LocalTestContext>('tells what is left of the burst of the host', async ({ randomResponse }) => {
+      const url = chance.url({ protocol: 'https' });
+      const host = new URL(url).hostname;
+      vi.mocked(fetch).mockResolvedValue(randomResponse());
+
+      expect(rateLimitBudget(host)).toBeUndefined();
+
+      await sendBatch(url, 2, burstRateLimit(1, 3));
+      const afterTwo = rateLimitBudget(host);
+      await sendBatch(url, 3, burstRateLimit(1, 3));
+      const afterFive = rateLimitBudget(host);
+
+      expect(afterTwo).toEqual({ tokens: 1, nextTokenIn: 0 });
+      // The last request waited for its token, the next one has to wait a whole second
+      expect(afterFive).toEqual({ tokens: 0, nextTokenIn: 1000 });
+    });
+
+    it<LocalTestContext>('does not use the burst for the retries', async ({ randomResponse }) => {
+      vi.mocked(fetch).mockResolvedValueOnce(randomResponse(false));
+      vi.mocked(fetch).mockResolvedValue(randomResponse());
+
+      await rateLimitingFetch(chance.url(), {}, burstRateLimit(1, 3));
+
+      expect(sleeps).toEqual([100, 1000]);
+    });
+  });
+
+  describe('when a host keeps failing', () => {
+    let time: number;
+
+    beforeEach(() => {
+      time = 0;
+      setNow(() => time);
+      setSleep(async () => {});
+      setCircuitBreaker({ failureThreshold: 3, cooldown: 30000 });
+    });
+
+    afterEach(() => {
+      setNow();
+      setSleep();
+      setCircuitBreaker();
+    });
+
+    const serverError = (randomResponse: LocalTestContext['randomResponse']) =>
+      ({ ...randomResponse(false), status: 503 }) as Response;
+
+    it<LocalTestContext>('fails the requests fast until the cooldown is over', async ({ randomResponse, init }) => {
+      const url = chance.url({ protocol: 'https' });
+      vi.mocked(fetch).mockResolvedValue(serverError(randomResponse));
+
+      await expect(rateLimitingFetch(url, init, { timeBetweenCalls: 0, maxAttempts: 3 })).rejects.toThrow(
+        MaximumRetriesReached
+      );
+      expect(fetch).toHaveBeenCalledTimes(3);
+
+      time += 29999;
+      await expect(rateLimitingFetch(url, init)).rejects.toThrow(CircuitOpen);
+      expect(fetch).toHaveBeenCalledTimes(3);
+    });
+
+    it<LocalTestContext>('recovers once a probe after the cooldown works', async ({ randomResponse, init }) => {
+      const url = chance.url({ protocol: 'https' });
+      const response = randomResponse();
+      vi.mocked(fetch).mockResolvedValueOnce(serverError(randomResponse));
+      vi.mocked(fetch).mockResolvedValueOnce(serverError(randomResponse));
+      vi.mocked(fetch).mockResolvedValueOnce(serverError(randomResponse));
+      vi.mocked(fetch).mockResolvedValue(response);
+      await expect(rateLimitingFetch(url, init, { timeBetweenCalls: 0, maxAttempts: 3 })).rejects.toThrow(
+        MaximumRetriesReached
+      );
+
+      time += 30000;
+
+      await expect(rateLimitingFetch(url, init)).resolves.toBe(response);
+      await expect(rateLimitingFetch(url, init)).resolves.toBe(response);
+      expect(fetch).toHaveBeenCalledTimes(5);
+    });
+
+    it<LocalTestContext>('opens again when the probe fails', async ({ randomResponse, init }) => {
+      const url = chance.url({ protocol: 'https' });
+      vi.mocked(fetch).mockResolvedValue(serverError(randomResponse));
+      await expect(rateLimitingFetch(url, init, { timeBetweenCalls: 0, maxAttempts: 3 })).rejects.toThrow(
+        MaximumRetriesReached
+      );
+
+      time += 30000;
+
+      await expect(rateLimitingFetch(url, init, { timeBetweenCalls: 0, maxAttempts: 3 })).rejects.toThrow(CircuitOpen);
+      await expect(rateLimitingFetch(url, init)).rejects.toThrow(CircuitOpen);
+      expect(fetch).toHaveBeenCalledTimes(4);
+    });
+  });
+
   it<LocalTestContext>('can handle multiple hosts', async ({ randomResponse, init }) => {
     const response1 = randomResponse();
     const response2 = randomResponse();
@@ -126,6 +457,33 @@ describe('The Rate Limiting Library', () => {
     expect(Date.now()).toEqual(600);
   });
 
+  describe('when the http cache is on', () => {
+    let cacheDirectory: string;
+
+    beforeEach(async () => {
+      cacheDirectory = await fs.mkdtemp(path.join(os.tmpdir(), 'mmm-http-cache-'));
+      setHttpCacheDirectory(cacheDirectory);
+    });
+
+    afterEach(async () => {
+      setHttpCacheDirectory();
+      await fs.rm(cacheDirectory, { recursive: true, force: true });
+    });
+
+    it<LocalTestContext>('answers from the cache when nothing changed', async ({ init, rateLimit }) => {
+      const url = chance.url({ protocol: 'https' });
+      const body = JSON.stringify({ data: chance.sentence() });
+      vi.mocked(fetch).mockResolvedValueOnce(new Response(body, { status: 200, headers: { ETag: '"v1"' } }));
+      vi.mocked(fetch).mockResolvedValueOnce(new Response(null, { status: 304 }));
+
+      expect(await (await rateLimitingFetch(url, init, rateLimit)).text()).toEqual(body);
+      const cached = await rateLimitingFetch(url, init, rateLimit);
+
+      expect(await cached.text()).toEqual(body);
+      expect(new Headers(vi.mocked(fetch).mock.calls[1][1]?.headers).get('If-None-Match')).toEqual('"v1"');
+    });
+  });
+
   it<LocalTestContext>('can handle a suddenly empty queue', ({ input }) => {
     /**
      * This is mainly to cover a very slim edge case that should never happen.
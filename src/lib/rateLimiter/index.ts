@@ -1,10 +1,43 @@
+import { OfflineException } from '../../errors/OfflineException.js';
+import { isOfflineMode } from '../offline.js';
 import { FetchJob } from './FetchJob.js';
 import { Retrying } from './Retrying.js';
+import { RetryingOnError } from './RetryingOnError.js';
+import { Backoff } from './backoff.js';
+import { circuitOpenError } from './circuitBreaker.js';
+import { sleep } from './clock.js';
+import { cachingFetch, getHttpCacheDirectory } from './httpCache.js';
+import { rateLimitForHost } from './platformLimits.js';
 import { Queue } from './queue.js';
+import { requestUrl } from './requestUrl.js';
+import { TokenAvailability, TokenBucket } from './tokenBucket.js';
+import { Fetcher } from './transport.js';
 
 export interface RateLimit {
   maxAttempts: number;
   timeBetweenCalls: number;
+  /**
+   * How the time between retries grows. Defaults to a constant timeBetweenCalls.
+   */
+  backoff?: Backoff;
+  /**
+   * A fraction (0-1) of the delay to randomly add or subtract between retries.
+   */
+  jitter?: number;
+  /**
+   * The upper limit of the time between retries in milliseconds.
+   */
+  maxInterval?: number;
+  /**
+   * How long a single attempt may take in milliseconds before it's cut off and retried. Defaults to 30 seconds.
+   */
+  timeout?: number;
+  /**
+   * How many requests can go out back to back before they're spaced out by timeBetweenCalls again.
+   * The bucket of a host is set up by the first request with a burst and fills up while the host is quiet.
+   * A retry doesn't use up the burst, it still waits for its own backoff. Defaults to 1, no bursts.
+   */
+  burst?: number;
 }
 
 interface JobState {
@@ -17,14 +50,33 @@ interface QueueRecord {
   queue: Queue<FetchJob>;
 }
 
+interface BucketRecord {
+  host: string;
+  bucket: TokenBucket;
+}
+
 const defaultRateLimiting: RateLimit = {
   timeBetweenCalls: 100,
   maxAttempts: 3
 };
 
 const queues: QueueRecord[] = [];
+const buckets: BucketRecord[] = [];
 const state: JobState[] = [];
 
+/**
+ * A rate limit of the given requests a second that lets a burst of them go out at once.
+ */
+export const burstRateLimit = (
+  requestsPerSecond: number,
+  burst: number,
+  maxAttempts = defaultRateLimiting.maxAttempts
+): RateLimit => ({
+  timeBetweenCalls: 1000 / requestsPerSecond,
+  maxAttempts: maxAttempts,
+  burst: burst
+});
+
 const isRunning = (forHost: string) => {
   const index = state.findIndex((s) => s.host === forHost);
 
@@ -64,6 +116,39 @@ const getQueue = (forHost: string): Queue<FetchJob> => {
   return queues[index].queue;
 };
 
+const getBucket = (forHost: string): TokenBucket | undefined => {
+  return buckets.find((b) => b.host === forHost)?.bucket;
+};
+
+const setUpBucket = (forHost: string, rateLimit: RateLimit) => {
+  if (rateLimit.burst && !getBucket(forHost)) {
+    buckets.push({
+      host: forHost,
+      bucket: new TokenBucket(rateLimit.burst, rateLimit.timeBetweenCalls)
+    });
+  }
+};
+
+/**
+ * What is left of the burst of the host, so a long pause can be explained as the rate limit being respected.
+ * A host without a bucket hasn't been called with a burst, its requests go out one by one.
+ */
+export const rateLimitBudget = (forHost: string): TokenAvailability | undefined => {
+  return getBucket(forHost)?.availability();
+};
+
+/**
+ * How long to wait before the next request to the host. A retry or a throttled host waits for what the job says,
+ * everything else waits for a token when the host has a bucket.
+ */
+const nextDelay = (host: string, item: FetchJob, retrying: boolean) => {
+  const bucket = getBucket(host);
+  if (!bucket || retrying || item.isThrottled()) {
+    return item.retryIn();
+  }
+  return bucket.reserve();
+};
+
 const processQueue = (host: string, queue: Queue<FetchJob>) => {
   const item = queue.dequeue();
 
@@ -73,47 +158,75 @@ const processQueue = (host: string, queue: Queue<FetchJob>) => {
   }
 
   mark(host, true);
+  let retrying = false;
 
   item
     .execute()
     .catch((e) => {
-      if (e instanceof Retrying) {
+      if (e instanceof Retrying || e instanceof RetryingOnError) {
+        retrying = true;
         queue.enqueue(item);
       }
     })
     .finally(() => {
       if (!queue.isEmpty()) {
-        setTimeout(() => {
+        sleep(nextDelay(host, item, retrying)).then(() => {
           processQueue(host, queue);
-        }, item.retryIn());
+        });
         return;
       }
       mark(host, false);
     });
 };
 
-export const rateLimitingFetch = (
-  input: RequestInfo | URL,
-  init?: RequestInit,
-  rateLimit?: RateLimit
-): Promise<Response> => {
-  const request = new Request(input);
-  const host = new URL(request.url).hostname;
+const queuedFetch = (input: RequestInfo | URL, init?: RequestInit, rateLimit?: RateLimit): Promise<Response> => {
+  const url = requestUrl(input);
+  if (isOfflineMode()) {
+    return Promise.reject(new OfflineException(url));
+  }
+
+  const host = new URL(url).hostname;
+  // A host that keeps failing gets a break, the request fails without waiting in the queue
+  const circuitOpen = circuitOpenError(host);
+  if (circuitOpen) {
+    return Promise.reject(circuitOpen);
+  }
+
   const jobs = getQueue(host);
 
+  const limit = rateLimit || rateLimitForHost(host) || defaultRateLimiting;
+  setUpBucket(host, limit);
+
   const promise = new Promise<Response>((resolve, reject) => {
-    const job = new FetchJob(input, init || {}, rateLimit || defaultRateLimiting);
+    const job = new FetchJob(input, init || {}, limit);
     job.onResponse(resolve);
     job.onError(reject);
+    init?.signal?.addEventListener('abort', () => reject(init.signal?.reason), { once: true });
     jobs.enqueue(job);
   });
 
   if (!isRunning(host)) {
     mark(host, true);
-    setTimeout(() => {
+    // The first request takes a token too, a burst right after another one has to wait
+    sleep(Math.max(100, getBucket(host)?.reserve() ?? 0)).then(() => {
       processQueue(host, jobs);
-    }, 100);
+    });
   }
 
   return promise;
 };
+
+export const rateLimitingFetch = (
+  input: RequestInfo | URL,
+  init?: RequestInit,
+  rateLimit?: RateLimit
+): Promise<Response> => {
+  // The cache asks the queue itself, only what it can't answer from the disk goes out
+  const cacheDirectory = getHttpCacheDirectory();
+  if (cacheDirectory) {
+    const queued: Fetcher = (cachedInput, cachedInit) => queuedFetch(cachedInput, cachedInit, rateLimit);
+    return cachingFetch(cacheDirectory, queued)(input, init);
+  }
+
+  return queuedFetch(input, init, rateLimit);
+};
@@ -0,0 +1,19 @@
+import { describe, expect, it } from 'vitest';
+import { MaximumRetriesReached } from './MaximumRetriesReached.js';
+import { RateLimited } from './RateLimited.js';
+import { isNotFound } from './notFound.js';
+
+describe('The not found check', () => {
+  it('recognises the retries that ran out on a 404', () => {
+    expect(isNotFound(new MaximumRetriesReached(new Response(null, { status: 404 })))).toBeTruthy();
+  });
+
+  it.each([
+    ['a server error', new MaximumRetriesReached(new Response(null, { status: 500 }))],
+    ['a rate limit', new RateLimited(new Response(null, { status: 429 }), null)],
+    ['any other error', new Error('404')],
+    ['something that is not an error', 404]
+  ])('ignores %s', (_description, error) => {
+    expect(isNotFound(error)).toBeFalsy();
+  });
+});
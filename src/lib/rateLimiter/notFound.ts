@@ -0,0 +1,10 @@
+import { MaximumRetriesReached } from './MaximumRetriesReached.js';
+
+const NOT_FOUND = 404;
+
+/**
+ * Whether the retries ran out on a 404, the rate limiter never hands the caller a response that isn't ok
+ */
+export const isNotFound = (error: unknown): boolean => {
+  return error instanceof MaximumRetriesReached && error.response().status === NOT_FOUND;
+};
@@ -0,0 +1,56 @@
+import { chance } from 'jest-chance';
+import { afterEach, describe, expect, it } from 'vitest';
+import { setBaseUrl } from '../baseUrl.js';
+import { Platform } from '../modlist.types.js';
+import {
+  defaultPlatformRateLimits,
+  platformForHost,
+  rateLimitForHost,
+  setPlatformRateLimit
+} from './platformLimits.js';
+
+describe('The platform rate limits', () => {
+  afterEach(() => {
+    setPlatformRateLimit(Platform.CURSEFORGE);
+    setPlatformRateLimit(Platform.MODRINTH);
+    setBaseUrl(Platform.CURSEFORGE);
+  });
+
+  it.each([
+    ['api.curseforge.com', Platform.CURSEFORGE],
+    ['api.modrinth.com', Platform.MODRINTH]
+  ])('knows that %s belongs to %s', (host, platform) => {
+    expect(platformForHost(host)).toEqual(platform);
+    expect(rateLimitForHost(host)).toEqual(defaultPlatformRateLimits[platform]);
+  });
+
+  it('knows the host of a configured base url', () => {
+    setBaseUrl(Platform.CURSEFORGE, 'https://cf-proxy.example.com/v1');
+
+    expect(platformForHost('cf-proxy.example.com')).toEqual(Platform.CURSEFORGE);
+    expect(platformForHost('api.curseforge.com')).toEqual(Platform.CURSEFORGE);
+  });
+
+  it('does not have a rate limit for other hosts', () => {
+    const host = chance.domain();
+
+    expect(platformForHost(host)).toBeUndefined();
+    expect(rateLimitForHost(host)).toBeUndefined();
+  });
+
+  it('can change the rate limit of a platform', () => {
+    const rateLimit = { timeBetweenCalls: chance.integer({ min: 0 }), maxAttempts: chance.integer({ min: 1 }) };
+
+    setPlatformRateLimit(Platform.MODRINTH, rateLimit);
+
+    expect(rateLimitForHost('api.modrinth.com')).toEqual(rateLimit);
+    expect(rateLimitForHost('api.curseforge.com')).toEqual(defaultPlatformRateLimits[Platform.CURSEFORGE]);
+  });
+
+  it('can restore the default rate limit of a platform', () => {
+    setPlatformRateLimit(Platform.CURSEFORGE, { timeBetweenCalls: 0, maxAttempts: 1 });
+    setPlatformRateLimit(Platform.CURSEFORGE);
+
+    expect(rateLimitForHost('api.curseforge.com')).toEqual(defaultPlatformRateLimits[Platform.CURSEFORGE]);
+  });
+});
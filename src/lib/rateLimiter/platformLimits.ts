@@ -0,0 +1,44 @@
+import { getBaseUrl } from '../baseUrl.js';
+import { Platform } from '../modlist.types.js';
+import { RateLimit } from './index.js';
+
+const platformHosts: Record<Platform, string> = {
+  [Platform.CURSEFORGE]: 'api.curseforge.com',
+  [Platform.MODRINTH]: 'api.modrinth.com'
+};
+
+export const defaultPlatformRateLimits: Record<Platform, RateLimit> = {
+  [Platform.CURSEFORGE]: {
+    timeBetweenCalls: 100,
+    maxAttempts: 3
+  },
+  // Modrinth allows 300 requests a minute
+  [Platform.MODRINTH]: {
+    timeBetweenCalls: 200,
+    maxAttempts: 3
+  }
+};
+
+const platformRateLimits: Record<Platform, RateLimit> = { ...defaultPlatformRateLimits };
+
+/**
+ * Recognises the public APIs and the configured base urls, like a proxy in front of the platform
+ */
+export const platformForHost = (host: string): Platform | undefined => {
+  return Object.values(Platform).find(
+    (platform) => platformHosts[platform] === host || new URL(getBaseUrl(platform)).hostname === host
+  );
+};
+
+/**
+ * Sets the rate limit used for every request to the platform's API that doesn't bring its own.
+ * Calling it without a rate limit restores the default.
+ */
+export const setPlatformRateLimit = (platform: Platform, rateLimit?: RateLimit) => {
+  platformRateLimits[platform] = rateLimit || defaultPlatformRateLimits[platform];
+};
+
+export const rateLimitForHost = (host: string): RateLimit | undefined => {
+  const platform = platformForHost(host);
+  return platform ? platformRateLimits[platform] : undefined;
+};
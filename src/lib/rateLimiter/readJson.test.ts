@@ -0,0 +1,93 @@
+import { chance } from 'jest-chance';
+import { afterEach, describe, expect, it, vi } from 'vitest';
+import { ResponseTooLarge } from './ResponseTooLarge.js';
+import { defaultMaxResponseSize, getMaxResponseSize, readJson, setMaxResponseSize } from './readJson.js';
+
+const streamOf = (chunkCount: number, chunkSize: number) => {
+  let sent = 0;
+  const pull = vi.fn((controller: ReadableStreamDefaultController<Uint8Array>) => {
+    if (sent === chunkCount) {
+      controller.close();
+      return;
+    }
+    sent++;
+    controller.enqueue(new Uint8Array(chunkSize).fill(32));
+  });
+
+  return { stream: new ReadableStream<Uint8Array>({ pull }), pull };
+};
+
+describe('The JSON reader', () => {
+  afterEach(() => {
+    setMaxResponseSize();
+  });
+
+  it('parses the body of the response', async () => {
+    const data = { name: chance.word(), values: chance.n(chance.integer, 5) };
+
+    const actual = await readJson(new Response(JSON.stringify(data)));
+
+    expect(actual).toEqual(data);
+  });
+
+  it('falls back to the json of a response without a body', async () => {
+    const data = { name: chance.word() };
+    const response = { body: null, json: vi.fn().mockResolvedValue(data) } as unknown as Response;
+
+    const actual = await readJson(response);
+
+    expect(actual).toEqual(data);
+  });
+
+  it('stops reading a body that is larger than the limit', async () => {
+    const { stream, pull } = streamOf(100, 1024);
+
+    await expect(readJson(new Response(stream), 4096)).rejects.toThrow(ResponseTooLarge);
+
+    // the rest of the stream is never read
+    expect(pull.mock.calls.length).toBeLessThan(10);
+  });
+
+  it('does not read the body when the advertised length is over the limit', async () => {
+    const { stream, pull } = streamOf(1, 16);
+    const response = new Response(stream, { headers: { 'content-length': '8192' } });
+
+    await expect(readJson(response, 4096)).rejects.toThrow(ResponseTooLarge);
+
+    expect(pull.mock.calls.length).toBeLessThanOrEqual(1);
+  });
+
+  it('accepts a body that is exactly at the limit', async () => {
+    const body = JSON.stringify({ data: 'x'.repeat(100) });
+
+    const actual = await readJson(new Response(body), Buffer.byteLength(body));
+
+    expect(actual).toEqual({ data: 'x'.repeat(100) });
+  });
+
+  it('uses the configured limit', async () => {
+    setMaxResponseSize(10);
+
+    expect(getMaxResponseSize()).toEqual(10);
+    await expect(readJson(new Response(JSON.stringify({ name: 'longer than ten bytes' })))).rejects.toThrow(
+      ResponseTooLarge
+    );
+  });
+
+  it('restores the default limit', () => {
+    setMaxResponseSize(10);
+    setMaxResponseSize();
+
+    expect(getMaxResponseSize()).toEqual(defaultMaxResponseSize);
+  });
+
+  it('reports the limit and the url', () => {
+    const url = chance.url();
+    const limit = chance.integer({ min: 1 });
+
+    const error = new ResponseTooLarge(url, limit);
+
+    expect(error.maxBytes()).toEqual(limit);
+    expect(error.message).toEqual(`The response from ${url} is larger than the allowed ${limit} bytes`);
+  });
+});
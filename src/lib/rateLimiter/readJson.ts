@@ -0,0 +1,52 @@
+import { ResponseTooLarge } from './ResponseTooLarge.js';
+
+export const defaultMaxResponseSize = 16 * 1024 * 1024;
+
+let maxResponseSize = defaultMaxResponseSize;
+
+/**
+ * Sets the largest response body in bytes that readJson accepts.
+ * Calling it without a value restores the default.
+ */
+export const setMaxResponseSize = (newMaxResponseSize?: number) => {
+  maxResponseSize = newMaxResponseSize || defaultMaxResponseSize;
+};
+
+export const getMaxResponseSize = () => maxResponseSize;
+
+/**
+ * Parses the JSON body of the response without reading more than maxBytes into memory.
+ *
+ * @throws {ResponseTooLarge} When the body is larger than maxBytes
+ */
+export const readJson = async <T>(response: Response, maxBytes: number = maxResponseSize): Promise<T> => {
+  if (!response.body) {
+    return response.json();
+  }
+
+  const contentLength = Number(response.headers.get('content-length'));
+  if (contentLength > maxBytes) {
+    await response.body.cancel();
+    throw new ResponseTooLarge(response.url, maxBytes);
+  }
+
+  const reader = response.body.getReader();
+  const chunks: Uint8Array[] = [];
+  let size = 0;
+
+  for (;;) {
+    const { done, value } = await reader.read();
+    if (done) {
+      break;
+    }
+
+    size += value.byteLength;
+    if (size > maxBytes) {
+      await reader.cancel();
+      throw new ResponseTooLarge(response.url, maxBytes);
+    }
+    chunks.push(value);
+  }
+
+  return JSON.parse(Buffer.concat(chunks).toString('utf8'));
+};
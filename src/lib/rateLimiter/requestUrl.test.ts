@@ -0,0 +1,26 @@
+import { describe, expect, it } from 'vitest';
+import { requestUrl } from './requestUrl.js';
+
+describe('The request url', () => {
+  it('is the url of a string', () => {
+    expect(requestUrl('https://api.modrinth.com/v2/search?query=sodium')).toEqual(
+      'https://api.modrinth.com/v2/search?query=sodium'
+    );
+  });
+
+  it('is normalized the way fetch would send it', () => {
+    expect(requestUrl('HTTPS://API.CURSEFORGE.COM')).toEqual('https://api.curseforge.com/');
+  });
+
+  it('is the url of a url object', () => {
+    expect(requestUrl(new URL('https://api.curseforge.com/v1/mods/1'))).toEqual('https://api.curseforge.com/v1/mods/1');
+  });
+
+  it('leaves the body of a request object alone', async () => {
+    const request = new Request('https://api.curseforge.com/v1/fingerprints', { method: 'POST', body: '{"a":1}' });
+
+    expect(requestUrl(request)).toEqual('https://api.curseforge.com/v1/fingerprints');
+    expect(request.bodyUsed).toBeFalsy();
+    expect(await request.text()).toEqual('{"a":1}');
+  });
+});
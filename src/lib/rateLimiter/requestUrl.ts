@@ -0,0 +1,7 @@
+/**
+ * The url a request goes to.
+ * Reading it through a new Request would use up the body of a request object, which then can't be sent or retried.
+ */
+export const requestUrl = (input: RequestInfo | URL): string => {
+  return input instanceof Request ? input.url : new URL(input).href;
+};
@@ -0,0 +1,46 @@
+import { afterEach, describe, expect, it } from 'vitest';
+import { remainingRetries, setRetryBudget, takeRetry } from './retryBudget.js';
+
+describe('The retry budget', () => {
+  afterEach(() => {
+    setRetryBudget();
+  });
+
+  it('allows every retry without a budget', () => {
+    expect(remainingRetries()).toBeUndefined();
+    expect(takeRetry()).toBeTruthy();
+    expect(takeRetry()).toBeTruthy();
+    expect(remainingRetries()).toBeUndefined();
+  });
+
+  it('allows as many retries as the budget', () => {
+    setRetryBudget(2);
+
+    expect(takeRetry()).toBeTruthy();
+    expect(remainingRetries()).toEqual(1);
+    expect(takeRetry()).toBeTruthy();
+    expect(takeRetry()).toBeFalsy();
+    expect(remainingRetries()).toEqual(0);
+  });
+
+  it('can forbid every retry', () => {
+    setRetryBudget(0);
+
+    expect(takeRetry()).toBeFalsy();
+  });
+
+  it('does not take negative or partial budgets', () => {
+    setRetryBudget(-3);
+    expect(remainingRetries()).toEqual(0);
+
+    setRetryBudget(2.7);
+    expect(remainingRetries()).toEqual(2);
+  });
+
+  it('can be removed', () => {
+    setRetryBudget(0);
+    setRetryBudget();
+
+    expect(takeRetry()).toBeTruthy();
+  });
+});
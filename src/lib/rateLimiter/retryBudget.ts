@@ -0,0 +1,29 @@
+let remaining: number | undefined;
+
+/**
+ * Sets how many retries all the requests of the run may use together.
+ * Once they are used up every failure is final, which keeps a run against a flaky API from dragging on for minutes.
+ * Calling it without a budget lets every request retry up to its own maxAttempts again.
+ */
+export const setRetryBudget = (retries?: number) => {
+  remaining = retries === undefined ? undefined : Math.max(0, Math.floor(retries));
+};
+
+/**
+ * The retries left in the budget, undefined when there is no budget
+ */
+export const remainingRetries = (): number | undefined => remaining;
+
+/**
+ * Takes a retry from the budget, false means the budget is used up and the request should fail right away
+ */
+export const takeRetry = (): boolean => {
+  if (remaining === undefined) {
+    return true;
+  }
+  if (remaining === 0) {
+    return false;
+  }
+  remaining--;
+  return true;
+};
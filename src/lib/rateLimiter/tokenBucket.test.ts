@@ -0,0 +1,118 @@
+import { afterEach, beforeEach, describe, expect, it } from 'vitest';
+import { setNow } from './clock.js';
+import { TokenBucket } from './tokenBucket.js';
+
+describe('The token bucket', () => {
+  let time: number;
+
+  beforeEach(() => {
+    time = 0;
+    setNow(() => time);
+  });
+
+  afterEach(() => {
+    setNow();
+  });
+
+  const reserveMany = (bucket: TokenBucket, count: number) => Array.from({ length: count }, () => bucket.reserve());
+
+  it('lets the burst go out at once', () => {
+    const bucket = new TokenBucket(3, 1000);
+
+    expect(reserveMany(bucket, 3)).toEqual([0, 0, 0]);
+  });
+
+  it('spaces out the requests after the burst', () => {
+    const bucket = new TokenBucket(3, 1000);
+
+    expect(reserveMany(bucket, 5)).toEqual([0, 0, 0, 1000, 2000]);
+  });
+
+  it('fills up while it is left alone', () => {
+    const bucket = new TokenBucket(3, 1000);
+    reserveMany(bucket, 3);
+
+    time += 2000;
+
+    expect(reserveMany(bucket, 3)).toEqual([0, 0, 1000]);
+  });
+
+  it('does not hold more than the burst', () => {
+    const bucket = new TokenBucket(2, 1000);
+
+    time += 60000;
+
+    expect(reserveMany(bucket, 3)).toEqual([0, 0, 1000]);
+  });
+
+  it('counts the waited time towards the next token', () => {
+    const bucket = new TokenBucket(1, 1000);
+    bucket.reserve();
+
+    const wait = bucket.reserve();
+    time += wait;
+
+    expect(wait).toEqual(1000);
+    expect(bucket.reserve()).toEqual(1000);
+  });
+
+  it('lets at least one request through', () => {
+    const bucket = new TokenBucket(0, 1000);
+
+    expect(reserveMany(bucket, 2)).toEqual([0, 1000]);
+  });
+
+  it('never waits without a time between the calls', () => {
+    const bucket = new TokenBucket(1, 0);
+
+    expect(reserveMany(bucket, 3)).toEqual([0, 0, 0]);
+  });
+
+  describe('when asked what is left', () => {
+    it('has the whole burst before any request', () => {
+      const bucket = new TokenBucket(3, 1000);
+
+      expect(bucket.availability()).toEqual({ tokens: 3, nextTokenIn: 0 });
+    });
+
+    it('drops with every request', () => {
+      const bucket = new TokenBucket(2, 1000);
+
+      const seen = Array.from({ length: 4 }, () => {
+        bucket.reserve();
+        return bucket.availability();
+      });
+
+      expect(seen).toEqual([
+        { tokens: 1, nextTokenIn: 0 },
+        { tokens: 0, nextTokenIn: 1000 },
+        { tokens: 0, nextTokenIn: 2000 },
+        { tokens: 0, nextTokenIn: 3000 }
+      ]);
+    });
+
+    it('counts the part of the next token that has filled up', () => {
+      const bucket = new TokenBucket(1, 1000);
+      bucket.reserve();
+
+      time += 400;
+
+      expect(bucket.availability()).toEqual({ tokens: 0, nextTokenIn: 600 });
+    });
+
+    it('does not take a token', () => {
+      const bucket = new TokenBucket(1, 1000);
+
+      bucket.availability();
+
+      expect(bucket.reserve()).toEqual(0);
+    });
+
+    it('is always full without a time between the calls', () => {
+      const bucket = new TokenBucket(2, 0);
+      reserveMany(bucket, 3);
+
+      expect(bucket.availability()).toEqual({ tokens: 2, nextTokenIn: 0 });
+    });
+  });
+});
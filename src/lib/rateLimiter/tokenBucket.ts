@@ -0,0 +1,67 @@
+import { now } from './clock.js';
+
+export interface TokenAvailability {
+  /**
+   * The requests that can go out right away
+   */
+  tokens: number;
+  /**
+   * How long the next request would wait for its token in milliseconds, 0 when one is left
+   */
+  nextTokenIn: number;
+}
+
+/**
+ * Lets a burst of requests go out back to back, then one more every refillEvery milliseconds.
+ * A bucket that was left alone fills up again, but never holds more than the burst.
+ */
+export class TokenBucket {
+  private tokens: number;
+  private updatedAt: number;
+  private readonly burst: number;
+  private readonly refillEvery: number;
+
+  constructor(burst: number, refillEvery: number) {
+    this.burst = Math.max(1, Math.floor(burst));
+    this.refillEvery = refillEvery;
+    this.tokens = this.burst;
+    this.updatedAt = now();
+  }
+
+  /**
+   * Takes a token for the next request and returns how long to wait before sending it, 0 when one was left.
+   * The bucket can go into debt, the request that waits for the token pays it back by waiting.
+   */
+  reserve(): number {
+    if (this.refillEvery <= 0) {
+      return 0;
+    }
+
+    const current = now();
+    this.tokens = this.refilledAt(current);
+    this.updatedAt = current;
+    this.tokens--;
+
+    return this.tokens >= 0 ? 0 : Math.ceil(-this.tokens * this.refillEvery);
+  }
+
+  /**
+   * What is left in the bucket, without taking a token. A bucket in debt has no tokens and a longer wait.
+   */
+  availability(): TokenAvailability {
+    if (this.refillEvery <= 0) {
+      return { tokens: this.burst, nextTokenIn: 0 };
+    }
+
+    const tokens = this.refilledAt(now());
+
+    return {
+      tokens: Math.max(0, Math.floor(tokens)),
+      nextTokenIn: tokens >= 1 ? 0 : Math.ceil((1 - tokens) * this.refillEvery)
+    };
+  }
+
+  private refilledAt(time: number): number {
+    return Math.min(this.burst, this.tokens + (time - this.updatedAt) / this.refillEvery);
+  }
+}
@@ -0,0 +1,44 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { isTransientNetworkError } from './transientError.js';
+
+const networkError = (code: string) => Object.assign(new Error(chance.sentence()), { code });
+
+describe('The transient network error detection', () => {
+  it.each(['ECONNRESET', 'ECONNREFUSED', 'ETIMEDOUT', 'ENOTFOUND', 'EAI_AGAIN', 'UND_ERR_CONNECT_TIMEOUT'])(
+    'retries on %s',
+    (code) => {
+      expect(isTransientNetworkError(networkError(code))).toBeTruthy();
+    }
+  );
+
+  it('looks at the cause of a failed fetch', () => {
+    const error = new TypeError('fetch failed', { cause: networkError('ECONNRESET') });
+
+    expect(isTransientNetworkError(error)).toBeTruthy();
+  });
+
+  it('does not retry unknown error codes', () => {
+    expect(isTransientNetworkError(networkError('ERR_INVALID_URL'))).toBeFalsy();
+  });
+
+  it('does not retry errors without a code', () => {
+    expect(isTransientNetworkError(new Error(chance.sentence()))).toBeFalsy();
+    expect(isTransientNetworkError(new TypeError('fetch failed', { cause: chance.word() }))).toBeFalsy();
+  });
+
+  it('does not retry non-string error codes', () => {
+    expect(isTransientNetworkError(Object.assign(new Error(), { code: 104 }))).toBeFalsy();
+  });
+
+  it('does not retry things that are not errors', () => {
+    expect(isTransientNetworkError(chance.word())).toBeFalsy();
+    expect(isTransientNetworkError(undefined)).toBeFalsy();
+  });
+
+  it.each(['AbortError', 'TimeoutError'])('does not retry a(n) %s', (name) => {
+    const error = Object.assign(new Error(chance.sentence(), { cause: networkError('ECONNRESET') }), { name });
+
+    expect(isTransientNetworkError(error)).toBeFalsy();
+  });
+});
@@ -0,0 +1,44 @@
+const TRANSIENT_ERROR_CODES = [
+  'ECONNRESET',
+  'ECONNREFUSED',
+  'ECONNABORTED',
+  'ETIMEDOUT',
+  'EPIPE',
+  'ENOTFOUND',
+  'EAI_AGAIN',
+  'ENETUNREACH',
+  'ENETDOWN',
+  'EHOSTUNREACH',
+  'UND_ERR_SOCKET',
+  'UND_ERR_CONNECT_TIMEOUT',
+  'UND_ERR_HEADERS_TIMEOUT',
+  'UND_ERR_BODY_TIMEOUT'
+];
+
+const errorCode = (error: unknown): string | undefined => {
+  if (error && typeof error === 'object' && 'code' in error && typeof error.code === 'string') {
+    return error.code;
+  }
+  return undefined;
+};
+
+/**
+ * Tells whether a failed fetch is worth trying again.
+ *
+ * Node's fetch rejects with a "fetch failed" TypeError and puts the underlying
+ * network error into the cause, so both the error and its cause are checked.
+ * Aborted requests are never retried as it was the caller who asked us to stop.
+ */
+export const isTransientNetworkError = (error: unknown): error is Error => {
+  if (!(error instanceof Error)) {
+    return false;
+  }
+
+  if (error.name === 'AbortError' || error.name === 'TimeoutError') {
+    return false;
+  }
+
+  const code = errorCode(error) ?? errorCode(error.cause);
+
+  return code !== undefined && TRANSIENT_ERROR_CODES.includes(code);
+};
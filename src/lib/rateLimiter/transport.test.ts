@@ -0,0 +1,131 @@
+import { chance } from 'jest-chance';
+import { ProxyAgent } from 'undici';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { proxyFor, setProxy, setTransport, transportFetch } from './transport.js';
+
+vi.mock('undici');
+
+const proxyVariables = ['HTTP_PROXY', 'http_proxy', 'HTTPS_PROXY', 'https_proxy', 'NO_PROXY', 'no_proxy'];
+
+describe('The transport', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+    vi.stubGlobal('fetch', vi.fn());
+    vi.mocked(fetch).mockResolvedValue(new Response());
+    proxyVariables.forEach((variable) => {
+      delete process.env[variable];
+    });
+  });
+
+  afterEach(() => {
+    setProxy();
+    setTransport();
+    proxyVariables.forEach((variable) => {
+      delete process.env[variable];
+    });
+  });
+
+  it('connects directly without a proxy', async () => {
+    const url = chance.url({ protocol: 'https' });
+    const init = { method: 'GET' };
+
+    await transportFetch(url, init);
+
+    expect(vi.mocked(fetch)).toHaveBeenCalledWith(url, init);
+    expect(vi.mocked(ProxyAgent)).not.toHaveBeenCalled();
+  });
+
+  it('dials the requests through the configured proxy', async () => {
+    const url = chance.url({ protocol: 'https' });
+    const proxy = chance.url({ protocol: 'http' });
+    setProxy(proxy);
+
+    await transportFetch(url, { method: 'GET' });
+
+    expect(vi.mocked(ProxyAgent)).toHaveBeenCalledWith(proxy);
+    const init = vi.mocked(fetch).mock.calls[0][1] as RequestInit & { dispatcher: unknown };
+    expect(init.method).toEqual('GET');
+    expect(init.dispatcher).toBe(vi.mocked(ProxyAgent).mock.instances[0]);
+  });
+
+  it('reuses the agent of a proxy', async () => {
+    setProxy(chance.url({ protocol: 'http' }));
+
+    await transportFetch(chance.url({ protocol: 'https' }));
+    await transportFetch(chance.url({ protocol: 'https' }));
+
+    expect(vi.mocked(ProxyAgent)).toHaveBeenCalledOnce();
+  });
+
+  it('uses the custom transport when there is one', async () => {
+    const transport = vi.fn().mockResolvedValue(new Response());
+    const url = chance.url({ protocol: 'https' });
+    setProxy('http://proxy.example.com:3128');
+    setTransport(transport);
+
+    await transportFetch(url, { method: 'POST' });
+
+    expect(transport).toHaveBeenCalledWith(url, { method: 'POST' });
+    expect(vi.mocked(fetch)).not.toHaveBeenCalled();
+  });
+
+  describe('when picking the proxy', () => {
+    it('uses HTTPS_PROXY for https urls', () => {
+      process.env.HTTPS_PROXY = 'http://secure-proxy:8080';
+      process.env.HTTP_PROXY = 'http://plain-proxy:8080';
+
+      expect(proxyFor('https://api.modrinth.com/v2/project/abc')).toEqual('http://secure-proxy:8080');
+    });
+
+    it('uses HTTP_PROXY for http urls', () => {
+      process.env.HTTPS_PROXY = 'http://secure-proxy:8080';
+      process.env.HTTP_PROXY = 'http://plain-proxy:8080';
+
+      expect(proxyFor('http://localhost/v2')).toEqual('http://plain-proxy:8080');
+    });
+
+    it('understands the lowercase variables', () => {
+      process.env.https_proxy = 'http://secure-proxy:8080';
+
+      expect(proxyFor('https://api.curseforge.com/v1')).toEqual('http://secure-proxy:8080');
+    });
+
+    it('does not use a proxy without the variables', () => {
+      expect(proxyFor('https://api.curseforge.com/v1')).toBeUndefined();
+    });
+
+    it.each(['api.modrinth.com', 'modrinth.com', '.modrinth.com', '*.modrinth.com', 'modrinth.com:443', '*'])(
+      'skips the hosts matching %s in NO_PROXY',
+      (entry) => {
+        process.env.HTTPS_PROXY = 'http://secure-proxy:8080';
+        process.env.NO_PROXY = `localhost, ${entry}`;
+
+        expect(proxyFor('https://api.modrinth.com/v2')).toBeUndefined();
+      }
+    );
+
+    it('still proxies the hosts that are not in NO_PROXY', () => {
+      process.env.HTTPS_PROXY = 'http://secure-proxy:8080';
+      process.env.no_proxy = 'modrinth.com';
+
+      expect(proxyFor('https://api.curseforge.com/v1')).toEqual('http://secure-proxy:8080');
+      expect(proxyFor('https://notmodrinth.com')).toEqual('http://secure-proxy:8080');
+    });
+
+    it('prefers the configured proxy', () => {
+      process.env.HTTPS_PROXY = 'http://secure-proxy:8080';
+      process.env.NO_PROXY = '*';
+      setProxy('http://configured-proxy:3128');
+
+      expect(proxyFor('https://api.curseforge.com/v1')).toEqual('http://configured-proxy:3128');
+    });
+
+    it('goes back to the environment when the configured proxy is removed', () => {
+      process.env.HTTPS_PROXY = 'http://secure-proxy:8080';
+      setProxy('http://configured-proxy:3128');
+      setProxy();
+
+      expect(proxyFor('https://api.curseforge.com/v1')).toEqual('http://secure-proxy:8080');
+    });
+  });
+});
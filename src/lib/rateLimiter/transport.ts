@@ -0,0 +1,81 @@
+import { Dispatcher, ProxyAgent } from 'undici';
+import { requestUrl } from './requestUrl.js';
+
+export type Fetcher = (input: RequestInfo | URL, init?: RequestInit) => Promise<Response>;
+
+let configuredProxy: string | undefined;
+let configuredTransport: Fetcher | undefined;
+const proxyAgents = new Map<string, Dispatcher>();
+
+/**
+ * Sends every request through the given proxy, regardless of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+ * environment variables. Calling it without a value goes back to using the environment variables.
+ */
+export const setProxy = (proxyUrl?: string) => {
+  configuredProxy = proxyUrl || undefined;
+};
+
+/**
+ * Replaces the fetch that sends the requests, for example to route them through a custom agent.
+ * A custom transport is responsible for its own proxying.
+ * Calling it without a value goes back to the default transport.
+ */
+export const setTransport = (transport?: Fetcher) => {
+  configuredTransport = transport;
+};
+
+const isExcluded = (hostname: string) => {
+  const noProxy = process.env.NO_PROXY ?? process.env.no_proxy ?? '';
+
+  return noProxy
+    .split(',')
+    .map((entry) => entry.trim().toLowerCase().replace(/:\d+$/, '').replace(/^\*?\./, ''))
+    .filter((entry) => entry.length > 0)
+    .some((entry) => entry === '*' || hostname === entry || hostname.endsWith(`.${entry}`));
+};
+
+const proxyFromEnvironment = (url: URL) => {
+  if (isExcluded(url.hostname.toLowerCase())) {
+    return undefined;
+  }
+
+  if (url.protocol === 'https:') {
+    return process.env.HTTPS_PROXY || process.env.https_proxy || undefined;
+  }
+
+  return process.env.HTTP_PROXY || process.env.http_proxy || undefined;
+};
+
+/**
+ * The proxy a request to the url goes through, if any.
+ * An explicitly set proxy wins, otherwise HTTPS_PROXY is used for https urls and HTTP_PROXY for http urls,
+ * unless the host is listed in NO_PROXY.
+ */
+export const proxyFor = (url: string): string | undefined => configuredProxy ?? proxyFromEnvironment(new URL(url));
+
+const proxyAgentFor = (proxyUrl: string) => {
+  let agent = proxyAgents.get(proxyUrl);
+  if (!agent) {
+    agent = new ProxyAgent(proxyUrl);
+    proxyAgents.set(proxyUrl, agent);
+  }
+  return agent;
+};
+
+/**
+ * The fetch every outgoing request goes through.
+ * It uses the custom transport when one is set, and the proxy of the url otherwise.
+ */
+export const transportFetch: Fetcher = (input: RequestInfo | URL, init?: RequestInit) => {
+  if (configuredTransport) {
+    return configuredTransport(input, init);
+  }
+
+  const proxy = proxyFor(requestUrl(input));
+  if (!proxy) {
+    return fetch(input, init);
+  }
+
+  // The dispatcher is how Node's fetch can be told to connect through a proxy
+  return fetch(input, { ...init, dispatcher: proxyAgentFor(proxy) } as RequestInit);
+};
@@ -0,0 +1,29 @@
+import { chance } from 'jest-chance';
+import { afterEach, describe, expect, it } from 'vitest';
+import { version } from '../../version.js';
+import { defaultUserAgent, getUserAgent, setUserAgent } from './userAgent.js';
+
+describe('The user agent', () => {
+  afterEach(() => {
+    setUserAgent();
+  });
+
+  it('identifies the app by default', () => {
+    expect(defaultUserAgent).toEqual(`github_com/meza/minecraft-mod-manager/${version}`);
+    expect(getUserAgent()).toEqual(defaultUserAgent);
+  });
+
+  it('can be changed', () => {
+    const randomUserAgent = chance.word();
+    setUserAgent(randomUserAgent);
+
+    expect(getUserAgent()).toEqual(randomUserAgent);
+  });
+
+  it('can be restored to the default', () => {
+    setUserAgent(chance.word());
+    setUserAgent('');
+
+    expect(getUserAgent()).toEqual(defaultUserAgent);
+  });
+});
@@ -0,0 +1,15 @@
+import { version } from '../../version.js';
+
+export const defaultUserAgent = `github_com/meza/minecraft-mod-manager/${version}`;
+
+let userAgent = defaultUserAgent;
+
+/**
+ * Sets the User-Agent sent with every rate limited request that doesn't set one itself.
+ * Calling it without a value restores the default.
+ */
+export const setUserAgent = (newUserAgent?: string) => {
+  userAgent = newUserAgent || defaultUserAgent;
+};
+
+export const getUserAgent = () => userAgent;
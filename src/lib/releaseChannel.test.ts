@@ -0,0 +1,137 @@
+import { describe, expect, it } from 'vitest';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { Platform, ReleaseChannel, ReleaseType } from './modlist.types.js';
+import { allowedReleaseTypesOf, fallbackOf, releaseTypesForChannel } from './releaseChannel.js';
+
+describe('The release channels', () => {
+  it('only allows releases on the release channel', () => {
+    expect(releaseTypesForChannel(ReleaseChannel.RELEASE)).toEqual([ReleaseType.RELEASE]);
+  });
+
+  it('allows betas on the beta channel', () => {
+    expect(releaseTypesForChannel(ReleaseChannel.BETA)).toEqual([ReleaseType.RELEASE, ReleaseType.BETA]);
+  });
+
+  it('allows everything on the alpha channel', () => {
+    expect(releaseTypesForChannel(ReleaseChannel.ALPHA)).toEqual([
+      ReleaseType.RELEASE,
+      ReleaseType.BETA,
+      ReleaseType.ALPHA
+    ]);
+  });
+
+  describe('when picking the release types of a mod', () => {
+    const configuration = generateModsJson({
+      defaultAllowedReleaseTypes: [ReleaseType.RELEASE, ReleaseType.BETA, ReleaseType.ALPHA],
+      platformAllowedReleaseTypes: {
+        [Platform.CURSEFORGE]: [ReleaseType.RELEASE]
+      }
+    }).generated;
+
+    const modOf = (type: Platform, allowedReleaseTypes?: ReleaseType[]) => {
+      const mod = generateModConfig({ type: type }).generated;
+      mod.allowedReleaseTypes = allowedReleaseTypes;
+      return mod;
+    };
+
+    it('uses the default of the modlist without anything more specific', () => {
+      const actual = allowedReleaseTypesOf(modOf(Platform.CURSEFORGE), {
+        ...configuration,
+        platformAllowedReleaseTypes: undefined
+      });
+
+      expect(actual).toEqual([ReleaseType.RELEASE, ReleaseType.BETA, ReleaseType.ALPHA]);
+    });
+
+    it('uses the default of the modlist for a platform without its own', () => {
+      expect(allowedReleaseTypesOf(modOf(Platform.MODRINTH), configuration)).toEqual([
+        ReleaseType.RELEASE,
+        ReleaseType.BETA,
+        ReleaseType.ALPHA
+      ]);
+    });
+
+    it('prefers the default of the platform to the default of the modlist', () => {
+      expect(allowedReleaseTypesOf(modOf(Platform.CURSEFORGE), configuration)).toEqual([ReleaseType.RELEASE]);
+    });
+
+    it('prefers the release types of the mod to the default of its platform', () => {
+      const mod = modOf(Platform.CURSEFORGE, [ReleaseType.RELEASE, ReleaseType.BETA]);
+
+      expect(allowedReleaseTypesOf(mod, configuration)).toEqual([ReleaseType.RELEASE, ReleaseType.BETA]);
+    });
+
+    it('prefers the release types of the mod to the default of the modlist', () => {
+      const mod = modOf(Platform.MODRINTH, [ReleaseType.RELEASE]);
+
+      expect(allowedReleaseTypesOf(mod, configuration)).toEqual([ReleaseType.RELEASE]);
+    });
+
+    it('uses the release channel of the mod', () => {
+      const mod = { ...modOf(Platform.CURSEFORGE), releaseChannel: ReleaseChannel.BETA };
+
+      expect(allowedReleaseTypesOf(mod, configuration)).toEqual([ReleaseType.RELEASE, ReleaseType.BETA]);
+    });
+
+    it('prefers the release types of the mod to its release channel', () => {
+      const mod = { ...modOf(Platform.MODRINTH, [ReleaseType.ALPHA]), releaseChannel: ReleaseChannel.RELEASE };
+
+      expect(allowedReleaseTypesOf(mod, configuration)).toEqual([ReleaseType.ALPHA]);
+    });
+
+    it('lets an empty platform default stand', () => {
+      const actual = allowedReleaseTypesOf(modOf(Platform.MODRINTH), {
+        ...configuration,
+        platformAllowedReleaseTypes: { [Platform.MODRINTH]: [] }
+      });
+
+      expect(actual).toEqual([]);
+    });
+  });
+
+  describe('when picking the release types of a fallback', () => {
+    const configuration = generateModsJson({
+      defaultAllowedReleaseTypes: [ReleaseType.RELEASE, ReleaseType.BETA, ReleaseType.ALPHA],
+      platformAllowedReleaseTypes: {
+        [Platform.CURSEFORGE]: [ReleaseType.RELEASE]
+      }
+    }).generated;
+
+    const modWithFallback = () => {
+      const mod = generateModConfig({
+        type: Platform.CURSEFORGE,
+        fallback: { type: Platform.MODRINTH, id: 'AANobbMI' }
+      }).generated;
+      delete mod.allowedReleaseTypes;
+      return mod;
+    };
+
+    it('has no fallback without one', () => {
+      const mod = modWithFallback();
+      delete mod.fallback;
+
+      expect(fallbackOf(mod, configuration)).toBeUndefined();
+    });
+
+    it('uses the release types of the platform of the fallback', () => {
+      expect(fallbackOf(modWithFallback(), configuration)).toEqual({
+        type: Platform.MODRINTH,
+        id: 'AANobbMI',
+        allowedReleaseTypes: [ReleaseType.RELEASE, ReleaseType.BETA, ReleaseType.ALPHA]
+      });
+    });
+
+    it('prefers the release types of the mod', () => {
+      const mod = { ...modWithFallback(), allowedReleaseTypes: [ReleaseType.BETA] };
+
+      expect(fallbackOf(mod, configuration)?.allowedReleaseTypes).toEqual([ReleaseType.BETA]);
+    });
+
+    it('prefers the release channel of the mod', () => {
+      const mod = { ...modWithFallback(), releaseChannel: ReleaseChannel.RELEASE };
+
+      expect(fallbackOf(mod, configuration)?.allowedReleaseTypes).toEqual([ReleaseType.RELEASE]);
+    });
+  });
+});
@@ -0,0 +1,56 @@
+import { Mod, ModsJson, ReleaseChannel, ReleaseType, ResolvedModFallback } from './modlist.types.js';
+
+export const releaseTypesForChannel = (channel: ReleaseChannel): ReleaseType[] => {
+  switch (channel) {
+    case ReleaseChannel.ALPHA:
+      return [ReleaseType.RELEASE, ReleaseType.BETA, ReleaseType.ALPHA];
+    case ReleaseChannel.BETA:
+      return [ReleaseType.RELEASE, ReleaseType.BETA];
+    default:
+      return [ReleaseType.RELEASE];
+  }
+};
+
+/**
+ * The release types a mod accepts, the most specific setting wins:
+ * 1. the allowedReleaseTypes of the mod
+ * 2. the releaseChannel of the mod
+ * 3. the platformAllowedReleaseTypes of the modlist for the platform of the mod
+ * 4. the defaultAllowedReleaseTypes of the modlist
+ */
+export const allowedReleaseTypesOf = (
+  mod: Pick<Mod, 'type' | 'allowedReleaseTypes' | 'releaseChannel'>,
+  configuration: ModsJson
+): ReleaseType[] => {
+  return (
+    mod.allowedReleaseTypes ??
+    (mod.releaseChannel ? releaseTypesForChannel(mod.releaseChannel) : undefined) ??
+    configuration.platformAllowedReleaseTypes?.[mod.type] ??
+    configuration.defaultAllowedReleaseTypes
+  );
+};
+
+/**
+ * The fallback of the mod with the release types for the platform of the fallback.
+ * The release types and the release channel of the mod still win, they are about the mod on every platform.
+ */
+export const fallbackOf = (
+  mod: Pick<Mod, 'fallback' | 'allowedReleaseTypes' | 'releaseChannel'>,
+  configuration: ModsJson
+): ResolvedModFallback | undefined => {
+  if (!mod.fallback) {
+    return undefined;
+  }
+
+  return {
+    ...mod.fallback,
+    allowedReleaseTypes: allowedReleaseTypesOf(
+      {
+        type: mod.fallback.type,
+        allowedReleaseTypes: mod.allowedReleaseTypes,
+        releaseChannel: mod.releaseChannel
+      },
+      configuration
+    )
+  };
+};
@@ -0,0 +1,173 @@
+import fs from 'node:fs/promises';
+import path from 'node:path';
+import { chance } from 'jest-chance';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { RunInProgressException } from '../errors/RunInProgressException.js';
+import { fileExists } from './config.js';
+import { acquireRunLock, getRunLockName, releaseRunLock } from './runLock.js';
+
+interface LocalTestContext {
+  configPath: string;
+  runLock: string;
+}
+
+// Only the listeners of the run lock, the test runner has its own
+const listenersAddedSince = (event: string, before: ReturnType<typeof process.listeners>) => {
+  return process.listeners(event as NodeJS.Signals).filter((listener) => !before.includes(listener));
+};
+
+const noSuchProcess = () => {
+  throw Object.assign(new Error('kill ESRCH'), { code: 'ESRCH' });
+};
+
+describe('The run lock', () => {
+  beforeEach<LocalTestContext>((context) => {
+    context.configPath = `${chance.guid()}.json`;
+    context.runLock = getRunLockName(context.configPath);
+  });
+
+  afterEach<LocalTestContext>(async ({ runLock }) => {
+    vi.restoreAllMocks();
+    await releaseRunLock();
+    await fs.rm(runLock, { force: true });
+  });
+
+  it('sits next to the lock file', () => {
+    expect(getRunLockName('./some/folder/modlist.json')).toEqual(path.resolve('modlist-run.pid'));
+  });
+
+  it<LocalTestContext>('holds the process id of the run', async ({ configPath, runLock }) => {
+    await acquireRunLock(configPath);
+
+    expect(await fs.readFile(runLock, { encoding: 'utf8' })).toEqual(String(process.pid));
+  });
+
+  it<LocalTestContext>('lets go when the run is done', async ({ configPath, runLock }) => {
+    const exitListeners = process.listenerCount('exit');
+    const interruptListeners = process.listenerCount('SIGINT');
+    await acquireRunLock(configPath);
+
+    await releaseRunLock();
+
+    expect(await fileExists(runLock)).toBeFalsy();
+    expect(process.listenerCount('exit')).toEqual(exitListeners);
+    expect(process.listenerCount('SIGINT')).toEqual(interruptListeners);
+  });
+
+  it<LocalTestContext>('lets go when the process exits', async ({ configPath, runLock }) => {
+    const before = process.listeners('exit');
+    await acquireRunLock(configPath);
+
+    listenersAddedSince('exit', before).forEach((listener) => listener.call(process, 0));
+
+    expect(await fileExists(runLock)).toBeFalsy();
+    expect(listenersAddedSince('exit', before)).toEqual([]);
+  });
+
+  it<LocalTestContext>('lets go and exits when the run is stopped', async ({ configPath, runLock }) => {
+    const exit = vi.spyOn(process, 'exit').mockImplementation(() => undefined as never);
+    const before = process.listeners('SIGTERM');
+    await acquireRunLock(configPath);
+
+    listenersAddedSince('SIGTERM', before).forEach((listener) => listener.call(process, 'SIGTERM'));
+
+    expect(await fileExists(runLock)).toBeFalsy();
+    expect(exit).toHaveBeenCalledWith(143);
+  });
+
+  it<LocalTestContext>('refuses to run while another run holds it', async ({ configPath, runLock }) => {
+    await fs.writeFile(runLock, String(process.ppid));
+
+    await expect(acquireRunLock(configPath)).rejects.toThrow(new RunInProgressException(runLock, process.ppid));
+
+    expect(await fs.readFile(runLock, { encoding: 'utf8' })).toEqual(String(process.ppid));
+  });
+
+  it<LocalTestContext>('refuses a second run of the same folder', async ({ configPath, runLock }) => {
+    await acquireRunLock(configPath);
+
+    await expect(acquireRunLock(configPath)).rejects.toThrow(new RunInProgressException(runLock, process.pid));
+  });
+
+  it<LocalTestContext>('refuses to run while the other run is still starting', async ({ configPath, runLock }) => {
+    await fs.writeFile(runLock, '');
+
+    await expect(acquireRunLock(configPath)).rejects.toThrow(new RunInProgressException(runLock));
+  });
+
+  it<LocalTestContext>('counts a process it may not signal as running', async ({ configPath, runLock }) => {
+    vi.spyOn(process, 'kill').mockImplementation(() => {
+      throw Object.assign(new Error('kill EPERM'), { code: 'EPERM' });
+    });
+    await fs.writeFile(runLock, '1');
+
+    await expect(acquireRunLock(configPath)).rejects.toThrow(RunInProgressException);
+  });
+
+  it<LocalTestContext>('takes over the run lock of a run that crashed', async ({ configPath, runLock }) => {
+    const kill = vi.spyOn(process, 'kill').mockImplementation(noSuchProcess);
+    await fs.writeFile(runLock, '4194305');
+
+    await acquireRunLock(configPath);
+
+    expect(kill).toHaveBeenCalledWith(4194305, 0);
+    expect(await fs.readFile(runLock, { encoding: 'utf8' })).toEqual(String(process.pid));
+  });
+
+  it<LocalTestContext>('refuses when another run takes over the crashed run first', async ({ configPath, runLock }) => {
+    vi.spyOn(process, 'kill').mockImplementation(noSuchProcess);
+    await fs.writeFile(runLock, '4194305');
+    const rename = fs.rename;
+    vi.spyOn(fs, 'rename').mockImplementationOnce(async (from, to) => {
+      await fs.rm(runLock);
+      await fs.writeFile(runLock, '4194306');
+      await rename(from, to);
+    });
+
+    await expect(acquireRunLock(configPath)).rejects.toThrow(new RunInProgressException(runLock, 4194306));
+    expect(await fs.readFile(runLock, { encoding: 'utf8' })).toEqual('4194306');
+    expect(await fileExists(`${runLock}.${process.pid}`)).toBeFalsy();
+  });
+
+  it<LocalTestContext>('refuses when another run moved the lock aside first', async ({ configPath, runLock }) => {
+    vi.spyOn(process, 'kill').mockImplementation(noSuchProcess);
+    await fs.writeFile(runLock, '4194305');
+    vi.spyOn(fs, 'rename').mockImplementationOnce(async () => {
+      await fs.writeFile(runLock, '4194306');
+      throw Object.assign(new Error('rename ENOENT'), { code: 'ENOENT' });
+    });
+
+    await expect(acquireRunLock(configPath)).rejects.toThrow(new RunInProgressException(runLock, 4194306));
+  });
+
+  it<LocalTestContext>('locks when another run moved the crashed run aside but did not lock yet', async ({
+    configPath,
+    runLock
+  }) => {
+    vi.spyOn(process, 'kill').mockImplementation(noSuchProcess);
+    await fs.writeFile(runLock, '4194305');
+    vi.spyOn(fs, 'rename').mockImplementationOnce(async () => {
+      await fs.rm(runLock);
+      throw Object.assign(new Error('rename ENOENT'), { code: 'ENOENT' });
+    });
+
+    await acquireRunLock(configPath);
+
+    expect(await fs.readFile(runLock, { encoding: 'utf8' })).toEqual(String(process.pid));
+  });
+
+  it<LocalTestContext>('passes on the errors of the file system when taking over', async ({ configPath, runLock }) => {
+    vi.spyOn(process, 'kill').mockImplementation(noSuchProcess);
+    await fs.writeFile(runLock, '4194305');
+    vi.spyOn(fs, 'rename').mockRejectedValueOnce(Object.assign(new Error('EACCES'), { code: 'EACCES' }));
+
+    await expect(acquireRunLock(configPath)).rejects.toThrow('EACCES');
+  });
+
+  it<LocalTestContext>('passes on the errors of the file system', async ({ configPath, runLock }) => {
+    vi.spyOn(fs, 'writeFile').mockRejectedValueOnce(Object.assign(new Error('EACCES'), { code: 'EACCES' }));
+
+    await expect(acquireRunLock(configPath)).rejects.toThrow('EACCES');
+    expect(await fileExists(runLock)).toBeFalsy();
+  });
+});
@@ -0,0 +1,136 @@
+import { rmSync } from 'node:fs';
+import fs from 'node:fs/promises';
+import os from 'node:os';
+import path from 'node:path';
+import { RunInProgressException } from '../errors/RunInProgressException.js';
+
+/**
+ * The signals that stop a run, the run lock is let go before the process goes down
+ */
+const STOP_SIGNALS: NodeJS.Signals[] = ['SIGINT', 'SIGTERM', 'SIGHUP'];
+
+let heldRunLock: string | undefined;
+
+/**
+ * The run lock sits next to the lock file, it holds the process id of the run that is changing them
+ */
+export const getRunLockName = (configPath: string) => {
+  return path.resolve(path.basename(configPath, path.extname(configPath)) + '-run.pid');
+};
+
+const isRunning = (pid: number) => {
+  try {
+    process.kill(pid, 0);
+    return true;
+  } catch (error) {
+    // The process is there, it's just not ours to signal
+    return (error as NodeJS.ErrnoException).code === 'EPERM';
+  }
+};
+
+const holderOf = async (runLock: string): Promise<number | undefined> => {
+  const contents = await fs.readFile(runLock, { encoding: 'utf8' }).catch(() => '');
+  const pid = Number.parseInt(contents, 10);
+  return Number.isNaN(pid) ? undefined : pid;
+};
+
+const tryToLock = async (runLock: string): Promise<boolean> => {
+  try {
+    await fs.writeFile(runLock, String(process.pid), { flag: 'wx' });
+    return true;
+  } catch (error) {
+    if ((error as NodeJS.ErrnoException).code === 'EEXIST') {
+      return false;
+    }
+    throw error;
+  }
+};
+
+/**
+ * Moves the run lock of the crashed run aside before locking. Renaming is atomic, so of the runs that found the same
+ * crashed run only one moves its lock. A run that moved the fresh lock of another run instead puts it back.
+ */
+const takeOver = async (runLock: string, crashed: number): Promise<boolean> => {
+  const claim = `${runLock}.${process.pid}`;
+  try {
+    await fs.rename(runLock, claim);
+  } catch (error) {
+    // Another run moved it first, the one that locks next gets it
+    if ((error as NodeJS.ErrnoException).code === 'ENOENT') {
+      return tryToLock(runLock);
+    }
+    throw error;
+  }
+
+  if ((await holderOf(claim)) !== crashed) {
+    // Linking doesn't overwrite, so a run that locked in the meantime keeps its lock
+    await fs.link(claim, runLock).catch(() => undefined);
+    await fs.rm(claim, { force: true });
+    return false;
+  }
+
+  await fs.rm(claim, { force: true });
+  return tryToLock(runLock);
+};
+
+// Nothing asynchronous runs once the process is exiting
+const releaseOnExit = () => {
+  if (heldRunLock) {
+    rmSync(heldRunLock, { force: true });
+    heldRunLock = undefined;
+    stopListening();
+  }
+};
+
+const releaseOnSignal = (signal: NodeJS.Signals) => {
+  releaseOnExit();
+  // eslint-disable-next-line no-process-exit
+  process.exit(128 + os.constants.signals[signal]);
+};
+
+const stopListening = () => {
+  process.off('exit', releaseOnExit);
+  STOP_SIGNALS.forEach((signal) => {
+    process.off(signal, releaseOnSignal);
+  });
+};
+
+/**
+ * Makes sure only one run changes the modlist and the lock file at a time, like when a scheduled update overlaps
+ * with one started by hand. The lock is let go by releaseRunLock, when the process exits or when it is stopped.
+ * A run lock left behind by a run that crashed is taken over.
+ *
+ * @throws {RunInProgressException} When another run holds the run lock
+ */
+export const acquireRunLock = async (configPath: string): Promise<void> => {
+  const runLock = getRunLockName(configPath);
+
+  if (!(await tryToLock(runLock))) {
+    const holder = await holderOf(runLock);
+    // Without a process id the other run could have only just made the file, so it's left alone
+    if (holder === undefined || isRunning(holder)) {
+      throw new RunInProgressException(runLock, holder);
+    }
+
+    if (!(await takeOver(runLock, holder))) {
+      throw new RunInProgressException(runLock, await holderOf(runLock));
+    }
+  }
+
+  heldRunLock = runLock;
+  process.on('exit', releaseOnExit);
+  STOP_SIGNALS.forEach((signal) => {
+    process.on(signal, releaseOnSignal);
+  });
+};
+
+export const releaseRunLock = async (): Promise<void> => {
+  if (!heldRunLock) {
+    return;
+  }
+
+  const runLock = heldRunLock;
+  heldRunLock = undefined;
+  stopListening();
+  await fs.rm(runLock, { force: true });
+};
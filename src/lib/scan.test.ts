@@ -1,6 +1,7 @@
-import curseforge from '@meza/curseforge-fingerprint';
+import fs from 'node:fs/promises';
 import { chance } from 'jest-chance';
 import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateCurseforgeModFile } from '../../test/generateCurseforgeModFile.js';
 import { generatePlatformLookupResult } from '../../test/generatePlatformLookupResult.js';
 import { generateRandomPlatform } from '../../test/generateRandomPlatform.js';
 import { generateRemoteModDetails } from '../../test/generateRemoteDetails.js';
@@ -8,18 +9,22 @@ import { generateResultItem } from '../../test/generateResultItem.js';
 import { generateModsJson } from '../../test/modlistGenerator.js';
 import { CurseforgeDownloadUrlError } from '../errors/CurseforgeDownloadUrlError.js';
 import { NoRemoteFileFound } from '../errors/NoRemoteFileFound.js';
+import { resolveFuzzy } from '../repositories/curseforge/fuzzy.js';
 import { fetchModDetails, lookup } from '../repositories/index.js';
 import { fileIsManaged } from './configurationHelper.js';
 import { getModFiles } from './fileHelper.js';
+import { getFingerprints } from './fingerprint.js';
 import { getHash } from './hash.js';
 import { ModInstall, ModsJson, Platform } from './modlist.types.js';
-import { scan } from './scan.js';
+import { fingerprintFiles, guessUnmatchedFiles, scan, unmatchedFiles } from './scan.js';
 
 vi.mock('./fileHelper.js');
+vi.mock('./fingerprint.js');
 vi.mock('./hash.js');
-vi.mock('@meza/curseforge-fingerprint');
 vi.mock('./configurationHelper.js');
 vi.mock('../repositories/index.js');
+vi.mock('../repositories/curseforge/fuzzy.js');
+vi.mock('node:fs/promises');
 
 interface LocalTestContext {
   randomConfiguration: ModsJson;
@@ -31,6 +36,9 @@ interface LocalTestContext {
 describe('The scan library', () => {
   beforeEach<LocalTestContext>((context) => {
     vi.resetAllMocks();
+    vi.mocked(getFingerprints).mockResolvedValue({ fingerprints: [], errors: [] });
+    vi.mocked(resolveFuzzy).mockResolvedValue([]);
+    vi.mocked(fs.stat).mockResolvedValue({ size: 1024 } as Awaited<ReturnType<typeof fs.stat>>);
     context.randomConfiguration = generateModsJson().generated;
     context.randomInstallations = [];
     context.randomPlatform = generateRandomPlatform();
@@ -51,7 +59,7 @@ describe('The scan library', () => {
         context.randomInstallations
       );
 
-      expect(actual).toEqual([]);
+      expect(actual.results).toEqual([]);
       expect(vi.mocked(getModFiles)).toHaveBeenCalledWith(context.config, context.randomConfiguration);
     });
   });
@@ -68,7 +76,7 @@ describe('The scan library', () => {
         context.randomInstallations
       );
 
-      expect(actual).toEqual([]);
+      expect(actual.results).toEqual([]);
     });
   });
 
@@ -83,7 +91,10 @@ describe('The scan library', () => {
       context.randomConfiguration.modsFolder = randomModsFolder;
       vi.mocked(getModFiles).mockResolvedValueOnce([randomFileName]);
       vi.mocked(fileIsManaged).mockReturnValueOnce(false); // non-managed path
-      vi.mocked(curseforge.fingerprint).mockReturnValueOnce(randomFingerprint);
+      vi.mocked(getFingerprints).mockResolvedValueOnce({
+        fingerprints: [{ file: expectedPath, fingerprint: randomFingerprint }],
+        errors: []
+      });
       vi.mocked(getHash).mockResolvedValueOnce(randomHash);
 
       vi.mocked(lookup).mockResolvedValueOnce([]); // we don't care about the return just yet
@@ -91,9 +102,9 @@ describe('The scan library', () => {
       await scan(context.config, context.randomPlatform, context.randomConfiguration, context.randomInstallations);
 
       //expectations
-      // do we call the curseforge fingerprint with the correct values?
-      expect(curseforge.fingerprint).toHaveBeenCalledOnce();
-      expect(curseforge.fingerprint).toHaveBeenCalledWith(expectedPath); // whatever comes from the getModFiles
+      // do we fingerprint the files with the pool?
+      expect(getFingerprints).toHaveBeenCalledOnce();
+      expect(getFingerprints).toHaveBeenCalledWith([expectedPath], undefined); // whatever comes from the getModFiles
 
       // do we call the modrinth hasher with the correct values?
       expect(getHash).toHaveBeenCalledOnce();
@@ -120,8 +131,9 @@ describe('The scan library', () => {
       context.randomConfiguration.modsFolder = randomModsFolder;
       vi.mocked(getModFiles).mockResolvedValueOnce([randomFileName]);
       vi.mocked(fileIsManaged).mockReturnValueOnce(false); // non-managed path
-      vi.mocked(curseforge.fingerprint).mockImplementation(() => {
-        throw new Error('test-error');
+      vi.mocked(getFingerprints).mockResolvedValueOnce({
+        fingerprints: [],
+        errors: [{ file: expectedPath, error: new Error('test-error') }]
       });
 
       vi.mocked(getHash).mockResolvedValueOnce(randomHash);
@@ -131,9 +143,9 @@ describe('The scan library', () => {
       await scan(context.config, context.randomPlatform, context.randomConfiguration, context.randomInstallations);
 
       //expectations
-      // do we call the curseforge fingerprint with the correct values?
-      expect(curseforge.fingerprint).toHaveBeenCalledOnce();
-      expect(curseforge.fingerprint).toHaveBeenCalledWith(expectedPath); // whatever comes from the getModFiles
+      // do we fingerprint the files with the pool?
+      expect(getFingerprints).toHaveBeenCalledOnce();
+      expect(getFingerprints).toHaveBeenCalledWith([expectedPath], undefined); // whatever comes from the getModFiles
 
       expect(vi.mocked(lookup)).toHaveBeenCalledWith([
         {
@@ -154,7 +166,10 @@ describe('The scan library', () => {
 
       vi.mocked(getModFiles).mockResolvedValueOnce([randomFileName]);
       vi.mocked(fileIsManaged).mockReturnValueOnce(false); // non-managed path
-      vi.mocked(curseforge.fingerprint).mockReturnValueOnce(randomFingerprint);
+      vi.mocked(getFingerprints).mockResolvedValueOnce({
+        fingerprints: [{ file: randomFileName, fingerprint: randomFingerprint }],
+        errors: []
+      });
       vi.mocked(getHash).mockResolvedValueOnce(randomHash);
 
       vi.mocked(lookup).mockResolvedValueOnce([]); // we don't care about the return just yet
@@ -173,6 +188,36 @@ describe('The scan library', () => {
       });
     });
 
+    it<LocalTestContext>('traces the files that no platform matched', async (context) => {
+      const matchedHash = chance.hash();
+      vi.mocked(getModFiles).mockResolvedValueOnce(['/mods/known.jar', '/mods/unknown.jar', '/mods/unreadable.jar']);
+      vi.mocked(fileIsManaged).mockReturnValue(false);
+      vi.mocked(getFingerprints).mockResolvedValueOnce({
+        fingerprints: [
+          { file: '/mods/known.jar', fingerprint: 111 },
+          { file: '/mods/unknown.jar', fingerprint: 222 }
+        ],
+        errors: [{ file: '/mods/unreadable.jar', error: new Error('unreadable') }]
+      });
+      vi.mocked(getHash)
+        .mockResolvedValueOnce(matchedHash)
+        .mockResolvedValueOnce(chance.hash())
+        .mockResolvedValueOnce(chance.hash());
+      vi.mocked(fetchModDetails).mockResolvedValueOnce(generateRemoteModDetails().generated);
+      vi.mocked(lookup).mockResolvedValueOnce([
+        generateResultItem({ sha1Hash: matchedHash, hits: [generatePlatformLookupResult().generated] }).generated
+      ]);
+
+      const actual = await scan(
+        context.config,
+        context.randomPlatform,
+        context.randomConfiguration,
+        context.randomInstallations
+      );
+
+      expect(actual.unmatched).toEqual([{ fingerprint: '222', files: ['/mods/unknown.jar'] }]);
+    });
+
     describe('and the preferred platform has no results', () => {
       const preferredPlatform = Platform.MODRINTH;
       const notThePreferredPlatform = Platform.CURSEFORGE;
@@ -201,9 +246,9 @@ describe('The scan library', () => {
           context.randomInstallations
         );
 
-        expect(actual[0].preferredDetails).toBe(expectedModDetails);
-        expect(actual[0].localDetails[0]).toBe(expectedHit);
-        expect(actual[0].allRemoteDetails[0]).toBe(expectedModDetails);
+        expect(actual.results[0].preferredDetails).toBe(expectedModDetails);
+        expect(actual.results[0].localDetails[0]).toBe(expectedHit);
+        expect(actual.results[0].allRemoteDetails[0]).toBe(expectedModDetails);
       });
     });
 
@@ -235,9 +280,9 @@ describe('The scan library', () => {
           context.randomInstallations
         );
 
-        expect(actual[0].preferredDetails).toBe(expectedModDetails);
-        expect(actual[0].allRemoteDetails[0]).toBe(expectedModDetails);
-        expect(actual[0].localDetails[0]).toBe(preferredHit);
+        expect(actual.results[0].preferredDetails).toBe(expectedModDetails);
+        expect(actual.results[0].allRemoteDetails[0]).toBe(expectedModDetails);
+        expect(actual.results[0].localDetails[0]).toBe(preferredHit);
       });
 
       it<LocalTestContext>('adds all results to the response', async (context) => {
@@ -281,11 +326,81 @@ describe('The scan library', () => {
         expect(fetchCalls[1][1]).toEqual(notPreferredHit.modId);
 
         // Assess the result
-        expect(actual[0].preferredDetails).toBe(preferredModDetails);
-        expect(actual[0].allRemoteDetails[0]).toBe(preferredModDetails);
-        expect(actual[0].allRemoteDetails[1]).toBe(notPreferredModDetails);
-        expect(actual[0].localDetails[0]).toBe(preferredHit);
-        expect(actual[0].localDetails[1]).toBe(notPreferredHit);
+        expect(actual.results[0].preferredDetails).toBe(preferredModDetails);
+        expect(actual.results[0].allRemoteDetails[0]).toBe(preferredModDetails);
+        expect(actual.results[0].allRemoteDetails[1]).toBe(notPreferredModDetails);
+        expect(actual.results[0].localDetails[0]).toBe(preferredHit);
+        expect(actual.results[0].localDetails[1]).toBe(notPreferredHit);
+      });
+    });
+
+    describe('and the files are on both platforms', () => {
+      it<LocalTestContext>('tags every match with its platform', async (context) => {
+        const curseforgeHit = generatePlatformLookupResult({ platform: Platform.CURSEFORGE }).generated;
+        const modrinthHit = generatePlatformLookupResult({ platform: Platform.MODRINTH }).generated;
+        const curseforgeDetails = generateRemoteModDetails().generated;
+        const modrinthDetails = generateRemoteModDetails().generated;
+
+        vi.mocked(getModFiles).mockResolvedValueOnce([chance.word(), chance.word()]);
+        vi.mocked(fetchModDetails).mockResolvedValueOnce(curseforgeDetails);
+        vi.mocked(fetchModDetails).mockResolvedValueOnce(modrinthDetails);
+        vi.mocked(lookup).mockResolvedValueOnce([
+          generateResultItem({ hits: [curseforgeHit] }).generated,
+          generateResultItem({ hits: [modrinthHit] }).generated
+        ]);
+
+        const actual = await scan(
+          context.config,
+          Platform.MODRINTH,
+          context.randomConfiguration,
+          context.randomInstallations
+        );
+
+        expect(actual.results.map((result) => result.matches)).toEqual([
+          [
+            {
+              platform: Platform.CURSEFORGE,
+              modId: curseforgeHit.modId,
+              local: curseforgeHit.mod,
+              remote: curseforgeDetails
+            }
+          ],
+          [
+            {
+              platform: Platform.MODRINTH,
+              modId: modrinthHit.modId,
+              local: modrinthHit.mod,
+              remote: modrinthDetails
+            }
+          ]
+        ]);
+      });
+
+      it<LocalTestContext>('keeps the platform of the match that could be resolved', async (context) => {
+        const modrinthHit = generatePlatformLookupResult({ platform: Platform.MODRINTH }).generated;
+        const curseforgeHit = generatePlatformLookupResult({ platform: Platform.CURSEFORGE }).generated;
+        const curseforgeDetails = generateRemoteModDetails().generated;
+
+        vi.mocked(getModFiles).mockResolvedValueOnce([chance.word()]);
+        vi.mocked(fetchModDetails).mockRejectedValueOnce(new NoRemoteFileFound(modrinthHit.modId, Platform.MODRINTH));
+        vi.mocked(fetchModDetails).mockResolvedValueOnce(curseforgeDetails);
+        vi.mocked(lookup).mockResolvedValueOnce([generateResultItem({ hits: [curseforgeHit, modrinthHit] }).generated]);
+
+        const actual = await scan(
+          context.config,
+          Platform.MODRINTH,
+          context.randomConfiguration,
+          context.randomInstallations
+        );
+
+        expect(actual.results[0].matches).toEqual([
+          {
+            platform: Platform.CURSEFORGE,
+            modId: curseforgeHit.modId,
+            local: curseforgeHit.mod,
+            remote: curseforgeDetails
+          }
+        ]);
       });
     });
 
@@ -313,11 +428,12 @@ describe('The scan library', () => {
           context.randomInstallations
         );
 
-        expect(actual).toEqual([
+        expect(actual.results).toEqual([
           {
             preferredDetails: undefined,
             allRemoteDetails: [],
-            localDetails: lookupResult.hits
+            localDetails: lookupResult.hits,
+            matches: []
           }
         ]);
       });
@@ -345,11 +461,12 @@ describe('The scan library', () => {
           context.randomInstallations
         );
 
-        expect(actual).toEqual([
+        expect(actual.results).toEqual([
           {
             preferredDetails: undefined,
             allRemoteDetails: [],
-            localDetails: lookupResult.hits
+            localDetails: lookupResult.hits,
+            matches: []
           }
         ]);
       });
@@ -372,8 +489,115 @@ describe('The scan library', () => {
           context.randomInstallations
         );
 
-        expect(actual).toEqual([]);
+        expect(actual.results).toEqual([]);
+      });
+    });
+  });
+
+  describe('when tracing the fingerprints back to the files', () => {
+    it('keys the files by their fingerprints', async () => {
+      vi.mocked(getFingerprints).mockResolvedValueOnce({
+        fingerprints: [
+          { file: '/mods/a.jar', fingerprint: 111 },
+          { file: '/mods/b.jar', fingerprint: 222 }
+        ],
+        errors: []
+      });
+
+      const actual = await fingerprintFiles(['/mods/a.jar', '/mods/b.jar'], 4);
+
+      expect(vi.mocked(getFingerprints)).toHaveBeenCalledWith(['/mods/a.jar', '/mods/b.jar'], 4);
+      expect(actual).toEqual(
+        new Map([
+          ['111', ['/mods/a.jar']],
+          ['222', ['/mods/b.jar']]
+        ])
+      );
+    });
+
+    it('keeps every copy of the same jar', async () => {
+      vi.mocked(getFingerprints).mockResolvedValueOnce({
+        fingerprints: [
+          { file: '/mods/a.jar', fingerprint: 111 },
+          { file: '/mods/copy-of-a.jar', fingerprint: 111 }
+        ],
+        errors: []
+      });
+
+      const actual = await fingerprintFiles(['/mods/a.jar', '/mods/copy-of-a.jar']);
+
+      expect(actual.get('111')).toEqual(['/mods/a.jar', '/mods/copy-of-a.jar']);
+    });
+
+    it('leaves out the files without a fingerprint', async () => {
+      vi.mocked(getFingerprints).mockResolvedValueOnce({
+        fingerprints: [{ file: '/mods/b.jar', fingerprint: 222 }],
+        errors: [{ file: '/mods/broken.jar', error: new Error('unreadable') }]
       });
+
+      const actual = await fingerprintFiles(['/mods/broken.jar', '/mods/b.jar']);
+
+      expect([...actual.keys()]).toEqual(['222']);
+    });
+
+    it('tells which files were not matched', () => {
+      const fingerprintedFiles = new Map([
+        ['111', ['/mods/a.jar']],
+        ['222', ['/mods/b.jar', '/mods/copy-of-b.jar']],
+        ['333', ['/mods/c.jar']]
+      ]);
+
+      const actual = unmatchedFiles(['222', '333'], fingerprintedFiles);
+
+      expect(actual).toEqual([
+        { fingerprint: '222', files: ['/mods/b.jar', '/mods/copy-of-b.jar'] },
+        { fingerprint: '333', files: ['/mods/c.jar'] }
+      ]);
+    });
+
+    it('ignores the fingerprints that were not scanned', () => {
+      const actual = unmatchedFiles(['999'], new Map([['111', ['/mods/a.jar']]]));
+
+      expect(actual).toEqual([]);
+    });
+  });
+
+  describe('when guessing the unmatched files', () => {
+    it('asks Curseforge about the first copy of each file', async () => {
+      vi.mocked(fs.stat).mockResolvedValueOnce({ size: 2048 } as Awaited<ReturnType<typeof fs.stat>>);
+
+      await guessUnmatchedFiles([{ fingerprint: '222', files: ['/mods/b.jar', '/mods/copy-of-b.jar'] }]);
+
+      expect(vi.mocked(fs.stat)).toHaveBeenCalledWith('/mods/b.jar');
+      expect(vi.mocked(resolveFuzzy)).toHaveBeenCalledWith([{ fingerprint: 222, fileName: 'b.jar', size: 2048 }]);
+    });
+
+    it('keeps the guess of a partially matched file', async () => {
+      const guess = { fingerprint: 222, modId: '394468', file: generateCurseforgeModFile().generated, confidence: 0.9 };
+      vi.mocked(resolveFuzzy).mockResolvedValueOnce([guess]);
+
+      const actual = await guessUnmatchedFiles([
+        { fingerprint: '222', files: ['/mods/b.jar'] },
+        { fingerprint: '333', files: ['/mods/c.jar'] }
+      ]);
+
+      expect(actual).toEqual([
+        { fingerprint: '222', files: ['/mods/b.jar'], guess: guess },
+        { fingerprint: '333', files: ['/mods/c.jar'] }
+      ]);
+    });
+
+    it('leaves the files unmatched when Curseforge fails', async () => {
+      vi.mocked(resolveFuzzy).mockRejectedValueOnce(new Error('Curseforge is down'));
+
+      const actual = await guessUnmatchedFiles([{ fingerprint: '222', files: ['/mods/b.jar'] }]);
+
+      expect(actual).toEqual([{ fingerprint: '222', files: ['/mods/b.jar'] }]);
+    });
+
+    it('does not ask Curseforge when every file matched', async () => {
+      expect(await guessUnmatchedFiles([])).toEqual([]);
+      expect(vi.mocked(resolveFuzzy)).not.toHaveBeenCalled();
     });
   });
 });
@@ -1,56 +1,145 @@
-import curseforge from '@meza/curseforge-fingerprint';
-import { ScanResults } from '../actions/scan.js';
+import fs from 'node:fs/promises';
+import path from 'path';
+import { ScanMatch, ScanResults } from '../actions/scan.js';
 import { CurseforgeDownloadUrlError } from '../errors/CurseforgeDownloadUrlError.js';
 import { NoRemoteFileFound } from '../errors/NoRemoteFileFound.js';
+import { FuzzyMatch, resolveFuzzy } from '../repositories/curseforge/fuzzy.js';
 import { LookupInput, ResultItem, fetchModDetails, lookup } from '../repositories/index.js';
 import { Modrinth } from '../repositories/modrinth/index.js';
 import { fileIsManaged } from './configurationHelper.js';
 import { getModFiles } from './fileHelper.js';
+import { getFingerprints } from './fingerprint.js';
 import { getHash } from './hash.js';
 import { ModInstall, ModsJson, Platform } from './modlist.types.js';
+import { allowedReleaseTypesOf } from './releaseChannel.js';
+
+export interface UnmatchedFile {
+  fingerprint: string;
+  /**
+   * Every file with the fingerprint, the same jar can be in the folder more than once
+   */
+  files: string[];
+  /**
+   * The Curseforge file the jar most likely is, only there when Curseforge matched it partially
+   */
+  guess?: FuzzyMatch;
+}
+
+/**
+ * Keys the files by their Curseforge fingerprints, so the fingerprints Curseforge answers with can be traced back to
+ * the jars they came from. The files without a fingerprint are left out.
+ *
+ * @param files The absolute paths of the files
+ * @param concurrency The maximum number of files fingerprinted at the same time
+ */
+export const fingerprintFiles = async (files: string[], concurrency?: number): Promise<Map<string, string[]>> => {
+  const { fingerprints } = await getFingerprints(files, concurrency);
+  const fingerprintedFiles = new Map<string, string[]>();
+
+  fingerprints.forEach(({ file, fingerprint }) => {
+    const key = String(fingerprint);
+    fingerprintedFiles.set(key, [...(fingerprintedFiles.get(key) || []), file]);
+  });
+
+  return fingerprintedFiles;
+};
+
+/**
+ * The files behind the fingerprints that Curseforge matched neither exactly nor partially
+ *
+ * @param unmatched The unmatched fingerprints of the lookup
+ * @param fingerprintedFiles The files keyed by their fingerprints
+ */
+export const unmatchedFiles = (unmatched: string[], fingerprintedFiles: Map<string, string[]>): UnmatchedFile[] => {
+  return unmatched
+    .filter((fingerprint) => fingerprintedFiles.has(fingerprint))
+    .map((fingerprint) => ({ fingerprint: fingerprint, files: fingerprintedFiles.get(fingerprint) as string[] }));
+};
+
+/**
+ * Guesses which Curseforge files the unmatched jars are from the partial matches of Curseforge, like the jars that
+ * were re-zipped. The guesses are only a hint, the files are left unmatched when Curseforge can't make one.
+ *
+ * @param unmatched The files that no platform could match
+ */
+export const guessUnmatchedFiles = async (unmatched: UnmatchedFile[]): Promise<UnmatchedFile[]> => {
+  if (unmatched.length === 0) {
+    return unmatched;
+  }
 
-const getScanResults = async (files: string[], installations: ModInstall[]) => {
+  try {
+    const jars = await Promise.all(
+      unmatched.map(async ({ fingerprint, files }) => ({
+        fingerprint: Number(fingerprint),
+        fileName: path.basename(files[0]),
+        size: (await fs.stat(files[0])).size
+      }))
+    );
+    const guesses = await resolveFuzzy(jars);
+
+    return unmatched.map((unmatchedFile) => {
+      const guess = guesses.find((match) => String(match.fingerprint) === unmatchedFile.fingerprint);
+      return guess ? { ...unmatchedFile, guess: guess } : unmatchedFile;
+    });
+  } catch {
+    return unmatched;
+  }
+};
+
+interface ScanLookup {
+  lookupResults: ResultItem[];
+  unmatched: UnmatchedFile[];
+}
+
+const getScanResults = async (files: string[], installations: ModInstall[]): Promise<ScanLookup> => {
+  const unmanagedFiles = files.filter((filePath) => !fileIsManaged(filePath, installations));
+  if (unmanagedFiles.length === 0) {
+    return { lookupResults: [], unmatched: [] };
+  }
+
+  // The files are fingerprinted by the pool, the ones that can't be are left to Modrinth
+  const fingerprintedFiles = await fingerprintFiles(unmanagedFiles);
+  const hashes = await Promise.all(unmanagedFiles.map((filePath) => getHash(filePath, Modrinth.PREFERRED_HASH)));
   const cfInput: LookupInput = {
     platform: Platform.CURSEFORGE,
-    hash: []
+    hash: [...fingerprintedFiles.keys()]
   };
   const modrinthInput: LookupInput = {
     platform: Platform.MODRINTH,
-    hash: []
+    hash: hashes
   };
-  let found = 0;
-  const all = files.map(async (filePath) => {
-    if (fileIsManaged(filePath, installations)) {
-      return;
-    }
-    found++;
-    try {
-      const fingerprint = curseforge.fingerprint(filePath);
-      cfInput.hash.push(String(fingerprint));
-    } catch (_) {
-      //ignore
-    }
-    const fileSha1Hash = await getHash(filePath, Modrinth.PREFERRED_HASH);
-    modrinthInput.hash.push(fileSha1Hash);
-  });
-
-  await Promise.all(all);
-  if (found === 0) {
-    return [];
-  }
 
   const lookupResults: ResultItem[] = await lookup([cfInput, modrinthInput]);
-  return lookupResults;
+
+  // Both platforms answer with the sha1 of the file, whatever it was looked up with
+  const matchedHashes = new Set(lookupResults.map((lookupResult) => lookupResult.sha1Hash));
+  const matchedFiles = new Set(unmanagedFiles.filter((_filePath, index) => matchedHashes.has(hashes[index])));
+  const unmatchedFingerprints = [...fingerprintedFiles.entries()]
+    .filter(([, fingerprinted]) => !fingerprinted.some((filePath) => matchedFiles.has(filePath)))
+    .map(([fingerprint]) => fingerprint);
+
+  return {
+    lookupResults: lookupResults,
+    unmatched: await guessUnmatchedFiles(unmatchedFiles(unmatchedFingerprints, fingerprintedFiles))
+  };
 };
 
+export interface FolderScan {
+  results: ScanResults[];
+  /**
+   * The files that no platform could match
+   */
+  unmatched: UnmatchedFile[];
+}
+
 export const scanFiles = async (
   files: string[],
   installations: ModInstall[],
   prefer: Platform,
   configuration: ModsJson
-) => {
+): Promise<FolderScan> => {
   performance.mark('lib-scan-start');
-  const lookupResults = await getScanResults(files, installations);
+  const { lookupResults, unmatched } = await getScanResults(files, installations);
 
   const normalizers: Promise<ScanResults>[] = [];
 
@@ -64,19 +153,26 @@ export const scanFiles = async (
     });
 
     const allDetails = [];
+    const matches: ScanMatch[] = [];
 
     for (let i = 0; i < lookupResult.hits.length; i++) {
       try {
         const deets = await fetchModDetails(
           lookupResult.hits[i].platform,
           lookupResult.hits[i].modId,
-          configuration.defaultAllowedReleaseTypes,
+          allowedReleaseTypesOf({ type: lookupResult.hits[i].platform }, configuration),
           configuration.gameVersion,
           configuration.loader,
           false //TODO: Figure out how to handle this. Should scan allow fallback? Does it even matter? What's the logic here?
         );
 
         allDetails[i] = deets;
+        matches.push({
+          platform: lookupResult.hits[i].platform,
+          modId: lookupResult.hits[i].modId,
+          local: lookupResult.hits[i].mod,
+          remote: deets
+        });
       } catch (error) {
         if (!(error instanceof CurseforgeDownloadUrlError || error instanceof NoRemoteFileFound)) {
           // Edge case for a freak Curseforge bug and the no remote file
@@ -93,7 +189,8 @@ export const scanFiles = async (
     return {
       preferredDetails: finalDetails[0],
       allRemoteDetails: finalDetails,
-      localDetails: lookupResult.hits
+      localDetails: lookupResult.hits,
+      matches: matches
     };
   };
 
@@ -112,7 +209,10 @@ export const scanFiles = async (
     result.push(normalizeResult.value);
   });
 
-  return result;
+  return {
+    results: result,
+    unmatched: unmatched
+  };
 };
 
 export const scan = async (
@@ -120,7 +220,7 @@ export const scan = async (
   prefer: Platform,
   configuration: ModsJson,
   installations: ModInstall[]
-) => {
+): Promise<FolderScan> => {
   const files = await getModFiles(configLocation, configuration);
   return scanFiles(files, installations, prefer, configuration);
 };
@@ -0,0 +1,21 @@
+import { describe, expect, it } from 'vitest';
+import { supportedTargetsOf } from './supportedTargets.js';
+
+describe('The supported targets', () => {
+  it('collects the game versions newest first and the loaders once', () => {
+    const files = [
+      { gameVersions: ['1.19.2', '1.20.1'], loaders: ['Fabric'] },
+      { gameVersions: ['1.20.4', '1.20.1'], loaders: ['fabric', 'Quilt'] },
+      { gameVersions: ['1.18.2'], loaders: ['forge'] }
+    ];
+
+    expect(supportedTargetsOf(files)).toEqual({
+      gameVersions: ['1.20.4', '1.20.1', '1.19.2', '1.18.2'],
+      loaders: ['fabric', 'forge', 'quilt']
+    });
+  });
+
+  it('knows nothing without files', () => {
+    expect(supportedTargetsOf([])).toEqual({ gameVersions: [], loaders: [] });
+  });
+});
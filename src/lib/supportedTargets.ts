@@ -0,0 +1,15 @@
+import { SupportedTargets } from '../errors/NoRemoteFileFound.js';
+import { compareGameVersions } from './gameVersionMatcher.js';
+
+/**
+ * Collects the game versions and the loaders the files were made for, the game versions newest first
+ */
+export const supportedTargetsOf = (files: { gameVersions: string[]; loaders: string[] }[]): SupportedTargets => {
+  const gameVersions = new Set(files.flatMap((file) => file.gameVersions));
+  const loaders = new Set(files.flatMap((file) => file.loaders.map((loader) => loader.toLowerCase())));
+
+  return {
+    gameVersions: [...gameVersions].sort((a, b) => compareGameVersions(b, a)),
+    loaders: [...loaders].sort()
+  };
+};
@@ -0,0 +1,81 @@
+import { describe, expect, it } from 'vitest';
+import { TomlSyntaxError } from '../errors/TomlSyntaxError.js';
+import { parseToml, stringifyToml } from './toml.js';
+
+const errorOf = (text: string): TomlSyntaxError | undefined => {
+  try {
+    parseToml(text);
+  } catch (error) {
+    return error as TomlSyntaxError;
+  }
+  return undefined;
+};
+
+describe('The TOML library', () => {
+  describe('when reading', () => {
+    it('reads the keys and the tables', () => {
+      const text = [
+        '# The modlist',
+        'loader = "fabric" # the loader',
+        '',
+        '[server]',
+        "modsFolder = 'C:\\mods'",
+        '',
+        '[[mods]]',
+        'id = "AANobbMI"'
+      ].join('\n');
+
+      expect(parseToml(text)).toEqual({
+        loader: 'fabric',
+        server: {
+          modsFolder: 'C:\\mods'
+        },
+        mods: [{ id: 'AANobbMI' }]
+      });
+    });
+
+    it.each([
+      ['value = "open', 1],
+      ['\n\nvalue = what', 3],
+      ['value = 1\nvalue = 2', 2]
+    ])('does not read %j', (text, line) => {
+      const error = errorOf(text);
+
+      expect(error).toBeInstanceOf(TomlSyntaxError);
+      expect(error?.line).toEqual(line);
+    });
+
+    it('does not repeat the document in the reason', () => {
+      const reason = errorOf('value = "open')?.reason;
+
+      expect(reason).not.toContain('\n');
+      expect(reason).not.toContain('Invalid TOML document');
+    });
+  });
+
+  describe('when writing', () => {
+    it('leaves out the keys without a value', () => {
+      const actual = stringifyToml({
+        loader: 'fabric',
+        nothing: undefined,
+        empty: null,
+        mods: [{ id: 'AANobbMI', name: undefined }]
+      });
+
+      expect(parseToml(actual)).toEqual({ loader: 'fabric', mods: [{ id: 'AANobbMI' }] });
+    });
+
+    it('reads back what it writes', () => {
+      const value = {
+        name: 'Sodium "Extra"\t\\',
+        count: 3,
+        enabled: false,
+        list: [1, 'two', [3]],
+        nested: { deeper: { deepest: 'yes' } },
+        tables: [{ inner: { 'odd key': 'value' } }, { other: true }]
+      };
+
+      expect(parseToml(stringifyToml(value))).toEqual(value);
+    });
+  });
+});
@@ -0,0 +1,53 @@
+import { TomlError, parse, stringify } from 'smol-toml';
+import { TomlSyntaxError } from '../errors/TomlSyntaxError.js';
+
+export type TomlValue = string | number | boolean | TomlValue[] | TomlTable;
+
+export interface TomlTable {
+  [key: string]: TomlValue;
+}
+
+/**
+ * The first line of the message, without the part of the document the library quotes after it
+ */
+const reasonOf = (error: TomlError) => {
+  return error.message.split('\n')[0].replace(/^Invalid TOML document: /, '');
+};
+
+/**
+ * @throws {TomlSyntaxError} When the text isn't valid TOML
+ */
+export const parseToml = (text: string): TomlTable => {
+  try {
+    return parse(text) as TomlTable;
+  } catch (error) {
+    if (error instanceof TomlError) {
+      throw new TomlSyntaxError(error.line, reasonOf(error));
+    }
+    throw error;
+  }
+};
+
+/**
+ * TOML has no null, the keys without a value are left out like JSON.stringify leaves out the undefined ones
+ */
+const withoutEmptyValues = (value: unknown): unknown => {
+  if (Array.isArray(value)) {
+    return value.map(withoutEmptyValues);
+  }
+  if (typeof value === 'object' && value !== null) {
+    return Object.fromEntries(
+      Object.entries(value)
+        .filter(([, inner]) => inner !== undefined && inner !== null)
+        .map(([key, inner]) => [key, withoutEmptyValues(inner)])
+    );
+  }
+  return value;
+};
+
+/**
+ * Writes the object as a TOML document, the plain values of a table come before its tables
+ */
+export const stringifyToml = (value: object): string => {
+  return stringify(withoutEmptyValues(value) as TomlTable);
+};
@@ -0,0 +1,96 @@
+import { describe, expect, it } from 'vitest';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { Mod, ModInstall, Platform, RequiredDependency } from './modlist.types.js';
+import { UnsatisfiedReason, findUnsatisfiedDependencies } from './unsatisfiedDependencies.js';
+
+const configurationWith = (mods: Mod[]) => generateModsJson({ mods: mods }).generated;
+
+const installationOf = (mod: Mod, dependencies?: RequiredDependency[]): ModInstall =>
+  generateModInstall({ type: mod.type, id: mod.id, dependencies: dependencies }).generated;
+
+const curseforge = (id: string): RequiredDependency => ({ type: Platform.CURSEFORGE, id: id });
+
+describe('The unsatisfied dependency detection', () => {
+  it('accepts a modlist that has every required dependency', () => {
+    const mod = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+    const dependency = generateModConfig({ type: Platform.CURSEFORGE, id: '2' }).generated;
+
+    const actual = findUnsatisfiedDependencies(configurationWith([mod, dependency]), [
+      installationOf(mod, [curseforge('2')]),
+      installationOf(dependency)
+    ]);
+
+    expect(actual).toEqual([]);
+  });
+
+  it('flags a required dependency that is not in the modlist', () => {
+    const mod = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+
+    const actual = findUnsatisfiedDependencies(configurationWith([mod]), [installationOf(mod, [curseforge('2')])]);
+
+    expect(actual).toEqual([{ mod: mod, dependency: curseforge('2'), reason: UnsatisfiedReason.MISSING }]);
+  });
+
+  it('flags a required dependency that no longer supports the game version', () => {
+    const mod = generateModConfig({ type: Platform.MODRINTH, id: 'sodium-extra' }).generated;
+    const dependency = generateModConfig({ type: Platform.MODRINTH, id: 'sodium' }).generated;
+
+    // Nothing could be installed for the dependency, it has no file for the game version anymore
+    const actual = findUnsatisfiedDependencies(configurationWith([mod, dependency]), [
+      installationOf(mod, [{ type: Platform.MODRINTH, id: 'sodium' }])
+    ]);
+
+    expect(actual).toEqual([
+      {
+        mod: mod,
+        dependency: { type: Platform.MODRINTH, id: 'sodium' },
+        reason: UnsatisfiedReason.UNAVAILABLE,
+        provider: dependency
+      }
+    ]);
+  });
+
+  it('accepts a dependency provided by the fallback of a mod', () => {
+    const mod = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+    const dependency = generateModConfig({
+      type: Platform.MODRINTH,
+      id: 'P7dR8mSH',
+      fallback: { type: Platform.CURSEFORGE, id: '2' }
+    }).generated;
+
+    const actual = findUnsatisfiedDependencies(configurationWith([mod, dependency]), [
+      installationOf(mod, [curseforge('2')]),
+      installationOf(dependency)
+    ]);
+
+    expect(actual).toEqual([]);
+  });
+
+  it('does not let a mod depend on itself', () => {
+    const mod = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+
+    const actual = findUnsatisfiedDependencies(configurationWith([mod]), [installationOf(mod, [curseforge('1')])]);
+
+    expect(actual).toEqual([]);
+  });
+
+  it('ignores the mods that are not installed', () => {
+    const mod = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+
+    expect(findUnsatisfiedDependencies(configurationWith([mod]), [])).toEqual([]);
+  });
+
+  it('reports the dependency for every mod that needs it', () => {
+    const first = generateModConfig({ type: Platform.CURSEFORGE, id: '1' }).generated;
+    const second = generateModConfig({ type: Platform.CURSEFORGE, id: '2' }).generated;
+
+    const actual = findUnsatisfiedDependencies(configurationWith([first, second]), [
+      installationOf(first, [curseforge('3')]),
+      installationOf(second, [curseforge('3')])
+    ]);
+
+    expect(actual.map((unsatisfied) => unsatisfied.mod)).toEqual([first, second]);
+  });
+});
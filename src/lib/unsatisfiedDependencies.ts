@@ -0,0 +1,73 @@
+import { getInstallation } from './configurationHelper.js';
+import { Mod, ModInstall, ModsJson, RequiredDependency } from './modlist.types.js';
+
+export enum UnsatisfiedReason {
+  // eslint-disable-next-line no-unused-vars
+  MISSING = 'missing',
+  // eslint-disable-next-line no-unused-vars
+  UNAVAILABLE = 'unavailable'
+}
+
+export interface UnsatisfiedDependency {
+  /**
+   * The mod whose installed file requires the other one
+   */
+  mod: Mod;
+  dependency: RequiredDependency;
+  reason: UnsatisfiedReason;
+  /**
+   * The mod of the modlist that should provide the dependency, when there is one
+   */
+  provider?: Mod;
+}
+
+/**
+ * Whether the mod is the project, on its own platform or through its fallback
+ */
+export const provides = (mod: Mod, dependency: RequiredDependency) => {
+  return (
+    (mod.type === dependency.type && mod.id === dependency.id) ||
+    (mod.fallback?.type === dependency.type && mod.fallback?.id === dependency.id)
+  );
+};
+
+/**
+ * The required dependencies of the installed mods the modlist can't satisfy, because they aren't in it or couldn't be
+ * installed, like a dependency that was removed from the platform or doesn't support the game version anymore.
+ * This should be checked once the mods are resolved, a mod without its dependencies won't load.
+ *
+ * A dependency required by several mods is reported for each of them.
+ *
+ * @param configuration
+ * @param installations The mods that ended up installed, with the dependencies of their files
+ */
+export const findUnsatisfiedDependencies = (
+  configuration: ModsJson,
+  installations: ModInstall[]
+): UnsatisfiedDependency[] => {
+  const unsatisfied: UnsatisfiedDependency[] = [];
+
+  configuration.mods.forEach((mod) => {
+    const installation = installations[getInstallation(mod, installations)];
+
+    (installation?.dependencies || [])
+      .filter((dependency) => !provides(mod, dependency))
+      .forEach((dependency) => {
+        const provider = configuration.mods.find((other) => provides(other, dependency));
+        if (!provider) {
+          unsatisfied.push({ mod: mod, dependency: dependency, reason: UnsatisfiedReason.MISSING });
+          return;
+        }
+        if (getInstallation(provider, installations) === -1) {
+          unsatisfied.push({
+            mod: mod,
+            dependency: dependency,
+            reason: UnsatisfiedReason.UNAVAILABLE,
+            provider: provider
+          });
+        }
+      });
+  });
+
+  return unsatisfied;
+};
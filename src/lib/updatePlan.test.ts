@@ -0,0 +1,204 @@
+import { chance } from 'jest-chance';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateRemoteModDetails } from '../../test/generateRemoteDetails.js';
+import { generateModConfig } from '../../test/modConfigGenerator.js';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { generateModsJson } from '../../test/modlistGenerator.js';
+import { ProjectToResolve, resolveProjects } from '../repositories/index.js';
+import { Mod, ModInstall, ModsJson, ReleaseType, RemoteModDetails } from './modlist.types.js';
+import { PlannedChangeType, planUpdate } from './updatePlan.js';
+
+vi.mock('../repositories/index.js');
+
+const olderDate = '2023-01-01T00:00:00.000Z';
+const newerDate = '2023-06-01T00:00:00.000Z';
+
+const installationFor = (mod: Mod, overrides?: Partial<ModInstall>) =>
+  generateModInstall({ id: mod.id, type: mod.type, releasedOn: olderDate, ...overrides }).generated;
+
+const assumeResolved = (details: RemoteModDetails[]) => {
+  vi.mocked(resolveProjects).mockImplementationOnce(async (projects: ProjectToResolve[]) => ({
+    resolved: projects.map((project, index) => ({ project: project, details: details[index] })),
+    errors: []
+  }));
+};
+
+describe('The update plan', () => {
+  let configuration: ModsJson;
+  let mod: Mod;
+
+  beforeEach(() => {
+    vi.resetAllMocks();
+    mod = generateModConfig().generated;
+    configuration = generateModsJson({ mods: [mod] }).generated;
+  });
+
+  it('plans an upgrade when a newer file is available', async () => {
+    const installation = installationFor(mod);
+    const details = generateRemoteModDetails({ releaseDate: newerDate }).generated;
+    assumeResolved([details]);
+
+    const actual = await planUpdate(configuration, [installation]);
+
+    expect(actual.errors).toEqual([]);
+    expect(actual.changes).toEqual([
+      {
+        id: mod.id,
+        name: details.name,
+        platform: mod.type,
+        type: PlannedChangeType.UPGRADE,
+        currentFileName: installation.fileName,
+        currentReleaseDate: olderDate,
+        newFileName: details.fileName,
+        newReleaseDate: newerDate
+      }
+    ]);
+  });
+
+  it('keeps the id of a new file from Curseforge', async () => {
+    assumeResolved([generateRemoteModDetails({ releaseDate: newerDate, curseforgeFileId: 4567890 }).generated]);
+
+    const actual = await planUpdate(configuration, [installationFor(mod)]);
+
+    expect(actual.changes[0].curseforgeFileId).toEqual(4567890);
+  });
+
+  it('plans an upgrade when the file changed on the same date', async () => {
+    const installation = installationFor(mod);
+    assumeResolved([generateRemoteModDetails({ releaseDate: olderDate }).generated]);
+
+    const actual = await planUpdate(configuration, [installation]);
+
+    expect(actual.changes[0].type).toEqual(PlannedChangeType.UPGRADE);
+  });
+
+  it('plans a downgrade when the available file is older', async () => {
+    const installation = installationFor(mod, { releasedOn: newerDate });
+    assumeResolved([generateRemoteModDetails({ releaseDate: olderDate }).generated]);
+
+    const actual = await planUpdate(configuration, [installation]);
+
+    expect(actual.changes[0].type).toEqual(PlannedChangeType.DOWNGRADE);
+  });
+
+  it('plans nothing when the installed file is the latest', async () => {
+    const installation = installationFor(mod);
+    assumeResolved([generateRemoteModDetails({ hash: installation.hash, releaseDate: olderDate }).generated]);
+
+    const actual = await planUpdate(configuration, [installation]);
+
+    expect(actual.changes[0].type).toEqual(PlannedChangeType.NONE);
+  });
+
+  it('plans an install when the mod is not in the lock file', async () => {
+    const details = generateRemoteModDetails().generated;
+    assumeResolved([details]);
+
+    const actual = await planUpdate(configuration, []);
+
+    expect(actual.changes[0]).toEqual(
+      expect.objectContaining({
+        type: PlannedChangeType.INSTALL,
+        currentFileName: undefined,
+        currentReleaseDate: undefined,
+        newFileName: details.fileName
+      })
+    );
+  });
+
+  it('keeps an installed pinned mod on its file even when a newer one is available', async () => {
+    const pinnedMod = generateModConfig({ pinned: true }).generated;
+    const installation = installationFor(pinnedMod);
+    configuration.mods = [mod, pinnedMod];
+    assumeResolved([generateRemoteModDetails({ releaseDate: newerDate }).generated]);
+
+    const actual = await planUpdate(configuration, [installation]);
+
+    expect(vi.mocked(resolveProjects).mock.calls[0][0].map((project) => project.id)).toEqual([mod.id]);
+    expect(actual.changes[1]).toEqual({
+      id: pinnedMod.id,
+      name: pinnedMod.name,
+      platform: pinnedMod.type,
+      type: PlannedChangeType.PINNED,
+      currentFileName: installation.fileName,
+      currentReleaseDate: olderDate,
+      newFileName: installation.fileName,
+      newReleaseDate: olderDate
+    });
+  });
+
+  it('plans an install for a pinned mod that is not installed yet', async () => {
+    configuration.mods = [generateModConfig({ pinned: true, forceFileId: '4567890' }).generated];
+    assumeResolved([generateRemoteModDetails().generated]);
+
+    const actual = await planUpdate(configuration, []);
+
+    expect(actual.changes[0].type).toEqual(PlannedChangeType.INSTALL);
+  });
+
+  it('resolves the mods with the configured settings', async () => {
+    const concurrency = chance.integer({ min: 1, max: 10 });
+    delete mod.allowedReleaseTypes;
+    assumeResolved([generateRemoteModDetails().generated]);
+
+    await planUpdate(configuration, [], concurrency);
+
+    expect(vi.mocked(resolveProjects)).toHaveBeenCalledWith(
+      [
+        {
+          platform: mod.type,
+          id: mod.id,
+          allowedReleaseTypes: configuration.defaultAllowedReleaseTypes,
+          gameVersion: configuration.gameVersion,
+          loader: configuration.loader,
+          allowFallback: !!mod.allowVersionFallback,
+          version: mod.version
+        }
+      ],
+      concurrency
+    );
+  });
+
+  it('resolves the mods with the release types of their platform', async () => {
+    delete mod.allowedReleaseTypes;
+    configuration.platformAllowedReleaseTypes = { [mod.type]: [ReleaseType.RELEASE] };
+    assumeResolved([generateRemoteModDetails().generated]);
+
+    await planUpdate(configuration, []);
+
+    expect(vi.mocked(resolveProjects)).toHaveBeenCalledWith(
+      [expect.objectContaining({ allowedReleaseTypes: [ReleaseType.RELEASE] })],
+      undefined
+    );
+  });
+
+  it('resolves the mods with their file overrides', async () => {
+    mod.excludeFileNamePattern = '-forge';
+    mod.forceFileId = '4567890';
+    assumeResolved([generateRemoteModDetails().generated]);
+
+    await planUpdate(configuration, []);
+
+    expect(vi.mocked(resolveProjects).mock.calls[0][0][0].overrides).toEqual({
+      excludeFileNamePattern: '-forge',
+      forceFileId: '4567890'
+    });
+  });
+
+  it('reports the mods that could not be resolved', async () => {
+    const failingMod = generateModConfig().generated;
+    configuration.mods.push(failingMod);
+    const error = new Error(chance.sentence());
+
+    vi.mocked(resolveProjects).mockImplementationOnce(async (projects: ProjectToResolve[]) => ({
+      resolved: [{ project: projects[0], details: generateRemoteModDetails().generated }],
+      errors: [{ project: projects[1], error: error }]
+    }));
+
+    const actual = await planUpdate(configuration, [installationFor(mod)]);
+
+    expect(actual.changes).toHaveLength(1);
+    expect(actual.changes[0].id).toEqual(mod.id);
+    expect(actual.errors).toEqual([{ mod: failingMod, error: error }]);
+  });
+});
@@ -0,0 +1,135 @@
+import { ProjectToResolve, resolveProjects } from '../repositories/index.js';
+import { getInstallation } from './configurationHelper.js';
+import { fileOverridesOf } from './fileOverrides.js';
+import { Mod, ModInstall, ModsJson, Platform } from './modlist.types.js';
+import { allowedReleaseTypesOf, fallbackOf } from './releaseChannel.js';
+
+export enum PlannedChangeType {
+  INSTALL = 'install',
+  UPGRADE = 'upgrade',
+  DOWNGRADE = 'downgrade',
+  NONE = 'none',
+  PINNED = 'pinned'
+}
+
+export interface PlannedChange {
+  id: string;
+  name: string;
+  platform: Platform;
+  type: PlannedChangeType;
+  currentFileName?: string;
+  currentReleaseDate?: string;
+  newFileName: string;
+  newReleaseDate: string;
+  /**
+   * The id of the new file when it's from Curseforge
+   */
+  curseforgeFileId?: number;
+}
+
+export interface PlanError {
+  mod: Mod;
+  error: unknown;
+}
+
+export interface UpdatePlan {
+  changes: PlannedChange[];
+  errors: PlanError[];
+}
+
+/**
+ * Describes how a configured mod should be resolved against its platform
+ *
+ * @param mod
+ * @param configuration
+ */
+export const projectForMod = (mod: Mod, configuration: ModsJson): ProjectToResolve => ({
+  platform: mod.type,
+  id: mod.id,
+  allowedReleaseTypes: allowedReleaseTypesOf(mod, configuration),
+  gameVersion: configuration.gameVersion,
+  loader: configuration.loader,
+  allowFallback: !!mod.allowVersionFallback,
+  version: mod.version,
+  fallback: fallbackOf(mod, configuration),
+  overrides: fileOverridesOf(mod)
+});
+
+const changeType = (installation: ModInstall | undefined, hash: string, releaseDate: string) => {
+  if (!installation) {
+    return PlannedChangeType.INSTALL;
+  }
+
+  const isNewer = releaseDate > installation.releasedOn;
+
+  if (hash === installation.hash && !isNewer) {
+    return PlannedChangeType.NONE;
+  }
+
+  if (isNewer || releaseDate === installation.releasedOn) {
+    return PlannedChangeType.UPGRADE;
+  }
+
+  return PlannedChangeType.DOWNGRADE;
+};
+
+const isPinnedInstallation = (mod: Mod, installations: ModInstall[]) => {
+  return !!mod.pinned && getInstallation(mod, installations) > -1;
+};
+
+const pinnedChange = (mod: Mod, installation: ModInstall): PlannedChange => ({
+  id: mod.id,
+  name: mod.name,
+  platform: mod.type,
+  type: PlannedChangeType.PINNED,
+  currentFileName: installation.fileName,
+  currentReleaseDate: installation.releasedOn,
+  newFileName: installation.fileName,
+  newReleaseDate: installation.releasedOn
+});
+
+/**
+ * Works out what an update would do without downloading or removing anything.
+ * The installed state comes from the lock file so the mods folder is never touched.
+ * The installed pinned mods aren't resolved, they stay on the file they have.
+ *
+ * @param configuration
+ * @param installations The contents of the lock file
+ * @param concurrency The maximum number of mods resolved at the same time
+ */
+export const planUpdate = async (
+  configuration: ModsJson,
+  installations: ModInstall[],
+  concurrency?: number
+): Promise<UpdatePlan> => {
+  const pinned = configuration.mods.filter((mod) => isPinnedInstallation(mod, installations));
+  const toResolve = configuration.mods.filter((mod) => !pinned.includes(mod));
+  const projects = toResolve.map((mod) => projectForMod(mod, configuration));
+
+  const result = await resolveProjects(projects, concurrency);
+  const modFor = (project: ProjectToResolve) => toResolve[projects.indexOf(project)];
+
+  const changes = result.resolved.map(({ project, details }): PlannedChange => {
+    const mod = modFor(project);
+    const installation = installations[getInstallation(mod, installations)];
+
+    return {
+      id: mod.id,
+      name: details.name,
+      platform: mod.type,
+      type: changeType(installation, details.hash, details.releaseDate),
+      currentFileName: installation?.fileName,
+      currentReleaseDate: installation?.releasedOn,
+      newFileName: details.fileName,
+      newReleaseDate: details.releaseDate,
+      curseforgeFileId: details.curseforgeFileId
+    };
+  });
+
+  const pinnedChanges = pinned.map((mod) => pinnedChange(mod, installations[getInstallation(mod, installations)]));
+
+  return {
+    changes: [...changes, ...pinnedChanges],
+    errors: result.errors.map(({ project, error }) => ({ mod: modFor(project), error: error }))
+  };
+};
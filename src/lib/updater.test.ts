@@ -2,13 +2,23 @@ import fs from 'node:fs/promises';
 import path from 'node:path';
 import { chance } from 'jest-chance';
 import { afterEach, describe, expect, it, vi } from 'vitest';
+import { generateRemoteModDetails } from '../../test/generateRemoteDetails.js';
 import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { fileExists } from './config.js';
 import { downloadFile } from './downloader.js';
-import { updateMod } from './updater.js';
+import { AdditionalFile } from './modlist.types.js';
+import { restoreAdditionalFiles, updateAdditionalFiles, updateMod } from './updater.js';
 
 vi.mock('node:fs/promises');
+vi.mock('./config.js');
 vi.mock('./downloader.js');
 
+const generateAdditionalFile = (): AdditionalFile => ({
+  fileName: `${chance.word()}.zip`,
+  hash: chance.hash(),
+  downloadUrl: chance.url()
+});
+
 const assumeDownloadSuccessful = () => {
   vi.mocked(downloadFile).mockResolvedValueOnce();
 };
@@ -29,7 +39,11 @@ describe('The updater module', () => {
 
     await updateMod(randomMod, originalPath, randomModsFolder);
 
-    expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(randomMod.downloadUrl, expectedNewPath);
+    expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+      randomMod.downloadUrl,
+      expectedNewPath,
+      { expectedHash: randomMod.hash, expectedLength: undefined }
+    );
     expect(vi.mocked(fs.rm)).toHaveBeenCalledWith(originalPath);
   });
 
@@ -43,7 +57,95 @@ describe('The updater module', () => {
 
     await updateMod(randomMod, originalPath, randomModsFolder);
 
-    expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(randomMod.downloadUrl, expectedNewPath);
+    expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+      randomMod.downloadUrl,
+      expectedNewPath,
+      { expectedHash: randomMod.hash, expectedLength: undefined }
+    );
     expect(vi.mocked(fs.rm)).not.toHaveBeenCalled();
   });
+
+  it('checks the size of a freshly fetched file', async () => {
+    const randomMod = generateRemoteModDetails({ fileLength: chance.integer({ min: 1, max: 100000 }) }).generated;
+    const randomModsFolder = chance.word();
+    const expectedNewPath = path.resolve(randomModsFolder, randomMod.fileName);
+
+    assumeDownloadSuccessful();
+
+    await updateMod(randomMod, expectedNewPath, randomModsFolder);
+
+    expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+      randomMod.downloadUrl,
+      expectedNewPath,
+      { expectedHash: randomMod.hash, expectedLength: randomMod.fileLength }
+    );
+  });
+
+  describe('when restoring the additional files', () => {
+    it('downloads the ones that are missing', async () => {
+      const present = generateAdditionalFile();
+      const missing = generateAdditionalFile();
+      const modsFolder = chance.word();
+      vi.mocked(fileExists).mockResolvedValueOnce(true).mockResolvedValueOnce(false);
+
+      await restoreAdditionalFiles([present, missing], modsFolder);
+
+      expect(vi.mocked(downloadFile)).toHaveBeenCalledOnce();
+      expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+        missing.downloadUrl,
+        path.resolve(modsFolder, missing.fileName),
+        { expectedHash: missing.hash, skipCache: false }
+      );
+    });
+
+    it('downloads every one of them again when forced', async () => {
+      const additionalFile = generateAdditionalFile();
+      const modsFolder = chance.word();
+
+      await restoreAdditionalFiles([additionalFile], modsFolder, true);
+
+      expect(vi.mocked(fileExists)).not.toHaveBeenCalled();
+      expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+        additionalFile.downloadUrl,
+        path.resolve(modsFolder, additionalFile.fileName),
+        { expectedHash: additionalFile.hash, skipCache: true }
+      );
+    });
+
+    it('does nothing for a mod without additional files', async () => {
+      await restoreAdditionalFiles(undefined, chance.word());
+
+      expect(vi.mocked(downloadFile)).not.toHaveBeenCalled();
+    });
+  });
+
+  describe('when updating the additional files', () => {
+    it('downloads the new ones and removes the ones that are gone', async () => {
+      const kept = generateAdditionalFile();
+      const removed = generateAdditionalFile();
+      const added = generateAdditionalFile();
+      const modsFolder = chance.word();
+
+      await updateAdditionalFiles([kept, removed], [{ ...kept, hash: chance.hash() }, added], modsFolder);
+
+      expect(vi.mocked(downloadFile)).toHaveBeenCalledTimes(2);
+      expect(vi.mocked(downloadFile)).toHaveBeenCalledWith(
+        added.downloadUrl,
+        path.resolve(modsFolder, added.fileName),
+        { expectedHash: added.hash }
+      );
+      expect(vi.mocked(fs.rm)).toHaveBeenCalledOnce();
+      expect(vi.mocked(fs.rm)).toHaveBeenCalledWith(path.resolve(modsFolder, removed.fileName), { force: true });
+    });
+
+    it('removes every additional file when the new version has none', async () => {
+      const removed = generateAdditionalFile();
+      const modsFolder = chance.word();
+
+      await updateAdditionalFiles([removed], undefined, modsFolder);
+
+      expect(vi.mocked(downloadFile)).not.toHaveBeenCalled();
+      expect(vi.mocked(fs.rm)).toHaveBeenCalledWith(path.resolve(modsFolder, removed.fileName), { force: true });
+    });
+  });
 });
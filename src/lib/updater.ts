@@ -1,7 +1,8 @@
 import fs from 'node:fs/promises';
 import path from 'path';
+import { fileExists } from './config.js';
 import { downloadFile } from './downloader.js';
-import { ModInstall, RemoteModDetails } from './modlist.types.js';
+import { AdditionalFile, ModInstall, RemoteModDetails } from './modlist.types.js';
 
 export const updateMod = async (
   mod: ModInstall | RemoteModDetails,
@@ -9,9 +10,55 @@ export const updateMod = async (
   modsFolder: string
 ): Promise<ModInstall | RemoteModDetails> => {
   const newPath = path.resolve(modsFolder, mod.fileName);
-  await downloadFile(mod.downloadUrl, newPath);
+  // Only the freshly fetched details know the size, the lock file doesn't keep it
+  const expectedLength = 'fileLength' in mod ? mod.fileLength : undefined;
+  await downloadFile(mod.downloadUrl, newPath, { expectedHash: mod.hash, expectedLength: expectedLength });
   if (modPath !== newPath) {
     await fs.rm(modPath);
   }
   return mod;
 };
+
+/**
+ * Downloads the additional files of a mod that are missing from the mods folder.
+ * Forcing it downloads every one of them again, without the file cache.
+ */
+export const restoreAdditionalFiles = async (
+  additionalFiles: AdditionalFile[] | undefined,
+  modsFolder: string,
+  force = false
+) => {
+  for (const additionalFile of additionalFiles || []) {
+    const additionalFilePath = path.resolve(modsFolder, additionalFile.fileName);
+    if (!force && (await fileExists(additionalFilePath))) {
+      continue;
+    }
+    await downloadFile(additionalFile.downloadUrl, additionalFilePath, {
+      expectedHash: additionalFile.hash,
+      skipCache: force
+    });
+  }
+};
+
+/**
+ * Swaps the additional files of the installed version for the ones of the new version.
+ * The files the new version doesn't have anymore are removed from the mods folder.
+ */
+export const updateAdditionalFiles = async (
+  installedFiles: AdditionalFile[] | undefined,
+  newFiles: AdditionalFile[] | undefined,
+  modsFolder: string
+) => {
+  for (const additionalFile of newFiles || []) {
+    await downloadFile(additionalFile.downloadUrl, path.resolve(modsFolder, additionalFile.fileName), {
+      expectedHash: additionalFile.hash
+    });
+  }
+
+  const keptFileNames = new Set((newFiles || []).map((additionalFile) => additionalFile.fileName));
+  for (const additionalFile of installedFiles || []) {
+    if (!keptFileNames.has(additionalFile.fileName)) {
+      await fs.rm(path.resolve(modsFolder, additionalFile.fileName), { force: true });
+    }
+  }
+};
@@ -0,0 +1,79 @@
+import * as crypto from 'crypto';
+import fs from 'node:fs/promises';
+import os from 'node:os';
+import path from 'node:path';
+import { chance } from 'jest-chance';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateModInstall } from '../../test/modInstallGenerator.js';
+import { ModInstall } from './modlist.types.js';
+import { verifyInstallation } from './verifyInstallation.js';
+
+interface LocalTestContext {
+  modsFolder: string;
+}
+
+const sha1 = (contents: Buffer) => crypto.createHash('sha1').update(contents).digest('hex');
+
+// The smallest thing that passes for a jar: a local file header, some data and the end of central directory record
+const jar = (): Buffer => {
+  const endOfCentralDirectory = Buffer.alloc(22);
+  endOfCentralDirectory.writeUInt32LE(0x06054b50, 0);
+  return Buffer.concat([Buffer.from([0x50, 0x4b, 0x03, 0x04]), Buffer.from(chance.paragraph()), endOfCentralDirectory]);
+};
+
+const installationOf = (contents: Buffer): ModInstall =>
+  generateModInstall({ fileName: `${chance.guid()}.jar`, hash: sha1(contents) }).generated;
+
+describe('The installation verification', () => {
+  beforeEach<LocalTestContext>(async (context) => {
+    vi.stubGlobal('fetch', vi.fn());
+    context.modsFolder = await fs.mkdtemp(path.join(os.tmpdir(), 'mmm-verify-'));
+  });
+
+  afterEach<LocalTestContext>(async (context) => {
+    vi.unstubAllGlobals();
+    await fs.rm(context.modsFolder, { recursive: true, force: true });
+  });
+
+  it<LocalTestContext>('sorts the locked files by what happened to them', async ({ modsFolder }) => {
+    const intactContents = jar();
+    const intact = installationOf(intactContents);
+    await fs.writeFile(path.resolve(modsFolder, intact.fileName), intactContents);
+
+    const modifiedContents = jar();
+    const modified = installationOf(modifiedContents);
+    modifiedContents[4] = modifiedContents[4] ^ 0xff;
+    await fs.writeFile(path.resolve(modsFolder, modified.fileName), modifiedContents);
+
+    const corruptedContents = jar();
+    const corrupted = installationOf(corruptedContents);
+    await fs.writeFile(path.resolve(modsFolder, corrupted.fileName), corruptedContents.subarray(0, 10));
+
+    const missing = installationOf(jar());
+
+    const actual = await verifyInstallation([intact, modified, corrupted, missing], modsFolder);
+
+    expect(actual).toEqual({
+      intact: [intact],
+      modified: [modified],
+      corrupted: [corrupted],
+      missing: [missing]
+    });
+    expect(vi.mocked(fetch)).not.toHaveBeenCalled();
+  });
+
+  it<LocalTestContext>('treats an empty file as corrupted', async ({ modsFolder }) => {
+    const installation = installationOf(jar());
+    await fs.writeFile(path.resolve(modsFolder, installation.fileName), '');
+
+    const actual = await verifyInstallation([installation], modsFolder);
+
+    expect(actual.corrupted).toEqual([installation]);
+  });
+
+  it<LocalTestContext>('has nothing to check without a lock file', async ({ modsFolder }) => {
+    const actual = await verifyInstallation([], modsFolder);
+
+    expect(actual).toEqual({ intact: [], modified: [], corrupted: [], missing: [] });
+  });
+});
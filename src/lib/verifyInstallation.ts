@@ -0,0 +1,67 @@
+import fs from 'node:fs/promises';
+import path from 'node:path';
+import { fileExists } from './config.js';
+import { getHash } from './hash.js';
+import { ModInstall } from './modlist.types.js';
+
+export interface InstallationVerification {
+  intact: ModInstall[];
+  /**
+   * Readable jars whose contents are no longer the locked file, like the ones changed by hand
+   */
+  modified: ModInstall[];
+  /**
+   * Files that aren't a complete jar anymore, like the leftovers of a download that crashed
+   */
+  corrupted: ModInstall[];
+  missing: ModInstall[];
+}
+
+const END_OF_CENTRAL_DIRECTORY = Buffer.from([0x50, 0x4b, 0x05, 0x06]);
+// The end of central directory record is 22 bytes followed by a comment of at most 65535 bytes
+const MAX_END_OF_CENTRAL_DIRECTORY_OFFSET = 22 + 65535;
+
+/**
+ * A jar is a zip which always ends with the end of central directory record, a truncated file loses it
+ */
+const isCompleteJar = (contents: Buffer): boolean => {
+  const tail = contents.subarray(Math.max(0, contents.length - MAX_END_OF_CENTRAL_DIRECTORY_OFFSET));
+  return tail.includes(END_OF_CENTRAL_DIRECTORY);
+};
+
+/**
+ * Checks the installed files against the hashes in the lock file without touching the network.
+ * Useful after a sync that went wrong or a download that crashed halfway.
+ *
+ * @param installations The contents of the lock file
+ * @param modsFolder
+ */
+export const verifyInstallation = async (
+  installations: ModInstall[],
+  modsFolder: string
+): Promise<InstallationVerification> => {
+  const verification: InstallationVerification = { intact: [], modified: [], corrupted: [], missing: [] };
+
+  for (const installation of installations) {
+    const modPath = path.resolve(modsFolder, installation.fileName);
+
+    if (!(await fileExists(modPath))) {
+      verification.missing.push(installation);
+      continue;
+    }
+
+    if ((await getHash(modPath)) === installation.hash) {
+      verification.intact.push(installation);
+      continue;
+    }
+
+    if (isCompleteJar(await fs.readFile(modPath))) {
+      verification.modified.push(installation);
+      continue;
+    }
+
+    verification.corrupted.push(installation);
+  }
+
+  return verification;
+};
@@ -8,6 +8,7 @@ import { DefaultOptions } from '../mmm.js';
 import { fetchModDetails } from '../repositories/index.js';
 import { Logger } from './Logger.js';
 import { readConfigFile } from './config.js';
+import { suggestGameVersions } from './gameVersionSuggestions.js';
 import { getLatestMinecraftVersion, verifyMinecraftVersion } from './minecraftVersionVerifier.js';
 import { ModsJson } from './modlist.types.js';
 import { verifyUpgradeIsPossible } from './verifyUpgrade.js';
@@ -23,6 +24,7 @@ vi.mock('../repositories/index.js');
 vi.mock('../lib/Logger.js');
 vi.mock('../lib/config.js');
 vi.mock('../lib/minecraftVersionVerifier.js');
+vi.mock('./gameVersionSuggestions.js');
 
 describe('The Upgrade Test Module', () => {
   beforeEach<LocalTestContext>((context) => {
@@ -142,6 +144,16 @@ describe('The Upgrade Test Module', () => {
         IncorrectMinecraftVersionException
       );
     });
+
+    it<LocalTestContext>('should suggest the versions that were meant', async ({ options, logger }) => {
+      vi.mocked(verifyMinecraftVersion).mockResolvedValueOnce(false);
+      vi.mocked(suggestGameVersions).mockResolvedValueOnce(['1.19.2']);
+
+      await expect(verifyUpgradeIsPossible('1.19.w', options, logger)).rejects.toThrow(
+        new IncorrectMinecraftVersionException('1.19.w', ['1.19.2'])
+      );
+      expect(suggestGameVersions).toHaveBeenCalledWith('1.19.w');
+    });
   });
 
   describe('when the same game version is used as the one in the current config', () => {
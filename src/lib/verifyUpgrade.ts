@@ -5,8 +5,11 @@ import { DefaultOptions } from '../mmm.js';
 import { fetchModDetails } from '../repositories/index.js';
 import { Logger } from './Logger.js';
 import { readConfigFile } from './config.js';
+import { suggestGameVersions } from './gameVersionSuggestions.js';
+import { acceptedLoaders } from './loaderCompatibility.js';
 import { verifyMinecraftVersion } from './minecraftVersionVerifier.js';
 import { Mod } from './modlist.types.js';
+import { allowedReleaseTypesOf } from './releaseChannel.js';
 
 export type VerifyUpgradeOptions = DefaultOptions & {
   force?: boolean;
@@ -31,7 +34,7 @@ export const verifyUpgradeIsPossible = async (
 
   const isValidVersion = await verifyMinecraftVersion(version);
   if (!isValidVersion) {
-    throw new IncorrectMinecraftVersionException(version);
+    throw new IncorrectMinecraftVersionException(version, await suggestGameVersions(version));
   }
 
   const configuration = await readConfigFile(options.config);
@@ -57,9 +60,9 @@ export const verifyUpgradeIsPossible = async (
       await fetchModDetails(
         mod.type,
         mod.id,
-        mod.allowedReleaseTypes || configuration.defaultAllowedReleaseTypes,
+        allowedReleaseTypesOf(mod, configuration),
         version,
-        configuration.loader,
+        acceptedLoaders(mod, configuration),
         !!mod.allowVersionFallback
       );
     } catch {
@@ -0,0 +1,80 @@
+import { chance } from 'jest-chance';
+import { describe, expect, it } from 'vitest';
+import { DEFAULT_CONCURRENCY, mapWithConcurrency } from './workerPool.js';
+
+const tick = () => new Promise((resolve) => setTimeout(resolve, 1));
+
+const trackConcurrency = () => {
+  const tracker = { active: 0, max: 0 };
+  const worker = async (item: number) => {
+    tracker.active++;
+    tracker.max = Math.max(tracker.max, tracker.active);
+    await tick();
+    tracker.active--;
+    return item * 2;
+  };
+  return { tracker, worker };
+};
+
+describe('The worker pool', () => {
+  it('returns the results in the order of the items', async () => {
+    const items = chance.n(chance.integer, 20);
+
+    const actual = await mapWithConcurrency(items, async (item) => {
+      await new Promise((resolve) => setTimeout(resolve, chance.integer({ min: 0, max: 5 })));
+      return item;
+    });
+
+    expect(actual).toEqual(items.map((item) => ({ status: 'fulfilled', value: item })));
+  });
+
+  it('does not run more workers than allowed', async () => {
+    const { tracker, worker } = trackConcurrency();
+
+    await mapWithConcurrency(Array.from({ length: 30 }, (_v, i) => i), worker, 3);
+
+    expect(tracker.max).toEqual(3);
+  });
+
+  it('uses the default concurrency', async () => {
+    const { tracker, worker } = trackConcurrency();
+
+    await mapWithConcurrency(Array.from({ length: 30 }, (_v, i) => i), worker);
+
+    expect(tracker.max).toEqual(DEFAULT_CONCURRENCY);
+  });
+
+  it('runs at least one worker', async () => {
+    const { tracker, worker } = trackConcurrency();
+
+    const actual = await mapWithConcurrency([1, 2, 3], worker, 0);
+
+    expect(tracker.max).toEqual(1);
+    expect(actual).toHaveLength(3);
+  });
+
+  it('keeps going when an item fails', async () => {
+    const error = new Error(chance.sentence());
+
+    const actual = await mapWithConcurrency(
+      [1, 2, 3],
+      async (item) => {
+        if (item === 2) {
+          throw error;
+        }
+        return item;
+      },
+      2
+    );
+
+    expect(actual).toEqual([
+      { status: 'fulfilled', value: 1 },
+      { status: 'rejected', reason: error },
+      { status: 'fulfilled', value: 3 }
+    ]);
+  });
+
+  it('handles no items', async () => {
+    expect(await mapWithConcurrency([], async () => 1)).toEqual([]);
+  });
+});
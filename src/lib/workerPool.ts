@@ -0,0 +1,30 @@
+export const DEFAULT_CONCURRENCY = 8;
+
+/**
+ * Runs the worker on every item with at most `concurrency` workers running at the same time.
+ * A failing item doesn't stop the others, the results are settled and kept in the order of the items.
+ */
+export const mapWithConcurrency = async <T, R>(
+  items: T[],
+  worker: (item: T, index: number) => Promise<R>,
+  concurrency: number = DEFAULT_CONCURRENCY
+): Promise<PromiseSettledResult<R>[]> => {
+  const results: PromiseSettledResult<R>[] = new Array(items.length);
+  let next = 0;
+
+  const runWorker = async () => {
+    while (next < items.length) {
+      const index = next++;
+      try {
+        results[index] = { status: 'fulfilled', value: await worker(items[index], index) };
+      } catch (reason) {
+        results[index] = { status: 'rejected', reason: reason };
+      }
+    }
+  };
+
+  const workerCount = Math.min(Math.max(1, Math.floor(concurrency)), items.length);
+  await Promise.all(Array.from({ length: workerCount }, runWorker));
+
+  return results;
+};
@@ -0,0 +1,87 @@
+import { execFileSync } from 'node:child_process';
+import fs from 'node:fs/promises';
+import os from 'node:os';
+import path from 'node:path';
+import { describe, expect, it } from 'vitest';
+import { readZip } from '../../test/readZip.js';
+import { crc32, createZip } from './zip.js';
+
+describe('The zip library', () => {
+  it.each([
+    ['', 0],
+    ['a', 0xe8b7be43],
+    ['The quick brown fox jumps over the lazy dog', 0x414fa339]
+  ])('calculates the crc32 of %j', (text, expected) => {
+    expect(crc32(Buffer.from(text))).toEqual(expected);
+  });
+
+  it('packs the entries in order', () => {
+    const archive = createZip([
+      { name: 'modrinth.index.json', data: Buffer.from('{"formatVersion":1}') },
+      { name: 'overrides/mods/sodium.jar', data: Buffer.alloc(4096, 7) },
+      { name: 'overrides/config/empty.txt', data: Buffer.alloc(0) }
+    ]);
+
+    const entries = readZip(archive);
+
+    expect(entries.map((entry) => entry.name)).toEqual([
+      'modrinth.index.json',
+      'overrides/mods/sodium.jar',
+      'overrides/config/empty.txt'
+    ]);
+    expect(entries[0].data.toString()).toEqual('{"formatVersion":1}');
+    expect(entries[1].data).toEqual(Buffer.alloc(4096, 7));
+    expect(entries[2].data).toEqual(Buffer.alloc(0));
+    entries.forEach((entry) => {
+      expect(entry.method).toEqual(8);
+      expect(entry.crc).toEqual(crc32(entry.data));
+    });
+  });
+
+  it('compresses the data', () => {
+    const archive = createZip([{ name: 'zeros', data: Buffer.alloc(100000) }]);
+
+    expect(archive.length).toBeLessThan(1000);
+  });
+
+  it('keeps the names in UTF-8', () => {
+    const archive = createZip([{ name: 'overrides/Faithful 32x – 1.20.1.zip', data: Buffer.from('pack') }]);
+
+    expect(readZip(archive)[0].name).toEqual('overrides/Faithful 32x – 1.20.1.zip');
+    expect(archive.readUInt16LE(6) & 0x0800).toEqual(0x0800);
+  });
+
+  it('makes the same archive from the same files', () => {
+    const entries = [{ name: 'a.txt', data: Buffer.from('a') }];
+
+    expect(createZip(entries)).toEqual(createZip(entries));
+  });
+
+  it('makes an archive unzip accepts', async () => {
+    const folder = await fs.mkdtemp(path.join(os.tmpdir(), 'mmm-zip-'));
+    const file = path.join(folder, 'pack.mrpack');
+    try {
+      await fs.writeFile(
+        file,
+        createZip([
+          { name: 'modrinth.index.json', data: Buffer.from('{"formatVersion":1}') },
+          { name: 'overrides/mods/sodium.jar', data: Buffer.alloc(4096, 7) },
+          { name: 'overrides/config/empty.txt', data: Buffer.alloc(0) }
+        ])
+      );
+
+      const output = execFileSync('unzip', ['-t', file], { encoding: 'utf8' });
+
+      expect(output).toContain('No errors detected');
+      expect(execFileSync('unzip', ['-p', file, 'modrinth.index.json'], { encoding: 'utf8' })).toEqual(
+        '{"formatVersion":1}'
+      );
+    } finally {
+      await fs.rm(folder, { recursive: true, force: true });
+    }
+  });
+
+  it('makes an empty archive', () => {
+    expect(readZip(createZip([]))).toEqual([]);
+  });
+});
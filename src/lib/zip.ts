@@ -0,0 +1,100 @@
+import zlib from 'node:zlib';
+
+export interface ZipEntry {
+  /**
+   * The path in the archive with forward slashes, like overrides/mods/sodium.jar
+   */
+  name: string;
+  data: Buffer;
+}
+
+const LOCAL_FILE_HEADER = 0x04034b50;
+const CENTRAL_DIRECTORY_HEADER = 0x02014b50;
+const END_OF_CENTRAL_DIRECTORY = 0x06054b50;
+const VERSION = 20;
+const UTF8_NAMES = 0x0800;
+const DEFLATE = 8;
+/**
+ * 1980-01-01, the first day a zip can tell. Every entry gets it, so the same files always make the same archive.
+ */
+const DOS_DATE = (1 << 5) | 1;
+const DOS_TIME = 0;
+
+const crcTable = Array.from({ length: 256 }, (_, index) => {
+  let crc = index;
+  for (let bit = 0; bit < 8; bit++) {
+    crc = crc & 1 ? 0xedb88320 ^ (crc >>> 1) : crc >>> 1;
+  }
+  return crc >>> 0;
+});
+
+export const crc32 = (data: Buffer): number => {
+  let crc = 0xffffffff;
+  for (const byte of data) {
+    crc = crcTable[(crc ^ byte) & 0xff] ^ (crc >>> 8);
+  }
+  return (crc ^ 0xffffffff) >>> 0;
+};
+
+/**
+ * The fields the local and the central headers share, from the version needed to the length of the name
+ */
+const sharedHeader = (name: Buffer, crc: number, compressedSize: number, size: number) => {
+  const header = Buffer.alloc(26);
+  header.writeUInt16LE(VERSION, 0);
+  header.writeUInt16LE(UTF8_NAMES, 2);
+  header.writeUInt16LE(DEFLATE, 4);
+  header.writeUInt16LE(DOS_TIME, 6);
+  header.writeUInt16LE(DOS_DATE, 8);
+  header.writeUInt32LE(crc, 10);
+  header.writeUInt32LE(compressedSize, 14);
+  header.writeUInt32LE(size, 18);
+  header.writeUInt16LE(name.length, 22);
+  header.writeUInt16LE(0, 24);
+  return header;
+};
+
+const signature = (value: number) => {
+  const buffer = Buffer.alloc(4);
+  buffer.writeUInt32LE(value, 0);
+  return buffer;
+};
+
+/**
+ * Packs the entries into a deflated zip archive, in the given order.
+ * It doesn't write zip64, so the archive and every entry in it has to stay below 4GB.
+ */
+export const createZip = (entries: ZipEntry[]): Buffer => {
+  const localParts: Buffer[] = [];
+  const centralParts: Buffer[] = [];
+  let offset = 0;
+
+  for (const entry of entries) {
+    const name = Buffer.from(entry.name, 'utf8');
+    const compressed = zlib.deflateRawSync(entry.data);
+    const shared = sharedHeader(name, crc32(entry.data), compressed.length, entry.data.length);
+
+    const local = Buffer.concat([signature(LOCAL_FILE_HEADER), shared, name, compressed]);
+    localParts.push(local);
+
+    // The central header adds the version made by in front, the comment, disk, attributes and offset after
+    const central = Buffer.alloc(46);
+    central.writeUInt32LE(CENTRAL_DIRECTORY_HEADER, 0);
+    central.writeUInt16LE(VERSION, 4);
+    shared.copy(central, 6);
+    central.writeUInt32LE(offset, 42);
+    centralParts.push(central, name);
+
+    offset += local.length;
+  }
+
+  const centralDirectory = Buffer.concat(centralParts);
+  const end = Buffer.alloc(22);
+  end.writeUInt32LE(END_OF_CENTRAL_DIRECTORY, 0);
+  end.writeUInt16LE(entries.length, 8);
+  end.writeUInt16LE(entries.length, 10);
+  end.writeUInt32LE(centralDirectory.length, 12);
+  end.writeUInt32LE(offset, 16);
+
+  return Buffer.concat([...localParts, centralDirectory, end]);
+};
@@ -1,7 +1,12 @@
+import path from 'node:path';
 import { chance } from 'jest-chance';
 import { beforeEach, describe, expect, it, vi } from 'vitest';
 import { add } from './actions/add.js';
 import { changeGameVersion } from './actions/change.js';
+import { clearCache } from './actions/clearCache.js';
+import { diffLocks } from './actions/diff.js';
+import { exportMrpack } from './actions/exportMrpack.js';
+import { importPackwiz } from './actions/importPackwiz.js';
 import { install } from './actions/install.js';
 import { list } from './actions/list.js';
 import { prune } from './actions/prune.js';
@@ -9,9 +14,28 @@ import { removeAction } from './actions/remove.js';
 import { scan } from './actions/scan.js';
 import { testGameVersion } from './actions/testGameVersion.js';
 import { update } from './actions/update.js';
+import { verify } from './actions/verify.js';
+import { InvalidBaseUrlException } from './errors/InvalidBaseUrlException.js';
+import { MultiError } from './errors/MultiError.js';
+import { RunInProgressException } from './errors/RunInProgressException.js';
 import { initializeConfig } from './interactions/initializeConfig.js';
 import { Logger } from './lib/Logger.js';
+import { lineApiLogger, setApiLogger } from './lib/apiLogger.js';
+import { verifyEnvironmentBaseUrls } from './lib/baseUrl.js';
+import { setDownloadBandwidth } from './lib/downloadThrottle.js';
+import { setDownloadIdleTimeout } from './lib/downloader.js';
+import { getFileCacheDirectory, setFileCacheSize } from './lib/fileCache.js';
+import { setSnapshotGameVersions, setStrictGameVersionMatching } from './lib/gameVersionMatcher.js';
+import { setStrictLoaderMatching } from './lib/loaderCompatibility.js';
+import { formatMetrics } from './lib/metrics.js';
 import { Platform } from './lib/modlist.types.js';
+import { setOfflineMode } from './lib/offline.js';
+import { setProgress } from './lib/progress.js';
+import { setCacheTtl, setHttpCacheDirectory } from './lib/rateLimiter/httpCache.js';
+import { setRetryBudget } from './lib/rateLimiter/retryBudget.js';
+import { setProxy } from './lib/rateLimiter/transport.js';
+import { acquireRunLock, releaseRunLock } from './lib/runLock.js';
+import { setPreferServerPacks } from './repositories/curseforge/fetch.js';
 import { Telemetry } from './telemetry/telemetry.js';
 
 vi.mock('./telemetry/telemetry.js', () => {
@@ -24,6 +48,21 @@ vi.mock('./telemetry/telemetry.js', () => {
   };
 });
 vi.mock('./lib/Logger.js');
+vi.mock('./lib/apiLogger.js');
+vi.mock('./lib/baseUrl.js');
+vi.mock('./lib/downloadThrottle.js');
+vi.mock('./lib/downloader.js');
+vi.mock('./lib/loaderCompatibility.js');
+vi.mock('./lib/gameVersionMatcher.js');
+vi.mock('./lib/offline.js');
+vi.mock('./lib/progress.js');
+vi.mock('./lib/metrics.js');
+vi.mock('./lib/rateLimiter/transport.js');
+vi.mock('./lib/rateLimiter/httpCache.js');
+vi.mock('./lib/rateLimiter/retryBudget.js');
+vi.mock('./lib/fileCache.js');
+vi.mock('./lib/runLock.js');
+vi.mock('./repositories/curseforge/fetch.js');
 vi.mock('./actions/add.js');
 vi.mock('./actions/list.js');
 vi.mock('./actions/scan.js');
@@ -34,6 +73,11 @@ vi.mock('./interactions/initializeConfig.js');
 vi.mock('./actions/testGameVersion.js');
 vi.mock('./actions/change.js');
 vi.mock('./actions/remove.js');
+vi.mock('./actions/importPackwiz.js');
+vi.mock('./actions/exportMrpack.js');
+vi.mock('./actions/clearCache.js');
+vi.mock('./actions/diff.js');
+vi.mock('./actions/verify.js');
 
 describe('The main CLI configuration', () => {
   let logger: Logger;
@@ -57,7 +101,13 @@ describe('The main CLI configuration', () => {
   it('has add hooked up to the correct function', async () => {
     const { program } = await import('./mmm.js');
     vi.mocked(add).mockResolvedValueOnce();
-    await program.parse(['', '', chance.pickone(['add', 'a']), chance.pickone(Object.values(Platform)), chance.word()]);
+    await program.parseAsync([
+      '',
+      '',
+      chance.pickone(['add', 'a']),
+      chance.pickone(Object.values(Platform)),
+      chance.word()
+    ]);
     expect(vi.mocked(add)).toHaveBeenCalledOnce();
   });
 
@@ -65,7 +115,7 @@ describe('The main CLI configuration', () => {
     const { program } = await import('./mmm.js');
 
     vi.mocked(list).mockResolvedValueOnce();
-    await program.parse(['', '', chance.pickone(['list', 'ls'])]);
+    await program.parseAsync(['', '', chance.pickone(['list', 'ls'])]);
     expect(vi.mocked(list)).toHaveBeenCalledOnce();
   });
 
@@ -73,7 +123,7 @@ describe('The main CLI configuration', () => {
     const { program } = await import('./mmm.js');
 
     vi.mocked(install).mockResolvedValueOnce();
-    await program.parse(['', '', chance.pickone(['install', 'i'])]);
+    await program.parseAsync(['', '', chance.pickone(['install', 'i'])]);
     expect(vi.mocked(install)).toHaveBeenCalledOnce();
   });
 
@@ -81,50 +131,99 @@ describe('The main CLI configuration', () => {
     const { program } = await import('./mmm.js');
 
     vi.mocked(update).mockResolvedValueOnce();
-    await program.parse(['', '', chance.pickone(['update', 'u'])]);
+    await program.parseAsync(['', '', chance.pickone(['update', 'u'])]);
     expect(vi.mocked(update)).toHaveBeenCalledOnce();
   });
 
+  it('exits with an error when some of the mods could not be updated', async () => {
+    const { program } = await import('./mmm.js');
+
+    vi.mocked(update).mockResolvedValueOnce(new MultiError([]));
+    await program.parseAsync(['', '', 'update']);
+    expect(process.exitCode).toEqual(1);
+    process.exitCode = undefined;
+  });
+
   it('has initialize hooked up to the correct function', async () => {
     const { program } = await import('./mmm.js');
 
     vi.mocked(initializeConfig).mockResolvedValueOnce(expect.anything());
-    await program.parse(['', '', chance.pickone(['init'])]);
+    await program.parseAsync(['', '', chance.pickone(['init'])]);
     expect(vi.mocked(initializeConfig)).toHaveBeenCalledOnce();
   });
 
   it('has the test hooked up to the correct function', async () => {
     const { program } = await import('./mmm.js');
     vi.mocked(testGameVersion).mockResolvedValueOnce(expect.anything());
-    await program.parse(['', '', chance.pickone(['test', 't'])]);
+    await program.parseAsync(['', '', chance.pickone(['test', 't'])]);
     expect(testGameVersion).toHaveBeenCalledOnce();
   });
 
   it('has the change hooked up to the correct function', async () => {
     const { program } = await import('./mmm.js');
     vi.mocked(changeGameVersion).mockResolvedValueOnce(expect.anything());
-    await program.parse(['', '', chance.pickone(['change'])]);
+    await program.parseAsync(['', '', chance.pickone(['change'])]);
     expect(changeGameVersion).toHaveBeenCalledOnce();
   });
 
   it('has the scan hooked up to the correct function', async () => {
     const { program } = await import('./mmm.js');
     vi.mocked(scan).mockResolvedValueOnce(expect.anything());
-    await program.parse(['', '', chance.pickone(['scan'])]);
+    await program.parseAsync(['', '', chance.pickone(['scan'])]);
     expect(scan).toHaveBeenCalledOnce();
   });
 
+  it('has the import hooked up to the correct function', async () => {
+    const { program } = await import('./mmm.js');
+    const pack = chance.word();
+    vi.mocked(importPackwiz).mockResolvedValueOnce();
+    await program.parseAsync(['', '', 'import', pack]);
+    expect(importPackwiz).toHaveBeenCalledWith(pack, expect.anything(), expect.anything());
+  });
+
+  it('has the export hooked up to the correct function', async () => {
+    const { program } = await import('./mmm.js');
+    vi.mocked(exportMrpack).mockResolvedValueOnce();
+    await program.parseAsync(['', '', 'export', 'pack.mrpack', '--loader-version', '0.15.11']);
+    expect(exportMrpack).toHaveBeenCalledWith(
+      'pack.mrpack',
+      expect.objectContaining({ loaderVersion: '0.15.11', packVersion: '1.0.0' }),
+      expect.anything()
+    );
+  });
+
   it('has the prune hooked up to the correct function', async () => {
     const { program } = await import('./mmm.js');
     vi.mocked(prune).mockResolvedValueOnce(expect.anything());
-    await program.parse(['', '', chance.pickone(['prune'])]);
+    await program.parseAsync(['', '', chance.pickone(['prune'])]);
     expect(prune).toHaveBeenCalledOnce();
   });
 
+  it('has the clear cache hooked up to the correct function', async () => {
+    const { program } = await import('./mmm.js');
+    vi.mocked(clearCache).mockResolvedValueOnce();
+    await program.parseAsync(['', '', 'clear-cache']);
+    expect(clearCache).toHaveBeenCalledOnce();
+  });
+
+  it('has the verify hooked up to the correct function', async () => {
+    const { program } = await import('./mmm.js');
+    vi.mocked(verify).mockResolvedValueOnce();
+    await program.parseAsync(['', '', 'verify']);
+    expect(verify).toHaveBeenCalledOnce();
+  });
+
+  it('has the diff hooked up to the correct function', async () => {
+    const { program } = await import('./mmm.js');
+    vi.mocked(diffLocks).mockResolvedValueOnce();
+    await program.parseAsync(['', '', 'diff', 'main-lock.json']);
+    expect(diffLocks).toHaveBeenCalledWith('main-lock.json', 'modlist-lock.json', expect.anything(), expect.anything());
+  });
+
   it('has the remove action hooked up to the correct function', async () => {
     const { program } = await import('./mmm.js');
     vi.mocked(removeAction).mockResolvedValueOnce(expect.anything());
-    await program.parse(['', '', chance.pickone(['remove']), []]);
+    await program.parseAsync(['', '', chance.pickone(['remove']), []]);
     expect(removeAction).toHaveBeenCalledOnce();
   });
 
@@ -140,6 +239,216 @@ describe('The main CLI configuration', () => {
     expect(logger.flagDebug).toHaveBeenCalledOnce();
   });
 
+  it('sends the api logs to the debug messages when the debug option is supplied', async () => {
+    const line = chance.sentence();
+    const { program } = await import('./mmm.js');
+    await program.parse(['', '', chance.pickone(['-d', '--debug']), chance.pickone(['init'])]);
+
+    expect(setApiLogger).toHaveBeenCalledOnce();
+    vi.mocked(lineApiLogger).mock.calls[0][0](line);
+    expect(logger.debug).toHaveBeenCalledWith(line);
+  });
+
+  it('only accepts the files of the configured loader when the strict loader option is supplied', async () => {
+    const { program } = await import('./mmm.js');
+    await program.parse(['', '', '--strict-loader', chance.pickone(['init'])]);
+    expect(setStrictLoaderMatching).toHaveBeenCalledWith(true);
+  });
+
+  it('only accepts the files of the exact game version when the strict game version option is supplied', async () => {
+    const { program } = await import('./mmm.js');
+    await program.parse(['', '', '--strict-game-version', chance.pickone(['init'])]);
+    expect(setStrictGameVersionMatching).toHaveBeenCalledWith(true);
+  });
+
+  it('considers the snapshots when the snapshots option is supplied', async () => {
+    const { program } = await import('./mmm.js');
+    await program.parse(['', '', '--snapshots', chance.pickone(['init'])]);
+    expect(setSnapshotGameVersions).toHaveBeenCalledWith(true);
+  });
+
+  it('shows the progress when the progress option is supplied', async () => {
+    const { program } = await import('./mmm.js');
+    await program.parse(['', '', '--progress', chance.pickone(['init'])]);
+    expect(setProgress).toHaveBeenCalledOnce();
+  });
+
+  it('goes offline when the offline option is supplied', async () => {
+    const { program } = await import('./mmm.js');
+    await program.parse(['', '', '--offline', chance.pickone(['init'])]);
+    expect(setOfflineMode).toHaveBeenCalledWith(true);
+  });
+
+  it('sends the requests through the proxy of the proxy option', async () => {
+    const proxyUrl = chance.url({ protocol: 'http' });
+    const { program } = await import('./mmm.js');
+    await program.parse(['', '', '--proxy', proxyUrl, chance.pickone(['init'])]);
+    expect(setProxy).toHaveBeenCalledWith(proxyUrl);
+  });
+
+  it('reports an API url in the environment that is not a url before running the command', async () => {
+    const error = new InvalidBaseUrlException(Platform.MODRINTH, 'not a url');
+    vi.mocked(verifyEnvironmentBaseUrls).mockImplementation(() => {
+      throw error;
+    });
+    const { program, logger } = await import('./mmm.js');
+    vi.mocked(logger.error).mockImplementation(() => {
+      throw new Error('process.exit');
+    });
+
+    await expect(program.parseAsync(['', '', 'list'])).rejects.toThrow('process.exit');
+
+    expect(logger.error).toHaveBeenCalledWith(error.message, 1);
+    expect(list).not.toHaveBeenCalled();
+  });
+
+  it('caches the API responses when the http cache option is supplied', async () => {
+    vi.mocked(getFileCacheDirectory).mockReturnValue('/cache/mmm');
+    const { program } = await import('./mmm.js');
+    await program.parse(['', '', '--http-cache', chance.pickone(['init'])]);
+    expect(setHttpCacheDirectory).toHaveBeenCalledWith(path.join('/cache/mmm', 'http'));
+  });
+
+  it('sets the TTL of the http cache', async () => {
+    vi.mocked(list).mockResolvedValueOnce();
+    const { program } = await import('./mmm.js');
+
+    await program.parseAsync(['', '', '--http-cache-ttl', '600', 'list']);
+
+    expect(setCacheTtl).toHaveBeenCalledWith(600);
+  });
+
+  it('refuses a TTL that is not a positive number', async () => {
+    vi.spyOn(process.stderr, 'write').mockImplementation(() => true);
+    const { program } = await import('./mmm.js');
+    program.exitOverride();
+
+    await expect(program.parseAsync(['', '', '--http-cache-ttl', 'soon', 'list'])).rejects.toThrow(
+      'It has to be a positive number.'
+    );
+    expect(setCacheTtl).not.toHaveBeenCalled();
+  });
+
+  it('sets the retry budget of the run', async () => {
+    vi.mocked(list).mockResolvedValueOnce();
+    const { program } = await import('./mmm.js');
+
+    await program.parseAsync(['', '', '--retry-budget', '0', 'list']);
+
+    expect(setRetryBudget).toHaveBeenCalledWith(0);
+  });
+
+  it('refuses a retry budget that is not a whole number', async () => {
+    vi.spyOn(process.stderr, 'write').mockImplementation(() => true);
+    const { program } = await import('./mmm.js');
+    program.exitOverride();
+
+    await expect(
+      program.parseAsync(['', '', '--retry-budget', chance.pickone(['-1', '2.5', 'many']), 'list'])
+    ).rejects.toThrow('It has to be a whole number.');
+    expect(setRetryBudget).not.toHaveBeenCalled();
+  });
+
+  it('caps the bandwidth of the downloads', async () => {
+    vi.mocked(list).mockResolvedValueOnce();
+    const { program } = await import('./mmm.js');
+
+    await program.parseAsync(['', '', '--bandwidth', '1048576', 'list']);
+
+    expect(setDownloadBandwidth).toHaveBeenCalledWith(1048576);
+  });
+
+  it('sets the size of the file cache in megabytes', async () => {
+    vi.mocked(list).mockResolvedValueOnce();
+    const { program } = await import('./mmm.js');
+
+    await program.parseAsync(['', '', '--cache-size', '512', 'list']);
+
+    expect(setFileCacheSize).toHaveBeenCalledWith(512 * 1024 * 1024);
+  });
+
+  it('sets the idle timeout of the downloads in seconds', async () => {
+    vi.mocked(list).mockResolvedValueOnce();
+    const { program } = await import('./mmm.js');
+
+    await program.parseAsync(['', '', '--idle-timeout', '90', 'list']);
+
+    expect(setDownloadIdleTimeout).toHaveBeenCalledWith(90000);
+  });
+
+  it('prefers the server packs when the server packs option is supplied', async () => {
+    vi.mocked(list).mockResolvedValueOnce();
+    const { program } = await import('./mmm.js');
+
+    await program.parseAsync(['', '', '--server-packs', 'list']);
+
+    expect(setPreferServerPacks).toHaveBeenCalledWith(true);
+  });
+
+  it('shows the metrics after the command when the metrics option is supplied', async () => {
+    const metrics = chance.sentence();
+    vi.mocked(formatMetrics).mockReturnValue(metrics);
+    vi.mocked(list).mockResolvedValueOnce();
+    const { program, logger } = await import('./mmm.js');
+
+    await program.parseAsync(['', '', '--metrics', 'list']);
+
+    expect(logger.log).toHaveBeenCalledWith(metrics);
+  });
+
+  it('holds the run lock while a command changes the modlist', async () => {
+    vi.mocked(update).mockResolvedValueOnce();
+    const { program } = await import('./mmm.js');
+
+    await program.parseAsync(['', '', 'update']);
+
+    expect(acquireRunLock).toHaveBeenCalledWith('./modlist.json');
+    expect(update).toHaveBeenCalledOnce();
+    expect(releaseRunLock).toHaveBeenCalledOnce();
+  });
+
+  it('holds the run lock while pruning the mods folder', async () => {
+    vi.mocked(prune).mockResolvedValueOnce();
+    const { program } = await import('./mmm.js');
+
+    await program.parseAsync(['', '', 'prune']);
+
+    expect(acquireRunLock).toHaveBeenCalledWith('./modlist.json');
+    expect(releaseRunLock).toHaveBeenCalledOnce();
+  });
+
+  it('does not hold the run lock for the commands that only read', async () => {
+    vi.mocked(list).mockResolvedValueOnce();
+    const { program } = await import('./mmm.js');
+
+    await program.parseAsync(['', '', 'list']);
+
+    expect(acquireRunLock).not.toHaveBeenCalled();
+  });
+
+  it('refuses to run while another run holds the run lock', async () => {
+    const error = new RunInProgressException('modlist-run.pid', chance.natural());
+    vi.mocked(acquireRunLock).mockRejectedValueOnce(error);
+    const { program, logger } = await import('./mmm.js');
+    vi.mocked(logger.error).mockImplementation(() => {
+      throw new Error('process.exit');
+    });
+
+    await expect(program.parseAsync(['', '', 'update'])).rejects.toThrow('process.exit');
+
+    expect(logger.error).toHaveBeenCalledWith(error.message, 1);
+    expect(update).not.toHaveBeenCalled();
+  });
+
+  it('does not show the metrics without the metrics option', async () => {
+    vi.mocked(list).mockResolvedValueOnce();
+    const { program } = await import('./mmm.js');
+
+    await program.parseAsync(['', '', 'list']);
+
+    expect(formatMetrics).not.toHaveBeenCalled();
+  });
+
   it('can stop the execution', async () => {
     vi.spyOn(process, 'exit').mockImplementation(() => {
       throw new Error('process.exit');
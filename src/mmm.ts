@@ -1,8 +1,13 @@
 #!/usr/bin/env node
-import { Command } from 'commander';
+import path from 'node:path';
+import { Command, InvalidArgumentError } from 'commander';
 import 'dotenv/config';
 import { add } from './actions/add.js';
 import { changeGameVersion } from './actions/change.js';
+import { clearCache } from './actions/clearCache.js';
+import { diffLocks } from './actions/diff.js';
+import { exportMrpack } from './actions/exportMrpack.js';
+import { importPackwiz } from './actions/importPackwiz.js';
 import { install } from './actions/install.js';
 import { list } from './actions/list.js';
 import { prune } from './actions/prune.js';
@@ -10,10 +15,26 @@ import { removeAction } from './actions/remove.js';
 import { scan } from './actions/scan.js';
 import { testGameVersion } from './actions/testGameVersion.js';
 import { update } from './actions/update.js';
+import { verify } from './actions/verify.js';
 import { helpUrl } from './env.js';
 import { initializeConfig } from './interactions/initializeConfig.js';
 import { Logger } from './lib/Logger.js';
+import { lineApiLogger, setApiLogger } from './lib/apiLogger.js';
+import { verifyEnvironmentBaseUrls } from './lib/baseUrl.js';
+import { setDownloadBandwidth } from './lib/downloadThrottle.js';
+import { setDownloadIdleTimeout } from './lib/downloader.js';
+import { getFileCacheDirectory, setFileCacheSize } from './lib/fileCache.js';
+import { setSnapshotGameVersions, setStrictGameVersionMatching } from './lib/gameVersionMatcher.js';
+import { setStrictLoaderMatching } from './lib/loaderCompatibility.js';
+import { formatMetrics, getMetrics } from './lib/metrics.js';
 import { Loader, Platform, ReleaseType } from './lib/modlist.types.js';
+import { setOfflineMode } from './lib/offline.js';
+import { lineProgress, setProgress } from './lib/progress.js';
+import { setCacheTtl, setHttpCacheDirectory } from './lib/rateLimiter/httpCache.js';
+import { setRetryBudget } from './lib/rateLimiter/retryBudget.js';
+import { setProxy } from './lib/rateLimiter/transport.js';
+import { acquireRunLock, releaseRunLock } from './lib/runLock.js';
+import { setPreferServerPacks } from './repositories/curseforge/fetch.js';
 import { Telemetry } from './telemetry/telemetry.js';
 import { version } from './version.js';
 
@@ -58,11 +79,116 @@ program.on('option:quiet', () => {
 
 program.on('option:debug', () => {
   logger.flagDebug();
+  setApiLogger(lineApiLogger((line) => logger.debug(line)));
+});
+
+program.on('option:strict-loader', () => {
+  setStrictLoaderMatching(true);
+});
+
+program.on('option:strict-game-version', () => {
+  setStrictGameVersionMatching(true);
+});
+
+program.on('option:snapshots', () => {
+  setSnapshotGameVersions(true);
+});
+
+program.on('option:offline', () => {
+  setOfflineMode(true);
+});
+
+program.on('option:progress', () => {
+  setProgress(lineProgress((line) => logger.log(line)));
+});
+
+program.on('option:proxy', (proxyUrl: string) => {
+  setProxy(proxyUrl);
+});
+
+program.on('option:http-cache', () => {
+  setHttpCacheDirectory(path.join(getFileCacheDirectory(), 'http'));
+});
+
+program.on('option:server-packs', () => {
+  setPreferServerPacks(true);
+});
+
+program.on('option:metrics', () => {
+  program.hook('postAction', () => {
+    logger.log(formatMetrics(getMetrics()));
+  });
+});
+
+const positiveNumber = (value: string): number => {
+  const parsed = Number(value);
+  if (!Number.isFinite(parsed) || parsed <= 0) {
+    throw new InvalidArgumentError('It has to be a positive number.');
+  }
+  return parsed;
+};
+
+const wholeNumber = (value: string): number => {
+  const parsed = Number(value);
+  if (!Number.isInteger(parsed) || parsed < 0) {
+    throw new InvalidArgumentError('It has to be a whole number.');
+  }
+  return parsed;
+};
+
+program.hook('preAction', () => {
+  try {
+    verifyEnvironmentBaseUrls();
+  } catch (error) {
+    logger.error((error as Error).message, EXIT_CODE.GENERAL_ERROR);
+  }
+});
+
+// The numbers are only known once commander parsed them, the option events see the raw text
+program.hook('preAction', () => {
+  const options = program.opts();
+  if (options.httpCacheTtl !== undefined) {
+    setCacheTtl(options.httpCacheTtl);
+  }
+  if (options.retryBudget !== undefined) {
+    setRetryBudget(options.retryBudget);
+  }
+  if (options.bandwidth !== undefined) {
+    setDownloadBandwidth(options.bandwidth);
+  }
+  if (options.idleTimeout !== undefined) {
+    setDownloadIdleTimeout(options.idleTimeout * 1000);
+  }
+  if (options.cacheSize !== undefined) {
+    setFileCacheSize(options.cacheSize * 1024 * 1024);
+  }
+});
+
+/**
+ * The commands that change the modlist, the lock file or the mods folder. Two of them running on the same folder
+ * would overwrite each other's changes, so the second one is refused.
+ */
+const CHANGING_COMMANDS = ['install', 'update', 'add', 'init', 'change', 'scan', 'import', 'remove', 'prune'];
+
+program.hook('preAction', async (_program, actionCommand) => {
+  if (!CHANGING_COMMANDS.includes(actionCommand.name())) {
+    return;
+  }
+  try {
+    await acquireRunLock(actionCommand.optsWithGlobals().config);
+  } catch (error) {
+    logger.error((error as Error).message, EXIT_CODE.GENERAL_ERROR);
+  }
+});
+
+program.hook('postAction', async () => {
+  await releaseRunLock();
 });
 
 commands.push(
   program
     .command('list')
+    .option('--json', 'Print a line of JSON for every mod with the version the platform offers', false)
     .action(async (_options, cmd) => {
       await list(cmd.optsWithGlobals(), logger);
     })
@@ -72,6 +198,8 @@ commands.push(
 commands.push(
   program
     .command('install')
+    .option('-f, --force', 'Download every mod again, even the ones that are up to date', false)
+    .option('--check-drift', 'Tell about the locked files the platforms would serve differently now', false)
     .action(async (_options, cmd) => {
       await install(cmd.optsWithGlobals(), logger);
     })
@@ -81,8 +209,14 @@ commands.push(
 commands.push(
   program
     .command('update')
+    .option('-n, --dry-run', 'Print out the mods that would have been updated', false)
+    .option('-f, --force', 'Download every mod again, even the ones that are up to date', false)
+    .option('--fail-fast', 'Stop at the first mod that fails instead of updating the rest', false)
+    .option('--changelog', 'Print the changelog of the new Curseforge files in the dry-run', false)
     .action(async (_options, cmd) => {
-      await update(cmd.optsWithGlobals(), logger);
+      if (await update(cmd.optsWithGlobals(), logger)) {
+        process.exitCode = EXIT_CODE.GENERAL_ERROR;
+      }
     })
     .aliases(['u'])
 );
@@ -91,7 +225,7 @@ commands.push(
   program
     .command('add')
     .argument('<type>', 'curseforge or modrinth')
-    .argument('<id>', 'Curseforge or Modrinth Project Id')
+    .argument('<id>', 'Curseforge or Modrinth Project Id, or the name of the mod')
     .option(
       '-v, --version <version>',
       'The version of the mod to add. If not specified, the latest version will be used'
@@ -164,16 +298,71 @@ commands.push(
     })
 );
 
+commands.push(
+  program
+    .command('import')
+    .description('Imports the mods of a packwiz pack into the modlist.')
+    .argument('<pack>', 'The pack.toml of the pack or the folder it is in')
+    .action(async (pack: string, _options, cmd) => {
+      await importPackwiz(pack, cmd.optsWithGlobals(), logger);
+    })
+);
+
+commands.push(
+  program
+    .command('export')
+    .description('Exports the installed mods as a Modrinth .mrpack modpack.')
+    .argument('[file]', 'The file to write the modpack to', 'modpack.mrpack')
+    .requiredOption('--loader-version <version>', 'The version of the loader the launchers install, like 0.15.11')
+    .option('--name <name>', 'The name of the modpack, the name of the file by default')
+    .option('--pack-version <version>', 'The version of the modpack', '1.0.0')
+    .option('--embed', "Put the files the launchers can't download in the pack, like the ones from Curseforge", false)
+    .action(async (file: string, _options, cmd) => {
+      await exportMrpack(file, cmd.optsWithGlobals(), logger);
+    })
+);
+
 commands.push(
   program
     .command('prune')
     .description('Prunes the mod directory from all the unmanaged files.')
     .option('-f, --force', 'Delete the files without asking', false)
+    .option('-a, --all', 'Delete every unmanaged file, not just the leftovers of the managed mods', false)
+    .option('-n, --dry-run', 'Print out the files that would have been deleted', false)
     .action(async (_options, cmd) => {
       await prune(cmd.optsWithGlobals(), logger);
     })
 );
 
+commands.push(
+  program
+    .command('clear-cache')
+    .description('Removes the downloaded mod files kept in the cache for later, like the offline mode.')
+    .action(async (_options, cmd) => {
+      await clearCache(cmd.optsWithGlobals(), logger);
+    })
+);
+
+commands.push(
+  program
+    .command('verify')
+    .description('Checks the installed mods against the hashes in the lock file, without going to the network.')
+    .action(async (_options, cmd) => {
+      await verify(cmd.optsWithGlobals(), logger);
+    })
+);
+
+commands.push(
+  program
+    .command('diff')
+    .description('Prints the mods that were added, removed or changed between two lock files.')
+    .argument('<before>', 'The old lock file, like the one of the main branch')
+    .argument('<after>', 'The new lock file', 'modlist-lock.json')
+    .action(async (before: string, after: string, _options, cmd) => {
+      await diffLocks(before, after, cmd.optsWithGlobals(), logger);
+    })
+);
+
 commands.push(
   program
     .command('remove')
@@ -192,3 +381,33 @@ program.option(
 );
 program.option('-q, --quiet', 'Suppress all output', false);
 program.option('-d, --debug', 'Enable debug messages', false);
+program.option('--strict-loader', 'Only accept files made for the configured loader', false);
+program.option('--strict-game-version', 'Only accept files tagged with the exact game version', false);
+program.option('--snapshots', 'Consider the snapshot game versions, like 24w09a', false);
+program.option('--offline', 'Only use the lock file and the file cache, never the network', false);
+program.option('--progress', 'Show how far the resolving and the downloads got', false);
+program.option('--proxy <url>', 'The proxy to send the requests through, instead of HTTPS_PROXY or HTTP_PROXY');
+program.option('--http-cache', 'Cache the API responses and only download the ones that changed', false);
+program.option(
+  '--http-cache-ttl <seconds>',
+  'How many seconds a cached API response without a max-age is revalidated before it is downloaded again',
+  positiveNumber
+);
+program.option('--metrics', 'Count the requests, retries and cache hits and show them after the run', false);
+program.option(
+  '--retry-budget <retries>',
+  'How many retries all the requests of the run may use together',
+  wholeNumber
+);
+program.option('--bandwidth <bytes>', 'How many bytes per second the downloads may receive together', positiveNumber);
+program.option(
+  '--idle-timeout <seconds>',
+  'How many seconds a download may go without receiving data before it is resumed',
+  positiveNumber
+);
+program.option(
+  '--cache-size <megabytes>',
+  'How many megabytes the cached mod files may take up, the ones used the longest time ago make room',
+  positiveNumber
+);
+program.option('--server-packs', 'Download the server pack of a Curseforge file when there is one', false);
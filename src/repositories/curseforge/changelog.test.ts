@@ -0,0 +1,137 @@
+import { chance } from 'jest-chance';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { CouldNotFindModException } from '../../errors/CouldNotFindModException.js';
+import { Platform } from '../../lib/modlist.types.js';
+import { MaximumRetriesReached } from '../../lib/rateLimiter/MaximumRetriesReached.js';
+import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { changelogToText, getFileChangelog } from './changelog.js';
+
+vi.mock('../../lib/rateLimiter/index.js');
+
+interface LocalTestContext {
+  projectId: string;
+  fileId: number;
+}
+
+describe('The Curseforge changelog', () => {
+  beforeEach<LocalTestContext>((context) => {
+    vi.resetAllMocks();
+    context.projectId = String(chance.integer({ min: 1, max: 999999 }));
+    context.fileId = chance.integer({ min: 1, max: 9999999 });
+  });
+
+  it<LocalTestContext>('fetches the changelog of the file', async ({ projectId, fileId }) => {
+    const changelog = `<p>${chance.sentence()}</p>`;
+    vi.mocked(rateLimitingFetch).mockResolvedValueOnce(
+      new Response(JSON.stringify({ data: changelog }), { status: 200 })
+    );
+
+    const actual = await getFileChangelog(projectId, fileId);
+
+    expect(actual).toEqual(changelog);
+    expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual(
+      `https://api.curseforge.com/v1/mods/${projectId}/files/${fileId}/changelog`
+    );
+  });
+
+  it<LocalTestContext>('returns an empty changelog', async ({ projectId, fileId }) => {
+    vi.mocked(rateLimitingFetch).mockResolvedValueOnce(new Response(JSON.stringify({ data: '' }), { status: 200 }));
+
+    expect(await getFileChangelog(projectId, fileId)).toEqual('');
+  });
+
+  it<LocalTestContext>('treats a missing changelog as empty', async ({ projectId, fileId }) => {
+    vi.mocked(rateLimitingFetch).mockResolvedValueOnce(new Response(JSON.stringify({}), { status: 200 }));
+
+    expect(await getFileChangelog(projectId, fileId)).toEqual('');
+  });
+
+  it<LocalTestContext>('reports an unknown project', async ({ projectId, fileId }) => {
+    vi.mocked(rateLimitingFetch).mockRejectedValueOnce(
+      new MaximumRetriesReached(new Response(null, { status: 404 }))
+    );
+
+    await expect(getFileChangelog(projectId, fileId)).rejects.toThrow(
+      new CouldNotFindModException(projectId, Platform.CURSEFORGE)
+    );
+  });
+
+  it<LocalTestContext>('passes on the retries that ran out for other reasons', async ({ projectId, fileId }) => {
+    const error = new MaximumRetriesReached(new Response(null, { status: 500 }));
+    vi.mocked(rateLimitingFetch).mockRejectedValueOnce(error);
+
+    await expect(getFileChangelog(projectId, fileId)).rejects.toBe(error);
+  });
+
+  it<LocalTestContext>('throws when Curseforge fails', async ({ projectId, fileId }) => {
+    const statusText = chance.sentence();
+    vi.mocked(rateLimitingFetch).mockResolvedValueOnce(
+      new Response(null, { status: 500, statusText: statusText })
+    );
+
+    await expect(getFileChangelog(projectId, fileId)).rejects.toThrow(statusText);
+  });
+
+  describe('when turning it into text', () => {
+    it('keeps the lines and the list items', () => {
+      const changelog =
+        '<h2>1.2.0</h2><p>Fixed a <b>crash</b> &amp; a leak<br>on servers</p><ul><li>First</li><li>Second</li></ul>';
+
+      expect(changelogToText(changelog)).toEqual('1.2.0\nFixed a crash & a leak\non servers\n\n- First\n- Second');
+    });
+
+    it('does not keep more than one empty line', () => {
+      expect(changelogToText('<p>One</p><p></p><p></p><p>Two</p>')).toEqual('One\n\nTwo');
+    });
+
+    it('leaves plain text alone', () => {
+      const changelog = chance.sentence();
+
+      expect(changelogToText(changelog)).toEqual(changelog);
+    });
+
+    it('keeps where the links go', () => {
+      const changelog = '<p>See <a href="https://github.com/meza/mmm/issues/12" rel="nofollow">the issue</a></p>';
+
+      expect(changelogToText(changelog)).toEqual('See the issue (https://github.com/meza/mmm/issues/12)');
+    });
+
+    it('does not repeat the address of a link that shows it', () => {
+      const changelog = '<a href="https://modrinth.com">https://modrinth.com</a>';
+
+      expect(changelogToText(changelog)).toEqual('https://modrinth.com');
+      expect(changelogToText('<a href="https://modrinth.com"><img src="logo.png"></a>')).toEqual(
+        'https://modrinth.com'
+      );
+    });
+
+    it('turns the numbered entities into characters', () => {
+      expect(changelogToText('<p>Don&#8217;t &#x2F; won&apos;t</p>')).toEqual('Don\u2019t / won\'t');
+    });
+
+    it('decodes the entities whatever their case', () => {
+      expect(changelogToText('<p>Fish &AMP; chips &Lt;3 &#X41;&#X2f;</p>')).toEqual('Fish & chips <3 A/');
+    });
+
+    it('leaves the entities that are not characters alone', () => {
+      expect(changelogToText('&#99999999; &copy;')).toEqual('&#99999999; &copy;');
+    });
+
+    it('turns a typical changelog into readable text', () => {
+      const changelog = [
+        '<h3>Changes</h3>',
+        '<ol><li>Updated to <strong>1.20.1</strong></li>',
+        '<li>Fixed the <a href="https://example.com/1">config</a></li></ol>',
+        '<p>Thanks&nbsp;to everyone &lt;3</p>'
+      ].join('');
+
+      expect(changelogToText(changelog)).toEqual(
+        'Changes\n\n- Updated to 1.20.1\n- Fixed the config (https://example.com/1)\nThanks to everyone <3'
+      );
+    });
+
+    it('returns nothing for an empty changelog', () => {
+      expect(changelogToText('')).toEqual('');
+    });
+  });
+});
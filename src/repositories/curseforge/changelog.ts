@@ -0,0 +1,83 @@
+import { CouldNotFindModException } from '../../errors/CouldNotFindModException.js';
+import { apiUrl } from '../../lib/baseUrl.js';
+import { Platform } from '../../lib/modlist.types.js';
+import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { isNotFound } from '../../lib/rateLimiter/notFound.js';
+import { readJson } from '../../lib/rateLimiter/readJson.js';
+
+const htmlEntities: Record<string, string> = {
+  '&amp;': '&',
+  '&lt;': '<',
+  '&gt;': '>',
+  '&quot;': '"',
+  '&#39;': "'",
+  '&apos;': "'",
+  '&nbsp;': ' '
+};
+
+/**
+ * Returns the changelog of a file as Curseforge has it, which is usually HTML.
+ * Files without a changelog come back with an empty string.
+ *
+ * @throws {CouldNotFindModException} When Curseforge doesn't know about the project or the file
+ */
+export const getFileChangelog = async (projectId: string, fileId: number): Promise<string> => {
+  const url = apiUrl(Platform.CURSEFORGE, `mods/${projectId}/files/${fileId}/changelog`);
+  const response = await rateLimitingFetch(url, {
+    headers: {
+      Accept: 'application/json'
+    }
+  }).catch((error) => {
+    if (isNotFound(error)) {
+      throw new CouldNotFindModException(projectId, Platform.CURSEFORGE);
+    }
+    throw error;
+  });
+
+  if (!response.ok) {
+    throw new Error(response.statusText);
+  }
+
+  const changelog = await readJson<{ data?: unknown }>(response);
+  return typeof changelog?.data === 'string' ? changelog.data : '';
+};
+
+/**
+ * The entities are matched without the case, so `&AMP;` and `&#X41;` are decoded like their lowercase forms
+ */
+const decodeEntity = (entity: string, code: string): string => {
+  const named = htmlEntities[entity.toLowerCase()];
+  if (named) {
+    return named;
+  }
+  const hexadecimal = code.toLowerCase().startsWith('#x');
+  const codePoint = hexadecimal ? Number.parseInt(code.slice(2), 16) : Number.parseInt(code.slice(1), 10);
+  return Number.isNaN(codePoint) || codePoint > 0x10ffff ? entity : String.fromCodePoint(codePoint);
+};
+
+/**
+ * Keeps where the link goes, unless the text of the link already says it
+ */
+const linkToText = (_link: string, href: string, text: string): string => {
+  const label = text.replace(/<[^>]+>/g, '').trim();
+  return label === href || label.length === 0 ? href : `${label} (${href})`;
+};
+
+/**
+ * Turns an HTML changelog into plain text that can be printed to the terminal.
+ * Every block element and line break becomes a new line, list items get a dash and links keep their address.
+ * The raw changelog from getFileChangelog is still there for anything that can show HTML.
+ */
+export const changelogToText = (changelog: string): string => {
+  return changelog
+    .replace(/<a\s[^>]*href=["']([^"']*)["'][^>]*>(.*?)<\/a>/gis, linkToText)
+    .replace(/<li[^>]*>/gi, '\n- ')
+    .replace(/<br\s*\/?>|<\/(p|div|h[1-6]|ul|ol)>/gi, '\n')
+    .replace(/<[^>]+>/g, '')
+    .replace(/&(amp|lt|gt|quot|apos|#39|nbsp|#\d+|#x[\da-f]+);/gi, decodeEntity)
+    .split('\n')
+    .map((line) => line.trim())
+    .filter((line, index, lines) => line.length > 0 || (index > 0 && lines[index - 1].length > 0))
+    .join('\n')
+    .trim();
+};
@@ -0,0 +1,182 @@
+import { chance } from 'jest-chance';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateCurseforgeModFile } from '../../../test/generateCurseforgeModFile.js';
+import { NoRemoteFileFound } from '../../errors/NoRemoteFileFound.js';
+import { Loader, Platform, ReleaseType } from '../../lib/modlist.types.js';
+import { DependencyResolutionOptions, resolveDependencies } from './dependencies.js';
+import { CurseforgeModFile, CurseforgeRelationType, getLatestFile } from './fetch.js';
+
+vi.mock('./fetch.js');
+
+type Graph = Record<string, { required?: string[]; optional?: string[] }>;
+
+const fileFor = (graph: Graph, projectId: string): CurseforgeModFile => {
+  const node = graph[projectId];
+  return generateCurseforgeModFile({
+    fileName: `${projectId}.jar`,
+    dependencies: [
+      ...(node.required || []).map((id) => ({
+        modId: Number(id),
+        relationType: CurseforgeRelationType.REQUIRED_DEPENDENCY
+      })),
+      ...(node.optional || []).map((id) => ({
+        modId: Number(id),
+        relationType: CurseforgeRelationType.OPTIONAL_DEPENDENCY
+      }))
+    ]
+  }).generated;
+};
+
+const assumeGraph = (graph: Graph) => {
+  const files = Object.fromEntries(Object.keys(graph).map((id) => [id, fileFor(graph, id)]));
+  vi.mocked(getLatestFile).mockImplementation(async (projectId: string) => {
+    if (!files[projectId]) {
+      throw new NoRemoteFileFound(projectId, Platform.CURSEFORGE);
+    }
+    return files[projectId];
+  });
+  return files;
+};
+
+describe('The Curseforge dependency resolution', () => {
+  let options: DependencyResolutionOptions;
+
+  beforeEach(() => {
+    vi.resetAllMocks();
+    options = {
+      gameVersion: chance.pickone(['1.19.2', '1.20.1']),
+      loader: chance.pickone([Loader.FABRIC, Loader.FORGE]),
+      allowedReleaseTypes: [ReleaseType.RELEASE]
+    };
+  });
+
+  it('resolves the requested mods', async () => {
+    const files = assumeGraph({ '1': {} });
+
+    const actual = await resolveDependencies(['1'], options);
+
+    expect(actual).toEqual({ files: [{ projectId: '1', file: files['1'], requiredBy: [] }], cycles: [] });
+    expect(vi.mocked(getLatestFile)).toHaveBeenCalledWith(
+      '1',
+      options.gameVersion,
+      options.loader,
+      options.allowedReleaseTypes,
+      undefined
+    );
+  });
+
+  it('pulls in the dependencies of the dependencies', async () => {
+    assumeGraph({
+      '1': { required: ['2'] },
+      '2': { required: ['3'] },
+      '3': {}
+    });
+
+    const actual = await resolveDependencies(['1'], options);
+
+    expect(actual.files.map((file) => [file.projectId, file.requiredBy])).toEqual([
+      ['1', []],
+      ['2', ['1']],
+      ['3', ['2']]
+    ]);
+  });
+
+  it('resolves a shared dependency once', async () => {
+    assumeGraph({
+      '1': { required: ['3'] },
+      '2': { required: ['3'] },
+      '3': {}
+    });
+
+    const actual = await resolveDependencies(['1', '2'], options);
+
+    expect(actual.files.map((file) => file.projectId)).toEqual(['1', '3', '2']);
+    expect(actual.files.find((file) => file.projectId === '3')?.requiredBy).toEqual(['1', '2']);
+    expect(vi.mocked(getLatestFile)).toHaveBeenCalledTimes(3);
+  });
+
+  it('does not get stuck in a dependency loop', async () => {
+    assumeGraph({
+      '1': { required: ['2'] },
+      '2': { required: ['3'] },
+      '3': { required: ['2'] }
+    });
+
+    const actual = await resolveDependencies(['1'], options);
+
+    expect(actual.files.map((file) => file.projectId)).toEqual(['1', '2', '3']);
+    expect(actual.cycles).toEqual([['2', '3', '2']]);
+  });
+
+  it('handles a graph with a shared dependency and a loop', async () => {
+    assumeGraph({
+      '1': { required: ['3', '4'] },
+      '2': { required: ['3'] },
+      '3': { required: ['5'] },
+      '4': { required: ['1'] },
+      '5': {}
+    });
+
+    const actual = await resolveDependencies(['1', '2'], options);
+
+    expect(actual.files.map((file) => file.projectId).sort()).toEqual(['1', '2', '3', '4', '5']);
+    expect(actual.cycles).toEqual([['1', '4', '1']]);
+    expect(vi.mocked(getLatestFile)).toHaveBeenCalledTimes(5);
+  });
+
+  it('leaves out the optional dependencies', async () => {
+    assumeGraph({
+      '1': { required: ['2'], optional: ['3'] },
+      '2': {},
+      '3': {}
+    });
+
+    const actual = await resolveDependencies(['1'], options);
+
+    expect(actual.files.map((file) => file.projectId)).toEqual(['1', '2']);
+  });
+
+  it('includes the optional dependencies that were asked for', async () => {
+    assumeGraph({
+      '1': { optional: ['2', '3'] },
+      '2': {},
+      '3': {}
+    });
+
+    const actual = await resolveDependencies(['1'], { ...options, optional: ['3'] });
+
+    expect(actual.files.map((file) => file.projectId)).toEqual(['1', '3']);
+  });
+
+  it('ignores the other relations', async () => {
+    const file = generateCurseforgeModFile({
+      dependencies: [
+        { modId: 2, relationType: CurseforgeRelationType.EMBEDDED_LIBRARY },
+        { modId: 3, relationType: CurseforgeRelationType.INCOMPATIBLE },
+        { modId: 4, relationType: CurseforgeRelationType.TOOL }
+      ]
+    }).generated;
+    vi.mocked(getLatestFile).mockResolvedValueOnce(file);
+
+    const actual = await resolveDependencies(['1'], options);
+
+    expect(actual.files).toEqual([{ projectId: '1', file: file, requiredBy: [] }]);
+  });
+
+  it('throws when a dependency has no suitable file', async () => {
+    assumeGraph({ '1': { required: ['2'] } });
+
+    await expect(resolveDependencies(['1'], options)).rejects.toThrow(new NoRemoteFileFound('2', Platform.CURSEFORGE));
+  });
+
+  it('can be cancelled', async () => {
+    const controller = new AbortController();
+    controller.abort();
+
+    await expect(resolveDependencies(['1'], { ...options, signal: controller.signal })).rejects.toHaveProperty(
+      'name',
+      'AbortError'
+    );
+    expect(vi.mocked(getLatestFile)).not.toHaveBeenCalled();
+  });
+});
@@ -0,0 +1,104 @@
+import { Loader, ReleaseType } from '../../lib/modlist.types.js';
+import { CurseforgeModFile, CurseforgeRelationType, getLatestFile } from './fetch.js';
+
+export interface DependencyResolutionOptions {
+  gameVersion: string;
+  loader: Loader;
+  allowedReleaseTypes: ReleaseType[];
+  /**
+   * The optional dependencies to install anyway, by project id
+   */
+  optional?: string[];
+  signal?: AbortSignal;
+}
+
+export interface ResolvedFile {
+  projectId: string;
+  file: CurseforgeModFile;
+  /**
+   * The projects that pulled this one in, empty for the requested ones
+   */
+  requiredBy: string[];
+}
+
+export interface DependencyResolution {
+  /**
+   * Every file to install, each project once
+   */
+  files: ResolvedFile[];
+  /**
+   * The dependency loops that were found, like [a, b, a] when a needs b and b needs a
+   */
+  cycles: string[][];
+}
+
+const wantedDependencies = (file: CurseforgeModFile, optional: string[]): string[] => {
+  return (file.dependencies || [])
+    .filter(
+      (dependency) =>
+        dependency.relationType === CurseforgeRelationType.REQUIRED_DEPENDENCY ||
+        (dependency.relationType === CurseforgeRelationType.OPTIONAL_DEPENDENCY &&
+          optional.includes(String(dependency.modId)))
+    )
+    .map((dependency) => String(dependency.modId));
+};
+
+/**
+ * Finds the files of the requested projects and everything they need, like Fabric API or the libraries.
+ * Every dependency gets its own file for the game version and the loader, a project shared by several mods is
+ * only resolved once.
+ *
+ * A loop in the dependencies doesn't stop the resolution, it is reported in the cycles.
+ *
+ * @param projectIds The projects that were asked for
+ * @param options
+ * @throws {NoRemoteFileFound} When a project has no file for the game version and the loader
+ * @throws {CouldNotFindModException} When a project doesn't exist
+ */
+export const resolveDependencies = async (
+  projectIds: string[],
+  options: DependencyResolutionOptions
+): Promise<DependencyResolution> => {
+  const optional = options.optional || [];
+  const resolved = new Map<string, ResolvedFile>();
+  const cycles: string[][] = [];
+
+  const visit = async (projectId: string, path: string[]) => {
+    if (path.includes(projectId)) {
+      cycles.push([...path.slice(path.indexOf(projectId)), projectId]);
+      return;
+    }
+
+    const requiredBy = path.at(-1);
+    const known = resolved.get(projectId);
+    if (known) {
+      if (requiredBy && !known.requiredBy.includes(requiredBy)) {
+        known.requiredBy.push(requiredBy);
+      }
+      return;
+    }
+
+    options.signal?.throwIfAborted();
+    const file = await getLatestFile(
+      projectId,
+      options.gameVersion,
+      options.loader,
+      options.allowedReleaseTypes,
+      options.signal
+    );
+    resolved.set(projectId, { projectId: projectId, file: file, requiredBy: requiredBy ? [requiredBy] : [] });
+
+    for (const dependency of wantedDependencies(file, optional)) {
+      await visit(dependency, [...path, projectId]);
+    }
+  };
+
+  for (const projectId of projectIds) {
+    await visit(projectId, []);
+  }
+
+  return {
+    files: [...resolved.values()],
+    cycles: cycles
+  };
+};
@@ -0,0 +1,116 @@
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { CategoriesCouldNotBeFetchedException } from '../../errors/CategoriesCouldNotBeFetchedException.js';
+import { MinecraftVersionsCouldNotBeFetchedException } from '../../errors/MinecraftVersionsCouldNotBeFetchedException.js';
+import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { clearDiscoveries, getCurseforgeCategories, getCurseforgeMinecraftVersions } from './discovery.js';
+
+vi.mock('../../lib/rateLimiter/index.js');
+
+const versionsResponse = {
+  data: [
+    { id: 1, gameVersionId: 9990, versionString: '1.20.1', jarDownloadUrl: 'https://example.com/1.20.1.jar' },
+    { id: 2, gameVersionId: 10236, versionString: '1.21' },
+    { id: 3, gameVersionId: 7498, versionString: '1.16.5' }
+  ]
+};
+
+const categoriesResponse = {
+  data: [
+    { id: 420, gameId: 432, name: 'Storage', slug: 'storage', classId: 6, parentCategoryId: 6 },
+    { id: 406, gameId: 432, name: 'World Gen', slug: 'world-gen', classId: 6, parentCategoryId: 6 }
+  ]
+};
+
+const respondWith = (body: unknown, ok = true) => {
+  vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+    ok: ok,
+    json: () => Promise.resolve(body)
+  } as Response);
+};
+
+const requestedUrl = (call = 0) => new URL(vi.mocked(rateLimitingFetch).mock.calls[call][0] as string);
+
+describe('The Curseforge discovery', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+  });
+
+  afterEach(() => {
+    clearDiscoveries();
+  });
+
+  describe('when listing the Minecraft versions', () => {
+    it('reads the versions the newest first', async () => {
+      respondWith(versionsResponse);
+
+      expect(await getCurseforgeMinecraftVersions()).toEqual(['1.21', '1.20.1', '1.16.5']);
+      expect(requestedUrl().pathname).toEqual('/v1/minecraft/version');
+    });
+
+    it('only asks once a run', async () => {
+      respondWith(versionsResponse);
+
+      await Promise.all([getCurseforgeMinecraftVersions(), getCurseforgeMinecraftVersions()]);
+      await getCurseforgeMinecraftVersions();
+
+      expect(rateLimitingFetch).toHaveBeenCalledOnce();
+    });
+
+    it('asks again once the versions are cleared', async () => {
+      respondWith(versionsResponse);
+      respondWith(versionsResponse);
+
+      await getCurseforgeMinecraftVersions();
+      clearDiscoveries();
+      await getCurseforgeMinecraftVersions();
+
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(2);
+    });
+
+    it('fails when curseforge does not list them', async () => {
+      respondWith({}, false);
+
+      await expect(getCurseforgeMinecraftVersions()).rejects.toThrow(MinecraftVersionsCouldNotBeFetchedException);
+    });
+
+    it('asks again after a failure', async () => {
+      vi.mocked(rateLimitingFetch).mockRejectedValueOnce(new Error('ECONNRESET'));
+      respondWith(versionsResponse);
+
+      await expect(getCurseforgeMinecraftVersions()).rejects.toThrow(MinecraftVersionsCouldNotBeFetchedException);
+
+      expect(await getCurseforgeMinecraftVersions()).toHaveLength(3);
+    });
+  });
+
+  describe('when listing the categories', () => {
+    it('reads the categories of the mods', async () => {
+      respondWith(categoriesResponse);
+
+      const categories = await getCurseforgeCategories();
+
+      expect(categories.map((category) => category.slug)).toEqual(['storage', 'world-gen']);
+      expect(categories[0]).toMatchObject({ id: 420, name: 'Storage', classId: 6 });
+      expect(requestedUrl().pathname).toEqual('/v1/categories');
+      expect(requestedUrl().searchParams.get('gameId')).toEqual('432');
+      expect(requestedUrl().searchParams.get('classId')).toEqual('6');
+    });
+
+    it('only asks once a run', async () => {
+      respondWith(categoriesResponse);
+
+      await getCurseforgeCategories();
+      await getCurseforgeCategories();
+
+      expect(rateLimitingFetch).toHaveBeenCalledOnce();
+    });
+
+    it('fails when curseforge does not list them', async () => {
+      respondWith({}, false);
+      respondWith(categoriesResponse);
+
+      await expect(getCurseforgeCategories()).rejects.toThrow(CategoriesCouldNotBeFetchedException);
+      expect(await getCurseforgeCategories()).toHaveLength(2);
+    });
+  });
+});
@@ -0,0 +1,101 @@
+import { CategoriesCouldNotBeFetchedException } from '../../errors/CategoriesCouldNotBeFetchedException.js';
+import { MinecraftVersionsCouldNotBeFetchedException } from '../../errors/MinecraftVersionsCouldNotBeFetchedException.js';
+import { apiUrl } from '../../lib/baseUrl.js';
+import { compareGameVersions } from '../../lib/gameVersionMatcher.js';
+import { Platform } from '../../lib/modlist.types.js';
+import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { MINECRAFT_GAME_ID, MODS_CLASS_ID } from './search.js';
+
+export interface CurseforgeMinecraftVersion {
+  id: number;
+  gameVersionId: number;
+  versionString: string;
+}
+
+export interface CurseforgeCategory {
+  id: number;
+  name: string;
+  slug: string;
+  classId?: number;
+  parentCategoryId?: number;
+}
+
+let minecraftVersions: Promise<string[]> | undefined;
+let categories: Promise<CurseforgeCategory[]> | undefined;
+
+const fetchList = async <T>(url: string, failure: () => Error): Promise<T[]> => {
+  try {
+    const response = await rateLimitingFetch(url, {
+      headers: {
+        Accept: 'application/json'
+      }
+    });
+
+    if (!response.ok) {
+      throw failure();
+    }
+
+    const list: { data: T[] } = await response.json();
+    return list.data;
+  } catch {
+    throw failure();
+  }
+};
+
+/**
+ * Keeps the list for the rest of the run, a failed request is forgotten so the next call asks again
+ */
+const remember = <T>(list: Promise<T>, forget: () => void): Promise<T> => {
+  list.catch(forget);
+  return list;
+};
+
+/**
+ * The Minecraft versions Curseforge has files for, the newest first.
+ * They are only asked for once a run.
+ *
+ * @throws {MinecraftVersionsCouldNotBeFetchedException} When Curseforge can't list them
+ */
+export const getCurseforgeMinecraftVersions = (): Promise<string[]> => {
+  if (!minecraftVersions) {
+    const url = apiUrl(Platform.CURSEFORGE, 'minecraft/version');
+    minecraftVersions = remember(
+      fetchList<CurseforgeMinecraftVersion>(url, () => new MinecraftVersionsCouldNotBeFetchedException()).then(
+        (versions) => versions.map((version) => version.versionString).sort((a, b) => compareGameVersions(b, a))
+      ),
+      () => {
+        minecraftVersions = undefined;
+      }
+    );
+  }
+  return minecraftVersions;
+};
+
+/**
+ * The categories of the Minecraft mods on Curseforge, like Storage or World Gen.
+ * They are only asked for once a run.
+ *
+ * @throws {CategoriesCouldNotBeFetchedException} When Curseforge can't list them
+ */
+export const getCurseforgeCategories = (): Promise<CurseforgeCategory[]> => {
+  if (!categories) {
+    const url = new URL(apiUrl(Platform.CURSEFORGE, 'categories'));
+    url.searchParams.set('gameId', String(MINECRAFT_GAME_ID));
+    url.searchParams.set('classId', String(MODS_CLASS_ID));
+    categories = remember(
+      fetchList<CurseforgeCategory>(url.toString(), () => new CategoriesCouldNotBeFetchedException()),
+      () => {
+        categories = undefined;
+      }
+    );
+  }
+  return categories;
+};
+
+/**
+ * Forgets the lists, the next calls ask Curseforge again
+ */
+export const clearDiscoveries = () => {
+  minecraftVersions = undefined;
+  categories = undefined;
+};
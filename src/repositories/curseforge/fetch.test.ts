@@ -1,13 +1,57 @@
 import { chance } from 'jest-chance';
-import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
 import { generateCurseforgeModFile } from '../../../test/generateCurseforgeModFile.js';
+import { RecordingApiLogger, recordingApiLogger } from '../../../test/recordingApiLogger.js';
 import { CouldNotFindModException } from '../../errors/CouldNotFindModException.js';
 import { CurseforgeDownloadUrlError } from '../../errors/CurseforgeDownloadUrlError.js';
+import { InvalidPageSizeException } from '../../errors/InvalidPageSizeException.js';
 import { NoRemoteFileFound } from '../../errors/NoRemoteFileFound.js';
-import { Loader, Platform, ReleaseType, RemoteModDetails } from '../../lib/modlist.types.js';
+import { setApiLogger } from '../../lib/apiLogger.js';
+import { setBaseUrl } from '../../lib/baseUrl.js';
+import { setStrictGameVersionMatching } from '../../lib/gameVersionMatcher.js';
+import { setStrictLoaderMatching } from '../../lib/loaderCompatibility.js';
+import {
+  FileOverrides,
+  Loader,
+  Platform,
+  ReleaseChannel,
+  ReleaseType,
+  RemoteModDetails
+} from '../../lib/modlist.types.js';
+import { ResponseTooLarge } from '../../lib/rateLimiter/ResponseTooLarge.js';
+import { setNow } from '../../lib/rateLimiter/clock.js';
 import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { setMaxResponseSize } from '../../lib/rateLimiter/readJson.js';
+import { releaseTypesForChannel } from '../../lib/releaseChannel.js';
 import { RepositoryTestContext } from '../index.test.js';
-import { CurseforgeModFile, HashFunctions, curseforgeFileToRemoteModDetails, getMod } from './fetch.js';
+import { InvalidReleaseTypeException } from './InvalidReleaseTypeException.js';
+import {
+  CurseforgeModFile,
+  CurseforgeRelationType,
+  HashFunctions,
+  MAX_FILES_PAGE_SIZE,
+  MOD_INFO_CHUNK_SIZE,
+  curseforgeFileToReleasedFile,
+  curseforgeFileToRemoteModDetails,
+  curseforgeFilesUrl,
+  getAdditionalFiles,
+  getFilesPageSize,
+  getFilesUntil,
+  getMod,
+  getLatestFile,
+  getModInfo,
+  getModsInfo,
+  getProjectFiles,
+  md5Hash,
+  prefersServerPacks,
+  requiredDependencies,
+  selectFile,
+  setFilesPageSize,
+  setPreferServerPacks,
+  sha1Hash
+} from './fetch.js';
+import { CurseforgeGameVersionType } from './gameVersionTypes.js';
+import { CurseforgeLoader } from './index.js';
 
 enum Release {
   ALPHA = 3,
@@ -61,6 +105,14 @@ describe('The Curseforge repository', () => {
     context.gameVersion = chance.pickone(['1.16.5', '1.17.1', '1.18.1', '1.18.2', '1.19']);
     context.loader = chance.pickone(testLoaders);
     context.allowFallback = false;
+    // Only the compatibility tests expect the files of other loaders and game versions to be fetched
+    setStrictLoaderMatching(true);
+    setStrictGameVersionMatching(true);
+  });
+
+  afterEach(() => {
+    setStrictLoaderMatching();
+    setStrictGameVersionMatching();
   });
 
   it<RepositoryTestContext>('throws an error when the mod details could not be fetched', async (context) => {
@@ -107,6 +159,37 @@ describe('The Curseforge repository', () => {
     ).rejects.toThrow(new CurseforgeDownloadUrlError(randomName));
   });
 
+  it.each([null, ''])(
+    'tells which file has its downloads disabled when the url is %j',
+    async (downloadUrl) => {
+      const projectId = chance.word();
+      const gameVersion = '1.20.1';
+      const randomName = chance.word();
+      const randomFile = generateCurseforgeModFile({
+        isAvailable: true,
+        fileStatus: releasedStatus,
+        releaseType: Release.RELEASE,
+        sortableGameVersions: [
+          {
+            gameVersionName: gameVersion,
+            gameVersion: gameVersion
+          }
+        ],
+        // @ts-ignore
+        downloadUrl: downloadUrl
+      }).generated;
+
+      assumeSuccessfulModFetch(randomName, [randomFile]);
+
+      const error = await getMod(projectId, [ReleaseType.RELEASE], gameVersion, Loader.FORGE, false).catch((e) => e);
+
+      expect(error).toBeInstanceOf(CurseforgeDownloadUrlError);
+      expect(error.modName).toEqual(randomName);
+      expect(error.projectId).toEqual(projectId);
+      expect(error.fileId).toEqual(randomFile.id);
+    }
+  );
+
   it<RepositoryTestContext>('throws an error when the files cannot be fetched', async (context) => {
     assumeFailedModFetch();
     await expect(async () => {
@@ -193,6 +276,27 @@ describe('The Curseforge repository', () => {
     }).rejects.toThrow(new NoRemoteFileFound(randomName, context.platform));
   });
 
+  it<RepositoryTestContext>('does not take a tag of another type for the game version', async (context) => {
+    const randomName = chance.word();
+    const randomFile = generateCurseforgeModFile({
+      isAvailable: true,
+      fileStatus: releasedStatus,
+      releaseType: Release.RELEASE,
+      sortableGameVersions: [
+        {
+          gameVersionName: context.gameVersion,
+          gameVersion: context.gameVersion,
+          gameVersionTypeId: CurseforgeGameVersionType.LOADER
+        }
+      ]
+    });
+    assumeSuccessfulModFetch(randomName, [randomFile.generated]);
+
+    await expect(async () => {
+      await getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, context.loader, false);
+    }).rejects.toThrow(new NoRemoteFileFound(randomName, context.platform));
+  });
+
   it<RepositoryTestContext>('throws an error when no files are available', async (context) => {
     const randomName = chance.word();
     const randomFile = generateCurseforgeModFile({
@@ -283,9 +387,11 @@ describe('The Curseforge repository', () => {
       expect(actual).toEqual({
         name: randomName,
         fileName: randomFile.generated.fileName,
+        version: randomFile.generated.displayName,
         releaseDate: randomFile.generated.fileDate,
         hash: randomFile.generated.hashes.find((hash) => hash.algo === HashFunctions.sha1)?.value,
-        downloadUrl: randomFile.generated.downloadUrl
+        downloadUrl: randomFile.generated.downloadUrl,
+        curseforgeFileId: randomFile.generated.id
       });
     });
   });
@@ -330,9 +436,11 @@ describe('The Curseforge repository', () => {
       expect(actual).toEqual({
         name: randomName,
         fileName: randomFile.generated.fileName,
+        version: randomFile.generated.displayName,
         releaseDate: randomFile.generated.fileDate,
         hash: randomFile.generated.hashes.find((hash) => hash.algo === HashFunctions.sha1)?.value,
-        downloadUrl: randomFile.generated.downloadUrl
+        downloadUrl: randomFile.generated.downloadUrl,
+        curseforgeFileId: randomFile.generated.id
       });
     });
   });
@@ -363,9 +471,11 @@ describe('The Curseforge repository', () => {
     expect(actual).toEqual({
       name: randomName,
       fileName: randomFile.generated.fileName,
+      version: randomFile.generated.displayName,
       releaseDate: randomFile.generated.fileDate,
       hash: randomFile.generated.hashes.find((hash) => hash.algo === HashFunctions.sha1)?.value,
-      downloadUrl: randomFile.generated.downloadUrl
+      downloadUrl: randomFile.generated.downloadUrl,
+      curseforgeFileId: randomFile.generated.id
     });
   });
 
@@ -437,9 +547,11 @@ describe('The Curseforge repository', () => {
     expect(actual).toEqual({
       name: randomName,
       fileName: randomFile2.generated.fileName,
+      version: randomFile2.generated.displayName,
       releaseDate: randomFile2.generated.fileDate,
       hash: randomFile2.generated.hashes.find((hash) => hash.algo === HashFunctions.sha1)?.value,
-      downloadUrl: randomFile2.generated.downloadUrl
+      downloadUrl: randomFile2.generated.downloadUrl,
+      curseforgeFileId: randomFile2.generated.id
     });
   });
 
@@ -449,11 +561,13 @@ describe('The Curseforge repository', () => {
     const randomFileDate = chance.date();
     const randomHash = chance.word();
     const randomDownloadUrl = chance.word();
+    const randomFileLength = chance.integer({ min: 1, max: 100000 });
 
     const file = generateCurseforgeModFile({
       fileName: randomFileName,
       fileDate: randomFileDate,
       downloadUrl: randomDownloadUrl,
+      fileLength: randomFileLength,
       hashes: [
         {
           algo: HashFunctions.sha1,
@@ -469,6 +583,8 @@ describe('The Curseforge repository', () => {
     expect(actual.fileName).toEqual(randomFileName);
     expect(actual.releaseDate).toEqual(randomFileDate);
     expect(actual.downloadUrl).toEqual(randomDownloadUrl);
+    expect(actual.fileLength).toEqual(randomFileLength);
+    expect(actual.curseforgeFileId).toEqual(file.id);
   });
 
   describe('when a specific mod version is requested', () => {
@@ -545,10 +661,1461 @@ describe('The Curseforge repository', () => {
       expect(actual).toEqual({
         name: randomName,
         fileName: randomFile3.generated.fileName,
+        version: randomFile3.generated.displayName,
         releaseDate: randomFile3.generated.fileDate,
         hash: randomFile3.generated.hashes.find((hash) => hash.algo === HashFunctions.sha1)?.value,
-        downloadUrl: randomFile3.generated.downloadUrl
+        downloadUrl: randomFile3.generated.downloadUrl,
+        curseforgeFileId: randomFile3.generated.id
+      });
+    });
+  });
+
+  describe('when the files are paginated', () => {
+    const assumeModDetails = (modName: string) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () =>
+          Promise.resolve({
+            data: {
+              name: modName
+            }
+          })
+      } as Response);
+    };
+
+    const assumeFilesPage = (files: CurseforgeModFile[], index: number, totalCount: number) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () =>
+          Promise.resolve({
+            data: files,
+            pagination: {
+              index: index,
+              pageSize: 50,
+              resultCount: files.length,
+              totalCount: totalCount
+            }
+          })
+      } as Response);
+    };
+
+    const releasedFile = (gameVersion: string, fileDate: string) =>
+      generateCurseforgeModFile({
+        isAvailable: true,
+        fileStatus: releasedStatus,
+        fileDate: fileDate,
+        releaseType: Release.RELEASE,
+        sortableGameVersions: [
+          {
+            gameVersionName: gameVersion,
+            gameVersion: gameVersion
+          }
+        ]
+      }).generated;
+
+    it<RepositoryTestContext>('fetches every page', async (context) => {
+      const randomName = chance.word();
+      const oldFile = releasedFile(context.gameVersion, '2018-08-24T14:15:22Z');
+      const newFile = releasedFile(context.gameVersion, '2020-08-24T14:15:22Z');
+
+      assumeModDetails(randomName);
+      assumeFilesPage([oldFile], 0, 2);
+      assumeFilesPage([newFile], 1, 2);
+
+      const actual = await getMod(
+        context.id,
+        [ReleaseType.RELEASE],
+        context.gameVersion,
+        context.loader,
+        context.allowFallback
+      );
+
+      expect(actual.fileName).toEqual(newFile.fileName);
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(3);
+      expect(vi.mocked(rateLimitingFetch).mock.calls[1][0]).toContain('&index=0');
+      expect(vi.mocked(rateLimitingFetch).mock.calls[2][0]).toContain('&index=1');
+    });
+
+    it<RepositoryTestContext>('stops at an empty page and uses the files it has', async (context) => {
+      const randomName = chance.word();
+      const file = releasedFile(context.gameVersion, '2018-08-24T14:15:22Z');
+
+      assumeModDetails(randomName);
+      assumeFilesPage([file], 0, 10);
+      assumeFilesPage([], 1, 10);
+
+      const actual = await getMod(
+        context.id,
+        [ReleaseType.RELEASE],
+        context.gameVersion,
+        context.loader,
+        context.allowFallback
+      );
+
+      expect(actual.fileName).toEqual(file.fileName);
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(3);
+    });
+
+    it<RepositoryTestContext>('stops when the page index does not advance', async (context) => {
+      const randomName = chance.word();
+      const file = releasedFile(context.gameVersion, '2018-08-24T14:15:22Z');
+
+      assumeModDetails(randomName);
+      assumeFilesPage([file], 0, 10);
+      assumeFilesPage([file], 0, 10);
+
+      const actual = await getMod(
+        context.id,
+        [ReleaseType.RELEASE],
+        context.gameVersion,
+        context.loader,
+        context.allowFallback
+      );
+
+      expect(actual.fileName).toEqual(file.fileName);
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(3);
+    });
+
+    it<RepositoryTestContext>('still fails when a page cannot be fetched', async (context) => {
+      assumeModDetails(chance.word());
+      assumeFilesPage([releasedFile(context.gameVersion, '2018-08-24T14:15:22Z')], 0, 10);
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: false
+      } as Response);
+
+      await expect(
+        getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, context.loader, context.allowFallback)
+      ).rejects.toThrow(new CouldNotFindModException(context.id, context.platform));
+    });
+
+    describe('with a smaller page size', () => {
+      afterEach(() => {
+        setFilesPageSize();
+      });
+
+      it<RepositoryTestContext>('asks for pages of that size until every file is in', async (context) => {
+        setFilesPageSize(2);
+        const files = Array.from({ length: 5 }, (_value, index) =>
+          releasedFile(context.gameVersion, `201${index}-08-24T14:15:22Z`)
+        );
+
+        assumeModDetails(chance.word());
+        assumeFilesPage(files.slice(0, 2), 0, 5);
+        assumeFilesPage(files.slice(2, 4), 2, 5);
+        assumeFilesPage(files.slice(4), 4, 5);
+
+        const actual = await getMod(
+          context.id,
+          [ReleaseType.RELEASE],
+          context.gameVersion,
+          context.loader,
+          context.allowFallback
+        );
+
+        expect(actual.fileName).toEqual(files[4].fileName);
+        expect(rateLimitingFetch).toHaveBeenCalledTimes(4);
+        expect(vi.mocked(rateLimitingFetch).mock.calls[1][0]).toContain('&index=0&pageSize=2');
+        expect(vi.mocked(rateLimitingFetch).mock.calls[2][0]).toContain('&index=2&pageSize=2');
+        expect(vi.mocked(rateLimitingFetch).mock.calls[3][0]).toContain('&index=4&pageSize=2');
       });
     });
   });
+
+  describe('when setting the files page size', () => {
+    afterEach(() => {
+      setFilesPageSize();
+    });
+
+    it('asks for the biggest pages by default', () => {
+      expect(getFilesPageSize()).toEqual(MAX_FILES_PAGE_SIZE);
+      expect(MAX_FILES_PAGE_SIZE).toEqual(50);
+    });
+
+    it('uses the page size it was given', () => {
+      const pageSize = chance.integer({ min: 1, max: MAX_FILES_PAGE_SIZE });
+
+      setFilesPageSize(pageSize);
+
+      expect(getFilesPageSize()).toEqual(pageSize);
+      expect(curseforgeFilesUrl('123', '', CurseforgeLoader.ANY, 0)).toContain(`&pageSize=${pageSize}`);
+    });
+
+    it('caps the page size at what Curseforge allows', () => {
+      setFilesPageSize(chance.integer({ min: MAX_FILES_PAGE_SIZE + 1 }));
+
+      expect(getFilesPageSize()).toEqual(MAX_FILES_PAGE_SIZE);
+    });
+
+    it('goes back to the default without a value', () => {
+      setFilesPageSize(10);
+      setFilesPageSize();
+
+      expect(getFilesPageSize()).toEqual(MAX_FILES_PAGE_SIZE);
+    });
+
+    it.each([0, -1, 2.5, Number.NaN])('refuses %s', (pageSize) => {
+      setFilesPageSize(10);
+
+      expect(() => setFilesPageSize(pageSize)).toThrow(new InvalidPageSizeException(pageSize));
+      expect(getFilesPageSize()).toEqual(10);
+    });
+  });
+
+  describe('when the lookup is cancelled', () => {
+    const releasedFile = (gameVersion: string) =>
+      generateCurseforgeModFile({
+        isAvailable: true,
+        fileStatus: releasedStatus,
+        releaseType: Release.RELEASE,
+        sortableGameVersions: [
+          {
+            gameVersionName: gameVersion,
+            gameVersion: gameVersion
+          }
+        ]
+      }).generated;
+
+    it<RepositoryTestContext>('stops fetching the pages', async (context) => {
+      const controller = new AbortController();
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => {
+          controller.abort();
+          return Promise.resolve({
+            data: [releasedFile(context.gameVersion)],
+            pagination: { index: 0, pageSize: 50, resultCount: 1, totalCount: 10 }
+          });
+        }
+      } as Response);
+
+      await expect(
+        getLatestFile(context.id, context.gameVersion, context.loader, [ReleaseType.RELEASE], controller.signal)
+      ).rejects.toHaveProperty('name', 'AbortError');
+
+      expect(rateLimitingFetch).toHaveBeenCalledOnce();
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][1]?.signal).toBe(controller.signal);
+    });
+
+    it<RepositoryTestContext>('does not start when it has already been cancelled', async (context) => {
+      await expect(
+        getLatestFile(context.id, context.gameVersion, context.loader, [ReleaseType.RELEASE], AbortSignal.abort())
+      ).rejects.toHaveProperty('name', 'AbortError');
+
+      expect(rateLimitingFetch).not.toHaveBeenCalled();
+    });
+  });
+
+  describe('when the files response is too large', () => {
+    afterEach(() => {
+      setMaxResponseSize();
+    });
+
+    it<RepositoryTestContext>('refuses to read it', async (context) => {
+      setMaxResponseSize(64);
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce(
+        new Response(JSON.stringify({ data: [generateCurseforgeModFile().generated] }))
+      );
+
+      await expect(
+        getLatestFile(context.id, context.gameVersion, context.loader, [ReleaseType.RELEASE])
+      ).rejects.toThrow(ResponseTooLarge);
+    });
+  });
+
+  describe('when building the files url', () => {
+    afterEach(() => {
+      setBaseUrl(Platform.CURSEFORGE);
+    });
+
+    it('uses the configured base url', () => {
+      setBaseUrl(Platform.CURSEFORGE, 'https://proxy.example.com/v1/');
+
+      expect(curseforgeFilesUrl('123', '1.20.1', CurseforgeLoader.FABRIC, 0)).toMatchInlineSnapshot(
+        '"https://proxy.example.com/v1/mods/123/files?gameVersion=1.20.1&modLoaderType=4&index=0&pageSize=50"'
+      );
+    });
+
+    it('filters by game version and loader on the server', () => {
+      expect(curseforgeFilesUrl('123', '1.20.1', CurseforgeLoader.FABRIC, 0)).toMatchInlineSnapshot(
+        '"https://api.curseforge.com/v1/mods/123/files?gameVersion=1.20.1&modLoaderType=4&index=0&pageSize=50"'
+      );
+    });
+
+    it('includes the page index', () => {
+      expect(curseforgeFilesUrl('123', '1.19', CurseforgeLoader.NEOFORGE, 50)).toMatchInlineSnapshot(
+        '"https://api.curseforge.com/v1/mods/123/files?gameVersion=1.19&modLoaderType=6&index=50&pageSize=50"'
+      );
+    });
+
+    it('leaves out an empty game version', () => {
+      expect(curseforgeFilesUrl('123', '', CurseforgeLoader.FORGE, 0)).toMatchInlineSnapshot(
+        '"https://api.curseforge.com/v1/mods/123/files?modLoaderType=1&index=0&pageSize=50"'
+      );
+    });
+
+    it('leaves out the loader when any loader is fine', () => {
+      expect(curseforgeFilesUrl('123', '', CurseforgeLoader.ANY, 0)).toMatchInlineSnapshot(
+        '"https://api.curseforge.com/v1/mods/123/files?index=0&pageSize=50"'
+      );
+    });
+
+    it<RepositoryTestContext>('is used to fetch the files', async (context) => {
+      assumeFailedModFetch();
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: { name: chance.word() } })
+      } as Response);
+
+      await expect(
+        getMod(context.id, context.allowedReleaseTypes, context.gameVersion, Loader.QUILT, context.allowFallback)
+      ).rejects.toThrow(CouldNotFindModException);
+
+      expect(vi.mocked(rateLimitingFetch).mock.calls[1][0]).toEqual(
+        `https://api.curseforge.com/v1/mods/${context.id}/files?gameVersion=${context.gameVersion}&modLoaderType=5&index=0&pageSize=50`
+      );
+    });
+  });
+
+  describe('when fetching every file of a project', () => {
+    it('asks for the files without a game version or a loader', async () => {
+      const projectId = String(chance.integer({ min: 1 }));
+      const files = [
+        generateCurseforgeModFile({ fileDate: '2023-06-01T00:00:00Z' }).generated,
+        generateCurseforgeModFile({ fileDate: '2023-01-01T00:00:00Z' }).generated
+      ];
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: files })
+      } as Response);
+
+      const actual = await getProjectFiles(projectId);
+
+      expect(actual).toEqual(files);
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual(
+        `https://api.curseforge.com/v1/mods/${projectId}/files?index=0&pageSize=50`
+      );
+    });
+
+    it('returns the files the newest first whatever order the pages come in', async () => {
+      const fileOf = (id: number, fileDate: string) =>
+        generateCurseforgeModFile({ id: id, fileDate: fileDate }).generated;
+      const oldest = fileOf(1, '2022-01-01T00:00:00Z');
+      const older = fileOf(2, '2023-01-01T00:00:00.000Z');
+      const sameTimeLowerId = fileOf(3, '2023-06-01T00:00:00Z');
+      const sameTimeHigherId = fileOf(4, '2023-06-01T00:00:00Z');
+      const newest = fileOf(5, '2024-01-01T00:00:00Z');
+      const page = (data: CurseforgeModFile[], index: number) =>
+        ({
+          ok: true,
+          json: () =>
+            Promise.resolve({
+              data: data,
+              pagination: { index: index, pageSize: 50, resultCount: data.length, totalCount: 5 }
+            })
+        }) as Response;
+      vi.mocked(rateLimitingFetch)
+        .mockResolvedValueOnce(page([older, sameTimeLowerId, newest], 0))
+        .mockResolvedValueOnce(page([oldest, sameTimeHigherId], 3));
+
+      const actual = await getProjectFiles(String(chance.integer({ min: 1 })));
+
+      expect(actual).toEqual([newest, sameTimeHigherId, sameTimeLowerId, older, oldest]);
+    });
+
+    it('throws when the project does not exist', async () => {
+      const projectId = String(chance.integer({ min: 1 }));
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({ ok: false } as Response);
+
+      await expect(getProjectFiles(projectId)).rejects.toThrow(
+        new CouldNotFindModException(projectId, Platform.CURSEFORGE)
+      );
+    });
+  });
+
+  describe('when paging the files until they are enough', () => {
+    let time: number;
+
+    const file = () => generateCurseforgeModFile().generated;
+    const target = file();
+    const pages = [[file(), file()], [file(), target], [file()]];
+
+    const assumePagesTaking = (milliseconds: number) => {
+      let index = 0;
+      for (const page of pages) {
+        const pageIndex = index;
+        vi.mocked(rateLimitingFetch).mockImplementationOnce(async () => {
+          time += milliseconds;
+          return {
+            ok: true,
+            json: () =>
+              Promise.resolve({
+                data: page,
+                pagination: { index: pageIndex, pageSize: 2, resultCount: page.length, totalCount: 5 }
+              })
+          } as Response;
+        });
+        index += page.length;
+      }
+    };
+
+    beforeEach(() => {
+      time = 0;
+      setNow(() => time);
+    });
+
+    afterEach(() => {
+      setNow();
+    });
+
+    it('stops at the page that has the file it needs', async () => {
+      assumePagesTaking(100);
+
+      const actual = await getFilesUntil('1', '1.20.1', Loader.FABRIC, { enough: (files) => files.includes(target) });
+
+      expect(actual).toEqual([...pages[0], ...pages[1]]);
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(2);
+      expect(vi.mocked(rateLimitingFetch).mock.calls[1][0]).toContain('&index=2');
+    });
+
+    it('fetches every page when the files are never enough', async () => {
+      assumePagesTaking(100);
+
+      const actual = await getFilesUntil('1', '1.20.1', Loader.FABRIC, { enough: () => false });
+
+      expect(actual).toEqual(pages.flat());
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(3);
+    });
+
+    it('stops when the next page would arrive after the deadline', async () => {
+      assumePagesTaking(300);
+
+      const actual = await getFilesUntil('1', '1.20.1', Loader.FABRIC, { deadline: 500 });
+
+      expect(actual).toEqual(pages[0]);
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(1);
+    });
+
+    it('keeps paging while there is time left', async () => {
+      assumePagesTaking(300);
+
+      const actual = await getFilesUntil('1', '1.20.1', Loader.FABRIC, { deadline: 10000 });
+
+      expect(actual).toEqual(pages.flat());
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(3);
+    });
+
+    it('still fetches the first page when the deadline has passed', async () => {
+      time = 1000;
+      assumePagesTaking(300);
+
+      const actual = await getFilesUntil('1', '1.20.1', Loader.FABRIC, { deadline: 500 });
+
+      expect(actual).toEqual(pages[0]);
+    });
+  });
+
+  describe('when the file has a server pack', () => {
+    const assumeFileFetch = (file: CurseforgeModFile) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: file })
+      } as Response);
+    };
+
+    afterEach(() => {
+      setPreferServerPacks();
+    });
+
+    it('uses the regular files by default', () => {
+      expect(prefersServerPacks()).toBeFalsy();
+    });
+
+    it('picks the server pack when it is preferred', async () => {
+      const serverPack = generateCurseforgeModFile().generated;
+      const file = generateCurseforgeModFile({ serverPackFileId: serverPack.id }).generated;
+      assumeFileFetch(serverPack);
+
+      const actual = await selectFile('123', file, true);
+
+      expect(actual).toEqual(serverPack);
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual(
+        `https://api.curseforge.com/v1/mods/123/files/${serverPack.id}`
+      );
+    });
+
+    it('keeps the regular file when server packs are not preferred', async () => {
+      const file = generateCurseforgeModFile({ serverPackFileId: chance.integer({ min: 1 }) }).generated;
+
+      expect(await selectFile('123', file, false)).toBe(file);
+      expect(vi.mocked(rateLimitingFetch)).not.toHaveBeenCalled();
+    });
+
+    it.each([undefined, null, 0])('falls back to the regular file when the server pack id is %j', async (id) => {
+      const file = generateCurseforgeModFile({ serverPackFileId: id }).generated;
+
+      expect(await selectFile('123', file, true)).toBe(file);
+      expect(vi.mocked(rateLimitingFetch)).not.toHaveBeenCalled();
+    });
+
+    it('throws when the server pack cannot be fetched', async () => {
+      const file = generateCurseforgeModFile({ serverPackFileId: chance.integer({ min: 1 }) }).generated;
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({ ok: false } as Response);
+
+      await expect(selectFile('123', file, true)).rejects.toThrow(
+        new CouldNotFindModException('123', Platform.CURSEFORGE)
+      );
+    });
+
+    it<RepositoryTestContext>('resolves the mod to its server pack', async (context) => {
+      setPreferServerPacks(true);
+      const serverPack = generateCurseforgeModFile({ fileName: 'server-pack.zip' }).generated;
+      const file = generateCurseforgeModFile({
+        isAvailable: true,
+        fileStatus: releasedStatus,
+        releaseType: Release.RELEASE,
+        sortableGameVersions: [{ gameVersionName: context.gameVersion, gameVersion: context.gameVersion }],
+        serverPackFileId: serverPack.id
+      }).generated;
+      assumeSuccessfulModFetch(chance.word(), [file]);
+      assumeFileFetch(serverPack);
+
+      const actual = await getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, context.loader, false);
+
+      expect(actual.fileName).toEqual('server-pack.zip');
+      expect(actual.downloadUrl).toEqual(serverPack.downloadUrl);
+    });
+  });
+
+  describe('when looking at the dependencies of a file', () => {
+    it('only returns the required ones', () => {
+      const file = generateCurseforgeModFile({
+        dependencies: [
+          { modId: 306612, relationType: CurseforgeRelationType.REQUIRED_DEPENDENCY },
+          { modId: 308769, relationType: CurseforgeRelationType.OPTIONAL_DEPENDENCY },
+          { modId: 238222, relationType: CurseforgeRelationType.INCOMPATIBLE },
+          { modId: 348521, relationType: CurseforgeRelationType.REQUIRED_DEPENDENCY },
+          { modId: 419699, relationType: CurseforgeRelationType.EMBEDDED_LIBRARY }
+        ]
+      }).generated;
+
+      expect(requiredDependencies(file)).toEqual([
+        { modId: 306612, relationType: CurseforgeRelationType.REQUIRED_DEPENDENCY },
+        { modId: 348521, relationType: CurseforgeRelationType.REQUIRED_DEPENDENCY }
+      ]);
+    });
+
+    it('handles files without dependencies', () => {
+      const file = generateCurseforgeModFile().generated;
+
+      expect(requiredDependencies(file)).toEqual([]);
+    });
+
+    it('handles responses that leave the dependencies out', () => {
+      const file = generateCurseforgeModFile().generated;
+      // @ts-ignore
+      delete file.dependencies;
+
+      expect(requiredDependencies(file)).toEqual([]);
+    });
+
+    it('keeps the required ones in the mod details', () => {
+      const file = generateCurseforgeModFile({
+        dependencies: [
+          { modId: 306612, relationType: CurseforgeRelationType.REQUIRED_DEPENDENCY },
+          { modId: 308769, relationType: CurseforgeRelationType.OPTIONAL_DEPENDENCY }
+        ],
+        hashes: [{ algo: HashFunctions.sha1, value: chance.hash() }]
+      }).generated;
+
+      expect(curseforgeFileToRemoteModDetails(file, chance.word()).dependencies).toEqual([
+        { type: Platform.CURSEFORGE, id: '306612' }
+      ]);
+    });
+
+    it('leaves them out of the mod details when there are none', () => {
+      const file = generateCurseforgeModFile({
+        hashes: [{ algo: HashFunctions.sha1, value: chance.hash() }]
+      }).generated;
+
+      expect(curseforgeFileToRemoteModDetails(file, chance.word())).not.toHaveProperty('dependencies');
+    });
+
+    it('keeps the incompatible ones in the mod details', () => {
+      const file = generateCurseforgeModFile({
+        dependencies: [
+          { modId: 306612, relationType: CurseforgeRelationType.REQUIRED_DEPENDENCY },
+          { modId: 455508, relationType: CurseforgeRelationType.INCOMPATIBLE }
+        ],
+        hashes: [{ algo: HashFunctions.sha1, value: chance.hash() }]
+      }).generated;
+
+      expect(curseforgeFileToRemoteModDetails(file, chance.word()).incompatibilities).toEqual([
+        { type: Platform.CURSEFORGE, id: '455508' }
+      ]);
+    });
+  });
+
+  describe('when fetching the details of many mods', () => {
+    const generateProject = (id: number) => ({
+      id: id,
+      name: chance.word(),
+      slug: chance.word(),
+      summary: chance.sentence(),
+      logo: null
+    });
+
+    const assumeProjects = (projects: ReturnType<typeof generateProject>[]) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: projects })
+      } as Response);
+    };
+
+    const requestedIds = (call: number) =>
+      JSON.parse(vi.mocked(rateLimitingFetch).mock.calls[call][1]?.body as string).modIds;
+
+    it('asks for every project in a single request', async () => {
+      const projects = [generateProject(1), generateProject(2)];
+      assumeProjects(projects);
+
+      const actual = await getModsInfo(['1', '2']);
+
+      expect(actual).toEqual(projects);
+      expect(rateLimitingFetch).toHaveBeenCalledOnce();
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual('https://api.curseforge.com/v1/mods');
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][1]).toMatchObject({ method: 'POST' });
+      expect(requestedIds(0)).toEqual([1, 2]);
+    });
+
+    it('asks for a hundred projects at a time by default', () => {
+      expect(MOD_INFO_CHUNK_SIZE).toEqual(100);
+    });
+
+    it('splits the projects into batches and merges the results', async () => {
+      const projects = [1, 2, 3, 4, 5].map(generateProject);
+      assumeProjects(projects.slice(0, 2));
+      assumeProjects(projects.slice(2, 4));
+      assumeProjects(projects.slice(4));
+
+      const actual = await getModsInfo(['1', '2', '3', '4', '5'], 2);
+
+      expect(actual).toEqual(projects);
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(3);
+      expect([requestedIds(0), requestedIds(1), requestedIds(2)]).toEqual([[1, 2], [3, 4], [5]]);
+    });
+
+    it('returns the projects in the order they were asked for', async () => {
+      const projects = [generateProject(1), generateProject(2)];
+      assumeProjects([projects[1], projects[0]]);
+
+      const actual = await getModsInfo(['1', '2']);
+
+      expect(actual).toEqual(projects);
+    });
+
+    it('asks for a project once', async () => {
+      const project = generateProject(1);
+      assumeProjects([project]);
+
+      const actual = await getModsInfo(['1', '1']);
+
+      expect(actual).toEqual([project]);
+      expect(requestedIds(0)).toEqual([1]);
+    });
+
+    it('leaves out the projects curseforge does not know', async () => {
+      const project = generateProject(1);
+      assumeProjects([project]);
+
+      const actual = await getModsInfo(['1', '2']);
+
+      expect(actual).toEqual([project]);
+    });
+
+    it('handles a response without projects', async () => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({})
+      } as Response);
+
+      expect(await getModsInfo(['1'])).toEqual([]);
+    });
+
+    it('does not ask for anything without projects', async () => {
+      expect(await getModsInfo([])).toEqual([]);
+      expect(rateLimitingFetch).not.toHaveBeenCalled();
+    });
+
+    it('throws when a batch cannot be fetched', async () => {
+      assumeProjects([generateProject(1)]);
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({ ok: false } as Response);
+
+      await expect(getModsInfo(['1', '2', '3'], 1)).rejects.toThrow(
+        new CouldNotFindModException('2', Platform.CURSEFORGE)
+      );
+    });
+
+    it('stops between the batches when cancelled', async () => {
+      const controller = new AbortController();
+      vi.mocked(rateLimitingFetch).mockImplementationOnce(async () => {
+        controller.abort();
+        return { ok: true, json: () => Promise.resolve({ data: [generateProject(1)] }) } as Response;
+      });
+
+      await expect(getModsInfo(['1', '2'], 1, controller.signal)).rejects.toHaveProperty('name', 'AbortError');
+      expect(rateLimitingFetch).toHaveBeenCalledOnce();
+    });
+  });
+
+  describe('when fetching the details of a mod', () => {
+    it<RepositoryTestContext>('returns the project information', async (context) => {
+      const project = {
+        id: chance.integer({ min: 1, max: 999999 }),
+        name: chance.word(),
+        slug: chance.word(),
+        summary: chance.sentence(),
+        logo: {
+          url: chance.url(),
+          thumbnailUrl: chance.url()
+        },
+        downloadCount: chance.integer({ min: 1, max: 999999 })
+      };
+
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: project })
+      } as Response);
+
+      const actual = await getModInfo(context.id);
+
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual(`https://api.curseforge.com/v1/mods/${context.id}`);
+      expect(actual).toEqual({
+        id: project.id,
+        name: project.name,
+        slug: project.slug,
+        summary: project.summary,
+        logo: project.logo
+      });
+    });
+
+    it<RepositoryTestContext>('handles projects without a logo', async (context) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: { id: 1, name: chance.word(), slug: chance.word(), summary: '' } })
+      } as Response);
+
+      const actual = await getModInfo(context.id);
+
+      expect(actual.logo).toBeNull();
+    });
+
+    it<RepositoryTestContext>('throws when the project does not exist', async (context) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: false,
+        status: 404
+      } as Response);
+
+      await expect(getModInfo(context.id)).rejects.toThrow(new CouldNotFindModException(context.id, context.platform));
+    });
+
+    it<RepositoryTestContext>('throws when the response has no project in it', async (context) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ error: chance.sentence() })
+      } as Response);
+
+      await expect(getModInfo(context.id)).rejects.toThrow(new CouldNotFindModException(context.id, context.platform));
+    });
+
+    it<RepositoryTestContext>('throws when the response is empty', async (context) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve(null)
+      } as Response);
+
+      await expect(getModInfo(context.id)).rejects.toThrow(new CouldNotFindModException(context.id, context.platform));
+    });
+
+    it<RepositoryTestContext>('throws when the project has no name', async (context) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: { id: 1 } })
+      } as Response);
+
+      await expect(getModInfo(context.id)).rejects.toThrow(new CouldNotFindModException(context.id, context.platform));
+    });
+
+    it<RepositoryTestContext>('throws when the response is not valid json', async (context) => {
+      const error = new SyntaxError('Unexpected token < in JSON at position 0');
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.reject(error)
+      } as unknown as Response);
+
+      await expect(getModInfo(context.id)).rejects.toThrow(error);
+    });
+
+    it<RepositoryTestContext>('passes the signal to the request', async (context) => {
+      const controller = new AbortController();
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: { id: 1, name: chance.word(), slug: chance.word(), summary: '' } })
+      } as Response);
+
+      await getModInfo(context.id, controller.signal);
+
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][1]?.signal).toBe(controller.signal);
+    });
+
+    describe('and the response is too large', () => {
+      afterEach(() => {
+        setMaxResponseSize();
+      });
+
+      it<RepositoryTestContext>('refuses to read it', async (context) => {
+        setMaxResponseSize(16);
+        vi.mocked(rateLimitingFetch).mockResolvedValueOnce(
+          new Response(JSON.stringify({ data: { id: 1, name: chance.word(), slug: chance.word(), summary: '' } }))
+        );
+
+        await expect(getModInfo(context.id)).rejects.toThrow(ResponseTooLarge);
+      });
+    });
+  });
+
+  describe('when looking for the latest file', () => {
+    const assumeFiles = (files: CurseforgeModFile[]) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: files })
+      } as Response);
+    };
+
+    const compatibleFile = (gameVersion: string, releaseType: Release, fileDate: string) =>
+      generateCurseforgeModFile({
+        isAvailable: true,
+        fileStatus: releasedStatus,
+        releaseType: releaseType,
+        fileDate: fileDate,
+        sortableGameVersions: [
+          {
+            gameVersionName: gameVersion,
+            gameVersion: gameVersion
+          }
+        ]
+      }).generated;
+
+    it<RepositoryTestContext>('returns the newest compatible file', async (context) => {
+      const oldest = compatibleFile(context.gameVersion, Release.RELEASE, '2019-01-01T00:00:00Z');
+      const newest = compatibleFile(context.gameVersion, Release.RELEASE, '2021-01-01T00:00:00Z');
+      const middle = compatibleFile(context.gameVersion, Release.RELEASE, '2020-01-01T00:00:00Z');
+      assumeFiles([oldest, newest, middle]);
+
+      const actual = await getLatestFile(context.id, context.gameVersion, context.loader, [ReleaseType.RELEASE]);
+
+      expect(actual).toBe(newest);
+    });
+
+    it<RepositoryTestContext>('skips the release types that are not allowed', async (context) => {
+      const release = compatibleFile(context.gameVersion, Release.RELEASE, '2019-01-01T00:00:00Z');
+      const beta = compatibleFile(context.gameVersion, Release.BETA, '2020-01-01T00:00:00Z');
+      const alpha = compatibleFile(context.gameVersion, Release.ALPHA, '2021-01-01T00:00:00Z');
+      assumeFiles([release, beta, alpha]);
+
+      const actual = await getLatestFile(context.id, context.gameVersion, context.loader, [
+        ReleaseType.RELEASE,
+        ReleaseType.BETA
+      ]);
+
+      expect(actual).toBe(beta);
+    });
+
+    it<RepositoryTestContext>('skips the files of other game versions', async (context) => {
+      const compatible = compatibleFile(context.gameVersion, Release.RELEASE, '2019-01-01T00:00:00Z');
+      const otherVersion = compatibleFile('0.0.1', Release.RELEASE, '2021-01-01T00:00:00Z');
+      assumeFiles([compatible, otherVersion]);
+
+      const actual = await getLatestFile(context.id, context.gameVersion, context.loader, [ReleaseType.RELEASE]);
+
+      expect(actual).toBe(compatible);
+    });
+
+    it.each([
+      [ReleaseChannel.RELEASE, 'release'],
+      [ReleaseChannel.BETA, 'beta'],
+      [ReleaseChannel.ALPHA, 'alpha']
+    ])('picks the newest file on the %s channel', async (channel, expectedFile) => {
+      const gameVersion = '1.20.1';
+      const files = {
+        release: compatibleFile(gameVersion, Release.RELEASE, '2019-01-01T00:00:00Z'),
+        beta: compatibleFile(gameVersion, Release.BETA, '2020-01-01T00:00:00Z'),
+        alpha: compatibleFile(gameVersion, Release.ALPHA, '2021-01-01T00:00:00Z')
+      };
+      assumeFiles([files.alpha, files.release, files.beta]);
+
+      const actual = await getLatestFile(chance.word(), gameVersion, Loader.FABRIC, releaseTypesForChannel(channel));
+
+      expect(actual).toBe(files[expectedFile as keyof typeof files]);
+    });
+
+    it<RepositoryTestContext>('throws when nothing is compatible', async (context) => {
+      assumeFiles([compatibleFile(context.gameVersion, Release.ALPHA, '2021-01-01T00:00:00Z')]);
+
+      await expect(
+        getLatestFile(context.id, context.gameVersion, context.loader, [ReleaseType.RELEASE])
+      ).rejects.toThrow(new NoRemoteFileFound(context.id, context.platform));
+    });
+
+    describe('and the files are tagged with a broader game version', () => {
+      const taggedFile = (...tags: string[]) =>
+        generateCurseforgeModFile({
+          isAvailable: true,
+          fileStatus: releasedStatus,
+          releaseType: Release.RELEASE,
+          sortableGameVersions: tags.map((tag) => ({ gameVersionName: tag, gameVersion: tag }))
+        }).generated;
+
+      beforeEach(() => {
+        setStrictGameVersionMatching();
+      });
+
+      it<RepositoryTestContext>('ignores the loader names next to the game version', async (context) => {
+        const file = taggedFile('1.20.1', 'Forge');
+        assumeFiles([file]);
+
+        const actual = await getLatestFile(context.id, '1.20.1', Loader.FORGE, [ReleaseType.RELEASE]);
+
+        expect(actual).toBe(file);
+        expect(vi.mocked(rateLimitingFetch)).toHaveBeenCalledOnce();
+      });
+
+      it<RepositoryTestContext>('accepts a file tagged with the minor version', async (context) => {
+        const file = taggedFile('1.20', 'Forge');
+        assumeFiles([]);
+        assumeFiles([file]);
+
+        const actual = await getLatestFile(context.id, '1.20.1', Loader.FORGE, [ReleaseType.RELEASE]);
+
+        expect(actual).toBe(file);
+        expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toContain('gameVersion=1.20.1&');
+        expect(vi.mocked(rateLimitingFetch).mock.calls[1][0]).toContain('gameVersion=1.20&');
+      });
+
+      it<RepositoryTestContext>('rejects a file tagged with the minor version when strict', async (context) => {
+        setStrictGameVersionMatching(true);
+        assumeFiles([taggedFile('1.20', 'Forge')]);
+
+        await expect(getLatestFile(context.id, '1.20.1', Loader.FORGE, [ReleaseType.RELEASE])).rejects.toThrow(
+          new NoRemoteFileFound(context.id, context.platform)
+        );
+        const requests = vi.mocked(rateLimitingFetch).mock.calls.map(([url]) => String(url));
+        expect(requests.filter((url) => url.includes('gameVersion='))).toHaveLength(1);
+      });
+
+      it<RepositoryTestContext>('rejects a snapshot file for a release', async (context) => {
+        assumeFiles([taggedFile('1.20.1-pre1', 'Forge')]);
+        assumeFiles([taggedFile('23w31a', 'Forge')]);
+
+        await expect(getLatestFile(context.id, '1.20.1', Loader.FORGE, [ReleaseType.RELEASE])).rejects.toThrow(
+          new NoRemoteFileFound(context.id, context.platform)
+        );
+      });
+    });
+
+    describe('and the loader can use the files of another loader', () => {
+      beforeEach(() => {
+        setStrictLoaderMatching();
+      });
+
+      it<RepositoryTestContext>('accepts a Fabric file for Quilt', async (context) => {
+        const fabricFile = compatibleFile(context.gameVersion, Release.RELEASE, '2021-01-01T00:00:00Z');
+        assumeFiles([]);
+        assumeFiles([fabricFile]);
+
+        const actual = await getLatestFile(context.id, context.gameVersion, Loader.QUILT, [ReleaseType.RELEASE]);
+
+        expect(actual).toBe(fabricFile);
+        expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toContain(`modLoaderType=${CurseforgeLoader.QUILT}`);
+        expect(vi.mocked(rateLimitingFetch).mock.calls[1][0]).toContain(`modLoaderType=${CurseforgeLoader.FABRIC}`);
+      });
+
+      it<RepositoryTestContext>('prefers the files made for Quilt', async (context) => {
+        const quiltFile = compatibleFile(context.gameVersion, Release.RELEASE, '2020-01-01T00:00:00Z');
+        assumeFiles([quiltFile]);
+
+        const actual = await getLatestFile(context.id, context.gameVersion, Loader.QUILT, [ReleaseType.RELEASE]);
+
+        expect(actual).toBe(quiltFile);
+        expect(vi.mocked(rateLimitingFetch)).toHaveBeenCalledOnce();
+      });
+
+      it<RepositoryTestContext>('rejects a Fabric file for Quilt when strict', async (context) => {
+        setStrictLoaderMatching(true);
+        assumeFiles([]);
+
+        await expect(
+          getLatestFile(context.id, context.gameVersion, Loader.QUILT, [ReleaseType.RELEASE])
+        ).rejects.toThrow(new NoRemoteFileFound(context.id, context.platform));
+        const requests = vi.mocked(rateLimitingFetch).mock.calls.map(([url]) => String(url));
+        expect(requests.filter((url) => url.includes('modLoaderType='))).toHaveLength(1);
+      });
+
+      it<RepositoryTestContext>('throws when none of the loaders have a file', async (context) => {
+        assumeFiles([]);
+        assumeFiles([]);
+
+        await expect(
+          getLatestFile(context.id, context.gameVersion, Loader.QUILT, [ReleaseType.RELEASE])
+        ).rejects.toThrow(new NoRemoteFileFound(context.id, context.platform));
+      });
+
+      it<RepositoryTestContext>('uses the Fabric file of a fixed version for Quilt', async (context) => {
+        const fabricFile = compatibleFile(context.gameVersion, Release.RELEASE, '2021-01-01T00:00:00Z');
+        assumeSuccessfulModFetch(chance.word(), []);
+        assumeFiles([fabricFile]);
+
+        const actual = await getMod(
+          context.id,
+          [ReleaseType.RELEASE],
+          context.gameVersion,
+          Loader.QUILT,
+          false,
+          fabricFile.fileName
+        );
+
+        expect(actual.fileName).toEqual(fabricFile.fileName);
+      });
+    });
+
+    describe('and none of the files fit', () => {
+      const taggedFile = (...tags: string[]) =>
+        generateCurseforgeModFile({
+          sortableGameVersions: tags.map((tag) => ({ gameVersionName: tag, gameVersion: tag }))
+        }).generated;
+
+      const assumeProjectFiles = (files: CurseforgeModFile[] | Error) => {
+        vi.mocked(rateLimitingFetch).mockImplementation(async (url) => {
+          if (String(url).includes('gameVersion=')) {
+            return { ok: true, json: () => Promise.resolve({ data: [] }) } as Response;
+          }
+          if (files instanceof Error) {
+            throw files;
+          }
+          return { ok: true, json: () => Promise.resolve({ data: files }) } as Response;
+        });
+      };
+
+      it<RepositoryTestContext>('tells what the files of the mod are made for', async (context) => {
+        assumeProjectFiles([
+          taggedFile('1.19.2', 'Fabric'),
+          taggedFile('1.20.4', 'Fabric', 'Quilt'),
+          taggedFile('1.20.1', 'NeoForge')
+        ]);
+
+        const error = await getLatestFile(context.id, '1.21', Loader.FORGE, [ReleaseType.RELEASE]).catch((e) => e);
+
+        expect(error).toBeInstanceOf(NoRemoteFileFound);
+        expect(error.supported).toEqual({
+          gameVersions: ['1.20.4', '1.20.1', '1.19.2'],
+          loaders: ['fabric', 'neoforge', 'quilt']
+        });
+        expect(error.message).toEqual(
+          `No compatible files were found for the given mod: curseforge: ${context.id}. It has files for the game ` +
+            'versions 1.20.4, 1.20.1, 1.19.2 and the loaders fabric, neoforge, quilt.'
+        );
+      });
+
+      it<RepositoryTestContext>('leaves it out when the files cannot be listed', async (context) => {
+        assumeProjectFiles(new Error('offline'));
+
+        const error = await getLatestFile(context.id, '1.21', Loader.FORGE, [ReleaseType.RELEASE]).catch((e) => e);
+
+        expect(error).toBeInstanceOf(NoRemoteFileFound);
+        expect(error.supported).toBeUndefined();
+      });
+    });
+  });
+
+  describe('when reading the hashes of a file', () => {
+    it('returns both the sha1 and the md5 hash', () => {
+      const sha1 = chance.hash({ length: 40 });
+      const md5 = chance.hash({ length: 32 });
+      const file = generateCurseforgeModFile({
+        hashes: [
+          { algo: HashFunctions.md5, value: md5 },
+          { algo: HashFunctions.sha1, value: sha1 }
+        ]
+      }).generated;
+
+      expect(sha1Hash(file)).toEqual(sha1);
+      expect(md5Hash(file)).toEqual(md5);
+    });
+
+    it('returns an empty string for missing hashes', () => {
+      const file = generateCurseforgeModFile({ hashes: [] }).generated;
+
+      expect(sha1Hash(file)).toEqual('');
+      expect(md5Hash(file)).toEqual('');
+    });
+
+    it('handles files without a hash list', () => {
+      const file = generateCurseforgeModFile().generated;
+      // @ts-ignore
+      delete file.hashes;
+
+      expect(sha1Hash(file)).toEqual('');
+      expect(md5Hash(file)).toEqual('');
+    });
+  });
+
+  describe('when logging the selection', () => {
+    let logger: RecordingApiLogger;
+
+    const releasedFile = (gameVersion: string) =>
+      generateCurseforgeModFile({
+        isAvailable: true,
+        fileStatus: releasedStatus,
+        releaseType: Release.RELEASE,
+        sortableGameVersions: [
+          {
+            gameVersionName: gameVersion,
+            gameVersion: gameVersion
+          }
+        ]
+      }).generated;
+
+    beforeEach(() => {
+      logger = recordingApiLogger();
+      setApiLogger(logger);
+    });
+
+    afterEach(() => {
+      setApiLogger();
+    });
+
+    it<RepositoryTestContext>('logs the selected file', async (context) => {
+      const file = releasedFile(context.gameVersion);
+      assumeSuccessfulModFetch(chance.word(), [file]);
+
+      await getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, context.loader, false);
+
+      expect(logger.entries).toEqual([
+        {
+          level: 'debug',
+          message: 'selected file',
+          fields: { platform: Platform.CURSEFORGE, projectId: context.id, fileName: file.fileName, candidates: 1 }
+        }
+      ]);
+    });
+
+    it<RepositoryTestContext>('logs the game versions it falls back to', async (context) => {
+      assumeSuccessfulModFetch(chance.word(), []);
+      assumeSuccessfulModFetch(chance.word(), []);
+
+      await expect(getMod(context.id, [ReleaseType.RELEASE], '1.19.1', context.loader, true)).rejects.toThrow(
+        NoRemoteFileFound
+      );
+
+      expect(logger.entries).toEqual([
+        {
+          level: 'debug',
+          message: 'trying an older game version',
+          fields: {
+            platform: Platform.CURSEFORGE,
+            projectId: context.id,
+            gameVersion: '1.19.1',
+            nextGameVersion: '1.19'
+          }
+        },
+        {
+          level: 'debug',
+          message: 'no suitable file',
+          fields: { platform: Platform.CURSEFORGE, projectId: context.id, gameVersion: '1.19', loader: context.loader }
+        }
+      ]);
+    });
+
+    it<RepositoryTestContext>('logs the latest file', async (context) => {
+      const file = releasedFile(context.gameVersion);
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: [file] })
+      } as Response);
+
+      await getLatestFile(context.id, context.gameVersion, context.loader, [ReleaseType.RELEASE]);
+
+      expect(logger.entries).toEqual([
+        {
+          level: 'debug',
+          message: 'selected file',
+          fields: { platform: Platform.CURSEFORGE, projectId: context.id, fileName: file.fileName, candidates: 1 }
+        }
+      ]);
+    });
+
+    it<RepositoryTestContext>('logs when there is no latest file', async (context) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: [] })
+      } as Response);
+
+      await expect(
+        getLatestFile(context.id, context.gameVersion, context.loader, [ReleaseType.RELEASE])
+      ).rejects.toThrow(NoRemoteFileFound);
+
+      expect(logger.entries).toEqual([
+        {
+          level: 'debug',
+          message: 'no suitable file',
+          fields: {
+            platform: Platform.CURSEFORGE,
+            projectId: context.id,
+            gameVersion: context.gameVersion,
+            loader: context.loader
+          }
+        }
+      ]);
+    });
+
+    it('logs the switch to the server pack', async () => {
+      const serverPack = generateCurseforgeModFile().generated;
+      const file = generateCurseforgeModFile({ serverPackFileId: serverPack.id }).generated;
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: serverPack })
+      } as Response);
+
+      await selectFile('123', file, true);
+
+      expect(logger.entries).toEqual([
+        {
+          level: 'debug',
+          message: 'using the server pack',
+          fields: { projectId: '123', fileId: file.id, serverPackFileId: serverPack.id }
+        }
+      ]);
+    });
+  });
+
+  describe('when the mod has file overrides', () => {
+    const suitableFile = (gameVersion: string, fileName: string, fileDate: string) =>
+      generateCurseforgeModFile({
+        fileName: fileName,
+        fileDate: fileDate,
+        isAvailable: true,
+        fileStatus: releasedStatus,
+        releaseType: Release.RELEASE,
+        sortableGameVersions: [{ gameVersionName: gameVersion, gameVersion: gameVersion }]
+      }).generated;
+
+    const getModWith = (context: RepositoryTestContext, overrides: FileOverrides) =>
+      getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, context.loader, false, undefined, overrides);
+
+    const assumeForcedFileFetch = (modName: string, file: CurseforgeModFile) => {
+      vi.mocked(rateLimitingFetch)
+        .mockResolvedValueOnce({ ok: true, json: () => Promise.resolve({ data: { name: modName } }) } as Response)
+        .mockResolvedValueOnce({ ok: true, json: () => Promise.resolve({ data: file }) } as Response);
+    };
+
+    it<RepositoryTestContext>('picks the newest file that is not excluded', async (context) => {
+      const combined = suitableFile(context.gameVersion, 'mod-forge-fabric-2.0.jar', '2023-02-01T00:00:00Z');
+      const fabricOnly = suitableFile(context.gameVersion, 'mod-fabric-1.9.jar', '2023-01-01T00:00:00Z');
+      assumeSuccessfulModFetch(chance.word(), [combined, fabricOnly]);
+
+      const actual = await getModWith(context, { excludeFileNamePattern: 'FORGE' });
+
+      expect(actual.fileName).toEqual(fabricOnly.fileName);
+    });
+
+    it<RepositoryTestContext>('picks the newest file without a pattern', async (context) => {
+      const combined = suitableFile(context.gameVersion, 'mod-forge-fabric-2.0.jar', '2023-02-01T00:00:00Z');
+      const fabricOnly = suitableFile(context.gameVersion, 'mod-fabric-1.9.jar', '2023-01-01T00:00:00Z');
+      assumeSuccessfulModFetch(chance.word(), [fabricOnly, combined]);
+
+      const actual = await getModWith(context, {});
+
+      expect(actual.fileName).toEqual(combined.fileName);
+    });
+
+    it<RepositoryTestContext>('does not fall back to an excluded file', async (context) => {
+      const randomName = chance.word();
+      const excluded = suitableFile(context.gameVersion, 'mod-forge.jar', '2023-01-01T00:00:00Z');
+      assumeSuccessfulModFetch(randomName, [excluded]);
+
+      await expect(getModWith(context, { excludeFileNamePattern: 'forge' })).rejects.toThrow(
+        new NoRemoteFileFound(randomName, Platform.CURSEFORGE)
+      );
+    });
+
+    it<RepositoryTestContext>('keeps excluding the files for the older game versions', async (context) => {
+      const randomName = chance.word();
+      const olderFile = suitableFile('1.19.1', 'mod-forge.jar', '2023-01-01T00:00:00Z');
+      vi.mocked(rateLimitingFetch).mockImplementation(async (url) => {
+        const data = String(url).includes('/files') ? [olderFile] : { name: randomName };
+        return { ok: true, json: () => Promise.resolve({ data: data }) } as Response;
+      });
+
+      await expect(
+        getMod(context.id, [ReleaseType.RELEASE], '1.19.2', context.loader, true, undefined, {
+          excludeFileNamePattern: 'forge'
+        })
+      ).rejects.toThrow(
+        new NoRemoteFileFound(randomName, Platform.CURSEFORGE, { gameVersions: ['1.19.1'], loaders: [] })
+      );
+    });
+
+    it<RepositoryTestContext>('uses the forced file whatever the default selection would pick', async (context) => {
+      const randomName = chance.word();
+      const forced = generateCurseforgeModFile({ releaseType: Release.ALPHA, isAvailable: false }).generated;
+      assumeForcedFileFetch(randomName, forced);
+
+      const actual = await getModWith(
+        { ...context, id: '123' },
+        { forceFileId: String(forced.id), excludeFileNamePattern: '.' }
+      );
+
+      expect(actual).toEqual(curseforgeFileToRemoteModDetails(forced, randomName));
+      expect(vi.mocked(rateLimitingFetch).mock.calls[1][0]).toEqual(
+        `https://api.curseforge.com/v1/mods/123/files/${forced.id}`
+      );
+      expect(vi.mocked(rateLimitingFetch)).toHaveBeenCalledTimes(2);
+    });
+
+    it<RepositoryTestContext>('cannot use a forced file without a download url', async (context) => {
+      const randomName = chance.word();
+      // @ts-ignore
+      const forced = generateCurseforgeModFile({ downloadUrl: null }).generated;
+      assumeForcedFileFetch(randomName, forced);
+
+      await expect(getModWith(context, { forceFileId: String(forced.id) })).rejects.toThrow(
+        new CurseforgeDownloadUrlError(randomName)
+      );
+    });
+
+    it<RepositoryTestContext>('throws when the forced file does not exist', async (context) => {
+      assumeFailedModFetch();
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: { name: chance.word() } })
+      } as Response);
+
+      await expect(getModWith(context, { forceFileId: '404' })).rejects.toThrow(
+        new CouldNotFindModException(context.id, Platform.CURSEFORGE)
+      );
+    });
+  });
+
+  describe('when the file has additional files', () => {
+    const resourcePack = generateCurseforgeModFile({ id: 111, fileName: 'textures.zip' }).generated;
+    const shaders = generateCurseforgeModFile({ id: 222, fileName: 'shaders.zip' }).generated;
+
+    const fileWithAdditionalFiles = (gameVersion: string) =>
+      generateCurseforgeModFile({
+        isAvailable: true,
+        fileStatus: releasedStatus,
+        releaseType: Release.RELEASE,
+        sortableGameVersions: [{ gameVersionName: gameVersion, gameVersion: gameVersion }],
+        alternateFileId: 333,
+        additionalFileIds: [111, 222, 111]
+      }).generated;
+
+    const assumeFiles = (modName: string, file: CurseforgeModFile, attached: CurseforgeModFile[]) => {
+      vi.mocked(rateLimitingFetch).mockImplementation(async (url) => {
+        const fileId = String(url).match(/\/files\/(\d+)$/)?.[1];
+        if (fileId) {
+          const data = attached.find((a) => a.id === Number(fileId));
+          return { ok: true, json: () => Promise.resolve({ data: data }) } as Response;
+        }
+        const data = String(url).includes('/files') ? [file] : { name: modName };
+        return { ok: true, json: () => Promise.resolve({ data: data }) } as Response;
+      });
+    };
+
+    const getModWithAdditionalFiles = (context: RepositoryTestContext) =>
+      getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, context.loader, false, undefined, {
+        installAdditionalFiles: true
+      });
+
+    it<RepositoryTestContext>('leaves them out unless they are asked for', async (context) => {
+      const file = fileWithAdditionalFiles(context.gameVersion);
+      assumeFiles(chance.word(), file, [resourcePack, shaders]);
+
+      const actual = await getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, context.loader, false);
+
+      expect(actual.additionalFiles).toBeUndefined();
+      expect(vi.mocked(rateLimitingFetch)).toHaveBeenCalledTimes(2);
+    });
+
+    it<RepositoryTestContext>('resolves each of them once', async (context) => {
+      const file = fileWithAdditionalFiles(context.gameVersion);
+      assumeFiles(chance.word(), file, [resourcePack, shaders]);
+
+      const actual = await getModWithAdditionalFiles(context);
+
+      expect(actual.additionalFiles).toEqual([
+        { fileName: 'textures.zip', hash: sha1Hash(resourcePack), downloadUrl: resourcePack.downloadUrl },
+        { fileName: 'shaders.zip', hash: sha1Hash(shaders), downloadUrl: shaders.downloadUrl }
+      ]);
+      const requested = vi.mocked(rateLimitingFetch).mock.calls.map((call) => String(call[0]));
+      expect(requested.filter((url) => url.endsWith('/files/111'))).toHaveLength(1);
+      expect(requested.some((url) => url.endsWith('/files/333'))).toBe(false);
+    });
+
+    it<RepositoryTestContext>('cannot install an additional file without a download url', async (context) => {
+      const randomName = chance.word();
+      const file = fileWithAdditionalFiles(context.gameVersion);
+      // @ts-ignore
+      assumeFiles(randomName, file, [resourcePack, { ...shaders, downloadUrl: null }]);
+
+      await expect(getModWithAdditionalFiles(context)).rejects.toThrow(new CurseforgeDownloadUrlError(randomName));
+    });
+
+    it<RepositoryTestContext>('has nothing to fetch for a file without additional files', async (context) => {
+      const file = generateCurseforgeModFile().generated;
+      delete file.additionalFileIds;
+
+      expect(await getAdditionalFiles(context.id, file)).toEqual([]);
+      expect(vi.mocked(rateLimitingFetch)).not.toHaveBeenCalled();
+    });
+  });
+
+  describe('when describing a file the way both platforms do', () => {
+    it('keeps what the other platforms know about a file', () => {
+      const file = generateCurseforgeModFile({
+        releaseType: Release.BETA,
+        fileLength: 4096,
+        sortableGameVersions: [
+          { gameVersionName: '1.20.1', gameVersion: '1.20.1' },
+          { gameVersionName: 'Fabric', gameVersion: '', gameVersionTypeId: CurseforgeGameVersionType.LOADER },
+          { gameVersionName: 'Client', gameVersion: '', gameVersionTypeId: CurseforgeGameVersionType.ENVIRONMENT }
+        ]
+      }).generated;
+
+      expect(curseforgeFileToReleasedFile(file)).toEqual({
+        platform: Platform.CURSEFORGE,
+        id: String(file.id),
+        displayName: file.displayName,
+        fileName: file.fileName,
+        gameVersions: ['1.20.1'],
+        loaders: ['fabric'],
+        releaseType: ReleaseType.BETA,
+        downloadUrl: file.downloadUrl,
+        sha1: sha1Hash(file),
+        length: 4096,
+        releaseDate: file.fileDate
+      });
+    });
+
+    it('has an empty hash when Curseforge sends none', () => {
+      const file = generateCurseforgeModFile({ releaseType: Release.RELEASE, hashes: [] }).generated;
+
+      expect(curseforgeFileToReleasedFile(file).sha1).toEqual('');
+    });
+
+    it('throws on a release type it does not know', () => {
+      const file = generateCurseforgeModFile({ releaseType: 42 }).generated;
+
+      expect(() => curseforgeFileToReleasedFile(file)).toThrow(new InvalidReleaseTypeException(42));
+    });
+  });
 });
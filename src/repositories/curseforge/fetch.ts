@@ -1,12 +1,24 @@
-import { curseForgeApiKey } from '../../env.js';
 import { CouldNotFindModException } from '../../errors/CouldNotFindModException.js';
 import { CurseforgeDownloadUrlError } from '../../errors/CurseforgeDownloadUrlError.js';
-import { NoRemoteFileFound } from '../../errors/NoRemoteFileFound.js';
+import { CurseforgePaginationError } from '../../errors/CurseforgePaginationError.js';
+import { InvalidPageSizeException } from '../../errors/InvalidPageSizeException.js';
+import { NoRemoteFileFound, SupportedTargets } from '../../errors/NoRemoteFileFound.js';
+import { getApiLogger } from '../../lib/apiLogger.js';
+import { apiUrl } from '../../lib/baseUrl.js';
+import { chunk } from '../../lib/chunk.js';
 import { getNextVersionDown } from '../../lib/fallbackVersion.js';
-import { Loader, Platform, ReleaseType, RemoteModDetails } from '../../lib/modlist.types.js';
+import { isExcludedFileName } from '../../lib/fileOverrides.js';
+import { gameVersionMatches, gameVersionsToRequest } from '../../lib/gameVersionMatcher.js';
+import { compatibleLoaders } from '../../lib/loaderCompatibility.js';
+import { FileOverrides, Loader, Platform, ReleaseType, RemoteModDetails } from '../../lib/modlist.types.js';
+import { now } from '../../lib/rateLimiter/clock.js';
 import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { readJson } from '../../lib/rateLimiter/readJson.js';
+import { supportedTargetsOf } from '../../lib/supportedTargets.js';
+import { ReleasedFile } from '../index.js';
 import { InvalidReleaseTypeException } from './InvalidReleaseTypeException.js';
-import { Curseforge } from './index.js';
+import { CurseforgeGameVersion, loaders, minecraftVersions } from './gameVersionTypes.js';
+import { Curseforge, CurseforgeLoader } from './index.js';
 
 export enum HashFunctions {
   // eslint-disable-next-line no-unused-vars
@@ -20,12 +32,28 @@ interface Hash {
   value: string;
 }
 
-interface CurseForgeGameVersion {
-  gameVersionName: string;
-  gameVersion: string;
+export enum CurseforgeRelationType {
+  // eslint-disable-next-line no-unused-vars
+  EMBEDDED_LIBRARY = 1,
+  // eslint-disable-next-line no-unused-vars
+  OPTIONAL_DEPENDENCY = 2,
+  // eslint-disable-next-line no-unused-vars
+  REQUIRED_DEPENDENCY = 3,
+  // eslint-disable-next-line no-unused-vars
+  TOOL = 4,
+  // eslint-disable-next-line no-unused-vars
+  INCOMPATIBLE = 5,
+  // eslint-disable-next-line no-unused-vars
+  INCLUDE = 6
+}
+
+export interface CurseforgeFileDependency {
+  modId: number;
+  relationType: CurseforgeRelationType;
 }
 
 export interface CurseforgeModFile {
+  id: number;
   displayName: string;
   fileDate: string;
   releaseType: number;
@@ -34,10 +62,49 @@ export interface CurseforgeModFile {
   fileStatus: number;
   isAvailable: boolean;
   hashes: Hash[];
-  sortableGameVersions: CurseForgeGameVersion[];
+  sortableGameVersions: CurseforgeGameVersion[];
   fileFingerprint: number;
+  /**
+   * The size of the file in bytes
+   */
+  fileLength?: number;
+  /**
+   * The file to run on a server instead of this one, when the author published a separate server pack
+   */
+  serverPackFileId?: number | null;
+  /**
+   * The same release in another flavour, like a build for another loader
+   */
+  alternateFileId?: number | null;
+  /**
+   * The files that belong with this one, like a resource pack shipped next to the mod
+   */
+  additionalFileIds?: number[];
+  dependencies: CurseforgeFileDependency[];
+}
+
+interface CurseforgeModLogo {
+  url: string;
+  thumbnailUrl: string;
 }
 
+export interface CurseforgeMod {
+  id: number;
+  name: string;
+  slug: string;
+  summary: string;
+  logo: CurseforgeModLogo | null;
+}
+
+/**
+ * The mods that need to be installed alongside the file, like Fabric API
+ */
+export const requiredDependencies = (file: CurseforgeModFile): CurseforgeFileDependency[] => {
+  return (file.dependencies || []).filter(
+    (dependency) => dependency.relationType === CurseforgeRelationType.REQUIRED_DEPENDENCY
+  );
+};
+
 const getHash = (hashes: Hash[], algo: HashFunctions): string => {
   const hash = hashes.find((h) => h.algo === algo);
   if (!hash) {
@@ -46,6 +113,20 @@ const getHash = (hashes: Hash[], algo: HashFunctions): string => {
   return hash.value;
 };
 
+const findHash = (file: CurseforgeModFile, algo: HashFunctions): string => {
+  return (file.hashes || []).find((h) => h.algo === algo)?.value || '';
+};
+
+/**
+ * The SHA-1 hash Curseforge advertises for the file or an empty string when it has none
+ */
+export const sha1Hash = (file: CurseforgeModFile): string => findHash(file, HashFunctions.sha1);
+
+/**
+ * The MD5 hash Curseforge advertises for the file or an empty string when it has none
+ */
+export const md5Hash = (file: CurseforgeModFile): string => findHash(file, HashFunctions.md5);
+
 const releaseTypeFromNumber = (curseForgeReleaseType: number): ReleaseType => {
   switch (curseForgeReleaseType) {
     case 1:
@@ -59,32 +140,243 @@ const releaseTypeFromNumber = (curseForgeReleaseType: number): ReleaseType => {
   }
 };
 
-const getFiles = async (projectId: string, gameVersion: string, loader: Loader): Promise<CurseforgeModFile[]> => {
-  const cfLoader = Curseforge.curseforgeLoaderFromLoader(loader);
-  const url = `https://api.curseforge.com/v1/mods/${projectId}/files?gameVersion=${gameVersion}&modLoaderType=${cfLoader}`;
+interface CurseforgePagination {
+  index: number;
+  pageSize: number;
+  resultCount: number;
+  totalCount: number;
+}
+
+interface CurseforgeFilesResponse {
+  data: CurseforgeModFile[];
+  pagination?: CurseforgePagination;
+}
 
-  const modFiles = await rateLimitingFetch(url, {
-    headers: {
-      Accept: 'application/json',
-      'x-api-key': curseForgeApiKey
+/**
+ * Curseforge doesn't send bigger pages of files than this
+ */
+export const MAX_FILES_PAGE_SIZE = 50;
+
+let filesPageSize = MAX_FILES_PAGE_SIZE;
+
+/**
+ * Sets how many files to ask for in a single request, anything above the maximum is capped.
+ * Calling it without a value goes back to the maximum.
+ *
+ * @throws {InvalidPageSizeException} When the page size is not a whole number above 0
+ */
+export const setFilesPageSize = (pageSize?: number) => {
+  if (pageSize === undefined) {
+    filesPageSize = MAX_FILES_PAGE_SIZE;
+    return;
+  }
+
+  if (!Number.isInteger(pageSize) || pageSize < 1) {
+    throw new InvalidPageSizeException(pageSize);
+  }
+
+  filesPageSize = Math.min(pageSize, MAX_FILES_PAGE_SIZE);
+};
+
+export const getFilesPageSize = () => filesPageSize;
+
+/**
+ * Lets Curseforge do the filtering so we don't have to page through every file of popular mods.
+ * Empty filters are left out, which returns every file of the project.
+ */
+export const curseforgeFilesUrl = (
+  projectId: string,
+  gameVersion: string,
+  loader: CurseforgeLoader,
+  index: number,
+  pageSize: number = filesPageSize
+): string => {
+  const url = new URL(apiUrl(Platform.CURSEFORGE, `mods/${projectId}/files`));
+
+  if (gameVersion) {
+    url.searchParams.set('gameVersion', gameVersion);
+  }
+
+  if (loader !== CurseforgeLoader.ANY) {
+    url.searchParams.set('modLoaderType', String(loader));
+  }
+
+  url.searchParams.set('index', String(index));
+  url.searchParams.set('pageSize', String(pageSize));
+
+  return url.toString();
+};
+
+export interface FilesPaginationLimits {
+  /**
+   * Stops asking for more pages once the files collected so far are enough, like when one of them is the one we need
+   */
+  enough?: (files: CurseforgeModFile[]) => boolean;
+  /**
+   * The time in milliseconds since the epoch after which no more pages are asked for.
+   * The paging stops early when the next page would most likely arrive after it.
+   */
+  deadline?: number;
+}
+
+const hasTimeForAnotherPage = (deadline: number | undefined, pageStartedAt: number): boolean => {
+  if (deadline === undefined) {
+    return true;
+  }
+  const pageFinishedAt = now();
+  return pageFinishedAt + (pageFinishedAt - pageStartedAt) <= deadline;
+};
+
+const fetchFiles = async (
+  projectId: string,
+  gameVersion: string,
+  cfLoader: CurseforgeLoader,
+  signal?: AbortSignal,
+  limits: FilesPaginationLimits = {}
+): Promise<CurseforgeModFile[]> => {
+  const files: CurseforgeModFile[] = [];
+  const pageSize = filesPageSize;
+  let index = 0;
+
+  for (;;) {
+    signal?.throwIfAborted();
+    const pageStartedAt = now();
+    const url = curseforgeFilesUrl(projectId, gameVersion, cfLoader, index, pageSize);
+
+    const modFiles = await rateLimitingFetch(url, {
+      headers: {
+        Accept: 'application/json'
+      },
+      signal: signal
+    });
+
+    if (!modFiles.ok) {
+      throw new CouldNotFindModException(projectId, Platform.CURSEFORGE);
     }
-  });
 
-  if (!modFiles.ok) {
-    throw new CouldNotFindModException(projectId, Platform.CURSEFORGE);
+    const filesData = await readJson<CurseforgeFilesResponse>(modFiles);
+    files.push(...filesData.data);
+
+    const pagination = filesData.pagination;
+    if (!pagination || files.length >= pagination.totalCount) {
+      return files;
+    }
+
+    if (limits.enough?.(files) || !hasTimeForAnotherPage(limits.deadline, pageStartedAt)) {
+      getApiLogger().debug('stopped paging the files early', {
+        platform: Platform.CURSEFORGE,
+        projectId: projectId,
+        files: files.length,
+        totalCount: pagination.totalCount
+      });
+      return files;
+    }
+
+    // An empty page would make us ask for the same page forever
+    const nextIndex = pagination.index + pagination.resultCount;
+    if (pagination.resultCount === 0 || nextIndex <= index) {
+      throw new CurseforgePaginationError(projectId, files, pagination.totalCount);
+    }
+
+    index = nextIndex;
   }
+};
 
-  const filesData = await modFiles.json();
-  return filesData.data as CurseforgeModFile[];
+const getFiles = (
+  projectId: string,
+  gameVersion: string,
+  loader: Loader,
+  signal?: AbortSignal
+): Promise<CurseforgeModFile[]> => {
+  return fetchFiles(projectId, gameVersion, Curseforge.curseforgeLoaderFromLoader(loader), signal);
+};
+
+/**
+ * Orders the files from the newest to the oldest. The files released at the same time are ordered by their id,
+ * the bigger one is the newer upload, so the order doesn't depend on the order the pages arrived in.
+ */
+export const newestFileFirst = (a: CurseforgeModFile, b: CurseforgeModFile): number => {
+  return Date.parse(b.fileDate) - Date.parse(a.fileDate) || b.id - a.id;
+};
+
+/**
+ * Every file of the project, regardless of the game version and the loader, the newest first
+ *
+ * @throws {CouldNotFindModException} When Curseforge doesn't know the project
+ * @throws {CurseforgePaginationError} When Curseforge keeps sending the same page
+ */
+export const getProjectFiles = async (projectId: string, signal?: AbortSignal): Promise<CurseforgeModFile[]> => {
+  const files = await fetchFiles(projectId, '', CurseforgeLoader.ANY, signal);
+  return files.sort(newestFileFirst);
+};
+
+/**
+ * The files for the game version and the loader without paging through every file of the really popular mods.
+ * The paging stops as soon as the files collected so far are enough or the deadline is getting close, so the files
+ * can be a part of the matching files only.
+ *
+ * @throws {CouldNotFindModException} When Curseforge doesn't know the project
+ * @throws {CurseforgePaginationError} When Curseforge keeps sending the same page
+ */
+export const getFilesUntil = (
+  projectId: string,
+  gameVersion: string,
+  loader: Loader,
+  limits: FilesPaginationLimits,
+  signal?: AbortSignal
+): Promise<CurseforgeModFile[]> => {
+  return fetchFiles(projectId, gameVersion, Curseforge.curseforgeLoaderFromLoader(loader), signal, limits);
 };
 
 export const curseforgeFileToRemoteModDetails = (file: CurseforgeModFile, name: string): RemoteModDetails => {
-  return {
+  const modData: RemoteModDetails = {
     name: name,
     fileName: file.fileName,
+    version: file.displayName,
     releaseDate: file.fileDate,
     hash: getHash(file.hashes, HashFunctions.sha1),
-    downloadUrl: file.downloadUrl
+    downloadUrl: file.downloadUrl,
+    fileLength: file.fileLength,
+    curseforgeFileId: file.id
+  };
+
+  const dependencies = requiredDependencies(file);
+  if (dependencies.length > 0) {
+    modData.dependencies = dependencies.map((dependency) => ({
+      type: Platform.CURSEFORGE,
+      id: String(dependency.modId)
+    }));
+  }
+
+  const incompatibilities = (file.dependencies || []).filter(
+    (dependency) => dependency.relationType === CurseforgeRelationType.INCOMPATIBLE
+  );
+  if (incompatibilities.length > 0) {
+    modData.incompatibilities = incompatibilities.map((dependency) => ({
+      type: Platform.CURSEFORGE,
+      id: String(dependency.modId)
+    }));
+  }
+
+  return modData;
+};
+
+/**
+ * @throws {InvalidReleaseTypeException} When Curseforge sends a release type we don't know
+ */
+export const curseforgeFileToReleasedFile = (file: CurseforgeModFile): ReleasedFile => {
+  return {
+    platform: Platform.CURSEFORGE,
+    id: String(file.id),
+    displayName: file.displayName,
+    fileName: file.fileName,
+    gameVersions: minecraftVersions(file),
+    loaders: loaders(file).map((loader) => loader.toLowerCase()),
+    releaseType: releaseTypeFromNumber(file.releaseType),
+    downloadUrl: file.downloadUrl,
+    sha1: sha1Hash(file),
+    length: file.fileLength,
+    releaseDate: file.fileDate
   };
 };
 
@@ -95,9 +387,7 @@ const getPotentialFiles = (
 ): CurseforgeModFile[] => {
   return files
     .filter((file) => {
-      return file.sortableGameVersions.find(
-        (gameVersion) => gameVersion.gameVersionName.toLowerCase() === allowedGameVersion.toLowerCase()
-      );
+      return gameVersionMatches(allowedGameVersion, minecraftVersions(file));
     })
     .filter((file) => {
       try {
@@ -110,66 +400,258 @@ const getPotentialFiles = (
         return false;
       }
     })
-    .sort((a, b) => {
-      return a.fileDate < b.fileDate ? 1 : -1;
-    });
+    .sort(newestFileFirst);
 };
 
-export const getMod = async (
+const getAvailableFiles = (
   projectId: string,
-  allowedReleaseTypes: ReleaseType[],
-  allowedGameVersion: string,
+  gameVersion: string,
   loader: Loader,
-  allowFallback: boolean,
-  fixedModVersion?: string
-): Promise<RemoteModDetails> => {
-  performance.mark('curseforge-getmod-start');
+  signal?: AbortSignal
+): Promise<CurseforgeModFile[]> => {
+  return getFiles(projectId, gameVersion, loader, signal).catch((error) => {
+    // A broken page shouldn't stop us from using the files we've already got
+    if (error instanceof CurseforgePaginationError) {
+      return error.files;
+    }
+    throw error;
+  });
+};
+
+/**
+ * Files made for the loader itself are preferred, the compatible loaders are only asked for when there are none.
+ * Curseforge filters on the exact game version, so the broader game versions need their own requests too.
+ */
+const getSuitableFiles = async (
+  projectId: string,
+  gameVersion: string,
+  loader: Loader,
+  select: (files: CurseforgeModFile[]) => CurseforgeModFile[],
+  signal?: AbortSignal
+): Promise<CurseforgeModFile[]> => {
+  for (const compatibleLoader of compatibleLoaders(loader)) {
+    for (const requestedVersion of gameVersionsToRequest(gameVersion)) {
+      const files = select(await getAvailableFiles(projectId, requestedVersion, compatibleLoader, signal));
+      if (files.length > 0) {
+        return files;
+      }
+    }
+  }
+  return [];
+};
+
+/**
+ * What every file of the project is made for, only asked for when none of them fit.
+ * It just explains the failure, so a listing that fails leaves it out.
+ */
+const getSupportedTargets = async (projectId: string, signal?: AbortSignal): Promise<SupportedTargets | undefined> => {
+  try {
+    const files = await getProjectFiles(projectId, signal);
+    return supportedTargetsOf(files.map((file) => ({ gameVersions: minecraftVersions(file), loaders: loaders(file) })));
+  } catch {
+    return undefined;
+  }
+};
+
+/**
+ * Returns the newest file of the project that works with the given game version, loader and release types.
+ * The signal can be used to abort the lookup between the pages of files.
+ */
+export const getLatestFile = async (
+  projectId: string,
+  gameVersion: string,
+  loader: Loader,
+  allowedReleaseTypes: ReleaseType[],
+  signal?: AbortSignal
+): Promise<CurseforgeModFile> => {
+  const potentialFiles = await getSuitableFiles(
+    projectId,
+    gameVersion,
+    loader,
+    (files) => getPotentialFiles(files, gameVersion, allowedReleaseTypes),
+    signal
+  );
+
+  if (potentialFiles.length === 0) {
+    getApiLogger().debug('no suitable file', {
+      platform: Platform.CURSEFORGE,
+      projectId: projectId,
+      gameVersion: gameVersion,
+      loader: loader
+    });
+    throw new NoRemoteFileFound(projectId, Platform.CURSEFORGE, await getSupportedTargets(projectId, signal));
+  }
 
-  const url = `https://api.curseforge.com/v1/mods/${projectId}`;
+  getApiLogger().debug('selected file', {
+    platform: Platform.CURSEFORGE,
+    projectId: projectId,
+    fileName: potentialFiles[0].fileName,
+    candidates: potentialFiles.length
+  });
+  return potentialFiles[0];
+};
+
+let preferServerPacks = false;
+
+/**
+ * Makes the file selection pick the server pack of a file when there is one, which is what a server needs.
+ * Calling it without a value goes back to the regular files.
+ */
+export const setPreferServerPacks = (prefer?: boolean) => {
+  preferServerPacks = !!prefer;
+};
+
+export const prefersServerPacks = () => preferServerPacks;
+
+/**
+ * Fetches the details of a single file of the project
+ *
+ * @throws {CouldNotFindModException} When Curseforge doesn't know the file
+ */
+export const getFile = async (projectId: string, fileId: number, signal?: AbortSignal): Promise<CurseforgeModFile> => {
+  const url = apiUrl(Platform.CURSEFORGE, `mods/${projectId}/files/${fileId}`);
+  const response = await rateLimitingFetch(url, {
+    headers: {
+      Accept: 'application/json'
+    },
+    signal: signal
+  });
+
+  if (!response.ok) {
+    throw new CouldNotFindModException(projectId, Platform.CURSEFORGE);
+  }
+
+  const file = await readJson<{ data: CurseforgeModFile }>(response);
+  return file.data;
+};
+
+/**
+ * Swaps the file for its server pack when server packs are preferred and the author published one.
+ * Files without a server pack are used as they are.
+ *
+ * @throws {CouldNotFindModException} When the server pack can't be fetched
+ */
+export const selectFile = async (
+  projectId: string,
+  file: CurseforgeModFile,
+  preferServerPack: boolean = preferServerPacks,
+  signal?: AbortSignal
+): Promise<CurseforgeModFile> => {
+  if (!preferServerPack || !file.serverPackFileId) {
+    return file;
+  }
+  getApiLogger().debug('using the server pack', {
+    projectId: projectId,
+    fileId: file.id,
+    serverPackFileId: file.serverPackFileId
+  });
+  return getFile(projectId, file.serverPackFileId, signal);
+};
+
+/**
+ * Fetches the files the author attached to the file, each of them once
+ *
+ * @throws {CouldNotFindModException} When one of the files can't be fetched
+ */
+export const getAdditionalFiles = async (
+  projectId: string,
+  file: CurseforgeModFile,
+  signal?: AbortSignal
+): Promise<CurseforgeModFile[]> => {
+  const fileIds = [...new Set(file.additionalFileIds || [])];
+  return Promise.all(fileIds.map((fileId) => getFile(projectId, fileId, signal)));
+};
+
+export const curseforgeModFromProject = (project: CurseforgeMod): CurseforgeMod => {
+  return {
+    id: project.id,
+    name: project.name,
+    slug: project.slug,
+    summary: project.summary,
+    logo: project.logo || null
+  };
+};
+
+/**
+ * Fetches the details of the project
+ *
+ * @throws {CouldNotFindModException} When Curseforge doesn't know the project
+ */
+export const getModInfo = async (projectId: string, signal?: AbortSignal): Promise<CurseforgeMod> => {
+  const url = apiUrl(Platform.CURSEFORGE, `mods/${projectId}`);
   const modDetailsRequest = await rateLimitingFetch(url, {
     headers: {
-      Accept: 'application/json',
-      'x-api-key': curseForgeApiKey
-    }
+      Accept: 'application/json'
+    },
+    signal: signal
   });
 
   if (!modDetailsRequest.ok) {
     throw new CouldNotFindModException(projectId, Platform.CURSEFORGE);
   }
 
-  const modDetails = await modDetailsRequest.json();
-  const files = await getFiles(projectId, allowedGameVersion, loader);
+  const modDetails = await readJson<{ data?: CurseforgeMod }>(modDetailsRequest);
+
+  if (!modDetails?.data || typeof modDetails.data.name !== 'string') {
+    throw new CouldNotFindModException(projectId, Platform.CURSEFORGE);
+  }
+
+  return curseforgeModFromProject(modDetails.data);
+};
+
+/**
+ * How many projects are asked for in a single request for the details of many mods
+ */
+export const MOD_INFO_CHUNK_SIZE = 100;
 
-  let potentialFiles = [];
+/**
+ * Fetches the details of many projects with a request per batch instead of one per project.
+ * The projects Curseforge doesn't know are left out, the rest come back in the order they were asked for.
+ *
+ * @throws {CouldNotFindModException} When a batch can't be fetched
+ */
+export const getModsInfo = async (
+  projectIds: string[],
+  chunkSize: number = MOD_INFO_CHUNK_SIZE,
+  signal?: AbortSignal
+): Promise<CurseforgeMod[]> => {
+  const ids = [...new Set(projectIds)];
+  const url = apiUrl(Platform.CURSEFORGE, 'mods');
+  const found = new Map<string, CurseforgeMod>();
 
-  if (fixedModVersion) {
-    potentialFiles = files.filter((file) => {
-      return file.fileName.toLowerCase() === fixedModVersion.toLowerCase();
+  for (const batch of chunk(ids, chunkSize)) {
+    signal?.throwIfAborted();
+    const response = await rateLimitingFetch(url, {
+      headers: {
+        Accept: 'application/json',
+        'Content-Type': 'application/json'
+      },
+      method: 'POST',
+      body: JSON.stringify({ modIds: batch.map(Number) }),
+      signal: signal
     });
-  } else {
-    potentialFiles = getPotentialFiles(files, allowedGameVersion, allowedReleaseTypes);
-  }
 
-  if (potentialFiles.length === 0) {
-    if (allowFallback) {
-      const versionDown = getNextVersionDown(allowedGameVersion);
-      return getMod(projectId, allowedReleaseTypes, versionDown.nextVersionToTry, loader, versionDown.canGoDown);
+    if (!response.ok) {
+      throw new CouldNotFindModException(batch.join(', '), Platform.CURSEFORGE);
     }
 
-    performance.mark('curseforge-getmod-failed');
-    performance.measure(`curseforge-getmod-${projectId}-failed`, 'curseforge-getmod-start', 'curseforge-getmod-failed');
-
-    throw new NoRemoteFileFound(modDetails.data.name, Platform.CURSEFORGE);
+    const mods = await readJson<{ data?: CurseforgeMod[] }>(response);
+    for (const mod of mods.data || []) {
+      found.set(String(mod.id), curseforgeModFromProject(mod));
+    }
   }
 
-  const latestFile = potentialFiles[0];
+  return ids.filter((id) => found.has(id)).map((id) => found.get(id) as CurseforgeMod);
+};
 
-  if (latestFile.downloadUrl === null) {
-    throw new CurseforgeDownloadUrlError(modDetails.data.name);
+const toRemoteModDetails = (projectId: string, name: string, file: CurseforgeModFile): RemoteModDetails => {
+  // Authors can disable third party downloads, the file exists but there's nothing for us to download
+  if (!file.downloadUrl) {
+    throw new CurseforgeDownloadUrlError(name, projectId, file.id);
   }
 
   try {
-    const modData = curseforgeFileToRemoteModDetails(latestFile, modDetails.data.name);
+    const modData = curseforgeFileToRemoteModDetails(file, name);
     performance.mark('curseforge-getmod-end');
     performance.measure(`curseforge-getmod-${projectId}`, 'curseforge-getmod-start', 'curseforge-getmod-end');
     return modData;
@@ -177,6 +659,108 @@ export const getMod = async (
     // Catch when the hash is not found (due to curseforge error)
     performance.mark('curseforge-getmod-failed');
     performance.measure(`curseforge-getmod-${projectId}-failed`, 'curseforge-getmod-start', 'curseforge-getmod-failed');
-    throw new NoRemoteFileFound(modDetails.data.name, Platform.CURSEFORGE);
+    throw new NoRemoteFileFound(name, Platform.CURSEFORGE);
+  }
+};
+
+const withAdditionalFiles = async (
+  projectId: string,
+  name: string,
+  file: CurseforgeModFile,
+  overrides: FileOverrides
+): Promise<RemoteModDetails> => {
+  const modData = toRemoteModDetails(projectId, name, file);
+  if (!overrides.installAdditionalFiles) {
+    return modData;
   }
+
+  const additionalFiles = await getAdditionalFiles(projectId, file);
+  return {
+    ...modData,
+    additionalFiles: additionalFiles.map((additionalFile) => {
+      // A missing part of the mod is worse than no mod, the platform fallback can still step in
+      const details = toRemoteModDetails(projectId, name, additionalFile);
+      return { fileName: details.fileName, hash: details.hash, downloadUrl: details.downloadUrl };
+    })
+  };
+};
+
+/**
+ * Finds the file of the project to install.
+ * A forced file is used as it is, otherwise the excluded files are left out before the newest suitable one is picked.
+ */
+export const getMod = async (
+  projectId: string,
+  allowedReleaseTypes: ReleaseType[],
+  allowedGameVersion: string,
+  loader: Loader,
+  allowFallback: boolean,
+  fixedModVersion?: string,
+  overrides: FileOverrides = {}
+): Promise<RemoteModDetails> => {
+  performance.mark('curseforge-getmod-start');
+
+  const modDetails = await getModInfo(projectId);
+
+  if (overrides.forceFileId) {
+    getApiLogger().debug('using the forced file', {
+      platform: Platform.CURSEFORGE,
+      projectId: projectId,
+      fileId: overrides.forceFileId
+    });
+    const forcedFile = await getFile(projectId, Number(overrides.forceFileId));
+    return withAdditionalFiles(projectId, modDetails.name, forcedFile, overrides);
+  }
+
+  const potentialFiles = await getSuitableFiles(projectId, allowedGameVersion, loader, (files) => {
+    const selectableFiles = files.filter((file) => !isExcludedFileName(file.fileName, overrides));
+    if (fixedModVersion) {
+      return selectableFiles.filter((file) => {
+        return file.fileName.toLowerCase() === fixedModVersion.toLowerCase();
+      });
+    }
+    return getPotentialFiles(selectableFiles, allowedGameVersion, allowedReleaseTypes);
+  });
+
+  if (potentialFiles.length === 0) {
+    if (allowFallback) {
+      const versionDown = getNextVersionDown(allowedGameVersion);
+      getApiLogger().debug('trying an older game version', {
+        platform: Platform.CURSEFORGE,
+        projectId: projectId,
+        gameVersion: allowedGameVersion,
+        nextGameVersion: versionDown.nextVersionToTry
+      });
+      return getMod(
+        projectId,
+        allowedReleaseTypes,
+        versionDown.nextVersionToTry,
+        loader,
+        versionDown.canGoDown,
+        undefined,
+        overrides
+      );
+    }
+
+    performance.mark('curseforge-getmod-failed');
+    performance.measure(`curseforge-getmod-${projectId}-failed`, 'curseforge-getmod-start', 'curseforge-getmod-failed');
+
+    getApiLogger().debug('no suitable file', {
+      platform: Platform.CURSEFORGE,
+      projectId: projectId,
+      gameVersion: allowedGameVersion,
+      loader: loader
+    });
+    throw new NoRemoteFileFound(modDetails.name, Platform.CURSEFORGE, await getSupportedTargets(projectId));
+  }
+
+  getApiLogger().debug('selected file', {
+    platform: Platform.CURSEFORGE,
+    projectId: projectId,
+    fileName: potentialFiles[0].fileName,
+    candidates: potentialFiles.length
+  });
+  const latestFile = await selectFile(projectId, potentialFiles[0]);
+
+  return withAdditionalFiles(projectId, modDetails.name, latestFile, overrides);
 };
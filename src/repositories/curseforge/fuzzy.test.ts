@@ -0,0 +1,254 @@
+import { chance } from 'jest-chance';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateCurseforgeModFile } from '../../../test/generateCurseforgeModFile.js';
+import { CouldNotFindModException } from '../../errors/CouldNotFindModException.js';
+import { Platform } from '../../lib/modlist.types.js';
+import { MaximumRetriesReached } from '../../lib/rateLimiter/MaximumRetriesReached.js';
+import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { CurseforgeModFile, getProjectFiles } from './fetch.js';
+import { LocalJar, confidence, nameSimilarity, resolveFuzzy, sizeSimilarity } from './fuzzy.js';
+import { CurseforgeLookupResult } from './lookup.js';
+
+vi.mock('../../lib/rateLimiter/index.js');
+vi.mock('../../lib/Logger.js');
+vi.mock('./fetch.js');
+vi.mock('../../mmm.js');
+
+const assumeLookupResponse = (data: CurseforgeLookupResult['data']) => {
+  vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+    ok: true,
+    json: () => Promise.resolve({ data: data })
+  } as Response);
+};
+
+const generateJar = (overrides?: Partial<LocalJar>): LocalJar => ({
+  fingerprint: chance.integer({ min: 1, max: 999999 }),
+  fileName: `${chance.word()}-1.0.0.jar`,
+  size: chance.integer({ min: 1000, max: 100000 }),
+  ...overrides
+});
+
+describe('The Curseforge fuzzy resolution', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+  });
+
+  describe('when comparing names', () => {
+    it('treats the same name as a perfect match', () => {
+      expect(nameSimilarity('Sodium-1.0.jar', 'sodium-1.0')).toEqual(1);
+    });
+
+    it('prefers the names that share more', () => {
+      expect(nameSimilarity('sodium-1.2.jar', 'sodium-1.3.jar')).toBeGreaterThan(
+        nameSimilarity('sodium-1.2.jar', 'lithium-1.2.jar')
+      );
+    });
+
+    it('does not match names that are too short to compare', () => {
+      expect(nameSimilarity('a', 'b')).toEqual(0);
+    });
+  });
+
+  describe('when comparing sizes', () => {
+    it('treats the same size as a perfect match', () => {
+      expect(sizeSimilarity(1234, 1234)).toEqual(1);
+    });
+
+    it('scores by how far the sizes are apart', () => {
+      expect(sizeSimilarity(500, 1000)).toEqual(0.5);
+      expect(sizeSimilarity(1000, 500)).toEqual(0.5);
+    });
+
+    it('does not trust a file without a size', () => {
+      expect(sizeSimilarity(1234)).toEqual(0);
+      expect(sizeSimilarity(0, 1234)).toEqual(0);
+    });
+  });
+
+  it('weighs the size and the name the same', () => {
+    const jar = generateJar({ fileName: 'sodium.jar', size: 500 });
+    const file = generateCurseforgeModFile({ fileName: 'sodium.jar', fileLength: 1000 }).generated;
+
+    expect(confidence(jar, file)).toEqual(0.75);
+  });
+
+  describe('when there is a partial match', () => {
+    it('picks the closest file of the matched project', async () => {
+      const jar = generateJar({ fileName: 'sodium-fabric-0.5.3.jar', size: 10000 });
+      const projectId = chance.integer({ min: 1, max: 999999 });
+      const matchedFile = generateCurseforgeModFile().generated;
+      const closest = generateCurseforgeModFile({ fileName: 'sodium-fabric-0.5.3.jar', fileLength: 9900 }).generated;
+      const other = generateCurseforgeModFile({ fileName: 'sodium-fabric-0.4.0.jar', fileLength: 5000 }).generated;
+
+      assumeLookupResponse({
+        exactMatches: [],
+        exactFingerprints: [],
+        partialMatches: [{ id: projectId, file: matchedFile }],
+        partialMatchFingerprints: { [String(matchedFile.id)]: [jar.fingerprint] }
+      });
+      vi.mocked(getProjectFiles).mockResolvedValueOnce([other, closest]);
+
+      const actual = await resolveFuzzy([jar]);
+
+      expect(vi.mocked(getProjectFiles)).toHaveBeenCalledWith(String(projectId), undefined);
+      expect(actual).toHaveLength(1);
+      expect(actual[0]).toMatchObject({ fingerprint: jar.fingerprint, modId: String(projectId), file: closest });
+      expect(actual[0].confidence).toBeCloseTo(0.995);
+    });
+
+    it('only compares the projects the fingerprint was matched to', async () => {
+      const jar = generateJar();
+      const otherJar = generateJar();
+      const matched = generateCurseforgeModFile().generated;
+      const otherMatched = generateCurseforgeModFile().generated;
+      const file = generateCurseforgeModFile({ fileLength: jar.size }).generated;
+      const otherFile = generateCurseforgeModFile({ fileLength: otherJar.size }).generated;
+
+      assumeLookupResponse({
+        exactMatches: [],
+        exactFingerprints: [],
+        partialMatches: [
+          { id: 1, file: matched },
+          { id: 2, file: otherMatched }
+        ],
+        partialMatchFingerprints: {
+          [String(matched.id)]: [jar.fingerprint],
+          [String(otherMatched.id)]: [otherJar.fingerprint]
+        }
+      });
+      vi.mocked(getProjectFiles).mockResolvedValueOnce([file]).mockResolvedValueOnce([otherFile]);
+
+      const actual = await resolveFuzzy([jar, otherJar]);
+
+      expect(vi.mocked(getProjectFiles)).toHaveBeenCalledTimes(2);
+      expect(actual.map((match) => [match.fingerprint, match.modId, match.file])).toEqual([
+        [jar.fingerprint, '1', file],
+        [otherJar.fingerprint, '2', otherFile]
+      ]);
+    });
+
+    it('considers every partial match when Curseforge does not say which fingerprint they belong to', async () => {
+      const jar = generateJar();
+      const worse = generateCurseforgeModFile({ fileLength: jar.size * 2 }).generated;
+      const better = generateCurseforgeModFile({ fileLength: jar.size }).generated;
+
+      assumeLookupResponse({
+        exactMatches: [],
+        exactFingerprints: [],
+        partialMatches: [
+          { id: 1, file: generateCurseforgeModFile().generated },
+          { id: 2, file: generateCurseforgeModFile().generated }
+        ]
+      });
+      vi.mocked(getProjectFiles).mockResolvedValueOnce([worse]).mockResolvedValueOnce([better]);
+
+      const actual = await resolveFuzzy([jar]);
+
+      expect(actual).toHaveLength(1);
+      expect(actual[0].modId).toEqual('2');
+      expect(actual[0].file).toEqual(better);
+    });
+
+    it('fetches the files of a project once', async () => {
+      const first = generateJar();
+      const second = generateJar();
+      const matched = generateCurseforgeModFile().generated;
+
+      assumeLookupResponse({
+        exactMatches: [],
+        exactFingerprints: [],
+        partialMatches: [{ id: 1, file: matched }],
+        partialMatchFingerprints: { [String(matched.id)]: [first.fingerprint, second.fingerprint] }
+      });
+      vi.mocked(getProjectFiles).mockResolvedValue([generateCurseforgeModFile().generated]);
+
+      const actual = await resolveFuzzy([first, second]);
+
+      expect(actual).toHaveLength(2);
+      expect(vi.mocked(getProjectFiles)).toHaveBeenCalledTimes(1);
+    });
+
+    it('skips the projects that cannot be read', async () => {
+      const jar = generateJar();
+      const file = generateCurseforgeModFile().generated;
+
+      assumeLookupResponse({
+        exactMatches: [],
+        exactFingerprints: [],
+        partialMatches: [
+          { id: 1, file: generateCurseforgeModFile().generated },
+          { id: 2, file: generateCurseforgeModFile().generated }
+        ]
+      });
+      vi.mocked(getProjectFiles)
+        .mockRejectedValueOnce(new CouldNotFindModException('1', Platform.CURSEFORGE))
+        .mockResolvedValueOnce([file]);
+
+      const actual = await resolveFuzzy([jar]);
+
+      expect(actual.map((match) => match.modId)).toEqual(['2']);
+    });
+
+    it('leaves the jar out when the project has no files', async () => {
+      assumeLookupResponse({
+        exactMatches: [],
+        exactFingerprints: [],
+        partialMatches: [{ id: 1, file: generateCurseforgeModFile().generated }]
+      });
+      vi.mocked(getProjectFiles).mockResolvedValueOnce([]);
+
+      const actual = await resolveFuzzy([generateJar()]);
+
+      expect(actual).toEqual([]);
+    });
+  });
+
+  it('leaves out the jars with an exact match', async () => {
+    const jar = generateJar();
+    const file: CurseforgeModFile = generateCurseforgeModFile({ fileFingerprint: jar.fingerprint }).generated;
+
+    assumeLookupResponse({
+      exactMatches: [{ id: 1, file: file }],
+      exactFingerprints: [jar.fingerprint],
+      partialMatches: [{ id: 2, file: generateCurseforgeModFile().generated }]
+    });
+
+    const actual = await resolveFuzzy([jar]);
+
+    expect(actual).toEqual([]);
+    expect(vi.mocked(getProjectFiles)).not.toHaveBeenCalled();
+  });
+
+  it('leaves out the jars without any match', async () => {
+    const jar = generateJar();
+
+    assumeLookupResponse({ exactMatches: [], exactFingerprints: [], unmatchedFingerprints: [jar.fingerprint] });
+
+    const actual = await resolveFuzzy([jar]);
+
+    expect(actual).toEqual([]);
+  });
+
+  it('moves on when Curseforge cannot be reached', async () => {
+    vi.mocked(rateLimitingFetch).mockRejectedValueOnce(new MaximumRetriesReached(new Response(null, { status: 503 })));
+
+    const actual = await resolveFuzzy([generateJar()]);
+
+    expect(actual).toEqual([]);
+  });
+
+  it('does not look anything up without jars', async () => {
+    const actual = await resolveFuzzy([]);
+
+    expect(actual).toEqual([]);
+    expect(vi.mocked(rateLimitingFetch)).not.toHaveBeenCalled();
+  });
+
+  it('can be cancelled', async () => {
+    const controller = new AbortController();
+    controller.abort();
+
+    await expect(resolveFuzzy([generateJar()], controller.signal)).rejects.toHaveProperty('name', 'AbortError');
+    expect(vi.mocked(rateLimitingFetch)).not.toHaveBeenCalled();
+  });
+});
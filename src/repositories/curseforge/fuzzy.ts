@@ -0,0 +1,156 @@
+import { chunk } from '../../lib/chunk.js';
+import { CurseforgeModFile, getProjectFiles } from './fetch.js';
+import { CurseforgeLookupMatches, FINGERPRINT_CHUNK_SIZE, lookupChunk } from './lookup.js';
+
+export interface LocalJar {
+  fingerprint: number;
+  fileName: string;
+  /**
+   * The size of the jar in bytes
+   */
+  size: number;
+}
+
+export interface FuzzyMatch {
+  fingerprint: number;
+  modId: string;
+  file: CurseforgeModFile;
+  /**
+   * How sure we are that the jar is this file, from 0 to 1
+   */
+  confidence: number;
+}
+
+const withoutExtension = (fileName: string) => fileName.toLowerCase().replace(/\.jar$/, '');
+
+const pairs = (text: string): string[] => {
+  const result: string[] = [];
+  for (let i = 0; i < text.length - 1; i++) {
+    result.push(text.slice(i, i + 2));
+  }
+  return result;
+};
+
+/**
+ * The Dice coefficient of the letter pairs, so my-mod-1.2.jar is closer to my-mod-1.3.jar than to other-mod-1.2.jar
+ */
+export const nameSimilarity = (a: string, b: string): number => {
+  const first = withoutExtension(a);
+  const second = withoutExtension(b);
+
+  if (first === second) {
+    return 1;
+  }
+
+  const firstPairs = pairs(first);
+  const secondPairs = pairs(second);
+  if (firstPairs.length === 0 || secondPairs.length === 0) {
+    return 0;
+  }
+
+  const remaining = [...secondPairs];
+  let common = 0;
+  firstPairs.forEach((pair) => {
+    const index = remaining.indexOf(pair);
+    if (index !== -1) {
+      common++;
+      remaining.splice(index, 1);
+    }
+  });
+
+  return (2 * common) / (firstPairs.length + secondPairs.length);
+};
+
+/**
+ * 1 for the same size, going towards 0 as the sizes drift apart. Files without a known size score 0.
+ */
+export const sizeSimilarity = (size: number, fileLength?: number): number => {
+  if (!fileLength || size <= 0) {
+    return 0;
+  }
+  return Math.min(size, fileLength) / Math.max(size, fileLength);
+};
+
+/**
+ * The size and the name count the same, a recompressed jar keeps its name but its size shifts a little
+ */
+export const confidence = (jar: LocalJar, file: CurseforgeModFile): number => {
+  return (sizeSimilarity(jar.size, file.fileLength) + nameSimilarity(jar.fileName, file.fileName)) / 2;
+};
+
+/**
+ * The projects Curseforge partially matched the fingerprint to.
+ * Older responses don't say which fingerprint a partial match belongs to, then every partial match is a candidate.
+ */
+const candidatesFor = (
+  fingerprint: number,
+  partialMatches: CurseforgeLookupMatches[],
+  partialMatchFingerprints?: Record<string, number[]>
+): string[] => {
+  const matches = partialMatchFingerprints
+    ? partialMatches.filter((match) => (partialMatchFingerprints[String(match.file.id)] || []).includes(fingerprint))
+    : partialMatches;
+
+  return [...new Set(matches.map((match) => String(match.id)))];
+};
+
+/**
+ * Finds the Curseforge files of the jars that don't match any file byte for byte, like the ones that were re-zipped.
+ * Every file of the partially matched projects is compared to the jar and the closest one by size and name wins.
+ *
+ * Jars with an exact match or without any match at all are left out.
+ *
+ * @param jars The jars to resolve
+ * @param signal Cancels the lookup
+ */
+export const resolveFuzzy = async (jars: LocalJar[], signal?: AbortSignal): Promise<FuzzyMatch[]> => {
+  performance.mark('curseforge-fuzzy-start');
+
+  const projectFiles = new Map<string, Promise<CurseforgeModFile[]>>();
+  const filesOf = (projectId: string) => {
+    if (!projectFiles.has(projectId)) {
+      // A project we can't read just isn't a candidate anymore
+      projectFiles.set(projectId, getProjectFiles(projectId, signal).catch(() => []));
+    }
+    return projectFiles.get(projectId)!;
+  };
+
+  const matches: FuzzyMatch[] = [];
+
+  for (const jarChunk of chunk(jars, FINGERPRINT_CHUNK_SIZE)) {
+    signal?.throwIfAborted();
+    const result = await lookupChunk(jarChunk.map((jar) => String(jar.fingerprint)), signal);
+
+    if (!result) {
+      continue;
+    }
+
+    const exact = new Set(result.data.exactFingerprints || []);
+    const partialMatches = result.data.partialMatches || [];
+
+    for (const jar of jarChunk) {
+      if (exact.has(jar.fingerprint)) {
+        continue;
+      }
+
+      let best: FuzzyMatch | undefined;
+      for (const projectId of candidatesFor(jar.fingerprint, partialMatches, result.data.partialMatchFingerprints)) {
+        for (const file of await filesOf(projectId)) {
+          const score = confidence(jar, file);
+          if (!best || score > best.confidence) {
+            best = { fingerprint: jar.fingerprint, modId: projectId, file: file, confidence: score };
+          }
+        }
+      }
+
+      if (best) {
+        matches.push(best);
+      }
+    }
+  }
+
+  performance.mark('curseforge-fuzzy-end');
+  performance.measure('curseforge-fuzzy', 'curseforge-fuzzy-start', 'curseforge-fuzzy-end');
+
+  return matches;
+};
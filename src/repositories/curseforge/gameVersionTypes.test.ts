@@ -0,0 +1,75 @@
+import { describe, expect, it } from 'vitest';
+import { generateCurseforgeModFile } from '../../../test/generateCurseforgeModFile.js';
+import {
+  CurseforgeGameVersion,
+  CurseforgeGameVersionType,
+  GameVersionKind,
+  classifyGameVersion,
+  loaders,
+  minecraftVersions
+} from './gameVersionTypes.js';
+
+const MINECRAFT_1_20 = 75125;
+const MINECRAFT_1_19 = 73407;
+
+// Like what Curseforge sends for a file that works with Fabric and Quilt on 1.20.1 and 1.19.4
+const fixture: CurseforgeGameVersion[] = [
+  { gameVersionName: 'Fabric', gameVersion: '', gameVersionTypeId: CurseforgeGameVersionType.LOADER },
+  { gameVersionName: '1.20.1', gameVersion: '1.20.1', gameVersionTypeId: MINECRAFT_1_20 },
+  { gameVersionName: 'Client', gameVersion: '', gameVersionTypeId: CurseforgeGameVersionType.ENVIRONMENT },
+  { gameVersionName: 'Quilt', gameVersion: '', gameVersionTypeId: CurseforgeGameVersionType.LOADER },
+  { gameVersionName: 'Java 17', gameVersion: '', gameVersionTypeId: CurseforgeGameVersionType.JAVA },
+  { gameVersionName: '1.19.4', gameVersion: '1.19.4', gameVersionTypeId: MINECRAFT_1_19 },
+  { gameVersionName: 'Server', gameVersion: '', gameVersionTypeId: CurseforgeGameVersionType.ENVIRONMENT }
+];
+
+describe('The Curseforge game version types', () => {
+  it.each([
+    [fixture[0], GameVersionKind.LOADER],
+    [fixture[1], GameVersionKind.MINECRAFT],
+    [fixture[2], GameVersionKind.OTHER],
+    [fixture[4], GameVersionKind.OTHER],
+    [fixture[5], GameVersionKind.MINECRAFT]
+  ])('classifies %j as %s', (version, kind) => {
+    expect(classifyGameVersion(version)).toEqual(kind);
+  });
+
+  it('trusts the type id over the name', () => {
+    const loader = { gameVersionName: '1.20.1', gameVersion: '', gameVersionTypeId: CurseforgeGameVersionType.LOADER };
+    const minecraft = { gameVersionName: 'Fabric', gameVersion: '', gameVersionTypeId: MINECRAFT_1_20 };
+
+    expect(classifyGameVersion(loader)).toEqual(GameVersionKind.LOADER);
+    expect(classifyGameVersion(minecraft)).toEqual(GameVersionKind.MINECRAFT);
+  });
+
+  it.each([
+    ['Fabric', GameVersionKind.LOADER],
+    [' NeoForge ', GameVersionKind.LOADER],
+    ['Client', GameVersionKind.OTHER],
+    ['server', GameVersionKind.OTHER],
+    ['Java 21', GameVersionKind.OTHER],
+    ['1.20.1', GameVersionKind.MINECRAFT],
+    ['1.20-Snapshot', GameVersionKind.MINECRAFT]
+  ])('guesses %j as %s without a type id', (name, kind) => {
+    expect(classifyGameVersion({ gameVersionName: name, gameVersion: '' })).toEqual(kind);
+  });
+
+  it('lists the Minecraft versions of a file', () => {
+    const file = generateCurseforgeModFile({ sortableGameVersions: fixture }).generated;
+
+    expect(minecraftVersions(file)).toEqual(['1.20.1', '1.19.4']);
+  });
+
+  it('lists the loaders of a file', () => {
+    const file = generateCurseforgeModFile({ sortableGameVersions: fixture }).generated;
+
+    expect(loaders(file)).toEqual(['Fabric', 'Quilt']);
+  });
+
+  it('lists nothing for a file without game versions', () => {
+    const file = generateCurseforgeModFile({ sortableGameVersions: [] }).generated;
+
+    expect(minecraftVersions(file)).toEqual([]);
+    expect(loaders(file)).toEqual([]);
+  });
+});
@@ -0,0 +1,83 @@
+import { Loader } from '../../lib/modlist.types.js';
+
+/**
+ * The type ids Curseforge tags the entries of sortableGameVersions with that aren't Minecraft versions.
+ * Every Minecraft minor version has a type id of its own, so anything else is a Minecraft version.
+ */
+export enum CurseforgeGameVersionType {
+  // eslint-disable-next-line no-unused-vars
+  JAVA = 8326,
+  // eslint-disable-next-line no-unused-vars
+  LOADER = 68441,
+  // eslint-disable-next-line no-unused-vars
+  ENVIRONMENT = 75208
+}
+
+export enum GameVersionKind {
+  // eslint-disable-next-line no-unused-vars
+  MINECRAFT = 'minecraft',
+  // eslint-disable-next-line no-unused-vars
+  LOADER = 'loader',
+  // eslint-disable-next-line no-unused-vars
+  OTHER = 'other'
+}
+
+export interface CurseforgeGameVersion {
+  gameVersionName: string;
+  gameVersion: string;
+  /**
+   * Older files and some proxies leave it out
+   */
+  gameVersionTypeId?: number;
+}
+
+const loaderNames: string[] = Object.values(Loader);
+const otherNames = /^(client|server|java \d+)$/;
+
+/**
+ * Without a type id, the name is all there is to go on
+ */
+const classifyByName = (name: string): GameVersionKind => {
+  const normalizedName = name.trim().toLowerCase();
+  if (loaderNames.includes(normalizedName)) {
+    return GameVersionKind.LOADER;
+  }
+  if (otherNames.test(normalizedName)) {
+    return GameVersionKind.OTHER;
+  }
+  return GameVersionKind.MINECRAFT;
+};
+
+export const classifyGameVersion = (version: CurseforgeGameVersion): GameVersionKind => {
+  switch (version.gameVersionTypeId) {
+    case undefined:
+      return classifyByName(version.gameVersionName);
+    case CurseforgeGameVersionType.LOADER:
+      return GameVersionKind.LOADER;
+    case CurseforgeGameVersionType.JAVA:
+    case CurseforgeGameVersionType.ENVIRONMENT:
+      return GameVersionKind.OTHER;
+    default:
+      return GameVersionKind.MINECRAFT;
+  }
+};
+
+const namesOfKind = (file: { sortableGameVersions: CurseforgeGameVersion[] }, kind: GameVersionKind) => {
+  return file.sortableGameVersions
+    .filter((version) => classifyGameVersion(version) === kind)
+    .map((version) => version.gameVersionName);
+};
+
+/**
+ * The Minecraft versions the file is made for, without the loaders and the other tags
+ */
+export const minecraftVersions = (file: { sortableGameVersions: CurseforgeGameVersion[] }): string[] => {
+  return namesOfKind(file, GameVersionKind.MINECRAFT);
+};
+
+/**
+ * The loaders the file is made for, as Curseforge names them, like Fabric or NeoForge
+ */
+export const loaders = (file: { sortableGameVersions: CurseforgeGameVersion[] }): string[] => {
+  return namesOfKind(file, GameVersionKind.LOADER);
+};
@@ -66,7 +66,26 @@ describe('The Curseforge Repository class', () => {
       allowedReleaseTypes,
       allowedGameVersion,
       loader,
-      allowFallback
+      allowFallback,
+      undefined,
+      undefined
+    );
+  });
+
+  it('passes the file overrides on to the fetching module', async () => {
+    const overrides = { excludeFileNamePattern: 'forge', forceFileId: String(chance.integer({ min: 1 })) };
+    vi.mocked(getMod).mockResolvedValueOnce(generateRemoteModDetails().generated);
+
+    await new Curseforge().fetchMod('1', [ReleaseType.RELEASE], '1.20.1', Loader.FABRIC, false, 'pinned', overrides);
+
+    expect(vi.mocked(getMod)).toHaveBeenCalledWith(
+      '1',
+      [ReleaseType.RELEASE],
+      '1.20.1',
+      Loader.FABRIC,
+      false,
+      undefined,
+      overrides
     );
   });
 
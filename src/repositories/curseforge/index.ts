@@ -1,5 +1,5 @@
 import { UnknownLoaderException } from '../../errors/UnknownLoaderException.js';
-import { Loader, ReleaseType, RemoteModDetails } from '../../lib/modlist.types.js';
+import { FileOverrides, Loader, ReleaseType, RemoteModDetails } from '../../lib/modlist.types.js';
 import { PlatformLookupResult, Repository } from '../index.js';
 import { getMod } from './fetch.js';
 import { lookup as cfLookup } from './lookup.js';
@@ -39,9 +39,11 @@ export class Curseforge implements Repository {
     allowedReleaseTypes: ReleaseType[],
     allowedGameVersion: string,
     loader: Loader,
-    allowFallback: boolean
+    allowFallback: boolean,
+    _fixedVersion?: string,
+    overrides?: FileOverrides
   ): Promise<RemoteModDetails> {
-    return getMod(projectId, allowedReleaseTypes, allowedGameVersion, loader, allowFallback);
+    return getMod(projectId, allowedReleaseTypes, allowedGameVersion, loader, allowFallback, undefined, overrides);
   }
 
   lookup(lookup: string[]): Promise<PlatformLookupResult[]> {
@@ -0,0 +1,77 @@
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateCurseforgeModFile } from '../../../test/generateCurseforgeModFile.js';
+import { CouldNotFindModException } from '../../errors/CouldNotFindModException.js';
+import { Platform } from '../../lib/modlist.types.js';
+import { CurseforgeModFile, getProjectFiles } from './fetch.js';
+import { CurseforgeGameVersionType } from './gameVersionTypes.js';
+import { getLatestFileOfEveryLoader } from './latestByLoader.js';
+
+vi.mock('./fetch.js');
+
+const fileFor = (loaderNames: string[], fileDate: string, isAvailable = true): CurseforgeModFile =>
+  generateCurseforgeModFile({
+    fileDate: fileDate,
+    isAvailable: isAvailable,
+    sortableGameVersions: [
+      { gameVersionName: '1.20.1', gameVersion: '1.20.1' },
+      ...loaderNames.map((name) => ({
+        gameVersionName: name,
+        gameVersion: '',
+        gameVersionTypeId: CurseforgeGameVersionType.LOADER
+      }))
+    ]
+  }).generated;
+
+describe('The latest Curseforge file of every loader', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+  });
+
+  it('groups the files of the project by their loader', async () => {
+    const oldFabric = fileFor(['Fabric'], '2023-01-01T00:00:00Z');
+    const newFabric = fileFor(['Fabric', 'Quilt'], '2023-06-01T00:00:00Z');
+    const forge = fileFor(['Forge'], '2023-03-01T00:00:00Z');
+    const newestQuilt = fileFor(['Quilt'], '2023-09-01T00:00:00Z');
+    vi.mocked(getProjectFiles).mockResolvedValueOnce([newestQuilt, newFabric, forge, oldFabric]);
+
+    const actual = await getLatestFileOfEveryLoader('1');
+
+    expect(actual).toEqual([
+      { loader: 'Fabric', file: newFabric },
+      { loader: 'Forge', file: forge },
+      { loader: 'Quilt', file: newestQuilt }
+    ]);
+  });
+
+  it('leaves out the files that are not available', async () => {
+    const available = fileFor(['Forge'], '2023-01-01T00:00:00Z');
+    vi.mocked(getProjectFiles).mockResolvedValueOnce([available, fileFor(['Forge'], '2023-06-01T00:00:00Z', false)]);
+
+    const actual = await getLatestFileOfEveryLoader('1');
+
+    expect(actual).toEqual([{ loader: 'Forge', file: available }]);
+  });
+
+  it('leaves out the files without a loader', async () => {
+    vi.mocked(getProjectFiles).mockResolvedValueOnce([fileFor([], '2023-01-01T00:00:00Z')]);
+
+    expect(await getLatestFileOfEveryLoader('1')).toEqual([]);
+  });
+
+  it('passes the signal on', async () => {
+    const controller = new AbortController();
+    vi.mocked(getProjectFiles).mockResolvedValueOnce([]);
+
+    await getLatestFileOfEveryLoader('1', controller.signal);
+
+    expect(vi.mocked(getProjectFiles)).toHaveBeenCalledWith('1', controller.signal);
+  });
+
+  it('throws when the project does not exist', async () => {
+    vi.mocked(getProjectFiles).mockRejectedValueOnce(new CouldNotFindModException('1', Platform.CURSEFORGE));
+
+    await expect(getLatestFileOfEveryLoader('1')).rejects.toThrow(
+      new CouldNotFindModException('1', Platform.CURSEFORGE)
+    );
+  });
+});
@@ -0,0 +1,39 @@
+import { CurseforgeModFile, getProjectFiles } from './fetch.js';
+import { loaders } from './gameVersionTypes.js';
+
+export interface LatestFileOfLoader {
+  /**
+   * The loader as Curseforge names it, like Fabric or NeoForge
+   */
+  loader: string;
+  file: CurseforgeModFile;
+}
+
+/**
+ * The newest available file of the project for every loader it has files for, to show what else is out there,
+ * like "latest: Fabric 1.3.0, Forge 1.2.1". It looks at every file regardless of the game version and the release
+ * type, so it must not be used to pick the file to install.
+ *
+ * @throws {CouldNotFindModException} When Curseforge doesn't know the project
+ * @throws {CurseforgePaginationError} When Curseforge keeps sending the same page
+ */
+export const getLatestFileOfEveryLoader = async (
+  projectId: string,
+  signal?: AbortSignal
+): Promise<LatestFileOfLoader[]> => {
+  const latest = new Map<string, LatestFileOfLoader>();
+
+  for (const file of await getProjectFiles(projectId, signal)) {
+    if (!file.isAvailable) {
+      continue;
+    }
+    for (const loader of loaders(file)) {
+      // The files come the newest first, the first one of the loader is its latest
+      if (!latest.has(loader.toLowerCase())) {
+        latest.set(loader.toLowerCase(), { loader: loader, file: file });
+      }
+    }
+  }
+
+  return [...latest.values()].sort((a, b) => a.loader.localeCompare(b.loader));
+};
@@ -1,14 +1,15 @@
 import { chance } from 'jest-chance';
-import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
 import { generateCurseforgeModFile } from '../../../test/generateCurseforgeModFile.js';
 import { generateRemoteModDetails } from '../../../test/generateRemoteDetails.js';
-import * as envvars from '../../env.js';
 import { Logger } from '../../lib/Logger.js';
+import { setBaseUrl } from '../../lib/baseUrl.js';
 import { Platform } from '../../lib/modlist.types.js';
+import { MaximumRetriesReached } from '../../lib/rateLimiter/MaximumRetriesReached.js';
 import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
 import { logger } from '../../mmm.js';
 import { curseforgeFileToRemoteModDetails } from './fetch.js';
-import { lookup } from './lookup.js';
+import { lookup, lookupFingerprints } from './lookup.js';
 
 vi.mock('../../lib/rateLimiter/index.js');
 vi.mock('../../lib/Logger.js');
@@ -16,22 +17,23 @@ vi.mock('./fetch.js');
 vi.mock('../../mmm.js');
 
 interface LocalTestContext {
-  apiKey: string;
   logger: Logger;
 }
 
+// The rate limiter gives up on a batch Curseforge keeps refusing, like one that is too large
+const refusedBatch = () => new MaximumRetriesReached(new Response(null, { status: 400 }));
+
 describe('The Curseforge Lookup module', () => {
-  beforeEach<LocalTestContext>((context) => {
+  beforeEach(() => {
     vi.resetAllMocks();
-    context.apiKey = chance.hash();
+  });
 
-    vi.spyOn(envvars, 'curseForgeApiKey', 'get').mockReturnValue(context.apiKey);
+  afterEach(() => {
+    setBaseUrl(Platform.CURSEFORGE);
   });
 
-  it<LocalTestContext>('correctly calls the curseforge api', async ({ apiKey }) => {
-    vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
-      ok: false // fastest way to exit out of the function under test
-    } as unknown as Response);
+  it('correctly calls the curseforge api', async () => {
+    vi.mocked(rateLimitingFetch).mockRejectedValueOnce(refusedBatch());
 
     await lookup(['fingerprint1', 'fingerprint2', 'fingerprint3']);
 
@@ -43,17 +45,24 @@ describe('The Curseforge Lookup module', () => {
     expect(requestParams.method).toEqual('POST');
     expect(requestParams.headers).toHaveProperty('Accept', 'application/json');
     expect(requestParams.headers).toHaveProperty('Content-Type', 'application/json');
-    expect(requestParams.headers).toHaveProperty('x-api-key', apiKey);
+    expect(requestParams.headers).not.toHaveProperty('x-api-key'); // the rate limiter injects it
     expect(requestParams.body).toMatchInlineSnapshot(
       '"{"fingerprints":["fingerprint1","fingerprint2","fingerprint3"]}"'
     );
   });
 
+  it('calls the configured base url', async () => {
+    setBaseUrl(Platform.CURSEFORGE, 'https://proxy.example.com/v1');
+    vi.mocked(rateLimitingFetch).mockRejectedValueOnce(refusedBatch());
+
+    await lookup(['fingerprint1']);
+
+    expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual('https://proxy.example.com/v1/fingerprints');
+  });
+
   it<LocalTestContext>('logs the failed attempt correctly', async () => {
-    vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
-      ok: false // fastest way to exit out of the function under test
-    } as unknown as Response);
-    const actual = await lookup([]);
+    vi.mocked(rateLimitingFetch).mockRejectedValueOnce(refusedBatch());
+    const actual = await lookup([chance.word()]);
 
     const logMessage = vi.mocked(logger.log).mock.calls[0][0];
     expect(logMessage).toMatchInlineSnapshot('"Could not reach Curseforge, please try again"');
@@ -91,4 +100,208 @@ describe('The Curseforge Lookup module', () => {
     expect(actual[0].modId).toEqual(modId.toString());
     expect(actual[0].mod).toBe(randomModFile);
   });
+
+  describe('when there are partial matches', () => {
+    it('keeps them apart from the exact matches', async () => {
+      const exactFile = generateCurseforgeModFile().generated;
+      const partialFile = generateCurseforgeModFile().generated;
+      const exactMod = generateRemoteModDetails().generated;
+      const partialMod = generateRemoteModDetails().generated;
+      const exactId = chance.integer({ min: 1, max: 999999 });
+      const partialId = chance.integer({ min: 1, max: 999999 });
+
+      vi.mocked(curseforgeFileToRemoteModDetails).mockReturnValueOnce(exactMod);
+      vi.mocked(curseforgeFileToRemoteModDetails).mockReturnValueOnce(partialMod);
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: async () => ({
+          data: {
+            exactMatches: [{ id: exactId, file: exactFile }],
+            exactFingerprints: [exactFile.fileFingerprint],
+            partialMatches: [{ id: partialId, file: partialFile }]
+          }
+        })
+      } as unknown as Response);
+
+      const actual = await lookupFingerprints([chance.word(), chance.word()]);
+
+      expect(vi.mocked(curseforgeFileToRemoteModDetails)).toHaveBeenCalledWith(partialFile, partialFile.displayName);
+      expect(actual.exact).toEqual([{ modId: String(exactId), platform: Platform.CURSEFORGE, mod: exactMod }]);
+      expect(actual.partial).toEqual([{ modId: String(partialId), platform: Platform.CURSEFORGE, mod: partialMod }]);
+    });
+
+    it('does not return them from the regular lookup', async () => {
+      const exactMod = generateRemoteModDetails().generated;
+
+      vi.mocked(curseforgeFileToRemoteModDetails).mockReturnValueOnce(exactMod);
+      vi.mocked(curseforgeFileToRemoteModDetails).mockReturnValueOnce(generateRemoteModDetails().generated);
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: async () => ({
+          data: {
+            exactMatches: [{ id: 1, file: generateCurseforgeModFile().generated }],
+            exactFingerprints: [1],
+            partialMatches: [{ id: 2, file: generateCurseforgeModFile().generated }]
+          }
+        })
+      } as unknown as Response);
+
+      const actual = await lookup([chance.word()]);
+
+      expect(actual).toEqual([{ modId: '1', platform: Platform.CURSEFORGE, mod: exactMod }]);
+    });
+
+    it('handles a response without partial matches', async () => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: async () => ({
+          data: {
+            exactMatches: [],
+            exactFingerprints: []
+          }
+        })
+      } as unknown as Response);
+
+      const actual = await lookupFingerprints([chance.word()]);
+
+      expect(actual).toEqual({ exact: [], partial: [], unmatched: [] });
+    });
+
+    it('reports the fingerprints as unmatched when Curseforge cannot be reached', async () => {
+      const fingerprint = chance.word();
+      vi.mocked(rateLimitingFetch).mockRejectedValueOnce(refusedBatch());
+
+      const actual = await lookupFingerprints([fingerprint]);
+
+      expect(actual).toEqual({ exact: [], partial: [], unmatched: [fingerprint] });
+    });
+
+    it('lets the errors that are not about Curseforge through', async () => {
+      const error = new Error(chance.sentence());
+      vi.mocked(rateLimitingFetch).mockRejectedValueOnce(error);
+
+      await expect(lookupFingerprints([chance.word()])).rejects.toThrow(error);
+    });
+  });
+
+  describe('when there are a lot of fingerprints', () => {
+    const fingerprints = (count: number) => Array.from({ length: count }, (_v, i) => String(i + 1));
+
+    it('does not call curseforge without fingerprints', async () => {
+      const actual = await lookupFingerprints([]);
+
+      expect(rateLimitingFetch).not.toHaveBeenCalled();
+      expect(actual).toEqual({ exact: [], partial: [], unmatched: [] });
+    });
+
+    it('splits them into chunks of a thousand', async () => {
+      vi.mocked(rateLimitingFetch).mockResolvedValue({
+        ok: true,
+        json: async () => ({
+          data: {
+            exactMatches: [],
+            exactFingerprints: []
+          }
+        })
+      } as unknown as Response);
+
+      await lookupFingerprints(fingerprints(2500));
+
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(3);
+
+      const sentFingerprints = vi
+        .mocked(rateLimitingFetch)
+        .mock.calls.map((call) => JSON.parse(call[1]!.body as string).fingerprints.length);
+      expect(sentFingerprints).toEqual([1000, 1000, 500]);
+    });
+
+    it('can use a custom chunk size', async () => {
+      vi.mocked(rateLimitingFetch).mockResolvedValue({
+        ok: true,
+        json: async () => ({
+          data: {
+            exactMatches: [],
+            exactFingerprints: []
+          }
+        })
+      } as unknown as Response);
+
+      await lookupFingerprints(fingerprints(10), 3);
+
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(4);
+    });
+
+    it('stops between the chunks when cancelled', async () => {
+      const controller = new AbortController();
+      vi.mocked(rateLimitingFetch).mockResolvedValue({
+        ok: true,
+        json: async () => {
+          controller.abort();
+          return {
+            data: {
+              exactMatches: [],
+              exactFingerprints: []
+            }
+          };
+        }
+      } as unknown as Response);
+
+      await expect(lookupFingerprints(fingerprints(10), 3, controller.signal)).rejects.toHaveProperty(
+        'name',
+        'AbortError'
+      );
+
+      expect(rateLimitingFetch).toHaveBeenCalledOnce();
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][1]?.signal).toBe(controller.signal);
+    });
+
+    it('merges the results of every chunk', async () => {
+      const firstFile = generateCurseforgeModFile().generated;
+      const secondFile = generateCurseforgeModFile().generated;
+      const firstMod = generateRemoteModDetails().generated;
+      const secondMod = generateRemoteModDetails().generated;
+
+      vi.mocked(curseforgeFileToRemoteModDetails).mockImplementation((file) =>
+        file === firstFile ? firstMod : secondMod
+      );
+
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: async () => ({
+          data: {
+            exactMatches: [{ id: 1, file: firstFile }],
+            exactFingerprints: [1],
+            unmatchedFingerprints: [3, 2]
+          }
+        })
+      } as unknown as Response);
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: async () => ({
+          data: {
+            exactMatches: [
+              { id: 1, file: firstFile },
+              { id: 2, file: secondFile }
+            ],
+            exactFingerprints: [4, 5],
+            partialMatches: [{ id: 2, file: secondFile }],
+            unmatchedFingerprints: [6]
+          }
+        })
+      } as unknown as Response);
+      vi.mocked(rateLimitingFetch).mockRejectedValueOnce(refusedBatch());
+
+      const actual = await lookupFingerprints(fingerprints(7), 3);
+
+      expect(rateLimitingFetch).toHaveBeenCalledTimes(3);
+      expect(actual.exact).toEqual([
+        { modId: '1', platform: Platform.CURSEFORGE, mod: firstMod },
+        { modId: '2', platform: Platform.CURSEFORGE, mod: secondMod }
+      ]);
+      expect(actual.partial).toEqual([{ modId: '2', platform: Platform.CURSEFORGE, mod: secondMod }]);
+      // The last chunk failed, its fingerprint is reported as unmatched
+      expect(actual.unmatched).toEqual(['2', '3', '6', '7']);
+      expect(logger.log).toHaveBeenCalledTimes(1);
+    });
+  });
 });
@@ -1,56 +1,131 @@
 import chalk from 'chalk';
-import { curseForgeApiKey } from '../../env.js';
+import { apiUrl } from '../../lib/baseUrl.js';
+import { chunk } from '../../lib/chunk.js';
 import { Platform } from '../../lib/modlist.types.js';
+import { MaximumRetriesReached } from '../../lib/rateLimiter/MaximumRetriesReached.js';
 import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { readJson } from '../../lib/rateLimiter/readJson.js';
 import { logger } from '../../mmm.js';
 import { PlatformLookupResult } from '../index.js';
 import { CurseforgeModFile, curseforgeFileToRemoteModDetails } from './fetch.js';
 
-interface CurseforgeLookupMatches {
+export interface CurseforgeLookupMatches {
   id: number;
   file: CurseforgeModFile;
 }
-interface CurseforgeLookupResult {
+export interface CurseforgeLookupResult {
   data: {
     exactMatches: CurseforgeLookupMatches[];
     exactFingerprints: number[];
+    partialMatches?: CurseforgeLookupMatches[];
+    /**
+     * The fingerprints that led to each partial match, keyed by the id of the matched file
+     */
+    partialMatchFingerprints?: Record<string, number[]>;
+    unmatchedFingerprints?: number[];
   };
 }
 
-export const lookup = async (fingerprints: string[]): Promise<PlatformLookupResult[]> => {
-  const url = 'https://api.curseforge.com/v1/fingerprints';
-  performance.mark('curseforge-lookup-start');
-  const modSearchResult = await rateLimitingFetch(url, {
-    headers: {
-      Accept: 'application/json',
-      'Content-Type': 'application/json',
-      'x-api-key': curseForgeApiKey
-    },
-    method: 'POST',
-    body: JSON.stringify({
-      fingerprints: fingerprints
-    })
+export interface CurseforgeFingerprintMatches {
+  exact: PlatformLookupResult[];
+  /**
+   * Files Curseforge recognises but that don't match byte for byte, like re-zipped jars.
+   */
+  partial: PlatformLookupResult[];
+  unmatched: string[];
+}
+
+/**
+ * Curseforge rejects fingerprint batches that are larger than this
+ */
+export const FINGERPRINT_CHUNK_SIZE = 1000;
+
+const toPlatformLookupResult = (match: CurseforgeLookupMatches): PlatformLookupResult => {
+  return {
+    modId: String(match.id),
+    platform: Platform.CURSEFORGE,
+    mod: curseforgeFileToRemoteModDetails(match.file, match.file.displayName)
+  };
+};
+
+const unique = (results: PlatformLookupResult[]): PlatformLookupResult[] => {
+  const seen = new Set<string>();
+  return results.filter((result) => {
+    const key = `${result.modId}-${result.mod.fileName}`;
+    if (seen.has(key)) {
+      return false;
+    }
+    seen.add(key);
+    return true;
   });
+};
+
+/**
+ * Asks Curseforge about a single batch of fingerprints, null means Curseforge couldn't be reached.
+ * The rate limiter only ever resolves with an ok response, a batch Curseforge keeps refusing runs out of retries.
+ */
+export const lookupChunk = async (fingerprints: string[], signal?: AbortSignal): Promise<CurseforgeLookupResult | null> => {
+  const url = apiUrl(Platform.CURSEFORGE, 'fingerprints');
+  try {
+    const modSearchResult = await rateLimitingFetch(url, {
+      headers: {
+        Accept: 'application/json',
+        'Content-Type': 'application/json'
+      },
+      method: 'POST',
+      body: JSON.stringify({
+        fingerprints: fingerprints
+      }),
+      signal: signal
+    });
 
-  if (!modSearchResult.ok) {
-    logger.log(chalk.whiteBright(chalk.bgRed('Could not reach Curseforge, please try again')));
-    return [];
+    return await readJson<CurseforgeLookupResult>(modSearchResult);
+  } catch (error) {
+    if (error instanceof MaximumRetriesReached) {
+      logger.log(chalk.whiteBright(chalk.bgRed('Could not reach Curseforge, please try again')));
+      return null;
+    }
+    throw error;
   }
+};
 
-  const data: CurseforgeLookupResult = await modSearchResult.json();
+export const lookupFingerprints = async (
+  fingerprints: string[],
+  chunkSize: number = FINGERPRINT_CHUNK_SIZE,
+  signal?: AbortSignal
+): Promise<CurseforgeFingerprintMatches> => {
+  performance.mark('curseforge-lookup-start');
 
-  const result: PlatformLookupResult[] = [];
+  const exact: PlatformLookupResult[] = [];
+  const partial: PlatformLookupResult[] = [];
+  const unmatched = new Set<string>();
 
-  data.data.exactMatches.forEach((match) => {
-    result.push({
-      modId: String(match.id),
-      platform: Platform.CURSEFORGE,
-      mod: curseforgeFileToRemoteModDetails(match.file, match.file.displayName)
-    });
-  });
+  for (const fingerprintChunk of chunk(fingerprints, chunkSize)) {
+    signal?.throwIfAborted();
+    const data = await lookupChunk(fingerprintChunk, signal);
+
+    // The jars of a chunk that couldn't be looked up are still reported, they're unmatched as far as we know
+    if (!data) {
+      fingerprintChunk.forEach((fingerprint) => unmatched.add(String(fingerprint)));
+      continue;
+    }
+
+    exact.push(...data.data.exactMatches.map(toPlatformLookupResult));
+    partial.push(...(data.data.partialMatches || []).map(toPlatformLookupResult));
+    (data.data.unmatchedFingerprints || []).forEach((fingerprint) => unmatched.add(String(fingerprint)));
+  }
 
   performance.mark('curseforge-lookup-end');
   performance.measure('curseforge-lookup', 'curseforge-lookup-start', 'curseforge-lookup-end');
 
-  return result;
+  return {
+    exact: unique(exact),
+    partial: unique(partial),
+    unmatched: [...new Set(fingerprints.map(String))].filter((fingerprint) => unmatched.has(fingerprint))
+  };
+};
+
+export const lookup = async (fingerprints: string[]): Promise<PlatformLookupResult[]> => {
+  const result = await lookupFingerprints(fingerprints);
+  return result.exact;
 };
@@ -0,0 +1,153 @@
+import { chance } from 'jest-chance';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { SearchFailedException } from '../../errors/SearchFailedException.js';
+import { setBaseUrl } from '../../lib/baseUrl.js';
+import { Loader, Platform } from '../../lib/modlist.types.js';
+import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { CurseforgeSearchMod, curseforgeSearchHit, searchMods } from './search.js';
+
+vi.mock('../../lib/rateLimiter/index.js');
+
+const generateProject = (): CurseforgeSearchMod => ({
+  id: chance.integer({ min: 1, max: 999999 }),
+  name: chance.word(),
+  slug: chance.word(),
+  summary: chance.sentence(),
+  logo: null,
+  authors: [{ name: chance.word() }],
+  downloadCount: chance.integer({ min: 0, max: 1000000 }),
+  latestFilesIndexes: [{ gameVersion: '1.20.1' }, { gameVersion: '1.19.2' }]
+});
+
+const assumeSearchPage = (projects: Partial<CurseforgeSearchMod>[], totalCount: number) => {
+  vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+    ok: true,
+    json: () =>
+      Promise.resolve({
+        data: projects,
+        pagination: {
+          index: 0,
+          pageSize: 50,
+          resultCount: projects.length,
+          totalCount: totalCount
+        }
+      })
+  } as Response);
+};
+
+describe('The Curseforge search', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+  });
+
+  afterEach(() => {
+    setBaseUrl(Platform.CURSEFORGE);
+  });
+
+  it('calls the search api with the right parameters', async () => {
+    assumeSearchPage([], 0);
+
+    await searchMods('fabric api', '1.20.1', Loader.FABRIC);
+
+    expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toMatchInlineSnapshot(
+      '"https://api.curseforge.com/v1/mods/search?gameId=432&classId=6&searchFilter=fabric+api&gameVersion=1.20.1&modLoaderType=4&sortField=2&sortOrder=desc&index=0&pageSize=50"'
+    );
+  });
+
+  it('calls the configured base url', async () => {
+    setBaseUrl(Platform.CURSEFORGE, 'https://proxy.example.com/v1');
+    assumeSearchPage([], 0);
+
+    await searchMods('fabric api', '1.20.1', Loader.FABRIC);
+
+    expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toMatch(/^https:\/\/proxy\.example\.com\/v1\/mods\/search\?/);
+  });
+
+  it('returns the results in the order curseforge ranked them', async () => {
+    const projects = [generateProject(), generateProject(), generateProject()];
+    assumeSearchPage(projects, 3);
+
+    const actual = await searchMods(chance.word(), '1.19.2', Loader.FORGE);
+
+    expect(actual).toEqual(projects);
+    expect(rateLimitingFetch).toHaveBeenCalledTimes(1);
+  });
+
+  it('pages through the results up to the limit', async () => {
+    const firstPage = Array.from({ length: 50 }, generateProject);
+    const secondPage = Array.from({ length: 25 }, generateProject);
+    assumeSearchPage(firstPage, 500);
+    assumeSearchPage(secondPage, 500);
+
+    const actual = await searchMods(chance.word(), '1.19.2', Loader.QUILT, 75);
+
+    expect(actual).toEqual([...firstPage, ...secondPage]);
+    expect(rateLimitingFetch).toHaveBeenCalledTimes(2);
+    expect(vi.mocked(rateLimitingFetch).mock.calls[1][0]).toContain('&index=50&pageSize=25');
+  });
+
+  it('does not return more results than the limit', async () => {
+    assumeSearchPage(Array.from({ length: 10 }, generateProject), 500);
+
+    const actual = await searchMods(chance.word(), '1.19.2', Loader.FORGE, 5);
+
+    expect(actual.length).toEqual(5);
+  });
+
+  it('stops when curseforge runs out of results', async () => {
+    assumeSearchPage([generateProject()], 100);
+    assumeSearchPage([], 100);
+
+    const actual = await searchMods(chance.word(), '1.19.2', Loader.FORGE, 100);
+
+    expect(actual.length).toEqual(1);
+    expect(rateLimitingFetch).toHaveBeenCalledTimes(2);
+  });
+
+  it('throws when the search fails', async () => {
+    const query = chance.word();
+    vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+      ok: false
+    } as Response);
+
+    await expect(searchMods(query, '1.19.2', Loader.FORGE)).rejects.toThrow(
+      new SearchFailedException(query, Platform.CURSEFORGE)
+    );
+  });
+
+  it('fills in what the search left out', async () => {
+    const project = { id: 1, name: chance.word(), slug: chance.word(), summary: chance.sentence(), logo: null };
+    assumeSearchPage([project], 1);
+
+    const actual = await searchMods(chance.word(), '1.19.2', Loader.FORGE);
+
+    expect(actual).toEqual([{ ...project, authors: [], downloadCount: 0, latestFilesIndexes: [] }]);
+  });
+
+  it('can be presented alongside the results of the other platforms', () => {
+    const project = generateProject();
+
+    expect(curseforgeSearchHit(project)).toEqual({
+      platform: Platform.CURSEFORGE,
+      id: String(project.id),
+      slug: project.slug,
+      name: project.name,
+      summary: project.summary,
+      author: project.authors[0].name,
+      downloads: project.downloadCount,
+      latestGameVersion: '1.20.1'
+    });
+  });
+
+  it('lists every author of a mod', () => {
+    const project = { ...generateProject(), authors: [{ name: 'first' }, { name: 'second' }] };
+
+    expect(curseforgeSearchHit(project).author).toEqual('first, second');
+  });
+
+  it('has no latest game version for a mod without files', () => {
+    const project = { ...generateProject(), latestFilesIndexes: [] };
+
+    expect(curseforgeSearchHit(project).latestGameVersion).toBeUndefined();
+  });
+});
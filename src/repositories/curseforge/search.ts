@@ -0,0 +1,114 @@
+import { SearchFailedException } from '../../errors/SearchFailedException.js';
+import { apiUrl } from '../../lib/baseUrl.js';
+import { Loader, Platform } from '../../lib/modlist.types.js';
+import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { SearchHit } from '../index.js';
+import { CurseforgeMod, curseforgeModFromProject } from './fetch.js';
+import { Curseforge } from './index.js';
+
+export const MINECRAFT_GAME_ID = 432;
+export const MODS_CLASS_ID = 6;
+const SORT_BY_POPULARITY = 2;
+
+/**
+ * Curseforge doesn't allow bigger pages than this
+ */
+const MAX_PAGE_SIZE = 50;
+
+export const SEARCH_RESULT_LIMIT = 50;
+
+/**
+ * The search sends more about a mod than the mod details are read for
+ */
+export interface CurseforgeSearchMod extends CurseforgeMod {
+  authors: { name: string }[];
+  downloadCount: number;
+  /**
+   * The latest file of every game version and loader, the newest game version first
+   */
+  latestFilesIndexes: { gameVersion: string }[];
+}
+
+interface CurseforgeSearchResult {
+  data: CurseforgeSearchMod[];
+  pagination: {
+    index: number;
+    pageSize: number;
+    resultCount: number;
+    totalCount: number;
+  };
+}
+
+const searchUrl = (query: string, gameVersion: string, loader: Loader, index: number, pageSize: number): string => {
+  const url = new URL(apiUrl(Platform.CURSEFORGE, 'mods/search'));
+  url.searchParams.set('gameId', String(MINECRAFT_GAME_ID));
+  url.searchParams.set('classId', String(MODS_CLASS_ID));
+  url.searchParams.set('searchFilter', query);
+  url.searchParams.set('gameVersion', gameVersion);
+  url.searchParams.set('modLoaderType', String(Curseforge.curseforgeLoaderFromLoader(loader)));
+  url.searchParams.set('sortField', String(SORT_BY_POPULARITY));
+  url.searchParams.set('sortOrder', 'desc');
+  url.searchParams.set('index', String(index));
+  url.searchParams.set('pageSize', String(pageSize));
+  return url.toString();
+};
+
+const curseforgeSearchMod = (mod: CurseforgeSearchMod): CurseforgeSearchMod => {
+  return {
+    ...curseforgeModFromProject(mod),
+    authors: mod.authors || [],
+    downloadCount: mod.downloadCount || 0,
+    latestFilesIndexes: mod.latestFilesIndexes || []
+  };
+};
+
+export const curseforgeSearchHit = (mod: CurseforgeSearchMod): SearchHit => {
+  return {
+    platform: Platform.CURSEFORGE,
+    id: String(mod.id),
+    slug: mod.slug,
+    name: mod.name,
+    summary: mod.summary,
+    author: mod.authors.map((author) => author.name).join(', '),
+    downloads: mod.downloadCount,
+    latestGameVersion: mod.latestFilesIndexes[0]?.gameVersion
+  };
+};
+
+/**
+ * Finds the mods matching the query in the order Curseforge ranks them.
+ */
+export const searchMods = async (
+  query: string,
+  gameVersion: string,
+  loader: Loader,
+  limit: number = SEARCH_RESULT_LIMIT
+): Promise<CurseforgeSearchMod[]> => {
+  performance.mark('curseforge-search-start');
+  const results: CurseforgeSearchMod[] = [];
+
+  while (results.length < limit) {
+    const pageSize = Math.min(MAX_PAGE_SIZE, limit - results.length);
+    const response = await rateLimitingFetch(searchUrl(query, gameVersion, loader, results.length, pageSize), {
+      headers: {
+        Accept: 'application/json'
+      }
+    });
+
+    if (!response.ok) {
+      throw new SearchFailedException(query, Platform.CURSEFORGE);
+    }
+
+    const searchResult: CurseforgeSearchResult = await response.json();
+    results.push(...searchResult.data.map(curseforgeSearchMod));
+
+    if (searchResult.data.length === 0 || results.length >= searchResult.pagination.totalCount) {
+      break;
+    }
+  }
+
+  performance.mark('curseforge-search-end');
+  performance.measure('curseforge-search', 'curseforge-search-start', 'curseforge-search-end');
+
+  return results.slice(0, limit);
+};
@@ -3,10 +3,21 @@ import { beforeEach, describe, expect, it, vi } from 'vitest';
 import { generatePlatformLookupResult } from '../../test/generatePlatformLookupResult.js';
 import { generateRandomPlatform } from '../../test/generateRandomPlatform.js';
 import { generateRemoteModDetails } from '../../test/generateRemoteDetails.js';
+import { CouldNotFindModException } from '../errors/CouldNotFindModException.js';
+import { CurseforgeDownloadUrlError } from '../errors/CurseforgeDownloadUrlError.js';
+import { NoRemoteFileFound } from '../errors/NoRemoteFileFound.js';
 import { UnknownPlatformException } from '../errors/UnknownPlatformException.js';
 import { Loader, Platform, ReleaseType } from '../lib/modlist.types.js';
 import { Curseforge } from './curseforge/index.js';
-import { LookupInput, PlatformLookupResult, fetchModDetails, lookup } from './index.js';
+import {
+  LookupInput,
+  PlatformLookupResult,
+  ProjectToResolve,
+  fetchModDetails,
+  getRepository,
+  lookup,
+  resolveProjects
+} from './index.js';
 import { Modrinth } from './modrinth/index.js';
 
 vi.mock('./modrinth/index.js', () => {
@@ -64,6 +75,21 @@ describe('The repository facade', () => {
     }
   });
 
+  describe('when getting a repository', () => {
+    it('returns the Curseforge repository for Curseforge', () => {
+      expect(getRepository(Platform.CURSEFORGE)).toBeInstanceOf(Curseforge);
+    });
+
+    it('returns the Modrinth repository for Modrinth', () => {
+      expect(getRepository(Platform.MODRINTH)).toBeInstanceOf(Modrinth);
+    });
+
+    it('throws an exception when an unknown platform is used', () => {
+      const invalidPlatform = chance.word();
+      expect(() => getRepository(invalidPlatform as Platform)).toThrow(new UnknownPlatformException(invalidPlatform));
+    });
+  });
+
   describe('when fetching mod details', () => {
     it<RepositoryTestContext>('throws an exception when an unknown platform is used', async (context) => {
       const invalidPlatform = chance.word();
@@ -102,12 +128,360 @@ describe('The repository facade', () => {
           context.gameVersion,
           context.loader,
           context.allowFallback,
-          context.version
+          context.version,
+          undefined
+        );
+      });
+
+      it<RepositoryTestContext>(`passes the file overrides on to ${platform}`, async (context) => {
+        const overrides = { excludeFileNamePattern: '-forge', forceFileId: '4567890' };
+        vi.mocked(implementation).mockResolvedValueOnce(generateRemoteModDetails().generated);
+        await fetchModDetails(
+          platform,
+          context.id,
+          context.allowedReleaseTypes,
+          context.gameVersion,
+          context.loader,
+          context.allowFallback,
+          context.version,
+          undefined,
+          overrides
+        );
+
+        expect(implementation).toBeCalledWith(
+          context.id,
+          context.allowedReleaseTypes,
+          context.gameVersion,
+          context.loader,
+          context.allowFallback,
+          context.version,
+          overrides
         );
       });
     });
   });
 
+  describe('when the mod accepts more than one loader', () => {
+    const loaders = [Loader.FORGE, Loader.FABRIC];
+
+    it<RepositoryTestContext>('uses the second loader when only it has a file', async (context) => {
+      const details = generateRemoteModDetails().generated;
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(new NoRemoteFileFound('Sodium', Platform.CURSEFORGE));
+      vi.mocked(curseforge.fetchMod).mockResolvedValueOnce(details);
+
+      const actual = await fetchModDetails(
+        Platform.CURSEFORGE,
+        context.id,
+        context.allowedReleaseTypes,
+        context.gameVersion,
+        loaders,
+        context.allowFallback
+      );
+
+      expect(actual).toEqual({ ...details, loader: Loader.FABRIC });
+      expect(vi.mocked(curseforge.fetchMod).mock.calls.map((call) => call[3])).toEqual(loaders);
+    });
+
+    it<RepositoryTestContext>('stops at the first loader that has a file', async (context) => {
+      vi.mocked(modrinth.fetchMod).mockResolvedValueOnce(generateRemoteModDetails().generated);
+
+      const actual = await fetchModDetails(
+        Platform.MODRINTH,
+        context.id,
+        context.allowedReleaseTypes,
+        context.gameVersion,
+        loaders,
+        context.allowFallback
+      );
+
+      expect(actual.loader).toEqual(Loader.FORGE);
+      expect(modrinth.fetchMod).toHaveBeenCalledOnce();
+    });
+
+    it<RepositoryTestContext>('asks the platform fallback before moving on to the next loader', async (context) => {
+      const details = generateRemoteModDetails().generated;
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(new NoRemoteFileFound('Sodium', Platform.CURSEFORGE));
+      vi.mocked(modrinth.fetchMod).mockResolvedValueOnce(details);
+
+      const actual = await fetchModDetails(
+        Platform.CURSEFORGE,
+        context.id,
+        context.allowedReleaseTypes,
+        context.gameVersion,
+        loaders,
+        context.allowFallback,
+        undefined,
+        { type: Platform.MODRINTH, id: 'sodium' }
+      );
+
+      expect(actual).toEqual({ ...details, loader: Loader.FORGE });
+    });
+
+    it<RepositoryTestContext>('reports the error of the last loader when none of them has a file', async (context) => {
+      const error = new NoRemoteFileFound('Sodium', Platform.CURSEFORGE);
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(new NoRemoteFileFound('Sodium', Platform.CURSEFORGE));
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(error);
+
+      await expect(
+        fetchModDetails(
+          Platform.CURSEFORGE,
+          context.id,
+          context.allowedReleaseTypes,
+          context.gameVersion,
+          loaders,
+          context.allowFallback
+        )
+      ).rejects.toBe(error);
+    });
+
+    it<RepositoryTestContext>('does not try the other loaders for a mod that is gone', async (context) => {
+      const error = new CouldNotFindModException(context.id, Platform.CURSEFORGE);
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(error);
+
+      await expect(
+        fetchModDetails(
+          Platform.CURSEFORGE,
+          context.id,
+          context.allowedReleaseTypes,
+          context.gameVersion,
+          loaders,
+          context.allowFallback
+        )
+      ).rejects.toBe(error);
+      expect(curseforge.fetchMod).toHaveBeenCalledOnce();
+    });
+  });
+
+  describe('when the mod has a fallback on another platform', () => {
+    const fallback = { type: Platform.MODRINTH, id: 'sodium' };
+
+    it<RepositoryTestContext>('uses the other platform when the download is disabled', async (context) => {
+      const modrinthDetails = generateRemoteModDetails().generated;
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(new CurseforgeDownloadUrlError('Sodium', context.id, 1));
+      vi.mocked(modrinth.fetchMod).mockResolvedValueOnce(modrinthDetails);
+
+      const actual = await fetchModDetails(
+        Platform.CURSEFORGE,
+        context.id,
+        context.allowedReleaseTypes,
+        context.gameVersion,
+        context.loader,
+        context.allowFallback,
+        context.version,
+        fallback
+      );
+
+      expect(actual).toBe(modrinthDetails);
+      expect(modrinth.fetchMod).toHaveBeenCalledWith(
+        'sodium',
+        context.allowedReleaseTypes,
+        context.gameVersion,
+        context.loader,
+        context.allowFallback
+      );
+    });
+
+    it<RepositoryTestContext>('uses the release types of the other platform', async (context) => {
+      const releaseTypes = [ReleaseType.RELEASE, ReleaseType.BETA];
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(new NoRemoteFileFound('Sodium', Platform.CURSEFORGE));
+      vi.mocked(modrinth.fetchMod).mockResolvedValueOnce(generateRemoteModDetails().generated);
+
+      await fetchModDetails(
+        Platform.CURSEFORGE,
+        context.id,
+        [ReleaseType.RELEASE],
+        context.gameVersion,
+        context.loader,
+        context.allowFallback,
+        undefined,
+        { ...fallback, allowedReleaseTypes: releaseTypes }
+      );
+
+      expect(modrinth.fetchMod).toHaveBeenCalledWith(
+        'sodium',
+        releaseTypes,
+        context.gameVersion,
+        context.loader,
+        context.allowFallback
+      );
+    });
+
+    it<RepositoryTestContext>('uses the other platform when there is no suitable file', async (context) => {
+      const modrinthDetails = generateRemoteModDetails().generated;
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(new NoRemoteFileFound('Sodium', Platform.CURSEFORGE));
+      vi.mocked(modrinth.fetchMod).mockResolvedValueOnce(modrinthDetails);
+
+      const actual = await fetchModDetails(
+        Platform.CURSEFORGE,
+        context.id,
+        context.allowedReleaseTypes,
+        context.gameVersion,
+        context.loader,
+        context.allowFallback,
+        undefined,
+        fallback
+      );
+
+      expect(actual).toBe(modrinthDetails);
+    });
+
+    it<RepositoryTestContext>('does not look for a mod that is gone', async (context) => {
+      const error = new CouldNotFindModException(context.id, Platform.CURSEFORGE);
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(error);
+
+      await expect(
+        fetchModDetails(
+          Platform.CURSEFORGE,
+          context.id,
+          context.allowedReleaseTypes,
+          context.gameVersion,
+          context.loader,
+          context.allowFallback,
+          undefined,
+          fallback
+        )
+      ).rejects.toBe(error);
+      expect(modrinth.fetchMod).not.toHaveBeenCalled();
+    });
+
+    it<RepositoryTestContext>('only falls back when asked to', async (context) => {
+      const error = new CurseforgeDownloadUrlError('Sodium', context.id, 1);
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(error);
+
+      await expect(
+        fetchModDetails(
+          Platform.CURSEFORGE,
+          context.id,
+          context.allowedReleaseTypes,
+          context.gameVersion,
+          context.loader,
+          context.allowFallback
+        )
+      ).rejects.toBe(error);
+      expect(modrinth.fetchMod).not.toHaveBeenCalled();
+    });
+
+    it<RepositoryTestContext>('reports the error of the other platform when it fails too', async (context) => {
+      const error = new NoRemoteFileFound('sodium', Platform.MODRINTH);
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(new CurseforgeDownloadUrlError('Sodium', context.id, 1));
+      vi.mocked(modrinth.fetchMod).mockRejectedValueOnce(error);
+
+      await expect(
+        fetchModDetails(
+          Platform.CURSEFORGE,
+          context.id,
+          context.allowedReleaseTypes,
+          context.gameVersion,
+          context.loader,
+          context.allowFallback,
+          undefined,
+          fallback
+        )
+      ).rejects.toBe(error);
+    });
+
+    it<RepositoryTestContext>('is used when resolving many projects', async (context) => {
+      const modrinthDetails = generateRemoteModDetails().generated;
+      vi.mocked(curseforge.fetchMod).mockRejectedValueOnce(new CurseforgeDownloadUrlError('Sodium', context.id, 1));
+      vi.mocked(modrinth.fetchMod).mockResolvedValueOnce(modrinthDetails);
+      const project: ProjectToResolve = {
+        platform: Platform.CURSEFORGE,
+        id: context.id,
+        allowedReleaseTypes: context.allowedReleaseTypes,
+        gameVersion: context.gameVersion,
+        loader: context.loader,
+        allowFallback: context.allowFallback,
+        fallback: fallback
+      };
+
+      const actual = await resolveProjects([project]);
+
+      expect(actual.resolved).toEqual([{ project: project, details: modrinthDetails }]);
+    });
+  });
+
+  describe('when resolving many projects', () => {
+    const projectFor = (context: RepositoryTestContext, id: string): ProjectToResolve => ({
+      platform: Platform.CURSEFORGE,
+      id: id,
+      allowedReleaseTypes: context.allowedReleaseTypes,
+      gameVersion: context.gameVersion,
+      loader: context.loader,
+      allowFallback: context.allowFallback,
+      version: context.version
+    });
+
+    it<RepositoryTestContext>('resolves every project', async (context) => {
+      const projects = Array.from({ length: 5 }, (_v, i) => projectFor(context, `project-${i}`));
+      const details = projects.map(() => generateRemoteModDetails().generated);
+      details.forEach((detail) => vi.mocked(curseforge.fetchMod).mockResolvedValueOnce(detail));
+
+      const actual = await resolveProjects(projects);
+
+      expect(actual.errors).toEqual([]);
+      expect(actual.resolved).toEqual(projects.map((project, index) => ({ project: project, details: details[index] })));
+      expect(curseforge.fetchMod).toHaveBeenCalledWith(
+        projects[0].id,
+        context.allowedReleaseTypes,
+        context.gameVersion,
+        context.loader,
+        context.allowFallback,
+        context.version,
+        undefined
+      );
+    });
+
+    it<RepositoryTestContext>('resolves a project with its file overrides', async (context) => {
+      const overrides = { forceFileId: '4567890' };
+      vi.mocked(curseforge.fetchMod).mockResolvedValueOnce(generateRemoteModDetails().generated);
+
+      await resolveProjects([{ ...projectFor(context, 'forced'), overrides: overrides }]);
+
+      expect(curseforge.fetchMod).toHaveBeenCalledWith(
+        'forced',
+        context.allowedReleaseTypes,
+        context.gameVersion,
+        context.loader,
+        context.allowFallback,
+        context.version,
+        overrides
+      );
+    });
+
+    it<RepositoryTestContext>('does not let a failing project stop the others', async (context) => {
+      const projects = ['first', 'broken', 'last'].map((id) => projectFor(context, id));
+      const error = new Error(chance.sentence());
+      vi.mocked(curseforge.fetchMod).mockImplementation(async (id) => {
+        if (id === 'broken') {
+          throw error;
+        }
+        return generateRemoteModDetails({ name: id }).generated;
+      });
+
+      const actual = await resolveProjects(projects, 1);
+
+      expect(actual.resolved.map((resolved) => resolved.details.name)).toEqual(['first', 'last']);
+      expect(actual.errors).toEqual([{ project: projects[1], error: error }]);
+    });
+
+    it<RepositoryTestContext>('limits the number of concurrent lookups', async (context) => {
+      const projects = Array.from({ length: 12 }, (_v, i) => projectFor(context, `project-${i}`));
+      let active = 0;
+      let maxActive = 0;
+      vi.mocked(curseforge.fetchMod).mockImplementation(async () => {
+        active++;
+        maxActive = Math.max(maxActive, active);
+        await new Promise((resolve) => setTimeout(resolve, 1));
+        active--;
+        return generateRemoteModDetails().generated;
+      });
+
+      await resolveProjects(projects, 4);
+
+      expect(maxActive).toEqual(4);
+    });
+  });
+
   describe('when looking up mods', () => {
     it('can handle an empty input', async () => {
       const actual = await lookup([]);
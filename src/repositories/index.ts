@@ -1,14 +1,71 @@
+import { CurseforgeDownloadUrlError } from '../errors/CurseforgeDownloadUrlError.js';
+import { NoRemoteFileFound } from '../errors/NoRemoteFileFound.js';
 import { UnknownPlatformException } from '../errors/UnknownPlatformException.js';
-import { Loader, Platform, ReleaseType, RemoteModDetails } from '../lib/modlist.types.js';
+import {
+  FileOverrides,
+  Loader,
+  ModFallback,
+  Platform,
+  ReleaseType,
+  RemoteModDetails,
+  ResolvedModFallback
+} from '../lib/modlist.types.js';
+import { mapWithConcurrency } from '../lib/workerPool.js';
 import { Curseforge } from './curseforge/index.js';
 import { Modrinth } from './modrinth/index.js';
 
+/**
+ * A file of a mod in the same shape for every platform, so choosing a file doesn't depend on where it comes from
+ */
+export interface ReleasedFile {
+  platform: Platform;
+  /**
+   * The file id on Curseforge, the version id on Modrinth
+   */
+  id: string;
+  displayName: string;
+  fileName: string;
+  /**
+   * Only the Minecraft versions, without the loaders and the other tags
+   */
+  gameVersions: string[];
+  /**
+   * In lower case, like fabric or neoforge
+   */
+  loaders: string[];
+  releaseType: ReleaseType;
+  downloadUrl: string;
+  sha1: string;
+  /**
+   * The size of the file in bytes, when the platform tells it
+   */
+  length?: number;
+  releaseDate: string;
+}
+
 export interface PlatformLookupResult {
   platform: Platform;
   modId: string;
   mod: RemoteModDetails;
 }
 
+/**
+ * A mod found by searching one of the platforms, in the same shape for every platform
+ */
+export interface SearchHit {
+  platform: Platform;
+  id: string;
+  slug: string;
+  name: string;
+  summary: string;
+  author: string;
+  downloads: number;
+  /**
+   * The newest Minecraft version the mod has a file for, if it has any
+   */
+  latestGameVersion?: string;
+}
+
 export interface LookupHits {
   platform: Platform;
   mod: RemoteModDetails;
@@ -35,7 +92,8 @@ export interface Repository {
     allowedGameVersion: string,
     loader: Loader,
     allowFallback: boolean,
-    version?: string
+    version?: string,
+    overrides?: FileOverrides
   ) => Promise<RemoteModDetails>;
   lookup: (lookup: string[]) => Promise<PlatformLookupResult[]>;
 }
@@ -45,7 +103,13 @@ export interface ResultItem {
   hits: PlatformLookupResult[];
 }
 
-const getRepository = (platform: Platform): Repository => {
+/**
+ * Returns the repository implementation for the given platform
+ *
+ * @param platform
+ * @throws {UnknownPlatformException} When the platform is not supported
+ */
+export const getRepository = (platform: Platform): Repository => {
   switch (platform) {
     case Platform.CURSEFORGE:
       return new Curseforge();
@@ -56,6 +120,49 @@ const getRepository = (platform: Platform): Repository => {
   }
 };
 
+/**
+ * Fetches the details of the mod for one loader, asking the platform fallback when the platform has no usable file
+ */
+const fetchModDetailsForLoader = async (
+  platform: Platform,
+  id: string,
+  allowedReleaseTypes: ReleaseType[],
+  gameVersion: string,
+  loader: Loader,
+  allowFallback: boolean,
+  fixedModVersion?: string,
+  platformFallback?: ModFallback | ResolvedModFallback,
+  overrides?: FileOverrides
+) => {
+  const repository = getRepository(platform);
+  try {
+    return await repository.fetchMod(
+      id,
+      allowedReleaseTypes,
+      gameVersion,
+      loader,
+      allowFallback,
+      fixedModVersion,
+      overrides
+    );
+  } catch (error) {
+    // A mod that is gone is a configuration problem, only a mod without a usable file is looked for elsewhere
+    const hasNoUsableFile = error instanceof NoRemoteFileFound || error instanceof CurseforgeDownloadUrlError;
+    if (!platformFallback || !hasNoUsableFile) {
+      throw error;
+    }
+
+    const fallbackRepository = getRepository(platformFallback.type);
+    return await fallbackRepository.fetchMod(
+      platformFallback.id,
+      'allowedReleaseTypes' in platformFallback ? platformFallback.allowedReleaseTypes : allowedReleaseTypes,
+      gameVersion,
+      loader,
+      allowFallback
+    );
+  }
+};
+
 /**
  * Fetches the mod's details
  *
@@ -63,9 +170,13 @@ const getRepository = (platform: Platform): Repository => {
  * @param id
  * @param allowedReleaseTypes
  * @param gameVersion
- * @param loader
+ * @param loader The loaders are tried in order until one of them has a file, the details tell which one it was
  * @param allowFallback
  * @param fixedModVersion
+ * @param platformFallback The same mod on another platform, asked when the platform has no file we can download.
+ *                         The fixed version is a file name of the first platform so it isn't used for the fallback.
+ *                         Without its own release types it gets the ones of the first platform.
+ * @param overrides Steer the file selection of the first platform, they aren't used for the fallback either
  * @throws {CouldNotFindModException} When the mod itself cannot be found
  * @throws {NoRemoteFileFound} When a suitable file for the mod cannot be found
  */
@@ -74,12 +185,119 @@ export const fetchModDetails = async (
   id: string,
   allowedReleaseTypes: ReleaseType[],
   gameVersion: string,
-  loader: Loader,
+  loader: Loader | Loader[],
   allowFallback: boolean,
-  fixedModVersion?: string
-) => {
-  const repository = getRepository(platform);
-  return await repository.fetchMod(id, allowedReleaseTypes, gameVersion, loader, allowFallback, fixedModVersion);
+  fixedModVersion?: string,
+  platformFallback?: ModFallback | ResolvedModFallback,
+  overrides?: FileOverrides
+): Promise<RemoteModDetails> => {
+  if (!Array.isArray(loader)) {
+    return fetchModDetailsForLoader(
+      platform,
+      id,
+      allowedReleaseTypes,
+      gameVersion,
+      loader,
+      allowFallback,
+      fixedModVersion,
+      platformFallback,
+      overrides
+    );
+  }
+
+  let lastError: unknown;
+  for (const acceptedLoader of loader) {
+    try {
+      const details = await fetchModDetailsForLoader(
+        platform,
+        id,
+        allowedReleaseTypes,
+        gameVersion,
+        acceptedLoader,
+        allowFallback,
+        fixedModVersion,
+        platformFallback,
+        overrides
+      );
+      return { ...details, loader: acceptedLoader };
+    } catch (error) {
+      // Only a loader without a file is worth moving on from, the other errors are the same for every loader
+      if (!(error instanceof NoRemoteFileFound)) {
+        throw error;
+      }
+      lastError = error;
+    }
+  }
+  throw lastError;
+};
+
+export interface ProjectToResolve {
+  platform: Platform;
+  id: string;
+  allowedReleaseTypes: ReleaseType[];
+  gameVersion: string;
+  loader: Loader;
+  allowFallback: boolean;
+  version?: string;
+  fallback?: ModFallback | ResolvedModFallback;
+  overrides?: FileOverrides;
+}
+
+export interface ResolvedProject {
+  project: ProjectToResolve;
+  details: RemoteModDetails;
+}
+
+export interface ProjectResolutionError {
+  project: ProjectToResolve;
+  error: unknown;
+}
+
+export interface ProjectResolutionResult {
+  resolved: ResolvedProject[];
+  errors: ProjectResolutionError[];
+}
+
+/**
+ * Fetches the details of many projects at once with a limited number of concurrent lookups.
+ * The requests still go through the rate limiter. A project that fails is reported in the errors
+ * and doesn't stop the others.
+ *
+ * @param projects
+ * @param concurrency The maximum number of projects resolved at the same time
+ */
+export const resolveProjects = async (
+  projects: ProjectToResolve[],
+  concurrency?: number
+): Promise<ProjectResolutionResult> => {
+  const settled = await mapWithConcurrency(
+    projects,
+    (project) =>
+      fetchModDetails(
+        project.platform,
+        project.id,
+        project.allowedReleaseTypes,
+        project.gameVersion,
+        project.loader,
+        project.allowFallback,
+        project.version,
+        project.fallback,
+        project.overrides
+      ),
+    concurrency
+  );
+
+  const result: ProjectResolutionResult = { resolved: [], errors: [] };
+
+  settled.forEach((outcome, index) => {
+    if (outcome.status === 'rejected') {
+      result.errors.push({ project: projects[index], error: outcome.reason });
+      return;
+    }
+    result.resolved.push({ project: projects[index], details: outcome.value });
+  });
+
+  return result;
 };
 
 export const lookup = async (lookup: LookupInput[]): Promise<ResultItem[]> => {
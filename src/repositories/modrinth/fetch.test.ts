@@ -2,12 +2,23 @@ import { chance } from 'jest-chance';
 import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
 import { generateModrinthFile } from '../../../test/generateModrinthFile.js';
 import { generateModrinthVersion } from '../../../test/generateModrinthVersion.js';
+import { RecordingApiLogger, recordingApiLogger } from '../../../test/recordingApiLogger.js';
 import { CouldNotFindModException } from '../../errors/CouldNotFindModException.js';
 import { NoRemoteFileFound } from '../../errors/NoRemoteFileFound.js';
-import { Loader, Platform, ReleaseType } from '../../lib/modlist.types.js';
+import { setApiLogger } from '../../lib/apiLogger.js';
+import { setBaseUrl } from '../../lib/baseUrl.js';
+import { setStrictGameVersionMatching } from '../../lib/gameVersionMatcher.js';
+import { setStrictLoaderMatching } from '../../lib/loaderCompatibility.js';
+import { FileOverrides, Loader, Platform, ReleaseType } from '../../lib/modlist.types.js';
 import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
 import { RepositoryTestContext } from '../index.test.js';
-import { ModrinthVersion, getMod } from './fetch.js';
+import {
+  ModrinthVersion,
+  getMod,
+  getProjectSides,
+  getVersionsForProject,
+  modrinthVersionToReleasedFile
+} from './fetch.js';
 
 vi.mock('../../lib/rateLimiter/index.js');
 const assumeFailedModFetch = () => {
@@ -55,10 +66,15 @@ describe('The Modrinth repository', () => {
     context.gameVersion = chance.pickone(['1.16.5', '1.17.1', '1.18.1', '1.18.2', '1.19']);
     context.loader = chance.pickone(Object.values(Loader));
     context.allowFallback = false;
+    // Only the compatibility tests expect the files of other loaders and game versions to be accepted
+    setStrictLoaderMatching(true);
+    setStrictGameVersionMatching(true);
   });
 
   afterEach(() => {
     vi.resetAllMocks();
+    setStrictLoaderMatching();
+    setStrictGameVersionMatching();
   });
 
   it<RepositoryTestContext>('throws an error when the mod details could not be fetched', async (context) => {
@@ -185,6 +201,7 @@ describe('The Modrinth repository', () => {
       expect(actual).toEqual({
         name: randomName,
         fileName: randomFile.filename,
+        version: versionToFind.version_number,
         releaseDate: versionToFind.date_published,
         hash: randomFile.hashes.sha1,
         downloadUrl: randomFile.url
@@ -230,6 +247,7 @@ describe('The Modrinth repository', () => {
       expect(actual).toEqual({
         name: randomName,
         fileName: randomFile.filename,
+        version: versionToFind.version_number,
         releaseDate: versionToFind.date_published,
         hash: randomFile.hashes.sha1,
         downloadUrl: randomFile.url
@@ -264,12 +282,79 @@ describe('The Modrinth repository', () => {
     expect(actual).toEqual({
       name: randomName,
       fileName: randomFile.filename,
+      version: randomVersion.version_number,
       releaseDate: randomVersion.date_published,
       hash: randomFile.hashes.sha1,
       downloadUrl: randomFile.url
     });
   });
 
+  it<RepositoryTestContext>('passes on the size of the file', async (context) => {
+    const randomFile = generateModrinthFile({ size: chance.integer({ min: 1, max: 100000 }) }).generated;
+    const randomVersion = generateModrinthVersion({
+      loaders: [context.loader],
+      // eslint-disable-next-line camelcase
+      version_type: ReleaseType.RELEASE,
+      // eslint-disable-next-line camelcase
+      game_versions: ['1.19.2'],
+      files: [randomFile]
+    }).generated;
+
+    assumeSuccessfulDetailsFetch(chance.word(), [randomVersion]);
+
+    const actual = await getMod(context.id, [ReleaseType.RELEASE], '1.19.2', context.loader, false);
+
+    expect(actual.fileLength).toEqual(randomFile.size);
+  });
+
+  it<RepositoryTestContext>('passes on the projects the version requires', async (context) => {
+    const randomVersion = generateModrinthVersion({
+      loaders: [context.loader],
+      // eslint-disable-next-line camelcase
+      version_type: ReleaseType.RELEASE,
+      // eslint-disable-next-line camelcase
+      game_versions: ['1.19.2'],
+      dependencies: [
+        // eslint-disable-next-line camelcase
+        { project_id: 'P7dR8mSH', version_id: null, dependency_type: 'required' },
+        // eslint-disable-next-line camelcase
+        { project_id: 'mOgUt4GM', version_id: null, dependency_type: 'optional' },
+        // eslint-disable-next-line camelcase
+        { project_id: null, version_id: 'Wnxd13zP', dependency_type: 'required' }
+      ]
+    }).generated;
+
+    assumeSuccessfulDetailsFetch(chance.word(), [randomVersion]);
+
+    const actual = await getMod(context.id, [ReleaseType.RELEASE], '1.19.2', context.loader, false);
+
+    expect(actual.dependencies).toEqual([{ type: Platform.MODRINTH, id: 'P7dR8mSH' }]);
+  });
+
+  it<RepositoryTestContext>('passes on the projects the version is incompatible with', async (context) => {
+    const randomVersion = generateModrinthVersion({
+      loaders: [context.loader],
+      // eslint-disable-next-line camelcase
+      version_type: ReleaseType.RELEASE,
+      // eslint-disable-next-line camelcase
+      game_versions: ['1.19.2'],
+      dependencies: [
+        // eslint-disable-next-line camelcase
+        { project_id: 'YL57xq9U', version_id: null, dependency_type: 'incompatible' },
+        // eslint-disable-next-line camelcase
+        { project_id: 'P7dR8mSH', version_id: null, dependency_type: 'required' },
+        // eslint-disable-next-line camelcase
+        { project_id: null, version_id: 'Wnxd13zP', dependency_type: 'incompatible' }
+      ]
+    }).generated;
+
+    assumeSuccessfulDetailsFetch(chance.word(), [randomVersion]);
+
+    const actual = await getMod(context.id, [ReleaseType.RELEASE], '1.19.2', context.loader, false);
+
+    expect(actual.incompatibilities).toEqual([{ type: Platform.MODRINTH, id: 'YL57xq9U' }]);
+  });
+
   it<RepositoryTestContext>('returns the most recent file for a given game version', async (context) => {
     const randomName = chance.word();
     const randomFile = generateModrinthFile().generated;
@@ -324,6 +409,7 @@ describe('The Modrinth repository', () => {
     expect(actual).toEqual({
       name: randomName,
       fileName: randomFile.filename,
+      version: randomVersion.version_number,
       releaseDate: randomVersion.date_published,
       hash: randomFile.hashes.sha1,
       downloadUrl: randomFile.url
@@ -393,10 +479,428 @@ describe('The Modrinth repository', () => {
       expect(actual).toEqual({
         name: randomName,
         fileName: randomFile.filename,
+        version: randomVersion.version_number,
         releaseDate: randomVersion.date_published,
         hash: randomFile.hashes.sha1,
         downloadUrl: randomFile.url
       });
     });
   });
+
+  describe('when the loader can use the files of another loader', () => {
+    const versionFor = (loader: Loader, gameVersion: string, datePublished: string) =>
+      generateModrinthVersion({
+        loaders: [loader],
+        // eslint-disable-next-line camelcase
+        game_versions: [gameVersion],
+        // eslint-disable-next-line camelcase
+        version_type: ReleaseType.RELEASE,
+        // eslint-disable-next-line camelcase
+        date_published: datePublished
+      }).generated;
+
+    beforeEach(() => {
+      setStrictLoaderMatching();
+    });
+
+    it<RepositoryTestContext>('asks for the files of every compatible loader', async (context) => {
+      assumeSuccessfulDetailsFetch(chance.word(), [versionFor(Loader.FABRIC, context.gameVersion, '2021-01-01')]);
+
+      await getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, Loader.QUILT, false);
+
+      const url = new URL(vi.mocked(rateLimitingFetch).mock.calls[1][0]);
+      expect(`${url.origin}${url.pathname}`).toEqual(`https://api.modrinth.com/v2/project/${context.id}/version`);
+      expect(url.searchParams.get('game_versions')).toEqual(`["${context.gameVersion}"]`);
+      expect(url.searchParams.get('loaders')).toEqual('["quilt","fabric"]');
+    });
+
+    it<RepositoryTestContext>('accepts a Fabric file for Quilt', async (context) => {
+      const fabricVersion = versionFor(Loader.FABRIC, context.gameVersion, '2021-01-01');
+      assumeSuccessfulDetailsFetch(chance.word(), [fabricVersion]);
+
+      const actual = await getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, Loader.QUILT, false);
+
+      expect(actual.fileName).toEqual(fabricVersion.files[0].filename);
+    });
+
+    it<RepositoryTestContext>('prefers the files made for Quilt even when a Fabric file is newer', async (context) => {
+      const quiltVersion = versionFor(Loader.QUILT, context.gameVersion, '2020-01-01');
+      const fabricVersion = versionFor(Loader.FABRIC, context.gameVersion, '2021-01-01');
+      assumeSuccessfulDetailsFetch(chance.word(), [fabricVersion, quiltVersion]);
+
+      const actual = await getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, Loader.QUILT, false);
+
+      expect(actual.fileName).toEqual(quiltVersion.files[0].filename);
+    });
+
+    it<RepositoryTestContext>('rejects a Fabric file for Quilt when strict', async (context) => {
+      setStrictLoaderMatching(true);
+      assumeSuccessfulDetailsFetch(chance.word(), [versionFor(Loader.FABRIC, context.gameVersion, '2021-01-01')]);
+
+      await expect(
+        getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, Loader.QUILT, false)
+      ).rejects.toThrow(new NoRemoteFileFound(context.id, Platform.MODRINTH));
+      expect(new URL(vi.mocked(rateLimitingFetch).mock.calls[1][0]).searchParams.get('loaders')).toEqual('["quilt"]');
+    });
+  });
+
+  describe('when the files are tagged with a broader game version', () => {
+    const versionFor = (...gameVersions: string[]) =>
+      generateModrinthVersion({
+        loaders: [Loader.FORGE],
+        // eslint-disable-next-line camelcase
+        game_versions: gameVersions,
+        // eslint-disable-next-line camelcase
+        version_type: ReleaseType.RELEASE
+      }).generated;
+
+    beforeEach(() => {
+      setStrictGameVersionMatching();
+    });
+
+    it<RepositoryTestContext>('asks for the minor version as well', async (context) => {
+      assumeSuccessfulDetailsFetch(chance.word(), [versionFor('1.20.1')]);
+
+      await getMod(context.id, [ReleaseType.RELEASE], '1.20.1', Loader.FORGE, false);
+
+      const url = new URL(vi.mocked(rateLimitingFetch).mock.calls[1][0]);
+      expect(url.searchParams.get('game_versions')).toEqual('["1.20.1","1.20"]');
+      expect(url.searchParams.get('loaders')).toEqual('["forge"]');
+    });
+
+    it<RepositoryTestContext>('accepts a file tagged with the minor version', async (context) => {
+      const version = versionFor('1.20');
+      assumeSuccessfulDetailsFetch(chance.word(), [version]);
+
+      const actual = await getMod(context.id, [ReleaseType.RELEASE], '1.20.1', Loader.FORGE, false);
+
+      expect(actual.fileName).toEqual(version.files[0].filename);
+    });
+
+    it<RepositoryTestContext>('rejects a file tagged with the minor version when strict', async (context) => {
+      setStrictGameVersionMatching(true);
+      assumeSuccessfulDetailsFetch(chance.word(), [versionFor('1.20')]);
+
+      await expect(getMod(context.id, [ReleaseType.RELEASE], '1.20.1', Loader.FORGE, false)).rejects.toThrow(
+        new NoRemoteFileFound(context.id, Platform.MODRINTH)
+      );
+    });
+
+    it<RepositoryTestContext>('rejects a snapshot file for a release', async (context) => {
+      assumeSuccessfulDetailsFetch(chance.word(), [versionFor('23w31a'), versionFor('1.20.1-rc1')]);
+
+      await expect(getMod(context.id, [ReleaseType.RELEASE], '1.20.1', Loader.FORGE, false)).rejects.toThrow(
+        new NoRemoteFileFound(context.id, Platform.MODRINTH)
+      );
+    });
+  });
+
+  describe('when listing the versions of a project', () => {
+    afterEach(() => {
+      setBaseUrl(Platform.MODRINTH);
+    });
+
+    it<RepositoryTestContext>('calls the configured base url', async (context) => {
+      setBaseUrl(Platform.MODRINTH, 'http://localhost:8080/v2');
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve([])
+      } as Response);
+
+      await getVersionsForProject(context.id);
+
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual(
+        `http://localhost:8080/v2/project/${context.id}/version`
+      );
+    });
+
+    it<RepositoryTestContext>('calls the versions endpoint without filters', async (context) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve([])
+      } as Response);
+
+      await getVersionsForProject(context.id);
+
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual(
+        `https://api.modrinth.com/v2/project/${context.id}/version`
+      );
+    });
+
+    it<RepositoryTestContext>('encodes the filters into the query', async (context) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve([])
+      } as Response);
+
+      await getVersionsForProject(context.id, {
+        loaders: ['fabric', 'quilt'],
+        gameVersions: ['1.20.1'],
+        featured: true
+      });
+
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual(
+        `https://api.modrinth.com/v2/project/${context.id}/version?game_versions=%5B%221.20.1%22%5D&loaders=%5B%22fabric%22%2C%22quilt%22%5D&featured=true`
+      );
+    });
+
+    it<RepositoryTestContext>('leaves out the empty filters', async (context) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve([])
+      } as Response);
+
+      await getVersionsForProject(context.id, { loaders: [], gameVersions: [], featured: false });
+
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual(
+        `https://api.modrinth.com/v2/project/${context.id}/version?featured=false`
+      );
+    });
+
+    it<RepositoryTestContext>('returns the filtered versions as Modrinth sends them', async (context) => {
+      const featured = generateModrinthVersion({ featured: true, loaders: ['fabric'] }).generated;
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve([featured])
+      } as Response);
+
+      const actual = await getVersionsForProject(context.id, { loaders: ['fabric'], featured: true });
+
+      expect(actual).toEqual([featured]);
+      expect(actual[0].featured).toBe(true);
+    });
+
+    it<RepositoryTestContext>('returns the versions newest first', async (context) => {
+      const oldest = generateModrinthVersion({ date_published: '2019-01-01T00:00:00Z' }).generated;
+      const newest = generateModrinthVersion({ date_published: '2021-01-01T00:00:00Z' }).generated;
+      const middle = generateModrinthVersion({ date_published: '2020-01-01T00:00:00Z' }).generated;
+
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve([oldest, newest, middle])
+      } as Response);
+
+      const actual = await getVersionsForProject(context.id);
+
+      expect(actual).toEqual([newest, middle, oldest]);
+    });
+
+    it<RepositoryTestContext>('throws when the project does not exist', async (context) => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: false,
+        status: 404
+      } as Response);
+
+      await expect(getVersionsForProject(context.id)).rejects.toThrow(
+        new CouldNotFindModException(context.id, Platform.MODRINTH)
+      );
+    });
+  });
+
+  describe('when logging the selection', () => {
+    let logger: RecordingApiLogger;
+
+    beforeEach(() => {
+      logger = recordingApiLogger();
+      setApiLogger(logger);
+    });
+
+    afterEach(() => {
+      setApiLogger();
+    });
+
+    it<RepositoryTestContext>('logs the selected file', async (context) => {
+      const file = generateModrinthFile().generated;
+      const version = generateModrinthVersion({
+        loaders: [context.loader],
+        // eslint-disable-next-line camelcase
+        version_type: ReleaseType.RELEASE,
+        // eslint-disable-next-line camelcase
+        game_versions: [context.gameVersion],
+        files: [file]
+      }).generated;
+      assumeSuccessfulDetailsFetch(chance.word(), [version]);
+
+      await getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, context.loader, false);
+
+      expect(logger.entries).toEqual([
+        {
+          level: 'debug',
+          message: 'selected file',
+          fields: { platform: Platform.MODRINTH, projectId: context.id, fileName: file.filename, candidates: 1 }
+        }
+      ]);
+    });
+
+    it<RepositoryTestContext>('logs the game versions it falls back to', async (context) => {
+      assumeSuccessfulDetailsFetch(chance.word(), []);
+      assumeSuccessfulDetailsFetch(chance.word(), []);
+
+      await expect(getMod(context.id, [ReleaseType.RELEASE], '1.19.1', context.loader, true)).rejects.toThrow(
+        NoRemoteFileFound
+      );
+
+      expect(logger.entries).toEqual([
+        {
+          level: 'debug',
+          message: 'trying an older game version',
+          fields: {
+            platform: Platform.MODRINTH,
+            projectId: context.id,
+            gameVersion: '1.19.1',
+            nextGameVersion: '1.19'
+          }
+        },
+        {
+          level: 'debug',
+          message: 'no suitable file',
+          fields: { platform: Platform.MODRINTH, projectId: context.id, gameVersion: '1.19', loader: context.loader }
+        }
+      ]);
+    });
+  });
+
+  describe('when the mod has file overrides', () => {
+    const suitableVersion = (context: RepositoryTestContext, filename: string, datePublished: string) =>
+      generateModrinthVersion({
+        date_published: datePublished,
+        game_versions: [context.gameVersion],
+        loaders: [context.loader],
+        version_type: ReleaseType.RELEASE,
+        files: [generateModrinthFile({ filename: filename }).generated]
+      }).generated;
+
+    const getModWith = (context: RepositoryTestContext, overrides: FileOverrides) =>
+      getMod(context.id, [ReleaseType.RELEASE], context.gameVersion, context.loader, false, undefined, overrides);
+
+    it<RepositoryTestContext>('picks the newest version without an excluded file', async (context) => {
+      const combined = suitableVersion(context, 'mod-forge-fabric-2.0.jar', '2023-02-01T00:00:00Z');
+      const fabricOnly = suitableVersion(context, 'mod-fabric-1.9.jar', '2023-01-01T00:00:00Z');
+      assumeSuccessfulDetailsFetch(chance.word(), [combined, fabricOnly]);
+
+      const actual = await getModWith(context, { excludeFileNamePattern: 'forge' });
+
+      expect(actual.fileName).toEqual('mod-fabric-1.9.jar');
+    });
+
+    it<RepositoryTestContext>('leaves out a version when any of its files is excluded', async (context) => {
+      const version = suitableVersion(context, 'mod-1.0.jar', '2023-01-01T00:00:00Z');
+      version.files.push(generateModrinthFile({ filename: 'mod-1.0-forge.jar' }).generated);
+      assumeSuccessfulDetailsFetch(chance.word(), [version]);
+
+      await expect(getModWith(context, { excludeFileNamePattern: 'forge' })).rejects.toThrow(
+        new NoRemoteFileFound(context.id, Platform.MODRINTH)
+      );
+    });
+
+    it<RepositoryTestContext>('uses the forced version whatever the default selection would pick', async (context) => {
+      const randomName = chance.word();
+      const forced = generateModrinthVersion({ version_type: ReleaseType.ALPHA, game_versions: ['1.7.10'] }).generated;
+      assumeSuccessfulModFetch(randomName);
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve(forced)
+      } as Response);
+
+      const actual = await getModWith(context, { forceFileId: 'AABBCCDD', excludeFileNamePattern: '.' });
+
+      expect(actual).toEqual({
+        name: randomName,
+        fileName: forced.files[0].filename,
+        version: forced.version_number,
+        releaseDate: forced.date_published,
+        hash: forced.files[0].hashes.sha1,
+        downloadUrl: forced.files[0].url
+      });
+      expect(vi.mocked(rateLimitingFetch).mock.calls[1][0]).toEqual('https://api.modrinth.com/v2/version/AABBCCDD');
+      expect(vi.mocked(rateLimitingFetch)).toHaveBeenCalledTimes(2);
+    });
+
+    it<RepositoryTestContext>('throws when the forced version does not exist', async (context) => {
+      assumeFailedDetailsFetch(chance.word());
+
+      await expect(getModWith(context, { forceFileId: 'AABBCCDD' })).rejects.toThrow(
+        new CouldNotFindModException(context.id, Platform.MODRINTH)
+      );
+    });
+  });
+
+  describe('when describing a version the way both platforms do', () => {
+    it('keeps what the other platforms know about the primary file', () => {
+      const version = generateModrinthVersion({
+        version_type: ReleaseType.BETA,
+        loaders: ['fabric', 'quilt'],
+        game_versions: ['1.20.1', '1.20.2'],
+        files: [generateModrinthFile({ size: 2048 }).generated, generateModrinthFile().generated]
+      }).generated;
+
+      expect(modrinthVersionToReleasedFile(version)).toEqual({
+        platform: Platform.MODRINTH,
+        id: version.id,
+        displayName: version.name,
+        fileName: version.files[0].filename,
+        gameVersions: ['1.20.1', '1.20.2'],
+        loaders: ['fabric', 'quilt'],
+        releaseType: ReleaseType.BETA,
+        downloadUrl: version.files[0].url,
+        sha1: version.files[0].hashes.sha1,
+        length: 2048,
+        releaseDate: version.date_published
+      });
+    });
+  });
+
+  describe('when none of the versions fit', () => {
+    it<RepositoryTestContext>('tells what the versions of the mod are made for', async (context) => {
+      assumeSuccessfulDetailsFetch(chance.word(), []);
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () =>
+          Promise.resolve([
+            generateModrinthVersion({ game_versions: ['1.20.1', '1.20.4'], loaders: ['fabric'] }).generated,
+            generateModrinthVersion({ game_versions: ['1.20.4'], loaders: ['Quilt', 'fabric'] }).generated
+          ])
+      } as Response);
+
+      const error = await getMod(context.id, [ReleaseType.RELEASE], '1.21', Loader.FORGE, false).catch((e) => e);
+
+      expect(error).toBeInstanceOf(NoRemoteFileFound);
+      expect(error.supported).toEqual({ gameVersions: ['1.20.4', '1.20.1'], loaders: ['fabric', 'quilt'] });
+      expect(error.message).toContain('game versions 1.20.4, 1.20.1 and the loaders fabric, quilt.');
+      expect(vi.mocked(rateLimitingFetch).mock.calls[2][0]).toEqual(
+        `https://api.modrinth.com/v2/project/${context.id}/version`
+      );
+    });
+  });
+
+  describe('when fetching the sides of the projects', () => {
+    it('asks for every project in one request', async () => {
+      const sides = [{ id: 'AANobbMI', client_side: 'required', server_side: 'unsupported' }];
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve(sides)
+      } as Response);
+
+      const actual = await getProjectSides(['AANobbMI', 'P7dR8mSH']);
+
+      expect(actual).toEqual(sides);
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual(
+        'https://api.modrinth.com/v2/projects?ids=%5B%22AANobbMI%22%2C%22P7dR8mSH%22%5D'
+      );
+    });
+
+    it('does not ask without projects', async () => {
+      expect(await getProjectSides([])).toEqual([]);
+      expect(rateLimitingFetch).not.toHaveBeenCalled();
+    });
+
+    it('throws when Modrinth fails', async () => {
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: false,
+        statusText: 'Bad Gateway'
+      } as Response);
+
+      await expect(getProjectSides(['AANobbMI'])).rejects.toThrow('Bad Gateway');
+    });
+  });
 });
@@ -1,8 +1,15 @@
 import { CouldNotFindModException } from '../../errors/CouldNotFindModException.js';
-import { NoRemoteFileFound } from '../../errors/NoRemoteFileFound.js';
+import { NoRemoteFileFound, SupportedTargets } from '../../errors/NoRemoteFileFound.js';
+import { getApiLogger } from '../../lib/apiLogger.js';
+import { apiUrl } from '../../lib/baseUrl.js';
 import { getNextVersionDown } from '../../lib/fallbackVersion.js';
-import { Loader, Platform, ReleaseType, RemoteModDetails } from '../../lib/modlist.types.js';
+import { isExcludedFileName } from '../../lib/fileOverrides.js';
+import { gameVersionMatches, gameVersionsToRequest } from '../../lib/gameVersionMatcher.js';
+import { compatibleLoaders } from '../../lib/loaderCompatibility.js';
+import { FileOverrides, Loader, Platform, ReleaseType, RemoteModDetails } from '../../lib/modlist.types.js';
 import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { supportedTargetsOf } from '../../lib/supportedTargets.js';
+import { ReleasedFile } from '../index.js';
 import { Modrinth } from './index.js';
 
 export interface Hash {
@@ -14,9 +21,20 @@ export interface ModrinthFile {
   hashes: Hash;
   url: string;
   filename: string;
+  /**
+   * The size of the file in bytes
+   */
+  size?: number;
+}
+
+export interface ModrinthDependency {
+  project_id?: string | null;
+  version_id?: string | null;
+  dependency_type: 'required' | 'optional' | 'incompatible' | 'embedded';
 }
 
 export interface ModrinthVersion {
+  id: string;
   project_id: string;
   name: string;
   loaders: string[];
@@ -25,6 +43,20 @@ export interface ModrinthVersion {
   version_number: string;
   version_type: ReleaseType;
   files: ModrinthFile[];
+  /**
+   * Whether the author features the version on the project page
+   */
+  featured?: boolean;
+  dependencies?: ModrinthDependency[];
+}
+
+export interface ModrinthVersionFilters {
+  loaders?: string[];
+  gameVersions?: string[];
+  /**
+   * Only the versions the author features, or only the ones they don't
+   */
+  featured?: boolean;
 }
 
 interface ModrinthMod {
@@ -34,7 +66,7 @@ interface ModrinthMod {
 
 const getName = async (projectId: string): Promise<string> => {
   performance.mark('modrinth-getname-start');
-  const url = `https://api.modrinth.com/v2/project/${projectId}`;
+  const url = apiUrl(Platform.MODRINTH, `project/${projectId}`);
   const modInfoRequest = await rateLimitingFetch(url, {
     headers: Modrinth.API_HEADERS
   });
@@ -49,10 +81,7 @@ const getName = async (projectId: string): Promise<string> => {
   return modInfo.title;
 };
 
-const getModDetails = async (projectId: string, gameVersion: string, loader: Loader): Promise<ModrinthMod> => {
-  const name = await getName(projectId);
-  const url = `https://api.modrinth.com/v2/project/${projectId}/version?game_versions=["${gameVersion}"]&loaders=["${loader}"]`;
-
+const requestVersions = async (projectId: string, url: string): Promise<ModrinthVersion[]> => {
   const modDetailsRequest = await rateLimitingFetch(url, {
     headers: Modrinth.API_HEADERS
   });
@@ -61,7 +90,104 @@ const getModDetails = async (projectId: string, gameVersion: string, loader: Loa
     throw new CouldNotFindModException(projectId, Platform.MODRINTH);
   }
 
-  const modVersions = (await modDetailsRequest.json()) as ModrinthVersion[];
+  return (await modDetailsRequest.json()) as ModrinthVersion[];
+};
+
+/**
+ * Fetches a single version of the project
+ *
+ * @throws {CouldNotFindModException} When Modrinth doesn't know the version
+ */
+export const getVersion = async (projectId: string, versionId: string): Promise<ModrinthVersion> => {
+  const url = apiUrl(Platform.MODRINTH, `version/${versionId}`);
+  const versionRequest = await rateLimitingFetch(url, {
+    headers: Modrinth.API_HEADERS
+  });
+
+  if (!versionRequest.ok) {
+    throw new CouldNotFindModException(projectId, Platform.MODRINTH);
+  }
+
+  return (await versionRequest.json()) as ModrinthVersion;
+};
+
+/**
+ * If a project has to be on the client or the server, unknown when the author didn't tell
+ */
+export type ModrinthSide = 'required' | 'optional' | 'unsupported' | 'unknown';
+
+export interface ModrinthProjectSides {
+  id: string;
+  client_side: ModrinthSide;
+  server_side: ModrinthSide;
+}
+
+/**
+ * Fetches on which sides the projects run, all of them in one request
+ */
+export const getProjectSides = async (projectIds: string[]): Promise<ModrinthProjectSides[]> => {
+  if (projectIds.length === 0) {
+    return [];
+  }
+
+  const url = new URL(apiUrl(Platform.MODRINTH, 'projects'));
+  url.searchParams.set('ids', JSON.stringify(projectIds));
+  const response = await rateLimitingFetch(url.toString(), {
+    headers: Modrinth.API_HEADERS
+  });
+
+  if (!response.ok) {
+    throw new Error(response.statusText);
+  }
+
+  return (await response.json()) as ModrinthProjectSides[];
+};
+
+/**
+ * Lets Modrinth do the filtering so we only download the versions we can use.
+ * The lists go into the query as JSON arrays, the filters without a value are left out.
+ */
+export const modrinthVersionsUrl = (projectId: string, filters: ModrinthVersionFilters = {}): string => {
+  const url = new URL(apiUrl(Platform.MODRINTH, `project/${projectId}/version`));
+
+  if (filters.gameVersions && filters.gameVersions.length > 0) {
+    url.searchParams.set('game_versions', JSON.stringify(filters.gameVersions));
+  }
+
+  if (filters.loaders && filters.loaders.length > 0) {
+    url.searchParams.set('loaders', JSON.stringify(filters.loaders));
+  }
+
+  if (filters.featured !== undefined) {
+    url.searchParams.set('featured', String(filters.featured));
+  }
+
+  return url.toString();
+};
+
+/**
+ * Returns the versions of the project that pass the filters, every version without them, newest first
+ */
+export const getVersionsForProject = async (
+  projectId: string,
+  filters: ModrinthVersionFilters = {}
+): Promise<ModrinthVersion[]> => {
+  const url = modrinthVersionsUrl(projectId, filters);
+  const versions = await requestVersions(projectId, url);
+
+  return [...versions].sort((versionA, versionB) => {
+    return versionA.date_published < versionB.date_published ? 1 : -1;
+  });
+};
+
+const getModDetails = async (projectId: string, gameVersion: string, loader: Loader): Promise<ModrinthMod> => {
+  const name = await getName(projectId);
+  const url = modrinthVersionsUrl(projectId, {
+    gameVersions: gameVersionsToRequest(gameVersion),
+    loaders: compatibleLoaders(loader)
+  });
+
+  const modVersions = await requestVersions(projectId, url);
 
   return {
     versions: modVersions,
@@ -78,10 +204,10 @@ const hasTheCorrectReleaseType = (version: ModrinthVersion, allowedReleaseTypes:
 };
 
 const hasTheCorrectVersion = (version: ModrinthVersion, allowedGameVersion: string) => {
-  return version.game_versions.includes(allowedGameVersion);
+  return gameVersionMatches(allowedGameVersion, version.game_versions);
 };
 
-const getPotentialFiles = (
+const getPotentialFilesForLoader = (
   versions: ModrinthVersion[],
   loader: Loader,
   allowedReleaseTypes: ReleaseType[],
@@ -102,48 +228,176 @@ const getPotentialFiles = (
     });
 };
 
+/**
+ * Files made for the loader itself are preferred, the compatible loaders are only used when there are none
+ */
+const getPotentialFiles = (
+  versions: ModrinthVersion[],
+  loader: Loader,
+  allowedReleaseTypes: ReleaseType[],
+  allowedGameVersion: string
+) => {
+  for (const compatibleLoader of compatibleLoaders(loader)) {
+    const files = getPotentialFilesForLoader(versions, compatibleLoader, allowedReleaseTypes, allowedGameVersion);
+    if (files.length > 0) {
+      return files;
+    }
+  }
+  return [];
+};
+
+/**
+ * The version as a released file, with the first file of the version like the rest of the lookups use
+ */
+export const modrinthVersionToReleasedFile = (version: ModrinthVersion): ReleasedFile => {
+  return {
+    platform: Platform.MODRINTH,
+    id: version.id,
+    displayName: version.name,
+    fileName: version.files[0].filename,
+    gameVersions: version.game_versions,
+    loaders: version.loaders.map((loader) => loader.toLowerCase()),
+    releaseType: version.version_type,
+    downloadUrl: version.files[0].url,
+    sha1: version.files[0].hashes.sha1,
+    length: version.files[0].size,
+    releaseDate: version.date_published
+  };
+};
+
+const toRemoteModDetails = (projectId: string, name: string, version: ModrinthVersion): RemoteModDetails => {
+  const modData: RemoteModDetails = {
+    name: name,
+    fileName: version.files[0].filename,
+    version: version.version_number,
+    releaseDate: version.date_published,
+    hash: version.files[0].hashes.sha1,
+    downloadUrl: version.files[0].url,
+    fileLength: version.files[0].size
+  };
+
+  // A dependency on a version alone doesn't say which project to look for in the modlist
+  const dependencies = (version.dependencies || []).filter(
+    (dependency) => dependency.dependency_type === 'required' && !!dependency.project_id
+  );
+  if (dependencies.length > 0) {
+    modData.dependencies = dependencies.map((dependency) => ({
+      type: Platform.MODRINTH,
+      id: dependency.project_id as string
+    }));
+  }
+
+  const incompatibilities = (version.dependencies || []).filter(
+    (dependency) => dependency.dependency_type === 'incompatible' && !!dependency.project_id
+  );
+  if (incompatibilities.length > 0) {
+    modData.incompatibilities = incompatibilities.map((dependency) => ({
+      type: Platform.MODRINTH,
+      id: dependency.project_id as string
+    }));
+  }
+
+  performance.mark('modrinth-getmod-end');
+  performance.measure(`modrinth-getmod-${projectId}`, 'modrinth-getmod-start', 'modrinth-getmod-end');
+
+  return modData;
+};
+
+/**
+ * What every version of the project is made for, only asked for when none of them fit.
+ * It just explains the failure, so a listing that fails leaves it out.
+ */
+const getSupportedTargets = async (projectId: string): Promise<SupportedTargets | undefined> => {
+  try {
+    const versions = await getVersionsForProject(projectId);
+    return supportedTargetsOf(
+      versions.map((version) => ({ gameVersions: version.game_versions, loaders: version.loaders }))
+    );
+  } catch {
+    return undefined;
+  }
+};
+
+/**
+ * Finds the version of the project to install.
+ * A forced version is used as it is, otherwise the versions with an excluded file are left out before the newest
+ * suitable one is picked.
+ */
 export const getMod = async (
   projectId: string,
   allowedReleaseTypes: ReleaseType[],
   allowedGameVersion: string,
   loader: Loader,
   allowFallback: boolean,
-  fixedModVersion?: string
+  fixedModVersion?: string,
+  overrides: FileOverrides = {}
 ): Promise<RemoteModDetails> => {
   performance.mark('modrinth-getmod-start');
+
+  if (overrides.forceFileId) {
+    getApiLogger().debug('using the forced file', {
+      platform: Platform.MODRINTH,
+      projectId: projectId,
+      fileId: overrides.forceFileId
+    });
+    const [forcedName, forcedVersion] = await Promise.all([
+      getName(projectId),
+      getVersion(projectId, overrides.forceFileId)
+    ]);
+    return toRemoteModDetails(projectId, forcedName, forcedVersion);
+  }
+
   const { name, versions } = await getModDetails(projectId, allowedGameVersion, loader);
+  const selectableVersions = versions.filter(
+    (version) => !version.files.some((file) => isExcludedFileName(file.filename, overrides))
+  );
   let potentialFiles = [];
   if (fixedModVersion) {
-    potentialFiles = versions.filter((file) => {
+    potentialFiles = selectableVersions.filter((file) => {
       return file.version_number === fixedModVersion;
     });
   } else {
-    potentialFiles = getPotentialFiles(versions, loader, allowedReleaseTypes, allowedGameVersion);
+    potentialFiles = getPotentialFiles(selectableVersions, loader, allowedReleaseTypes, allowedGameVersion);
   }
 
   if (potentialFiles.length === 0) {
     if (allowFallback) {
       const versionDown = getNextVersionDown(allowedGameVersion);
-      return getMod(projectId, allowedReleaseTypes, versionDown.nextVersionToTry, loader, versionDown.canGoDown);
+      getApiLogger().debug('trying an older game version', {
+        platform: Platform.MODRINTH,
+        projectId: projectId,
+        gameVersion: allowedGameVersion,
+        nextGameVersion: versionDown.nextVersionToTry
+      });
+      return getMod(
+        projectId,
+        allowedReleaseTypes,
+        versionDown.nextVersionToTry,
+        loader,
+        versionDown.canGoDown,
+        undefined,
+        overrides
+      );
     }
 
     performance.mark('modrinth-getmod-failed');
     performance.measure(`modrinth-getmod-${projectId}-failed`, 'modrinth-getmod-start', 'modrinth-getmod-failed');
-    throw new NoRemoteFileFound(projectId, Platform.MODRINTH);
+    getApiLogger().debug('no suitable file', {
+      platform: Platform.MODRINTH,
+      projectId: projectId,
+      gameVersion: allowedGameVersion,
+      loader: loader
+    });
+    throw new NoRemoteFileFound(projectId, Platform.MODRINTH, await getSupportedTargets(projectId));
   }
 
   const latestFile = potentialFiles[0];
-
-  const modData: RemoteModDetails = {
-    name: name,
+  getApiLogger().debug('selected file', {
+    platform: Platform.MODRINTH,
+    projectId: projectId,
     fileName: latestFile.files[0].filename,
-    releaseDate: latestFile.date_published,
-    hash: latestFile.files[0].hashes.sha1,
-    downloadUrl: latestFile.files[0].url
-  };
-
-  performance.mark('modrinth-getmod-end');
-  performance.measure(`modrinth-getmod-${projectId}`, 'modrinth-getmod-start', 'modrinth-getmod-end');
+    candidates: potentialFiles.length
+  });
 
-  return modData;
+  return toRemoteModDetails(projectId, name, latestFile);
 };
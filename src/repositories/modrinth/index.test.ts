@@ -59,7 +59,25 @@ describe('The Modrinth Repository class', () => {
       allowedGameVersion,
       loader,
       allowFallback,
-      fixedVersion
+      fixedVersion,
+      undefined
+    );
+  });
+
+  it('passes the file overrides on to the fetching module', async () => {
+    const overrides = { excludeFileNamePattern: 'forge', forceFileId: chance.word() };
+    vi.mocked(getMod).mockResolvedValueOnce(generateRemoteModDetails().generated);
+
+    await new Modrinth().fetchMod('1', [ReleaseType.RELEASE], '1.20.1', Loader.FABRIC, false, 'pinned', overrides);
+
+    expect(vi.mocked(getMod)).toHaveBeenCalledWith(
+      '1',
+      [ReleaseType.RELEASE],
+      '1.20.1',
+      Loader.FABRIC,
+      false,
+      'pinned',
+      overrides
     );
   });
 
@@ -1,5 +1,5 @@
 import { modrinthApiKey } from '../../env.js';
-import { Loader, ReleaseType, RemoteModDetails } from '../../lib/modlist.types.js';
+import { FileOverrides, Loader, ReleaseType, RemoteModDetails } from '../../lib/modlist.types.js';
 import { version } from '../../version.js';
 import { PlatformLookupResult, Repository } from '../index.js';
 import { getMod } from './fetch.js';
@@ -19,9 +19,10 @@ export class Modrinth implements Repository {
     allowedGameVersion: string,
     loader: Loader,
     allowFallback: boolean,
-    fixedVersion?: string
+    fixedVersion?: string,
+    overrides?: FileOverrides
   ): Promise<RemoteModDetails> {
-    return getMod(projectId, allowedReleaseTypes, allowedGameVersion, loader, allowFallback, fixedVersion);
+    return getMod(projectId, allowedReleaseTypes, allowedGameVersion, loader, allowFallback, fixedVersion, overrides);
   }
 
   lookup(lookup: string[]): Promise<PlatformLookupResult[]> {
@@ -1,13 +1,15 @@
 import { chance } from 'jest-chance';
-import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
 import { generateModrinthFile } from '../../../test/generateModrinthFile.js';
 import { generateModrinthVersion } from '../../../test/generateModrinthVersion.js';
 import { Logger } from '../../lib/Logger.js';
+import { setBaseUrl } from '../../lib/baseUrl.js';
 import { Platform } from '../../lib/modlist.types.js';
+import { MaximumRetriesReached } from '../../lib/rateLimiter/MaximumRetriesReached.js';
 import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
 import { Hash } from './fetch.js';
 import { Modrinth } from './index.js';
-import { lookup } from './lookup.js';
+import { getVersionByHash, getVersionsByHashes, lookup } from './lookup.js';
 
 vi.mock('../../lib/rateLimiter/index.js');
 vi.mock('../../lib/Logger.js');
@@ -17,12 +19,16 @@ interface LocalTestContext {
   logger: Logger;
 }
 
-describe('The Curseforge Lookup module', () => {
+describe('The Modrinth Lookup module', () => {
   beforeEach<LocalTestContext>((context) => {
     vi.resetAllMocks();
     context.logger = new Logger({} as never);
   });
 
+  afterEach(() => {
+    setBaseUrl(Platform.MODRINTH);
+  });
+
   it<LocalTestContext>('correctly calls the modrinth api', async () => {
     vi.mocked(rateLimitingFetch).mockResolvedValue({
       ok: false // fastest way to exit out of the function under test
@@ -30,25 +36,27 @@ describe('The Curseforge Lookup module', () => {
 
     const actual = await lookup(['fingerprint1', 'fingerprint2', 'fingerprint3']);
 
-    expect(rateLimitingFetch).toHaveBeenNthCalledWith(
-      1,
-      'https://api.modrinth.com/v2/version_file/fingerprint1?algorithm=sha1',
-      { headers: Modrinth.API_HEADERS }
-    );
-    expect(rateLimitingFetch).toHaveBeenNthCalledWith(
-      2,
-      'https://api.modrinth.com/v2/version_file/fingerprint2?algorithm=sha1',
-      { headers: Modrinth.API_HEADERS }
-    );
-    expect(rateLimitingFetch).toHaveBeenNthCalledWith(
-      3,
-      'https://api.modrinth.com/v2/version_file/fingerprint3?algorithm=sha1',
-      { headers: Modrinth.API_HEADERS }
-    );
+    expect(rateLimitingFetch).toHaveBeenCalledOnce();
+    expect(rateLimitingFetch).toHaveBeenCalledWith('https://api.modrinth.com/v2/version_files', {
+      method: 'POST',
+      headers: { ...Modrinth.API_HEADERS, 'Content-Type': 'application/json' },
+      body: JSON.stringify({ hashes: ['fingerprint1', 'fingerprint2', 'fingerprint3'], algorithm: 'sha1' })
+    });
 
     expect(actual).toEqual([]);
   });
 
+  it('calls the configured base url', async () => {
+    setBaseUrl(Platform.MODRINTH, 'https://proxy.example.com/v2/');
+    vi.mocked(rateLimitingFetch).mockResolvedValue({
+      ok: false
+    } as unknown as Response);
+
+    await lookup(['fingerprint1']);
+
+    expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual('https://proxy.example.com/v2/version_files');
+  });
+
   it<LocalTestContext>('transforms the response correctly', async () => {
     const modId = chance.hash({ length: 6 });
     const randomHash = chance.hash();
@@ -61,7 +69,7 @@ describe('The Curseforge Lookup module', () => {
 
     vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
       ok: true,
-      json: async () => modVersion
+      json: async () => ({ [randomHash]: modVersion })
     } as unknown as Response);
     const actual = await lookup([randomHash]);
 
@@ -74,4 +82,135 @@ describe('The Curseforge Lookup module', () => {
     expect(actual[0].mod.fileName).toEqual(file.filename);
     expect(actual[0].mod.downloadUrl).toEqual(file.url);
   });
+
+  it<LocalTestContext>('uses the file that matches the hash', async () => {
+    const randomHash = chance.hash();
+    const otherFile = generateModrinthFile().generated;
+    const matchingFile = generateModrinthFile({
+      hashes: { sha1: randomHash } as unknown as Hash
+    }).generated;
+    const modVersion = generateModrinthVersion({ files: [otherFile, matchingFile] }).generated;
+
+    vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+      ok: true,
+      json: async () => ({ [randomHash]: modVersion })
+    } as unknown as Response);
+    const actual = await lookup([randomHash]);
+
+    expect(actual[0].mod.fileName).toEqual(matchingFile.filename);
+  });
+
+  it<LocalTestContext>('leaves out the unmatched hashes', async () => {
+    const matchedHash = chance.hash();
+    const unmatchedHash = chance.hash();
+    const file = generateModrinthFile({
+      hashes: { sha1: matchedHash } as unknown as Hash
+    }).generated;
+    const modVersion = generateModrinthVersion({ files: [file] }).generated;
+
+    vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+      ok: true,
+      json: async () => ({ [matchedHash]: modVersion })
+    } as unknown as Response);
+    const actual = await lookup([matchedHash, unmatchedHash]);
+
+    expect(actual.length).toEqual(1);
+    expect(actual[0].mod.hash).toEqual(matchedHash);
+  });
+
+  describe('when looking up a single hash', () => {
+    it('calls the version file endpoint with the algorithm', async () => {
+      const hash = chance.hash();
+      const modVersion = generateModrinthVersion().generated;
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        status: 200,
+        json: async () => modVersion
+      } as unknown as Response);
+
+      const actual = await getVersionByHash(hash, 'sha512');
+
+      expect(rateLimitingFetch).toHaveBeenCalledWith(
+        `https://api.modrinth.com/v2/version_file/${hash}?algorithm=sha512`,
+        { headers: Modrinth.API_HEADERS }
+      );
+      expect(actual).toEqual(modVersion);
+    });
+
+    it('defaults to the preferred hash algorithm', async () => {
+      const hash = chance.hash();
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        status: 200,
+        json: async () => generateModrinthVersion().generated
+      } as unknown as Response);
+
+      await getVersionByHash(hash);
+
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toEqual(
+        `https://api.modrinth.com/v2/version_file/${hash}?algorithm=${Modrinth.PREFERRED_HASH}`
+      );
+    });
+
+    it('returns null for an unmatched hash', async () => {
+      vi.mocked(rateLimitingFetch).mockRejectedValueOnce(
+        new MaximumRetriesReached(new Response(null, { status: 404 }))
+      );
+
+      const actual = await getVersionByHash(chance.hash());
+
+      expect(actual).toBeNull();
+    });
+
+    it('passes on the retries that ran out for other reasons', async () => {
+      const error = new MaximumRetriesReached(new Response(null, { status: 500 }));
+      vi.mocked(rateLimitingFetch).mockRejectedValueOnce(error);
+
+      await expect(getVersionByHash(chance.hash())).rejects.toBe(error);
+    });
+
+    it('throws when the request fails', async () => {
+      const statusText = chance.sentence();
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: false,
+        status: 500,
+        statusText: statusText
+      } as unknown as Response);
+
+      await expect(getVersionByHash(chance.hash())).rejects.toThrow(statusText);
+    });
+  });
+
+  describe('when looking up multiple hashes', () => {
+    it('does not call the api without hashes', async () => {
+      const actual = await getVersionsByHashes([]);
+
+      expect(rateLimitingFetch).not.toHaveBeenCalled();
+      expect(actual).toEqual({});
+    });
+
+    it('returns the versions keyed by their hashes', async () => {
+      const hash = chance.hash();
+      const modVersion = generateModrinthVersion().generated;
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: true,
+        json: async () => ({ [hash]: modVersion })
+      } as unknown as Response);
+
+      const actual = await getVersionsByHashes([hash, chance.hash()], 'sha512');
+
+      expect(vi.mocked(rateLimitingFetch).mock.calls[0][1]?.body).toContain('"algorithm":"sha512"');
+      expect(actual).toEqual({ [hash]: modVersion });
+    });
+
+    it('throws when the request fails', async () => {
+      const statusText = chance.sentence();
+      vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+        ok: false,
+        statusText: statusText
+      } as unknown as Response);
+
+      await expect(getVersionsByHashes([chance.hash()])).rejects.toThrow(statusText);
+    });
+  });
 });
@@ -1,15 +1,33 @@
 import { PlatformLookupResult } from '../index.js';
 
+import { apiUrl } from '../../lib/baseUrl.js';
 import { Platform, RemoteModDetails } from '../../lib/modlist.types.js';
 import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { isNotFound } from '../../lib/rateLimiter/notFound.js';
 import { ModrinthFile, ModrinthVersion } from './fetch.js';
 import { Modrinth } from './index.js';
 
-const startLookup = async (hash: string) => {
-  const url = `https://api.modrinth.com/v2/version_file/${hash}?algorithm=sha1`;
+/**
+ * Returns the version containing the file with the given hash or null when Modrinth doesn't know about it
+ */
+export const getVersionByHash = async (
+  hash: string,
+  algorithm: string = Modrinth.PREFERRED_HASH
+): Promise<ModrinthVersion | null> => {
+  const url = apiUrl(Platform.MODRINTH, `version_file/${hash}?algorithm=${algorithm}`);
   const response = await rateLimitingFetch(url, {
     headers: Modrinth.API_HEADERS
+  }).catch((error) => {
+    if (isNotFound(error)) {
+      return null;
+    }
+    throw error;
   });
+
+  if (!response) {
+    return null;
+  }
+
   if (!response.ok) {
     throw new Error(response.statusText);
   }
@@ -17,24 +35,47 @@ const startLookup = async (hash: string) => {
   return (await response.json()) as ModrinthVersion;
 };
 
+/**
+ * Returns the versions keyed by the hashes they were found with. Unmatched hashes are left out.
+ */
+export const getVersionsByHashes = async (
+  hashes: string[],
+  algorithm: string = Modrinth.PREFERRED_HASH
+): Promise<Record<string, ModrinthVersion>> => {
+  if (hashes.length === 0) {
+    return {};
+  }
+
+  const response = await rateLimitingFetch(apiUrl(Platform.MODRINTH, 'version_files'), {
+    method: 'POST',
+    headers: {
+      ...Modrinth.API_HEADERS,
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify({ hashes, algorithm })
+  });
+
+  if (!response.ok) {
+    throw new Error(response.statusText);
+  }
+
+  return (await response.json()) as Record<string, ModrinthVersion>;
+};
+
+const matchingFileFor = (version: ModrinthVersion, hash: string): ModrinthFile => {
+  const file = version.files.find((candidate) => candidate.hashes.sha1 === hash);
+  return file ?? version.files[0];
+};
+
 export const lookup = async (hashes: string[]): Promise<PlatformLookupResult[]> => {
-  const lookupQueue: Promise<ModrinthVersion>[] = [];
   performance.mark('modrinth-lookup-start');
-  hashes.forEach((hash) => {
-    lookupQueue.push(startLookup(hash));
-  });
 
-  const settledQueue = await Promise.allSettled(lookupQueue);
+  const versions = await getVersionsByHashes(hashes).catch((): Record<string, ModrinthVersion> => ({}));
 
   const results: PlatformLookupResult[] = [];
 
-  settledQueue.forEach((lookup) => {
-    if (lookup.status === 'rejected') {
-      return;
-    }
-
-    const data: ModrinthVersion = lookup.value;
-    const matchingFile: ModrinthFile = data.files[0];
+  Object.entries(versions).forEach(([hash, data]) => {
+    const matchingFile: ModrinthFile = matchingFileFor(data, hash);
 
     const modData: RemoteModDetails = {
       name: data.name,
@@ -0,0 +1,175 @@
+import { chance } from 'jest-chance';
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { SearchFailedException } from '../../errors/SearchFailedException.js';
+import { setBaseUrl } from '../../lib/baseUrl.js';
+import { setSnapshotGameVersions } from '../../lib/gameVersionMatcher.js';
+import { Loader, Platform } from '../../lib/modlist.types.js';
+import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { Modrinth } from './index.js';
+import { searchFacets, searchProjects } from './search.js';
+
+vi.mock('../../lib/rateLimiter/index.js');
+
+interface Project {
+  project_id: string;
+  slug: string;
+  title: string;
+  description: string;
+  author: string;
+  downloads: number;
+  versions: string[];
+}
+
+const generateProject = (): Project => ({
+  project_id: chance.string({ alpha: true, numeric: true, length: 8 }),
+  slug: chance.word(),
+  title: chance.word(),
+  description: chance.sentence(),
+  author: chance.word(),
+  downloads: chance.integer({ min: 0, max: 1000000 }),
+  versions: ['1.19.2', '1.20.1']
+});
+
+const assumeSearchPage = (projects: Project[], totalHits: number) => {
+  vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+    ok: true,
+    json: () =>
+      Promise.resolve({
+        hits: projects,
+        offset: 0,
+        limit: 50,
+        total_hits: totalHits
+      })
+  } as Response);
+};
+
+describe('The Modrinth search', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+  });
+
+  afterEach(() => {
+    setBaseUrl(Platform.MODRINTH);
+    setSnapshotGameVersions();
+  });
+
+  it('filters on the loader, the game version and the mods', () => {
+    expect(JSON.stringify(searchFacets('1.20.1', Loader.FABRIC))).toMatchInlineSnapshot(
+      '"[["categories:fabric"],["versions:1.20.1"],["project_type:mod"]]"'
+    );
+  });
+
+  it('calls the search api with the right parameters', async () => {
+    assumeSearchPage([], 0);
+
+    await searchProjects('fabric api', '1.20.1', Loader.FABRIC);
+
+    const url = new URL(vi.mocked(rateLimitingFetch).mock.calls[0][0]);
+    expect(`${url.origin}${url.pathname}`).toEqual('https://api.modrinth.com/v2/search');
+    expect(url.searchParams.get('query')).toEqual('fabric api');
+    expect(url.searchParams.get('facets')).toEqual('[["categories:fabric"],["versions:1.20.1"],["project_type:mod"]]');
+    expect(url.searchParams.get('offset')).toEqual('0');
+    expect(url.searchParams.get('limit')).toEqual('50');
+    expect(vi.mocked(rateLimitingFetch).mock.calls[0][1]).toEqual({ headers: Modrinth.API_HEADERS });
+  });
+
+  it('calls the configured base url', async () => {
+    setBaseUrl(Platform.MODRINTH, 'https://proxy.example.com/v2');
+    assumeSearchPage([], 0);
+
+    await searchProjects('fabric api', '1.20.1', Loader.FABRIC);
+
+    expect(vi.mocked(rateLimitingFetch).mock.calls[0][0]).toMatch(/^https:\/\/proxy\.example\.com\/v2\/search\?/);
+  });
+
+  it('returns the hits in the shape the other platforms use', async () => {
+    const project = generateProject();
+    assumeSearchPage([project], 1);
+
+    const actual = await searchProjects(chance.word(), '1.19.2', Loader.FORGE);
+
+    expect(actual).toEqual([
+      {
+        platform: Platform.MODRINTH,
+        id: project.project_id,
+        slug: project.slug,
+        name: project.title,
+        summary: project.description,
+        author: project.author,
+        downloads: project.downloads,
+        latestGameVersion: '1.20.1'
+      }
+    ]);
+  });
+
+  it('has no latest game version for a project without versions', async () => {
+    assumeSearchPage([{ ...generateProject(), versions: [] }], 1);
+
+    const actual = await searchProjects(chance.word(), '1.19.2', Loader.FORGE);
+
+    expect(actual[0].latestGameVersion).toBeUndefined();
+  });
+
+  it('has no latest game version for a project that comes without the versions', async () => {
+    const project = generateProject();
+    // @ts-ignore
+    delete project.versions;
+    assumeSearchPage([project], 1);
+
+    const actual = await searchProjects(chance.word(), '1.19.2', Loader.FORGE);
+
+    expect(actual[0].latestGameVersion).toBeUndefined();
+  });
+
+  it('leaves the snapshots out of the latest game version unless they are allowed', async () => {
+    assumeSearchPage([{ ...generateProject(), versions: ['1.20.4', '24w09a', '1.20.1'] }], 1);
+    assumeSearchPage([{ ...generateProject(), versions: ['1.20.4', '24w09a', '1.20.1'] }], 1);
+
+    const ignored = await searchProjects(chance.word(), '1.20.4', Loader.FABRIC);
+    setSnapshotGameVersions(true);
+    const allowed = await searchProjects(chance.word(), '1.20.4', Loader.FABRIC);
+
+    expect(ignored[0].latestGameVersion).toEqual('1.20.4');
+    expect(allowed[0].latestGameVersion).toEqual('24w09a');
+  });
+
+  it('fetches more pages until the limit is reached', async () => {
+    assumeSearchPage(Array.from({ length: 100 }, generateProject), 500);
+    assumeSearchPage(Array.from({ length: 20 }, generateProject), 500);
+
+    const actual = await searchProjects(chance.word(), '1.19.2', Loader.FORGE, 120);
+
+    expect(actual.length).toEqual(120);
+    expect(new URL(vi.mocked(rateLimitingFetch).mock.calls[1][0]).searchParams.get('offset')).toEqual('100');
+    expect(new URL(vi.mocked(rateLimitingFetch).mock.calls[1][0]).searchParams.get('limit')).toEqual('20');
+  });
+
+  it('does not return more results than the limit', async () => {
+    assumeSearchPage(Array.from({ length: 10 }, generateProject), 500);
+
+    const actual = await searchProjects(chance.word(), '1.19.2', Loader.FORGE, 5);
+
+    expect(actual.length).toEqual(5);
+  });
+
+  it('stops when modrinth runs out of results', async () => {
+    assumeSearchPage([generateProject()], 100);
+    assumeSearchPage([], 100);
+
+    const actual = await searchProjects(chance.word(), '1.19.2', Loader.FORGE, 100);
+
+    expect(actual.length).toEqual(1);
+    expect(rateLimitingFetch).toHaveBeenCalledTimes(2);
+  });
+
+  it('throws when the search fails', async () => {
+    const query = chance.word();
+    vi.mocked(rateLimitingFetch).mockResolvedValueOnce({
+      ok: false
+    } as Response);
+
+    await expect(searchProjects(query, '1.19.2', Loader.FORGE)).rejects.toThrow(
+      new SearchFailedException(query, Platform.MODRINTH)
+    );
+  });
+});
@@ -0,0 +1,100 @@
+import { SearchFailedException } from '../../errors/SearchFailedException.js';
+import { apiUrl } from '../../lib/baseUrl.js';
+import { latestGameVersion } from '../../lib/gameVersionMatcher.js';
+import { Loader, Platform } from '../../lib/modlist.types.js';
+import { rateLimitingFetch } from '../../lib/rateLimiter/index.js';
+import { SearchHit } from '../index.js';
+import { Modrinth } from './index.js';
+
+/**
+ * Modrinth doesn't allow bigger pages than this
+ */
+const MAX_PAGE_SIZE = 100;
+
+export const SEARCH_RESULT_LIMIT = 50;
+
+interface ModrinthSearchProject {
+  project_id: string;
+  slug: string;
+  title: string;
+  description: string;
+  author: string;
+  downloads: number;
+  /**
+   * The Minecraft versions of the project, the snapshots included. Not every hit has them.
+   */
+  versions?: string[];
+}
+
+interface ModrinthSearchResult {
+  hits: ModrinthSearchProject[];
+  offset: number;
+  limit: number;
+  total_hits: number;
+}
+
+/**
+ * The facets are ANDed together, the values inside a facet would be ORed.
+ */
+export const searchFacets = (gameVersion: string, loader: Loader): string[][] => {
+  return [[`categories:${loader}`], [`versions:${gameVersion}`], ['project_type:mod']];
+};
+
+const searchUrl = (query: string, gameVersion: string, loader: Loader, offset: number, limit: number): string => {
+  const url = new URL(apiUrl(Platform.MODRINTH, 'search'));
+  url.searchParams.set('query', query);
+  url.searchParams.set('facets', JSON.stringify(searchFacets(gameVersion, loader)));
+  url.searchParams.set('index', 'relevance');
+  url.searchParams.set('offset', String(offset));
+  url.searchParams.set('limit', String(limit));
+  return url.toString();
+};
+
+export const modrinthSearchHit = (project: ModrinthSearchProject): SearchHit => {
+  return {
+    platform: Platform.MODRINTH,
+    id: project.project_id,
+    slug: project.slug,
+    name: project.title,
+    summary: project.description,
+    author: project.author,
+    downloads: project.downloads,
+    latestGameVersion: latestGameVersion(project.versions || [])
+  };
+};
+
+/**
+ * Finds the mods matching the query in the order Modrinth ranks them.
+ */
+export const searchProjects = async (
+  query: string,
+  gameVersion: string,
+  loader: Loader,
+  limit: number = SEARCH_RESULT_LIMIT
+): Promise<SearchHit[]> => {
+  performance.mark('modrinth-search-start');
+  const results: SearchHit[] = [];
+
+  while (results.length < limit) {
+    const pageSize = Math.min(MAX_PAGE_SIZE, limit - results.length);
+    const response = await rateLimitingFetch(searchUrl(query, gameVersion, loader, results.length, pageSize), {
+      headers: Modrinth.API_HEADERS
+    });
+
+    if (!response.ok) {
+      throw new SearchFailedException(query, Platform.MODRINTH);
+    }
+
+    const searchResult: ModrinthSearchResult = await response.json();
+    results.push(...searchResult.hits.map(modrinthSearchHit));
+
+    if (searchResult.hits.length === 0 || results.length >= searchResult.total_hits) {
+      break;
+    }
+  }
+
+  performance.mark('modrinth-search-end');
+  performance.measure('modrinth-search', 'modrinth-search-start', 'modrinth-search-end');
+
+  return results.slice(0, limit);
+};
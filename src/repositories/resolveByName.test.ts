@@ -0,0 +1,117 @@
+import { chance } from 'jest-chance';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import { generateSearchHit } from '../../test/generateSearchHit.js';
+import { SearchFailedException } from '../errors/SearchFailedException.js';
+import { UnknownPlatformException } from '../errors/UnknownPlatformException.js';
+import { Loader, Platform } from '../lib/modlist.types.js';
+import { CurseforgeSearchMod, curseforgeSearchHit, searchMods } from './curseforge/search.js';
+import { SearchHit } from './index.js';
+import { searchProjects } from './modrinth/search.js';
+import { CANDIDATE_LIMIT, resolveByName } from './resolveByName.js';
+
+vi.mock('./curseforge/search.js');
+vi.mock('./modrinth/search.js');
+
+const generateHit = (overrides?: Partial<SearchHit>): SearchHit => generateSearchHit(overrides).generated;
+
+describe('The resolution of a mod by its name', () => {
+  beforeEach(() => {
+    vi.resetAllMocks();
+  });
+
+  it('searches the platform for the game version and the loader', async () => {
+    vi.mocked(searchProjects).mockResolvedValueOnce([]);
+
+    await resolveByName(Platform.MODRINTH, 'sodium', '1.20.1', Loader.FABRIC);
+
+    expect(vi.mocked(searchProjects)).toHaveBeenCalledWith('sodium', '1.20.1', Loader.FABRIC, CANDIDATE_LIMIT);
+  });
+
+  it('is a match when there is a single hit', async () => {
+    const hit = generateHit();
+    vi.mocked(searchProjects).mockResolvedValueOnce([hit]);
+
+    const actual = await resolveByName(Platform.MODRINTH, chance.word(), '1.20.1', Loader.FABRIC);
+
+    expect(actual).toEqual({ match: hit, candidates: [] });
+  });
+
+  it('is a match when a single hit has the name', async () => {
+    const hit = generateHit({ name: 'Sodium Extra' });
+    vi.mocked(searchProjects).mockResolvedValueOnce([generateHit(), hit, generateHit()]);
+
+    const actual = await resolveByName(Platform.MODRINTH, 'sodium-extra', '1.20.1', Loader.FABRIC);
+
+    expect(actual).toEqual({ match: hit, candidates: [] });
+  });
+
+  it('is a match when a single hit has the slug', async () => {
+    const hit = generateHit({ slug: 'sodium' });
+    vi.mocked(searchProjects).mockResolvedValueOnce([generateHit(), hit]);
+
+    const actual = await resolveByName(Platform.MODRINTH, 'Sodium', '1.20.1', Loader.FABRIC);
+
+    expect(actual.match).toEqual(hit);
+  });
+
+  it('offers the hits to choose from when the name is ambiguous', async () => {
+    const hits = [generateHit(), generateHit(), generateHit()];
+    vi.mocked(searchProjects).mockResolvedValueOnce(hits);
+
+    const actual = await resolveByName(Platform.MODRINTH, 'sodium', '1.20.1', Loader.FABRIC);
+
+    expect(actual).toEqual({ candidates: hits });
+  });
+
+  it('offers the hits to choose from when several of them have the name', async () => {
+    const hits = [generateHit({ name: 'Sodium' }), generateHit({ slug: 'sodium' })];
+    vi.mocked(searchProjects).mockResolvedValueOnce(hits);
+
+    const actual = await resolveByName(Platform.MODRINTH, 'sodium', '1.20.1', Loader.FABRIC);
+
+    expect(actual).toEqual({ candidates: hits });
+  });
+
+  it('has neither a match nor candidates when nothing is found', async () => {
+    vi.mocked(searchProjects).mockResolvedValueOnce([]);
+
+    const actual = await resolveByName(Platform.MODRINTH, chance.word(), '1.20.1', Loader.FABRIC);
+
+    expect(actual).toEqual({ candidates: [] });
+  });
+
+  it('offers no more candidates than the limit', async () => {
+    vi.mocked(searchProjects).mockResolvedValueOnce([]);
+
+    await resolveByName(Platform.MODRINTH, 'sodium', '1.20.1', Loader.FABRIC, 3);
+
+    expect(vi.mocked(searchProjects)).toHaveBeenCalledWith('sodium', '1.20.1', Loader.FABRIC, 3);
+  });
+
+  it('presents the Curseforge mods like the other platforms', async () => {
+    const mods = [{ id: 1 }, { id: 2 }] as CurseforgeSearchMod[];
+    const hits = [generateHit({ platform: Platform.CURSEFORGE }), generateHit({ platform: Platform.CURSEFORGE })];
+    vi.mocked(searchMods).mockResolvedValueOnce(mods);
+    vi.mocked(curseforgeSearchHit).mockReturnValueOnce(hits[0]).mockReturnValueOnce(hits[1]);
+
+    const actual = await resolveByName(Platform.CURSEFORGE, 'sodium', '1.19.2', Loader.FORGE);
+
+    expect(vi.mocked(searchMods)).toHaveBeenCalledWith('sodium', '1.19.2', Loader.FORGE, CANDIDATE_LIMIT);
+    expect(vi.mocked(curseforgeSearchHit).mock.calls.map((call) => call[0])).toEqual(mods);
+    expect(actual).toEqual({ candidates: hits });
+  });
+
+  it('fails when the search fails', async () => {
+    vi.mocked(searchProjects).mockRejectedValueOnce(new SearchFailedException('sodium', Platform.MODRINTH));
+
+    await expect(resolveByName(Platform.MODRINTH, 'sodium', '1.20.1', Loader.FABRIC)).rejects.toThrow(
+      new SearchFailedException('sodium', Platform.MODRINTH)
+    );
+  });
+
+  it('does not know other platforms', async () => {
+    await expect(resolveByName('unknown' as Platform, 'sodium', '1.20.1', Loader.FABRIC)).rejects.toThrow(
+      new UnknownPlatformException('unknown')
+    );
+  });
+});
@@ -0,0 +1,77 @@
+import { UnknownPlatformException } from '../errors/UnknownPlatformException.js';
+import { Loader, Platform } from '../lib/modlist.types.js';
+import { curseforgeSearchHit, searchMods } from './curseforge/search.js';
+import { SearchHit } from './index.js';
+import { searchProjects } from './modrinth/search.js';
+
+/**
+ * How many of the hits are offered to choose from when the name is ambiguous
+ */
+export const CANDIDATE_LIMIT = 10;
+
+export interface ResolveResult {
+  /**
+   * The mod that was meant, when it is clear from the search
+   */
+  match?: SearchHit;
+  /**
+   * The hits to choose from when it isn't, in the order the platform ranked them
+   */
+  candidates: SearchHit[];
+}
+
+const comparableName = (name: string) => name.toLowerCase().replace(/[^a-z0-9]/g, '');
+
+const search = async (
+  platform: Platform,
+  name: string,
+  gameVersion: string,
+  loader: Loader,
+  limit: number
+): Promise<SearchHit[]> => {
+  switch (platform) {
+    case Platform.CURSEFORGE:
+      return (await searchMods(name, gameVersion, loader, limit)).map(curseforgeSearchHit);
+    case Platform.MODRINTH:
+      return await searchProjects(name, gameVersion, loader, limit);
+    default:
+      throw new UnknownPlatformException(platform);
+  }
+};
+
+/**
+ * Finds the mod a name refers to on the platform.
+ * It is a match when the search has a single hit, or when a single hit has the name or the slug that was asked for,
+ * ignoring the case and the punctuation. Otherwise every hit is a candidate and the user has to choose.
+ * Neither a match nor candidates means nothing was found.
+ *
+ * @param platform
+ * @param name
+ * @param gameVersion
+ * @param loader
+ * @param limit The maximum number of candidates
+ * @throws {SearchFailedException} When the platform cannot be searched
+ * @throws {UnknownPlatformException} When the platform is not supported
+ */
+export const resolveByName = async (
+  platform: Platform,
+  name: string,
+  gameVersion: string,
+  loader: Loader,
+  limit: number = CANDIDATE_LIMIT
+): Promise<ResolveResult> => {
+  const hits = await search(platform, name, gameVersion, loader, limit);
+
+  if (hits.length === 1) {
+    return { match: hits[0], candidates: [] };
+  }
+
+  const wanted = comparableName(name);
+  const named = hits.filter((hit) => comparableName(hit.name) === wanted || comparableName(hit.slug) === wanted);
+
+  if (named.length === 1) {
+    return { match: named[0], candidates: [] };
+  }
+
+  return { candidates: hits };
+};
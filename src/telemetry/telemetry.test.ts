@@ -2,6 +2,7 @@ import { getHWID } from 'hwid';
 import { PostHog } from 'posthog-node';
 import { beforeEach, describe, expect, it, vi } from 'vitest';
 import { ModsJson } from '../lib/modlist.types.js';
+import { setOfflineMode } from '../lib/offline.js';
 import { Telemetry } from './telemetry.js';
 
 vi.mock('posthog-node');
@@ -45,6 +46,15 @@ describe('Telemetry', () => {
     });
   });
 
+  it('should not capture anything in offline mode', async () => {
+    setOfflineMode(true);
+
+    await telemetry.capture('test-event', { key: 'value' });
+    setOfflineMode();
+
+    expect(posthogInstance.capture).not.toHaveBeenCalled();
+  });
+
   it('should capture command correctly', async () => {
     const commandTelemetry = {
       command: 'test-command',
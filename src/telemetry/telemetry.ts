@@ -2,6 +2,7 @@ import { getHWID } from 'hwid';
 import { PostHog } from 'posthog-node';
 import { posthogApiKey } from '../env.js';
 import { ModsJson } from '../lib/modlist.types.js';
+import { isOfflineMode } from '../lib/offline.js';
 import { DefaultOptions } from '../mmm.js';
 import { version } from '../version.js';
 
@@ -30,6 +31,10 @@ export class Telemetry {
   }
 
   public async capture(event: string, properties?: Record<string, unknown>): Promise<void> {
+    if (isOfflineMode()) {
+      return;
+    }
+
     const distinctId = await getHWID();
     this.posthog.capture({
       distinctId: distinctId,
@@ -1,10 +1,11 @@
 import { chance } from 'jest-chance';
-import { CurseforgeModFile, HashFunctions } from '../src/repositories/curseforge/fetch.js';
+import { CurseforgeFileDependency, CurseforgeModFile, HashFunctions } from '../src/repositories/curseforge/fetch.js';
 import { GeneratorResult } from './test.types.js';
 
 export const generateCurseforgeModFile = (
   overrides?: Partial<CurseforgeModFile>
 ): GeneratorResult<CurseforgeModFile> => {
+  const id = chance.integer({ min: 100000, max: 9999999 });
   const displayName = chance.word();
   const fileDate = chance.date().toISOString();
   const releaseType = chance.integer({ min: 1, max: 3 });
@@ -23,6 +24,7 @@ export const generateCurseforgeModFile = (
       value: chance.hash({ casing: 'upper', length: 16 })
     }
   ];
+  const dependencies: CurseforgeFileDependency[] = [];
   const sortableGameVersions = [
     {
       gameVersionName: chance.word(),
@@ -31,6 +33,7 @@ export const generateCurseforgeModFile = (
   ];
 
   const generated: CurseforgeModFile = {
+    id: id,
     fileFingerprint: fileFingerprint,
     displayName: displayName,
     fileDate: fileDate,
@@ -41,10 +44,12 @@ export const generateCurseforgeModFile = (
     isAvailable: isAvailable,
     hashes: hashes,
     sortableGameVersions: sortableGameVersions,
+    dependencies: dependencies,
     ...overrides
   };
 
   const expected: CurseforgeModFile = {
+    id: id,
     fileFingerprint: fileFingerprint,
     displayName: displayName,
     fileDate: fileDate,
@@ -55,6 +60,7 @@ export const generateCurseforgeModFile = (
     isAvailable: isAvailable,
     hashes: hashes,
     sortableGameVersions: sortableGameVersions,
+    dependencies: dependencies,
     ...overrides
   };
 
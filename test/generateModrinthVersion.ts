@@ -6,6 +6,7 @@ import { generateModrinthFile } from './generateModrinthFile.js';
 import { GeneratorResult } from './test.types.js';
 
 export const generateModrinthVersion = (overrides?: Partial<ModrinthVersion>): GeneratorResult<ModrinthVersion> => {
+  const id = chance.string({ alpha: true, numeric: true, length: 8 });
   const name = chance.word();
   const projectId = chance.word();
   const versionType = chance.integer({ min: 1, max: 3 });
@@ -27,6 +28,7 @@ export const generateModrinthVersion = (overrides?: Partial<ModrinthVersion>): G
   }
 
   const generated: ModrinthVersion = {
+    id: id,
     date_published: datePublished,
     files: files,
     game_versions: gameVersions,
@@ -39,6 +41,7 @@ export const generateModrinthVersion = (overrides?: Partial<ModrinthVersion>): G
   };
 
   const expected: ModrinthVersion = {
+    id: id,
     name: name,
     project_id: projectId,
     loaders: loaders,
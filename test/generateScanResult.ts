@@ -1,5 +1,5 @@
 import { chance } from 'jest-chance';
-import { ScanResults } from '../src/actions/scan.js';
+import { ScanMatch, ScanResults } from '../src/actions/scan.js';
 import { Platform, RemoteModDetails } from '../src/lib/modlist.types.js';
 import { PlatformLookupResult } from '../src/repositories/index.js';
 import { generatePlatformLookupResult } from './generatePlatformLookupResult.js';
@@ -30,16 +30,25 @@ export const generateScanResult = (overrides?: ScanResultGeneratorOverrides): Ge
     { name: name } as Partial<RemoteModDetails>
   ).generated;
 
+  const match: ScanMatch = {
+    platform: platform,
+    modId: modId,
+    local: localDetails.mod,
+    remote: resolvedDetails
+  };
+
   return {
     generated: {
       allRemoteDetails: [resolvedDetails],
       localDetails: [localDetails],
-      preferredDetails: resolvedDetails
+      preferredDetails: resolvedDetails,
+      matches: [match]
     },
     expected: {
       allRemoteDetails: [resolvedDetails],
       localDetails: [localDetails],
-      preferredDetails: resolvedDetails
+      preferredDetails: resolvedDetails,
+      matches: [match]
     }
   };
 };
@@ -0,0 +1,23 @@
+import { chance } from 'jest-chance';
+import { Platform } from '../src/lib/modlist.types.js';
+import { SearchHit } from '../src/repositories/index.js';
+import { GeneratorResult } from './test.types.js';
+
+export const generateSearchHit = (overrides?: Partial<SearchHit>): GeneratorResult<SearchHit> => {
+  const generated: SearchHit = {
+    platform: Platform.MODRINTH,
+    id: chance.string({ alpha: true, numeric: true, length: 8 }),
+    slug: chance.word(),
+    name: chance.word(),
+    summary: chance.sentence(),
+    author: chance.word(),
+    downloads: chance.integer({ min: 0, max: 1000000 }),
+    latestGameVersion: '1.20.1',
+    ...overrides
+  };
+
+  return {
+    generated: generated,
+    expected: generated
+  };
+};
@@ -0,0 +1,55 @@
+import zlib from 'node:zlib';
+
+export interface ReadZipEntry {
+  name: string;
+  data: Buffer;
+  crc: number;
+  method: number;
+}
+
+/**
+ * Reads a zip the way an unzip tool does, from the end of central directory record through the central directory
+ * to the local headers, so the tests can tell if an archive is well-formed
+ */
+export const readZip = (archive: Buffer): ReadZipEntry[] => {
+  const endOffset = archive.lastIndexOf(Buffer.from([0x50, 0x4b, 0x05, 0x06]));
+  if (endOffset < 0) {
+    throw new Error('The end of central directory record is missing');
+  }
+
+  const count = archive.readUInt16LE(endOffset + 10);
+  let centralOffset = archive.readUInt32LE(endOffset + 16);
+  const entries: ReadZipEntry[] = [];
+
+  for (let index = 0; index < count; index++) {
+    if (archive.readUInt32LE(centralOffset) !== 0x02014b50) {
+      throw new Error(`The central directory header ${index} is missing`);
+    }
+    const method = archive.readUInt16LE(centralOffset + 10);
+    const crc = archive.readUInt32LE(centralOffset + 16);
+    const compressedSize = archive.readUInt32LE(centralOffset + 20);
+    const size = archive.readUInt32LE(centralOffset + 24);
+    const nameLength = archive.readUInt16LE(centralOffset + 28);
+    const extraLength = archive.readUInt16LE(centralOffset + 30);
+    const commentLength = archive.readUInt16LE(centralOffset + 32);
+    const localOffset = archive.readUInt32LE(centralOffset + 42);
+    const name = archive.subarray(centralOffset + 46, centralOffset + 46 + nameLength).toString('utf8');
+
+    if (archive.readUInt32LE(localOffset) !== 0x04034b50) {
+      throw new Error(`The local header of ${name} is missing`);
+    }
+    const localNameLength = archive.readUInt16LE(localOffset + 26);
+    const localExtraLength = archive.readUInt16LE(localOffset + 28);
+    const dataOffset = localOffset + 30 + localNameLength + localExtraLength;
+    const compressed = archive.subarray(dataOffset, dataOffset + compressedSize);
+    const data = method === 8 ? zlib.inflateRawSync(compressed) : compressed;
+    if (data.length !== size) {
+      throw new Error(`${name} is ${data.length} bytes instead of ${size}`);
+    }
+
+    entries.push({ name: name, data: data, crc: crc, method: method });
+    centralOffset += 46 + nameLength + extraLength + commentLength;
+  }
+
+  return entries;
+};
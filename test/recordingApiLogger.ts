@@ -0,0 +1,26 @@
+import { ApiLogFields, ApiLogLevel, ApiLogger } from '../src/lib/apiLogger.js';
+
+export interface ApiLogEntry {
+  level: ApiLogLevel;
+  message: string;
+  fields?: ApiLogFields;
+}
+
+export interface RecordingApiLogger extends ApiLogger {
+  entries: ApiLogEntry[];
+}
+
+export const recordingApiLogger = (): RecordingApiLogger => {
+  const entries: ApiLogEntry[] = [];
+  const record = (level: ApiLogLevel) => (message: string, fields?: ApiLogFields) => {
+    entries.push({ level: level, message: message, fields: fields });
+  };
+
+  return {
+    entries: entries,
+    debug: record('debug'),
+    info: record('info'),
+    warn: record('warn'),
+    error: record('error')
+  };
+};
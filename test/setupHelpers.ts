@@ -2,6 +2,7 @@ import path from 'node:path';
 import { expect, vi } from 'vitest';
 import { ensureConfiguration, fileExists, readLockFile, writeConfigFile, writeLockFile } from '../src/lib/config.js';
 import { downloadFile } from '../src/lib/downloader.js';
+import { fileOverridesOf } from '../src/lib/fileOverrides.js';
 import { Mod, ModInstall, ModsJson } from '../src/lib/modlist.types.js';
 import { updateMod } from '../src/lib/updater.js';
 import { fetchModDetails } from '../src/repositories/index.js';
@@ -27,7 +28,9 @@ export const expectModDetailsHaveBeenFetchedCorrectlyForMod = (mod: Mod, modsJso
     modsJson.gameVersion,
     modsJson.loader,
     mod.allowVersionFallback,
-    mod.version
+    mod.version,
+    mod.fallback,
+    fileOverridesOf(mod)
   );
 };
 
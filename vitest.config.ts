@@ -24,7 +24,7 @@ export default defineConfig({
     isolate: true,
     coverage: {
       include: ['src/**/*.ts'],
-      exclude: ['**/*.testGameVersion.ts', '**/__mocks__/**.*', '**/*.d.ts', '**/*.test.ts'],
+      exclude: ['**/*.testGameVersion.ts', '**/__mocks__/**.*', '**/*.d.ts', '**/*.test.ts', '**/*.bench.ts'],
       all: true,
       reportsDirectory: './reports/coverage/unit',
       reporter: coverageReporters,